@@ -0,0 +1,156 @@
+// Package client calls the hepsre API from other Go services. Its types
+// and one method per operation are generated from the OpenAPI description
+// served at /openapi.json, so they cannot drift from the server; methods
+// are named after the operation, e.g. PostAnalyzePod for POST
+// /api/v1/analyze/pod.
+//
+//	c := client.New("http://hepsre:8080", client.WithAPIKey(key))
+//	analysis, err := c.PostAnalyzePod(ctx, client.AnalyzePodRequest{Namespace: "shop", Pod: "cart-0"}, client.PostAnalyzePodParams{})
+package client
+
+//go:generate go run ./internal/gen
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Client calls one hepsre server. It is safe for concurrent use.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	apiKey     string
+	token      string
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithAPIKey authenticates with an API key (server.api_keys)
+func WithAPIKey(key string) Option {
+	return func(c *Client) { c.apiKey = key }
+}
+
+// WithBearerToken authenticates with an OIDC ID token (server.oidc)
+func WithBearerToken(token string) Option {
+	return func(c *Client) { c.token = token }
+}
+
+// WithHTTPClient sends requests with hc instead of a client with a
+// five-minute timeout, which fits synchronous analyses
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.httpClient = hc }
+}
+
+// New returns a client of the server at baseURL, e.g. http://hepsre:8080
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: &http.Client{Timeout: 5 * time.Minute},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Error is a non-2xx response of the server
+type Error struct {
+	StatusCode int
+	Message    string `json:"error"`
+	// Class is the error class of failed analyses, e.g. llm_timeout
+	Class string `json:"error_class"`
+}
+
+func (e *Error) Error() string {
+	if e.Class != "" {
+		return fmt.Sprintf("hepsre: %d %s: %s", e.StatusCode, e.Class, e.Message)
+	}
+	return fmt.Sprintf("hepsre: %d: %s", e.StatusCode, e.Message)
+}
+
+// WaitJob polls an analysis job every interval until it finishes. A failed
+// job is returned with its Error set, not as an error.
+func (c *Client) WaitJob(ctx context.Context, id string, interval time.Duration) (*AnalysisJob, error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		resp, err := c.GetJobsJob(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if job := resp.Job; job != nil && job.Done() {
+			return job, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// do sends a request with body encoded as JSON and decodes the response
+// into out, or copies it if out is a *[]byte
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out any) error {
+	target := c.baseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.apiKey != "" {
+		req.Header.Set("X-API-Key", c.apiKey)
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		apiErr := &Error{StatusCode: resp.StatusCode}
+		if json.Unmarshal(data, apiErr) != nil || apiErr.Message == "" {
+			apiErr.Message = strings.TrimSpace(string(data))
+		}
+		return apiErr
+	}
+	if raw, ok := out.(*[]byte); ok {
+		*raw = data
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,1850 @@
+// Code generated by go run ./internal/gen from openapi.json; DO NOT EDIT.
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// AdmissionContext is the AdmissionContext schema of the API
+type AdmissionContext struct {
+	Failures         []AdmissionFailure `json:"failures,omitempty"`
+	PodMissing       bool               `json:"pod_missing,omitempty"`
+	PodSecurity      map[string]string  `json:"pod_security,omitempty"`
+	PodSecurityError string             `json:"pod_security_error,omitempty"`
+}
+
+// AdmissionFailure is the AdmissionFailure schema of the API
+type AdmissionFailure struct {
+	Category string    `json:"category,omitempty"`
+	Count    int       `json:"count,omitempty"`
+	Kind     string    `json:"kind,omitempty"`
+	LastSeen time.Time `json:"last_seen,omitempty"`
+	Message  string    `json:"message,omitempty"`
+	Name     string    `json:"name,omitempty"`
+	Reason   string    `json:"reason,omitempty"`
+}
+
+// Alert is the Alert schema of the API
+type Alert struct {
+	Annotations  map[string]string `json:"annotations,omitempty"`
+	EndsAt       time.Time         `json:"endsAt,omitempty"`
+	Fingerprint  string            `json:"fingerprint,omitempty"`
+	GeneratorURL string            `json:"generatorURL,omitempty"`
+	Labels       map[string]string `json:"labels,omitempty"`
+	StartsAt     time.Time         `json:"startsAt,omitempty"`
+	Status       string            `json:"status,omitempty"`
+}
+
+// AlertAnalysisError is the AlertAnalysisError schema of the API
+type AlertAnalysisError struct {
+	AlertName   string    `json:"alert_name,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	ErrorClass  string    `json:"error_class,omitempty"`
+	Fingerprint string    `json:"fingerprint,omitempty"`
+	RetryAfter  time.Time `json:"retry_after,omitempty"`
+}
+
+// AlertAnalysisResult is the AlertAnalysisResult schema of the API
+type AlertAnalysisResult struct {
+	Links         map[string]Link `json:"_links,omitempty"`
+	AlertName     string          `json:"alert_name,omitempty"`
+	Analysis      *Analysis       `json:"analysis,omitempty"`
+	CollectedData *CollectedData  `json:"collected_data,omitempty"`
+	Deduplicated  bool            `json:"deduplicated,omitempty"`
+	Fingerprint   string          `json:"fingerprint,omitempty"`
+	ID            int64           `json:"id,omitempty"`
+	Incident      string          `json:"incident,omitempty"`
+	Namespace     string          `json:"namespace,omitempty"`
+	Pod           string          `json:"pod,omitempty"`
+	Recurrence    *Recurrence     `json:"recurrence,omitempty"`
+	Replayed      bool            `json:"replayed,omitempty"`
+	Severity      string          `json:"severity,omitempty"`
+	Status        string          `json:"status,omitempty"`
+}
+
+// AlertManagerWebhook is the AlertManagerWebhook schema of the API
+type AlertManagerWebhook struct {
+	Alerts            []Alert           `json:"alerts,omitempty"`
+	CommonAnnotations map[string]string `json:"commonAnnotations,omitempty"`
+	CommonLabels      map[string]string `json:"commonLabels,omitempty"`
+	ExternalURL       string            `json:"externalURL,omitempty"`
+	GroupKey          string            `json:"groupKey,omitempty"`
+	GroupLabels       map[string]string `json:"groupLabels,omitempty"`
+	Receiver          string            `json:"receiver,omitempty"`
+	Status            string            `json:"status,omitempty"`
+	TruncatedAlerts   int               `json:"truncatedAlerts,omitempty"`
+	Version           string            `json:"version,omitempty"`
+}
+
+// AlertMetrics is the AlertMetrics schema of the API
+type AlertMetrics struct {
+	Expression  string         `json:"expression,omitempty"`
+	Series      []MetricSeries `json:"series,omitempty"`
+	TotalSeries int            `json:"total_series,omitempty"`
+}
+
+// AlertSummary is the AlertSummary schema of the API
+type AlertSummary struct {
+	Cluster   string    `json:"cluster,omitempty"`
+	Name      string    `json:"name,omitempty"`
+	Namespace string    `json:"namespace,omitempty"`
+	Pod       string    `json:"pod,omitempty"`
+	Severity  string    `json:"severity,omitempty"`
+	StartedAt time.Time `json:"started_at,omitempty"`
+	Workload  string    `json:"workload,omitempty"`
+}
+
+// Analysis is the Analysis schema of the API
+type Analysis struct {
+	Confidence       string            `json:"confidence,omitempty"`
+	ConfidenceRubric *ConfidenceRubric `json:"confidence_rubric,omitempty"`
+	ConfidenceScore  int               `json:"confidence_score,omitempty"`
+	Evidence         *Evidence         `json:"evidence,omitempty"`
+	Hypotheses       []Hypothesis      `json:"hypotheses,omitempty"`
+	Reasoning        string            `json:"reasoning,omitempty"`
+	Recommendations  []Recommendation  `json:"recommendations,omitempty"`
+	RootCause        string            `json:"root_cause,omitempty"`
+	Timeline         []TimelineEvent   `json:"timeline,omitempty"`
+}
+
+// AnalysisJob is the AnalysisJob schema of the API
+type AnalysisJob struct {
+	AnalysisID int64     `json:"analysis_id,omitempty"`
+	Attempts   int       `json:"attempts,omitempty"`
+	CreatedAt  time.Time `json:"created_at,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	ErrorClass string    `json:"error_class,omitempty"`
+	FinishedAt time.Time `json:"finished_at,omitempty"`
+	ID         string    `json:"id,omitempty"`
+	Kind       string    `json:"kind,omitempty"`
+	Lookback   string    `json:"lookback,omitempty"`
+	Namespace  string    `json:"namespace,omitempty"`
+	NextRunAt  time.Time `json:"next_run_at,omitempty"`
+	RequestID  string    `json:"request_id,omitempty"`
+	Result     any       `json:"result,omitempty"`
+	Stage      string    `json:"stage,omitempty"`
+	StartedAt  time.Time `json:"started_at,omitempty"`
+	Status     string    `json:"status,omitempty"`
+	Target     string    `json:"target,omitempty"`
+}
+
+// AnalysisResponse is the AnalysisResponse schema of the API
+type AnalysisResponse struct {
+	Links          map[string]Link   `json:"_links,omitempty"`
+	Admission      *AdmissionContext `json:"admission,omitempty"`
+	Alert          *AlertSummary     `json:"alert,omitempty"`
+	Analysis       *Analysis         `json:"analysis,omitempty"`
+	Analyzers      []string          `json:"analyzers,omitempty"`
+	Artifacts      []ArtifactRef     `json:"artifacts,omitempty"`
+	BudgetExceeded bool              `json:"budget_exceeded,omitempty"`
+	CollectedData  *CollectedData    `json:"collected_data,omitempty"`
+	Error          string            `json:"error,omitempty"`
+	Escalation     []EscalationStep  `json:"escalation,omitempty"`
+	Feedback       *Feedback         `json:"feedback,omitempty"`
+	ID             int64             `json:"id,omitempty"`
+	IncidentID     int64             `json:"incident_id,omitempty"`
+	Manifest       []DataSource      `json:"manifest,omitempty"`
+	Metrics        *AlertMetrics     `json:"metrics,omitempty"`
+	Model          string            `json:"model,omitempty"`
+	OOM            *OOMContext       `json:"oom,omitempty"`
+	Probes         *ProbeReport      `json:"probes,omitempty"`
+	PromptVersion  string            `json:"prompt_version,omitempty"`
+	Provider       string            `json:"provider,omitempty"`
+	Recurrence     *Recurrence       `json:"recurrence,omitempty"`
+	Redactions     []Redaction       `json:"redactions,omitempty"`
+	RelatedAlerts  []RelatedAlert    `json:"related_alerts,omitempty"`
+	RequestID      string            `json:"request_id,omitempty"`
+	Rollout        *RolloutInfo      `json:"rollout,omitempty"`
+	Rule           *RuleMatch        `json:"rule,omitempty"`
+	Silences       []Silence         `json:"silences,omitempty"`
+	Stages         []Stage           `json:"stages,omitempty"`
+	Status         string            `json:"status,omitempty"`
+	Tags           []string          `json:"tags,omitempty"`
+	Usage          *LLMUsage         `json:"usage,omitempty"`
+	Verbosity      string            `json:"verbosity,omitempty"`
+	Workload       *WorkloadStatus   `json:"workload,omitempty"`
+}
+
+// AnalysisSummary is the AnalysisSummary schema of the API
+type AnalysisSummary struct {
+	Links           map[string]Link `json:"_links,omitempty"`
+	AlertName       string          `json:"alert_name,omitempty"`
+	Cluster         string          `json:"cluster,omitempty"`
+	Confidence      string          `json:"confidence,omitempty"`
+	ConfidenceScore int             `json:"confidence_score,omitempty"`
+	CreatedAt       time.Time       `json:"created_at,omitempty"`
+	Error           string          `json:"error,omitempty"`
+	ID              int64           `json:"id,omitempty"`
+	Namespace       string          `json:"namespace,omitempty"`
+	Pod             string          `json:"pod,omitempty"`
+	RootCause       string          `json:"root_cause,omitempty"`
+	Severity        string          `json:"severity,omitempty"`
+	Status          string          `json:"status,omitempty"`
+	Tags            []string        `json:"tags,omitempty"`
+}
+
+// AnalyzeAlertRequest is the AnalyzeAlertRequest schema of the API
+type AnalyzeAlertRequest struct {
+	AlertID   string `json:"alert_id,omitempty"`
+	Cluster   string `json:"cluster,omitempty"`
+	Lookback  string `json:"lookback,omitempty"`
+	Namespace string `json:"namespace"`
+	Pod       string `json:"pod"`
+	// Verbosity is one of brief, standard, deep
+	Verbosity string `json:"verbosity,omitempty"`
+}
+
+// AnalyzeDeploymentRequest is the AnalyzeDeploymentRequest schema of the API
+type AnalyzeDeploymentRequest struct {
+	Cluster   string `json:"cluster,omitempty"`
+	Lookback  string `json:"lookback,omitempty"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	// Verbosity is one of brief, standard, deep
+	Verbosity string `json:"verbosity,omitempty"`
+}
+
+// AnalyzePodRequest is the AnalyzePodRequest schema of the API
+type AnalyzePodRequest struct {
+	Cluster   string `json:"cluster,omitempty"`
+	Lookback  string `json:"lookback,omitempty"`
+	Namespace string `json:"namespace"`
+	Pod       string `json:"pod"`
+	// Verbosity is one of brief, standard, deep
+	Verbosity string `json:"verbosity,omitempty"`
+}
+
+// ArtifactRef is the ArtifactRef schema of the API
+type ArtifactRef struct {
+	ContentType string `json:"content_type,omitempty"`
+	ID          string `json:"id,omitempty"`
+	Name        string `json:"name,omitempty"`
+	Size        int    `json:"size,omitempty"`
+	Truncated   bool   `json:"truncated,omitempty"`
+}
+
+// CollectedData is the CollectedData schema of the API
+type CollectedData struct {
+	EventsCount int    `json:"events_count,omitempty"`
+	LogsLines   int    `json:"logs_lines,omitempty"`
+	TimeRange   string `json:"time_range,omitempty"`
+}
+
+// CommandCheck is the CommandCheck schema of the API
+type CommandCheck struct {
+	Error string `json:"error,omitempty"`
+	Valid bool   `json:"valid,omitempty"`
+	Verb  string `json:"verb,omitempty"`
+}
+
+// ConfidenceRubric is the ConfidenceRubric schema of the API
+type ConfidenceRubric struct {
+	Certainty           int    `json:"certainty,omitempty"`
+	CorroboratingEvents int    `json:"corroborating_events,omitempty"`
+	EvidenceCoverage    int    `json:"evidence_coverage,omitempty"`
+	SelfReported        int    `json:"self_reported,omitempty"`
+	SelfReportedLevel   string `json:"self_reported_level,omitempty"`
+}
+
+// CorrectionResponse is the CorrectionResponse schema of the API
+type CorrectionResponse struct {
+	Links            map[string]Link `json:"_links,omitempty"`
+	Analysis         *Analysis       `json:"analysis,omitempty"`
+	AnalysisID       int64           `json:"analysis_id,omitempty"`
+	CorrectsVersion  int             `json:"corrects_version,omitempty"`
+	RootCauseChanged bool            `json:"root_cause_changed,omitempty"`
+	Version          int             `json:"version,omitempty"`
+}
+
+// DataSource is the DataSource schema of the API
+type DataSource struct {
+	Duration   string    `json:"duration,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	ErrorClass string    `json:"error_class,omitempty"`
+	Items      int       `json:"items,omitempty"`
+	Name       string    `json:"name,omitempty"`
+	Since      time.Time `json:"since,omitempty"`
+	SkipReason string    `json:"skip_reason,omitempty"`
+	Skipped    bool      `json:"skipped,omitempty"`
+	Until      time.Time `json:"until,omitempty"`
+	Version    string    `json:"version,omitempty"`
+}
+
+// EscalationStep is the EscalationStep schema of the API
+type EscalationStep struct {
+	Confidence      string `json:"confidence,omitempty"`
+	ConfidenceScore int    `json:"confidence_score,omitempty"`
+	Error           string `json:"error,omitempty"`
+	RootCause       string `json:"root_cause,omitempty"`
+	Scope           string `json:"scope,omitempty"`
+}
+
+// EventEntry is the EventEntry schema of the API
+type EventEntry struct {
+	Message   string    `json:"message,omitempty"`
+	Reason    string    `json:"reason,omitempty"`
+	Timestamp time.Time `json:"timestamp,omitempty"`
+	Type      string    `json:"type,omitempty"`
+}
+
+// Evidence is the Evidence schema of the API
+type Evidence struct {
+	Events    []EventEntry `json:"events,omitempty"`
+	Logs      []LogEntry   `json:"logs,omitempty"`
+	PodConfig any          `json:"pod_config,omitempty"`
+}
+
+// EvidenceRef is the EvidenceRef schema of the API
+type EvidenceRef struct {
+	Index int    `json:"index,omitempty"`
+	Type  string `json:"type,omitempty"`
+}
+
+// Feedback is the Feedback schema of the API
+type Feedback struct {
+	Author            string    `json:"author,omitempty"`
+	CreatedAt         time.Time `json:"created_at,omitempty"`
+	Hint              string    `json:"hint,omitempty"`
+	RejectedRootCause string    `json:"rejected_root_cause,omitempty"`
+	Version           int       `json:"version,omitempty"`
+}
+
+// FeedbackRecord is the FeedbackRecord schema of the API
+type FeedbackRecord struct {
+	Links              map[string]Link `json:"_links,omitempty"`
+	AlertName          string          `json:"alert_name,omitempty"`
+	AnalysisID         int64           `json:"analysis_id,omitempty"`
+	Author             string          `json:"author,omitempty"`
+	Comment            string          `json:"comment,omitempty"`
+	CorrectedRootCause string          `json:"corrected_root_cause,omitempty"`
+	CorrectedVersion   int             `json:"corrected_version,omitempty"`
+	CreatedAt          time.Time       `json:"created_at,omitempty"`
+	Hint               string          `json:"hint,omitempty"`
+	ID                 int64           `json:"id,omitempty"`
+	Namespace          string          `json:"namespace,omitempty"`
+	Rating             string          `json:"rating,omitempty"`
+	RejectedRootCause  string          `json:"rejected_root_cause,omitempty"`
+	Version            int             `json:"version,omitempty"`
+}
+
+// FeedbackRequest is the FeedbackRequest schema of the API
+type FeedbackRequest struct {
+	Hint    string `json:"hint"`
+	Version int    `json:"version,omitempty"`
+}
+
+// HealthReport is the HealthReport schema of the API
+type HealthReport struct {
+	GeneratedAt   time.Time        `json:"generated_at,omitempty"`
+	Model         string           `json:"model,omitempty"`
+	Namespace     string           `json:"namespace,omitempty"`
+	OtherWarnings []WarningEvent   `json:"other_warnings,omitempty"`
+	Redactions    []Redaction      `json:"redactions,omitempty"`
+	Stages        []Stage          `json:"stages,omitempty"`
+	Status        string           `json:"status,omitempty"`
+	Summary       string           `json:"summary,omitempty"`
+	TimeRange     string           `json:"time_range,omitempty"`
+	Workloads     []WorkloadHealth `json:"workloads,omitempty"`
+}
+
+// Hypothesis is the Hypothesis schema of the API
+type Hypothesis struct {
+	Probability float64 `json:"probability,omitempty"`
+	Reasoning   string  `json:"reasoning,omitempty"`
+	RootCause   string  `json:"root_cause,omitempty"`
+}
+
+// IncidentAnalysesRequest is the IncidentAnalysesRequest schema of the API
+type IncidentAnalysesRequest struct {
+	AnalysisIDs []int64 `json:"analysis_ids"`
+}
+
+// IncidentRequest is the IncidentRequest schema of the API
+type IncidentRequest struct {
+	AnalysisIDs []int64 `json:"analysis_ids"`
+	Title       string  `json:"title,omitempty"`
+}
+
+// IncidentResponse is the IncidentResponse schema of the API
+type IncidentResponse struct {
+	Embedded struct {
+		Analyses []AnalysisSummary `json:"analyses,omitempty"`
+	} `json:"_embedded,omitempty"`
+	Links         map[string]Link `json:"_links,omitempty"`
+	AnalysisCount int             `json:"analysis_count,omitempty"`
+	Author        string          `json:"author,omitempty"`
+	CreatedAt     time.Time       `json:"created_at,omitempty"`
+	ID            int64           `json:"id,omitempty"`
+	Namespaces    []string        `json:"namespaces,omitempty"`
+	Severity      string          `json:"severity,omitempty"`
+	Status        string          `json:"status,omitempty"`
+	Title         string          `json:"title,omitempty"`
+	UpdatedAt     time.Time       `json:"updated_at,omitempty"`
+}
+
+// IncidentResult is the IncidentResult schema of the API
+type IncidentResult struct {
+	Links         map[string]Link `json:"_links,omitempty"`
+	Alerts        []string        `json:"alerts,omitempty"`
+	Analysis      *Analysis       `json:"analysis,omitempty"`
+	CollectedData *CollectedData  `json:"collected_data,omitempty"`
+	ID            int64           `json:"id,omitempty"`
+	Namespace     string          `json:"namespace,omitempty"`
+	Pods          []string        `json:"pods,omitempty"`
+	Severity      string          `json:"severity,omitempty"`
+	Workload      string          `json:"workload,omitempty"`
+}
+
+// IncidentUpdate is the IncidentUpdate schema of the API
+type IncidentUpdate struct {
+	// Status is one of open, resolved
+	Status string `json:"status,omitempty"`
+	Title  string `json:"title,omitempty"`
+}
+
+// LLMUsage is the LLMUsage schema of the API
+type LLMUsage struct {
+	CompletionTokens int     `json:"completion_tokens,omitempty"`
+	CostUSD          float64 `json:"cost_usd,omitempty"`
+	PromptTokens     int     `json:"prompt_tokens,omitempty"`
+}
+
+// Link is the Link schema of the API
+type Link struct {
+	Href   string `json:"href,omitempty"`
+	Method string `json:"method,omitempty"`
+	Title  string `json:"title,omitempty"`
+	Type   string `json:"type,omitempty"`
+}
+
+// LogEntry is the LogEntry schema of the API
+type LogEntry struct {
+	Container string    `json:"container,omitempty"`
+	Line      string    `json:"line,omitempty"`
+	Timestamp time.Time `json:"timestamp,omitempty"`
+}
+
+// MergeIncidentsRequest is the MergeIncidentsRequest schema of the API
+type MergeIncidentsRequest struct {
+	IncidentIDs []int64 `json:"incident_ids"`
+}
+
+// MetricPoint is the MetricPoint schema of the API
+type MetricPoint struct {
+	Timestamp time.Time `json:"timestamp,omitempty"`
+	Value     float64   `json:"value,omitempty"`
+}
+
+// MetricSeries is the MetricSeries schema of the API
+type MetricSeries struct {
+	Labels map[string]string `json:"labels,omitempty"`
+	Points []MetricPoint     `json:"points,omitempty"`
+}
+
+// NamespaceHealthRequest is the NamespaceHealthRequest schema of the API
+type NamespaceHealthRequest struct {
+	Lookback  string `json:"lookback,omitempty"`
+	Namespace string `json:"namespace"`
+}
+
+// OOMContext is the OOMContext schema of the API
+type OOMContext struct {
+	Classification       string       `json:"classification,omitempty"`
+	Container            string       `json:"container,omitempty"`
+	Evicted              bool         `json:"evicted,omitempty"`
+	KilledAt             time.Time    `json:"killed_at,omitempty"`
+	MemoryLimit          string       `json:"memory_limit,omitempty"`
+	MemoryRequest        string       `json:"memory_request,omitempty"`
+	Message              string       `json:"message,omitempty"`
+	NodeAllocatableBytes int64        `json:"node_allocatable_bytes,omitempty"`
+	NodeAvailableBytes   int64        `json:"node_available_bytes,omitempty"`
+	NodeEvents           []EventEntry `json:"node_events,omitempty"`
+	NodeMemoryPressure   bool         `json:"node_memory_pressure,omitempty"`
+	NodeName             string       `json:"node_name,omitempty"`
+	NodeWorkingSetBytes  int64        `json:"node_working_set_bytes,omitempty"`
+	SummaryError         string       `json:"summary_error,omitempty"`
+}
+
+// OpsgenieAlert is the OpsgenieAlert schema of the API
+type OpsgenieAlert struct {
+	AlertID   string            `json:"alertId,omitempty"`
+	Alias     string            `json:"alias,omitempty"`
+	CreatedAt int64             `json:"createdAt,omitempty"`
+	Details   map[string]string `json:"details,omitempty"`
+	Entity    string            `json:"entity,omitempty"`
+	Message   string            `json:"message,omitempty"`
+	Priority  string            `json:"priority,omitempty"`
+	Tags      []string          `json:"tags,omitempty"`
+	TinyID    string            `json:"tinyId,omitempty"`
+}
+
+// OpsgenieWebhook is the OpsgenieWebhook schema of the API
+type OpsgenieWebhook struct {
+	Action          string         `json:"action,omitempty"`
+	Alert           *OpsgenieAlert `json:"alert,omitempty"`
+	IntegrationName string         `json:"integrationName,omitempty"`
+}
+
+// PagerdutyEvent is the PagerdutyEvent schema of the API
+type PagerdutyEvent struct {
+	Data         *PagerdutyIncident `json:"data,omitempty"`
+	EventType    string             `json:"event_type,omitempty"`
+	ID           string             `json:"id,omitempty"`
+	OccurredAt   time.Time          `json:"occurred_at,omitempty"`
+	ResourceType string             `json:"resource_type,omitempty"`
+}
+
+// PagerdutyIncident is the PagerdutyIncident schema of the API
+type PagerdutyIncident struct {
+	Body struct {
+		Details any `json:"details,omitempty"`
+	} `json:"body,omitempty"`
+	CustomDetails any    `json:"custom_details,omitempty"`
+	HTMLURL       string `json:"html_url,omitempty"`
+	ID            string `json:"id,omitempty"`
+	Service       struct {
+		ID      string `json:"id,omitempty"`
+		Summary string `json:"summary,omitempty"`
+	} `json:"service,omitempty"`
+	Status  string `json:"status,omitempty"`
+	Title   string `json:"title,omitempty"`
+	Type    string `json:"type,omitempty"`
+	Urgency string `json:"urgency,omitempty"`
+}
+
+// PagerdutyWebhook is the PagerdutyWebhook schema of the API
+type PagerdutyWebhook struct {
+	Event *PagerdutyEvent `json:"event,omitempty"`
+}
+
+// PodHealth is the PodHealth schema of the API
+type PodHealth struct {
+	Analyzed bool   `json:"analyzed,omitempty"`
+	Name     string `json:"name,omitempty"`
+	Node     string `json:"node,omitempty"`
+	Phase    string `json:"phase,omitempty"`
+	Ready    bool   `json:"ready,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+	Restarts int    `json:"restarts,omitempty"`
+}
+
+// ProbeReport is the ProbeReport schema of the API
+type ProbeReport struct {
+	Container string        `json:"container,omitempty"`
+	Image     string        `json:"image,omitempty"`
+	Results   []ProbeResult `json:"results,omitempty"`
+}
+
+// ProbeResult is the ProbeResult schema of the API
+type ProbeResult struct {
+	Detail string `json:"detail,omitempty"`
+	Kind   string `json:"kind,omitempty"`
+	Ok     bool   `json:"ok,omitempty"`
+	Target string `json:"target,omitempty"`
+}
+
+// RatingRequest is the RatingRequest schema of the API
+type RatingRequest struct {
+	Comment            string `json:"comment,omitempty"`
+	CorrectedRootCause string `json:"corrected_root_cause,omitempty"`
+	// Rating is one of up, down
+	Rating  string `json:"rating"`
+	Version int    `json:"version,omitempty"`
+}
+
+// Recommendation is the Recommendation schema of the API
+type Recommendation struct {
+	Action       string        `json:"action,omitempty"`
+	Check        *CommandCheck `json:"check,omitempty"`
+	Command      string        `json:"command,omitempty"`
+	Details      string        `json:"details,omitempty"`
+	EvidenceRefs []EvidenceRef `json:"evidence_refs,omitempty"`
+	Priority     string        `json:"priority,omitempty"`
+}
+
+// Recurrence is the Recurrence schema of the API
+type Recurrence struct {
+	Count          int       `json:"count,omitempty"`
+	LastAnalysisID int64     `json:"last_analysis_id,omitempty"`
+	LastRootCause  string    `json:"last_root_cause,omitempty"`
+	LastSeen       time.Time `json:"last_seen,omitempty"`
+	Recurring      bool      `json:"recurring,omitempty"`
+	Since          time.Time `json:"since,omitempty"`
+}
+
+// Redaction is the Redaction schema of the API
+type Redaction struct {
+	Count    int    `json:"count,omitempty"`
+	Detector string `json:"detector,omitempty"`
+}
+
+// RelatedAlert is the RelatedAlert schema of the API
+type RelatedAlert struct {
+	AlertName   string    `json:"alert_name,omitempty"`
+	Fingerprint string    `json:"fingerprint,omitempty"`
+	Namespace   string    `json:"namespace,omitempty"`
+	Pod         string    `json:"pod,omitempty"`
+	Severity    string    `json:"severity,omitempty"`
+	StartsAt    time.Time `json:"starts_at,omitempty"`
+	Summary     string    `json:"summary,omitempty"`
+}
+
+// RemediationDecision is the RemediationDecision schema of the API
+type RemediationDecision struct {
+	Reason string `json:"reason,omitempty"`
+}
+
+// RerunRequest is the RerunRequest schema of the API
+type RerunRequest struct {
+	Lookback string `json:"lookback,omitempty"`
+	Model    string `json:"model,omitempty"`
+}
+
+// RolloutInfo is the RolloutInfo schema of the API
+type RolloutInfo struct {
+	Aborted          bool     `json:"aborted,omitempty"`
+	CanaryWeight     int      `json:"canary_weight,omitempty"`
+	CurrentStep      int      `json:"current_step,omitempty"`
+	FailedChecks     int      `json:"failed_checks,omitempty"`
+	Kind             string   `json:"kind,omitempty"`
+	Message          string   `json:"message,omitempty"`
+	Name             string   `json:"name,omitempty"`
+	Namespace        string   `json:"namespace,omitempty"`
+	Phase            string   `json:"phase,omitempty"`
+	Primary          bool     `json:"primary,omitempty"`
+	RollbackCommands []string `json:"rollback_commands,omitempty"`
+	TotalSteps       int      `json:"total_steps,omitempty"`
+}
+
+// RuleMatch is the RuleMatch schema of the API
+type RuleMatch struct {
+	Answered bool   `json:"answered,omitempty"`
+	Cause    string `json:"cause,omitempty"`
+	Rule     string `json:"rule,omitempty"`
+}
+
+// Silence is the Silence schema of the API
+type Silence struct {
+	Comment   string    `json:"comment,omitempty"`
+	CreatedBy string    `json:"created_by,omitempty"`
+	EndsAt    time.Time `json:"ends_at,omitempty"`
+	ID        string    `json:"id,omitempty"`
+	StartsAt  time.Time `json:"starts_at,omitempty"`
+	State     string    `json:"state,omitempty"`
+}
+
+// SkippedAlert is the SkippedAlert schema of the API
+type SkippedAlert struct {
+	AlertName   string `json:"alert_name,omitempty"`
+	Fingerprint string `json:"fingerprint,omitempty"`
+	Reason      string `json:"reason,omitempty"`
+}
+
+// Stage is the Stage schema of the API
+type Stage struct {
+	Duration   string `json:"duration,omitempty"`
+	DurationMs int64  `json:"duration_ms,omitempty"`
+	Name       string `json:"name,omitempty"`
+}
+
+// TagsRequest is the TagsRequest schema of the API
+type TagsRequest struct {
+	Tags []string `json:"tags"`
+}
+
+// TagsResponse is the TagsResponse schema of the API
+type TagsResponse struct {
+	Links      map[string]Link `json:"_links,omitempty"`
+	AnalysisID int64           `json:"analysis_id,omitempty"`
+	Tags       []string        `json:"tags,omitempty"`
+}
+
+// TimelineEvent is the TimelineEvent schema of the API
+type TimelineEvent struct {
+	Details   string    `json:"details,omitempty"`
+	Event     string    `json:"event,omitempty"`
+	Timestamp time.Time `json:"timestamp,omitempty"`
+}
+
+// WarningEvent is the WarningEvent schema of the API
+type WarningEvent struct {
+	Count    int       `json:"count,omitempty"`
+	LastSeen time.Time `json:"last_seen,omitempty"`
+	Message  string    `json:"message,omitempty"`
+	Object   string    `json:"object,omitempty"`
+	Reason   string    `json:"reason,omitempty"`
+}
+
+// WebhookAnalysisResponse is the WebhookAnalysisResponse schema of the API
+type WebhookAnalysisResponse struct {
+	Analyzed  int                   `json:"analyzed,omitempty"`
+	Errors    []AlertAnalysisError  `json:"errors,omitempty"`
+	Failed    int                   `json:"failed,omitempty"`
+	Incidents []IncidentResult      `json:"incidents,omitempty"`
+	Received  int                   `json:"received,omitempty"`
+	Results   []AlertAnalysisResult `json:"results,omitempty"`
+	Skipped   []SkippedAlert        `json:"skipped,omitempty"`
+}
+
+// WorkloadCondition is the WorkloadCondition schema of the API
+type WorkloadCondition struct {
+	Message string `json:"message,omitempty"`
+	Reason  string `json:"reason,omitempty"`
+	Status  string `json:"status,omitempty"`
+	Type    string `json:"type,omitempty"`
+}
+
+// WorkloadHealth is the WorkloadHealth schema of the API
+type WorkloadHealth struct {
+	Desired        int            `json:"desired,omitempty"`
+	Finding        string         `json:"finding,omitempty"`
+	Issues         []string       `json:"issues,omitempty"`
+	Kind           string         `json:"kind,omitempty"`
+	Name           string         `json:"name,omitempty"`
+	Pods           []PodHealth    `json:"pods,omitempty"`
+	Ready          int            `json:"ready,omitempty"`
+	Recommendation string         `json:"recommendation,omitempty"`
+	Restarts       int            `json:"restarts,omitempty"`
+	Severity       string         `json:"severity,omitempty"`
+	Warnings       []WarningEvent `json:"warnings,omitempty"`
+}
+
+// WorkloadStatus is the WorkloadStatus schema of the API
+type WorkloadStatus struct {
+	Available  int                 `json:"available,omitempty"`
+	Conditions []WorkloadCondition `json:"conditions,omitempty"`
+	Desired    int                 `json:"desired,omitempty"`
+	Kind       string              `json:"kind,omitempty"`
+	Name       string              `json:"name,omitempty"`
+	Namespace  string              `json:"namespace,omitempty"`
+	Pods       []PodHealth         `json:"pods,omitempty"`
+	Ready      int                 `json:"ready,omitempty"`
+	Updated    int                 `json:"updated,omitempty"`
+}
+
+// GetAdminAnalysesDeleted calls GET /api/v1/admin/analyses/deleted: List the deleted analyses that can be restored (admin)
+func (c *Client) GetAdminAnalysesDeleted(ctx context.Context, params GetAdminAnalysesDeletedParams) (json.RawMessage, error) {
+	query := params.values()
+	var out []byte
+	if err := c.do(ctx, http.MethodGet, "/api/v1/admin/analyses/deleted", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PostAdminAnalysesRestore calls POST /api/v1/admin/analyses/restore: Restore the analyses deleted since a time (admin)
+func (c *Client) PostAdminAnalysesRestore(ctx context.Context, params PostAdminAnalysesRestoreParams) (json.RawMessage, error) {
+	query := params.values()
+	var out []byte
+	if err := c.do(ctx, http.MethodPost, "/api/v1/admin/analyses/restore", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PostAdminAnalysesIDRestore calls POST /api/v1/admin/analyses/{id}/restore: Restore a deleted analysis (admin)
+func (c *Client) PostAdminAnalysesIDRestore(ctx context.Context, id int64) (*AnalysisSummary, error) {
+	var out AnalysisSummary
+	if err := c.do(ctx, http.MethodPost, "/api/v1/admin/analyses/"+strconv.FormatInt(id, 10)+"/restore", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetAdminAudit calls GET /api/v1/admin/audit: List who analyzed, re-ran, deleted or restored analyses, reloaded the configuration or decided remediations (admin)
+func (c *Client) GetAdminAudit(ctx context.Context, params GetAdminAuditParams) (json.RawMessage, error) {
+	query := params.values()
+	var out []byte
+	if err := c.do(ctx, http.MethodGet, "/api/v1/admin/audit", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetAdminBackup calls GET /api/v1/admin/backup: Stream a backup of the database as JSONL or SQL (admin)
+func (c *Client) GetAdminBackup(ctx context.Context, params GetAdminBackupParams) (json.RawMessage, error) {
+	query := params.values()
+	var out []byte
+	if err := c.do(ctx, http.MethodGet, "/api/v1/admin/backup", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// DeleteAnalyses calls DELETE /api/v1/analyses: Delete the analyses matching the filters (admin)
+func (c *Client) DeleteAnalyses(ctx context.Context, params DeleteAnalysesParams) (json.RawMessage, error) {
+	query := params.values()
+	var out []byte
+	if err := c.do(ctx, http.MethodDelete, "/api/v1/analyses", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetAnalyses calls GET /api/v1/analyses: Search stored analyses
+func (c *Client) GetAnalyses(ctx context.Context, params GetAnalysesParams) (*GetAnalysesResponse, error) {
+	query := params.values()
+	var out GetAnalysesResponse
+	if err := c.do(ctx, http.MethodGet, "/api/v1/analyses", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteAnalysesID calls DELETE /api/v1/analyses/{id}: Delete a stored analysis (admin)
+func (c *Client) DeleteAnalysesID(ctx context.Context, id int64) (json.RawMessage, error) {
+	var out []byte
+	if err := c.do(ctx, http.MethodDelete, "/api/v1/analyses/"+strconv.FormatInt(id, 10), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetAnalysesID calls GET /api/v1/analyses/{id}: Get a stored analysis
+func (c *Client) GetAnalysesID(ctx context.Context, id int64) (*AnalysisResponse, error) {
+	var out AnalysisResponse
+	if err := c.do(ctx, http.MethodGet, "/api/v1/analyses/"+strconv.FormatInt(id, 10), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetAnalysesIDExport calls GET /api/v1/analyses/{id}/export: Export an analysis as markdown, CSV or PDF
+func (c *Client) GetAnalysesIDExport(ctx context.Context, id int64, params GetAnalysesIDExportParams) (json.RawMessage, error) {
+	query := params.values()
+	var out []byte
+	if err := c.do(ctx, http.MethodGet, "/api/v1/analyses/"+strconv.FormatInt(id, 10)+"/export", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PostAnalysesIDFeedback calls POST /api/v1/analyses/{id}/feedback: Correct the root cause of an analysis
+func (c *Client) PostAnalysesIDFeedback(ctx context.Context, id int64, body FeedbackRequest, params PostAnalysesIDFeedbackParams) (*CorrectionResponse, error) {
+	query := params.values()
+	var out CorrectionResponse
+	if err := c.do(ctx, http.MethodPost, "/api/v1/analyses/"+strconv.FormatInt(id, 10)+"/feedback", query, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// PostAnalysesIDRating calls POST /api/v1/analyses/{id}/rating: Rate the root cause of an analysis
+func (c *Client) PostAnalysesIDRating(ctx context.Context, id int64, body RatingRequest) (*FeedbackRecord, error) {
+	var out FeedbackRecord
+	if err := c.do(ctx, http.MethodPost, "/api/v1/analyses/"+strconv.FormatInt(id, 10)+"/rating", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// PostAnalysesIDRerun calls POST /api/v1/analyses/{id}/rerun: Analyze the target of an analysis again
+func (c *Client) PostAnalysesIDRerun(ctx context.Context, id int64, body RerunRequest, params PostAnalysesIDRerunParams) (*CorrectionResponse, error) {
+	query := params.values()
+	var out CorrectionResponse
+	if err := c.do(ctx, http.MethodPost, "/api/v1/analyses/"+strconv.FormatInt(id, 10)+"/rerun", query, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetAnalysesIDSimilar calls GET /api/v1/analyses/{id}/similar: List analyses similar to one
+func (c *Client) GetAnalysesIDSimilar(ctx context.Context, id int64) (json.RawMessage, error) {
+	var out []byte
+	if err := c.do(ctx, http.MethodGet, "/api/v1/analyses/"+strconv.FormatInt(id, 10)+"/similar", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PostAnalysesIDTags calls POST /api/v1/analyses/{id}/tags: Tag an analysis
+func (c *Client) PostAnalysesIDTags(ctx context.Context, id int64, body TagsRequest) (*TagsResponse, error) {
+	var out TagsResponse
+	if err := c.do(ctx, http.MethodPost, "/api/v1/analyses/"+strconv.FormatInt(id, 10)+"/tags", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteAnalysesIDTagsTag calls DELETE /api/v1/analyses/{id}/tags/{tag}: Remove a tag from an analysis
+func (c *Client) DeleteAnalysesIDTagsTag(ctx context.Context, id int64, tag string) (*TagsResponse, error) {
+	var out TagsResponse
+	if err := c.do(ctx, http.MethodDelete, "/api/v1/analyses/"+strconv.FormatInt(id, 10)+"/tags/"+url.PathEscape(tag), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetAnalysesIDVersions calls GET /api/v1/analyses/{id}/versions: List the versions of an analysis
+func (c *Client) GetAnalysesIDVersions(ctx context.Context, id int64) (json.RawMessage, error) {
+	var out []byte
+	if err := c.do(ctx, http.MethodGet, "/api/v1/analyses/"+strconv.FormatInt(id, 10)+"/versions", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PostAnalyzeAlert calls POST /api/v1/analyze/alert: Analyze an alert on a pod
+func (c *Client) PostAnalyzeAlert(ctx context.Context, body AnalyzeAlertRequest, params PostAnalyzeAlertParams) (*AnalysisResponse, error) {
+	query := params.values()
+	var out AnalysisResponse
+	if err := c.do(ctx, http.MethodPost, "/api/v1/analyze/alert", query, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// PostAnalyzeDeployment calls POST /api/v1/analyze/deployment: Analyze a Deployment or StatefulSet
+func (c *Client) PostAnalyzeDeployment(ctx context.Context, body AnalyzeDeploymentRequest, params PostAnalyzeDeploymentParams) (*AnalysisResponse, error) {
+	query := params.values()
+	var out AnalysisResponse
+	if err := c.do(ctx, http.MethodPost, "/api/v1/analyze/deployment", query, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// PostAnalyzeJobs calls POST /api/v1/analyze/jobs: Start a pod analysis in the background
+func (c *Client) PostAnalyzeJobs(ctx context.Context, body AnalyzePodRequest) (*PostAnalyzeJobsResponse, error) {
+	var out PostAnalyzeJobsResponse
+	if err := c.do(ctx, http.MethodPost, "/api/v1/analyze/jobs", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// PostAnalyzeNamespace calls POST /api/v1/analyze/namespace: Report the health of a namespace
+func (c *Client) PostAnalyzeNamespace(ctx context.Context, body NamespaceHealthRequest, params PostAnalyzeNamespaceParams) (*HealthReport, error) {
+	query := params.values()
+	var out HealthReport
+	if err := c.do(ctx, http.MethodPost, "/api/v1/analyze/namespace", query, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// PostAnalyzePod calls POST /api/v1/analyze/pod: Analyze a pod
+func (c *Client) PostAnalyzePod(ctx context.Context, body AnalyzePodRequest, params PostAnalyzePodParams) (*AnalysisResponse, error) {
+	query := params.values()
+	var out AnalysisResponse
+	if err := c.do(ctx, http.MethodPost, "/api/v1/analyze/pod", query, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetFeedback calls GET /api/v1/feedback: List recent ratings and corrections
+func (c *Client) GetFeedback(ctx context.Context, params GetFeedbackParams) (json.RawMessage, error) {
+	query := params.values()
+	var out []byte
+	if err := c.do(ctx, http.MethodGet, "/api/v1/feedback", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetIncidents calls GET /api/v1/incidents: List incidents
+func (c *Client) GetIncidents(ctx context.Context, params GetIncidentsParams) (*GetIncidentsResponse, error) {
+	query := params.values()
+	var out GetIncidentsResponse
+	if err := c.do(ctx, http.MethodGet, "/api/v1/incidents", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// PostIncidents calls POST /api/v1/incidents: Group analyses into an incident
+func (c *Client) PostIncidents(ctx context.Context, body IncidentRequest) (*IncidentResponse, error) {
+	var out IncidentResponse
+	if err := c.do(ctx, http.MethodPost, "/api/v1/incidents", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteIncidentsID calls DELETE /api/v1/incidents/{id}: Delete an incident, ungrouping its analyses (admin)
+func (c *Client) DeleteIncidentsID(ctx context.Context, id int64) (json.RawMessage, error) {
+	var out []byte
+	if err := c.do(ctx, http.MethodDelete, "/api/v1/incidents/"+strconv.FormatInt(id, 10), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetIncidentsID calls GET /api/v1/incidents/{id}: Get an incident with its analyses
+func (c *Client) GetIncidentsID(ctx context.Context, id int64) (*IncidentResponse, error) {
+	var out IncidentResponse
+	if err := c.do(ctx, http.MethodGet, "/api/v1/incidents/"+strconv.FormatInt(id, 10), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// PatchIncidentsID calls PATCH /api/v1/incidents/{id}: Rename, resolve or reopen an incident
+func (c *Client) PatchIncidentsID(ctx context.Context, id int64, body IncidentUpdate) (*IncidentResponse, error) {
+	var out IncidentResponse
+	if err := c.do(ctx, http.MethodPatch, "/api/v1/incidents/"+strconv.FormatInt(id, 10), nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// PostIncidentsIDAnalyses calls POST /api/v1/incidents/{id}/analyses: Add analyses to an incident
+func (c *Client) PostIncidentsIDAnalyses(ctx context.Context, id int64, body IncidentAnalysesRequest) (*IncidentResponse, error) {
+	var out IncidentResponse
+	if err := c.do(ctx, http.MethodPost, "/api/v1/incidents/"+strconv.FormatInt(id, 10)+"/analyses", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteIncidentsIDAnalysesAnalysisID calls DELETE /api/v1/incidents/{id}/analyses/{analysis_id}: Remove an analysis from an incident
+func (c *Client) DeleteIncidentsIDAnalysesAnalysisID(ctx context.Context, id int64, analysisID int64) (*IncidentResponse, error) {
+	var out IncidentResponse
+	if err := c.do(ctx, http.MethodDelete, "/api/v1/incidents/"+strconv.FormatInt(id, 10)+"/analyses/"+strconv.FormatInt(analysisID, 10), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// PostIncidentsIDMerge calls POST /api/v1/incidents/{id}/merge: Merge other incidents into an incident
+func (c *Client) PostIncidentsIDMerge(ctx context.Context, id int64, body MergeIncidentsRequest) (*IncidentResponse, error) {
+	var out IncidentResponse
+	if err := c.do(ctx, http.MethodPost, "/api/v1/incidents/"+strconv.FormatInt(id, 10)+"/merge", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetJobsJob calls GET /api/v1/jobs/{job}: Get an analysis job
+func (c *Client) GetJobsJob(ctx context.Context, job string) (*GetJobsJobResponse, error) {
+	var out GetJobsJobResponse
+	if err := c.do(ctx, http.MethodGet, "/api/v1/jobs/"+url.PathEscape(job), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetK8sNamespaces calls GET /api/v1/k8s/namespaces: List namespaces
+func (c *Client) GetK8sNamespaces(ctx context.Context, params GetK8sNamespacesParams) (json.RawMessage, error) {
+	query := params.values()
+	var out []byte
+	if err := c.do(ctx, http.MethodGet, "/api/v1/k8s/namespaces", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetK8sPods calls GET /api/v1/k8s/pods: List the pods of a namespace
+func (c *Client) GetK8sPods(ctx context.Context, params GetK8sPodsParams) (json.RawMessage, error) {
+	query := params.values()
+	var out []byte
+	if err := c.do(ctx, http.MethodGet, "/api/v1/k8s/pods", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetRemediations calls GET /api/v1/remediations: List remediation proposals
+func (c *Client) GetRemediations(ctx context.Context, params GetRemediationsParams) (json.RawMessage, error) {
+	query := params.values()
+	var out []byte
+	if err := c.do(ctx, http.MethodGet, "/api/v1/remediations", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetRemediationsID calls GET /api/v1/remediations/{id}: Get a remediation and its audit trail
+func (c *Client) GetRemediationsID(ctx context.Context, id int64) (json.RawMessage, error) {
+	var out []byte
+	if err := c.do(ctx, http.MethodGet, "/api/v1/remediations/"+strconv.FormatInt(id, 10), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PostRemediationsIDApprove calls POST /api/v1/remediations/{id}/approve: Approve and run a remediation (admin)
+func (c *Client) PostRemediationsIDApprove(ctx context.Context, id int64, body RemediationDecision) (json.RawMessage, error) {
+	var out []byte
+	if err := c.do(ctx, http.MethodPost, "/api/v1/remediations/"+strconv.FormatInt(id, 10)+"/approve", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PostRemediationsIDReject calls POST /api/v1/remediations/{id}/reject: Reject a remediation (admin)
+func (c *Client) PostRemediationsIDReject(ctx context.Context, id int64, body RemediationDecision) (json.RawMessage, error) {
+	var out []byte
+	if err := c.do(ctx, http.MethodPost, "/api/v1/remediations/"+strconv.FormatInt(id, 10)+"/reject", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetReportsPostmortem calls GET /api/v1/reports/postmortem: Daily or weekly postmortem digest
+func (c *Client) GetReportsPostmortem(ctx context.Context, params GetReportsPostmortemParams) (json.RawMessage, error) {
+	query := params.values()
+	var out []byte
+	if err := c.do(ctx, http.MethodGet, "/api/v1/reports/postmortem", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetStatsAnalyses calls GET /api/v1/stats/analyses: Daily analysis counts
+func (c *Client) GetStatsAnalyses(ctx context.Context, params GetStatsAnalysesParams) (json.RawMessage, error) {
+	query := params.values()
+	var out []byte
+	if err := c.do(ctx, http.MethodGet, "/api/v1/stats/analyses", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetStatsBudget calls GET /api/v1/stats/budget: Today's LLM token spend
+func (c *Client) GetStatsBudget(ctx context.Context) (json.RawMessage, error) {
+	var out []byte
+	if err := c.do(ctx, http.MethodGet, "/api/v1/stats/budget", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetStatsCollectors calls GET /api/v1/stats/collectors: Count collector failures by error class
+func (c *Client) GetStatsCollectors(ctx context.Context) (json.RawMessage, error) {
+	var out []byte
+	if err := c.do(ctx, http.MethodGet, "/api/v1/stats/collectors", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetStatsFeedback calls GET /api/v1/stats/feedback: Root-cause accuracy from ratings
+func (c *Client) GetStatsFeedback(ctx context.Context, params GetStatsFeedbackParams) (json.RawMessage, error) {
+	query := params.values()
+	var out []byte
+	if err := c.do(ctx, http.MethodGet, "/api/v1/stats/feedback", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetTags calls GET /api/v1/tags: List the tags in use with their analysis counts
+func (c *Client) GetTags(ctx context.Context) (json.RawMessage, error) {
+	var out []byte
+	if err := c.do(ctx, http.MethodGet, "/api/v1/tags", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PostWebhookAlertmanager calls POST /api/v1/webhook/alertmanager: Analyze the alerts of an AlertManager webhook
+func (c *Client) PostWebhookAlertmanager(ctx context.Context, body AlertManagerWebhook, params PostWebhookAlertmanagerParams) (*WebhookAnalysisResponse, error) {
+	query := params.values()
+	var out WebhookAnalysisResponse
+	if err := c.do(ctx, http.MethodPost, "/api/v1/webhook/alertmanager", query, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// PostWebhookGeneric calls POST /api/v1/webhook/generic: Analyze the alerts of a JSON payload mapped by generic_webhooks
+func (c *Client) PostWebhookGeneric(ctx context.Context, body map[string]any, params PostWebhookGenericParams) (*WebhookAnalysisResponse, error) {
+	query := params.values()
+	var out WebhookAnalysisResponse
+	if err := c.do(ctx, http.MethodPost, "/api/v1/webhook/generic", query, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// PostWebhookOpsgenie calls POST /api/v1/webhook/opsgenie: Analyze the alert of an Opsgenie webhook Create action
+func (c *Client) PostWebhookOpsgenie(ctx context.Context, body OpsgenieWebhook, params PostWebhookOpsgenieParams) (*WebhookAnalysisResponse, error) {
+	query := params.values()
+	var out WebhookAnalysisResponse
+	if err := c.do(ctx, http.MethodPost, "/api/v1/webhook/opsgenie", query, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// PostWebhookPagerduty calls POST /api/v1/webhook/pagerduty: Analyze the incident of a PagerDuty V3 incident.triggered webhook
+func (c *Client) PostWebhookPagerduty(ctx context.Context, body PagerdutyWebhook, params PostWebhookPagerdutyParams) (*WebhookAnalysisResponse, error) {
+	query := params.values()
+	var out WebhookAnalysisResponse
+	if err := c.do(ctx, http.MethodPost, "/api/v1/webhook/pagerduty", query, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetLivez calls GET /livez: Liveness probe
+func (c *Client) GetLivez(ctx context.Context) (json.RawMessage, error) {
+	var out []byte
+	if err := c.do(ctx, http.MethodGet, "/livez", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetReadyz calls GET /readyz: Readiness probe checking the API server, database and LLM
+func (c *Client) GetReadyz(ctx context.Context) (json.RawMessage, error) {
+	var out []byte
+	if err := c.do(ctx, http.MethodGet, "/readyz", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetVersion calls GET /version: Server version and read-only mode
+func (c *Client) GetVersion(ctx context.Context) (json.RawMessage, error) {
+	var out []byte
+	if err := c.do(ctx, http.MethodGet, "/version", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetAdminAnalysesDeletedParams are the query parameters of GetAdminAnalysesDeleted
+type GetAdminAnalysesDeletedParams struct {
+	Cluster      string
+	Namespace    string
+	Pod          string
+	Alert        string
+	Severity     string
+	Confidence   string
+	Status       string
+	Q            string
+	Tag          string
+	Incident     string
+	Since        string
+	Until        string
+	DeletedSince string
+	Limit        string
+}
+
+func (p GetAdminAnalysesDeletedParams) values() url.Values {
+	q := url.Values{}
+	if p.Cluster != "" {
+		q.Set("cluster", p.Cluster)
+	}
+	if p.Namespace != "" {
+		q.Set("namespace", p.Namespace)
+	}
+	if p.Pod != "" {
+		q.Set("pod", p.Pod)
+	}
+	if p.Alert != "" {
+		q.Set("alert", p.Alert)
+	}
+	if p.Severity != "" {
+		q.Set("severity", p.Severity)
+	}
+	if p.Confidence != "" {
+		q.Set("confidence", p.Confidence)
+	}
+	if p.Status != "" {
+		q.Set("status", p.Status)
+	}
+	if p.Q != "" {
+		q.Set("q", p.Q)
+	}
+	if p.Tag != "" {
+		q.Set("tag", p.Tag)
+	}
+	if p.Incident != "" {
+		q.Set("incident", p.Incident)
+	}
+	if p.Since != "" {
+		q.Set("since", p.Since)
+	}
+	if p.Until != "" {
+		q.Set("until", p.Until)
+	}
+	if p.DeletedSince != "" {
+		q.Set("deleted_since", p.DeletedSince)
+	}
+	if p.Limit != "" {
+		q.Set("limit", p.Limit)
+	}
+	return q
+}
+
+// PostAdminAnalysesRestoreParams are the query parameters of PostAdminAnalysesRestore
+type PostAdminAnalysesRestoreParams struct {
+	Cluster      string
+	Namespace    string
+	Pod          string
+	Alert        string
+	Severity     string
+	Confidence   string
+	Status       string
+	Q            string
+	Tag          string
+	Incident     string
+	Since        string
+	Until        string
+	DeletedSince string
+	DryRun       string
+}
+
+func (p PostAdminAnalysesRestoreParams) values() url.Values {
+	q := url.Values{}
+	if p.Cluster != "" {
+		q.Set("cluster", p.Cluster)
+	}
+	if p.Namespace != "" {
+		q.Set("namespace", p.Namespace)
+	}
+	if p.Pod != "" {
+		q.Set("pod", p.Pod)
+	}
+	if p.Alert != "" {
+		q.Set("alert", p.Alert)
+	}
+	if p.Severity != "" {
+		q.Set("severity", p.Severity)
+	}
+	if p.Confidence != "" {
+		q.Set("confidence", p.Confidence)
+	}
+	if p.Status != "" {
+		q.Set("status", p.Status)
+	}
+	if p.Q != "" {
+		q.Set("q", p.Q)
+	}
+	if p.Tag != "" {
+		q.Set("tag", p.Tag)
+	}
+	if p.Incident != "" {
+		q.Set("incident", p.Incident)
+	}
+	if p.Since != "" {
+		q.Set("since", p.Since)
+	}
+	if p.Until != "" {
+		q.Set("until", p.Until)
+	}
+	if p.DeletedSince != "" {
+		q.Set("deleted_since", p.DeletedSince)
+	}
+	if p.DryRun != "" {
+		q.Set("dry_run", p.DryRun)
+	}
+	return q
+}
+
+// GetAdminAuditParams are the query parameters of GetAdminAudit
+type GetAdminAuditParams struct {
+	Actor    string
+	Action   string
+	Since    string
+	Until    string
+	Limit    string
+	BeforeID string
+}
+
+func (p GetAdminAuditParams) values() url.Values {
+	q := url.Values{}
+	if p.Actor != "" {
+		q.Set("actor", p.Actor)
+	}
+	if p.Action != "" {
+		q.Set("action", p.Action)
+	}
+	if p.Since != "" {
+		q.Set("since", p.Since)
+	}
+	if p.Until != "" {
+		q.Set("until", p.Until)
+	}
+	if p.Limit != "" {
+		q.Set("limit", p.Limit)
+	}
+	if p.BeforeID != "" {
+		q.Set("before_id", p.BeforeID)
+	}
+	return q
+}
+
+// GetAdminBackupParams are the query parameters of GetAdminBackup
+type GetAdminBackupParams struct {
+	Format string
+}
+
+func (p GetAdminBackupParams) values() url.Values {
+	q := url.Values{}
+	if p.Format != "" {
+		q.Set("format", p.Format)
+	}
+	return q
+}
+
+// DeleteAnalysesParams are the query parameters of DeleteAnalyses
+type DeleteAnalysesParams struct {
+	Cluster    string
+	Namespace  string
+	Pod        string
+	Alert      string
+	Severity   string
+	Confidence string
+	Status     string
+	Q          string
+	Tag        string
+	Incident   string
+	Since      string
+	Until      string
+}
+
+func (p DeleteAnalysesParams) values() url.Values {
+	q := url.Values{}
+	if p.Cluster != "" {
+		q.Set("cluster", p.Cluster)
+	}
+	if p.Namespace != "" {
+		q.Set("namespace", p.Namespace)
+	}
+	if p.Pod != "" {
+		q.Set("pod", p.Pod)
+	}
+	if p.Alert != "" {
+		q.Set("alert", p.Alert)
+	}
+	if p.Severity != "" {
+		q.Set("severity", p.Severity)
+	}
+	if p.Confidence != "" {
+		q.Set("confidence", p.Confidence)
+	}
+	if p.Status != "" {
+		q.Set("status", p.Status)
+	}
+	if p.Q != "" {
+		q.Set("q", p.Q)
+	}
+	if p.Tag != "" {
+		q.Set("tag", p.Tag)
+	}
+	if p.Incident != "" {
+		q.Set("incident", p.Incident)
+	}
+	if p.Since != "" {
+		q.Set("since", p.Since)
+	}
+	if p.Until != "" {
+		q.Set("until", p.Until)
+	}
+	return q
+}
+
+// GetAnalysesParams are the query parameters of GetAnalyses
+type GetAnalysesParams struct {
+	Cluster    string
+	Namespace  string
+	Pod        string
+	Alert      string
+	Severity   string
+	Confidence string
+	Status     string
+	Q          string
+	Tag        string
+	Incident   string
+	Since      string
+	Until      string
+	Sort       string
+	Page       string
+	PerPage    string
+}
+
+func (p GetAnalysesParams) values() url.Values {
+	q := url.Values{}
+	if p.Cluster != "" {
+		q.Set("cluster", p.Cluster)
+	}
+	if p.Namespace != "" {
+		q.Set("namespace", p.Namespace)
+	}
+	if p.Pod != "" {
+		q.Set("pod", p.Pod)
+	}
+	if p.Alert != "" {
+		q.Set("alert", p.Alert)
+	}
+	if p.Severity != "" {
+		q.Set("severity", p.Severity)
+	}
+	if p.Confidence != "" {
+		q.Set("confidence", p.Confidence)
+	}
+	if p.Status != "" {
+		q.Set("status", p.Status)
+	}
+	if p.Q != "" {
+		q.Set("q", p.Q)
+	}
+	if p.Tag != "" {
+		q.Set("tag", p.Tag)
+	}
+	if p.Incident != "" {
+		q.Set("incident", p.Incident)
+	}
+	if p.Since != "" {
+		q.Set("since", p.Since)
+	}
+	if p.Until != "" {
+		q.Set("until", p.Until)
+	}
+	if p.Sort != "" {
+		q.Set("sort", p.Sort)
+	}
+	if p.Page != "" {
+		q.Set("page", p.Page)
+	}
+	if p.PerPage != "" {
+		q.Set("per_page", p.PerPage)
+	}
+	return q
+}
+
+// GetAnalysesResponse is the response of GetAnalyses
+type GetAnalysesResponse struct {
+	Embedded struct {
+		Analyses []AnalysisSummary `json:"analyses,omitempty"`
+	} `json:"_embedded,omitempty"`
+	Links      map[string]Link `json:"_links,omitempty"`
+	Count      int             `json:"count,omitempty"`
+	Page       int             `json:"page,omitempty"`
+	PerPage    int             `json:"per_page,omitempty"`
+	Total      int             `json:"total,omitempty"`
+	TotalPages int             `json:"total_pages,omitempty"`
+}
+
+// GetAnalysesIDExportParams are the query parameters of GetAnalysesIDExport
+type GetAnalysesIDExportParams struct {
+	Format string
+}
+
+func (p GetAnalysesIDExportParams) values() url.Values {
+	q := url.Values{}
+	if p.Format != "" {
+		q.Set("format", p.Format)
+	}
+	return q
+}
+
+// PostAnalysesIDFeedbackParams are the query parameters of PostAnalysesIDFeedback
+type PostAnalysesIDFeedbackParams struct {
+	Async bool
+}
+
+func (p PostAnalysesIDFeedbackParams) values() url.Values {
+	q := url.Values{}
+	if p.Async {
+		q.Set("async", "true")
+	}
+	return q
+}
+
+// PostAnalysesIDRerunParams are the query parameters of PostAnalysesIDRerun
+type PostAnalysesIDRerunParams struct {
+	Async bool
+}
+
+func (p PostAnalysesIDRerunParams) values() url.Values {
+	q := url.Values{}
+	if p.Async {
+		q.Set("async", "true")
+	}
+	return q
+}
+
+// PostAnalyzeAlertParams are the query parameters of PostAnalyzeAlert
+type PostAnalyzeAlertParams struct {
+	Async bool
+}
+
+func (p PostAnalyzeAlertParams) values() url.Values {
+	q := url.Values{}
+	if p.Async {
+		q.Set("async", "true")
+	}
+	return q
+}
+
+// PostAnalyzeDeploymentParams are the query parameters of PostAnalyzeDeployment
+type PostAnalyzeDeploymentParams struct {
+	Async bool
+}
+
+func (p PostAnalyzeDeploymentParams) values() url.Values {
+	q := url.Values{}
+	if p.Async {
+		q.Set("async", "true")
+	}
+	return q
+}
+
+// PostAnalyzeJobsResponse is the response of PostAnalyzeJobs
+type PostAnalyzeJobsResponse struct {
+	Links  map[string]Link `json:"_links,omitempty"`
+	ID     string          `json:"id,omitempty"`
+	Status string          `json:"status,omitempty"`
+}
+
+// PostAnalyzeNamespaceParams are the query parameters of PostAnalyzeNamespace
+type PostAnalyzeNamespaceParams struct {
+	Async bool
+}
+
+func (p PostAnalyzeNamespaceParams) values() url.Values {
+	q := url.Values{}
+	if p.Async {
+		q.Set("async", "true")
+	}
+	return q
+}
+
+// PostAnalyzePodParams are the query parameters of PostAnalyzePod
+type PostAnalyzePodParams struct {
+	Async bool
+}
+
+func (p PostAnalyzePodParams) values() url.Values {
+	q := url.Values{}
+	if p.Async {
+		q.Set("async", "true")
+	}
+	return q
+}
+
+// GetFeedbackParams are the query parameters of GetFeedback
+type GetFeedbackParams struct {
+	AnalysisID string
+	AlertName  string
+	Rating     string
+	Limit      string
+}
+
+func (p GetFeedbackParams) values() url.Values {
+	q := url.Values{}
+	if p.AnalysisID != "" {
+		q.Set("analysis_id", p.AnalysisID)
+	}
+	if p.AlertName != "" {
+		q.Set("alert_name", p.AlertName)
+	}
+	if p.Rating != "" {
+		q.Set("rating", p.Rating)
+	}
+	if p.Limit != "" {
+		q.Set("limit", p.Limit)
+	}
+	return q
+}
+
+// GetIncidentsParams are the query parameters of GetIncidents
+type GetIncidentsParams struct {
+	Status string
+	Page   string
+}
+
+func (p GetIncidentsParams) values() url.Values {
+	q := url.Values{}
+	if p.Status != "" {
+		q.Set("status", p.Status)
+	}
+	if p.Page != "" {
+		q.Set("page", p.Page)
+	}
+	return q
+}
+
+// GetIncidentsResponse is the response of GetIncidents
+type GetIncidentsResponse struct {
+	Embedded struct {
+		Incidents []IncidentResponse `json:"incidents,omitempty"`
+	} `json:"_embedded,omitempty"`
+	Links map[string]Link `json:"_links,omitempty"`
+	Count int             `json:"count,omitempty"`
+	Page  int             `json:"page,omitempty"`
+}
+
+// GetJobsJobResponse is the response of GetJobsJob
+type GetJobsJobResponse struct {
+	Links map[string]Link `json:"_links,omitempty"`
+	Job   *AnalysisJob    `json:"job,omitempty"`
+}
+
+// GetK8sNamespacesParams are the query parameters of GetK8sNamespaces
+type GetK8sNamespacesParams struct {
+	Prefix string
+}
+
+func (p GetK8sNamespacesParams) values() url.Values {
+	q := url.Values{}
+	if p.Prefix != "" {
+		q.Set("prefix", p.Prefix)
+	}
+	return q
+}
+
+// GetK8sPodsParams are the query parameters of GetK8sPods
+type GetK8sPodsParams struct {
+	Namespace string
+	Prefix    string
+}
+
+func (p GetK8sPodsParams) values() url.Values {
+	q := url.Values{}
+	if p.Namespace != "" {
+		q.Set("namespace", p.Namespace)
+	}
+	if p.Prefix != "" {
+		q.Set("prefix", p.Prefix)
+	}
+	return q
+}
+
+// GetRemediationsParams are the query parameters of GetRemediations
+type GetRemediationsParams struct {
+	Status     string
+	Namespace  string
+	AnalysisID string
+	Limit      string
+}
+
+func (p GetRemediationsParams) values() url.Values {
+	q := url.Values{}
+	if p.Status != "" {
+		q.Set("status", p.Status)
+	}
+	if p.Namespace != "" {
+		q.Set("namespace", p.Namespace)
+	}
+	if p.AnalysisID != "" {
+		q.Set("analysis_id", p.AnalysisID)
+	}
+	if p.Limit != "" {
+		q.Set("limit", p.Limit)
+	}
+	return q
+}
+
+// GetReportsPostmortemParams are the query parameters of GetReportsPostmortem
+type GetReportsPostmortemParams struct {
+	Period    string
+	Namespace string
+	Format    string
+}
+
+func (p GetReportsPostmortemParams) values() url.Values {
+	q := url.Values{}
+	if p.Period != "" {
+		q.Set("period", p.Period)
+	}
+	if p.Namespace != "" {
+		q.Set("namespace", p.Namespace)
+	}
+	if p.Format != "" {
+		q.Set("format", p.Format)
+	}
+	return q
+}
+
+// GetStatsAnalysesParams are the query parameters of GetStatsAnalyses
+type GetStatsAnalysesParams struct {
+	Days      string
+	Namespace string
+}
+
+func (p GetStatsAnalysesParams) values() url.Values {
+	q := url.Values{}
+	if p.Days != "" {
+		q.Set("days", p.Days)
+	}
+	if p.Namespace != "" {
+		q.Set("namespace", p.Namespace)
+	}
+	return q
+}
+
+// GetStatsFeedbackParams are the query parameters of GetStatsFeedback
+type GetStatsFeedbackParams struct {
+	Days      string
+	AlertName string
+}
+
+func (p GetStatsFeedbackParams) values() url.Values {
+	q := url.Values{}
+	if p.Days != "" {
+		q.Set("days", p.Days)
+	}
+	if p.AlertName != "" {
+		q.Set("alert_name", p.AlertName)
+	}
+	return q
+}
+
+// PostWebhookAlertmanagerParams are the query parameters of PostWebhookAlertmanager
+type PostWebhookAlertmanagerParams struct {
+	Async bool
+}
+
+func (p PostWebhookAlertmanagerParams) values() url.Values {
+	q := url.Values{}
+	if p.Async {
+		q.Set("async", "true")
+	}
+	return q
+}
+
+// PostWebhookGenericParams are the query parameters of PostWebhookGeneric
+type PostWebhookGenericParams struct {
+	Source string
+	Async  bool
+}
+
+func (p PostWebhookGenericParams) values() url.Values {
+	q := url.Values{}
+	if p.Source != "" {
+		q.Set("source", p.Source)
+	}
+	if p.Async {
+		q.Set("async", "true")
+	}
+	return q
+}
+
+// PostWebhookOpsgenieParams are the query parameters of PostWebhookOpsgenie
+type PostWebhookOpsgenieParams struct {
+	Async bool
+}
+
+func (p PostWebhookOpsgenieParams) values() url.Values {
+	q := url.Values{}
+	if p.Async {
+		q.Set("async", "true")
+	}
+	return q
+}
+
+// PostWebhookPagerdutyParams are the query parameters of PostWebhookPagerduty
+type PostWebhookPagerdutyParams struct {
+	Async bool
+}
+
+func (p PostWebhookPagerdutyParams) values() url.Values {
+	q := url.Values{}
+	if p.Async {
+		q.Set("async", "true")
+	}
+	return q
+}
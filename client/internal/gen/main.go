@@ -0,0 +1,374 @@
+// Command gen writes the OpenAPI description of the server to openapi.json
+// and generates the types and operations of the Go client from it into
+// client_gen.go. Run it with go generate in the client directory.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/emirozbir/micro-sre/internal/api"
+)
+
+const (
+	specFile   = "openapi.json"
+	outputFile = "client_gen.go"
+)
+
+// schema is the subset of an OpenAPI schema object the server emits
+type schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 string             `json:"type"`
+	Format               string             `json:"format"`
+	Properties           map[string]*schema `json:"properties"`
+	AdditionalProperties *schema            `json:"additionalProperties"`
+	Items                *schema            `json:"items"`
+	Required             []string           `json:"required"`
+	Enum                 []string           `json:"enum"`
+}
+
+type parameter struct {
+	Name   string  `json:"name"`
+	In     string  `json:"in"`
+	Schema *schema `json:"schema"`
+}
+
+type media struct {
+	Schema *schema `json:"schema"`
+}
+
+type operation struct {
+	Summary     string      `json:"summary"`
+	OperationID string      `json:"operationId"`
+	Parameters  []parameter `json:"parameters"`
+	RequestBody *struct {
+		Content map[string]media `json:"content"`
+	} `json:"requestBody"`
+	Responses map[string]struct {
+		Content map[string]media `json:"content"`
+	} `json:"responses"`
+}
+
+type document struct {
+	Paths      map[string]map[string]*operation `json:"paths"`
+	Components struct {
+		Schemas map[string]*schema `json:"schemas"`
+	} `json:"components"`
+}
+
+func main() {
+	spec, err := api.OpenAPIJSON()
+	if err != nil {
+		log.Fatalf("failed to build the OpenAPI description: %v", err)
+	}
+	if err := os.WriteFile(specFile, append(spec, '\n'), 0o644); err != nil {
+		log.Fatal(err)
+	}
+
+	var doc document
+	if err := json.Unmarshal(spec, &doc); err != nil {
+		log.Fatalf("failed to parse %s: %v", specFile, err)
+	}
+	g := &generator{imports: map[string]bool{"context": true, "net/http": true}}
+	code := g.generate(&doc)
+	src, err := format.Source(code)
+	if err != nil {
+		log.Fatalf("failed to format the generated client: %v\n%s", err, code)
+	}
+	if err := os.WriteFile(outputFile, src, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+type generator struct {
+	buf     bytes.Buffer
+	imports map[string]bool
+	// types holds the types of inline responses and query parameters,
+	// written after the operations
+	types bytes.Buffer
+}
+
+func (g *generator) printf(format string, args ...any) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+// generate returns the source of the client: a type per schema, then a
+// method per operation, then the types of inline responses and query
+// parameters
+func (g *generator) generate(doc *document) []byte {
+	names := sortedKeys(doc.Components.Schemas)
+	for _, name := range names {
+		// The Error schema is returned as *Error by Client.do
+		if name == "Error" {
+			continue
+		}
+		g.printf("// %s is the %s schema of the API\n", name, name)
+		g.printf("type %s %s\n\n", name, g.goType(doc.Components.Schemas[name], true))
+	}
+
+	for _, path := range sortedKeys(doc.Paths) {
+		item := doc.Paths[path]
+		for _, method := range sortedKeys(item) {
+			g.operation(path, method, item[method])
+		}
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by go run ./internal/gen from %s; DO NOT EDIT.\n\n", specFile)
+	fmt.Fprintf(&out, "package client\n\nimport (\n")
+	for _, pkg := range sortedKeys(g.imports) {
+		fmt.Fprintf(&out, "\t%q\n", pkg)
+	}
+	fmt.Fprintf(&out, ")\n\n")
+	out.Write(g.buf.Bytes())
+	out.Write(g.types.Bytes())
+	return out.Bytes()
+}
+
+// operation writes the client method of one operation
+func (g *generator) operation(path, method string, op *operation) {
+	name := goName(op.OperationID, true)
+	httpMethod := "http.Method" + strings.ToUpper(method[:1]) + method[1:]
+
+	var args []string
+	var query []parameter
+	pathExpr := `"` + path + `"`
+	for _, p := range op.Parameters {
+		switch p.In {
+		case "path":
+			arg := goName(p.Name, false)
+			typ := g.goType(p.Schema, false)
+			args = append(args, arg+" "+typ)
+			value := "url.PathEscape(" + arg + ")"
+			switch typ {
+			case "int64":
+				value = "strconv.FormatInt(" + arg + ", 10)"
+				g.imports["strconv"] = true
+			case "int":
+				value = "strconv.Itoa(" + arg + ")"
+				g.imports["strconv"] = true
+			default:
+				g.imports["net/url"] = true
+			}
+			pathExpr = strings.Replace(pathExpr, "{"+p.Name+"}", `" + `+value+` + "`, 1)
+		case "query":
+			query = append(query, p)
+		}
+	}
+	pathExpr = strings.TrimSuffix(pathExpr, ` + ""`)
+
+	body := "nil"
+	if op.RequestBody != nil {
+		args = append(args, "body "+g.goType(op.RequestBody.Content["application/json"].Schema, false))
+		body = "body"
+	}
+	queryExpr := "nil"
+	if len(query) > 0 {
+		params := name + "Params"
+		args = append(args, "params "+params)
+		queryExpr = "query"
+		g.params(params, op.OperationID, query)
+	}
+
+	out := g.goType(op.Responses["200"].Content["application/json"].Schema, false)
+	response := op.Responses["200"].Content["application/json"].Schema
+	if response.Ref == "" && len(response.Properties) > 0 {
+		out = name + "Response"
+		fmt.Fprintf(&g.types, "// %s is the response of %s\n", out, name)
+		fmt.Fprintf(&g.types, "type %s %s\n\n", out, g.goType(response, true))
+	}
+
+	g.printf("// %s calls %s %s: %s\n", name, strings.ToUpper(method), path, op.Summary)
+	g.printf("func (c *Client) %s(ctx context.Context", name)
+	for _, arg := range args {
+		g.printf(", %s", arg)
+	}
+	if out == "json.RawMessage" {
+		g.printf(") (json.RawMessage, error) {\n")
+	} else {
+		g.printf(") (*%s, error) {\n", out)
+	}
+	if len(query) > 0 {
+		g.printf("\tquery := params.values()\n")
+	}
+	if out == "json.RawMessage" {
+		g.printf("\tvar out []byte\n")
+	} else {
+		g.printf("\tvar out %s\n", out)
+	}
+	g.printf("\tif err := c.do(ctx, %s, %s, %s, %s, &out); err != nil {\n\t\treturn nil, err\n\t}\n", httpMethod, pathExpr, queryExpr, body)
+	if out == "json.RawMessage" {
+		g.printf("\treturn out, nil\n}\n\n")
+	} else {
+		g.printf("\treturn &out, nil\n}\n\n")
+	}
+}
+
+// params writes the query parameters of an operation as a struct; zero
+// fields are not sent
+func (g *generator) params(name, operationID string, query []parameter) {
+	g.imports["net/url"] = true
+	fmt.Fprintf(&g.types, "// %s are the query parameters of %s\n", name, goName(operationID, true))
+	fmt.Fprintf(&g.types, "type %s struct {\n", name)
+	for _, p := range query {
+		fmt.Fprintf(&g.types, "\t%s %s\n", goName(p.Name, true), g.goType(p.Schema, false))
+	}
+	fmt.Fprintf(&g.types, "}\n\n")
+
+	fmt.Fprintf(&g.types, "func (p %s) values() url.Values {\n\tq := url.Values{}\n", name)
+	for _, p := range query {
+		field := "p." + goName(p.Name, true)
+		switch p.Schema.Type {
+		case "boolean":
+			fmt.Fprintf(&g.types, "\tif %s {\n\t\tq.Set(%q, \"true\")\n\t}\n", field, p.Name)
+		default:
+			fmt.Fprintf(&g.types, "\tif %s != \"\" {\n\t\tq.Set(%q, %s)\n\t}\n", field, p.Name, field)
+		}
+	}
+	fmt.Fprintf(&g.types, "\treturn q\n}\n\n")
+}
+
+// goType returns the Go type of s. Objects with properties become structs,
+// free-form objects are maps at the top level of a type and raw JSON
+// otherwise.
+func (g *generator) goType(s *schema, top bool) string {
+	if s == nil {
+		return "any"
+	}
+	if s.Ref != "" {
+		return strings.TrimPrefix(s.Ref, "#/components/schemas/")
+	}
+	switch s.Type {
+	case "boolean":
+		return "bool"
+	case "integer":
+		if s.Format == "int64" {
+			return "int64"
+		}
+		return "int"
+	case "number":
+		return "float64"
+	case "string":
+		if s.Format == "date-time" {
+			g.imports["time"] = true
+			return "time.Time"
+		}
+		return "string"
+	case "array":
+		return "[]" + g.fieldType(s.Items)
+	case "object":
+		if len(s.Properties) > 0 {
+			return g.structType(s)
+		}
+		if s.AdditionalProperties != nil {
+			return "map[string]" + g.fieldType(s.AdditionalProperties)
+		}
+		if top {
+			return "map[string]any"
+		}
+		g.imports["encoding/json"] = true
+		return "json.RawMessage"
+	}
+	return "any"
+}
+
+// fieldType is goType for struct fields, map values and array items, where
+// free-form objects are decoded as maps
+func (g *generator) fieldType(s *schema) string {
+	if s != nil && s.Ref == "" && s.Type == "object" && len(s.Properties) == 0 && s.AdditionalProperties == nil {
+		return "map[string]any"
+	}
+	return g.goType(s, false)
+}
+
+func (g *generator) structType(s *schema) string {
+	required := map[string]bool{}
+	for _, name := range s.Required {
+		required[name] = true
+	}
+	var sb strings.Builder
+	sb.WriteString("struct {\n")
+	for _, prop := range sortedKeys(s.Properties) {
+		p := s.Properties[prop]
+		if len(p.Enum) > 0 {
+			fmt.Fprintf(&sb, "\t// %s is one of %s\n", goName(prop, true), strings.Join(p.Enum, ", "))
+		}
+		typ := g.fieldType(p)
+		if p.Ref != "" {
+			typ = "*" + typ
+		}
+		tag := prop
+		if !required[prop] {
+			tag += ",omitempty"
+		}
+		fmt.Fprintf(&sb, "\t%s %s `json:%q`\n", goName(prop, true), typ, tag)
+	}
+	sb.WriteString("}")
+	return sb.String()
+}
+
+// initialisms are the words written in capitals in Go names
+var initialisms = map[string]string{
+	"api": "API", "id": "ID", "ids": "IDs", "url": "URL", "html": "HTML",
+	"http": "HTTP", "json": "JSON", "llm": "LLM", "oom": "OOM", "usd": "USD",
+	"ip": "IP", "cpu": "CPU", "uid": "UID",
+}
+
+// goName turns a JSON, parameter or operation name into a Go identifier,
+// e.g. analysis_id to AnalysisID, generatorURL to GeneratorURL and
+// getApiV1AnalysesId to GetAnalysesID. The /api/v1 prefix of routes is
+// left out of operation names.
+func goName(name string, exported bool) string {
+	var words []string
+	for _, part := range strings.FieldsFunc(name, func(r rune) bool { return r == '_' || r == '-' || r == '.' }) {
+		words = append(words, splitCamel(part)...)
+	}
+	if len(words) > 2 && words[1] == "api" && words[2] == "v1" {
+		words = append(words[:1], words[3:]...)
+	}
+
+	var sb strings.Builder
+	for i, w := range words {
+		switch {
+		case i == 0 && !exported:
+			sb.WriteString(w)
+		case initialisms[w] != "":
+			sb.WriteString(initialisms[w])
+		default:
+			sb.WriteString(strings.ToUpper(w[:1]) + w[1:])
+		}
+	}
+	return sb.String()
+}
+
+// splitCamel splits a camelCase word into lowercase words, keeping runs of
+// capitals such as URL together
+func splitCamel(s string) []string {
+	var words []string
+	runes := []rune(s)
+	start := 0
+	for i := 1; i < len(runes); i++ {
+		lowerBefore := unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1])
+		if unicode.IsUpper(runes[i]) && (lowerBefore || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+			words = append(words, strings.ToLower(string(runes[start:i])))
+			start = i
+		}
+	}
+	return append(words, strings.ToLower(string(runes[start:])))
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,35 @@
+package client
+
+// Ratings of RatingRequest
+const (
+	RatingUp   = "up"
+	RatingDown = "down"
+)
+
+// Analysis statuses; only completed analyses have a root cause
+const (
+	AnalysisPending   = "pending"
+	AnalysisRunning   = "running"
+	AnalysisCompleted = "completed"
+	AnalysisFailed    = "failed"
+)
+
+// Job statuses
+const (
+	JobQueued    = "queued"
+	JobScheduled = "scheduled"
+	JobRunning   = "running"
+	JobCompleted = "completed"
+	JobFailed    = "failed"
+)
+
+// Done reports whether the job completed or failed
+func (j *AnalysisJob) Done() bool {
+	return j.Status == JobCompleted || j.Status == JobFailed
+}
+
+// Incident statuses
+const (
+	IncidentOpen     = "open"
+	IncidentResolved = "resolved"
+)
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/emirozbir/micro-sre/internal/collectors"
+	"github.com/emirozbir/micro-sre/internal/config"
+	"github.com/emirozbir/micro-sre/internal/models"
+)
+
+// runAlerts implements "hepsre alerts list": it prints the alerts firing
+// in AlertManager and, with -analyze, analyzes one of them
+func runAlerts(args []string) error {
+	if len(args) == 0 || args[0] != "list" {
+		return fmt.Errorf("usage: hepsre alerts list [-namespace <namespace>] [-analyze <fingerprint>] [flags]")
+	}
+	fs := flag.NewFlagSet("alerts list", flag.ExitOnError)
+	var opts analysisOptions
+	fs.StringVar(&opts.namespace, "namespace", "", "Only list alerts of this namespace")
+	fs.StringVar(&opts.namespace, "n", "", "Only list alerts of this namespace (shorthand)")
+	analyze := fs.String("analyze", "", "Fingerprint (or unique prefix) of a listed alert to analyze right away")
+	fs.StringVar(&opts.lookback, "lookback", "1h", "Time range to look back with -analyze, unless the alert sets one")
+	fs.StringVar(&opts.verbosity, "verbosity", "", "Report verbosity with -analyze (default from the alert's report route)")
+	fs.StringVar(&opts.configPath, "config", "", "Path to config file")
+	fs.StringVar(&opts.outputFormat, "format", "pretty", "Output format: 'pretty' (a table) or 'json'")
+	fs.BoolVar(&opts.noColor, "no-color", false, "Disable colored output")
+	fs.Parse(args[1:])
+
+	cfg, err := config.Load(opts.configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	am, err := collectors.NewAlertManagerCollector(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to create alertmanager collector: %w", err)
+	}
+
+	ctx := context.Background()
+	var alerts []models.Alert
+	if opts.namespace != "" {
+		alerts, err = am.GetAlertsByNamespace(ctx, opts.namespace)
+	} else {
+		alerts, err = am.GetActiveAlerts(ctx)
+	}
+	if err != nil {
+		return err
+	}
+	sort.SliceStable(alerts, func(i, j int) bool { return alerts[i].StartsAt.After(alerts[j].StartsAt) })
+
+	if *analyze == "" {
+		if opts.outputFormat == "json" {
+			if alerts == nil {
+				alerts = []models.Alert{}
+			}
+			output, err := json.MarshalIndent(alerts, "", "  ")
+			if err != nil {
+				return fmt.Errorf("failed to marshal alerts: %w", err)
+			}
+			fmt.Println(string(output))
+			return nil
+		}
+		printAlerts(alerts)
+		return nil
+	}
+
+	alert, err := findAlert(alerts, *analyze)
+	if err != nil {
+		return err
+	}
+	if alert.GetNamespace() == "" || alert.GetPodName() == "" {
+		return fmt.Errorf("alert %s (%s) has no namespace or pod label to analyze", alert.Fingerprint, alert.GetAlertName())
+	}
+	opts.namespace = alert.GetNamespace()
+	opts.pod = alert.GetPodName()
+	opts.alert = alert
+	return runAnalysis(opts)
+}
+
+// findAlert returns the alert whose fingerprint is, or uniquely starts
+// with, fingerprint
+func findAlert(alerts []models.Alert, fingerprint string) (*models.Alert, error) {
+	var found *models.Alert
+	for i := range alerts {
+		switch {
+		case alerts[i].Fingerprint == fingerprint:
+			return &alerts[i], nil
+		case strings.HasPrefix(alerts[i].Fingerprint, fingerprint):
+			if found != nil {
+				return nil, fmt.Errorf("fingerprint %q matches more than one firing alert", fingerprint)
+			}
+			found = &alerts[i]
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("no firing alert with fingerprint %q", fingerprint)
+	}
+	return found, nil
+}
+
+// printAlerts prints firing alerts as a table, newest first
+func printAlerts(alerts []models.Alert) {
+	if len(alerts) == 0 {
+		fmt.Println("No firing alerts")
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "FINGERPRINT\tALERT\tSEVERITY\tNAMESPACE\tPOD\tAGE\tSUMMARY")
+	for _, a := range alerts {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			orDash(a.Fingerprint),
+			a.GetAlertName(),
+			a.GetSeverity(),
+			orDash(a.GetNamespace()),
+			orDash(a.GetPodName()),
+			time.Since(a.StartsAt).Truncate(time.Second),
+			orDash(a.Annotations["summary"]))
+	}
+	w.Flush()
+}
+
+// orDash returns s, or "-" for an empty table cell
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
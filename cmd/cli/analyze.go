@@ -0,0 +1,320 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/emirozbir/micro-sre/internal/agent"
+	"github.com/emirozbir/micro-sre/internal/collectors"
+	"github.com/emirozbir/micro-sre/internal/config"
+	"github.com/emirozbir/micro-sre/internal/formatter"
+	"github.com/emirozbir/micro-sre/internal/models"
+	"github.com/emirozbir/micro-sre/internal/ui"
+)
+
+// analysisOptions selects what an analysis looks at and how its report is
+// printed: a pod, a workload (Deployment or StatefulSet) or, with health,
+// the whole namespace. Without a pod name, the worst pod matching selector,
+// or with auto the worst pod with restarts or not ready, is analyzed.
+type analysisOptions struct {
+	namespace  string
+	pod        string
+	deployment string
+	selector   string
+	auto       bool
+	// alert is the AlertManager alert the pod is analyzed for, if any
+	alert        *models.Alert
+	health       bool
+	lookback     string
+	verbosity    string
+	configPath   string
+	outputFormat string
+	// output is the file the report is written to, stdout if empty
+	output  string
+	noColor bool
+}
+
+// analyzeKinds maps the targets of "hepsre analyze", with kubectl's plural
+// and short names, to their kind. Deployments and StatefulSets are both
+// looked up by name, a Deployment first.
+var analyzeKinds = map[string]string{
+	"pod":          "pod",
+	"pods":         "pod",
+	"po":           "pod",
+	"deployment":   "deployment",
+	"deployments":  "deployment",
+	"deploy":       "deployment",
+	"statefulset":  "statefulset",
+	"statefulsets": "statefulset",
+	"sts":          "statefulset",
+}
+
+// runAnalyze implements "hepsre analyze [pod|deployment|statefulset]
+// <name>", also written kubectl's way as <kind>/<name>: the pod, or the
+// unhealthy pods of the workload, are analyzed into one report. A bare name
+// is a pod, and a pod can be picked with -selector or --auto instead of by
+// name. The namespace defaults to that of the current kubectl context.
+func runAnalyze(args []string) error {
+	const usage = "usage: hepsre analyze [pod|deployment|statefulset] <name> [-n <namespace>] [flags], or hepsre analyze [pod] -selector <labels>|--auto [flags]"
+	kind := "pod"
+	var names []string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		prefix, name, qualified := strings.Cut(args[0], "/")
+		switch k, ok := analyzeKinds[prefix]; {
+		case ok && qualified:
+			kind, names, args = k, []string{name}, args[1:]
+		case ok:
+			kind, args = k, args[1:]
+		case qualified:
+			return fmt.Errorf("unknown kind %q: use pod, deployment or statefulset", prefix)
+		}
+	}
+
+	fs := flag.NewFlagSet("analyze "+kind, flag.ExitOnError)
+	var opts analysisOptions
+	fs.StringVar(&opts.namespace, "namespace", "", "Kubernetes namespace")
+	fs.StringVar(&opts.namespace, "n", "", "Kubernetes namespace (shorthand)")
+	fs.StringVar(&opts.lookback, "lookback", "1h", "Time range to look back (e.g., 1h, 30m)")
+	fs.StringVar(&opts.verbosity, "verbosity", "", "Report verbosity: 'brief', 'standard' or 'deep' (default from config)")
+	fs.StringVar(&opts.configPath, "config", "", "Path to config file")
+	registerOutput(fs, &opts)
+	fs.BoolVar(&opts.noColor, "no-color", false, "Disable colored output")
+	if kind == "pod" {
+		fs.StringVar(&opts.selector, "selector", "", "Label selector, e.g. 'app=checkout'; analyzes the worst matching pod")
+		fs.StringVar(&opts.selector, "l", "", "Label selector (shorthand)")
+		fs.BoolVar(&opts.auto, "auto", false, "Analyze the pod with the most restarts or not ready (within -selector, if given)")
+	}
+	names = append(names, parseInterspersed(fs, args)...)
+	if err := resolveOutputFormat(&opts, flagSet(fs, "format")); err != nil {
+		return err
+	}
+
+	picked := opts.selector != "" || opts.auto
+	if len(names) > 1 || (len(names) == 1) == picked {
+		return fmt.Errorf(usage)
+	}
+	switch {
+	case picked:
+	case kind == "pod":
+		opts.pod = names[0]
+	default:
+		opts.deployment = names[0]
+	}
+	return runAnalysis(opts)
+}
+
+// parseInterspersed parses flags given before, between or after the
+// positional arguments, which the flag package alone stops at, and returns
+// the positional arguments
+func parseInterspersed(fs *flag.FlagSet, args []string) []string {
+	var positional []string
+	for {
+		fs.Parse(args)
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional
+		}
+		if args[0] == "--" {
+			return append(positional, args[1:]...)
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// runAnalysis runs the analysis selected by opts in this process and
+// prints its report
+func runAnalysis(opts analysisOptions) error {
+	if opts.verbosity != "" && !config.ValidVerbosity(opts.verbosity) {
+		return fmt.Errorf("invalid verbosity %q: use brief, standard or deep", opts.verbosity)
+	}
+	if opts.health && opts.outputFormat != formatPretty && opts.outputFormat != formatJSON {
+		return fmt.Errorf("health reports are written as pretty or json")
+	}
+
+	// Parse lookback duration
+	lookbackDuration, err := time.ParseDuration(opts.lookback)
+	if err != nil {
+		return fmt.Errorf("invalid lookback duration: %w", err)
+	}
+
+	agentInstance, cfg, err := newAgent(opts.configPath)
+	if err != nil {
+		return err
+	}
+	defer agentInstance.Close()
+
+	if opts.namespace == "" {
+		if opts.namespace, err = collectors.DefaultNamespace(cfg); err != nil {
+			return err
+		}
+	}
+
+	ctx := context.Background()
+	if opts.selector != "" || opts.auto {
+		if opts.pod, err = pickPod(ctx, agentInstance.Kubernetes(), opts); err != nil {
+			return err
+		}
+	}
+
+	if opts.health {
+		stop := startProgress(agentInstance, opts)
+		report, err := agentInstance.NamespaceHealthReport(ctx, opts.namespace, lookbackDuration)
+		stop()
+		if err != nil {
+			return fmt.Errorf("health report failed: %w", err)
+		}
+		var output []byte
+		if opts.outputFormat == formatJSON {
+			output, err = json.MarshalIndent(report, "", "  ")
+			if err != nil {
+				return fmt.Errorf("failed to marshal report: %w", err)
+			}
+		} else {
+			output = []byte(formatter.NewFormatter(opts.output == "" && !opts.noColor).FormatHealthReport(report))
+		}
+		return writeOutput(opts.output, append(output, '\n'))
+	}
+
+	result, err := analyzeTarget(ctx, agentInstance, cfg, opts, lookbackDuration)
+	if err != nil {
+		return err
+	}
+	return printResult(0, result, opts)
+}
+
+// newAgent loads the configuration and creates an agent logging to stderr
+func newAgent(configPath string) (*agent.Agent, *config.Config, error) {
+	// Initialize logger
+	logger, err := zap.NewDevelopment()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to initialize logger: %w", err)
+	}
+
+	// Load configuration
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	// Initialize agent
+	agentInstance, err := agent.NewAgent(cfg, logger)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create agent: %w", err)
+	}
+	return agentInstance, cfg, nil
+}
+
+// startProgress sets up progress reporting based on the output format and
+// returns the function that stops it before output
+func startProgress(agentInstance *agent.Agent, opts analysisOptions) (stop func()) {
+	quiet := quietProgress(opts)
+	if !quiet && !opts.noColor {
+		// Normal mode: animated spinner
+		progress := ui.NewSpinnerProgress()
+		agentInstance.SetProgressReporter(progress)
+		progress.Start("Initializing analysis...")
+		return progress.Stop
+	}
+	if !quiet {
+		// No-color mode: simple text
+		target := "pod " + opts.pod
+		if opts.deployment != "" {
+			target = "workload " + opts.deployment
+		} else if opts.health {
+			target = "health of all workloads"
+		}
+		fmt.Printf("Analyzing %s in namespace %s (lookback: %s)...\n", target, opts.namespace, opts.lookback)
+	}
+	// Machine-readable output on stdout: completely silent
+	agentInstance.SetProgressReporter(&agent.NoOpProgressReporter{})
+	return func() {}
+}
+
+// analyzeTarget analyzes the pod or workload selected by opts, for
+// opts.alert if it is set
+func analyzeTarget(ctx context.Context, agentInstance *agent.Agent, cfg *config.Config, opts analysisOptions, lookback time.Duration) (*models.AnalysisResult, error) {
+	stop := startProgress(agentInstance, opts)
+
+	var (
+		result *models.AnalysisResult
+		err    error
+	)
+	if opts.deployment != "" {
+		result, err = agentInstance.AnalyzeDeployment(ctx, opts.namespace, opts.deployment, lookback, opts.verbosity)
+	} else {
+		req := agent.AnalysisRequest{
+			Namespace: opts.namespace,
+			PodName:   opts.pod,
+			Lookback:  lookback,
+			Verbosity: opts.verbosity,
+		}
+		if opts.alert != nil {
+			req.AlertFingerprint = opts.alert.Fingerprint
+			req.Alert = opts.alert
+			if alertLookback, _ := opts.alert.Lookback(); alertLookback > 0 {
+				req.Lookback = alertLookback
+			}
+			if req.Verbosity == "" {
+				req.Verbosity = cfg.Report.VerbosityFor(opts.alert.Labels)
+			}
+		}
+		result, err = agentInstance.AnalyzeAlert(ctx, req)
+	}
+
+	// Ensure spinner is stopped before output
+	stop()
+
+	if err != nil {
+		return nil, fmt.Errorf("analysis failed: %w", err)
+	}
+	return result, nil
+}
+
+// printResult writes an analysis result in the selected format; id is the
+// ID of a stored analysis, 0 otherwise
+func printResult(id int64, result *models.AnalysisResult, opts analysisOptions) error {
+	output, err := renderResult(id, result, opts.outputFormat, opts.output == "" && !opts.noColor)
+	if err != nil {
+		return err
+	}
+	return writeOutput(opts.output, output)
+}
+
+// pickPod returns the worst pod matching opts.selector, which with
+// opts.auto must have restarted or not be ready, and says which it picked
+// and why on stderr
+func pickPod(ctx context.Context, k8s *collectors.KubernetesCollector, opts analysisOptions) (string, error) {
+	pods, err := k8s.RankPods(ctx, opts.namespace, opts.selector)
+	if err != nil {
+		return "", err
+	}
+	where := "in namespace " + opts.namespace
+	if opts.selector != "" {
+		where = fmt.Sprintf("matching %q %s", opts.selector, where)
+	}
+	if len(pods) == 0 {
+		return "", fmt.Errorf("no pods %s", where)
+	}
+	worst := pods[0]
+	if opts.auto && worst.Healthy() && worst.Restarts == 0 {
+		return "", fmt.Errorf("no pods with restarts or not ready %s", where)
+	}
+
+	if opts.outputFormat != "json" {
+		reason := ""
+		if worst.Reason != "" {
+			reason = ", " + worst.Reason
+		}
+		fmt.Fprintf(os.Stderr, "Picked pod %s of %d %s (ready: %t, restarts: %d%s)\n",
+			worst.Name, len(pods), where, worst.Ready, worst.Restarts, reason)
+	}
+	return worst.Name, nil
+}
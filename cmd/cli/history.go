@@ -0,0 +1,335 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/emirozbir/micro-sre/internal/config"
+	"github.com/emirozbir/micro-sre/internal/database"
+	"github.com/emirozbir/micro-sre/internal/encryption"
+	"github.com/emirozbir/micro-sre/internal/models"
+)
+
+// maxHistory caps -limit at the server's largest search page
+const maxHistory = 100
+
+// historyEntry is a past analysis as "hepsre history" lists it, decoded
+// from the server's analysis summaries or read from the database
+type historyEntry struct {
+	ID         int64     `json:"id"`
+	CreatedAt  time.Time `json:"created_at"`
+	AlertName  string    `json:"alert_name"`
+	Namespace  string    `json:"namespace"`
+	Pod        string    `json:"pod"`
+	Severity   string    `json:"severity"`
+	RootCause  string    `json:"root_cause"`
+	Confidence string    `json:"confidence"`
+	Status     string    `json:"status"`
+}
+
+// historySource selects where "hepsre history" and "hepsre show" read
+// analyses from: the server, or with local the configured database
+type historySource struct {
+	server     string
+	local      bool
+	configPath string
+}
+
+// register adds the flags selecting the source to fs
+func (s *historySource) register(fs *flag.FlagSet) {
+	fs.StringVar(&s.server, "server", "http://localhost:8080", "Server URL to read analyses from")
+	fs.StringVar(&serverAPIKey, "api-key", os.Getenv("HEPSRE_API_KEY"), "API key sent to -server (default $HEPSRE_API_KEY)")
+	fs.BoolVar(&s.local, "local", false, "Read the database configured in -config directly instead of the server")
+	fs.StringVar(&s.configPath, "config", "", "Path to config file, for -local")
+}
+
+// openDatabase opens the configured database with its encryption key, so
+// that sealed results can be read
+func (s *historySource) openDatabase() (database.Store, error) {
+	cfg, err := config.Load(s.configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	cipher, err := encryption.New(cfg.Encryption)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load encryption key: %w", err)
+	}
+	db, err := database.Open(cfg.Database, cipher)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	return db, nil
+}
+
+// runHistory implements "hepsre history": it lists past analyses, newest
+// first
+func runHistory(args []string) error {
+	fs := flag.NewFlagSet("history", flag.ExitOnError)
+	var source historySource
+	source.register(fs)
+	var filter database.AnalysisFilter
+	fs.StringVar(&filter.Namespace, "namespace", "", "Only list analyses of this namespace")
+	fs.StringVar(&filter.Namespace, "n", "", "Only list analyses of this namespace (shorthand)")
+	fs.StringVar(&filter.PodName, "pod", "", "Only list analyses of this pod")
+	fs.StringVar(&filter.AlertName, "alert", "", "Only list analyses of this alert")
+	since := fs.String("since", "7d", "How far back to list, as a duration (e.g. 7d, 12h) or a date")
+	fs.IntVar(&filter.Limit, "limit", 20, fmt.Sprintf("Number of analyses to list (at most %d)", maxHistory))
+	outputFormat := fs.String("format", "pretty", "Output format: 'pretty' (a table) or 'json'")
+	fs.Parse(args)
+
+	if filter.Limit < 1 || filter.Limit > maxHistory {
+		return fmt.Errorf("-limit must be between 1 and %d", maxHistory)
+	}
+	if *since != "" {
+		t, err := parseSince(*since)
+		if err != nil {
+			return err
+		}
+		filter.Since = t
+	}
+
+	var (
+		entries []historyEntry
+		err     error
+	)
+	if source.local {
+		entries, err = localHistory(&source, filter)
+	} else {
+		entries, err = serverHistory(source.server, filter)
+	}
+	if err != nil {
+		return err
+	}
+
+	if *outputFormat == "json" {
+		if entries == nil {
+			entries = []historyEntry{}
+		}
+		output, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal analyses: %w", err)
+		}
+		fmt.Println(string(output))
+		return nil
+	}
+	printHistory(entries)
+	return nil
+}
+
+// parseSince parses a -since value: a number of days ("7d"), a Go
+// duration ("12h") or an RFC 3339 time or date
+func parseSince(value string) (time.Time, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			return time.Now().AddDate(0, 0, -n), nil
+		}
+	}
+	if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+		return time.Now().Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid -since %q: use a duration such as 7d or 12h, or a date", value)
+}
+
+// serverHistory lists analyses with the server's search API
+func serverHistory(server string, filter database.AnalysisFilter) ([]historyEntry, error) {
+	query := url.Values{"per_page": {strconv.Itoa(filter.Limit)}}
+	for param, value := range map[string]string{
+		"namespace": filter.Namespace,
+		"pod":       filter.PodName,
+		"alert":     filter.AlertName,
+	} {
+		if value != "" {
+			query.Set(param, value)
+		}
+	}
+	if !filter.Since.IsZero() {
+		query.Set("since", filter.Since.Format(time.RFC3339))
+	}
+
+	resp, err := serverRequest(http.MethodGet, strings.TrimSuffix(server, "/")+"/api/v1/analyses?"+query.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list analyses: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("server returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	var body struct {
+		Embedded struct {
+			Analyses []historyEntry `json:"analyses"`
+		} `json:"_embedded"`
+	}
+	if err := json.Unmarshal(data, &body); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	return body.Embedded.Analyses, nil
+}
+
+// localHistory lists analyses from the configured database
+func localHistory(source *historySource, filter database.AnalysisFilter) ([]historyEntry, error) {
+	db, err := source.openDatabase()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	stored, err := db.FindAnalyses(filter)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]historyEntry, 0, len(stored))
+	for _, s := range stored {
+		entries = append(entries, historyEntry{
+			ID:         s.ID,
+			CreatedAt:  s.CreatedAt,
+			AlertName:  s.AlertName,
+			Namespace:  s.Namespace,
+			Pod:        s.PodName,
+			Severity:   s.Severity,
+			RootCause:  s.RootCause,
+			Confidence: s.Confidence,
+			Status:     s.Status,
+		})
+	}
+	return entries, nil
+}
+
+// printHistory prints past analyses as a table, with root causes cut to
+// one line
+func printHistory(entries []historyEntry) {
+	if len(entries) == 0 {
+		fmt.Println("No analyses found")
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tCREATED\tALERT\tNAMESPACE\tPOD\tSTATUS\tCONFIDENCE\tROOT CAUSE")
+	for _, e := range entries {
+		rootCause, _, _ := strings.Cut(e.RootCause, "\n")
+		if r := []rune(rootCause); len(r) > 80 {
+			rootCause = string(r[:77]) + "..."
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			e.ID,
+			e.CreatedAt.Local().Format("2006-01-02 15:04"),
+			e.AlertName,
+			orDash(e.Namespace),
+			orDash(e.Pod),
+			e.Status,
+			orDash(e.Confidence),
+			orDash(rootCause))
+	}
+	w.Flush()
+}
+
+// runShow implements "hepsre show <id>": it prints the report of a stored
+// analysis
+func runShow(args []string) error {
+	const usage = "usage: hepsre show <id> [flags]"
+	fs := flag.NewFlagSet("show", flag.ExitOnError)
+	var source historySource
+	source.register(fs)
+	var opts analysisOptions
+	registerOutput(fs, &opts)
+	fs.BoolVar(&opts.noColor, "no-color", false, "Disable colored output")
+	ids := parseInterspersed(fs, args)
+	if len(ids) != 1 {
+		return fmt.Errorf(usage)
+	}
+	id, err := strconv.ParseInt(ids[0], 10, 64)
+	if err != nil || id < 1 {
+		return fmt.Errorf("invalid analysis ID %q", ids[0])
+	}
+	if err := resolveOutputFormat(&opts, flagSet(fs, "format")); err != nil {
+		return err
+	}
+
+	if !source.local {
+		return showServerAnalysis(source.server, id, opts)
+	}
+
+	db, err := source.openDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	stored, err := db.GetAnalysis(id)
+	if err != nil {
+		return err
+	}
+	if stored == nil {
+		return fmt.Errorf("analysis %d not found", id)
+	}
+	if opts.outputFormat != formatJSON && stored.Status != database.AnalysisCompleted {
+		return unfinishedAnalysis(id, stored.Status, stored.Error)
+	}
+	return printResult(id, &stored.AnalysisResult, opts)
+}
+
+// showServerAnalysis fetches a stored analysis from the server and prints
+// its report, or the server's JSON with -format json
+func showServerAnalysis(server string, id int64, opts analysisOptions) error {
+	resp, err := serverRequest(http.MethodGet, fmt.Sprintf("%s/api/v1/analyses/%d", strings.TrimSuffix(server, "/"), id), nil)
+	if err != nil {
+		return fmt.Errorf("failed to fetch analysis: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("server returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	if opts.outputFormat == formatJSON {
+		var out bytes.Buffer
+		if err := json.Indent(&out, data, "", "  "); err != nil {
+			return fmt.Errorf("failed to format response: %w", err)
+		}
+		out.WriteByte('\n')
+		return writeOutput(opts.output, out.Bytes())
+	}
+
+	var body struct {
+		Status string `json:"status"`
+		Error  string `json:"error"`
+		models.AnalysisResult
+	}
+	if err := json.Unmarshal(data, &body); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	if body.Status != "" && body.Status != database.AnalysisCompleted {
+		return unfinishedAnalysis(id, body.Status, body.Error)
+	}
+	return printResult(id, &body.AnalysisResult, opts)
+}
+
+// unfinishedAnalysis reports a stored analysis that has no report to show:
+// one still pending or running, or one that failed
+func unfinishedAnalysis(id int64, status, reason string) error {
+	if reason != "" {
+		return fmt.Errorf("analysis %d is %s: %s", id, status, reason)
+	}
+	return fmt.Errorf("analysis %d is %s", id, status)
+}
@@ -1,105 +1,612 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"log"
-	"time"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
 
 	"go.uber.org/zap"
 
 	"github.com/emirozbir/micro-sre/internal/agent"
+	"github.com/emirozbir/micro-sre/internal/collectors"
 	"github.com/emirozbir/micro-sre/internal/config"
+	"github.com/emirozbir/micro-sre/internal/database"
+	"github.com/emirozbir/micro-sre/internal/eval"
 	"github.com/emirozbir/micro-sre/internal/formatter"
-	"github.com/emirozbir/micro-sre/internal/ui"
+	"github.com/emirozbir/micro-sre/internal/models"
 )
 
 func main() {
-	namespace := flag.String("namespace", "", "Kubernetes namespace")
+	if len(os.Args) > 1 && os.Args[1] == "eval" {
+		if err := runEval(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "analyze" {
+		if err := runAnalyze(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "alerts" {
+		if err := runAlerts(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "watch" {
+		if err := runWatch(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "history" {
+		if err := runHistory(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "show" {
+		if err := runShow(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "db" {
+		if err := runDB(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	namespace := flag.String("namespace", "", "Kubernetes namespace (default: the namespace of the current kubectl context)")
 	pod := flag.String("pod", "", "Pod name")
+	deployment := flag.String("deployment", "", "Deployment or StatefulSet name (analyzes the workload instead of a single pod)")
+	selector := flag.String("selector", "", "Label selector, e.g. 'app=checkout'; analyzes the worst matching pod instead of -pod")
+	auto := flag.Bool("auto", false, "Analyze the pod with the most restarts or not ready instead of -pod (within -selector, if given)")
 	lookback := flag.String("lookback", "1h", "Time range to look back (e.g., 1h, 30m)")
+	verbosity := flag.String("verbosity", "", "Report verbosity: 'brief', 'standard' or 'deep' (default from config)")
 	configPath := flag.String("config", "", "Path to config file")
-	outputFormat := flag.String("format", "pretty", "Output format: 'pretty' or 'json'")
+	outputFormat := flag.String("format", formatPretty, "Output format: 'pretty' or 'json', and for analyses 'markdown', 'csv' or 'pdf' (default from the -output extension)")
+	output := flag.String("output", "", "File to write the analysis report to instead of stdout; .md, .json, .csv and .pdf select the format")
+	flag.StringVar(output, "o", "", "File to write the analysis report to (shorthand)")
 	noColor := flag.Bool("no-color", false, "Disable colored output")
+	health := flag.Bool("health", false, "Produce a health report for the whole namespace")
+	list := flag.String("list", "", "Print target names for shell completion: 'namespaces' or 'pods' (with -namespace)")
+	feedback := flag.Int64("feedback", 0, "ID of a stored analysis whose root cause is wrong; re-runs it on the server with -hint")
+	feedbackVersion := flag.Int("feedback-version", 0, "Analysis version judged wrong with -feedback (default: latest)")
+	hint := flag.String("hint", "", "Correction hint for -feedback, e.g. 'the database was failing over'")
+	postmortem := flag.String("postmortem", "", "Print a postmortem of the analyses stored on the server: 'daily' or 'weekly' (optionally with -namespace)")
+	follow := flag.String("follow", "", "ID of an analysis job queued on the server (?async=true); streams its progress and prints the result")
+	server := flag.String("server", "http://localhost:8080", "Server URL for -feedback, -postmortem and -follow")
+	apiKey := flag.String("api-key", os.Getenv("HEPSRE_API_KEY"), "API key sent to -server (default $HEPSRE_API_KEY)")
 
 	flag.Parse()
+	serverAPIKey = *apiKey
+
+	if *follow != "" {
+		if err := followJob(*server, *follow, *outputFormat, *noColor); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
 
-	if *namespace == "" || *pod == "" {
-		log.Fatal("Both -namespace and -pod flags are required")
+	if *feedback != 0 {
+		if *hint == "" {
+			log.Fatal("The -feedback flag requires -hint")
+		}
+		if err := submitFeedback(*server, *feedback, *feedbackVersion, *hint, *outputFormat); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if *postmortem != "" {
+		if err := printPostmortem(*server, *postmortem, *namespace, *outputFormat); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if *list != "" {
+		if err := listTargets(*configPath, *list, *namespace); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	picked := *selector != "" || *auto
+	if *health {
+		if *pod != "" || *deployment != "" || picked {
+			log.Fatal("The -health flag cannot be combined with -pod, -deployment, -selector or -auto")
+		}
+	} else if countTrue(*pod != "", *deployment != "", picked) != 1 {
+		log.Fatal("Exactly one of -pod, -deployment or -selector/-auto is required")
+	}
+
+	opts := analysisOptions{
+		namespace:    *namespace,
+		pod:          *pod,
+		deployment:   *deployment,
+		selector:     *selector,
+		auto:         *auto,
+		health:       *health,
+		lookback:     *lookback,
+		verbosity:    *verbosity,
+		configPath:   *configPath,
+		outputFormat: *outputFormat,
+		output:       *output,
+		noColor:      *noColor,
+	}
+	if err := resolveOutputFormat(&opts, flagSet(flag.CommandLine, "format")); err != nil {
+		log.Fatal(err)
+	}
+	if err := runAnalysis(opts); err != nil {
+		log.Fatal(err)
 	}
+}
+
+// countTrue counts the conditions that hold
+func countTrue(conds ...bool) int {
+	n := 0
+	for _, c := range conds {
+		if c {
+			n++
+		}
+	}
+	return n
+}
 
-	// Parse lookback duration
-	lookbackDuration, err := time.ParseDuration(*lookback)
+// listTargets prints allowed namespace or pod names one per line
+func listTargets(configPath, kind, namespace string) error {
+	cfg, err := config.Load(configPath)
 	if err != nil {
-		log.Fatalf("Invalid lookback duration: %v", err)
+		return fmt.Errorf("failed to load config: %w", err)
 	}
 
-	// Initialize logger
-	logger, err := zap.NewDevelopment()
+	k8s, err := collectors.NewKubernetesCollector(cfg)
 	if err != nil {
-		log.Fatalf("Failed to initialize logger: %v", err)
+		return fmt.Errorf("failed to create k8s collector: %w", err)
 	}
-	defer logger.Sync()
+	defer k8s.Close()
+
+	ctx := context.Background()
+	switch kind {
+	case "namespaces":
+		namespaces, err := k8s.ListNamespaces(ctx)
+		if err != nil {
+			return err
+		}
+		for _, ns := range namespaces {
+			fmt.Println(ns)
+		}
+	case "pods":
+		if namespace == "" {
+			return fmt.Errorf("-list pods requires -namespace")
+		}
+		pods, err := k8s.ListPods(ctx, namespace)
+		if err != nil {
+			return err
+		}
+		for _, pod := range pods {
+			fmt.Println(pod.Name)
+		}
+	default:
+		return fmt.Errorf("unknown -list value %q (want namespaces or pods)", kind)
+	}
+	return nil
+}
+
+// serverAPIKey is sent with every request to the server, if set
+var serverAPIKey string
+
+// serverRequest sends a request to the server with the API key. Bodies are
+// JSON.
+func serverRequest(method, target string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequest(method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if serverAPIKey != "" {
+		req.Header.Set("X-API-Key", serverAPIKey)
+	}
+	return http.DefaultClient.Do(req)
+}
+
+// submitFeedback posts a correction of a stored analysis to the server and
+// prints the re-analysis it triggered
+func submitFeedback(server string, id int64, version int, hint, outputFormat string) error {
+	body, err := json.Marshal(map[string]any{"version": version, "hint": hint})
+	if err != nil {
+		return fmt.Errorf("failed to marshal feedback: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/api/v1/analyses/%d/feedback", strings.TrimSuffix(server, "/"), id)
+	resp, err := serverRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to submit feedback: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("server returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	if outputFormat == "json" {
+		fmt.Println(string(data))
+		return nil
+	}
+
+	var out struct {
+		Version         int             `json:"version"`
+		CorrectsVersion int             `json:"corrects_version"`
+		Analysis        models.Analysis `json:"analysis"`
+	}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	fmt.Printf("Version %d (corrects version %d), confidence %s:\n%s\n",
+		out.Version, out.CorrectsVersion, out.Analysis.Confidence, out.Analysis.RootCause)
+	return nil
+}
+
+// printPostmortem fetches a postmortem of the stored analyses from the
+// server and prints it as markdown, or as the server's JSON with -format json
+func printPostmortem(server, period, namespace, outputFormat string) error {
+	format := "markdown"
+	if outputFormat == "json" {
+		format = "json"
+	}
+	query := url.Values{"period": {period}, "format": {format}}
+	if namespace != "" {
+		query.Set("namespace", namespace)
+	}
+
+	resp, err := serverRequest(http.MethodGet, strings.TrimSuffix(server, "/")+"/api/v1/reports/postmortem?"+query.Encode(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to fetch postmortem: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("server returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	fmt.Println(strings.TrimRight(string(data), "\n"))
+	return nil
+}
+
+// followJob streams the progress of an analysis job from the server to
+// stderr and prints the job's result once it finishes
+func followJob(server, jobID, outputFormat string, noColor bool) error {
+	resp, err := serverRequest(http.MethodGet, strings.TrimSuffix(server, "/")+"/api/v1/jobs/"+url.PathEscape(jobID)+"/stream", nil)
+	if err != nil {
+		return fmt.Errorf("failed to follow job: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("server returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+
+	type jobBody struct {
+		Job struct {
+			Status string          `json:"status"`
+			Stage  string          `json:"stage"`
+			Error  string          `json:"error"`
+			Result json.RawMessage `json:"result"`
+		} `json:"job"`
+	}
+	quiet := outputFormat == "json"
+	counting := false
+	progress := func(format string, args ...any) {
+		if quiet {
+			return
+		}
+		if counting {
+			fmt.Fprintln(os.Stderr)
+			counting = false
+		}
+		fmt.Fprintf(os.Stderr, format+"\n", args...)
+	}
+
+	// Results can be far larger than bufio's default line limit
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	var event, data string
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+			continue
+		case strings.HasPrefix(line, "data:"):
+			data += strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " ")
+			continue
+		case line != "":
+			// Comments (keepalives) and unknown fields
+			continue
+		}
+
+		switch event {
+		case "status":
+			var body jobBody
+			if err := json.Unmarshal([]byte(data), &body); err == nil {
+				progress("Job %s: %s", body.Job.Status, body.Job.Stage)
+			}
+		case "stage":
+			var body struct {
+				Stage string `json:"stage"`
+			}
+			if err := json.Unmarshal([]byte(data), &body); err == nil {
+				progress("%s", body.Stage)
+			}
+		case "token":
+			var body struct {
+				Received int `json:"received"`
+			}
+			if err := json.Unmarshal([]byte(data), &body); err == nil && !quiet {
+				fmt.Fprintf(os.Stderr, "\r  %d characters received", body.Received)
+				counting = true
+			}
+		case "done":
+			progress("Job finished")
+			var body jobBody
+			if err := json.Unmarshal([]byte(data), &body); err != nil {
+				return fmt.Errorf("failed to parse job: %w", err)
+			}
+			if body.Job.Status != "completed" {
+				return fmt.Errorf("analysis job %s: %s", body.Job.Status, body.Job.Error)
+			}
+			return printJobResult(body.Job.Result, outputFormat, noColor)
+		}
+		event, data = "", ""
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read job stream: %w", err)
+	}
+	return fmt.Errorf("job stream ended before the job finished")
+}
+
+// printJobResult prints the result of a finished job: pod and workload
+// analyses as a report, anything else as JSON
+func printJobResult(raw json.RawMessage, outputFormat string, noColor bool) error {
+	var result models.AnalysisResult
+	if outputFormat != "json" && json.Unmarshal(raw, &result) == nil && result.Analysis.RootCause != "" {
+		fmt.Println(formatter.NewFormatter(!noColor).FormatAnalysisResult(&result))
+		return nil
+	}
+
+	var out bytes.Buffer
+	if err := json.Indent(&out, raw, "", "  "); err != nil {
+		return fmt.Errorf("failed to format result: %w", err)
+	}
+	fmt.Println(out.String())
+	return nil
+}
+
+// runMigrate implements "hepsre migrate": it applies the pending schema
+// migrations of the configured database, or with -to reverts those above a
+// version, and prints the resulting version
+func runMigrate(args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	to := fs.Int("to", -1, "Schema version to migrate to, reverting newer migrations (default: the latest)")
+	configPath := fs.String("config", "", "Path to config file")
+	fs.Parse(args)
 
-	// Load configuration
 	cfg, err := config.Load(*configPath)
 	if err != nil {
-		logger.Fatal("Failed to load config", zap.Error(err))
+		return fmt.Errorf("failed to load config: %w", err)
 	}
+	// Opening the database applies the pending migrations
+	db, err := database.Open(cfg.Database, nil)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
 
-	// Initialize agent
-	agentInstance, err := agent.NewAgent(cfg, logger)
+	if *to >= 0 {
+		if err := db.Migrate(*to); err != nil {
+			return err
+		}
+	}
+	current, latest, err := db.SchemaVersion()
 	if err != nil {
-		logger.Fatal("Failed to create agent", zap.Error(err))
+		return err
 	}
+	fmt.Printf("Schema version %d (latest %d)\n", current, latest)
+	return nil
+}
 
-	// Set up progress reporting based on output format
-	var progress *ui.SpinnerProgress
-	if *outputFormat != "json" && !*noColor {
-		// Normal mode: animated spinner
-		progress = ui.NewSpinnerProgress()
-		agentInstance.SetProgressReporter(progress)
-		progress.Start("Initializing analysis...")
-	} else if *outputFormat != "json" {
-		// No-color mode: simple text
-		fmt.Printf("Analyzing pod %s/%s (lookback: %s)...\n", *namespace, *pod, *lookback)
-		agentInstance.SetProgressReporter(&agent.NoOpProgressReporter{})
-	} else {
-		// JSON mode: completely silent
-		agentInstance.SetProgressReporter(&agent.NoOpProgressReporter{})
+// runDB implements "hepsre db export" and "hepsre db import", which back
+// up the configured database and restore a backup into it
+func runDB(args []string) error {
+	if len(args) == 0 || (args[0] != "export" && args[0] != "import") {
+		return fmt.Errorf("usage: hepsre db export|import [flags]")
+	}
+	command := args[0]
+	fs := flag.NewFlagSet("db "+command, flag.ExitOnError)
+	configPath := fs.String("config", "", "Path to config file")
+	format := fs.String("format", database.BackupJSONL, "Backup format for export: 'jsonl' (loads into any database) or 'sql' (only into the same kind)")
+	file := fs.String("file", "-", "Backup file to write or read, '-' for stdout or stdin")
+	fs.Parse(args[1:])
+	if command == "export" && *format != database.BackupJSONL && *format != database.BackupSQL {
+		return fmt.Errorf("invalid -format %q: use jsonl or sql", *format)
 	}
 
-	// Run analysis
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	// Backups copy analysis results as stored, sealed or not, and need no
+	// encryption key
+	db, err := database.Open(cfg.Database, nil)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
 	ctx := context.Background()
-	result, err := agentInstance.AnalyzeAlert(ctx, agent.AnalysisRequest{
-		Namespace: *namespace,
-		PodName:   *pod,
-		Lookback:  lookbackDuration,
-	})
+	if command == "export" {
+		out := os.Stdout
+		if *file != "-" {
+			if out, err = os.Create(*file); err != nil {
+				return err
+			}
+		}
+		if err := db.Export(ctx, out, *format); err != nil {
+			out.Close()
+			return err
+		}
+		return out.Close()
+	}
+
+	in := os.Stdin
+	if *file != "-" {
+		if in, err = os.Open(*file); err != nil {
+			return err
+		}
+		defer in.Close()
+	}
+	n, err := db.Import(ctx, in)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Imported %d rows\n", n)
+	return nil
+}
+
+// runEval implements "hepsre eval": it replays a directory of recorded
+// incidents against each model and prints root-cause accuracy and JSON
+// validity per model
+func runEval(args []string) error {
+	fs := flag.NewFlagSet("eval", flag.ExitOnError)
+	fixturesDir := fs.String("fixtures", "", "Directory of incident fixtures, one subdirectory per incident")
+	modelList := fs.String("models", "", "Comma-separated provider/model pairs to compare, e.g. 'anthropic/claude-sonnet-4-20250514,openai/gpt-4o' (default: the configured model)")
+	configPath := fs.String("config", "", "Path to config file")
+	outputFormat := fs.String("format", "pretty", "Output format: 'pretty' or 'json'")
+	minAccuracy := fs.Float64("min-accuracy", 0, "Fail if any model's root-cause accuracy is below this (0-1)")
+	fs.Parse(args)
 
-	// Ensure spinner is stopped before output
-	if progress != nil {
-		progress.Stop()
+	if *fixturesDir == "" {
+		return fmt.Errorf("the -fixtures flag is required")
 	}
 
+	fixtures, err := eval.LoadFixtures(*fixturesDir)
+	if err != nil {
+		return err
+	}
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	targets, err := eval.ParseTargets(*modelList)
 	if err != nil {
-		logger.Fatal("Analysis failed", zap.Error(err))
+		return err
+	}
+	if len(targets) == 0 {
+		targets = []eval.Target{{Provider: cfg.LLM.Provider, Model: cfg.LLM.Model}}
+	}
+
+	// Only warnings, such as unparseable responses, are of interest here
+	logConfig := zap.NewDevelopmentConfig()
+	logConfig.Level = zap.NewAtomicLevelAt(zap.WarnLevel)
+	logger, err := logConfig.Build()
+	if err != nil {
+		return fmt.Errorf("failed to initialize logger: %w", err)
+	}
+	defer logger.Sync()
+
+	ctx := context.Background()
+	var reports []*eval.Report
+	for _, target := range targets {
+		if *outputFormat != "json" {
+			fmt.Fprintf(os.Stderr, "Evaluating %s on %d fixtures...\n", target, len(fixtures))
+		}
+		agentInstance, err := agent.NewOfflineAgent(eval.ConfigFor(cfg, target), logger)
+		if err != nil {
+			return fmt.Errorf("failed to create agent for %s: %w", target, err)
+		}
+		reports = append(reports, eval.Run(ctx, agentInstance, target, fixtures))
 	}
 
-	// Output result
 	if *outputFormat == "json" {
-		// JSON output
-		output, err := json.MarshalIndent(result, "", "  ")
+		output, err := json.MarshalIndent(reports, "", "  ")
 		if err != nil {
-			logger.Fatal("Failed to marshal result", zap.Error(err))
+			return fmt.Errorf("failed to marshal reports: %w", err)
 		}
 		fmt.Println(string(output))
 	} else {
-		// Pretty formatted output
-		outputFormatter := formatter.NewFormatter(!*noColor)
-		formattedOutput := outputFormatter.FormatAnalysisResult(result)
-		fmt.Println(formattedOutput)
+		printEvalReports(reports)
+	}
+
+	for _, r := range reports {
+		if r.Accuracy < *minAccuracy {
+			return fmt.Errorf("%s accuracy %.0f%% is below the minimum of %.0f%%", r.Target, r.Accuracy*100, *minAccuracy*100)
+		}
+	}
+	return nil
+}
+
+// printEvalReports prints the per-fixture results of each model, then a
+// summary table
+func printEvalReports(reports []*eval.Report) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for _, r := range reports {
+		fmt.Fprintf(w, "\n%s (prompt %s)\n", r.Target, r.PromptVersion)
+		for _, c := range r.Cases {
+			verdict := "FAIL"
+			if c.Correct {
+				verdict = "ok"
+			}
+			detail := c.RootCause
+			if c.Error != "" {
+				verdict, detail = "ERROR", c.Error
+			} else if c.LLM && !c.ValidJSON {
+				detail += " (invalid JSON)"
+			}
+			fmt.Fprintf(w, "  %s\t%s\t%.2f\t%s\t%s\n", verdict, c.Fixture, c.Score, c.Duration, detail)
+		}
+	}
+	w.Flush()
+
+	fmt.Println()
+	w = tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "MODEL\tACCURACY\tJSON VALID\tERRORS")
+	for _, r := range reports {
+		jsonValid := "-"
+		if r.LLMResponses > 0 {
+			jsonValid = fmt.Sprintf("%d/%d (%.0f%%)", r.ValidJSON, r.LLMResponses, r.JSONValidity*100)
+		}
+		fmt.Fprintf(w, "%s\t%d/%d (%.0f%%)\t%s\t%d\n", r.Target, r.Correct, r.Total, r.Accuracy*100, jsonValid, r.Errors)
 	}
+	w.Flush()
 }
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/emirozbir/micro-sre/internal/formatter"
+	"github.com/emirozbir/micro-sre/internal/models"
+)
+
+// Report formats. Pretty is the terminal report, colored unless written to
+// a file or with -no-color.
+const (
+	formatPretty   = "pretty"
+	formatJSON     = "json"
+	formatMarkdown = "markdown"
+	formatCSV      = "csv"
+	formatPDF      = "pdf"
+)
+
+// formatsByExtension selects the format of an -output file unless -format
+// is given; other files get the pretty report without colors
+var formatsByExtension = map[string]string{
+	".json":     formatJSON,
+	".md":       formatMarkdown,
+	".markdown": formatMarkdown,
+	".csv":      formatCSV,
+	".pdf":      formatPDF,
+}
+
+// registerOutput adds the -format, -output and -o flags to fs
+func registerOutput(fs *flag.FlagSet, opts *analysisOptions) {
+	fs.StringVar(&opts.outputFormat, "format", formatPretty, "Output format: 'pretty', 'json', 'markdown', 'csv' or 'pdf' (default from the -output extension)")
+	fs.StringVar(&opts.output, "output", "", "File to write the report to instead of stdout; .md, .json, .csv and .pdf select the format")
+	fs.StringVar(&opts.output, "o", "", "File to write the report to (shorthand)")
+}
+
+// resolveOutputFormat picks the format of opts.output by its extension
+// unless -format was given, and checks the format. formatSet reports
+// whether -format was given.
+func resolveOutputFormat(opts *analysisOptions, formatSet bool) error {
+	if opts.output != "" && !formatSet {
+		opts.outputFormat = formatPretty
+		if format, ok := formatsByExtension[strings.ToLower(filepath.Ext(opts.output))]; ok {
+			opts.outputFormat = format
+		}
+	}
+	switch opts.outputFormat {
+	case formatPretty, formatJSON, formatMarkdown, formatCSV, formatPDF:
+		return nil
+	}
+	return fmt.Errorf("invalid -format %q: use pretty, json, markdown, csv or pdf", opts.outputFormat)
+}
+
+// flagSet reports whether the named flag was given on fs
+func flagSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// quietProgress reports whether progress must stay off stdout because a
+// machine-readable report is written there
+func quietProgress(opts analysisOptions) bool {
+	return opts.outputFormat != formatPretty && opts.output == ""
+}
+
+// renderResult renders an analysis result in format; id is the ID of a
+// stored analysis, 0 otherwise
+func renderResult(id int64, result *models.AnalysisResult, format string, colors bool) ([]byte, error) {
+	switch format {
+	case formatJSON:
+		output, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal result: %w", err)
+		}
+		return append(output, '\n'), nil
+	case formatMarkdown:
+		return []byte(formatter.AnalysisMarkdown(id, result)), nil
+	case formatCSV:
+		var out bytes.Buffer
+		if err := formatter.AnalysisCSV(&out, id, result); err != nil {
+			return nil, fmt.Errorf("failed to write CSV: %w", err)
+		}
+		return out.Bytes(), nil
+	case formatPDF:
+		return formatter.AnalysisPDF(id, result), nil
+	}
+	return []byte(formatter.NewFormatter(colors).FormatAnalysisResult(result) + "\n"), nil
+}
+
+// writeOutput writes a report to the -output file, or to stdout if there
+// is none
+func writeOutput(path string, data []byte) error {
+	if path == "" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "Wrote report to %s\n", path)
+	return nil
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/emirozbir/micro-sre/internal/agent"
+	"github.com/emirozbir/micro-sre/internal/collectors"
+	"github.com/emirozbir/micro-sre/internal/config"
+	"github.com/emirozbir/micro-sre/internal/models"
+)
+
+// Watch sources
+const (
+	watchAlertManager = "alertmanager"
+	watchPods         = "pods"
+)
+
+// notifyTimeout bounds a -notify command
+const notifyTimeout = 30 * time.Second
+
+// watcher analyzes alerts as they fire, one at a time, and prints each
+// report as it is ready
+type watcher struct {
+	agent  *agent.Agent
+	cfg    *config.Config
+	opts   analysisOptions
+	notify string
+
+	lookback time.Duration
+	// seen holds the alert fingerprints, or pods, already analyzed and
+	// when; entries are forgotten once the alert resolves or the cooldown
+	// passes
+	seen map[string]time.Time
+}
+
+// runWatch implements "hepsre watch": it polls AlertManager, or watches
+// pods, and analyzes every new relevant alert until interrupted
+func runWatch(args []string) error {
+	fs := flag.NewFlagSet("watch", flag.ExitOnError)
+	var opts analysisOptions
+	fs.StringVar(&opts.namespace, "namespace", "", "Namespace to watch (default: all allowed namespaces)")
+	fs.StringVar(&opts.namespace, "n", "", "Namespace to watch (shorthand)")
+	source := fs.String("source", watchAlertManager, "What to watch: 'alertmanager' (firing alerts) or 'pods' (containers that crash loop, are OOMKilled or restart too often)")
+	interval := fs.Duration("interval", 0, "AlertManager poll interval (default alertmanager.poll_interval)")
+	cooldown := fs.Duration("cooldown", 0, "With -source pods, analyze a pod at most once per cooldown (default kubernetes.watch.cooldown)")
+	notify := fs.String("notify", "", "Shell command run after each analysis, with the result as JSON on stdin and HEPSRE_* variables set")
+	fs.StringVar(&opts.lookback, "lookback", "1h", "Time range to look back, unless an alert sets one")
+	fs.StringVar(&opts.verbosity, "verbosity", "", "Report verbosity: 'brief', 'standard' or 'deep' (default from the alert's report route)")
+	fs.StringVar(&opts.configPath, "config", "", "Path to config file")
+	fs.StringVar(&opts.outputFormat, "format", formatPretty, "Output format: 'pretty', 'json' or 'markdown'")
+	fs.BoolVar(&opts.noColor, "no-color", false, "Disable colored output")
+	fs.Parse(args)
+
+	switch opts.outputFormat {
+	case formatPretty, formatJSON, formatMarkdown:
+	default:
+		return fmt.Errorf("invalid -format %q: use pretty, json or markdown", opts.outputFormat)
+	}
+	if *source != watchAlertManager && *source != watchPods {
+		return fmt.Errorf("invalid -source %q: use alertmanager or pods", *source)
+	}
+	if opts.verbosity != "" && !config.ValidVerbosity(opts.verbosity) {
+		return fmt.Errorf("invalid verbosity %q: use brief, standard or deep", opts.verbosity)
+	}
+	lookback, err := time.ParseDuration(opts.lookback)
+	if err != nil {
+		return fmt.Errorf("invalid lookback duration: %w", err)
+	}
+
+	agentInstance, cfg, err := newAgent(opts.configPath)
+	if err != nil {
+		return err
+	}
+	defer agentInstance.Close()
+	if opts.namespace != "" && !agentInstance.Kubernetes().NamespaceAllowed(opts.namespace) {
+		return collectors.ErrNamespaceNotAllowed
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	w := &watcher{
+		agent:    agentInstance,
+		cfg:      cfg,
+		opts:     opts,
+		notify:   *notify,
+		lookback: lookback,
+		seen:     make(map[string]time.Time),
+	}
+	where := "all namespaces"
+	if opts.namespace != "" {
+		where = "namespace " + opts.namespace
+	}
+
+	if *source == watchPods {
+		if *cooldown == 0 {
+			*cooldown = cfg.Kubernetes.Watch.Cooldown
+		}
+		fmt.Fprintf(os.Stderr, "Watching pods in %s (Ctrl-C to stop)\n", where)
+		return w.watchPods(ctx, *cooldown)
+	}
+	if *interval == 0 {
+		*interval = cfg.AlertManager.PollInterval
+	}
+	if *interval <= 0 {
+		return fmt.Errorf("-interval must be positive")
+	}
+	fmt.Fprintf(os.Stderr, "Watching AlertManager alerts in %s every %s (Ctrl-C to stop)\n", where, *interval)
+	return w.pollAlerts(ctx, *interval)
+}
+
+// pollAlerts analyzes the new firing alerts of every poll. Each alert is
+// analyzed once per firing: its fingerprint is remembered until the alert
+// stops firing.
+func (w *watcher) pollAlerts(ctx context.Context, interval time.Duration) error {
+	am := w.agent.AlertManager()
+	filter := w.agent.AlertFilter()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		alerts, err := am.GetActiveAlerts(ctx)
+		if err != nil && ctx.Err() == nil {
+			fmt.Fprintf(os.Stderr, "Failed to poll AlertManager: %v\n", err)
+		}
+
+		firing := make(map[string]bool, len(alerts))
+		var fresh []models.Alert
+		for _, alert := range alerts {
+			if alert.Fingerprint == "" || (w.opts.namespace != "" && alert.GetNamespace() != w.opts.namespace) {
+				continue
+			}
+			firing[alert.Fingerprint] = true
+			if _, ok := w.seen[alert.Fingerprint]; ok {
+				continue
+			}
+			w.seen[alert.Fingerprint] = time.Now()
+			switch {
+			case alert.Skip():
+			case filter.Reject(&alert) != "":
+			case alert.GetPodName() == "" || !w.agent.Kubernetes().NamespaceAllowed(alert.GetNamespace()):
+			default:
+				fresh = append(fresh, alert)
+			}
+		}
+		// Forget resolved alerts so that they are analyzed again if they refire
+		if err == nil {
+			for fingerprint := range w.seen {
+				if !firing[fingerprint] {
+					delete(w.seen, fingerprint)
+				}
+			}
+		}
+
+		for i := range fresh {
+			if ctx.Err() != nil {
+				return nil
+			}
+			w.analyze(ctx, &fresh[i])
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// watchPods analyzes pods as they start failing, each at most once per
+// cooldown. Triggers become alerts named after their reason, e.g.
+// PodOOMKilled, like those of the server's pod watch.
+func (w *watcher) watchPods(ctx context.Context, cooldown time.Duration) error {
+	triggers := make(chan collectors.PodTrigger, 64)
+	var namespaces []string
+	if w.opts.namespace != "" {
+		namespaces = []string{w.opts.namespace}
+	}
+	watchErr := make(chan error, 1)
+	go func() {
+		watchErr <- w.agent.Kubernetes().WatchPods(ctx, namespaces, w.cfg.Kubernetes.Watch.RestartThreshold, func(t collectors.PodTrigger) {
+			select {
+			case triggers <- t:
+			default:
+				fmt.Fprintf(os.Stderr, "Busy, dropping %s of pod %s/%s\n", t.Reason, t.Namespace, t.Pod)
+			}
+		})
+	}()
+
+	for {
+		select {
+		case err := <-watchErr:
+			return err
+		case t := <-triggers:
+			if !w.agent.Kubernetes().NamespaceAllowed(t.Namespace) {
+				continue
+			}
+			key := t.Namespace + "/" + t.Pod
+			now := time.Now()
+			for k, at := range w.seen {
+				if now.Sub(at) >= cooldown {
+					delete(w.seen, k)
+				}
+			}
+			if _, ok := w.seen[key]; ok {
+				continue
+			}
+			w.seen[key] = now
+
+			alert := models.Alert{
+				Labels: map[string]string{
+					"alertname": "Pod" + t.Reason,
+					"namespace": t.Namespace,
+					"pod":       t.Pod,
+					"container": t.Container,
+					"severity":  w.cfg.Kubernetes.Watch.Severity,
+				},
+				Annotations: map[string]string{
+					"summary": "Container " + t.Container + " of pod " + t.Pod + " triggered " + t.Reason,
+				},
+				StartsAt: now,
+				Status:   "firing",
+			}
+			w.analyze(ctx, &alert)
+		}
+	}
+}
+
+// analyze analyzes the pod of an alert, prints the report and runs the
+// -notify command. Failures are reported and the watch goes on.
+func (w *watcher) analyze(ctx context.Context, alert *models.Alert) {
+	if w.opts.outputFormat != "json" {
+		fmt.Fprintf(os.Stderr, "%s %s fired on pod %s/%s\n",
+			time.Now().Format("15:04:05"), alert.GetAlertName(), alert.GetNamespace(), alert.GetPodName())
+	}
+	opts := w.opts
+	opts.namespace = alert.GetNamespace()
+	opts.pod = alert.GetPodName()
+	opts.alert = alert
+
+	result, err := analyzeTarget(ctx, w.agent, w.cfg, opts, w.lookback)
+	if err != nil {
+		if ctx.Err() == nil {
+			fmt.Fprintf(os.Stderr, "Analysis of pod %s/%s failed: %v\n", opts.namespace, opts.pod, err)
+		}
+		return
+	}
+	if err := printResult(0, result, opts); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	if w.notify != "" {
+		if err := runNotify(ctx, w.notify, result); err != nil {
+			fmt.Fprintf(os.Stderr, "Notify command failed: %v\n", err)
+		}
+	}
+}
+
+// runNotify runs the -notify command through the shell with the result as
+// JSON on stdin and its gist in HEPSRE_* environment variables
+func runNotify(ctx context.Context, command string, result *models.AnalysisResult) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("failed to marshal result: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, notifyTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(),
+		"HEPSRE_ALERT="+result.Alert.Name,
+		"HEPSRE_SEVERITY="+result.Alert.Severity,
+		"HEPSRE_NAMESPACE="+result.Alert.Namespace,
+		"HEPSRE_POD="+result.Alert.Pod,
+		"HEPSRE_ROOT_CAUSE="+result.Analysis.RootCause,
+		"HEPSRE_CONFIDENCE="+result.Analysis.Confidence,
+	)
+	return cmd.Run()
+}
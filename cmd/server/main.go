@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
@@ -13,8 +14,13 @@ import (
 
 	"github.com/emirozbir/micro-sre/internal/agent"
 	"github.com/emirozbir/micro-sre/internal/api"
+	"github.com/emirozbir/micro-sre/internal/artifacts"
+	"github.com/emirozbir/micro-sre/internal/collectors"
 	"github.com/emirozbir/micro-sre/internal/config"
 	"github.com/emirozbir/micro-sre/internal/database"
+	"github.com/emirozbir/micro-sre/internal/encryption"
+	"github.com/emirozbir/micro-sre/internal/tracing"
+	"github.com/emirozbir/micro-sre/internal/version"
 )
 
 func main() {
@@ -31,52 +37,265 @@ func main() {
 		logger.Fatal("Failed to load config", zap.Error(err))
 	}
 
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		logger.Fatal("Failed to set up tracing", zap.Error(err))
+	}
+
 	logger.Info("Starting micro-sre server",
-		zap.String("version", "0.1.0"),
+		zap.String("version", version.Version),
+		zap.Bool("read_only", cfg.ReadOnly),
 		zap.String("llm_provider", cfg.LLM.Provider),
 		zap.String("alertmanager", cfg.AlertManager.URL),
+		zap.Bool("informer_cache", cfg.Kubernetes.Cache.Enabled),
+		zap.Int("api_keys", len(cfg.Server.APIKeys)),
+		zap.String("oidc_issuer", cfg.Server.OIDC.IssuerURL),
+		zap.Bool("tracing", cfg.Tracing.Enabled),
+		zap.Bool("encryption", cfg.Encryption.Key != "" || cfg.Encryption.KeyFile != ""),
 	)
+	if len(cfg.Server.APIKeys) == 0 && !cfg.Server.OIDC.Enabled() {
+		logger.Warn("No server.api_keys or server.oidc configured: the API and dashboard are open to anyone who can reach the server")
+	}
 
 	// Initialize agent
 	agentInstance, err := agent.NewAgent(cfg, logger)
 	if err != nil {
 		logger.Fatal("Failed to create agent", zap.Error(err))
 	}
+	defer agentInstance.Close()
 
 	// Initialize database
-	db, err := database.New(cfg.Database.Path)
+	cipher, err := encryption.New(cfg.Encryption)
+	if err != nil {
+		logger.Fatal("Failed to load encryption key", zap.Error(err))
+	}
+	db, err := database.Open(cfg.Database, cipher)
 	if err != nil {
 		logger.Fatal("Failed to initialize database", zap.Error(err))
 	}
 	defer db.Close()
-	logger.Info("Database initialized", zap.String("path", cfg.Database.Path))
+	if cfg.Database.Driver == "postgres" {
+		logger.Info("Database initialized", zap.String("driver", cfg.Database.Driver))
+	} else {
+		logger.Info("Database initialized", zap.String("driver", cfg.Database.Driver), zap.String("path", cfg.Database.Path))
+	}
+
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+	go runRollups(backgroundCtx, db, cfg.Database, logger)
+	go runRetention(backgroundCtx, db, cfg.Database.Retention, logger)
+	go runArtifactRetention(backgroundCtx, agentInstance.Artifacts(), cfg.Artifacts, logger)
 
 	// Setup HTTP server
 	handler := api.NewHandler(agentInstance, logger, db)
 	router := api.SetupRoutes(handler)
 
+	if cfg.AlertManager.Poll.Enabled {
+		if cfg.AlertManager.URL == "" {
+			logger.Fatal("alertmanager.poll requires alertmanager.url")
+		}
+		if cfg.AlertManager.PollInterval <= 0 {
+			logger.Fatal("alertmanager.poll requires a positive alertmanager.poll_interval")
+		}
+		matchers, err := collectors.ParseAlertMatchers(cfg.AlertManager.Poll.Matchers)
+		if err != nil {
+			logger.Fatal("Invalid alertmanager.poll.matchers", zap.Error(err))
+		}
+
+		poller := &alertPoller{
+			am:       agentInstance.AlertManager(),
+			handler:  handler,
+			matchers: matchers,
+			interval: cfg.AlertManager.PollInterval,
+			logger:   logger,
+			seen:     map[string]bool{},
+		}
+		go poller.run(backgroundCtx)
+		logger.Info("AlertManager poller started", zap.Duration("interval", cfg.AlertManager.PollInterval))
+	}
+
+	if cfg.Kubernetes.Watch.Enabled {
+		watcher := &podWatcher{
+			agent:     agentInstance,
+			handler:   handler,
+			cfg:       cfg.Kubernetes.Watch,
+			logger:    logger,
+			triggered: map[string]time.Time{},
+		}
+		go watcher.run(backgroundCtx)
+		logger.Info("Pod watch started", zap.Strings("namespaces", cfg.Kubernetes.Watch.Namespaces))
+	}
+
 	// Start server
 	addr := fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port)
-	logger.Info("Server listening", zap.String("address", addr))
+	srv := &http.Server{Addr: addr, Handler: router}
+	srv.RegisterOnShutdown(handler.StopStreams)
+	scheme := "http"
+	if cfg.Server.TLS.Enabled() {
+		certs, err := newCertReloader(cfg.Server.TLS, logger)
+		if err != nil {
+			logger.Fatal("Failed to load TLS certificate", zap.Error(err))
+		}
+		go certs.run(backgroundCtx, cfg.Server.TLS.ReloadInterval)
+		srv.TLSConfig = certs.tlsConfig()
+		scheme = "https"
+	}
+	logger.Info("Server listening",
+		zap.String("address", addr),
+		zap.String("scheme", scheme),
+		zap.Bool("client_certs", cfg.Server.TLS.ClientCAFile != ""))
 
 	// Graceful shutdown
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 
 	go func() {
-		if err := router.Run(addr); err != nil {
+		var err error
+		if srv.TLSConfig != nil {
+			// The certificate comes from TLSConfig, so no files are passed
+			err = srv.ListenAndServeTLS("", "")
+		} else {
+			err = srv.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
 			logger.Fatal("Failed to start server", zap.Error(err))
 		}
 	}()
 
 	<-quit
-	logger.Info("Shutting down server...")
+	logger.Info("Shutting down server...", zap.Duration("timeout", cfg.Server.ShutdownTimeout))
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
 	defer cancel()
 
-	// Perform cleanup
-	_ = ctx
+	// Stop taking new work: the poller and watcher stop triggering
+	// analyses, and the listener stops accepting requests. Requests in
+	// flight, including synchronous analyses, may finish until the deadline.
+	stopBackground()
+	if err := srv.Shutdown(ctx); err != nil {
+		logger.Warn("Requests still in flight at the shutdown deadline", zap.Error(err))
+		srv.Close()
+	}
+
+	// Let queued and running analysis jobs finish. They write their results
+	// to the database before finishing, so it is safe to close afterwards.
+	if err := handler.Shutdown(ctx); err != nil {
+		logger.Warn("Analysis jobs still running at the shutdown deadline", zap.Error(err))
+	}
+
+	// Traces get a moment of their own, the deadline may be spent by now
+	flushCtx, cancelFlush := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelFlush()
+	if err := shutdownTracing(flushCtx); err != nil {
+		logger.Warn("Failed to flush traces", zap.Error(err))
+	}
 
 	logger.Info("Server stopped")
 }
+
+// runRollups keeps the daily stats summary table current. The first run
+// backfills an empty table; later runs recompute the most recent days.
+func runRollups(ctx context.Context, db database.Store, cfg config.DatabaseConfig, logger *zap.Logger) {
+	if cfg.RollupInterval <= 0 {
+		logger.Info("daily stats rollup disabled")
+		return
+	}
+	days := max(cfg.RollupDays, 1)
+
+	ticker := time.NewTicker(cfg.RollupInterval)
+	defer ticker.Stop()
+	for {
+		started := time.Now()
+		n, err := db.RollupDailyStats(started.AddDate(0, 0, -(days - 1)))
+		if err != nil {
+			logger.Error("daily stats rollup failed", zap.Error(err))
+		} else {
+			logger.Debug("daily stats rolled up", zap.Int("rows", n), zap.Duration("took", time.Since(started)))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runRetention prunes the analyses beyond the retention limits, purges the
+// deleted analyses past the restore window and periodically vacuums the
+// database
+func runRetention(ctx context.Context, db database.Store, cfg config.RetentionConfig, logger *zap.Logger) {
+	if cfg.Interval <= 0 {
+		logger.Info("database retention job disabled")
+		return
+	}
+
+	var lastVacuum time.Time
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		started := time.Now()
+		if cfg.MaxAge > 0 || cfg.MaxRows > 0 {
+			var before time.Time
+			if cfg.MaxAge > 0 {
+				before = started.Add(-cfg.MaxAge)
+			}
+			n, err := db.PruneAnalyses(before, cfg.MaxRows)
+			if err != nil {
+				logger.Error("pruning analyses failed", zap.Error(err))
+			} else if n > 0 {
+				logger.Info("pruned analyses", zap.Int("deleted", n), zap.Duration("took", time.Since(started)))
+			}
+		}
+		if cfg.RestoreWindow > 0 {
+			n, err := db.PurgeDeletedAnalyses(started.Add(-cfg.RestoreWindow))
+			if err != nil {
+				logger.Error("purging deleted analyses failed", zap.Error(err))
+			} else if n > 0 {
+				logger.Info("purged deleted analyses", zap.Int("purged", n), zap.Duration("took", time.Since(started)))
+			}
+		}
+
+		if cfg.VacuumInterval > 0 && started.Sub(lastVacuum) >= cfg.VacuumInterval {
+			lastVacuum = started
+			if err := db.Vacuum(); err != nil {
+				logger.Error("database vacuum failed", zap.Error(err))
+			} else {
+				logger.Debug("database vacuumed", zap.Duration("took", time.Since(started)))
+			}
+		}
+	}
+}
+
+// runArtifactRetention deletes the collected artifacts not written for
+// artifacts.max_age. Analyses keep their references; fetching a deleted
+// artifact answers 404.
+func runArtifactRetention(ctx context.Context, store artifacts.Store, cfg config.ArtifactsConfig, logger *zap.Logger) {
+	if store == nil || cfg.MaxAge <= 0 || cfg.PruneInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(cfg.PruneInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		started := time.Now()
+		n, err := store.Prune(ctx, started.Add(-cfg.MaxAge))
+		if err != nil {
+			logger.Error("pruning artifacts failed", zap.Int("deleted", n), zap.Error(err))
+		} else if n > 0 {
+			logger.Info("pruned artifacts", zap.Int("deleted", n), zap.Duration("took", time.Since(started)))
+		}
+	}
+}
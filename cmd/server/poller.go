@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/emirozbir/micro-sre/internal/api"
+	"github.com/emirozbir/micro-sre/internal/collectors"
+	"github.com/emirozbir/micro-sre/internal/models"
+)
+
+// alertPoller analyzes firing AlertManager alerts without a webhook. Each
+// alert is analyzed once per firing: its fingerprint is remembered until
+// the alert stops firing.
+type alertPoller struct {
+	am       *collectors.AlertManagerCollector
+	handler  *api.Handler
+	matchers collectors.AlertMatchers
+	interval time.Duration
+	logger   *zap.Logger
+
+	// seen holds the fingerprints of firing alerts already queued
+	seen map[string]bool
+}
+
+// run polls until ctx is cancelled
+func (p *alertPoller) run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		p.poll(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll fetches the firing alerts and queues the new ones that match
+func (p *alertPoller) poll(ctx context.Context) {
+	alerts, err := p.am.GetActiveAlerts(ctx)
+	if err != nil {
+		p.logger.Warn("failed to poll alertmanager", zap.Error(err))
+		return
+	}
+
+	firing := make(map[string]bool, len(alerts))
+	var fresh []models.Alert
+	for _, alert := range alerts {
+		if alert.Fingerprint == "" || !p.matchers.Matches(alert.Labels) {
+			continue
+		}
+		firing[alert.Fingerprint] = true
+		if !p.seen[alert.Fingerprint] {
+			fresh = append(fresh, alert)
+		}
+	}
+
+	// Forget resolved alerts so that they are analyzed again if they refire
+	for fingerprint := range p.seen {
+		if !firing[fingerprint] {
+			delete(p.seen, fingerprint)
+		}
+	}
+
+	if len(fresh) == 0 {
+		return
+	}
+	if !p.handler.EnqueueAlerts("alertmanager-poller", fresh) {
+		// Leave them unseen so that the next poll retries
+		p.logger.Warn("analysis queue is full, deferring polled alerts", zap.Int("alerts", len(fresh)))
+		return
+	}
+	for _, alert := range fresh {
+		p.seen[alert.Fingerprint] = true
+	}
+	p.logger.Info("queued polled alerts for analysis", zap.Int("alerts", len(fresh)))
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/emirozbir/micro-sre/internal/config"
+)
+
+// certReloader serves the certificate and client CAs of server.tls,
+// re-reading them when their files change so renewals (e.g. by
+// cert-manager) apply without a restart
+type certReloader struct {
+	cfg    config.ServerTLSConfig
+	logger *zap.Logger
+
+	mu        sync.RWMutex
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+	modTimes  map[string]time.Time
+}
+
+func newCertReloader(cfg config.ServerTLSConfig, logger *zap.Logger) (*certReloader, error) {
+	r := &certReloader{cfg: cfg, logger: logger}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// files are the files the TLS configuration is read from
+func (r *certReloader) files() []string {
+	files := []string{r.cfg.CertFile, r.cfg.KeyFile}
+	if r.cfg.ClientCAFile != "" {
+		files = append(files, r.cfg.ClientCAFile)
+	}
+	return files
+}
+
+// load reads the certificate and client CAs
+func (r *certReloader) load() error {
+	modTimes := map[string]time.Time{}
+	for _, name := range r.files() {
+		info, err := os.Stat(name)
+		if err != nil {
+			return fmt.Errorf("failed to stat %s: %w", name, err)
+		}
+		modTimes[name] = info.ModTime()
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.cfg.CertFile, r.cfg.KeyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load server certificate: %w", err)
+	}
+	var clientCAs *x509.CertPool
+	if r.cfg.ClientCAFile != "" {
+		pem, err := os.ReadFile(r.cfg.ClientCAFile)
+		if err != nil {
+			return fmt.Errorf("failed to read client CA file: %w", err)
+		}
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates found in %s", r.cfg.ClientCAFile)
+		}
+	}
+
+	r.mu.Lock()
+	r.cert, r.clientCAs, r.modTimes = &cert, clientCAs, modTimes
+	r.mu.Unlock()
+	return nil
+}
+
+// changed reports whether any file was modified since it was loaded
+func (r *certReloader) changed() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, name := range r.files() {
+		if info, err := os.Stat(name); err == nil && !info.ModTime().Equal(r.modTimes[name]) {
+			return true
+		}
+	}
+	return false
+}
+
+// run reloads changed files every interval until ctx is done. A failed
+// reload, e.g. of a half-written file, keeps the previous configuration
+// and is retried on the next tick.
+func (r *certReloader) run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !r.changed() {
+				continue
+			}
+			if err := r.load(); err != nil {
+				r.logger.Warn("Failed to reload TLS certificate", zap.Error(err))
+				continue
+			}
+			r.logger.Info("Reloaded TLS certificate")
+		}
+	}
+}
+
+// tlsConfig returns a TLS configuration whose certificate and client CAs
+// follow the reloader
+func (r *certReloader) tlsConfig() *tls.Config {
+	minVersion := uint16(tls.VersionTLS12)
+	if r.cfg.MinVersion == "1.3" {
+		minVersion = tls.VersionTLS13
+	}
+	clientAuth := tls.NoClientCert
+	if r.cfg.ClientCAFile != "" {
+		clientAuth = tls.RequireAndVerifyClientCert
+		if r.cfg.ClientAuth == "request" {
+			clientAuth = tls.VerifyClientCertIfGiven
+		}
+	}
+
+	base := &tls.Config{MinVersion: minVersion}
+	base.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		r.mu.RLock()
+		defer r.mu.RUnlock()
+		return &tls.Config{
+			MinVersion:   minVersion,
+			NextProtos:   []string{"h2", "http/1.1"},
+			Certificates: []tls.Certificate{*r.cert},
+			ClientAuth:   clientAuth,
+			ClientCAs:    r.clientCAs,
+		}, nil
+	}
+	return base
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/emirozbir/micro-sre/internal/agent"
+	"github.com/emirozbir/micro-sre/internal/api"
+	"github.com/emirozbir/micro-sre/internal/collectors"
+	"github.com/emirozbir/micro-sre/internal/config"
+	"github.com/emirozbir/micro-sre/internal/models"
+)
+
+// podWatcher analyzes pods as they start failing, for clusters without
+// AlertManager. Triggers become alerts named after their reason, e.g.
+// PodOOMKilled, so that history and recurrence treat them like any alert.
+type podWatcher struct {
+	agent   *agent.Agent
+	handler *api.Handler
+	cfg     config.KubernetesWatchConfig
+	logger  *zap.Logger
+
+	mu sync.Mutex
+	// triggered holds when each pod was last queued, for the cooldown
+	triggered map[string]time.Time
+}
+
+// run watches pods until ctx is cancelled
+func (w *podWatcher) run(ctx context.Context) {
+	err := w.agent.Kubernetes().WatchPods(ctx, w.cfg.Namespaces, w.cfg.RestartThreshold, w.trigger)
+	if err != nil {
+		w.logger.Error("pod watch failed", zap.Error(err))
+	}
+}
+
+func (w *podWatcher) trigger(t collectors.PodTrigger) {
+	if !w.agent.Kubernetes().NamespaceAllowed(t.Namespace) {
+		return
+	}
+
+	key := t.Namespace + "/" + t.Pod
+	now := time.Now()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for k, at := range w.triggered {
+		if now.Sub(at) >= w.cfg.Cooldown {
+			delete(w.triggered, k)
+		}
+	}
+	if _, ok := w.triggered[key]; ok {
+		return
+	}
+
+	alert := models.Alert{
+		Labels: map[string]string{
+			"alertname": "Pod" + t.Reason,
+			"namespace": t.Namespace,
+			"pod":       t.Pod,
+			"container": t.Container,
+			"severity":  w.cfg.Severity,
+		},
+		Annotations: map[string]string{
+			"summary": "Container " + t.Container + " of pod " + t.Pod + " triggered " + t.Reason,
+		},
+		StartsAt: now,
+		Status:   "firing",
+	}
+	if !w.handler.EnqueueAlerts("pod-watch", []models.Alert{alert}) {
+		// Not marked, so the next change of the pod retries
+		w.logger.Warn("analysis queue is full, dropping pod trigger", zap.String("namespace", t.Namespace), zap.String("pod", t.Pod))
+		return
+	}
+	w.triggered[key] = now
+	w.logger.Info("pod triggered analysis",
+		zap.String("namespace", t.Namespace),
+		zap.String("pod", t.Pod),
+		zap.String("container", t.Container),
+		zap.String("reason", t.Reason),
+		zap.Int32("restarts", t.Restarts))
+}
@@ -3,58 +3,305 @@ package agent
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
+	"reflect"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 
+	"github.com/emirozbir/micro-sre/internal/artifacts"
+	"github.com/emirozbir/micro-sre/internal/chaos"
 	"github.com/emirozbir/micro-sre/internal/collectors"
 	"github.com/emirozbir/micro-sre/internal/config"
+	"github.com/emirozbir/micro-sre/internal/encryption"
 	"github.com/emirozbir/micro-sre/internal/llm"
 	"github.com/emirozbir/micro-sre/internal/models"
+	"github.com/emirozbir/micro-sre/internal/redact"
+	"github.com/emirozbir/micro-sre/internal/remediation"
+	"github.com/emirozbir/micro-sre/internal/requestid"
+	"github.com/emirozbir/micro-sre/internal/tracing"
 	"github.com/emirozbir/micro-sre/internal/ui"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 type Agent struct {
-	k8sCollector *collectors.KubernetesCollector
-	amCollector  *collectors.AlertManagerCollector
-	llmClient    llm.Client
-	config       *config.Config
-	logger       *zap.Logger
-	progress     ui.ProgressReporter
+	k8sCollector  *collectors.KubernetesCollector
+	amCollector   *collectors.AlertManagerCollector
+	promCollector *collectors.PrometheusCollector
+	// probeCollector is nil unless probes are enabled outside read-only mode
+	probeCollector *collectors.ProbeCollector
+	// llmClient is nil unless the llm analyzer is in the pipeline
+	llmClient llm.Client
+	// llmChecker verifies the LLM credentials; nil like llmClient
+	llmChecker llm.Checker
+	// budget is nil unless llm.budget sets a limit
+	budget *llm.Budget
+	// analyzers is the analysis pipeline, in order
+	analyzers []Analyzer
+	config    *config.Config
+	logger    *zap.Logger
+	progress  ui.ProgressReporter
+	pool      *fetchPool
+	artifacts artifacts.Store
+	// collectorErrors counts collector failures by source and error class
+	collectorErrors *collectorErrorStats
+	// redactor scrubs every prompt before it is sent to the LLM
+	redactor *redact.Redactor
+	chaos    *chaos.Injector
+	// runner dry-runs and executes recommended commands; it is nil in
+	// read-only mode
+	runner *remediation.Runner
+	// alertFilter selects the alerts analyzed, from alert_filters
+	alertFilter *collectors.AlertFilter
 }
 
 func NewAgent(cfg *config.Config, logger *zap.Logger) (*Agent, error) {
+	if err := validateCollectionProfiles(cfg.CollectionProfiles); err != nil {
+		return nil, err
+	}
+	if err := validateAutoRemediation(cfg.Remediation.Auto); err != nil {
+		return nil, err
+	}
+
 	k8sCollector, err := collectors.NewKubernetesCollector(cfg)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create k8s collector: %w", err)
 	}
 
-	amCollector := collectors.NewAlertManagerCollector(cfg)
+	injector, err := chaos.New(cfg.Chaos)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure chaos faults: %w", err)
+	}
+
+	return newAgent(cfg, logger, k8sCollector, injector, newCollectorErrorStats(), nil)
+}
+
+// Reload returns an agent configured by cfg, for swapping in without a
+// restart. It shares the Kubernetes connection, the fault injector, the
+// collector error counts and today's token spend with a, so changing how
+// the cluster is reached (kubeconfig, context, cache, read-only mode) or
+// the chaos faults still requires a restart. a keeps working for the
+// analyses already running on it.
+func (a *Agent) Reload(cfg *config.Config) (*Agent, error) {
+	if err := validateCollectionProfiles(cfg.CollectionProfiles); err != nil {
+		return nil, err
+	}
+	if err := validateAutoRemediation(cfg.Remediation.Auto); err != nil {
+		return nil, err
+	}
+	old := a.config
+	if cfg.ReadOnly != old.ReadOnly ||
+		cfg.Kubernetes.Kubeconfig != old.Kubernetes.Kubeconfig ||
+		cfg.Kubernetes.Context != old.Kubernetes.Context ||
+		!reflect.DeepEqual(cfg.Kubernetes.Cache, old.Kubernetes.Cache) {
+		return nil, fmt.Errorf("read_only and the kubernetes connection and cache settings cannot be reloaded, restart the server")
+	}
+	return newAgent(cfg, a.logger, a.k8sCollector.WithConfig(cfg), a.chaos, a.collectorErrors, a.budget)
+}
+
+// newAgent builds the configurable parts of an agent around a Kubernetes
+// collector and fault injector. A non-nil budget is reconfigured and kept,
+// so its spend carries over.
+func newAgent(cfg *config.Config, logger *zap.Logger, k8sCollector *collectors.KubernetesCollector, injector *chaos.Injector, collectorErrors *collectorErrorStats, budget *llm.Budget) (*Agent, error) {
+	amCollector, err := collectors.NewAlertManagerCollector(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create alertmanager collector: %w", err)
+	}
+
+	alertFilter, err := collectors.NewAlertFilter(cfg.AlertFilters)
+	if err != nil {
+		return nil, err
+	}
+
+	// Without the LLM in the pipeline no API key is needed
+	var llmClient llm.Client
+	var llmChecker llm.Checker
+	if !usesLLM(cfg.Agent) || !cfg.LLM.Budget.Enabled() {
+		budget = nil
+	}
+	if usesLLM(cfg.Agent) {
+		client, err := llm.NewClient(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create LLM client: %w", err)
+		}
+		if llmChecker, err = llm.NewChecker(cfg); err != nil {
+			return nil, fmt.Errorf("failed to create LLM client: %w", err)
+		}
+		llmClient = injector.WrapClient(client)
+		if cfg.LLM.Budget.Enabled() {
+			if budget == nil {
+				budget = llm.NewBudget(cfg.LLM.Budget)
+			} else {
+				budget.Reconfigure(cfg.LLM.Budget)
+			}
+			llmClient = llm.WithBudget(llmClient, budget)
+		}
+	}
+	if injector.Enabled() {
+		logger.Warn("chaos mode is enabled: collector and LLM calls may be delayed or failed on purpose")
+	}
 
-	llmClient, err := llm.NewClient(cfg)
+	redactor, err := redact.New(cfg.Redaction)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create LLM client: %w", err)
+		return nil, fmt.Errorf("failed to create redactor: %w", err)
 	}
 
-	return &Agent{
-		k8sCollector: k8sCollector,
-		amCollector:  amCollector,
-		llmClient:    llmClient,
-		config:       cfg,
-		logger:       logger,
-		progress:     &NoOpProgressReporter{},
-	}, nil
+	cipher, err := encryption.New(cfg.Encryption)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load encryption key: %w", err)
+	}
+	artifactStore, err := artifacts.NewStore(cfg.Artifacts, cipher)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create artifact store: %w", err)
+	}
+
+	var probeCollector *collectors.ProbeCollector
+	if cfg.Probes.Enabled {
+		mutator, err := k8sCollector.Mutator()
+		if err != nil {
+			logger.Warn("connectivity probes disabled", zap.Error(err))
+		} else {
+			probeCollector = collectors.NewProbeCollector(mutator, cfg.Probes)
+		}
+	}
+
+	var runner *remediation.Runner
+	if mutator, err := k8sCollector.Mutator(); err == nil {
+		runner, err = remediation.NewRunner(mutator, cfg.Remediation.AllowedVerbs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create command runner: %w", err)
+		}
+	} else if cfg.Remediation.Auto.Enabled {
+		logger.Warn("auto-remediation disabled", zap.Error(err))
+	}
+
+	a := &Agent{
+		k8sCollector:   k8sCollector,
+		amCollector:    amCollector,
+		promCollector:  collectors.NewPrometheusCollector(cfg),
+		probeCollector: probeCollector,
+		llmClient:      llmClient,
+		llmChecker:     llmChecker,
+		budget:         budget,
+		config:         cfg,
+		logger:         logger,
+		progress:       &NoOpProgressReporter{},
+		pool:           newFetchPool(cfg.Agent.MaxParallelFetches, injector),
+		artifacts:      artifactStore,
+
+		collectorErrors: collectorErrors,
+		redactor:        redactor,
+		chaos:           injector,
+		runner:          runner,
+		alertFilter:     alertFilter,
+	}
+	a.analyzers, err = a.newPipeline()
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Kubernetes returns the Kubernetes collector, for handlers that read from
+// the cluster directly (live log tails)
+func (a *Agent) Kubernetes() *collectors.KubernetesCollector {
+	return a.k8sCollector
+}
+
+// log returns the agent's logger with the ID of the request the analysis
+// runs for, so agent logs line up with the access log
+func (a *Agent) log(ctx context.Context) *zap.Logger {
+	return requestid.Logger(ctx, a.logger)
+}
+
+// CheckLLM verifies the LLM credentials and model. It succeeds without the
+// LLM in the pipeline.
+func (a *Agent) CheckLLM(ctx context.Context) error {
+	if a.llmChecker == nil {
+		return nil
+	}
+	return a.llmChecker.Check(ctx)
+}
+
+// AlertManager returns the AlertManager collector, for the alert poller
+func (a *Agent) AlertManager() *collectors.AlertManagerCollector {
+	return a.amCollector
+}
+
+// AlertFilter returns the filter selecting the alerts to analyze
+func (a *Agent) AlertFilter() *collectors.AlertFilter {
+	return a.alertFilter
+}
+
+// PodWorkload returns the name of the Deployment or StatefulSet a pod
+// belongs to, or "" if it has neither
+func (a *Agent) PodWorkload(ctx context.Context, namespace, podName string) (string, error) {
+	var workload string
+	err := a.pool.do(ctx, collectors.SourcePod, func() (err error) {
+		workload, err = a.k8sCollector.GetPodWorkload(ctx, namespace, podName)
+		return err
+	})
+	return workload, err
+}
+
+// Chaos returns the fault injector used by the chaos admin endpoint
+func (a *Agent) Chaos() *chaos.Injector {
+	return a.chaos
+}
+
+// Config returns the configuration the agent was created with
+func (a *Agent) Config() *config.Config {
+	return a.config
+}
+
+// TokenBudget returns the LLM token budget, or nil if no limit is set
+func (a *Agent) TokenBudget() *llm.Budget {
+	return a.budget
+}
+
+// budgetFallback reports whether err is an exceeded token budget to be
+// answered without the LLM
+func (a *Agent) budgetFallback(err error) bool {
+	return a.budget != nil && a.budget.OnExceeded() == config.BudgetFallback && errors.Is(err, llm.ErrBudgetExceeded)
+}
+
+// Artifacts returns the artifact store, or nil if artifact storage is disabled
+func (a *Agent) Artifacts() artifacts.Store {
+	return a.artifacts
+}
+
+// Generator returns the model and prompt version new analyses are produced with
+func (a *Agent) Generator() (model, promptVersion string) {
+	return a.config.LLM.Model, PromptVersion
+}
+
+// Close releases background resources such as the informer cache
+func (a *Agent) Close() {
+	if a.k8sCollector != nil {
+		a.k8sCollector.Close()
+	}
+}
+
+// ReadOnly reports whether the agent runs in read-only mode
+func (a *Agent) ReadOnly() bool {
+	return a.config.ReadOnly
 }
 
 // SetProgressReporter sets the progress reporter for the agent
 func (a *Agent) SetProgressReporter(reporter ui.ProgressReporter) {
 	a.progress = reporter
 	// Also set it on the collectors
-	a.k8sCollector.SetProgressReporter(reporter)
+	if a.k8sCollector != nil {
+		a.k8sCollector.SetProgressReporter(reporter)
+	}
 }
 
 type AnalysisRequest struct {
@@ -62,131 +309,965 @@ type AnalysisRequest struct {
 	Namespace        string
 	PodName          string
 	Lookback         time.Duration
+	// Alert is the originating AlertManager alert, if any
+	Alert *models.Alert
+	// GroupLabels are the labels of the AlertManager group the alert was
+	// delivered in, used to find other alerts of the same incident
+	GroupLabels map[string]string
+	// Verbosity is the report verbosity (brief, standard, deep); empty uses
+	// report.verbosity
+	Verbosity string
+	// Cluster is the cluster the analysis is recorded under; empty takes it
+	// from the alert, or cluster.name
+	Cluster string
+	// Feedback is a human correction of an earlier analysis of the same
+	// data, for re-analyses
+	Feedback *models.Feedback
+	// Recurrence summarizes earlier analyses of the same alert on the pod,
+	// if the caller has a history to look them up in
+	Recurrence *models.Recurrence
+
+	// escalation are the controller and namespace context sections added
+	// when a low-confidence analysis is escalated
+	escalation []string
+}
+
+// AlertName returns the name the analysis of the request is stored under
+func (req AnalysisRequest) AlertName() string {
+	if req.Alert != nil {
+		return req.Alert.GetAlertName()
+	}
+	return "PodIncident"
+}
+
+// PodSummary returns the alert summary an analysis of req is recorded
+// under until it has a result. It starts now rather than at the alert,
+// whose analysis may already be stored.
+func (a *Agent) PodSummary(req AnalysisRequest) models.AlertSummary {
+	summary := models.AlertSummary{
+		Name:      req.AlertName(),
+		Cluster:   a.clusterOf(req.Cluster, req.alerts()),
+		Namespace: req.Namespace,
+		Pod:       req.PodName,
+		StartedAt: time.Now(),
+	}
+	if req.Alert != nil {
+		summary.Severity = req.Alert.GetSeverity()
+	}
+	return summary
+}
+
+// alerts returns the originating alert, if any, as a list
+func (req AnalysisRequest) alerts() []models.Alert {
+	if req.Alert == nil {
+		return nil
+	}
+	return []models.Alert{*req.Alert}
+}
+
+// clusterOf returns the cluster an analysis is recorded under: requested,
+// else the cluster label of the first alert carrying one, else cluster.name
+func (a *Agent) clusterOf(requested string, alerts []models.Alert) string {
+	if requested != "" {
+		return requested
+	}
+	for _, alert := range alerts {
+		if cluster := alert.Labels[a.config.Cluster.Label]; cluster != "" {
+			return cluster
+		}
+	}
+	return a.config.Cluster.Name
+}
+
+// alertLabels returns the label set used to match AlertManager objects
+// (silences, related alerts) against this request
+func (req AnalysisRequest) alertLabels() map[string]string {
+	labels := map[string]string{}
+	if req.Alert != nil {
+		for k, v := range req.Alert.Labels {
+			labels[k] = v
+		}
+	}
+	if _, ok := labels["namespace"]; !ok {
+		labels["namespace"] = req.Namespace
+	}
+	if _, ok := labels["pod"]; !ok {
+		labels["pod"] = req.PodName
+	}
+	return labels
 }
 
 func (a *Agent) AnalyzeAlert(ctx context.Context, req AnalysisRequest) (*models.AnalysisResult, error) {
-	a.logger.Info("starting alert analysis",
+	a.log(ctx).Info("starting alert analysis",
 		zap.String("namespace", req.Namespace),
 		zap.String("pod", req.PodName),
 		zap.Duration("lookback", req.Lookback),
 	)
 
-	// Collect data in parallel
+	ctx, span := tracing.Start(ctx, "analyze pod", trace.WithAttributes(
+		attribute.String("k8s.namespace.name", req.Namespace),
+		attribute.String("k8s.pod.name", req.PodName)))
+	defer span.End()
+
+	ctx, stages := withStageTimer(ctx)
+	ctx, usage := llm.WithUsage(ctx)
+	manifest := newManifestRecorder(ctx, a.collectorErrors)
+	since := time.Now().Add(-req.Lookback)
+	plan := a.collectionPlan(req)
+
+	// A pod that does not exist may never have been admitted; look at the
+	// controller side before giving up
+	var admission *models.AdmissionContext
+	podInfo, err := a.collectPodInfo(ctx, req, plan, since, manifest)
+	if collectors.ClassifyError(err) == collectors.ErrorNotFound && !plan.disabled(collectors.SourceAdmission, manifest) {
+		admission = a.collectAdmission(ctx, req, nil, since, manifest)
+		if admission != nil {
+			podInfo, err = missingPodInfo(req, err), nil
+			manifest.skip(collectors.SourceLogs, "pod does not exist")
+			manifest.skip(collectors.SourceEvents, "pod does not exist")
+		}
+	}
+	if err != nil {
+		a.reporter(ctx).Stop()
+		a.log(ctx).Error("failed to collect data", zap.Error(err))
+		return nil, fmt.Errorf("failed to collect data: %w", err)
+	}
+
+	// Collect supplementary context in parallel; every collector call goes
+	// through the shared fetch pool
+	a.stage(ctx, StageCollectContext, "Collecting alert context...")
 	var (
-		podInfo *collectors.PodInfo
-		err     error
-		wg      sync.WaitGroup
-		mu      sync.Mutex
-		errors  []error
+		wg            sync.WaitGroup
+		silences      []models.Silence
+		relatedAlerts []models.RelatedAlert
+		metrics       *models.AlertMetrics
+		rollout       *models.RolloutInfo
+		oom           *models.OOMContext
+		probes        *models.ProbeReport
 	)
 
-	wg.Add(1)
+	wg.Add(7)
 	go func() {
 		defer wg.Done()
-
-		// The collector will report its own progress for each step
-		pi, e := a.k8sCollector.GetPodInfo(ctx, req.Namespace, req.PodName, req.Lookback)
-		mu.Lock()
-		podInfo = pi
-		if e != nil {
-			errors = append(errors, e)
+		if !plan.disabled(collectors.SourceSilences, manifest) {
+			silences = a.collectSilences(ctx, req, manifest)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		if !plan.disabled(collectors.SourceRelatedAlerts, manifest) {
+			relatedAlerts = a.collectRelatedAlerts(ctx, req, manifest)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		if !plan.disabled(collectors.SourceMetrics, manifest) {
+			metrics = a.collectAlertMetrics(ctx, req, manifest)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		if plan.disabled(collectors.SourceRollout, manifest) {
+			return
+		}
+		started := time.Now()
+		err := a.pool.do(ctx, collectors.SourceRollout, func() (err error) {
+			rollout, err = a.k8sCollector.GetRolloutInfo(ctx, podInfo.Pod)
+			return err
+		})
+		if err != nil {
+			a.log(ctx).Warn("failed to collect rollout status", zap.Error(err))
+		}
+		manifest.record(collectors.SourceRollout, started, time.Time{}, boolToInt(rollout != nil), err)
+	}()
+	go func() {
+		defer wg.Done()
+		if plan.disabled(collectors.SourceNode, manifest) {
+			return
+		}
+		started := time.Now()
+		err := a.pool.do(ctx, collectors.SourceNode, func() (err error) {
+			oom, err = a.k8sCollector.GetOOMContext(ctx, podInfo.Pod)
+			return err
+		})
+		if err != nil {
+			a.log(ctx).Warn("failed to correlate OOM kill with node state", zap.Error(err))
+		}
+		if oom != nil || err != nil {
+			manifest.record(collectors.SourceNode, started, time.Time{}, boolToInt(oom != nil), err)
+		} else {
+			manifest.skip(collectors.SourceNode, "no OOM kill or eviction")
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		if !plan.disabled(collectors.SourceProbes, manifest) {
+			probes = a.collectProbes(ctx, podInfo.Pod, manifest)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		if admission == nil && !plan.disabled(collectors.SourceAdmission, manifest) {
+			admission = a.collectAdmission(ctx, req, podInfo.Pod, since, manifest)
 		}
-		mu.Unlock()
 	}()
-
 	wg.Wait()
 
-	if len(errors) > 0 {
-		a.progress.Stop()
-		a.logger.Error("failed to collect data", zap.Errors("errors", errors))
-		return nil, fmt.Errorf("failed to collect data: %v", errors)
+	collected := &models.AnalysisResult{
+		Silences:      silences,
+		RelatedAlerts: relatedAlerts,
+		Metrics:       metrics,
+		OOM:           oom,
+		Probes:        probes,
+		Admission:     admission,
+		Rollout:       rollout,
+		Manifest:      manifest.list(),
 	}
-
-	// Build context for LLM
-	a.progress.Update("Building analysis context...")
-	prompt := a.buildAnalysisPrompt(req, podInfo)
-
-	// Analyze with LLM
-	a.progress.Update("Analyzing with AI (this may take 5-15 seconds)...")
-	a.logger.Info("sending data to LLM for analysis")
-	analysisText, err := a.llmClient.Analyze(ctx, prompt)
+	result, err := a.analyzeCollected(ctx, req, podInfo, collected)
 	if err != nil {
-		a.progress.Stop()
-		return nil, fmt.Errorf("LLM analysis failed: %w", err)
+		a.reporter(ctx).Stop()
+		return nil, err
 	}
+	if a.shouldEscalate(result) {
+		result = a.escalate(ctx, req, podInfo, collected, result, manifest)
+	}
+	result.Manifest = manifest.list()
+	if a.artifacts != nil {
+		a.stage(ctx, StageStoreArtifacts, "Storing collected data...")
+		result.Artifacts = a.storeArtifacts(ctx, podInfo)
+	}
+	a.recordUsage(ctx, result, usage)
+	result.Stages = stages.finish()
 
-	// Parse the response and structure it
-	a.progress.Update("Parsing AI response...")
-	result := a.parseAnalysisResponse(req, podInfo, analysisText)
-
-	a.progress.Stop()
+	a.reporter(ctx).Stop()
 
-	a.logger.Info("analysis completed",
+	a.log(ctx).Info("analysis completed",
 		zap.String("root_cause", result.Analysis.RootCause),
 		zap.String("confidence", result.Analysis.Confidence),
+		zap.Any("stages", result.Stages),
 	)
 
 	return result, nil
 }
 
-func (a *Agent) buildAnalysisPrompt(req AnalysisRequest, podInfo *collectors.PodInfo) string {
-	return fmt.Sprintf(`You are an expert SRE analyzing a Kubernetes incident. Analyze the following data and provide a detailed root cause analysis.
+// analyzeCollected runs the analyzer pipeline over already collected data.
+// Supplementary context (silences, related
+// alerts, metrics, OOM correlation, rollout) is taken from collected, and
+// the failed data sources in collected.Manifest are listed as unavailable.
+func (a *Agent) analyzeCollected(ctx context.Context, req AnalysisRequest, podInfo *collectors.PodInfo, collected *models.AnalysisResult) (*models.AnalysisResult, error) {
+	var sections []string
+	if len(collected.Silences) > 0 {
+		sections = append(sections, a.formatSilences(collected.Silences))
+	}
+	if collected.Rollout != nil {
+		sections = append(sections, a.formatRollout(collected.Rollout))
+	}
+	if len(collected.RelatedAlerts) > 0 {
+		sections = append(sections, a.formatRelatedAlerts(collected.RelatedAlerts))
+	}
+	if collected.Metrics != nil {
+		sections = append(sections, a.formatAlertMetrics(collected.Metrics))
+	}
+	if collected.OOM != nil {
+		sections = append(sections, a.formatOOMContext(collected.OOM))
+	}
+	if collected.Probes != nil {
+		sections = append(sections, a.formatProbes(collected.Probes))
+	}
+	if collected.Admission != nil {
+		sections = append(sections, a.formatAdmission(collected.Admission))
+	}
+	if unavailable := formatUnavailable(collected.Manifest); unavailable != "" {
+		sections = append(sections, unavailable)
+	}
+	if req.Recurrence != nil {
+		sections = append(sections, formatRecurrence(req.AlertName(), req.Recurrence))
+	}
+	if req.Feedback != nil {
+		sections = append(sections, formatFeedback(req.Feedback))
+	}
+	sections = append(sections, req.escalation...)
 
-ALERT CONTEXT:
-- Namespace: %s
-- Pod: %s
-- Time Range: Last %s
+	verbosity := a.verbosity(req.Verbosity)
+	outcome, err := a.runPipeline(ctx, &AnalyzerInput{
+		Namespace: req.Namespace,
+		Verbosity: verbosity,
+		Feedback:  req.Feedback,
+		Pods:      []*collectors.PodInfo{podInfo},
+		Collected: collected,
+		Sections:  sections,
+		Prompt: func(sections []string) string {
+			return a.buildAnalysisPrompt(req, podInfo, sections, verbosity)
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
 
-POD STATUS:
-Phase: %s
-Conditions: %v
-Container Statuses: %v
+	result := a.newPodResult(req, podInfo, outcome.Analysis)
+	outcome.apply(result)
+	result.Verbosity = verbosity
+	result.Feedback = req.Feedback
+	result.Recurrence = req.Recurrence
+	result.Silences = collected.Silences
+	result.RelatedAlerts = collected.RelatedAlerts
+	result.Metrics = collected.Metrics
+	result.OOM = collected.OOM
+	result.Probes = collected.Probes
+	result.Admission = collected.Admission
+	if collected.Rollout != nil {
+		result.Rollout = collected.Rollout
+		a.addRollbackRecommendations(result, collected.Rollout)
+	}
+	a.checkCommands(&result.Analysis)
+	scoreConfidence(&result.Analysis, podInfo.Logs, podInfo.Events)
 
-POD CONFIGURATION:
-Resources: %v
-Image: %s
+	return result, nil
+}
 
-RECENT EVENTS:
-%s
+// collectPodInfo fetches the pod, then its logs and events in parallel.
+// Only a failure to fetch the pod itself is fatal.
+func (a *Agent) collectPodInfo(ctx context.Context, req AnalysisRequest, plan collectionPlan, since time.Time, manifest *manifestRecorder) (*collectors.PodInfo, error) {
+	a.stage(ctx, StageFetchPod, fmt.Sprintf("Fetching pod metadata for %s/%s...", req.Namespace, req.PodName))
 
-POD LOGS:
-%s
+	info := &collectors.PodInfo{}
+	started := time.Now()
+	err := a.pool.do(ctx, collectors.SourcePod, func() (err error) {
+		info.Pod, err = a.k8sCollector.GetPod(ctx, req.Namespace, req.PodName)
+		return err
+	})
+	manifest.record(collectors.SourcePod, started, time.Time{}, boolToInt(err == nil), err)
+	if err != nil {
+		return nil, err
+	}
+
+	a.stage(ctx, StageCollectLogs, "Collecting logs and events...")
+	a.collectPodData(ctx, info, plan, req.Lookback, since, manifest)
+	return info, nil
+}
+
+// collectPodData fetches the logs and events of info.Pod in parallel,
+// recording failures on info
+func (a *Agent) collectPodData(ctx context.Context, info *collectors.PodInfo, plan collectionPlan, lookback time.Duration, since time.Time, manifest *manifestRecorder) {
+	namespace, podName := info.Pod.Namespace, info.Pod.Name
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		if plan.disabled(collectors.SourceLogs, manifest) {
+			return
+		}
+		started := time.Now()
+		info.LogsError = a.pool.do(ctx, collectors.SourceLogs, func() (err error) {
+			info.Logs, err = a.k8sCollector.GetPodLogs(ctx, namespace, podName, lookback, plan.logOptions())
+			return err
+		})
+		if info.LogsError != nil {
+			info.Logs = fmt.Sprintf("Error fetching logs: %v", info.LogsError)
+		}
+		manifest.record(collectors.SourceLogs, started, since, countLines(info.Logs, info.LogsError), info.LogsError)
+	}()
+	go func() {
+		defer wg.Done()
+		if plan.disabled(collectors.SourceEvents, manifest) {
+			return
+		}
+		started := time.Now()
+		info.EventsError = a.pool.do(ctx, collectors.SourceEvents, func() (err error) {
+			info.Events, err = a.k8sCollector.GetPodEvents(ctx, namespace, podName, lookback)
+			return err
+		})
+		if info.EventsError != nil {
+			info.Events = []corev1.Event{}
+		}
+		manifest.record(collectors.SourceEvents, started, since, len(info.Events), info.EventsError)
+	}()
+	wg.Wait()
+}
+
+// storeArtifacts keeps the full collected data in the artifact store so it
+// can be retrieved later, after the prompt has truncated it and the pod is
+// gone. Logs and events over artifacts.max_size keep their newest entries;
+// a pod spec over it is not kept. Failures are logged and ignored.
+func (a *Agent) storeArtifacts(ctx context.Context, podInfo *collectors.PodInfo) []models.ArtifactRef {
+	if a.artifacts == nil {
+		return nil
+	}
+	maxSize := a.config.Artifacts.MaxSize
+
+	var refs []models.ArtifactRef
+	put := func(name, contentType string, data []byte, truncated bool) {
+		if len(data) == 0 {
+			return
+		}
+		if maxSize > 0 && len(data) > maxSize {
+			a.log(ctx).Warn("artifact exceeds artifacts.max_size, not storing it", zap.String("artifact", name), zap.Int("size", len(data)))
+			return
+		}
+		id, err := a.artifacts.Put(ctx, data)
+		if err != nil {
+			a.log(ctx).Warn("failed to store artifact", zap.String("artifact", name), zap.Error(err))
+			return
+		}
+		refs = append(refs, models.ArtifactRef{Name: name, ID: id, Size: len(data), ContentType: contentType, Truncated: truncated})
+	}
+
+	if podInfo.LogsError == nil {
+		logs, truncated := tailLogs(podInfo.Logs, maxSize)
+		put(models.ArtifactLogs, "text/plain; charset=utf-8", []byte(logs), truncated)
+	}
+	if events, truncated, err := newestEvents(podInfo.Events, maxSize); err == nil && len(podInfo.Events) > 0 {
+		put(models.ArtifactEvents, "application/json", events, truncated)
+	}
+	if pod, err := json.Marshal(podInfo.Pod); err == nil {
+		put(models.ArtifactPodSpec, "application/json", pod, false)
+	}
+
+	return refs
+}
 
-TASK:
+// tailLogs cuts logs over maxSize bytes (0 for no limit) down to their
+// newest whole lines
+func tailLogs(logs string, maxSize int) (string, bool) {
+	if maxSize <= 0 || len(logs) <= maxSize {
+		return logs, false
+	}
+	tail := logs[len(logs)-maxSize:]
+	if i := strings.IndexByte(tail, '\n'); i >= 0 {
+		tail = tail[i+1:]
+	}
+	return tail, true
+}
+
+// newestEvents marshals events, dropping the oldest while they take more
+// than maxSize bytes (0 for no limit). Nothing is left if the newest event
+// alone is too large.
+func newestEvents(events []corev1.Event, maxSize int) ([]byte, bool, error) {
+	data, err := json.Marshal(events)
+	if err != nil || maxSize <= 0 || len(data) <= maxSize {
+		return data, false, err
+	}
+
+	sorted := make([]corev1.Event, len(events))
+	copy(sorted, events)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].LastTimestamp.Before(&sorted[j].LastTimestamp)
+	})
+
+	// Each event takes its JSON and a comma, or the closing bracket
+	first, size := len(sorted), 1
+	for first > 0 {
+		event, err := json.Marshal(sorted[first-1])
+		if err != nil {
+			return nil, false, err
+		}
+		if size+len(event)+1 > maxSize {
+			break
+		}
+		size += len(event) + 1
+		first--
+	}
+	if first == len(sorted) {
+		return nil, true, nil
+	}
+	data, err = json.Marshal(sorted[first:])
+	return data, true, err
+}
+
+// collectSilences looks up AlertManager silences matching the request. Failures
+// are logged and ignored since silences only add context to the analysis.
+func (a *Agent) collectSilences(ctx context.Context, req AnalysisRequest, manifest *manifestRecorder) []models.Silence {
+	if a.config.AlertManager.URL == "" {
+		manifest.skip(collectors.SourceSilences, "alertmanager.url not configured")
+		return nil
+	}
+
+	a.reporter(ctx).Update("Checking AlertManager silences...")
+	started := time.Now()
+	since := started.Add(-req.Lookback)
+	var silences []models.Silence
+	err := a.pool.do(ctx, collectors.SourceSilences, func() (err error) {
+		silences, err = a.amCollector.GetSilencesForLabels(ctx, req.alertLabels(), since)
+		return err
+	})
+	manifest.record(collectors.SourceSilences, started, since, len(silences), err)
+	if err != nil {
+		a.log(ctx).Warn("failed to fetch alertmanager silences", zap.Error(err))
+		return nil
+	}
+	return silences
+}
+
+// maxRelatedAlerts caps how many related alerts are listed individually in
+// the prompt; the rest are only counted
+const maxRelatedAlerts = 20
+
+// collectRelatedAlerts fetches other firing alerts sharing the namespace or
+// AlertManager group of the request. Failures are logged and ignored.
+func (a *Agent) collectRelatedAlerts(ctx context.Context, req AnalysisRequest, manifest *manifestRecorder) []models.RelatedAlert {
+	if a.config.AlertManager.URL == "" {
+		manifest.skip(collectors.SourceRelatedAlerts, "alertmanager.url not configured")
+		return nil
+	}
+
+	a.reporter(ctx).Update("Fetching related firing alerts...")
+	fingerprint := req.AlertFingerprint
+	if fingerprint == "" && req.Alert != nil {
+		fingerprint = req.Alert.Fingerprint
+	}
+
+	started := time.Now()
+	var alerts []models.Alert
+	err := a.pool.do(ctx, collectors.SourceRelatedAlerts, func() (err error) {
+		alerts, err = a.amCollector.GetRelatedAlerts(ctx, fingerprint, req.Namespace, req.GroupLabels)
+		return err
+	})
+	manifest.record(collectors.SourceRelatedAlerts, started, time.Time{}, len(alerts), err)
+	if err != nil {
+		a.log(ctx).Warn("failed to fetch related alerts", zap.Error(err))
+		return nil
+	}
+
+	related := make([]models.RelatedAlert, 0, len(alerts))
+	for _, alert := range alerts {
+		related = append(related, models.RelatedAlert{
+			Fingerprint: alert.Fingerprint,
+			AlertName:   alert.GetAlertName(),
+			Namespace:   alert.GetNamespace(),
+			Pod:         alert.GetPodName(),
+			Severity:    alert.GetSeverity(),
+			Summary:     alert.Annotations["summary"],
+			StartsAt:    alert.StartsAt,
+		})
+	}
+	return related
+}
+
+func (a *Agent) formatRelatedAlerts(related []models.RelatedAlert) string {
+	counts := map[string]int{}
+	var names []string
+	for _, r := range related {
+		if counts[r.AlertName] == 0 {
+			names = append(names, r.AlertName)
+		}
+		counts[r.AlertName]++
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("RELATED FIRING ALERTS (%d in the same namespace or alert group):\n", len(related)))
+	for _, name := range names {
+		sb.WriteString(fmt.Sprintf("- %s: %d firing\n", name, counts[name]))
+	}
+	for i, r := range related {
+		if i >= maxRelatedAlerts {
+			sb.WriteString(fmt.Sprintf("... and %d more\n", len(related)-maxRelatedAlerts))
+			break
+		}
+		target := r.Namespace
+		if r.Pod != "" {
+			target += "/" + r.Pod
+		}
+		sb.WriteString(fmt.Sprintf("  * [%s] %s on %s since %s", r.Severity, r.AlertName, target, r.StartsAt.Format(time.RFC3339)))
+		if r.Summary != "" {
+			sb.WriteString(": " + r.Summary)
+		}
+		sb.WriteString("\n")
+	}
+	sb.WriteString("Consider whether this pod's issue is a symptom of a broader incident.\n")
+	return sb.String()
+}
+
+// trajectoryPoints is how many samples per series are shown to the LLM
+const trajectoryPoints = 12
+
+// collectAlertMetrics evaluates the alert expression in Prometheus. Failures
+// are logged and ignored.
+func (a *Agent) collectAlertMetrics(ctx context.Context, req AnalysisRequest, manifest *manifestRecorder) *models.AlertMetrics {
+	if !a.config.Prometheus.Enabled {
+		manifest.skip(collectors.SourceMetrics, "prometheus.enabled is false")
+		return nil
+	}
+	if req.Alert == nil {
+		manifest.skip(collectors.SourceMetrics, "analysis has no originating alert")
+		return nil
+	}
+
+	a.reporter(ctx).Update("Evaluating alert expression in Prometheus...")
+	started := time.Now()
+	var metrics *models.AlertMetrics
+	err := a.pool.do(ctx, collectors.SourceMetrics, func() (err error) {
+		metrics, err = a.promCollector.GetAlertMetrics(ctx, req.Alert, req.Lookback)
+		return err
+	})
+	items := 0
+	if metrics != nil {
+		items = len(metrics.Series)
+	}
+	manifest.record(collectors.SourceMetrics, started, started.Add(-req.Lookback), items, err)
+	if err != nil {
+		a.log(ctx).Warn("failed to evaluate alert expression", zap.Error(err))
+		return nil
+	}
+	return metrics
+}
+
+func (a *Agent) formatAlertMetrics(m *models.AlertMetrics) string {
+	var sb strings.Builder
+	sb.WriteString("ALERT EXPRESSION VALUES:\n")
+	sb.WriteString(fmt.Sprintf("Expression: %s\n", m.Expression))
+	if len(m.Series) == 0 {
+		sb.WriteString("The expression currently returns no data (the condition may have cleared).\n")
+		return sb.String()
+	}
+	if m.TotalSeries > len(m.Series) {
+		sb.WriteString(fmt.Sprintf("Showing the %d highest of %d series.\n", len(m.Series), m.TotalSeries))
+	}
+	for _, s := range m.Series {
+		sb.WriteString(fmt.Sprintf("- %s min=%g max=%g last=%g\n", s.LabelString(), s.Min(), s.Max(), s.Last()))
+
+		// Downsample to a short trajectory so the trend is visible without
+		// flooding the prompt
+		stride := len(s.Points)/trajectoryPoints + 1
+		var values []string
+		for i := 0; i < len(s.Points); i += stride {
+			p := s.Points[i]
+			values = append(values, fmt.Sprintf("%s=%g", p.Timestamp.Format("15:04"), p.Value))
+		}
+		sb.WriteString(fmt.Sprintf("  trajectory: %s\n", strings.Join(values, ", ")))
+	}
+	return sb.String()
+}
+
+// collectAdmission looks for admission and security-context failures on the
+// controllers of the pod. pod is nil when the pod does not exist. Failures
+// are logged and ignored.
+func (a *Agent) collectAdmission(ctx context.Context, req AnalysisRequest, pod *corev1.Pod, since time.Time, manifest *manifestRecorder) *models.AdmissionContext {
+	started := time.Now()
+	var admission *models.AdmissionContext
+	err := a.pool.do(ctx, collectors.SourceAdmission, func() (err error) {
+		admission, err = a.k8sCollector.GetAdmissionContext(ctx, req.Namespace, req.PodName, pod, since)
+		return err
+	})
+	items := 0
+	if admission != nil {
+		items = len(admission.Failures)
+	}
+	manifest.record(collectors.SourceAdmission, started, since, items, err)
+	if err != nil {
+		a.log(ctx).Warn("failed to check admission failures", zap.Error(err))
+		return nil
+	}
+	return admission
+}
+
+// missingPodInfo stands in for a pod that was never created, so the analysis
+// can proceed from the controller-side admission failures
+func missingPodInfo(req AnalysisRequest, cause error) *collectors.PodInfo {
+	return &collectors.PodInfo{
+		Pod: &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: req.PodName, Namespace: req.Namespace},
+			Status: corev1.PodStatus{
+				Phase:   corev1.PodUnknown,
+				Reason:  "NotFound",
+				Message: "the pod does not exist in the cluster",
+			},
+		},
+		Logs:        "No logs: the pod does not exist.",
+		Events:      []corev1.Event{},
+		LogsError:   cause,
+		EventsError: cause,
+	}
+}
+
+func (a *Agent) formatAdmission(adm *models.AdmissionContext) string {
+	var sb strings.Builder
+	sb.WriteString("ADMISSION AND SECURITY-CONTEXT FAILURES:\n")
+	if adm.PodMissing {
+		sb.WriteString("- The pod does NOT exist. It was most likely never admitted; analyze the controller-side failures below.\n")
+	}
+	if len(adm.PodSecurity) > 0 {
+		keys := make([]string, 0, len(adm.PodSecurity))
+		for k := range adm.PodSecurity {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		var levels []string
+		for _, k := range keys {
+			levels = append(levels, fmt.Sprintf("%s=%s", k, adm.PodSecurity[k]))
+		}
+		sb.WriteString(fmt.Sprintf("- Namespace Pod Security Admission labels: %s\n", strings.Join(levels, ", ")))
+	}
+	for _, f := range adm.Failures {
+		sb.WriteString(fmt.Sprintf("- [%s] %s %s: %s", f.Category, f.Kind, f.Name, f.Message))
+		if f.Count > 1 {
+			sb.WriteString(fmt.Sprintf(" (x%d, last %s)", f.Count, f.LastSeen.Format(time.RFC3339)))
+		}
+		sb.WriteString("\n")
+	}
+	sb.WriteString("Recommend fixing the policy violation or the pod spec; restarting pods will not help.\n")
+	return sb.String()
+}
+
+// collectProbes runs live DNS and TCP checks from inside the pod when
+// enabled. Failures are logged and ignored.
+func (a *Agent) collectProbes(ctx context.Context, pod *corev1.Pod, manifest *manifestRecorder) *models.ProbeReport {
+	if a.probeCollector == nil {
+		reason := "probes.enabled is false"
+		if a.config.Probes.Enabled {
+			reason = "not permitted in read-only mode"
+		}
+		manifest.skip(collectors.SourceProbes, reason)
+		return nil
+	}
+	if pod.Status.Phase != corev1.PodRunning {
+		manifest.skip(collectors.SourceProbes, "pod is not running")
+		return nil
+	}
+
+	a.reporter(ctx).Update("Probing DNS and connectivity from inside the pod...")
+	started := time.Now()
+	var report *models.ProbeReport
+	// Probes create an ephemeral container, so they are never retried
+	err := a.pool.doOnce(ctx, collectors.SourceProbes, func() (err error) {
+		report, err = a.probeCollector.Probe(ctx, pod)
+		return err
+	})
+	items := 0
+	if report != nil {
+		items = len(report.Results)
+	}
+	manifest.record(collectors.SourceProbes, started, time.Time{}, items, err)
+	if err != nil {
+		a.log(ctx).Warn("connectivity probe failed", zap.Error(err))
+		return nil
+	}
+	return report
+}
+
+func (a *Agent) formatProbes(r *models.ProbeReport) string {
+	var sb strings.Builder
+	sb.WriteString("LIVE CONNECTIVITY PROBES (run now from inside the pod's network namespace):\n")
+	for _, res := range r.Results {
+		status := "OK"
+		if !res.OK {
+			status = "FAILED"
+		}
+		sb.WriteString(fmt.Sprintf("- %s %s: %s", strings.ToUpper(res.Kind), res.Target, status))
+		if res.Detail != "" {
+			sb.WriteString(" (" + res.Detail + ")")
+		}
+		sb.WriteString("\n")
+	}
+	sb.WriteString("These reflect current state and may differ from the time of the incident.\n")
+	return sb.String()
+}
+
+func (a *Agent) formatOOMContext(oom *models.OOMContext) string {
+	var sb strings.Builder
+	sb.WriteString("MEMORY KILL CORRELATION:\n")
+	if oom.Evicted {
+		sb.WriteString(fmt.Sprintf("- The pod was evicted: %s\n", oom.Message))
+	} else {
+		sb.WriteString(fmt.Sprintf("- Container %q was OOMKilled at %s (memory limit: %s, request: %s)\n",
+			oom.Container, oom.KilledAt.Format(time.RFC3339), valueOr(oom.MemoryLimit, "none"), valueOr(oom.MemoryRequest, "none")))
+	}
+	if oom.NodeName != "" {
+		sb.WriteString(fmt.Sprintf("- Node %s MemoryPressure condition: %t\n", oom.NodeName, oom.NodeMemoryPressure))
+	}
+	if oom.NodeAvailableBytes > 0 {
+		sb.WriteString(fmt.Sprintf("- Node memory now: %d bytes available, %d bytes working set, %d bytes allocatable\n",
+			oom.NodeAvailableBytes, oom.NodeWorkingSetBytes, oom.NodeAllocatableBytes))
+	}
+	for _, e := range oom.NodeEvents {
+		sb.WriteString(fmt.Sprintf("- Node event [%s] %s: %s\n", e.Timestamp.Format(time.RFC3339), e.Reason, e.Message))
+	}
+	switch oom.Classification {
+	case models.OOMNodePressure:
+		sb.WriteString("Assessment: node-level memory pressure around the kill time; the container may not be at fault.\n")
+	case models.OOMCgroupLimit:
+		sb.WriteString("Assessment: no node memory pressure observed; the container most likely exceeded its own memory limit.\n")
+	}
+	return sb.String()
+}
+
+func valueOr(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+func (a *Agent) formatSilences(silences []models.Silence) string {
+	var sb strings.Builder
+	sb.WriteString("ALERTMANAGER SILENCES:\n")
+	for _, s := range silences {
+		if s.IsActive() {
+			sb.WriteString(fmt.Sprintf("- This alert IS currently silenced by %s until %s (silence %s): %s\n",
+				s.CreatedBy, s.EndsAt.Format(time.RFC3339), s.ID, s.Comment))
+		} else {
+			sb.WriteString(fmt.Sprintf("- This alert WAS silenced by %s until %s (silence %s): %s\n",
+				s.CreatedBy, s.EndsAt.Format(time.RFC3339), s.ID, s.Comment))
+		}
+	}
+	sb.WriteString("Someone may already be working on this incident; mention the silence in your reasoning.\n")
+	return sb.String()
+}
+
+func (a *Agent) formatRollout(r *models.RolloutInfo) string {
+	var sb strings.Builder
+	sb.WriteString("PROGRESSIVE DELIVERY:\n")
+	sb.WriteString(fmt.Sprintf("- This pod is managed by %s %q (phase: %s)\n", r.Kind, r.Name, r.Phase))
+	if r.TotalSteps > 0 {
+		sb.WriteString(fmt.Sprintf("- Canary step %d of %d, canary weight %d%%\n", r.CurrentStep, r.TotalSteps, r.CanaryWeight))
+	} else {
+		sb.WriteString(fmt.Sprintf("- Canary iteration %d, canary weight %d%%\n", r.CurrentStep, r.CanaryWeight))
+	}
+	if r.FailedChecks > 0 {
+		sb.WriteString(fmt.Sprintf("- Failed canary analysis checks: %d\n", r.FailedChecks))
+	}
+	if r.Aborted {
+		sb.WriteString("- The rollout has been aborted\n")
+	}
+	if r.Message != "" {
+		sb.WriteString(fmt.Sprintf("- Status message: %s\n", r.Message))
+	}
+	if len(r.RollbackCommands) > 0 {
+		sb.WriteString("If the new revision is at fault, recommend rolling back with:\n")
+		for _, cmd := range r.RollbackCommands {
+			sb.WriteString(fmt.Sprintf("  %s\n", cmd))
+		}
+	}
+	return sb.String()
+}
+
+// addRollbackRecommendations makes sure a failing rollout always carries its
+// rollback commands, even if the LLM did not suggest them
+func (a *Agent) addRollbackRecommendations(result *models.AnalysisResult, r *models.RolloutInfo) {
+	for _, cmd := range r.RollbackCommands {
+		found := false
+		for _, rec := range result.Analysis.Recommendations {
+			if rec.Command == cmd {
+				found = true
+				break
+			}
+		}
+		if found {
+			continue
+		}
+		result.Analysis.Recommendations = append(result.Analysis.Recommendations, models.Recommendation{
+			Priority: "high",
+			Action:   fmt.Sprintf("Roll back %s %s", r.Kind, r.Name),
+			Details:  fmt.Sprintf("The rollout is in phase %s at canary weight %d%%", r.Phase, r.CanaryWeight),
+			Command:  cmd,
+		})
+	}
+}
+
+// PromptVersion identifies the analysis prompt. Bump it whenever
+// buildAnalysisPrompt changes in a way that can affect results, so that
+// re-analyses of past incidents can be compared across prompt upgrades.
+const PromptVersion = "3"
+
+// analysisTask is the instruction and response-format part shared by the
+// pod and deployment prompts
+const analysisTask = `TASK:
 1. Identify the root cause of the issue
-2. Provide a confidence level (high/medium/low)
+2. Provide a confidence level (high/medium/low) and your certainty as a number from 0 to 100
 3. Explain your reasoning
 4. Create a timeline of key events
 5. Extract relevant evidence (log lines, events)
 6. Provide actionable recommendations with specific commands
+7. If more than one root cause is plausible, list the alternatives as hypotheses with probabilities that sum to 1, most likely first
+8. For each recommendation, reference the evidence entries that justify it by their zero-based index in evidence.logs or evidence.events
 
 Please respond in JSON format with the following structure:
 {
   "root_cause": "brief description",
   "confidence": "high|medium|low",
+  "certainty": 75,
   "reasoning": "detailed explanation",
+  "hypotheses": [{"root_cause": "...", "probability": 0.8, "reasoning": "..."}],
   "timeline": [{"timestamp": "...", "event": "...", "details": "..."}],
   "evidence": {
     "logs": [{"timestamp": "...", "line": "..."}],
     "events": [{"type": "...", "reason": "...", "message": "..."}]
   },
   "recommendations": [
-    {"priority": "high|medium|low", "action": "...", "details": "...", "command": "..."}
+    {"priority": "high|medium|low", "action": "...", "details": "...", "command": "...", "evidence_refs": [{"type": "log|event", "index": 0}]}
   ]
-}`,
+}`
+
+func (a *Agent) buildAnalysisPrompt(req AnalysisRequest, podInfo *collectors.PodInfo, sections []string, verbosity string) string {
+	return fmt.Sprintf(`You are an expert SRE analyzing a Kubernetes incident. Analyze the following data and provide a detailed root cause analysis.
+
+ALERT CONTEXT:
+- Namespace: %s
+- Pod: %s
+- Time Range: Last %s
+
+POD STATUS:
+Phase: %s
+Conditions: %v
+Container Statuses: %v
+
+POD CONFIGURATION:
+Resources: %v
+Image: %s
+
+RECENT EVENTS:
+%s
+
+POD LOGS:
+%s
+%s
+%s`,
 		req.Namespace,
 		req.PodName,
 		req.Lookback,
 		podInfo.Pod.Status.Phase,
 		podInfo.Pod.Status.Conditions,
 		podInfo.Pod.Status.ContainerStatuses,
-		podInfo.Pod.Spec.Containers[0].Resources,
-		podInfo.Pod.Spec.Containers[0].Image,
+		firstContainer(podInfo.Pod).Resources,
+		valueOr(firstContainer(podInfo.Pod).Image, "unknown"),
 		a.formatEvents(podInfo.Events),
 		a.truncateLogs(podInfo.Logs, 5000),
+		a.formatSections(sections),
+		analysisTaskFor(verbosity),
 	)
 }
 
+// firstContainer returns the pod's main container, or an empty one for pods
+// without a spec (a pod that was never created)
+func firstContainer(pod *corev1.Pod) corev1.Container {
+	if len(pod.Spec.Containers) == 0 {
+		return corev1.Container{}
+	}
+	return pod.Spec.Containers[0]
+}
+
+// formatRecurrence tells the LLM how often the incident happened before, so
+// that chronic problems are not reported as novel
+func formatRecurrence(alertName string, r *models.Recurrence) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("RECURRENCE:\n%s was analyzed on this pod %d times since %s. The last analysis (%s) concluded: %q\n",
+		alertName, r.Count, r.Since.Format(time.RFC3339), r.LastSeen.Format(time.RFC3339), r.LastRootCause))
+	if r.Recurring {
+		sb.WriteString("This is a recurring incident, not a novel one. Say whether the previous root cause still applies, and recommend a permanent fix rather than only an immediate mitigation.\n")
+	} else {
+		sb.WriteString("Say whether the previous root cause still applies.\n")
+	}
+	return sb.String()
+}
+
+// formatSections renders optional context sections appended to the prompt
+func (a *Agent) formatSections(sections []string) string {
+	if len(sections) == 0 {
+		return ""
+	}
+	return "\n" + strings.Join(sections, "\n")
+}
+
 func (a *Agent) formatEvents(events []corev1.Event) string {
 	if len(events) == 0 {
 		return "No recent events found"
@@ -213,14 +1294,12 @@ func (a *Agent) truncateLogs(logs string, maxChars int) string {
 	return logs[len(logs)-maxChars:] + "\n... (truncated)"
 }
 
-func (a *Agent) parseAnalysisResponse(req AnalysisRequest, podInfo *collectors.PodInfo, analysisText string) *models.AnalysisResult {
-	// Try to extract JSON from the response
-	analysis := a.extractAndParseJSON(analysisText)
-
-	// Build the complete result
+// newPodResult wraps an analysis of a single pod into a result
+func (a *Agent) newPodResult(req AnalysisRequest, podInfo *collectors.PodInfo, analysis models.Analysis) *models.AnalysisResult {
 	result := &models.AnalysisResult{
 		Alert: models.AlertSummary{
-			Name:      "PodIncident",
+			Name:      req.AlertName(),
+			Cluster:   a.clusterOf(req.Cluster, req.alerts()),
 			Namespace: req.Namespace,
 			Pod:       req.PodName,
 			StartedAt: time.Now().Add(-req.Lookback),
@@ -233,14 +1312,46 @@ func (a *Agent) parseAnalysisResponse(req AnalysisRequest, podInfo *collectors.P
 		},
 	}
 
+	return result
+}
+
+// recordUsage sets the LLM provider, tokens and estimated cost of an
+// analysis from the usage tracked for it, unless the LLM was not called
+func (a *Agent) recordUsage(ctx context.Context, result *models.AnalysisResult, usage *llm.UsageTracker) {
+	u := usage.Usage()
+	if u.Total() == 0 {
+		return
+	}
+	model := result.Model
+	if model == "" {
+		model = llm.Model(ctx, a.config.LLM.Model)
+	}
+	result.Provider = a.config.LLM.Provider
+	result.Usage = &models.LLMUsage{
+		PromptTokens:     u.InputTokens,
+		CompletionTokens: u.OutputTokens,
+		CostUSD:          a.config.LLM.Cost(model, u.InputTokens, u.OutputTokens),
+	}
+}
+
+// UnparsedRootCause is the root cause of analyses whose LLM response had no
+// valid JSON
+const UnparsedRootCause = "Unable to parse LLM response"
+
+// parseAnalysis extracts the structured analysis from the LLM response,
+// falling back to the raw text when it cannot be parsed
+func (a *Agent) parseAnalysis(analysisText string) models.Analysis {
+	// Try to extract JSON from the response
+	analysis := a.extractAndParseJSON(analysisText)
+
 	// If parsing failed, include the raw text in reasoning
 	if analysis.RootCause == "" && analysis.Reasoning == "" {
-		result.Analysis.Reasoning = analysisText
-		result.Analysis.RootCause = "Unable to parse LLM response"
-		result.Analysis.Confidence = "unknown"
+		analysis.Reasoning = analysisText
+		analysis.RootCause = UnparsedRootCause
+		analysis.Confidence = "unknown"
 	}
 
-	return result
+	return analysis
 }
 
 func (a *Agent) extractAndParseJSON(text string) models.Analysis {
@@ -255,10 +1366,11 @@ func (a *Agent) extractAndParseJSON(text string) models.Analysis {
 
 	// Parse the JSON
 	var response struct {
-		RootCause   string `json:"root_cause"`
-		Confidence  string `json:"confidence"`
-		Reasoning   string `json:"reasoning"`
-		Timeline    []struct {
+		RootCause  string  `json:"root_cause"`
+		Confidence string  `json:"confidence"`
+		Certainty  float64 `json:"certainty"`
+		Reasoning  string  `json:"reasoning"`
+		Timeline   []struct {
 			Timestamp string `json:"timestamp"`
 			Event     string `json:"event"`
 			Details   string `json:"details"`
@@ -276,11 +1388,20 @@ func (a *Agent) extractAndParseJSON(text string) models.Analysis {
 				Timestamp string `json:"timestamp,omitempty"`
 			} `json:"events"`
 		} `json:"evidence"`
+		Hypotheses []struct {
+			RootCause   string  `json:"root_cause"`
+			Probability float64 `json:"probability"`
+			Reasoning   string  `json:"reasoning"`
+		} `json:"hypotheses"`
 		Recommendations []struct {
-			Priority string `json:"priority"`
-			Action   string `json:"action"`
-			Details  string `json:"details,omitempty"`
-			Command  string `json:"command,omitempty"`
+			Priority     string `json:"priority"`
+			Action       string `json:"action"`
+			Details      string `json:"details,omitempty"`
+			Command      string `json:"command,omitempty"`
+			EvidenceRefs []struct {
+				Type  string `json:"type"`
+				Index int    `json:"index"`
+			} `json:"evidence_refs,omitempty"`
 		} `json:"recommendations"`
 	}
 
@@ -295,9 +1416,19 @@ func (a *Agent) extractAndParseJSON(text string) models.Analysis {
 	}
 
 	// Convert to models.Analysis
+	// Certainty is accepted as 0-1 or 0-100
+	certainty := response.Certainty
+	if certainty > 0 && certainty <= 1 {
+		certainty *= 100
+	}
+	level := strings.ToLower(strings.TrimSpace(response.Confidence))
 	analysis := models.Analysis{
-		RootCause:       response.RootCause,
-		Confidence:      response.Confidence,
+		RootCause:  response.RootCause,
+		Confidence: level,
+		ConfidenceRubric: &models.ConfidenceRubric{
+			SelfReportedLevel: level,
+			Certainty:         int(math.Round(certainty)),
+		},
 		Reasoning:       response.Reasoning,
 		Timeline:        make([]models.TimelineEvent, 0),
 		Evidence:        models.Evidence{Logs: []models.LogEntry{}, Events: []models.EventEntry{}},
@@ -335,19 +1466,84 @@ func (a *Agent) extractAndParseJSON(text string) models.Analysis {
 		})
 	}
 
-	// Parse recommendations
+	// Parse hypotheses
+	for _, h := range response.Hypotheses {
+		if h.RootCause == "" || h.Probability <= 0 {
+			continue
+		}
+		analysis.Hypotheses = append(analysis.Hypotheses, models.Hypothesis{
+			RootCause:   h.RootCause,
+			Probability: h.Probability,
+			Reasoning:   h.Reasoning,
+		})
+	}
+	a.rankHypotheses(&analysis)
+
+	// Parse recommendations, keeping only evidence references that point at
+	// entries which actually exist
 	for _, r := range response.Recommendations {
-		analysis.Recommendations = append(analysis.Recommendations, models.Recommendation{
+		rec := models.Recommendation{
 			Priority: r.Priority,
 			Action:   r.Action,
 			Details:  r.Details,
 			Command:  r.Command,
-		})
+		}
+		for _, ref := range r.EvidenceRefs {
+			if !a.validEvidenceRef(analysis.Evidence, ref.Type, ref.Index) {
+				a.logger.Warn("dropping invalid evidence reference",
+					zap.String("action", r.Action),
+					zap.String("type", ref.Type),
+					zap.Int("index", ref.Index),
+				)
+				continue
+			}
+			rec.EvidenceRefs = append(rec.EvidenceRefs, models.EvidenceRef{Type: ref.Type, Index: ref.Index})
+		}
+		analysis.Recommendations = append(analysis.Recommendations, rec)
 	}
 
 	return analysis
 }
 
+// rankHypotheses normalizes hypothesis probabilities (accepting either 0-1 or
+// percentage values from the LLM), sorts them by likelihood and fills in the
+// root cause from the top hypothesis if the LLM left it empty
+func (a *Agent) rankHypotheses(analysis *models.Analysis) {
+	if len(analysis.Hypotheses) == 0 {
+		return
+	}
+
+	var total float64
+	for _, h := range analysis.Hypotheses {
+		total += h.Probability
+	}
+	for i := range analysis.Hypotheses {
+		analysis.Hypotheses[i].Probability /= total
+	}
+
+	sort.SliceStable(analysis.Hypotheses, func(i, j int) bool {
+		return analysis.Hypotheses[i].Probability > analysis.Hypotheses[j].Probability
+	})
+
+	if analysis.RootCause == "" {
+		analysis.RootCause = analysis.Hypotheses[0].RootCause
+	}
+}
+
+func (a *Agent) validEvidenceRef(evidence models.Evidence, refType string, index int) bool {
+	if index < 0 {
+		return false
+	}
+	switch refType {
+	case models.EvidenceLog:
+		return index < len(evidence.Logs)
+	case models.EvidenceEvent:
+		return index < len(evidence.Events)
+	default:
+		return false
+	}
+}
+
 func (a *Agent) extractJSON(text string) string {
 	// Try to find JSON object in the text
 	startIdx := strings.Index(text, "{")
@@ -415,6 +1611,22 @@ func (a *Agent) parseTimestamp(ts string) time.Time {
 	return time.Now()
 }
 
+// countLines returns the number of collected log lines, or zero if
+// collection failed and logs only holds the error text
+func countLines(logs string, err error) int {
+	if err != nil || logs == "" {
+		return 0
+	}
+	return strings.Count(strings.TrimSuffix(logs, "\n"), "\n") + 1
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
 func min(a, b int) int {
 	if a < b {
 		return a
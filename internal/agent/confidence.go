@@ -0,0 +1,127 @@
+package agent
+
+import (
+	"math"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/emirozbir/micro-sre/internal/models"
+)
+
+// groundedLogsFull and groundedEventsFull are the numbers of grounded
+// evidence entries that earn the full weight of their criterion
+const (
+	groundedLogsFull   = 3
+	groundedEventsFull = 2
+	// groundingPrefix is the length of the prefix or suffix of a cited log
+	// line that must appear in the collected logs when the whole line does
+	// not, e.g. because the LLM dropped the timestamp
+	groundingPrefix = 60
+)
+
+// selfReportedCertainty is the certainty assumed for a level when the LLM
+// did not give a number
+var selfReportedCertainty = map[string]int{"high": 85, "medium": 55, "low": 25}
+
+// scoreConfidence replaces the self-reported confidence of an analysis
+// with a 0-100 score computed from the rubric, checking the cited evidence
+// against the collected logs and events. Unparsed analyses keep their
+// "unknown" confidence.
+func scoreConfidence(analysis *models.Analysis, logs string, events []corev1.Event) {
+	if analysis.Confidence == "unknown" {
+		return
+	}
+	rubric := analysis.ConfidenceRubric
+	if rubric == nil {
+		rubric = &models.ConfidenceRubric{SelfReportedLevel: strings.ToLower(analysis.Confidence)}
+	}
+
+	// Evidence coverage: grounded log lines, discounted by the share of
+	// cited lines that could not be found, plus recommendations that
+	// reference evidence
+	grounded := 0
+	for _, l := range analysis.Evidence.Logs {
+		if logGrounded(l.Line, logs) {
+			grounded++
+		}
+	}
+	logShare := 0.0
+	if cited := len(analysis.Evidence.Logs); cited > 0 {
+		logShare = fraction(grounded, groundedLogsFull) * float64(grounded) / float64(cited)
+	}
+	backed := 0
+	for _, rec := range analysis.Recommendations {
+		if len(rec.EvidenceRefs) > 0 {
+			backed++
+		}
+	}
+	recShare := 0.0
+	if len(analysis.Recommendations) > 0 {
+		recShare = float64(backed) / float64(len(analysis.Recommendations))
+	}
+	rubric.EvidenceCoverage = points(models.RubricEvidenceCoverage, 0.625*logShare+0.375*recShare)
+
+	// Corroborating events: cited events that were actually recorded
+	corroborating := 0
+	for _, e := range analysis.Evidence.Events {
+		if eventGrounded(e, events) {
+			corroborating++
+		}
+	}
+	rubric.CorroboratingEvents = points(models.RubricCorroboratingEvents, fraction(corroborating, groundedEventsFull))
+
+	// Self-reported certainty: the number if the LLM gave one, else the
+	// certainty of its level
+	certainty := rubric.Certainty
+	if certainty <= 0 {
+		certainty = selfReportedCertainty[rubric.SelfReportedLevel]
+	}
+	rubric.SelfReported = points(models.RubricSelfReported, float64(min(certainty, 100))/100)
+
+	analysis.ConfidenceRubric = rubric
+	analysis.ConfidenceScore = rubric.EvidenceCoverage + rubric.CorroboratingEvents + rubric.SelfReported
+	analysis.Confidence = models.ConfidenceLevel(analysis.ConfidenceScore)
+}
+
+// logGrounded reports whether a cited log line appears in the collected
+// logs, in full or by its prefix or suffix
+func logGrounded(line, logs string) bool {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return false
+	}
+	if strings.Contains(logs, line) {
+		return true
+	}
+	if len(line) <= groundingPrefix {
+		return false
+	}
+	return strings.Contains(logs, line[:groundingPrefix]) || strings.Contains(logs, line[len(line)-groundingPrefix:])
+}
+
+// eventGrounded reports whether a cited event matches a collected event by
+// reason and message
+func eventGrounded(cited models.EventEntry, events []corev1.Event) bool {
+	message := strings.TrimSpace(cited.Message)
+	for _, e := range events {
+		if !strings.EqualFold(e.Reason, cited.Reason) {
+			continue
+		}
+		if message == "" || strings.Contains(e.Message, message) || strings.Contains(message, e.Message) {
+			return true
+		}
+		if len(message) > groundingPrefix && strings.Contains(e.Message, message[:groundingPrefix]) {
+			return true
+		}
+	}
+	return false
+}
+
+func fraction(n, full int) float64 {
+	return math.Min(float64(n)/float64(full), 1)
+}
+
+func points(weight int, share float64) int {
+	return int(math.Round(float64(weight) * share))
+}
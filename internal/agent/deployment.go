@@ -0,0 +1,435 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/emirozbir/micro-sre/internal/collectors"
+	"github.com/emirozbir/micro-sre/internal/llm"
+	"github.com/emirozbir/micro-sre/internal/models"
+	"github.com/emirozbir/micro-sre/internal/tracing"
+)
+
+// maxDeploymentPods caps how many unhealthy pods of a workload have their
+// logs and events sent to the LLM
+const maxDeploymentPods = 3
+
+// AnalyzeDeployment analyzes a Deployment or StatefulSet as a whole. It
+// aggregates the status of all its pods, collects logs and events from the
+// most unhealthy ones and asks for a single consolidated root cause. An
+// empty verbosity uses report.verbosity.
+func (a *Agent) AnalyzeDeployment(ctx context.Context, namespace, name string, lookback time.Duration, verbosity string) (*models.AnalysisResult, error) {
+	return a.analyzeWorkload(ctx, namespace, name, lookback, verbosity, nil)
+}
+
+// DeploymentSummary returns the alert summary an analysis of a Deployment
+// or StatefulSet is recorded under until it has a result, started now
+func (a *Agent) DeploymentSummary(namespace, name, cluster string) models.AlertSummary {
+	return models.AlertSummary{
+		Name:      "DeploymentIncident",
+		Cluster:   a.clusterOf(cluster, nil),
+		Namespace: namespace,
+		Workload:  name,
+		StartedAt: time.Now(),
+	}
+}
+
+// IncidentRequest is a group of alerts firing on pods of one workload
+type IncidentRequest struct {
+	Namespace string
+	// Workload is the name of the Deployment or StatefulSet
+	Workload  string
+	Alerts    []models.Alert
+	Lookback  time.Duration
+	Verbosity string
+}
+
+// IncidentSummary returns the alert summary the analysis of an incident
+// is recorded under until it has a result, started now
+func (a *Agent) IncidentSummary(req IncidentRequest) models.AlertSummary {
+	summary := models.AlertSummary{
+		Name:      "DeploymentIncident",
+		Cluster:   a.clusterOf("", req.Alerts),
+		Namespace: req.Namespace,
+		Workload:  req.Workload,
+		StartedAt: time.Now(),
+	}
+	if len(req.Alerts) > 0 {
+		summary.Name, summary.Severity, _ = incidentSummary(req.Alerts)
+	}
+	return summary
+}
+
+// AnalyzeIncident analyzes the workload behind a group of correlated alerts
+// once, with the alerts in the prompt, instead of once per alert
+func (a *Agent) AnalyzeIncident(ctx context.Context, req IncidentRequest) (*models.AnalysisResult, error) {
+	return a.analyzeWorkload(ctx, req.Namespace, req.Workload, req.Lookback, req.Verbosity, req.Alerts)
+}
+
+// analyzeWorkload runs a deployment-level analysis; alerts, if any, are the
+// alerts that triggered it
+func (a *Agent) analyzeWorkload(ctx context.Context, namespace, name string, lookback time.Duration, verbosity string, alerts []models.Alert) (*models.AnalysisResult, error) {
+	a.log(ctx).Info("starting deployment analysis",
+		zap.String("namespace", namespace),
+		zap.String("workload", name),
+		zap.Duration("lookback", lookback),
+		zap.Int("alerts", len(alerts)),
+	)
+
+	ctx, span := tracing.Start(ctx, "analyze workload", trace.WithAttributes(
+		attribute.String("k8s.namespace.name", namespace),
+		attribute.String("hepsre.workload", name)))
+	defer span.End()
+
+	ctx, stages := withStageTimer(ctx)
+	ctx, usage := llm.WithUsage(ctx)
+	manifest := newManifestRecorder(ctx, a.collectorErrors)
+	since := time.Now().Add(-lookback)
+
+	a.stage(ctx, StageFetchWorkload, fmt.Sprintf("Fetching workload %s/%s...", namespace, name))
+	started := time.Now()
+	var (
+		workload *models.WorkloadStatus
+		pods     []corev1.Pod
+	)
+	err := a.pool.do(ctx, collectors.SourceWorkload, func() (err error) {
+		workload, pods, err = a.k8sCollector.GetWorkload(ctx, namespace, name)
+		return err
+	})
+	manifest.record(collectors.SourceWorkload, started, time.Time{}, len(pods), err)
+	if err != nil {
+		a.reporter(ctx).Stop()
+		return nil, fmt.Errorf("failed to collect data: %w", err)
+	}
+
+	selected := selectUnhealthyPods(workload, pods)
+	a.stage(ctx, StageCollectLogs, fmt.Sprintf("Collecting logs and events of %d pods...", len(selected)))
+
+	var (
+		wg            sync.WaitGroup
+		podInfos      = make([]*collectors.PodInfo, len(selected))
+		admission     *models.AdmissionContext
+		relatedAlerts []models.RelatedAlert
+		rollout       *models.RolloutInfo
+		oom           *models.OOMContext
+	)
+	for i, pod := range selected {
+		wg.Add(1)
+		go func(i int, pod *corev1.Pod) {
+			defer wg.Done()
+			info := &collectors.PodInfo{Pod: pod}
+			a.collectPodData(ctx, info, collectionPlan{}, lookback, since, manifest)
+			podInfos[i] = info
+		}(i, pod)
+	}
+
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		started := time.Now()
+		err := a.pool.do(ctx, collectors.SourceAdmission, func() (err error) {
+			admission, err = a.k8sCollector.GetWorkloadAdmissionContext(ctx, namespace, name, since)
+			return err
+		})
+		items := 0
+		if admission != nil {
+			items = len(admission.Failures)
+		}
+		manifest.record(collectors.SourceAdmission, started, since, items, err)
+		if err != nil {
+			a.log(ctx).Warn("failed to check admission failures", zap.Error(err))
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		relatedAlerts = a.collectRelatedAlerts(ctx, AnalysisRequest{Namespace: namespace}, manifest)
+	}()
+	go func() {
+		defer wg.Done()
+		if len(pods) == 0 {
+			manifest.skip(collectors.SourceRollout, "workload has no pods")
+			return
+		}
+		started := time.Now()
+		err := a.pool.do(ctx, collectors.SourceRollout, func() (err error) {
+			rollout, err = a.k8sCollector.GetRolloutInfo(ctx, &pods[0])
+			return err
+		})
+		if err != nil {
+			a.log(ctx).Warn("failed to collect rollout status", zap.Error(err))
+		}
+		manifest.record(collectors.SourceRollout, started, time.Time{}, boolToInt(rollout != nil), err)
+	}()
+	wg.Wait()
+
+	// The first OOM-killed pod among the analyzed ones stands for the rest
+	a.stage(ctx, StageCollectContext, "Correlating OOM kills with node state...")
+	started = time.Now()
+	for _, info := range podInfos {
+		err = a.pool.do(ctx, collectors.SourceNode, func() (err error) {
+			oom, err = a.k8sCollector.GetOOMContext(ctx, info.Pod)
+			return err
+		})
+		if oom != nil || err != nil {
+			break
+		}
+	}
+	if err != nil {
+		a.log(ctx).Warn("failed to correlate OOM kill with node state", zap.Error(err))
+	}
+	if oom != nil || err != nil {
+		manifest.record(collectors.SourceNode, started, time.Time{}, boolToInt(oom != nil), err)
+	} else {
+		manifest.skip(collectors.SourceNode, "no OOM kill or eviction")
+	}
+
+	var sections []string
+	if len(alerts) > 0 {
+		sections = append(sections, formatIncidentAlerts(alerts))
+	}
+	if admission != nil {
+		sections = append(sections, a.formatAdmission(admission))
+	}
+	if rollout != nil {
+		sections = append(sections, a.formatRollout(rollout))
+	}
+	if len(relatedAlerts) > 0 {
+		sections = append(sections, a.formatRelatedAlerts(relatedAlerts))
+	}
+	if oom != nil {
+		sections = append(sections, a.formatOOMContext(oom))
+	}
+	if unavailable := formatUnavailable(manifest.list()); unavailable != "" {
+		sections = append(sections, unavailable)
+	}
+
+	verbosity = a.verbosity(verbosity)
+	outcome, err := a.runPipeline(ctx, &AnalyzerInput{
+		Namespace: namespace,
+		Verbosity: verbosity,
+		Pods:      podInfos,
+		Collected: &models.AnalysisResult{OOM: oom, Admission: admission, Rollout: rollout, RelatedAlerts: relatedAlerts},
+		Sections:  sections,
+		Prompt: func(sections []string) string {
+			return a.buildDeploymentPrompt(workload, podInfos, lookback, sections, verbosity)
+		},
+	})
+	if err != nil {
+		a.reporter(ctx).Stop()
+		return nil, err
+	}
+
+	result := &models.AnalysisResult{
+		Alert: models.AlertSummary{
+			Name:      "DeploymentIncident",
+			Cluster:   a.clusterOf("", alerts),
+			Namespace: namespace,
+			Workload:  workload.Kind + "/" + workload.Name,
+			StartedAt: since,
+		},
+		Verbosity:     verbosity,
+		Analysis:      outcome.Analysis,
+		CollectedData: models.CollectedData{TimeRange: lookback.String()},
+		RelatedAlerts: relatedAlerts,
+		OOM:           oom,
+		Admission:     admission,
+		Workload:      workload,
+		Manifest:      manifest.list(),
+	}
+	outcome.apply(result)
+	var (
+		logs   strings.Builder
+		events []corev1.Event
+	)
+	for _, info := range podInfos {
+		result.CollectedData.LogLines += len(info.Logs)
+		result.CollectedData.EventsCount += len(info.Events)
+		logs.WriteString(info.Logs)
+		events = append(events, info.Events...)
+	}
+	if len(alerts) > 0 {
+		var startedAt time.Time
+		result.Alert.Name, result.Alert.Severity, startedAt = incidentSummary(alerts)
+		if !startedAt.IsZero() {
+			result.Alert.StartedAt = startedAt
+		}
+	}
+	if rollout != nil {
+		result.Rollout = rollout
+		a.addRollbackRecommendations(result, rollout)
+	}
+	a.checkCommands(&result.Analysis)
+	scoreConfidence(&result.Analysis, logs.String(), events)
+	a.recordUsage(ctx, result, usage)
+	result.Stages = stages.finish()
+
+	a.reporter(ctx).Stop()
+
+	a.log(ctx).Info("deployment analysis completed",
+		zap.String("root_cause", result.Analysis.RootCause),
+		zap.String("confidence", result.Analysis.Confidence),
+		zap.Any("stages", result.Stages),
+	)
+
+	return result, nil
+}
+
+// selectUnhealthyPods marks and returns the most unhealthy pods of the
+// workload: not ready first, then by restart count
+func selectUnhealthyPods(workload *models.WorkloadStatus, pods []corev1.Pod) []*corev1.Pod {
+	var candidates []int
+	for i, health := range workload.Pods {
+		if !health.Healthy() {
+			candidates = append(candidates, i)
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := workload.Pods[candidates[i]], workload.Pods[candidates[j]]
+		if a.Ready != b.Ready {
+			return !a.Ready
+		}
+		return a.Restarts > b.Restarts
+	})
+	if len(candidates) > maxDeploymentPods {
+		candidates = candidates[:maxDeploymentPods]
+	}
+
+	selected := make([]*corev1.Pod, 0, len(candidates))
+	for _, i := range candidates {
+		workload.Pods[i].Analyzed = true
+		selected = append(selected, &pods[i])
+	}
+	return selected
+}
+
+// formatWorkloadConditions lists the conditions of a workload for prompts
+func formatWorkloadConditions(workload *models.WorkloadStatus) string {
+	var conditions strings.Builder
+	for _, c := range workload.Conditions {
+		conditions.WriteString(fmt.Sprintf("- %s=%s %s: %s\n", c.Type, c.Status, c.Reason, c.Message))
+	}
+	if conditions.Len() == 0 {
+		conditions.WriteString("None reported\n")
+	}
+	return conditions.String()
+}
+
+// formatPodHealth lists the pods of a workload for prompts
+func formatPodHealth(workload *models.WorkloadStatus) string {
+	var health strings.Builder
+	for _, p := range workload.Pods {
+		health.WriteString(fmt.Sprintf("- %s: phase=%s ready=%t restarts=%d", p.Name, p.Phase, p.Ready, p.Restarts))
+		if p.Reason != "" {
+			health.WriteString(" reason=" + p.Reason)
+		}
+		if p.Node != "" {
+			health.WriteString(" node=" + p.Node)
+		}
+		health.WriteString("\n")
+	}
+	if health.Len() == 0 {
+		health.WriteString("The workload has no pods.\n")
+	}
+	return health.String()
+}
+
+func (a *Agent) buildDeploymentPrompt(workload *models.WorkloadStatus, podInfos []*collectors.PodInfo, lookback time.Duration, sections []string, verbosity string) string {
+	// Share the log budget of a single-pod prompt between the analyzed pods
+	logBudget := 5000
+	if len(podInfos) > 1 {
+		logBudget /= len(podInfos)
+	}
+
+	var details strings.Builder
+	for _, info := range podInfos {
+		details.WriteString(fmt.Sprintf(`
+UNHEALTHY POD %s:
+Container Statuses: %v
+Image: %s
+Recent events:
+%s
+Logs:
+%s
+`,
+			info.Pod.Name,
+			info.Pod.Status.ContainerStatuses,
+			valueOr(firstContainer(info.Pod).Image, "unknown"),
+			a.formatEvents(info.Events),
+			a.truncateLogs(info.Logs, logBudget),
+		))
+	}
+
+	return fmt.Sprintf(`You are an expert SRE analyzing a Kubernetes workload incident. Analyze the following data and provide a single consolidated root cause analysis for the workload as a whole, not for individual pods.
+
+WORKLOAD CONTEXT:
+- Kind: %s
+- Name: %s
+- Namespace: %s
+- Time Range: Last %s
+- Replicas: desired %d, ready %d, updated %d, available %d
+
+WORKLOAD CONDITIONS:
+%s
+POD HEALTH (%d pods):
+%s%s%s
+%s`,
+		workload.Kind,
+		workload.Name,
+		workload.Namespace,
+		lookback,
+		workload.Desired,
+		workload.Ready,
+		workload.Updated,
+		workload.Available,
+		formatWorkloadConditions(workload),
+		len(workload.Pods),
+		formatPodHealth(workload),
+		details.String(),
+		a.formatSections(sections),
+		analysisTaskFor(verbosity),
+	)
+}
+
+// formatIncidentAlerts lists the alerts of an incident for the prompt
+func formatIncidentAlerts(alerts []models.Alert) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("ALERTS IN THIS INCIDENT (%d firing on pods of this workload):\n", len(alerts)))
+	for _, alert := range alerts {
+		sb.WriteString(fmt.Sprintf("- %s (severity=%s, status=%s) on pod %s since %s",
+			alert.GetAlertName(), alert.GetSeverity(), alert.Status, alert.GetPodName(), alert.StartsAt.Format(time.RFC3339)))
+		if summary := alert.Annotations["summary"]; summary != "" {
+			sb.WriteString(": " + summary)
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// incidentSummary returns the name, severity and start of an incident: the
+// alert name if all alerts share it, the highest severity and the earliest
+// start (zero if no alert has one)
+func incidentSummary(alerts []models.Alert) (name, severity string, startedAt time.Time) {
+	name = alerts[0].GetAlertName()
+	severity = alerts[0].GetSeverity()
+	for _, alert := range alerts {
+		if alert.GetAlertName() != name {
+			name = "DeploymentIncident"
+		}
+		if models.SeverityRank(alert.GetSeverity()) > models.SeverityRank(severity) {
+			severity = alert.GetSeverity()
+		}
+		if !alert.StartsAt.IsZero() && (startedAt.IsZero() || alert.StartsAt.Before(startedAt)) {
+			startedAt = alert.StartsAt
+		}
+	}
+	return name, severity, startedAt
+}
@@ -0,0 +1,185 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/emirozbir/micro-sre/internal/collectors"
+	"github.com/emirozbir/micro-sre/internal/llm"
+	"github.com/emirozbir/micro-sre/internal/models"
+)
+
+// shouldEscalate reports whether a pod analysis answered by the LLM scored
+// too low to be trusted
+func (a *Agent) shouldEscalate(result *models.AnalysisResult) bool {
+	cfg := a.config.Agent.Escalation
+	if !cfg.Enabled || a.llmClient == nil || result.BudgetExceeded {
+		return false
+	}
+	if !hasAnalyzer(result.Analyzers, AnalyzerLLM) {
+		return false
+	}
+	return result.Analysis.ConfidenceScore < cfg.MinScore
+}
+
+func hasAnalyzer(analyzers []string, name string) bool {
+	for _, a := range analyzers {
+		if a == name {
+			return true
+		}
+	}
+	return false
+}
+
+// escalate re-analyzes a low-confidence pod analysis with the context of
+// the pod's controller, then of its namespace, until the confidence is no
+// longer below the threshold. Each scope's context is added to the one
+// before. The most confident analysis is returned, with the chain of
+// analyses in Escalation.
+func (a *Agent) escalate(ctx context.Context, req AnalysisRequest, podInfo *collectors.PodInfo, collected *models.AnalysisResult, result *models.AnalysisResult, manifest *manifestRecorder) *models.AnalysisResult {
+	chain := []models.EscalationStep{escalationStep(models.EscalationPod, result)}
+	best := result
+	var workload *models.WorkloadStatus
+
+	scopes := []struct {
+		scope   string
+		collect func() (string, error)
+	}{
+		{models.EscalationController, func() (section string, err error) {
+			workload, section, err = a.controllerContext(ctx, req.Namespace, req.PodName, manifest)
+			return section, err
+		}},
+		{models.EscalationNamespace, func() (string, error) {
+			return a.namespaceContext(ctx, req.Namespace, req.Lookback, manifest)
+		}},
+	}
+	for _, s := range scopes {
+		a.stage(ctx, StageEscalate, fmt.Sprintf("Low confidence, escalating to %s context...", s.scope))
+		a.log(ctx).Info("escalating low-confidence analysis",
+			zap.String("namespace", req.Namespace),
+			zap.String("pod", req.PodName),
+			zap.String("scope", s.scope),
+			zap.Int("confidence_score", best.Analysis.ConfidenceScore),
+		)
+
+		section, err := s.collect()
+		if err != nil {
+			a.log(ctx).Warn("failed to collect escalation context", zap.String("scope", s.scope), zap.Error(err))
+			chain = append(chain, models.EscalationStep{Scope: s.scope, Error: err.Error()})
+			continue
+		}
+		if section == "" {
+			chain = append(chain, models.EscalationStep{Scope: s.scope, Error: "pod has no Deployment or StatefulSet"})
+			continue
+		}
+		req.escalation = append(req.escalation, section)
+
+		escalated, err := a.analyzeCollected(ctx, req, podInfo, collected)
+		if err == nil && escalated.BudgetExceeded {
+			err = llm.ErrBudgetExceeded
+		}
+		if err != nil {
+			a.log(ctx).Warn("escalated analysis failed", zap.String("scope", s.scope), zap.Error(err))
+			chain = append(chain, models.EscalationStep{Scope: s.scope, Error: err.Error()})
+			break
+		}
+		chain = append(chain, escalationStep(s.scope, escalated))
+		if escalated.Analysis.ConfidenceScore >= best.Analysis.ConfidenceScore {
+			best = escalated
+		}
+		if !a.shouldEscalate(escalated) {
+			break
+		}
+	}
+
+	if workload != nil {
+		best.Workload = workload
+	}
+	best.Escalation = chain
+	return best
+}
+
+func escalationStep(scope string, result *models.AnalysisResult) models.EscalationStep {
+	return models.EscalationStep{
+		Scope:           scope,
+		RootCause:       result.Analysis.RootCause,
+		Confidence:      result.Analysis.Confidence,
+		ConfidenceScore: result.Analysis.ConfidenceScore,
+	}
+}
+
+// controllerContext fetches the Deployment or StatefulSet of a pod and
+// formats its status and the health of its other pods for the prompt. A pod
+// without a controller has no controller context.
+func (a *Agent) controllerContext(ctx context.Context, namespace, podName string, manifest *manifestRecorder) (*models.WorkloadStatus, string, error) {
+	name, err := a.PodWorkload(ctx, namespace, podName)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to find the pod's controller: %w", err)
+	}
+	if name == "" {
+		return nil, "", nil
+	}
+
+	started := time.Now()
+	var workload *models.WorkloadStatus
+	err = a.pool.do(ctx, collectors.SourceWorkload, func() (err error) {
+		workload, _, err = a.k8sCollector.GetWorkload(ctx, namespace, name)
+		return err
+	})
+	items := 0
+	if workload != nil {
+		items = len(workload.Pods)
+	}
+	manifest.record(collectors.SourceWorkload, started, time.Time{}, items, err)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to fetch workload %s: %w", name, err)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("CONTROLLER CONTEXT (%s/%s):\n", workload.Kind, workload.Name))
+	sb.WriteString(fmt.Sprintf("Replicas: desired=%d ready=%d updated=%d available=%d\n",
+		workload.Desired, workload.Ready, workload.Updated, workload.Available))
+	sb.WriteString("Conditions:\n")
+	sb.WriteString(formatWorkloadConditions(workload))
+	sb.WriteString(fmt.Sprintf("Pods (%d):\n", len(workload.Pods)))
+	sb.WriteString(formatPodHealth(workload))
+	return workload, sb.String(), nil
+}
+
+// namespaceContext scans the namespace of a pod and formats the other
+// workloads with issues for the prompt
+func (a *Agent) namespaceContext(ctx context.Context, namespace string, lookback time.Duration, manifest *manifestRecorder) (string, error) {
+	started := time.Now()
+	var (
+		workloads []models.WorkloadHealth
+		other     []models.WarningEvent
+	)
+	err := a.pool.do(ctx, collectors.SourceWorkload, func() (err error) {
+		workloads, other, err = a.k8sCollector.ScanNamespace(ctx, namespace, lookback)
+		return err
+	})
+	manifest.record(collectors.SourceWorkload, started, time.Now().Add(-lookback), len(workloads), err)
+	if err != nil {
+		return "", fmt.Errorf("failed to scan namespace: %w", err)
+	}
+
+	issues, healthy := formatWorkloadIssues(workloads)
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("NAMESPACE CONTEXT (%s, last %s):\n", namespace, lookback))
+	sb.WriteString(fmt.Sprintf("Workloads: %d total, %d without issues\n", len(workloads), healthy))
+	if issues == "" {
+		sb.WriteString("No workloads with issues\n")
+	} else {
+		sb.WriteString("Workloads with issues:")
+		sb.WriteString(issues)
+	}
+	if len(other) > 0 {
+		sb.WriteString("\nOther Warning events:\n")
+		sb.WriteString(formatWarnings(other))
+	}
+	return sb.String(), nil
+}
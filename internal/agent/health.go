@@ -0,0 +1,240 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/emirozbir/micro-sre/internal/collectors"
+	"github.com/emirozbir/micro-sre/internal/llm"
+	"github.com/emirozbir/micro-sre/internal/models"
+)
+
+// Limits on how much of a namespace scan is sent to the LLM
+const (
+	maxHealthWorkloads = 25
+	maxHealthPods      = 5
+	maxHealthWarnings  = 5
+)
+
+// healthTask is the instruction and response-format part of the namespace
+// health prompt
+const healthTask = `TASK:
+1. Summarize the overall health of the namespace for its service owners in 2-4 sentences
+2. Rate the namespace as healthy, degraded or critical
+3. For each workload with issues, state the most likely cause and one concrete next step
+4. Ignore workloads whose issues are clearly benign (e.g. completed jobs)
+
+Please respond in JSON format with the following structure:
+{
+  "status": "healthy|degraded|critical",
+  "summary": "...",
+  "findings": [
+    {"workload": "Kind/Name", "severity": "critical|warning|info", "finding": "...", "recommendation": "..."}
+  ]
+}`
+
+type healthResponse struct {
+	Status   string `json:"status"`
+	Summary  string `json:"summary"`
+	Findings []struct {
+		Workload       string `json:"workload"`
+		Severity       string `json:"severity"`
+		Finding        string `json:"finding"`
+		Recommendation string `json:"recommendation"`
+	} `json:"findings"`
+}
+
+// NamespaceHealthReport scans a namespace for pods that are not ready,
+// pending workloads, restarts and recent Warning events, and asks the LLM
+// for a single health digest with per-workload findings. A namespace
+// without issues is reported as healthy without calling the LLM, and
+// without the LLM in the pipeline the issues are reported as found.
+func (a *Agent) NamespaceHealthReport(ctx context.Context, namespace string, lookback time.Duration) (*models.HealthReport, error) {
+	a.log(ctx).Info("starting namespace health report",
+		zap.String("namespace", namespace),
+		zap.Duration("lookback", lookback),
+	)
+
+	ctx, stages := withStageTimer(ctx)
+	a.stage(ctx, StageScanNamespace, fmt.Sprintf("Scanning namespace %s...", namespace))
+	var (
+		workloads []models.WorkloadHealth
+		other     []models.WarningEvent
+	)
+	err := a.pool.do(ctx, collectors.SourceWorkload, func() (err error) {
+		workloads, other, err = a.k8sCollector.ScanNamespace(ctx, namespace, lookback)
+		return err
+	})
+	if err != nil {
+		a.reporter(ctx).Stop()
+		return nil, fmt.Errorf("failed to scan namespace: %w", err)
+	}
+
+	report := &models.HealthReport{
+		Namespace:     namespace,
+		GeneratedAt:   time.Now(),
+		TimeRange:     lookback.String(),
+		Status:        models.HealthHealthy,
+		Workloads:     workloads,
+		OtherWarnings: other,
+	}
+
+	unhealthy := 0
+	for _, w := range workloads {
+		if !w.Healthy() {
+			unhealthy++
+		}
+	}
+	if unhealthy == 0 && len(other) == 0 {
+		report.Summary = fmt.Sprintf("All %d workloads are healthy and no Warning events were recorded in the last %s.", len(workloads), lookback)
+		report.Stages = stages.finish()
+		a.reporter(ctx).Stop()
+		return report, nil
+	}
+
+	// Without the LLM the scan's issues are reported as they are
+	scanned := func(note string) (*models.HealthReport, error) {
+		report.Status = models.HealthDegraded
+		report.Summary = fmt.Sprintf("%s%d of %d workloads have issues and %d other Warning events were recorded in the last %s.",
+			note, unhealthy, len(workloads), len(other), lookback)
+		report.Stages = stages.finish()
+		a.reporter(ctx).Stop()
+		return report, nil
+	}
+	if a.llmClient == nil {
+		return scanned("")
+	}
+
+	a.stage(ctx, StageQueryLLM, "Summarizing namespace health with AI...")
+	prompt, redactions := a.redactor.Redact(a.buildHealthPrompt(namespace, lookback, workloads, other))
+	analysisText, err := a.llmClient.Analyze(llm.WithTenant(ctx, namespace), prompt)
+	if a.budgetFallback(err) {
+		a.log(ctx).Warn("LLM token budget exceeded, reporting the scan only", zap.String("namespace", namespace), zap.Error(err))
+		return scanned("The LLM token budget is spent for today. ")
+	}
+	if err != nil {
+		a.reporter(ctx).Stop()
+		return nil, fmt.Errorf("LLM analysis failed: %w", err)
+	}
+	report.Model = a.config.LLM.Model
+	report.Redactions = redactions
+
+	a.stage(ctx, StageParseResponse, "Parsing AI response...")
+	var resp healthResponse
+	if err := json.Unmarshal([]byte(a.extractJSON(analysisText)), &resp); err != nil {
+		a.log(ctx).Warn("failed to parse health report response", zap.Error(err))
+		report.Status = models.HealthDegraded
+		report.Summary = analysisText
+	} else {
+		report.Status = models.HealthDegraded
+		switch resp.Status {
+		case models.HealthHealthy, models.HealthDegraded, models.HealthCritical:
+			report.Status = resp.Status
+		}
+		report.Summary = resp.Summary
+		byKey := map[string]*models.WorkloadHealth{}
+		for i := range report.Workloads {
+			byKey[report.Workloads[i].Key()] = &report.Workloads[i]
+		}
+		for _, f := range resp.Findings {
+			if w, ok := byKey[f.Workload]; ok {
+				w.Severity = f.Severity
+				w.Finding = f.Finding
+				w.Recommendation = f.Recommendation
+			}
+		}
+	}
+
+	report.Stages = stages.finish()
+	a.reporter(ctx).Stop()
+
+	a.log(ctx).Info("namespace health report completed",
+		zap.String("namespace", namespace),
+		zap.String("status", report.Status),
+		zap.Int("unhealthy_workloads", unhealthy),
+	)
+
+	return report, nil
+}
+
+func (a *Agent) buildHealthPrompt(namespace string, lookback time.Duration, workloads []models.WorkloadHealth, other []models.WarningEvent) string {
+	issues, healthy := formatWorkloadIssues(workloads)
+
+	var others string
+	if len(other) > 0 {
+		others = "\nOTHER WARNING EVENTS:\n" + formatWarnings(other)
+	}
+
+	return fmt.Sprintf(`You are an expert SRE writing a daily health report for a Kubernetes namespace.
+
+NAMESPACE: %s
+Time Range: Last %s
+Workloads: %d total, %d without issues
+
+WORKLOADS WITH ISSUES:%s%s
+%s`,
+		namespace,
+		lookback,
+		len(workloads),
+		healthy,
+		issues,
+		others,
+		healthTask,
+	)
+}
+
+// formatWorkloadIssues lists the workloads with issues of a namespace scan,
+// with their unhealthy pods and Warning events, and counts the healthy ones
+func formatWorkloadIssues(workloads []models.WorkloadHealth) (string, int) {
+	var sb strings.Builder
+	healthy, listed := 0, 0
+	for _, w := range workloads {
+		if w.Healthy() {
+			healthy++
+			continue
+		}
+		if listed == maxHealthWorkloads {
+			continue
+		}
+		listed++
+
+		sb.WriteString(fmt.Sprintf("\nWORKLOAD %s (%d/%d ready, %d restarts)\nIssues: %s\n",
+			w.Key(), w.Ready, w.Desired, w.Restarts, strings.Join(w.Issues, ", ")))
+
+		pods := 0
+		for _, p := range w.Pods {
+			if p.Healthy() || pods == maxHealthPods {
+				continue
+			}
+			pods++
+			sb.WriteString(fmt.Sprintf("- pod %s: phase=%s ready=%t restarts=%d", p.Name, p.Phase, p.Ready, p.Restarts))
+			if p.Reason != "" {
+				sb.WriteString(" reason=" + p.Reason)
+			}
+			sb.WriteString("\n")
+		}
+		sb.WriteString(formatWarnings(w.Warnings))
+	}
+	if listed < len(workloads)-healthy {
+		sb.WriteString(fmt.Sprintf("\n(%d more workloads with issues omitted)\n", len(workloads)-healthy-listed))
+	}
+	return sb.String(), healthy
+}
+
+func formatWarnings(warnings []models.WarningEvent) string {
+	var sb strings.Builder
+	for i, w := range warnings {
+		if i == maxHealthWarnings {
+			sb.WriteString(fmt.Sprintf("- ... %d more warning events\n", len(warnings)-i))
+			break
+		}
+		sb.WriteString(fmt.Sprintf("- [%s] %s %s (x%d): %s\n",
+			w.LastSeen.Format(time.RFC3339), w.Object, w.Reason, w.Count, w.Message))
+	}
+	return sb.String()
+}
@@ -0,0 +1,144 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/emirozbir/micro-sre/internal/collectors"
+	"github.com/emirozbir/micro-sre/internal/metrics"
+	"github.com/emirozbir/micro-sre/internal/models"
+	"github.com/emirozbir/micro-sre/internal/tracing"
+)
+
+// manifestRecorder collects the data source manifest of a single analysis.
+// It is safe for concurrent use by collector goroutines.
+type manifestRecorder struct {
+	mu      sync.Mutex
+	sources []models.DataSource
+	stats   *collectorErrorStats
+	// ctx carries the span of the analysis that collector spans belong to
+	ctx context.Context
+}
+
+func newManifestRecorder(ctx context.Context, stats *collectorErrorStats) *manifestRecorder {
+	return &manifestRecorder{ctx: ctx, stats: stats}
+}
+
+// record adds a collector run to the manifest. Data that does not exist is
+// recorded as skipped; other failures keep the error and its class. The run
+// is also traced as a span starting at started.
+func (m *manifestRecorder) record(name string, started, since time.Time, items int, err error) {
+	source := models.DataSource{
+		Name:     name,
+		Version:  collectors.SourceVersions[name],
+		Items:    items,
+		Since:    since,
+		Until:    started,
+		Duration: time.Since(started).Round(time.Millisecond).String(),
+	}
+	var class string
+	if err != nil {
+		class = collectors.ClassifyError(err)
+		m.stats.add(name, class)
+		if class == collectors.ErrorNotFound {
+			source.Skipped = true
+			source.SkipReason = fmt.Sprintf("not found: %v", err)
+		} else {
+			source.Error = err.Error()
+			source.ErrorClass = class
+		}
+	}
+
+	metrics.CollectorDuration.WithLabelValues(name, class).Observe(time.Since(started).Seconds())
+	_, span := tracing.Start(m.ctx, "collect "+name, trace.WithTimestamp(started))
+	span.SetAttributes(attribute.String("hepsre.source", name), attribute.Int("hepsre.items", items))
+	if class != "" {
+		span.SetAttributes(attribute.String("hepsre.error_class", class))
+	}
+	tracing.End(span, err)
+
+	m.mu.Lock()
+	m.sources = append(m.sources, source)
+	m.mu.Unlock()
+}
+
+// skip records a data source that was not queried and why
+func (m *manifestRecorder) skip(name, reason string) {
+	m.mu.Lock()
+	m.sources = append(m.sources, models.DataSource{
+		Name:       name,
+		Version:    collectors.SourceVersions[name],
+		Skipped:    true,
+		SkipReason: reason,
+	})
+	m.mu.Unlock()
+}
+
+func (m *manifestRecorder) list() []models.DataSource {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]models.DataSource(nil), m.sources...)
+}
+
+// formatUnavailable tells the LLM which data sources of the manifest
+// failed, so it does not read missing data as evidence that nothing happened
+func formatUnavailable(manifest []models.DataSource) string {
+	var sb strings.Builder
+	for _, s := range manifest {
+		if s.Error == "" {
+			continue
+		}
+		if sb.Len() == 0 {
+			sb.WriteString("UNAVAILABLE DATA (collection failed; do not treat the absence of this data as evidence):\n")
+		}
+		sb.WriteString(fmt.Sprintf("- %s: %s (%s)\n", s.Name, valueOr(s.ErrorClass, collectors.ErrorUnknown), s.Error))
+	}
+	return sb.String()
+}
+
+// collectorErrorStats counts failed collector runs by data source and error
+// class across every analysis run by an agent
+type collectorErrorStats struct {
+	mu     sync.Mutex
+	counts map[string]map[string]int
+}
+
+func newCollectorErrorStats() *collectorErrorStats {
+	return &collectorErrorStats{counts: map[string]map[string]int{}}
+}
+
+func (s *collectorErrorStats) add(source, class string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.counts[source] == nil {
+		s.counts[source] = map[string]int{}
+	}
+	s.counts[source][class]++
+}
+
+// CollectorErrors returns the number of failed collector runs per data
+// source and error class since the agent started
+func (a *Agent) CollectorErrors() map[string]map[string]int {
+	s := a.collectorErrors
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make(map[string]map[string]int, len(s.counts))
+	for source, classes := range s.counts {
+		out[source] = make(map[string]int, len(classes))
+		for class, n := range classes {
+			out[source][class] = n
+		}
+	}
+	return out
+}
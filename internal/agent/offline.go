@@ -0,0 +1,64 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"github.com/emirozbir/micro-sre/internal/collectors"
+	"github.com/emirozbir/micro-sre/internal/config"
+	"github.com/emirozbir/micro-sre/internal/llm"
+	"github.com/emirozbir/micro-sre/internal/models"
+	"github.com/emirozbir/micro-sre/internal/redact"
+)
+
+// NewOfflineAgent creates an agent that only analyzes recorded data, such
+// as evaluation fixtures. It has no cluster, AlertManager or Prometheus
+// access, and its LLM calls are not counted against llm.budget.
+func NewOfflineAgent(cfg *config.Config, logger *zap.Logger) (*Agent, error) {
+	var llmClient llm.Client
+	if usesLLM(cfg.Agent) {
+		client, err := llm.NewClient(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create LLM client: %w", err)
+		}
+		llmClient = client
+	}
+
+	redactor, err := redact.New(cfg.Redaction)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create redactor: %w", err)
+	}
+
+	a := &Agent{
+		llmClient: llmClient,
+		config:    cfg,
+		logger:    logger,
+		progress:  &NoOpProgressReporter{},
+		pool:      newFetchPool(cfg.Agent.MaxParallelFetches, nil),
+
+		collectorErrors: newCollectorErrorStats(),
+		redactor:        redactor,
+	}
+	a.analyzers, err = a.newPipeline()
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// AnalyzeRecorded runs the analyzer pipeline over a recorded pod, its logs
+// and events, without collecting anything from the cluster
+func (a *Agent) AnalyzeRecorded(ctx context.Context, req AnalysisRequest, podInfo *collectors.PodInfo) (*models.AnalysisResult, error) {
+	ctx, stages := withStageTimer(ctx)
+	ctx, usage := llm.WithUsage(ctx)
+	result, err := a.analyzeCollected(ctx, req, podInfo, &models.AnalysisResult{})
+	a.reporter(ctx).Stop()
+	if err != nil {
+		return nil, err
+	}
+	a.recordUsage(ctx, result, usage)
+	result.Stages = stages.finish()
+	return result, nil
+}
@@ -0,0 +1,458 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/emirozbir/micro-sre/internal/collectors"
+	"github.com/emirozbir/micro-sre/internal/config"
+	"github.com/emirozbir/micro-sre/internal/llm"
+	"github.com/emirozbir/micro-sre/internal/models"
+	"github.com/emirozbir/micro-sre/internal/rules"
+	"github.com/emirozbir/micro-sre/internal/tracing"
+)
+
+// Built-in analyzer names
+const (
+	AnalyzerRules      = "rules"
+	AnalyzerHeuristics = "heuristics"
+	AnalyzerLLM        = "llm"
+)
+
+// defaultAnalyzers is the pipeline used when agent.analyzers is unset
+var defaultAnalyzers = []string{AnalyzerRules, AnalyzerLLM}
+
+// hintCertainty is the certainty of a heuristic triage that adopted the
+// cause suspected by an earlier analyzer
+const hintCertainty = 55
+
+// Analyzer is a step of the analysis pipeline. Analyzers run in the order
+// of agent.analyzers; each sees the findings of those before it and returns
+// its own, or nil if it has nothing to add.
+type Analyzer interface {
+	Name() string
+	Analyze(ctx context.Context, in *AnalyzerInput) (*Finding, error)
+}
+
+// AnalyzerInput is the data an analysis was collected for. Pods holds the
+// analyzed pod, or the sampled pods of a workload.
+type AnalyzerInput struct {
+	Namespace string
+	Verbosity string
+	Feedback  *models.Feedback
+	Pods      []*collectors.PodInfo
+	// Collected holds the supplementary context (silences, OOM
+	// correlation, rollout, ...)
+	Collected *models.AnalysisResult
+	// Sections are the context sections of the LLM prompt
+	Sections []string
+	// Prompt renders the LLM prompt with the given context sections
+	Prompt func(sections []string) string
+	// Findings are those of the analyzers that ran before
+	Findings []Finding
+}
+
+// Finding is the contribution of an analyzer. A hint only suggests a cause
+// to the analyzers after it; a conclusive finding answers the analysis and
+// ends the pipeline.
+type Finding struct {
+	Analyzer   string
+	Analysis   models.Analysis
+	Hint       bool
+	Conclusive bool
+	// Rule is the matched rule, for findings of the rules analyzer
+	Rule string
+	// Model, PromptVersion and Redactions are set by the LLM analyzer
+	Model         string
+	PromptVersion string
+	Redactions    []models.Redaction
+}
+
+// AnalyzerFactory creates an analyzer for an agent
+type AnalyzerFactory func(a *Agent) (Analyzer, error)
+
+var (
+	analyzersMu       sync.RWMutex
+	analyzerFactories = map[string]AnalyzerFactory{
+		AnalyzerRules: func(a *Agent) (Analyzer, error) {
+			return &rulesAnalyzer{config: a.config.Rules}, nil
+		},
+		AnalyzerHeuristics: func(a *Agent) (Analyzer, error) {
+			return heuristicAnalyzer{}, nil
+		},
+		AnalyzerLLM: func(a *Agent) (Analyzer, error) {
+			if a.llmClient == nil {
+				return nil, fmt.Errorf("LLM client not configured")
+			}
+			return &llmAnalyzer{agent: a}, nil
+		},
+	}
+)
+
+// RegisterAnalyzer makes a custom analyzer available to agent.analyzers
+// under name. It must be called before NewAgent.
+func RegisterAnalyzer(name string, factory AnalyzerFactory) {
+	analyzersMu.Lock()
+	defer analyzersMu.Unlock()
+	analyzerFactories[name] = factory
+}
+
+// analyzerNames returns the configured pipeline, or the default one
+func analyzerNames(cfg config.AgentConfig) []string {
+	if len(cfg.Analyzers) == 0 {
+		return defaultAnalyzers
+	}
+	return cfg.Analyzers
+}
+
+// usesLLM reports whether the configured pipeline includes the LLM
+func usesLLM(cfg config.AgentConfig) bool {
+	for _, name := range analyzerNames(cfg) {
+		if name == AnalyzerLLM {
+			return true
+		}
+	}
+	return false
+}
+
+// newPipeline creates the configured analyzers, in order
+func (a *Agent) newPipeline() ([]Analyzer, error) {
+	analyzersMu.RLock()
+	defer analyzersMu.RUnlock()
+
+	var pipeline []Analyzer
+	for _, name := range analyzerNames(a.config.Agent) {
+		factory, ok := analyzerFactories[name]
+		if !ok {
+			known := make([]string, 0, len(analyzerFactories))
+			for k := range analyzerFactories {
+				known = append(known, k)
+			}
+			sort.Strings(known)
+			return nil, fmt.Errorf("unknown analyzer %q in agent.analyzers (known: %v)", name, known)
+		}
+		analyzer, err := factory(a)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create analyzer %s: %w", name, err)
+		}
+		pipeline = append(pipeline, analyzer)
+	}
+	return pipeline, nil
+}
+
+// pipelineOutcome is the merged analysis of a pipeline run and the
+// findings it was merged from
+type pipelineOutcome struct {
+	Analysis models.Analysis
+	Findings []Finding
+	// BudgetExceeded is set when the LLM was skipped for the token budget
+	BudgetExceeded bool
+}
+
+// finding returns the finding of the named analyzer, or nil
+func (o *pipelineOutcome) finding(analyzer string) *Finding {
+	for i := range o.Findings {
+		if o.Findings[i].Analyzer == analyzer {
+			return &o.Findings[i]
+		}
+	}
+	return nil
+}
+
+// analyzers returns the names of the analyzers that contributed
+func (o *pipelineOutcome) analyzers() []string {
+	names := make([]string, 0, len(o.Findings))
+	for _, f := range o.Findings {
+		names = append(names, f.Analyzer)
+	}
+	return names
+}
+
+// apply copies what the findings say about how the analysis was produced
+// onto result
+func (o *pipelineOutcome) apply(result *models.AnalysisResult) {
+	result.Analyzers = o.analyzers()
+	result.BudgetExceeded = o.BudgetExceeded
+	if f := o.finding(AnalyzerLLM); f != nil {
+		result.Model = f.Model
+		result.PromptVersion = f.PromptVersion
+		result.Redactions = f.Redactions
+	}
+	if f := o.finding(AnalyzerRules); f != nil {
+		result.Rule = &models.RuleMatch{
+			Rule:     f.Rule,
+			Cause:    f.Analysis.RootCause,
+			Answered: f.Conclusive,
+		}
+	}
+}
+
+// runPipeline runs the analyzers in order and merges their findings. The
+// last full (non-hint) finding is the answer; evidence, timeline and
+// recommendations of the other full findings are added to it. An analyzer
+// refused by the token budget is replaced by heuristic triage when
+// llm.budget.on_exceeded is fallback.
+func (a *Agent) runPipeline(ctx context.Context, in *AnalyzerInput) (*pipelineOutcome, error) {
+	budgetExceeded := false
+	for _, analyzer := range a.analyzers {
+		name := analyzer.Name()
+		f, err := analyzer.Analyze(ctx, in)
+		if a.budgetFallback(err) {
+			a.log(ctx).Warn("LLM token budget exceeded, falling back to heuristic triage",
+				zap.String("namespace", in.Namespace), zap.Error(err))
+			budgetExceeded = true
+			name, f, err = AnalyzerHeuristics, nil, nil
+			if !hasFinding(in.Findings, AnalyzerHeuristics) {
+				f, err = heuristicAnalyzer{}.Analyze(ctx, in)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		if f == nil {
+			continue
+		}
+		f.Analyzer = name
+		in.Findings = append(in.Findings, *f)
+		if f.Conclusive {
+			a.log(ctx).Info("analysis answered by analyzer", zap.String("analyzer", f.Analyzer), zap.String("rule", f.Rule))
+			break
+		}
+	}
+
+	outcome := &pipelineOutcome{Findings: in.Findings, BudgetExceeded: budgetExceeded}
+	primary := -1
+	for i, f := range in.Findings {
+		if !f.Hint {
+			primary = i
+		}
+	}
+	if primary < 0 && len(in.Findings) > 0 {
+		primary = len(in.Findings) - 1
+	}
+	if primary < 0 {
+		outcome.Analysis = models.Analysis{
+			RootCause:  "No analyzer identified a root cause",
+			Confidence: "unknown",
+		}
+		return outcome, nil
+	}
+
+	outcome.Analysis = in.Findings[primary].Analysis
+	for i, f := range in.Findings {
+		if i != primary && !f.Hint {
+			mergeAnalysis(&outcome.Analysis, f.Analysis)
+		}
+	}
+	return outcome, nil
+}
+
+// hasFinding reports whether the named analyzer contributed a finding
+func hasFinding(findings []Finding, analyzer string) bool {
+	for _, f := range findings {
+		if f.Analyzer == analyzer {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeAnalysis adds the evidence, timeline and recommendations of other
+// that dst does not have yet
+func mergeAnalysis(dst *models.Analysis, other models.Analysis) {
+	logs := map[string]bool{}
+	for _, l := range dst.Evidence.Logs {
+		logs[l.Line] = true
+	}
+	for _, l := range other.Evidence.Logs {
+		if !logs[l.Line] {
+			logs[l.Line] = true
+			dst.Evidence.Logs = append(dst.Evidence.Logs, l)
+		}
+	}
+
+	events := map[string]bool{}
+	for _, e := range dst.Evidence.Events {
+		events[e.Reason+"\x00"+e.Message] = true
+	}
+	for _, e := range other.Evidence.Events {
+		if key := e.Reason + "\x00" + e.Message; !events[key] {
+			events[key] = true
+			dst.Evidence.Events = append(dst.Evidence.Events, e)
+		}
+	}
+
+	timeline := map[string]bool{}
+	for _, t := range dst.Timeline {
+		timeline[t.Event+"\x00"+t.Timestamp.String()] = true
+	}
+	for _, t := range other.Timeline {
+		if key := t.Event + "\x00" + t.Timestamp.String(); !timeline[key] {
+			timeline[key] = true
+			dst.Timeline = append(dst.Timeline, t)
+		}
+	}
+
+	recs := map[string]bool{}
+	for _, r := range dst.Recommendations {
+		recs[recommendationKey(r)] = true
+	}
+	for _, r := range other.Recommendations {
+		if key := recommendationKey(r); !recs[key] {
+			recs[key] = true
+			dst.Recommendations = append(dst.Recommendations, r)
+		}
+	}
+}
+
+func recommendationKey(r models.Recommendation) string {
+	if r.Command != "" {
+		return r.Command
+	}
+	return r.Action
+}
+
+// rulesAnalyzer matches deterministic rules against a single pod. A
+// conclusive match answers the analysis unless rules.answer_without_llm is
+// off or a human rejected the previous answer; any other match is a hint.
+type rulesAnalyzer struct {
+	config config.RulesConfig
+}
+
+func (r *rulesAnalyzer) Name() string { return AnalyzerRules }
+
+func (r *rulesAnalyzer) Analyze(ctx context.Context, in *AnalyzerInput) (*Finding, error) {
+	if !r.config.Enabled || len(in.Pods) != 1 {
+		return nil, nil
+	}
+	match := rules.Classify(rules.Input{Pod: in.Pods[0].Pod, Events: in.Pods[0].Events, OOM: in.Collected.OOM})
+	if match == nil {
+		return nil, nil
+	}
+	// A human rejected the previous answer, which may have been the rule's
+	conclusive := match.Conclusive && r.config.AnswerWithoutLLM && in.Feedback == nil
+	return &Finding{
+		Analysis:   match.Analysis,
+		Rule:       match.Rule,
+		Conclusive: conclusive,
+		Hint:       !conclusive,
+	}, nil
+}
+
+// heuristicAnalyzer triages the failing state of the pods without the
+// LLM. It adopts the cause suspected by an earlier hint, if any.
+type heuristicAnalyzer struct{}
+
+func (heuristicAnalyzer) Name() string { return AnalyzerHeuristics }
+
+func (heuristicAnalyzer) Analyze(ctx context.Context, in *AnalyzerInput) (*Finding, error) {
+	if len(in.Pods) == 0 {
+		return nil, nil
+	}
+
+	// The first failing pod names the cause; the others add evidence
+	triages := make([]models.Analysis, len(in.Pods))
+	primary := -1
+	for i, info := range in.Pods {
+		var failing bool
+		triages[i], failing = rules.Triage(rules.Input{Pod: info.Pod, Events: info.Events, Logs: info.Logs})
+		if failing && primary < 0 {
+			primary = i
+		}
+	}
+	primary = max(primary, 0)
+	analysis := triages[primary]
+	for i, triage := range triages {
+		if i != primary {
+			mergeAnalysis(&analysis, triage)
+		}
+	}
+
+	for i := len(in.Findings) - 1; i >= 0; i-- {
+		hint := in.Findings[i]
+		if !hint.Hint {
+			continue
+		}
+		analysis.RootCause = hint.Analysis.RootCause
+		analysis.Reasoning = hint.Analysis.Reasoning + " " + analysis.Reasoning
+		analysis.Confidence = "medium"
+		analysis.ConfidenceRubric = &models.ConfidenceRubric{SelfReportedLevel: "medium", Certainty: hintCertainty}
+		break
+	}
+	return &Finding{Analysis: analysis}, nil
+}
+
+// llmAnalyzer asks the LLM, with the earlier hints added to the prompt so
+// that it confirms or refutes them
+type llmAnalyzer struct {
+	agent *Agent
+}
+
+func (l *llmAnalyzer) Name() string { return AnalyzerLLM }
+
+func (l *llmAnalyzer) Analyze(ctx context.Context, in *AnalyzerInput) (*Finding, error) {
+	a := l.agent
+
+	sections := append([]string(nil), in.Sections...)
+	for _, f := range in.Findings {
+		if f.Hint {
+			sections = append(sections, formatHint(f))
+		}
+	}
+
+	a.stage(ctx, StageBuildPrompt, "Building analysis context...")
+	pods := make([]*corev1.Pod, 0, len(in.Pods))
+	for _, info := range in.Pods {
+		pods = append(pods, info.Pod)
+	}
+	redactor := a.redactor
+	if redactor.RedactsSecrets() && a.k8sCollector != nil {
+		values, err := a.k8sCollector.GetSecretValues(ctx, pods...)
+		if err != nil {
+			a.log(ctx).Warn("failed to read referenced secrets for redaction", zap.Error(err))
+		}
+		redactor = redactor.WithSecrets(values)
+	}
+	prompt, redactions := redactor.Redact(in.Prompt(sections), pods...)
+
+	a.stage(ctx, StageQueryLLM, "Analyzing with AI (this may take 5-15 seconds)...")
+	a.log(ctx).Info("sending data to LLM for analysis")
+	analysisText, err := a.llmClient.Analyze(a.llmContext(ctx, in.Namespace, in.Verbosity), prompt)
+	if err != nil {
+		return nil, fmt.Errorf("LLM analysis failed: %w", err)
+	}
+
+	a.stage(ctx, StageParseResponse, "Parsing AI response...")
+	_, span := tracing.Start(ctx, "parse analysis",
+		trace.WithAttributes(attribute.Int("hepsre.response_bytes", len(analysisText))))
+	analysis := a.parseAnalysis(analysisText)
+	span.SetAttributes(attribute.String("hepsre.confidence", analysis.Confidence))
+	span.End()
+
+	return &Finding{
+		Analysis:      analysis,
+		Model:         llm.Model(ctx, a.config.LLM.Model),
+		PromptVersion: PromptVersion,
+		Redactions:    redactions,
+	}, nil
+}
+
+// formatHint tells the LLM what an earlier analyzer suspects, so that it
+// confirms or refutes the cause instead of starting from scratch
+func formatHint(f Finding) string {
+	source := "analyzer " + f.Analyzer
+	if f.Rule != "" {
+		source = "deterministic rule " + f.Rule
+	}
+	return fmt.Sprintf(`SUSPECTED CAUSE (%s):
+%s
+%s
+Confirm or refute this using the data above.`, source, f.Analysis.RootCause, f.Analysis.Reasoning)
+}
@@ -0,0 +1,65 @@
+package agent
+
+import (
+	"context"
+	"time"
+
+	"github.com/emirozbir/micro-sre/internal/chaos"
+	"github.com/emirozbir/micro-sre/internal/collectors"
+)
+
+// defaultMaxParallelFetches is used when agent.max_parallel_fetches is unset
+const defaultMaxParallelFetches = 5
+
+// retryDelay is how long do waits before retrying a transient failure
+const retryDelay = 500 * time.Millisecond
+
+// fetchPool bounds the number of collector calls in flight across every
+// analysis run by an agent, protecting the kube-apiserver and other backends
+// from request storms during alert floods
+type fetchPool struct {
+	slots chan struct{}
+	// chaos injects simulated faults into the calls, by data source
+	chaos *chaos.Injector
+}
+
+func newFetchPool(size int, injector *chaos.Injector) *fetchPool {
+	if size <= 0 {
+		size = defaultMaxParallelFetches
+	}
+	return &fetchPool{slots: make(chan struct{}, size), chaos: injector}
+}
+
+// do runs fn, which queries the data source named source, once a slot is
+// free and retries it once if it fails with a transient error (timeout,
+// connection refused). fn must be safe to repeat.
+func (p *fetchPool) do(ctx context.Context, source string, fn func() error) error {
+	err := p.doOnce(ctx, source, fn)
+	if err == nil || !collectors.Retryable(err) || ctx.Err() != nil {
+		return err
+	}
+
+	select {
+	case <-time.After(retryDelay):
+	case <-ctx.Done():
+		return err
+	}
+	return p.doOnce(ctx, source, fn)
+}
+
+// doOnce runs fn once a slot is free, without retries. It returns ctx.Err()
+// without running fn if the context is done first. fn must not call the pool
+// itself, or a full pool would deadlock.
+func (p *fetchPool) doOnce(ctx context.Context, source string, fn func() error) error {
+	select {
+	case p.slots <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	defer func() { <-p.slots }()
+
+	if err := p.chaos.Inject(ctx, source); err != nil {
+		return err
+	}
+	return fn()
+}
@@ -0,0 +1,334 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/emirozbir/micro-sre/internal/llm"
+	"github.com/emirozbir/micro-sre/internal/models"
+)
+
+// Postmortem limits
+const (
+	// maxPostmortemIncidents bounds the incidents listed in the prompt; the
+	// most recent are kept
+	maxPostmortemIncidents = 200
+	maxPostmortemCauses    = 10
+	maxPostmortemActions   = 5
+)
+
+// postmortemTask is the instruction and response-format part of the
+// postmortem prompt
+const postmortemTask = `TASK:
+1. Summarize the period for engineering leadership in 3-5 sentences: what broke, how often, and whether it is getting better or worse
+2. Group incidents that share a root cause, even if it is worded differently; list only groups of two or more incidents, largest first
+3. Propose concrete action items that would prevent the recurring causes, each with a priority and the owning service
+
+Please respond in JSON format with the following structure:
+{
+  "summary": "...",
+  "recurring_causes": [
+    {"cause": "...", "analysis_ids": [12, 15]}
+  ],
+  "action_items": [
+    {"priority": "high|medium|low", "action": "...", "owner": "namespace/service"}
+  ]
+}`
+
+type postmortemResponse struct {
+	Summary         string `json:"summary"`
+	RecurringCauses []struct {
+		Cause       string  `json:"cause"`
+		AnalysisIDs []int64 `json:"analysis_ids"`
+	} `json:"recurring_causes"`
+	ActionItems []models.PostmortemAction `json:"action_items"`
+}
+
+// podNameSuffix matches the suffixes controllers add to pod names: the
+// ReplicaSet hash and random suffix of Deployment pods, the random suffix of
+// DaemonSet and Job pods, and the ordinal of StatefulSet pods. Generated
+// suffixes use Kubernetes' vowel-free alphabet, so words in names are kept.
+var podNameSuffix = regexp.MustCompile(`(-[bcdfghjklmnpqrstvwxz2456789]{6,10})?-[bcdfghjklmnpqrstvwxz2456789]{5}$|-[0-9]+$`)
+
+// serviceName returns the workload name of an incident target: the name of
+// a "<Kind>/<name>" workload, or the pod name without controller suffixes
+func serviceName(target string) string {
+	if i := strings.Index(target, "/"); i >= 0 {
+		return target[i+1:]
+	}
+	if name := podNameSuffix.ReplaceAllString(target, ""); name != "" {
+		return name
+	}
+	return target
+}
+
+// Postmortem summarizes the incidents analyzed between from and to:
+// affected services are counted from the incidents, while recurring causes,
+// action items and the summary come from the LLM. Without the LLM in the
+// pipeline, or once its token budget is spent, causes are grouped by
+// identical root cause and the most common recommendations become the
+// action items.
+func (a *Agent) Postmortem(ctx context.Context, period string, from, to time.Time, namespace string, incidents []models.PostmortemIncident) (*models.PostmortemReport, error) {
+	a.log(ctx).Info("starting postmortem report",
+		zap.String("period", period),
+		zap.String("namespace", namespace),
+		zap.Int("incidents", len(incidents)),
+	)
+
+	ctx, stages := withStageTimer(ctx)
+	report := &models.PostmortemReport{
+		Period:           period,
+		From:             from,
+		To:               to,
+		Namespace:        namespace,
+		GeneratedAt:      time.Now(),
+		Incidents:        len(incidents),
+		AffectedServices: affectedServices(incidents),
+		RecurringCauses:  groupCauses(incidents),
+		ActionItems:      commonActions(incidents),
+	}
+	report.Summary = postmortemSummary(report)
+
+	if len(incidents) == 0 || a.llmClient == nil {
+		report.Stages = stages.finish()
+		a.reporter(ctx).Stop()
+		return report, nil
+	}
+
+	a.stage(ctx, StageBuildPrompt, "Building postmortem context...")
+	prompt, redactions := a.redactor.Redact(a.buildPostmortemPrompt(report, incidents))
+
+	a.stage(ctx, StageQueryLLM, "Writing postmortem with AI...")
+	analysisText, err := a.llmClient.Analyze(llm.WithTenant(ctx, namespace), prompt)
+	if a.budgetFallback(err) {
+		// Keep the counted causes and actions
+		a.log(ctx).Warn("LLM token budget exceeded, counting causes only", zap.Error(err))
+		report.Stages = stages.finish()
+		a.reporter(ctx).Stop()
+		return report, nil
+	}
+	if err != nil {
+		a.reporter(ctx).Stop()
+		return nil, fmt.Errorf("LLM analysis failed: %w", err)
+	}
+	report.Model = a.config.LLM.Model
+	report.Redactions = redactions
+
+	a.stage(ctx, StageParseResponse, "Parsing AI response...")
+	var resp postmortemResponse
+	if err := json.Unmarshal([]byte(a.extractJSON(analysisText)), &resp); err != nil {
+		// Keep the counted causes and actions
+		a.log(ctx).Warn("failed to parse postmortem response", zap.Error(err))
+		report.Summary = analysisText
+	} else {
+		known := make(map[int64]bool, len(incidents))
+		for _, in := range incidents {
+			known[in.ID] = true
+		}
+		var causes []models.PostmortemCause
+		for _, c := range resp.RecurringCauses {
+			var ids []int64
+			for _, id := range c.AnalysisIDs {
+				if known[id] {
+					ids = append(ids, id)
+				}
+			}
+			if len(ids) == 0 || c.Cause == "" {
+				continue
+			}
+			causes = append(causes, models.PostmortemCause{Cause: c.Cause, Count: len(ids), AnalysisIDs: ids})
+		}
+		sort.SliceStable(causes, func(i, j int) bool { return causes[i].Count > causes[j].Count })
+
+		if resp.Summary != "" {
+			report.Summary = resp.Summary
+		}
+		report.RecurringCauses = causes
+		if len(resp.ActionItems) > 0 {
+			report.ActionItems = resp.ActionItems
+		}
+	}
+
+	report.Stages = stages.finish()
+	a.reporter(ctx).Stop()
+
+	a.log(ctx).Info("postmortem report completed",
+		zap.String("period", period),
+		zap.Int("recurring_causes", len(report.RecurringCauses)),
+		zap.Int("action_items", len(report.ActionItems)),
+	)
+
+	return report, nil
+}
+
+// affectedServices counts incidents per service, most affected first
+func affectedServices(incidents []models.PostmortemIncident) []models.PostmortemService {
+	type service struct {
+		models.PostmortemService
+		alerts map[string]int
+	}
+	byKey := map[string]*service{}
+	var order []string
+	for _, in := range incidents {
+		key := in.Namespace + "/" + serviceName(in.Target)
+		s, ok := byKey[key]
+		if !ok {
+			s = &service{
+				PostmortemService: models.PostmortemService{Namespace: in.Namespace, Service: serviceName(in.Target)},
+				alerts:            map[string]int{},
+			}
+			byKey[key] = s
+			order = append(order, key)
+		}
+		s.Incidents++
+		if strings.EqualFold(in.Severity, "critical") {
+			s.Critical++
+		}
+		s.alerts[in.AlertName]++
+	}
+
+	services := make([]models.PostmortemService, 0, len(order))
+	for _, key := range order {
+		s := byKey[key]
+		for name := range s.alerts {
+			s.Alerts = append(s.Alerts, name)
+		}
+		sort.Slice(s.Alerts, func(i, j int) bool {
+			if s.alerts[s.Alerts[i]] != s.alerts[s.Alerts[j]] {
+				return s.alerts[s.Alerts[i]] > s.alerts[s.Alerts[j]]
+			}
+			return s.Alerts[i] < s.Alerts[j]
+		})
+		services = append(services, s.PostmortemService)
+	}
+	sort.SliceStable(services, func(i, j int) bool { return services[i].Incidents > services[j].Incidents })
+	return services
+}
+
+// groupCauses groups incidents with the same root cause, ignoring case and
+// surrounding whitespace; only causes shared by two or more are returned
+func groupCauses(incidents []models.PostmortemIncident) []models.PostmortemCause {
+	byCause := map[string]*models.PostmortemCause{}
+	var order []string
+	for _, in := range incidents {
+		key := strings.ToLower(strings.TrimSpace(in.RootCause))
+		if key == "" {
+			continue
+		}
+		c, ok := byCause[key]
+		if !ok {
+			c = &models.PostmortemCause{Cause: in.RootCause}
+			byCause[key] = c
+			order = append(order, key)
+		}
+		c.Count++
+		c.AnalysisIDs = append(c.AnalysisIDs, in.ID)
+	}
+
+	var causes []models.PostmortemCause
+	for _, key := range order {
+		if c := byCause[key]; c.Count > 1 {
+			causes = append(causes, *c)
+		}
+	}
+	sort.SliceStable(causes, func(i, j int) bool { return causes[i].Count > causes[j].Count })
+	if len(causes) > maxPostmortemCauses {
+		causes = causes[:maxPostmortemCauses]
+	}
+	return causes
+}
+
+// commonActions returns the recommendations made for two or more
+// incidents, most frequent first
+func commonActions(incidents []models.PostmortemIncident) []models.PostmortemAction {
+	counts := map[string]int{}
+	var order []string
+	for _, in := range incidents {
+		seen := map[string]bool{}
+		for _, rec := range in.Recommendations {
+			if seen[rec] {
+				continue
+			}
+			seen[rec] = true
+			if counts[rec] == 0 {
+				order = append(order, rec)
+			}
+			counts[rec]++
+		}
+	}
+	sort.SliceStable(order, func(i, j int) bool { return counts[order[i]] > counts[order[j]] })
+
+	var actions []models.PostmortemAction
+	for _, rec := range order {
+		if counts[rec] < 2 || len(actions) == maxPostmortemActions {
+			break
+		}
+		actions = append(actions, models.PostmortemAction{
+			Priority: "medium",
+			Action:   fmt.Sprintf("%s (recommended for %d incidents)", rec, counts[rec]),
+		})
+	}
+	return actions
+}
+
+// postmortemSummary is the summary used without the LLM
+func postmortemSummary(report *models.PostmortemReport) string {
+	if report.Incidents == 0 {
+		return fmt.Sprintf("No incidents were analyzed between %s and %s.",
+			report.From.Format(time.RFC822), report.To.Format(time.RFC822))
+	}
+	summary := fmt.Sprintf("%d incidents were analyzed across %d services between %s and %s.",
+		report.Incidents, len(report.AffectedServices), report.From.Format(time.RFC822), report.To.Format(time.RFC822))
+	if len(report.AffectedServices) > 0 {
+		s := report.AffectedServices[0]
+		summary += fmt.Sprintf(" The most affected service was %s/%s with %d incidents.", s.Namespace, s.Service, s.Incidents)
+	}
+	if len(report.RecurringCauses) > 0 {
+		c := report.RecurringCauses[0]
+		summary += fmt.Sprintf(" The most frequent cause (%d incidents) was: %s", c.Count, c.Cause)
+	}
+	return summary
+}
+
+func (a *Agent) buildPostmortemPrompt(report *models.PostmortemReport, incidents []models.PostmortemIncident) string {
+	var sb strings.Builder
+	scope := ""
+	if report.Namespace != "" {
+		scope = " in namespace " + report.Namespace
+	}
+	sb.WriteString(fmt.Sprintf("You are an SRE writing a %s postmortem digest of the incidents analyzed%s between %s and %s.\n\n",
+		report.Period, scope, report.From.Format(time.RFC3339), report.To.Format(time.RFC3339)))
+
+	listed := incidents
+	if len(listed) > maxPostmortemIncidents {
+		listed = listed[:maxPostmortemIncidents]
+		sb.WriteString(fmt.Sprintf("INCIDENTS (%d, the most recent %d listed):\n", len(incidents), len(listed)))
+	} else {
+		sb.WriteString(fmt.Sprintf("INCIDENTS (%d):\n", len(incidents)))
+	}
+	for _, in := range listed {
+		recurring := ""
+		if in.Recurring {
+			recurring = " (recurring)"
+		}
+		sb.WriteString(fmt.Sprintf("#%d %s [%s] %s %s/%s%s: %s (confidence %s)\n",
+			in.ID, in.CreatedAt.Format("2006-01-02 15:04"), in.Severity, in.AlertName, in.Namespace, in.Target, recurring, in.RootCause, in.Confidence))
+	}
+
+	sb.WriteString("\nAFFECTED SERVICES:\n")
+	for _, s := range report.AffectedServices {
+		sb.WriteString(fmt.Sprintf("- %s/%s: %d incidents (%d critical); alerts: %s\n",
+			s.Namespace, s.Service, s.Incidents, s.Critical, strings.Join(s.Alerts, ", ")))
+	}
+
+	sb.WriteString("\n")
+	sb.WriteString(postmortemTask)
+	return sb.String()
+}
@@ -0,0 +1,64 @@
+package agent
+
+import (
+	"fmt"
+
+	"github.com/emirozbir/micro-sre/internal/collectors"
+	"github.com/emirozbir/micro-sre/internal/config"
+)
+
+// collectionPlan is the collection profile applied to one analysis. The
+// zero plan collects everything with the global settings.
+type collectionPlan struct {
+	severity string
+	profile  config.CollectionProfile
+	// container is the container the alert asks logs to be collected from
+	container string
+}
+
+// collectionPlan returns the plan for the severity of the request's alert,
+// collecting logs from the container its annotation names
+func (a *Agent) collectionPlan(req AnalysisRequest) collectionPlan {
+	if req.Alert == nil {
+		return collectionPlan{}
+	}
+	severity := req.Alert.GetSeverity()
+	return collectionPlan{
+		severity:  severity,
+		profile:   a.config.CollectionProfileFor(severity),
+		container: req.Alert.Container(),
+	}
+}
+
+// disabled reports whether the profile turns a data source off, recording
+// it as skipped in the manifest if so
+func (p collectionPlan) disabled(source string, manifest *manifestRecorder) bool {
+	if p.profile.Enabled(source) {
+		return false
+	}
+	manifest.skip(source, fmt.Sprintf("disabled by the %s collection profile", p.severity))
+	return true
+}
+
+func (p collectionPlan) logOptions() collectors.LogOptions {
+	return collectors.LogOptions{
+		TailLines:       p.profile.TailLines,
+		IncludePrevious: p.profile.IncludePrevious,
+		Container:       p.container,
+	}
+}
+
+// validateCollectionProfiles rejects profiles naming unknown data sources
+func validateCollectionProfiles(profiles map[string]config.CollectionProfile) error {
+	for severity, profile := range profiles {
+		for _, source := range profile.Collectors {
+			if source == collectors.SourcePod || source == collectors.SourceWorkload {
+				return fmt.Errorf("collection profile %q: %s cannot be disabled and must not be listed", severity, source)
+			}
+			if _, ok := collectors.SourceVersions[source]; !ok {
+				return fmt.Errorf("collection profile %q: unknown collector %q", severity, source)
+			}
+		}
+	}
+	return nil
+}
@@ -1,6 +1,13 @@
 package agent
 
-import "github.com/emirozbir/micro-sre/internal/ui"
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/emirozbir/micro-sre/internal/models"
+	"github.com/emirozbir/micro-sre/internal/ui"
+)
 
 // NoOpProgressReporter is a no-op implementation for JSON/API modes
 type NoOpProgressReporter struct{}
@@ -10,3 +17,91 @@ func (n *NoOpProgressReporter) Stop()                 {}
 
 // Ensure NoOpProgressReporter implements ui.ProgressReporter
 var _ ui.ProgressReporter = (*NoOpProgressReporter)(nil)
+
+type progressKey struct{}
+
+// WithProgress returns a context whose analysis reports progress to reporter
+// instead of the agent-wide reporter. The server uses it to follow a single
+// analysis while others run concurrently.
+func WithProgress(ctx context.Context, reporter ui.ProgressReporter) context.Context {
+	return context.WithValue(ctx, progressKey{}, reporter)
+}
+
+// reporter returns the progress reporter for an analysis running under ctx
+func (a *Agent) reporter(ctx context.Context) ui.ProgressReporter {
+	if r, ok := ctx.Value(progressKey{}).(ui.ProgressReporter); ok {
+		return r
+	}
+	return a.progress
+}
+
+// Analysis stages, reported to the progress reporter as they start and
+// timed in the result
+const (
+	StageFetchPod       = "fetch_pod"
+	StageFetchWorkload  = "fetch_workload"
+	StageScanNamespace  = "scan_namespace"
+	StageLoadBundle     = "load_bundle"
+	StageCollectLogs    = "collect_logs"
+	StageCollectContext = "collect_context"
+	StageBuildPrompt    = "build_prompt"
+	StageQueryLLM       = "query_llm"
+	StageParseResponse  = "parse_response"
+	StageEscalate       = "escalate"
+	StageStoreArtifacts = "store_artifacts"
+)
+
+type stagesKey struct{}
+
+// stageTimer times the stages of one analysis. Stages run one after the
+// other: starting a stage ends the previous one.
+type stageTimer struct {
+	mu      sync.Mutex
+	stages  []models.Stage
+	current string
+	started time.Time
+}
+
+// withStageTimer returns a context under which the stages of an analysis
+// are timed
+func withStageTimer(ctx context.Context) (context.Context, *stageTimer) {
+	t := &stageTimer{}
+	return context.WithValue(ctx, stagesKey{}, t), t
+}
+
+// stage starts a stage of the analysis running under ctx and reports
+// message to its progress reporter
+func (a *Agent) stage(ctx context.Context, name, message string) {
+	if t, ok := ctx.Value(stagesKey{}).(*stageTimer); ok {
+		t.start(name)
+	}
+	a.reporter(ctx).Update(message)
+}
+
+func (t *stageTimer) start(name string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.end()
+	t.current, t.started = name, time.Now()
+}
+
+// finish ends the current stage and returns all stages
+func (t *stageTimer) finish() []models.Stage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.end()
+	return t.stages
+}
+
+func (t *stageTimer) end() {
+	if t.current == "" {
+		return
+	}
+	d := time.Since(t.started)
+	t.stages = append(t.stages, models.Stage{
+		Name:       t.current,
+		Duration:   d.Round(time.Millisecond).String(),
+		DurationMs: d.Milliseconds(),
+	})
+	t.current = ""
+}
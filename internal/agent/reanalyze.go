@@ -0,0 +1,125 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/emirozbir/micro-sre/internal/collectors"
+	"github.com/emirozbir/micro-sre/internal/llm"
+	"github.com/emirozbir/micro-sre/internal/models"
+)
+
+// ErrNoBundle is returned when a past analysis cannot be re-run because its
+// collected data was not kept in the artifact store
+var ErrNoBundle = errors.New("analysis has no stored data bundle")
+
+// Reanalyze re-runs a past analysis with the current prompt and model. The
+// pod, logs and events are read back from the artifact store rather than the
+// cluster, so the LLM sees exactly the data the original analysis saw; the
+// supplementary context is taken from the original result.
+func (a *Agent) Reanalyze(ctx context.Context, original *models.AnalysisResult) (*models.AnalysisResult, error) {
+	return a.ReanalyzeWithFeedback(ctx, original, nil)
+}
+
+// ReanalyzeWithFeedback re-runs a past analysis like Reanalyze, telling the
+// LLM which root cause a human rejected and their hint. A nil feedback is a
+// plain re-analysis.
+func (a *Agent) ReanalyzeWithFeedback(ctx context.Context, original *models.AnalysisResult, feedback *models.Feedback) (*models.AnalysisResult, error) {
+	ctx, stages := withStageTimer(ctx)
+	ctx, usage := llm.WithUsage(ctx)
+	a.stage(ctx, StageLoadBundle, "Loading collected data...")
+	podInfo, err := a.loadBundle(ctx, original.Artifacts)
+	if err != nil {
+		return nil, err
+	}
+
+	lookback, err := time.ParseDuration(original.CollectedData.TimeRange)
+	if err != nil {
+		lookback = time.Hour
+	}
+
+	req := AnalysisRequest{
+		Namespace: original.Alert.Namespace,
+		PodName:   original.Alert.Pod,
+		Lookback:  lookback,
+		Verbosity: original.Verbosity,
+		Feedback:  feedback,
+		// Recurrence as of the original analysis, not counting itself
+		Recurrence: original.Recurrence,
+	}
+
+	a.log(ctx).Info("re-analyzing stored incident",
+		zap.String("namespace", req.Namespace),
+		zap.String("pod", req.PodName),
+		zap.String("original_prompt_version", original.PromptVersion),
+		zap.String("prompt_version", PromptVersion),
+	)
+
+	result, err := a.analyzeCollected(ctx, req, podInfo, original)
+	a.reporter(ctx).Stop()
+	if err != nil {
+		return nil, err
+	}
+	result.Alert = original.Alert
+	result.Manifest = original.Manifest
+	result.Artifacts = original.Artifacts
+	a.recordUsage(ctx, result, usage)
+	result.Stages = stages.finish()
+
+	return result, nil
+}
+
+// loadBundle rebuilds the collected pod data from stored artifacts. The pod
+// spec is required; logs and events are optional since they may legitimately
+// have been empty.
+func (a *Agent) loadBundle(ctx context.Context, refs []models.ArtifactRef) (*collectors.PodInfo, error) {
+	if a.artifacts == nil {
+		return nil, ErrNoBundle
+	}
+
+	blobs := make(map[string][]byte, len(refs))
+	for _, ref := range refs {
+		data, err := a.artifacts.Get(ctx, ref.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read artifact %s: %w", ref.Name, err)
+		}
+		blobs[ref.Name] = data
+	}
+
+	podData, ok := blobs[models.ArtifactPodSpec]
+	if !ok {
+		return nil, ErrNoBundle
+	}
+
+	info := &collectors.PodInfo{
+		Pod:    &corev1.Pod{},
+		Logs:   string(blobs[models.ArtifactLogs]),
+		Events: []corev1.Event{},
+	}
+	if err := json.Unmarshal(podData, info.Pod); err != nil {
+		return nil, fmt.Errorf("failed to decode stored pod: %w", err)
+	}
+	if events, ok := blobs[models.ArtifactEvents]; ok {
+		if err := json.Unmarshal(events, &info.Events); err != nil {
+			return nil, fmt.Errorf("failed to decode stored events: %w", err)
+		}
+	}
+
+	return info, nil
+}
+
+// formatFeedback tells the LLM which earlier conclusion a human rejected and
+// what they suggested instead
+func formatFeedback(feedback *models.Feedback) string {
+	return fmt.Sprintf(`HUMAN FEEDBACK ON AN EARLIER ANALYSIS OF THIS DATA:
+An earlier analysis concluded: %q
+An engineer marked this root cause as WRONG and gave this hint: %q
+Re-examine the data with the hint in mind. Do not repeat the rejected root cause unless the data leaves no alternative, and say in the reasoning how the hint was taken into account.
+`, feedback.RejectedRootCause, feedback.Hint)
+}
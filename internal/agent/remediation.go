@@ -0,0 +1,150 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/emirozbir/micro-sre/internal/collectors"
+	"github.com/emirozbir/micro-sre/internal/config"
+	"github.com/emirozbir/micro-sre/internal/models"
+	"github.com/emirozbir/micro-sre/internal/remediation"
+)
+
+// ErrRemediationNotAllowed is returned for commands that auto-remediation
+// may not execute: it is disabled, the namespace did not opt in, or the
+// command is not an allowed action
+var ErrRemediationNotAllowed = errors.New("remediation not allowed")
+
+// RemediationPlan is a recommended command that may be executed once
+// approved
+type RemediationPlan struct {
+	Action    string
+	Namespace string
+	Target    string
+}
+
+// validateAutoRemediation rejects unknown remediation actions
+func validateAutoRemediation(cfg config.AutoRemediationConfig) error {
+	for _, action := range cfg.Actions {
+		if !slices.Contains(remediation.Actions, action) {
+			return fmt.Errorf("unknown remediation action %q (want one of %s)", action, strings.Join(remediation.Actions, ", "))
+		}
+	}
+	return nil
+}
+
+// checkCommands validates the command of every recommendation so invalid
+// or disallowed commands are flagged before anyone copies them
+func (a *Agent) checkCommands(analysis *models.Analysis) {
+	for i := range analysis.Recommendations {
+		rec := &analysis.Recommendations[i]
+		if rec.Command == "" {
+			continue
+		}
+		cmd, err := remediation.Validate(rec.Command, a.config.Remediation.AllowedVerbs)
+		if err != nil {
+			rec.Check = &models.CommandCheck{Error: err.Error()}
+			continue
+		}
+		rec.Check = &models.CommandCheck{Valid: true, Verb: cmd.Verb}
+	}
+}
+
+// DryRun validates a recommended command and submits it to the API server
+// with dryRun=All. namespace is used when the command has no -n flag.
+// It returns collectors.ErrReadOnly in read-only mode and
+// collectors.ErrNamespaceNotAllowed for namespaces outside the allowlist.
+func (a *Agent) DryRun(ctx context.Context, command, namespace string) (*models.DryRunResult, error) {
+	if a.runner == nil {
+		return nil, collectors.ErrReadOnly
+	}
+	cmd, err := remediation.Validate(command, a.config.Remediation.AllowedVerbs)
+	if err != nil {
+		return nil, err
+	}
+	if cmd.Namespace != "" {
+		namespace = cmd.Namespace
+	}
+	if !a.k8sCollector.NamespaceAllowed(namespace) {
+		return nil, collectors.ErrNamespaceNotAllowed
+	}
+	result, err := a.runner.DryRun(ctx, command, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dry-run command: %w", err)
+	}
+	return result, nil
+}
+
+// PlanRemediation checks whether a recommended command may be proposed
+// for approval under the auto-remediation settings. Errors wrap
+// ErrRemediationNotAllowed, collectors.ErrReadOnly or
+// remediation.ErrInvalidCommand.
+func (a *Agent) PlanRemediation(command, namespace string) (*RemediationPlan, error) {
+	_, plan, err := a.planRemediation(command, namespace)
+	return plan, err
+}
+
+func (a *Agent) planRemediation(command, namespace string) (*remediation.Command, *RemediationPlan, error) {
+	if a.runner == nil {
+		return nil, nil, collectors.ErrReadOnly
+	}
+	auto := a.config.Remediation.Auto
+	cmd, err := remediation.Validate(command, a.config.Remediation.AllowedVerbs)
+	if err != nil {
+		return nil, nil, err
+	}
+	if cmd.Namespace != "" {
+		namespace = cmd.Namespace
+	}
+	if !auto.NamespaceEnabled(namespace) || !a.k8sCollector.NamespaceAllowed(namespace) {
+		return nil, nil, fmt.Errorf("%w: namespace %s has not opted in", ErrRemediationNotAllowed, namespace)
+	}
+
+	action := remediation.Classify(cmd)
+	if action == "" || !slices.Contains(auto.Actions, action) {
+		return nil, nil, fmt.Errorf("%w: %s is not an enabled action", ErrRemediationNotAllowed, cmd.Verb)
+	}
+	if action == remediation.ActionScale && remediation.Replicas(cmd) > auto.MaxReplicas {
+		return nil, nil, fmt.Errorf("%w: %d replicas is above the limit of %d",
+			ErrRemediationNotAllowed, remediation.Replicas(cmd), auto.MaxReplicas)
+	}
+
+	return cmd, &RemediationPlan{
+		Action:    action,
+		Namespace: namespace,
+		Target:    namespace + "/" + cmd.Target(),
+	}, nil
+}
+
+// ExecuteRemediation applies an approved command. The settings are checked
+// again, as they may have changed since the proposal, and the command is
+// dry-run first so that a change the API server would reject is never
+// attempted.
+func (a *Agent) ExecuteRemediation(ctx context.Context, command, namespace string) error {
+	cmd, plan, err := a.planRemediation(command, namespace)
+	if err != nil {
+		return err
+	}
+
+	check, err := a.runner.DryRun(ctx, command, plan.Namespace)
+	if err != nil {
+		return fmt.Errorf("failed to dry-run command: %w", err)
+	}
+	if check.Status == remediation.DryRunFailed {
+		return fmt.Errorf("dry-run failed: %s", check.Message)
+	}
+
+	if err := a.runner.Execute(ctx, cmd, plan.Namespace); err != nil {
+		return fmt.Errorf("failed to execute %s on %s: %w", plan.Action, plan.Target, err)
+	}
+	a.log(ctx).Info("remediation executed",
+		zap.String("action", plan.Action),
+		zap.String("target", plan.Target),
+	)
+	return nil
+}
@@ -0,0 +1,44 @@
+package agent
+
+import (
+	"context"
+
+	"github.com/emirozbir/micro-sre/internal/config"
+	"github.com/emirozbir/micro-sre/internal/llm"
+)
+
+// verbosityInstructions are appended to the analysis task to size the
+// report; the standard level adds none
+var verbosityInstructions = map[string]string{
+	config.VerbosityBrief: `
+REPORT LENGTH: brief. The report is posted to a chat channel. Keep root_cause to one sentence and reasoning to at most two sentences. Include at most 3 timeline entries, 3 evidence entries and the 2 most important recommendations. Leave hypotheses empty unless the root cause is genuinely ambiguous.`,
+	config.VerbosityDeep: `
+REPORT LENGTH: deep. The report feeds a postmortem. Explain the reasoning in depth, including contributing factors and why alternatives were ruled out. Give a complete timeline, every relevant evidence entry, and both immediate mitigations and long-term fixes as recommendations.`,
+}
+
+// verbosity returns the requested verbosity, or the configured default
+func (a *Agent) verbosity(requested string) string {
+	if config.ValidVerbosity(requested) {
+		return requested
+	}
+	return a.config.Report.Verbosity
+}
+
+// analysisTaskFor returns the analysis task sized for a verbosity level
+func analysisTaskFor(verbosity string) string {
+	return analysisTask + verbosityInstructions[verbosity]
+}
+
+// llmContext prepares ctx for an LLM call: it queues the call as the
+// namespace's on the provider limiter and caps the response of a brief or
+// deep report (standard reports use llm.max_tokens)
+func (a *Agent) llmContext(ctx context.Context, namespace, verbosity string) context.Context {
+	ctx = llm.WithTenant(ctx, namespace)
+	switch verbosity {
+	case config.VerbosityBrief:
+		return llm.WithMaxTokens(ctx, a.config.Report.BriefMaxTokens)
+	case config.VerbosityDeep:
+		return llm.WithMaxTokens(ctx, a.config.Report.DeepMaxTokens)
+	}
+	return ctx
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/emirozbir/micro-sre/internal/requestid"
+)
+
+// quietRoutes are polled by Kubernetes and Prometheus; their requests are
+// logged at debug level only
+var quietRoutes = map[string]bool{
+	routeLivez:   true,
+	routeReadyz:  true,
+	routeHealthz: true,
+	routeMetrics: true,
+}
+
+// assignRequestID takes the caller's X-Request-ID, or makes one up, echoes
+// it in the response and puts it on the request context for the agent and
+// the stored analysis
+func assignRequestID(c *gin.Context) {
+	id := c.GetHeader(requestid.Header)
+	if !requestid.Valid(id) {
+		id = requestid.New()
+	}
+	c.Header(requestid.Header, id)
+	c.Request = c.Request.WithContext(requestid.With(c.Request.Context(), id))
+	c.Next()
+}
+
+// accessLog logs every request with its status and latency, in place of
+// gin's default text logger
+func (h *Handler) accessLog(c *gin.Context) {
+	started := time.Now()
+	c.Next()
+
+	route := c.FullPath()
+	if route == "" {
+		route = "unmatched"
+	}
+	status := c.Writer.Status()
+	fields := []zap.Field{
+		zap.String("method", c.Request.Method),
+		zap.String("path", c.Request.URL.Path),
+		zap.String("route", route),
+		zap.Int("status", status),
+		zap.Duration("latency", time.Since(started)),
+		zap.String("client_ip", c.ClientIP()),
+		zap.Int("bytes", c.Writer.Size()),
+		zap.String("request_id", requestid.From(c.Request.Context())),
+	}
+	if err := c.Errors.Last(); err != nil {
+		fields = append(fields, zap.Error(err))
+	}
+
+	switch {
+	case status >= 500:
+		h.logger.Error("request", fields...)
+	case quietRoutes[route] && status < 400:
+		h.logger.Debug("request", fields...)
+	default:
+		h.logger.Info("request", fields...)
+	}
+}
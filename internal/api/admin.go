@@ -0,0 +1,299 @@
+package api
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/emirozbir/micro-sre/internal/config"
+	"github.com/emirozbir/micro-sre/internal/database"
+	"github.com/emirozbir/micro-sre/internal/models"
+)
+
+// requireAdmin rejects requests without the server.admin_token bearer
+// token, an admin API key or an OIDC token granted the admin scope. Without
+// API keys, admin endpoints are disabled while no token is configured.
+func (h *Handler) requireAdmin(c *gin.Context) {
+	if key, ok := apiKey(c); ok && key.Allows(config.ScopeAdmin) {
+		c.Next()
+		return
+	}
+	if _, ok := identity(c); ok && h.agent().Config().Server.OIDC.Allows(config.ScopeAdmin) {
+		c.Next()
+		return
+	}
+	token := h.agent().Config().Server.AdminToken
+	if token == "" {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "admin endpoints are disabled: set server.admin_token"})
+		return
+	}
+	given, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid or missing admin token"})
+		return
+	}
+	c.Next()
+}
+
+// actor names who made an admin request for the logs and the audit log:
+// the OIDC user, else the API key or admin token, else the proxy user if
+// one is configured, else the client address. Verified credentials come
+// first so a proxy header cannot override them.
+func (h *Handler) actor(c *gin.Context) string {
+	if id, ok := identity(c); ok {
+		return "oidc:" + id.User
+	}
+	if key, ok := apiKey(c); ok {
+		return "api-key:" + key.Name
+	}
+	// Without API keys requests are not authenticated up front, but the
+	// admin token still names its holder
+	if key, ok := findAPIKey(h.agent().Config().Server, requestAPIKey(c)); ok {
+		return "api-key:" + key.Name
+	}
+	if viewer, ok := h.viewer(c); ok && viewer.User != "" {
+		return viewer.User
+	}
+	return c.ClientIP()
+}
+
+// refreshStats recomputes the daily stats from since after analyses were
+// deleted or restored; a zero since recomputes every day
+func (h *Handler) refreshStats(since time.Time) {
+	if _, err := h.db.RollupDailyStats(since); err != nil {
+		h.logger.Warn("failed to refresh daily stats", zap.Error(err))
+	}
+}
+
+// DeleteAnalysis deletes a stored analysis. It can be restored until the
+// retention job purges it with its versions, feedback and alert deliveries.
+func (h *Handler) DeleteAnalysis(c *gin.Context) {
+	analysis, ok := h.loadAnalysis(c)
+	if !ok {
+		return
+	}
+
+	deleted, err := h.db.DeleteAnalysis(analysis.ID)
+	if err != nil {
+		h.logger.Error("failed to delete analysis", zap.Int64("id", analysis.ID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete analysis"})
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "analysis not found"})
+		return
+	}
+	h.refreshStats(analysis.CreatedAt)
+
+	h.logger.Info("analysis deleted", zap.Int64("id", analysis.ID), zap.String("actor", h.actor(c)))
+	c.Status(http.StatusNoContent)
+}
+
+// DeleteAnalyses deletes the stored analyses created before ?before=,
+// optionally narrowed with the search filters (namespace, pod, alert,
+// severity, confidence, q, tag, since), until they are restored or purged.
+// ?dry_run=true only counts them.
+func (h *Handler) DeleteAnalyses(c *gin.Context) {
+	before := c.Query("before")
+	if before == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "before is required"})
+		return
+	}
+	filter, ok := analysisFilter(c)
+	if !ok {
+		return
+	}
+	t, err := parseSearchTime(before, false)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "before must be an RFC 3339 time or a YYYY-MM-DD date"})
+		return
+	}
+	if filter.Until.IsZero() || t.Before(filter.Until) {
+		filter.Until = t
+	}
+
+	if c.Query("dry_run") == "true" {
+		n, err := h.db.CountFilteredAnalyses(filter)
+		if err != nil {
+			h.logger.Error("failed to count analyses", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to count analyses"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"deleted": n, "dry_run": true})
+		return
+	}
+
+	n, err := h.db.DeleteAnalyses(filter)
+	if err != nil {
+		h.logger.Error("failed to delete analyses", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete analyses"})
+		return
+	}
+	if n > 0 {
+		h.refreshStats(filter.Since)
+	}
+
+	h.logger.Info("analyses deleted",
+		zap.Int("count", n),
+		zap.Time("before", filter.Until),
+		zap.String("namespace", filter.Namespace),
+		zap.String("actor", h.actor(c)),
+	)
+	c.JSON(http.StatusOK, gin.H{"deleted": n, "dry_run": false})
+}
+
+// deletedAnalysis is the summary of a deleted analysis with when it was
+// deleted and when the retention job purges it, if it does
+type deletedAnalysis struct {
+	analysisSummary
+	DeletedAt time.Time  `json:"deleted_at"`
+	PurgeAt   *time.Time `json:"purge_at,omitempty"`
+}
+
+// deletedSince reads ?deleted_since= into the filter, responding with 400
+// and reporting false if it is invalid
+func deletedSince(c *gin.Context, filter *database.AnalysisFilter, required bool) bool {
+	v := c.Query("deleted_since")
+	if v == "" {
+		if required {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "deleted_since is required"})
+			return false
+		}
+		return true
+	}
+	t, err := parseSearchTime(v, false)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "deleted_since must be an RFC 3339 time or a YYYY-MM-DD date"})
+		return false
+	}
+	filter.DeletedSince = t
+	return true
+}
+
+// ListDeletedAnalyses lists the deleted analyses that can still be
+// restored, newest first, narrowed with the search filters and
+// ?deleted_since=, up to ?limit=
+func (h *Handler) ListDeletedAnalyses(c *gin.Context) {
+	filter, ok := analysisFilter(c)
+	if !ok || !deletedSince(c, &filter, false) {
+		return
+	}
+	filter.Deleted = true
+	filter.Limit = maxSearchPerPage
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxSearchPerPage {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("limit must be between 1 and %d", maxSearchPerPage)})
+			return
+		}
+		filter.Limit = n
+	}
+
+	total, err := h.db.CountFilteredAnalyses(filter)
+	if err != nil {
+		h.logger.Error("failed to count deleted analyses", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list deleted analyses"})
+		return
+	}
+	stored, err := h.db.FindAnalyses(filter)
+	if err != nil {
+		h.logger.Error("failed to list deleted analyses", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list deleted analyses"})
+		return
+	}
+
+	window := h.agent().Config().Database.Retention.RestoreWindow
+	analyses := make([]deletedAnalysis, 0, len(stored))
+	for _, s := range stored {
+		d := deletedAnalysis{analysisSummary: newAnalysisSummary(s), DeletedAt: s.DeletedAt}
+		if window > 0 {
+			purgeAt := s.DeletedAt.Add(window)
+			d.PurgeAt = &purgeAt
+		}
+		// The analysis is hidden until restored
+		d.Links = models.Links{"restore": {Href: analysisPath(routeAnalysisRestore, s.ID)}}
+		analyses = append(analyses, d)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"count": len(analyses),
+		"total": total,
+		"_embedded": gin.H{
+			"analyses": analyses,
+		},
+	})
+}
+
+// RestoreAnalysis brings back a deleted analysis that was not purged yet
+func (h *Handler) RestoreAnalysis(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid analysis ID"})
+		return
+	}
+
+	restored, err := h.db.RestoreAnalysis(id)
+	if err != nil {
+		h.logger.Error("failed to restore analysis", zap.Int64("id", id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to restore analysis"})
+		return
+	}
+	if !restored {
+		c.JSON(http.StatusNotFound, gin.H{"error": "deleted analysis not found"})
+		return
+	}
+	h.logger.Info("analysis restored", zap.Int64("id", id), zap.String("actor", h.actor(c)))
+
+	analysis, ok := h.loadAnalysis(c)
+	if !ok {
+		return
+	}
+	h.refreshStats(analysis.CreatedAt)
+	c.JSON(http.StatusOK, newAnalysisSummary(*analysis))
+}
+
+// RestoreAnalyses brings back the analyses deleted since ?deleted_since=,
+// optionally narrowed with the search filters. ?dry_run=true only counts
+// them.
+func (h *Handler) RestoreAnalyses(c *gin.Context) {
+	filter, ok := analysisFilter(c)
+	if !ok || !deletedSince(c, &filter, true) {
+		return
+	}
+	filter.Deleted = true
+
+	if c.Query("dry_run") == "true" {
+		n, err := h.db.CountFilteredAnalyses(filter)
+		if err != nil {
+			h.logger.Error("failed to count deleted analyses", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to count deleted analyses"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"restored": n, "dry_run": true})
+		return
+	}
+
+	n, err := h.db.RestoreAnalyses(filter)
+	if err != nil {
+		h.logger.Error("failed to restore analyses", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to restore analyses"})
+		return
+	}
+	if n > 0 {
+		h.refreshStats(filter.Since)
+	}
+
+	h.logger.Info("analyses restored",
+		zap.Int("count", n),
+		zap.Time("deleted_since", filter.DeletedSince),
+		zap.String("namespace", filter.Namespace),
+		zap.String("actor", h.actor(c)),
+	)
+	c.JSON(http.StatusOK, gin.H{"restored": n, "dry_run": false})
+}
@@ -0,0 +1,544 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+
+	"github.com/emirozbir/micro-sre/internal/agent"
+	"github.com/emirozbir/micro-sre/internal/collectors"
+	"github.com/emirozbir/micro-sre/internal/config"
+	"github.com/emirozbir/micro-sre/internal/llm"
+	"github.com/emirozbir/micro-sre/internal/requestid"
+	"github.com/emirozbir/micro-sre/internal/tracing"
+	"github.com/emirozbir/micro-sre/internal/ui"
+)
+
+// Analysis job states; finished jobs are completed or failed. Deferred jobs
+// were refused by the LLM token budget and are queued again when it resets;
+// scheduled jobs wait in the jobs table for a later attempt.
+const (
+	jobQueued    = "queued"
+	jobFailed    = "failed"
+	jobDeferred  = "deferred"
+	jobScheduled = "scheduled"
+)
+
+// Analysis job kinds, one per analyze endpoint
+const (
+	jobKindAlert      = "alert"
+	jobKindPod        = "pod"
+	jobKindDeployment = "deployment"
+	jobKindNamespace  = "namespace"
+	jobKindWebhook    = "webhook"
+	jobKindTriggered  = "triggered"
+	jobKindFeedback   = "feedback"
+	jobKindRerun      = "rerun"
+)
+
+// Job queue defaults, used when agent.jobs is unset
+const (
+	defaultJobWorkers   = 4
+	defaultJobQueueSize = 100
+)
+
+// analysisJobRetention is how long finished analysis jobs stay queryable
+const analysisJobRetention = time.Hour
+
+// jobTimeout bounds a job once a worker picks it up
+const jobTimeout = 10 * time.Minute
+
+// uiLookbacks are the lookback choices offered by the "New analysis" form
+var uiLookbacks = []string{"15m", "30m", "1h", "2h", "6h", "12h", "24h"}
+
+// uiVerbosities are the report verbosity choices offered by the form
+var uiVerbosities = []string{config.VerbosityBrief, config.VerbosityStandard, config.VerbosityDeep}
+
+// jobTask does the work of a job. It returns the response body the
+// synchronous endpoint would have sent and the ID of the stored analysis,
+// if any.
+type jobTask func(ctx context.Context) (result any, analysisID int64, err error)
+
+// analysisJob tracks an analysis run in the background. Stage is the latest
+// progress message reported by the agent; Target is the pod, workload or
+// webhook receiver.
+type analysisJob struct {
+	ID         string     `json:"id"`
+	Kind       string     `json:"kind"`
+	Status     string     `json:"status"`
+	Stage      string     `json:"stage,omitempty"`
+	Namespace  string     `json:"namespace,omitempty"`
+	Target     string     `json:"target,omitempty"`
+	Lookback   string     `json:"lookback,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	AnalysisID int64      `json:"analysis_id,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	ErrorClass string     `json:"error_class,omitempty"`
+	// RequestID is the X-Request-ID of the request that queued the job, or
+	// the job ID for jobs queued by the poller and watcher
+	RequestID string `json:"request_id,omitempty"`
+	Result    any    `json:"result,omitempty"`
+	// Attempts counts the runs of a stored job; NextRunAt is when a
+	// scheduled one runs
+	Attempts  int        `json:"attempts,omitempty"`
+	NextRunAt *time.Time `json:"next_run_at,omitempty"`
+
+	task jobTask
+	// webhook is the input of a webhook job, and stored whether the job is
+	// in the jobs table
+	webhook *webhookJob
+	stored  bool
+	// spanContext links the job's trace to the request that queued it
+	spanContext trace.SpanContext
+}
+
+// analysisJobs keeps analysis jobs in memory and feeds queued jobs to a
+// fixed number of workers. Subscribers receive the events of a job until it
+// finishes.
+type analysisJobs struct {
+	mu          sync.Mutex
+	jobs        map[string]*analysisJob
+	queue       chan *analysisJob
+	subscribers map[string][]chan jobEvent
+
+	// pending counts queued and running jobs. Once closed, no jobs are
+	// accepted and idle is closed when pending drops to zero.
+	pending int
+	closed  bool
+	idle    chan struct{}
+}
+
+func newAnalysisJobs(queueSize int) *analysisJobs {
+	if queueSize <= 0 {
+		queueSize = defaultJobQueueSize
+	}
+	return &analysisJobs{
+		jobs:        map[string]*analysisJob{},
+		queue:       make(chan *analysisJob, queueSize),
+		subscribers: map[string][]chan jobEvent{},
+		idle:        make(chan struct{}),
+	}
+}
+
+// enqueue registers a job and queues it for the workers, dropping finished
+// jobs past their retention. It returns false if the queue is full or the
+// server is shutting down.
+func (j *analysisJobs) enqueue(job *analysisJob) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.closed {
+		return false
+	}
+
+	cutoff := time.Now().Add(-analysisJobRetention)
+	for id, old := range j.jobs {
+		if old.FinishedAt != nil && old.FinishedAt.Before(cutoff) {
+			delete(j.jobs, id)
+		}
+	}
+
+	select {
+	case j.queue <- job:
+		j.jobs[job.ID] = job
+		j.pending++
+		return true
+	default:
+		return false
+	}
+}
+
+// schedule registers a job that waits for a later attempt. It returns
+// false if the server is shutting down.
+func (j *analysisJobs) schedule(job *analysisJob) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.closed {
+		return false
+	}
+	j.jobs[job.ID] = job
+	return true
+}
+
+// resume queues a scheduled job, or a stored one unknown to this server,
+// and reports whether it is queued or already was. It returns false if the
+// queue is full or the server is shutting down.
+func (j *analysisJobs) resume(job *analysisJob) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.closed {
+		return false
+	}
+	if known, ok := j.jobs[job.ID]; ok {
+		if known.Status != jobScheduled {
+			return true
+		}
+		job = known
+	}
+
+	select {
+	case j.queue <- job:
+		job.Status = jobQueued
+		job.Stage = "Queued"
+		job.NextRunAt = nil
+		j.jobs[job.ID] = job
+		j.pending++
+		j.changed(job)
+		return true
+	default:
+		return false
+	}
+}
+
+// due returns the scheduled jobs whose next attempt is due by now
+func (j *analysisJobs) due(now time.Time) []*analysisJob {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	var due []*analysisJob
+	for _, job := range j.jobs {
+		if job.Status == jobScheduled && job.NextRunAt != nil && !job.NextRunAt.After(now) {
+			due = append(due, job)
+		}
+	}
+	return due
+}
+
+// finished reports whether this server knows the job, and whether it has
+// finished
+func (j *analysisJobs) finished(id string) (known, finished bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	job, ok := j.jobs[id]
+	return ok, ok && job.FinishedAt != nil
+}
+
+// settle marks a pending job as no longer queued or running. The caller
+// holds mu.
+func (j *analysisJobs) settle() {
+	j.pending--
+	if j.closed && j.pending == 0 {
+		close(j.idle)
+	}
+}
+
+// close stops accepting jobs and returns a channel that is closed once the
+// pending jobs have finished or been deferred
+func (j *analysisJobs) close() <-chan struct{} {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if !j.closed {
+		j.closed = true
+		if j.pending == 0 {
+			close(j.idle)
+		}
+	}
+	return j.idle
+}
+
+// active returns the number of queued and running jobs
+func (j *analysisJobs) active() int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.pending
+}
+
+// snapshot returns a copy of the job that is safe to serialize
+func (j *analysisJobs) snapshot(id string) (analysisJob, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	job, ok := j.jobs[id]
+	if !ok {
+		return analysisJob{}, false
+	}
+	return *job, true
+}
+
+// jobProgress records agent progress messages as the job's stage and
+// publishes them, and the LLM response as it streams in, to subscribers
+type jobProgress struct {
+	jobs *analysisJobs
+	job  *analysisJob
+	// received counts the response characters of the current stage
+	received int
+}
+
+func (p *jobProgress) Update(message string) {
+	p.jobs.mu.Lock()
+	defer p.jobs.mu.Unlock()
+	p.job.Stage = message
+	p.received = 0
+	p.jobs.publish(p.job.ID, jobEvent{name: "stage", data: gin.H{"stage": message}})
+}
+
+// Text publishes a piece of the LLM response
+func (p *jobProgress) Text(text string) {
+	p.jobs.mu.Lock()
+	defer p.jobs.mu.Unlock()
+	p.received += len(text)
+	p.jobs.publish(p.job.ID, jobEvent{name: "token", data: gin.H{"text": text, "received": p.received}})
+}
+
+func (p *jobProgress) Stop() {}
+
+var _ ui.ProgressReporter = (*jobProgress)(nil)
+
+// startJobWorkers starts the workers that run queued analysis jobs
+func (h *Handler) startJobWorkers(workers int) {
+	if workers <= 0 {
+		workers = defaultJobWorkers
+	}
+	for i := 0; i < workers; i++ {
+		go func() {
+			for job := range h.analysisJobs.queue {
+				h.runAnalysisJob(job)
+			}
+		}()
+	}
+}
+
+func (h *Handler) runAnalysisJob(job *analysisJob) {
+	attempt := 0
+	if job.stored {
+		var ok bool
+		if attempt, ok = h.claimJob(job); !ok {
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(trace.ContextWithSpanContext(h.jobsCtx, job.spanContext), jobTimeout)
+	defer cancel()
+	ctx, span := tracing.Start(ctx, "analysis job", trace.WithAttributes(
+		attribute.String("hepsre.job", job.ID),
+		attribute.String("hepsre.kind", job.Kind)))
+	defer span.End()
+	progress := &jobProgress{jobs: h.analysisJobs, job: job}
+	ctx = agent.WithProgress(llm.WithTokens(ctx, progress.Text), progress)
+
+	started := time.Now()
+	h.analysisJobs.mu.Lock()
+	job.Status = jobRunning
+	job.Attempts = max(job.Attempts, attempt)
+	job.Stage = "Starting..."
+	job.StartedAt = &started
+	if job.RequestID == "" {
+		job.RequestID = job.ID
+	}
+	ctx = requestid.With(ctx, job.RequestID)
+	h.analysisJobs.changed(job)
+	h.analysisJobs.mu.Unlock()
+
+	result, id, err := job.task(ctx)
+	if err != nil && errorClass(err) == errorBudgetExceeded {
+		requestid.Logger(ctx, h.logger).Warn("analysis job deferred until the token budget resets", zap.String("job", job.ID), zap.String("kind", job.Kind), zap.Error(err))
+		h.analysisJobs.mu.Lock()
+		defer h.analysisJobs.mu.Unlock()
+		h.deferJob(job)
+		h.analysisJobs.settle()
+		return
+	}
+	if err != nil {
+		requestid.Logger(ctx, h.logger).Error("analysis job failed", zap.String("job", job.ID), zap.String("kind", job.Kind), zap.Error(err))
+	}
+	if job.stored && h.retryJob(job, attempt, result) {
+		return
+	}
+
+	finished := time.Now()
+	h.analysisJobs.mu.Lock()
+	defer h.analysisJobs.mu.Unlock()
+
+	job.FinishedAt = &finished
+	job.Stage = ""
+	if err != nil {
+		job.Status = jobFailed
+		job.Error = err.Error()
+		job.ErrorClass = errorClass(err)
+	} else {
+		job.Status = jobCompleted
+		job.AnalysisID = id
+		job.Result = result
+	}
+	h.analysisJobs.changed(job)
+	h.analysisJobs.settle()
+}
+
+// respond runs task for an analyze endpoint. With ?async=true the task is
+// queued as a job and the endpoint answers 202 with the job to poll;
+// otherwise it runs within the request. Jobs in a namespace the caller may
+// not analyze are refused.
+func (h *Handler) respond(c *gin.Context, job *analysisJob, task jobTask) {
+	if job.Namespace != "" && !allowNamespace(c, job.Namespace) {
+		return
+	}
+	if c.Query("async") == "true" {
+		h.startJob(c, job, task)
+		return
+	}
+
+	result, _, err := task(c.Request.Context())
+	if err != nil {
+		requestid.Logger(c.Request.Context(), h.logger).Error("analysis failed", zap.String("kind", job.Kind), zap.Error(err))
+		analysisFailed(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// startJob queues task as a job and answers 202 with its links, or 503 if
+// the queue is full
+func (h *Handler) startJob(c *gin.Context, job *analysisJob, task jobTask) {
+	job.ID = newJobID()
+	job.Status = jobQueued
+	job.Stage = "Queued"
+	job.CreatedAt = time.Now()
+	job.task = task
+	job.spanContext = trace.SpanContextFromContext(c.Request.Context())
+	job.RequestID = requestid.From(c.Request.Context())
+	if job.webhook != nil {
+		h.storeJob(job, job.CreatedAt)
+	}
+
+	if !h.analysisJobs.enqueue(job) {
+		h.unstoreJob(job)
+		c.Header("Retry-After", "30")
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": h.queueRefusal()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"id":     job.ID,
+		"status": job.Status,
+		"_links": analysisJobLinks(job),
+	})
+}
+
+// StartAnalysisJob starts a pod analysis in the background and returns the
+// job to poll, so browsers don't hold a request open for the LLM round trip
+func (h *Handler) StartAnalysisJob(c *gin.Context) {
+	var req AnalyzePodRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	lookback := 1 * time.Hour
+	if req.Lookback != "" {
+		var err error
+		lookback, err = time.ParseDuration(req.Lookback)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid lookback duration"})
+			return
+		}
+	}
+	if !h.agent().Kubernetes().NamespaceAllowed(req.Namespace) {
+		c.JSON(http.StatusForbidden, gin.H{"error": collectors.ErrNamespaceNotAllowed.Error()})
+		return
+	}
+	if !allowNamespace(c, req.Namespace) || !h.clusterAllowed(c, req.Cluster) {
+		return
+	}
+
+	job := &analysisJob{Kind: jobKindPod, Namespace: req.Namespace, Target: req.Pod, Lookback: lookback.String()}
+	h.startJob(c, job, h.podTask(agent.AnalysisRequest{
+		Namespace: req.Namespace,
+		PodName:   req.Pod,
+		Lookback:  lookback,
+		Verbosity: req.Verbosity,
+		Cluster:   req.Cluster,
+	}))
+}
+
+// podTask analyzes a pod (or an alert on it) and saves the result
+func (h *Handler) podTask(req agent.AnalysisRequest) jobTask {
+	return func(ctx context.Context) (any, int64, error) {
+		id, result, _, err := h.analyzePod(ctx, req)
+		if err != nil {
+			return nil, 0, err
+		}
+		return newAnalysisResponse(id, result), id, nil
+	}
+}
+
+// jobVisible reports whether the caller may see a job: jobs in a namespace
+// are hidden from OIDC callers not allowed to analyze it, like respond
+// refuses to start them
+func jobVisible(c *gin.Context, job *analysisJob) bool {
+	return job.Namespace == "" || callerAllows(c, job.Namespace)
+}
+
+// GetAnalysisJob reports the status of an analysis job and, once it has
+// completed, its result
+func (h *Handler) GetAnalysisJob(c *gin.Context) {
+	job, ok := h.analysisJobs.snapshot(c.Param("job"))
+	if !ok || !jobVisible(c, &job) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "job not found"})
+		return
+	}
+	c.JSON(http.StatusOK, analysisJobBody(&job))
+}
+
+func analysisJobLinks(job *analysisJob) map[string]gin.H {
+	params := map[string]string{"job": job.ID}
+	links := map[string]gin.H{
+		"self":     {"href": fillRoute(routeAnalysisJob, params)},
+		"progress": {"href": fillRoute(routeAnalysisJobPage, params), "type": "text/html"},
+		"stream":   {"href": fillRoute(routeAnalysisJobStream, map[string]string{"job": job.ID}), "type": "text/event-stream"},
+	}
+	if job.AnalysisID != 0 {
+		links["analysis"] = gin.H{"href": analysisPath(routeAnalysisPage, job.AnalysisID), "type": "text/html"}
+	}
+	return links
+}
+
+// NewAnalysisPage renders the "New analysis" form. Namespace and pod
+// suggestions are loaded from the autocomplete API by the page itself.
+func (h *Handler) NewAnalysisPage(c *gin.Context) {
+	data := gin.H{
+		"Namespace":      c.Query("namespace"),
+		"Pod":            c.Query("pod"),
+		"Lookbacks":      uiLookbacks,
+		"Verbosities":    uiVerbosities,
+		"Verbosity":      h.agent().Config().Report.Verbosity,
+		"JobsLink":       routeAnalysisJobs,
+		"NamespacesLink": routeK8sNamespaces,
+		"PodsLink":       routeK8sPods,
+	}
+
+	if err := h.tmpl.ExecuteTemplate(c.Writer, "new.html", data); err != nil {
+		h.logger.Error("failed to render template", zap.Error(err))
+		c.String(http.StatusInternalServerError, "Failed to render page")
+	}
+}
+
+// AnalysisJobPage renders the live progress view of an analysis job
+func (h *Handler) AnalysisJobPage(c *gin.Context) {
+	job, ok := h.analysisJobs.snapshot(c.Param("job"))
+	if !ok || !jobVisible(c, &job) {
+		c.String(http.StatusNotFound, "Analysis job not found")
+		return
+	}
+	if job.Status == jobCompleted && job.AnalysisID != 0 {
+		c.Redirect(http.StatusSeeOther, analysisPath(routeAnalysisPage, job.AnalysisID))
+		return
+	}
+
+	data := gin.H{
+		"Job":        job,
+		"JobLink":    fillRoute(routeAnalysisJob, map[string]string{"job": job.ID}),
+		"StreamLink": fillRoute(routeAnalysisJobStream, map[string]string{"job": job.ID}),
+	}
+	if err := h.tmpl.ExecuteTemplate(c.Writer, "progress.html", data); err != nil {
+		h.logger.Error("failed to render template", zap.Error(err))
+		c.String(http.StatusInternalServerError, "Failed to render page")
+	}
+}
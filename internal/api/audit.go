@@ -0,0 +1,146 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/emirozbir/micro-sre/internal/database"
+	"github.com/emirozbir/micro-sre/internal/requestid"
+)
+
+// Audit log actions
+const (
+	auditAnalyze           = "analysis.create"
+	auditRerun             = "analysis.rerun"
+	auditFeedback          = "analysis.feedback"
+	auditReanalyze         = "analysis.reanalyze"
+	auditDelete            = "analysis.delete"
+	auditRestore           = "analysis.restore"
+	auditConfigReload      = "config.reload"
+	auditRemediationDecide = "remediation.decide"
+	auditRemediationRun    = "remediation.execute"
+	auditBackup            = "backup.export"
+	auditChaos             = "chaos.update"
+)
+
+// Audit log page sizes
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+)
+
+// audit records the request in the audit log as action once it is
+// answered, whether or not it succeeded. It goes before requireAdmin so
+// that refused attempts are recorded too.
+func (h *Handler) audit(action string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		h.recordAudit(database.AuditEvent{
+			Actor:     h.actor(c),
+			Action:    action,
+			Resource:  c.Request.Method + " " + c.Request.URL.RequestURI(),
+			Status:    c.Writer.Status(),
+			ClientIP:  c.ClientIP(),
+			RequestID: requestid.From(c.Request.Context()),
+		})
+	}
+}
+
+// recordAudit stores an audit event. A failure is logged rather than
+// failing the action, which has already happened.
+func (h *Handler) recordAudit(e database.AuditEvent) {
+	if _, err := h.db.SaveAuditEvent(e); err != nil {
+		h.logger.Error("failed to record audit event",
+			zap.String("action", e.Action),
+			zap.String("actor", e.Actor),
+			zap.String("resource", e.Resource),
+			zap.Error(err))
+	}
+}
+
+// ListAuditEvents lists the audit log, newest first, filtered by ?actor=,
+// ?action= and the ?since= and ?until= range, up to ?limit= events. The
+// "next" link pages back with ?before_id=.
+func (h *Handler) ListAuditEvents(c *gin.Context) {
+	filter := database.AuditFilter{
+		Actor:  c.Query("actor"),
+		Action: c.Query("action"),
+		Limit:  defaultAuditLimit,
+	}
+	for _, bound := range []struct {
+		param string
+		until bool
+		dst   *time.Time
+	}{
+		{"since", false, &filter.Since},
+		{"until", true, &filter.Until},
+	} {
+		if v := c.Query(bound.param); v != "" {
+			t, err := parseSearchTime(v, bound.until)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": bound.param + " must be an RFC 3339 time or a YYYY-MM-DD date"})
+				return
+			}
+			*bound.dst = t
+		}
+	}
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxAuditLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("limit must be between 1 and %d", maxAuditLimit)})
+			return
+		}
+		filter.Limit = n
+	}
+	if v := c.Query("before_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "before_id must be an audit event ID"})
+			return
+		}
+		filter.BeforeID = id
+	}
+
+	events, err := h.db.ListAuditEvents(filter)
+	if err != nil {
+		h.logger.Error("failed to list audit events", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list audit events"})
+		return
+	}
+
+	out := make([]gin.H, 0, len(events))
+	for _, e := range events {
+		event := gin.H{
+			"id":         e.ID,
+			"created_at": e.CreatedAt,
+			"actor":      e.Actor,
+			"action":     e.Action,
+			"resource":   e.Resource,
+			"client_ip":  e.ClientIP,
+			"request_id": e.RequestID,
+			"detail":     e.Detail,
+		}
+		if e.Status != 0 {
+			event["status"] = e.Status
+		}
+		out = append(out, event)
+	}
+	links := gin.H{}
+	if len(events) == filter.Limit {
+		query := c.Request.URL.Query()
+		query.Set("before_id", strconv.FormatInt(events[len(events)-1].ID, 10))
+		links["next"] = gin.H{"href": routeAudit + "?" + query.Encode()}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"count": len(out),
+		"_embedded": gin.H{
+			"events": out,
+		},
+		"_links": links,
+	})
+}
@@ -0,0 +1,171 @@
+package api
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/emirozbir/micro-sre/internal/config"
+)
+
+const (
+	// apiKeyHeader carries the API key of API clients
+	apiKeyHeader = "X-API-Key"
+	// apiKeyCookie carries the API key of dashboard browsers, set by the
+	// login page
+	apiKeyCookie = "hepsre_api_key"
+	// apiKeyContextKey is where authenticate stores the request's key
+	apiKeyContextKey = "api_key"
+)
+
+// publicRoutes are served without an API key: probes, the API description,
+// the web UI's static assets, and Slack callbacks, which are verified by
+// their signature
+var publicRoutes = map[string]bool{
+	routeLivez:             true,
+	routeReadyz:            true,
+	routeHealthz:           true,
+	"/version":             true,
+	routeMetrics:           true,
+	routeOpenAPI:           true,
+	routeAPIDocs:           true,
+	routeStaticFiles:       true,
+	routeSlackInteractions: true,
+	routeSlackCommand:      true,
+}
+
+// unlinkRoutes are deletes that only detach labels or groupings, which
+// need no more than the scope that attached them
+var unlinkRoutes = map[string]bool{
+	routeAnalysisTag:      true,
+	routeIncidentAnalysis: true,
+}
+
+// requiredScope returns the scope a request needs: admin for admin
+// endpoints, deletes other than unlinkRoutes and remediation decisions,
+// read for other GETs and analyze for everything else
+func requiredScope(c *gin.Context) string {
+	path, method := c.FullPath(), c.Request.Method
+	switch {
+	case strings.HasPrefix(path, "/api/v1/admin/"), method == http.MethodDelete && !unlinkRoutes[path],
+		path == routeRemediationApprove, path == routeRemediationReject:
+		return config.ScopeAdmin
+	case method == http.MethodGet, method == http.MethodHead:
+		return config.ScopeRead
+	}
+	return config.ScopeAnalyze
+}
+
+// authenticate requires an API key with the scope of the route once any
+// key is configured in server.api_keys. The key is taken from the X-API-Key
+// header, a bearer token (for clients like AlertManager that can only set
+// that) or the dashboard's cookie. The admin token counts as an admin key.
+// With server.oidc, bearer JWTs from the issuer are accepted as well. With
+// server.webhook, the AlertManager webhook is left to verifyWebhook, and
+// with pagerduty.webhook_secret, the PagerDuty webhook checks its signature.
+func (h *Handler) authenticate(c *gin.Context) {
+	cfg := h.agent().Config().Server
+	if (len(cfg.APIKeys) == 0 && h.tokens == nil) || publicRoutes[c.FullPath()] ||
+		(c.FullPath() == routeAlertManagerWebhook && cfg.Webhook.Enabled()) ||
+		(c.FullPath() == routePagerDutyWebhook && h.pagerduty.CanVerify()) {
+		c.Next()
+		return
+	}
+
+	given := requestAPIKey(c)
+	if h.tokens != nil && isJWT(given) {
+		h.authenticateToken(c, given)
+		return
+	}
+
+	key, ok := findAPIKey(cfg, given)
+	if !ok {
+		if strings.HasPrefix(c.Request.URL.Path, "/api/") {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid or missing API key"})
+			return
+		}
+		c.Status(http.StatusUnauthorized)
+		if err := h.tmpl.ExecuteTemplate(c.Writer, "login.html", gin.H{"Cookie": apiKeyCookie}); err != nil {
+			h.logger.Error("failed to render template", zap.Error(err))
+		}
+		c.Abort()
+		return
+	}
+
+	scope := requiredScope(c)
+	if !key.Allows(scope) {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("API key %s lacks the %s scope", key.Name, scope)})
+		return
+	}
+	c.Set(apiKeyContextKey, key)
+	c.Next()
+}
+
+// authenticateToken verifies an OIDC bearer token and requires the scope
+// of the route from server.oidc.scopes
+func (h *Handler) authenticateToken(c *gin.Context, raw string) {
+	id, err := h.tokens.verify(c.Request.Context(), raw)
+	if err != nil {
+		h.logger.Warn("rejected OIDC token", zap.Error(err))
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired token"})
+		return
+	}
+
+	scope := requiredScope(c)
+	if !h.agent().Config().Server.OIDC.Allows(scope) {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("OIDC tokens lack the %s scope", scope)})
+		return
+	}
+	c.Set(identityContextKey, id)
+	c.Next()
+}
+
+// requestAPIKey returns the key presented by a request, if any
+func requestAPIKey(c *gin.Context) string {
+	if key := c.GetHeader(apiKeyHeader); key != "" {
+		return key
+	}
+	if key, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok {
+		return key
+	}
+	key, _ := c.Cookie(apiKeyCookie)
+	return key
+}
+
+// findAPIKey returns the configured key matching given. Every key is
+// compared, in constant time, so timing does not reveal which matched.
+func findAPIKey(cfg config.ServerConfig, given string) (config.APIKeyConfig, bool) {
+	keys := cfg.APIKeys
+	if cfg.AdminToken != "" {
+		keys = append(keys[:len(keys):len(keys)], config.APIKeyConfig{
+			Name:   "admin_token",
+			Key:    cfg.AdminToken,
+			Scopes: []string{config.ScopeAdmin},
+		})
+	}
+
+	var (
+		found config.APIKeyConfig
+		ok    bool
+	)
+	for _, key := range keys {
+		if given != "" && subtle.ConstantTimeCompare([]byte(given), []byte(key.Key)) == 1 {
+			found, ok = key, true
+		}
+	}
+	return found, ok
+}
+
+// apiKey returns the API key a request was authenticated with
+func apiKey(c *gin.Context) (config.APIKeyConfig, bool) {
+	key, ok := c.Get(apiKeyContextKey)
+	if !ok {
+		return config.APIKeyConfig{}, false
+	}
+	k, ok := key.(config.APIKeyConfig)
+	return k, ok
+}
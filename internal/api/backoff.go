@@ -0,0 +1,73 @@
+package api
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/emirozbir/micro-sre/internal/config"
+	"github.com/emirozbir/micro-sre/internal/database"
+	"github.com/emirozbir/micro-sre/internal/llm"
+)
+
+// failureBackoff returns the delay before the next webhook analysis of a
+// target that failed the given number of times in a row. A zero Max leaves
+// the delay uncapped; doubling then stops before it would overflow.
+func failureBackoff(cfg config.FailureBackoffConfig, failures int) time.Duration {
+	delay := cfg.Initial
+	for i := 1; i < failures && (cfg.Max <= 0 || delay < cfg.Max) && delay <= math.MaxInt64/2; i++ {
+		delay *= 2
+	}
+	if cfg.Max > 0 && delay > cfg.Max {
+		delay = cfg.Max
+	}
+	return delay
+}
+
+// backingOff returns the failure state of a target whose next attempt is
+// still in the future, or nil if the target may be analyzed now
+func (h *Handler) backingOff(namespace, target string) *database.TargetFailure {
+	failure, err := h.db.GetTargetFailure(namespace, target)
+	if err != nil {
+		// Don't block analyses on bookkeeping errors
+		h.logger.Warn("failed to check target backoff", zap.String("namespace", namespace), zap.String("target", target), zap.Error(err))
+		return nil
+	}
+	if failure == nil || time.Now().After(failure.NextAttemptAt) {
+		return nil
+	}
+	return failure
+}
+
+// recordAnalysisOutcome updates the failure state of a target after an
+// analysis attempt
+func (h *Handler) recordAnalysisOutcome(namespace, target string, analysisErr error) {
+	// A spent token budget says nothing about the target
+	if errors.Is(analysisErr, llm.ErrBudgetExceeded) {
+		return
+	}
+	if analysisErr == nil {
+		if err := h.db.ClearTargetFailure(namespace, target); err != nil {
+			h.logger.Warn("failed to clear target failures", zap.String("namespace", namespace), zap.String("target", target), zap.Error(err))
+		}
+		return
+	}
+
+	cfg := h.agent().Config().Agent.FailureBackoff
+	failure, err := h.db.RecordTargetFailure(namespace, target, analysisErr.Error(), func(failures int) time.Duration {
+		return failureBackoff(cfg, failures)
+	})
+	if err != nil {
+		h.logger.Warn("failed to record target failure", zap.String("namespace", namespace), zap.String("target", target), zap.Error(err))
+		return
+	}
+	if failure.Failures >= cfg.AttentionThreshold {
+		h.logger.Warn("target needs attention",
+			zap.String("namespace", namespace),
+			zap.String("target", target),
+			zap.Int("consecutive_failures", failure.Failures),
+			zap.Time("next_attempt", failure.NextAttemptAt))
+	}
+}
@@ -0,0 +1,43 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/emirozbir/micro-sre/internal/database"
+)
+
+// backupContentTypes maps the formats of Backup to their content type
+var backupContentTypes = map[string]string{
+	database.BackupJSONL: "application/x-ndjson",
+	database.BackupSQL:   "application/sql; charset=utf-8",
+}
+
+// Backup streams a backup of the database as an attachment (?format=jsonl
+// or sql, jsonl by default), for "hepsre db import" to restore or to load
+// into another database engine. A backup cut short by an error lacks its
+// last line, which import checks for.
+func (h *Handler) Backup(c *gin.Context) {
+	format := c.DefaultQuery("format", database.BackupJSONL)
+	contentType, ok := backupContentTypes[format]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be jsonl or sql"})
+		return
+	}
+
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="hepsre-backup-%s.%s"`, time.Now().UTC().Format("20060102-150405"), format))
+	if err := h.db.Export(c.Request.Context(), c.Writer, format); err != nil {
+		h.logger.Error("failed to export backup", zap.String("format", format), zap.Error(err))
+		if !c.Writer.Written() {
+			c.Header("Content-Disposition", "")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to export backup"})
+		}
+		return
+	}
+	h.logger.Info("backup exported", zap.String("format", format), zap.String("actor", h.actor(c)))
+}
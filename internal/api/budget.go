@@ -0,0 +1,110 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/emirozbir/micro-sre/internal/llm"
+	"github.com/emirozbir/micro-sre/internal/models"
+)
+
+// GetTokenBudget reports the LLM tokens spent today against the budgets
+func (h *Handler) GetTokenBudget(c *gin.Context) {
+	budget := h.agent().TokenBudget()
+	if budget == nil {
+		c.JSON(http.StatusOK, gin.H{"enabled": false})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"enabled": true,
+		"budget":  budget.State(),
+	})
+}
+
+// seedBudget counts the tokens stored with today's analyses against a new
+// token budget, which would otherwise start from zero after a restart
+func (h *Handler) seedBudget(budget *llm.Budget) {
+	if budget == nil {
+		return
+	}
+	stored, err := h.db.ListTokenSpend(time.Now())
+	if err != nil {
+		h.logger.Warn("failed to load today's token spend, the budget starts from zero", zap.Error(err))
+		return
+	}
+	spend := make(map[string]llm.Usage, len(stored))
+	for _, t := range stored {
+		spend[t.Namespace] = llm.Usage{InputTokens: int(t.PromptTokens), OutputTokens: int(t.CompletionTokens)}
+	}
+	budget.Seed(spend)
+}
+
+// deferOverBudget queues the webhook alerts refused by the token budget to
+// be analyzed again once it resets, and tells the caller when
+func (h *Handler) deferOverBudget(receiver string, alerts []models.Alert, failed []models.AlertAnalysisError) {
+	refused := map[string]bool{}
+	resetsAt := llm.BudgetResetsAt()
+	for i := range failed {
+		if failed[i].ErrorClass == errorBudgetExceeded {
+			refused[failed[i].Fingerprint] = true
+			failed[i].RetryAfter = &resetsAt
+		}
+	}
+	if len(refused) == 0 {
+		return
+	}
+
+	var deferred []models.Alert
+	for _, alert := range alerts {
+		if refused[alert.Fingerprint] {
+			deferred = append(deferred, alert)
+		}
+	}
+	h.logger.Warn("LLM token budget exceeded, deferring alerts until it resets",
+		zap.String("receiver", receiver),
+		zap.Int("alerts", len(deferred)),
+		zap.Time("resets_at", resetsAt))
+
+	webhook := models.AlertManagerWebhook{Status: "firing", Receiver: receiver, Alerts: deferred}
+	if !h.queueWebhook(jobKindTriggered, webhookJob{Webhook: webhook}, resetsAt) {
+		h.logger.Error("failed to schedule deferred alerts, dropping them", zap.String("receiver", receiver), zap.Int("alerts", len(deferred)))
+	}
+}
+
+// deferJob parks a job refused by the token budget and queues it again once
+// the budget resets; h.analysisJobs.mu must be held
+func (h *Handler) deferJob(job *analysisJob) {
+	resetsAt := llm.BudgetResetsAt()
+	job.Status = jobDeferred
+	job.Stage = "Waiting for the LLM token budget to reset at " + resetsAt.Format(time.RFC3339)
+	h.analysisJobs.changed(job)
+
+	time.AfterFunc(time.Until(resetsAt), func() {
+		h.analysisJobs.mu.Lock()
+		defer h.analysisJobs.mu.Unlock()
+
+		refusal := ""
+		if h.analysisJobs.closed {
+			refusal = "server is shutting down"
+		} else {
+			select {
+			case h.analysisJobs.queue <- job:
+				job.Status = jobQueued
+				job.Stage = "Queued"
+				h.analysisJobs.pending++
+			default:
+				refusal = "analysis queue is full"
+			}
+		}
+		if refusal != "" {
+			finished := time.Now()
+			job.Status = jobFailed
+			job.Error = refusal
+			job.FinishedAt = &finished
+		}
+		h.analysisJobs.changed(job)
+	})
+}
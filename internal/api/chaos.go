@@ -0,0 +1,74 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/emirozbir/micro-sre/internal/config"
+)
+
+// ChaosFault is a simulated fault as set and listed by the chaos endpoint
+type ChaosFault struct {
+	Target     string  `json:"target" binding:"required"`
+	Latency    string  `json:"latency,omitempty"`
+	ErrorRate  float64 `json:"error_rate,omitempty"`
+	ErrorClass string  `json:"error_class,omitempty"`
+}
+
+type ChaosRequest struct {
+	Faults []ChaosFault `json:"faults" binding:"dive"`
+}
+
+// GetChaos lists the faults being injected
+func (h *Handler) GetChaos(c *gin.Context) {
+	injector := h.agent().Chaos()
+	faults := []ChaosFault{}
+	for _, f := range injector.Faults() {
+		fault := ChaosFault{Target: f.Target, ErrorRate: f.ErrorRate, ErrorClass: f.ErrorClass}
+		if f.Latency > 0 {
+			fault.Latency = f.Latency.String()
+		}
+		faults = append(faults, fault)
+	}
+	c.JSON(http.StatusOK, gin.H{"enabled": injector.Enabled(), "faults": faults})
+}
+
+// SetChaos replaces the faults being injected; an empty list stops the
+// injection. It is refused unless chaos.enabled is set.
+func (h *Handler) SetChaos(c *gin.Context) {
+	injector := h.agent().Chaos()
+	if !injector.Enabled() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "chaos.enabled is false"})
+		return
+	}
+
+	var req ChaosRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	faults := make([]config.ChaosFault, 0, len(req.Faults))
+	for _, f := range req.Faults {
+		fault := config.ChaosFault{Target: f.Target, ErrorRate: f.ErrorRate, ErrorClass: f.ErrorClass}
+		if f.Latency != "" {
+			latency, err := time.ParseDuration(f.Latency)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid latency duration"})
+				return
+			}
+			fault.Latency = latency
+		}
+		faults = append(faults, fault)
+	}
+	if err := injector.SetFaults(faults); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.logger.Warn("chaos faults updated", zap.Int("faults", len(faults)))
+	h.GetChaos(c)
+}
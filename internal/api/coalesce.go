@@ -0,0 +1,123 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/emirozbir/micro-sre/internal/agent"
+	"github.com/emirozbir/micro-sre/internal/models"
+)
+
+// defaultAnalysisTimeout bounds a shared analysis run when
+// agent.analysis_timeout is unset
+const defaultAnalysisTimeout = 5 * time.Minute
+
+// analysisRun is one analysis shared by identical requests
+type analysisRun struct {
+	done     chan struct{}
+	id       int64
+	result   *models.AnalysisResult
+	err      error
+	finished time.Time
+}
+
+// analysisCoalescer runs a single analysis for identical requests that
+// arrive while one is in flight or within window after it succeeded, so
+// AlertManager group resends don't trigger duplicate LLM runs. Failed runs
+// are only shared with requests that were already waiting.
+type analysisCoalescer struct {
+	mu     sync.Mutex
+	window time.Duration
+	runs   map[string]*analysisRun
+}
+
+func newAnalysisCoalescer(window time.Duration) *analysisCoalescer {
+	return &analysisCoalescer{window: window, runs: map[string]*analysisRun{}}
+}
+
+// do returns the result of the run for key, starting it with fn if none is
+// in flight or recent. shared is true when another request's run was used.
+func (c *analysisCoalescer) do(ctx context.Context, key string, fn func() (int64, *models.AnalysisResult, error)) (id int64, result *models.AnalysisResult, shared bool, err error) {
+	c.mu.Lock()
+	now := time.Now()
+	for k, run := range c.runs {
+		if !run.finished.IsZero() && now.Sub(run.finished) > c.window {
+			delete(c.runs, k)
+		}
+	}
+	if run, ok := c.runs[key]; ok {
+		c.mu.Unlock()
+		select {
+		case <-run.done:
+		case <-ctx.Done():
+			return 0, nil, true, ctx.Err()
+		}
+		return run.id, run.result, true, run.err
+	}
+	run := &analysisRun{done: make(chan struct{})}
+	c.runs[key] = run
+	c.mu.Unlock()
+
+	run.id, run.result, run.err = fn()
+
+	c.mu.Lock()
+	run.finished = time.Now()
+	if run.err != nil {
+		delete(c.runs, key)
+	}
+	c.mu.Unlock()
+	close(run.done)
+
+	return run.id, run.result, false, run.err
+}
+
+// sharedRunContext returns the context of a coalesced run: ctx without its
+// cancellation, limited to agent.analysis_timeout
+func (h *Handler) sharedRunContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	timeout := h.agent().Config().Agent.AnalysisTimeout
+	if timeout <= 0 {
+		timeout = defaultAnalysisTimeout
+	}
+	return context.WithTimeout(context.WithoutCancel(ctx), timeout)
+}
+
+// analyzePod analyzes and saves a pod, sharing the run with identical
+// requests. The analysis is recorded as running until it completes or
+// fails. The run outlives the request that started it, since others may
+// be waiting on it, but keeps its context values (progress reporter).
+func (h *Handler) analyzePod(ctx context.Context, req agent.AnalysisRequest) (int64, *models.AnalysisResult, bool, error) {
+	// Only the same alert, by fingerprint or else name, on the same pod of
+	// the same cluster is the same analysis
+	alert := req.AlertFingerprint
+	if alert == "" {
+		alert = req.AlertName()
+	}
+	cluster := h.agent().PodSummary(req).Cluster
+	key := fmt.Sprintf("%s/%s/%s/%s/%s/%s", cluster, req.Namespace, req.PodName, alert, req.Lookback, req.Verbosity)
+
+	id, result, shared, err := h.coalescer.do(ctx, key, func() (int64, *models.AnalysisResult, error) {
+		runCtx, cancel := h.sharedRunContext(ctx)
+		defer cancel()
+
+		runID := h.startAnalysis(runCtx, h.agent().PodSummary(req))
+		req.Recurrence = h.findRecurrence(req)
+		result, err := h.agent().AnalyzeAlert(runCtx, req)
+		if err != nil {
+			h.failAnalysis(runCtx, runID, err)
+			return 0, nil, err
+		}
+
+		return h.saveAnalysis(runCtx, runID, result), result, nil
+	})
+	if shared {
+		h.logger.Info("reusing concurrent analysis",
+			zap.String("namespace", req.Namespace),
+			zap.String("pod", req.PodName),
+			zap.Int64("analysis_id", id))
+	}
+	return id, result, shared, err
+}
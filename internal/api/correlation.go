@@ -0,0 +1,194 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/emirozbir/micro-sre/internal/agent"
+	"github.com/emirozbir/micro-sre/internal/models"
+)
+
+// alertGroup is a set of webhook alerts firing on pods of one workload
+type alertGroup struct {
+	namespace string
+	workload  string
+	alerts    []models.Alert
+}
+
+func (g alertGroup) key() string {
+	return g.namespace + "/" + g.workload
+}
+
+// correlateAlerts groups webhook alerts firing on pods of the same
+// Deployment or StatefulSet. Groups of at least agent.incident_min_alerts
+// alerts are returned as incidents; every other alert is returned in rest,
+// to be analyzed on its own.
+func (h *Handler) correlateAlerts(ctx context.Context, alerts []models.Alert) (groups []alertGroup, rest []models.Alert) {
+	minAlerts := h.agent().Config().Agent.IncidentMinAlerts
+	if minAlerts <= 0 || len(alerts) < minAlerts {
+		return nil, alerts
+	}
+
+	// Only look up the workloads in namespaces with enough alerts
+	perNamespace := make(map[string]int)
+	for _, alert := range alerts {
+		if alert.GetNamespace() != "" && alert.GetPodName() != "" {
+			perNamespace[alert.GetNamespace()]++
+		}
+	}
+
+	workloads := make([]string, len(alerts))
+	var wg sync.WaitGroup
+	for i, alert := range alerts {
+		if perNamespace[alert.GetNamespace()] < minAlerts || alert.GetPodName() == "" {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, namespace, podName string) {
+			defer wg.Done()
+			workload, err := h.agent().PodWorkload(ctx, namespace, podName)
+			if err != nil {
+				// The alert is analyzed on its own, which reports the error
+				h.logger.Debug("failed to resolve pod workload",
+					zap.String("namespace", namespace),
+					zap.String("pod", podName),
+					zap.Error(err))
+				return
+			}
+			workloads[i] = workload
+		}(i, alert.GetNamespace(), alert.GetPodName())
+	}
+	wg.Wait()
+
+	index := make(map[string]int)
+	var candidates []alertGroup
+	for i, alert := range alerts {
+		if workloads[i] == "" {
+			rest = append(rest, alert)
+			continue
+		}
+		group := alertGroup{namespace: alert.GetNamespace(), workload: workloads[i]}
+		j, ok := index[group.key()]
+		if !ok {
+			j = len(candidates)
+			index[group.key()] = j
+			candidates = append(candidates, group)
+		}
+		candidates[j].alerts = append(candidates[j].alerts, alert)
+	}
+	for _, group := range candidates {
+		if len(group.alerts) >= minAlerts {
+			groups = append(groups, group)
+		} else {
+			rest = append(rest, group.alerts...)
+		}
+	}
+	return groups, rest
+}
+
+// analyzeIncident analyzes the workload of an alert group once and returns
+// the rolled-up result plus a result (or error) per alert pointing at it.
+// The workload is the backoff target; identical concurrent incidents share
+// one run.
+func (h *Handler) analyzeIncident(ctx context.Context, group alertGroup) (*models.IncidentResult, []models.AlertAnalysisResult, []models.AlertAnalysisError) {
+	failAll := func(message, class string, retryAfter *time.Time) []models.AlertAnalysisError {
+		errors := make([]models.AlertAnalysisError, 0, len(group.alerts))
+		for _, alert := range group.alerts {
+			errors = append(errors, models.AlertAnalysisError{
+				Fingerprint: alert.Fingerprint,
+				AlertName:   alert.GetAlertName(),
+				Error:       message,
+				ErrorClass:  class,
+				RetryAfter:  retryAfter,
+			})
+		}
+		return errors
+	}
+
+	if failure := h.backingOff(group.namespace, group.workload); failure != nil {
+		retryAfter := failure.NextAttemptAt
+		return nil, nil, failAll(fmt.Sprintf("backing off after %d consecutive failures: %s", failure.Failures, failure.LastError), "", &retryAfter)
+	}
+
+	// The most severe alert decides the verbosity; the widest lookback wins
+	representative := group.alerts[0]
+	var lookback time.Duration
+	for _, alert := range group.alerts {
+		if models.SeverityRank(alert.GetSeverity()) > models.SeverityRank(representative.GetSeverity()) {
+			representative = alert
+		}
+		lookback = max(lookback, h.webhookLookback(&alert))
+	}
+	req := agent.IncidentRequest{
+		Namespace: group.namespace,
+		Workload:  group.workload,
+		Alerts:    group.alerts,
+		Lookback:  lookback,
+		Verbosity: h.agent().Config().Report.VerbosityFor(representative.Labels),
+	}
+
+	cluster := h.agent().IncidentSummary(req).Cluster
+	key := fmt.Sprintf("incident:%s/%s/%s/%s", cluster, group.key(), req.Lookback, req.Verbosity)
+	id, result, shared, err := h.coalescer.do(ctx, key, func() (int64, *models.AnalysisResult, error) {
+		runCtx, cancel := h.sharedRunContext(ctx)
+		defer cancel()
+
+		runID := h.startAnalysis(runCtx, h.agent().IncidentSummary(req))
+		result, err := h.agent().AnalyzeIncident(runCtx, req)
+		if err != nil {
+			h.failAnalysis(runCtx, runID, err)
+			return 0, nil, err
+		}
+		return h.saveAnalysis(runCtx, runID, result), result, nil
+	})
+	if !shared {
+		h.recordAnalysisOutcome(group.namespace, group.workload, err)
+	}
+	if err != nil {
+		h.logger.Error("incident analysis failed",
+			zap.String("namespace", group.namespace),
+			zap.String("workload", group.workload),
+			zap.Int("alerts", len(group.alerts)),
+			zap.Error(err))
+		return nil, nil, failAll(err.Error(), errorClass(err), nil)
+	}
+
+	incident := &models.IncidentResult{
+		ID:            id,
+		Namespace:     group.namespace,
+		Workload:      group.workload,
+		Severity:      result.Alert.Severity,
+		Analysis:      &result.Analysis,
+		CollectedData: &result.CollectedData,
+		Links:         analysisLinks(id, result),
+	}
+	results := make([]models.AlertAnalysisResult, 0, len(group.alerts))
+	for _, alert := range group.alerts {
+		h.recordDelivery(alert, id)
+		incident.Alerts = append(incident.Alerts, alert.Fingerprint)
+		incident.Pods = append(incident.Pods, alert.GetPodName())
+		results = append(results, models.AlertAnalysisResult{
+			ID:           id,
+			Fingerprint:  alert.Fingerprint,
+			AlertName:    alert.GetAlertName(),
+			Namespace:    group.namespace,
+			Pod:          alert.GetPodName(),
+			Severity:     alert.GetSeverity(),
+			Status:       alert.Status,
+			Incident:     group.key(),
+			Deduplicated: shared,
+			Links:        analysisLinks(id, result),
+		})
+	}
+
+	h.logger.Info("incident analysis completed",
+		zap.String("namespace", group.namespace),
+		zap.String("workload", group.workload),
+		zap.Int("alerts", len(group.alerts)),
+		zap.Int64("analysis_id", id))
+	return incident, results, nil
+}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// cors answers cross-origin requests from server.cors.allowed_origins, so
+// SPAs and Grafana panels can call the JSON API from the browser.
+// Preflight requests are answered here, before authentication, as browsers
+// send them without credentials.
+func (h *Handler) cors(c *gin.Context) {
+	cfg := h.agent().Config().Server.CORS
+	origin := c.GetHeader("Origin")
+	if origin == "" || len(cfg.AllowedOrigins) == 0 {
+		c.Next()
+		return
+	}
+
+	allowed := ""
+	for _, pattern := range cfg.AllowedOrigins {
+		if pattern == "*" {
+			allowed = "*"
+			break
+		}
+		if ok, _ := path.Match(pattern, origin); ok {
+			allowed = origin
+			break
+		}
+	}
+	if allowed == "" {
+		c.Next()
+		return
+	}
+
+	header := c.Writer.Header()
+	header.Set("Access-Control-Allow-Origin", allowed)
+	if allowed != "*" {
+		header.Add("Vary", "Origin")
+	}
+	if cfg.AllowCredentials {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+
+	if c.Request.Method != http.MethodOptions || c.GetHeader("Access-Control-Request-Method") == "" {
+		c.Next()
+		return
+	}
+	header.Set("Access-Control-Allow-Methods", strings.Join(cfg.AllowedMethods, ", "))
+	header.Set("Access-Control-Allow-Headers", strings.Join(cfg.AllowedHeaders, ", "))
+	if cfg.MaxAge > 0 {
+		header.Set("Access-Control-Max-Age", strconv.Itoa(int(cfg.MaxAge.Seconds())))
+	}
+	c.AbortWithStatus(http.StatusNoContent)
+}
+
+// securityHeaders sets the standard browser security headers of
+// server.security_headers on every response
+func (h *Handler) securityHeaders(c *gin.Context) {
+	cfg := h.agent().Config().Server.SecurityHeaders
+	if !cfg.Enabled {
+		c.Next()
+		return
+	}
+
+	header := c.Writer.Header()
+	header.Set("X-Content-Type-Options", "nosniff")
+	header.Set("Referrer-Policy", "strict-origin-when-cross-origin")
+	if cfg.FrameOptions != "" {
+		header.Set("X-Frame-Options", cfg.FrameOptions)
+	}
+	if cfg.ContentSecurityPolicy != "" {
+		header.Set("Content-Security-Policy", cfg.ContentSecurityPolicy)
+	}
+	if cfg.HSTSMaxAge > 0 {
+		header.Set("Strict-Transport-Security", "max-age="+strconv.Itoa(int(cfg.HSTSMaxAge.Seconds())))
+	}
+	c.Next()
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"go.uber.org/zap"
+
+	"github.com/emirozbir/micro-sre/internal/models"
+)
+
+// replayDelivered splits webhook alerts into those analyzed by an earlier
+// delivery, keyed by fingerprint and start time, and the rest. AlertManager
+// re-sends webhooks it considers failed; the stored analyses are returned
+// for those instead of running again.
+func (h *Handler) replayDelivered(alerts []models.Alert) (fresh []models.Alert, replayed []models.AlertAnalysisResult) {
+	for _, alert := range alerts {
+		if alert.Fingerprint == "" {
+			fresh = append(fresh, alert)
+			continue
+		}
+		id, err := h.db.GetDeliveredAnalysisID(alert.Fingerprint, alert.StartsAt)
+		if err != nil {
+			// Analyze again rather than drop the alert
+			h.logger.Warn("failed to look up alert delivery", zap.String("fingerprint", alert.Fingerprint), zap.Error(err))
+			fresh = append(fresh, alert)
+			continue
+		}
+		if id == 0 {
+			fresh = append(fresh, alert)
+			continue
+		}
+		stored, err := h.db.GetAnalysis(id)
+		if err != nil || stored == nil {
+			fresh = append(fresh, alert)
+			continue
+		}
+
+		result := &stored.AnalysisResult
+		replayed = append(replayed, models.AlertAnalysisResult{
+			ID:            id,
+			Fingerprint:   alert.Fingerprint,
+			AlertName:     alert.GetAlertName(),
+			Namespace:     alert.GetNamespace(),
+			Pod:           alert.GetPodName(),
+			Severity:      alert.GetSeverity(),
+			Status:        alert.Status,
+			Analysis:      &result.Analysis,
+			CollectedData: &result.CollectedData,
+			Recurrence:    result.Recurrence,
+			Replayed:      true,
+			Links:         analysisLinks(id, result),
+		})
+		h.logger.Info("returning stored analysis for re-delivered alert",
+			zap.String("fingerprint", alert.Fingerprint),
+			zap.Int64("analysis_id", id))
+	}
+	return fresh, replayed
+}
+
+// recordDelivery stores the analysis of an alert under its idempotency key
+func (h *Handler) recordDelivery(alert models.Alert, analysisID int64) {
+	if alert.Fingerprint == "" || analysisID == 0 {
+		return
+	}
+	if err := h.db.SaveDelivery(alert.Fingerprint, alert.StartsAt, analysisID); err != nil {
+		h.logger.Warn("failed to record alert delivery", zap.String("fingerprint", alert.Fingerprint), zap.Error(err))
+	}
+}
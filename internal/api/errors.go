@@ -0,0 +1,61 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/emirozbir/micro-sre/internal/collectors"
+	"github.com/emirozbir/micro-sre/internal/llm"
+)
+
+// errorBudgetExceeded is the error class of analyses refused by the LLM
+// token budget (llm.budget.on_exceeded: defer)
+const errorBudgetExceeded = "budget_exceeded"
+
+// errorClass classifies the error of a failed analysis
+func errorClass(err error) string {
+	if errors.Is(err, llm.ErrBudgetExceeded) {
+		return errorBudgetExceeded
+	}
+	return collectors.ClassifyError(err)
+}
+
+// errorStatus maps a collector error class to the HTTP status of a failed
+// analysis
+func errorStatus(class string) int {
+	switch class {
+	case collectors.ErrorNotFound:
+		return http.StatusNotFound
+	case collectors.ErrorForbidden:
+		return http.StatusForbidden
+	case collectors.ErrorTimeout:
+		return http.StatusGatewayTimeout
+	case collectors.ErrorConnectionRefused:
+		return http.StatusBadGateway
+	case errorBudgetExceeded:
+		return http.StatusTooManyRequests
+	}
+	return http.StatusInternalServerError
+}
+
+// analysisFailed responds with the error of a failed analysis and its class
+func analysisFailed(c *gin.Context, err error) {
+	class := errorClass(err)
+	if class == errorBudgetExceeded {
+		c.Header("Retry-After", strconv.Itoa(int(time.Until(llm.BudgetResetsAt()).Seconds())+1))
+	}
+	c.JSON(errorStatus(class), gin.H{
+		"error":       err.Error(),
+		"error_class": class,
+	})
+}
+
+// CollectorErrors reports failed collector runs per data source and error
+// class since the server started
+func (h *Handler) CollectorErrors(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"collector_errors": h.agent().CollectorErrors()})
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/emirozbir/micro-sre/internal/formatter"
+)
+
+// exportFormats maps the formats of ExportAnalysis to their content type
+// and file extension
+var exportFormats = map[string]struct{ contentType, extension string }{
+	"markdown": {"text/markdown; charset=utf-8", "md"},
+	"csv":      {"text/csv; charset=utf-8", "csv"},
+	"pdf":      {"application/pdf", "pdf"},
+}
+
+// ExportAnalysis renders a stored analysis as a markdown, CSV or PDF
+// attachment (?format=, markdown by default), for tickets and postmortems
+func (h *Handler) ExportAnalysis(c *gin.Context) {
+	format := c.DefaultQuery("format", "markdown")
+	export, ok := exportFormats[format]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be markdown, csv or pdf"})
+		return
+	}
+	analysis, ok := h.loadAnalysis(c)
+	if !ok {
+		return
+	}
+	result := &analysis.AnalysisResult
+
+	var body []byte
+	switch format {
+	case "markdown":
+		body = []byte(formatter.AnalysisMarkdown(analysis.ID, result))
+	case "csv":
+		var buf bytes.Buffer
+		if err := formatter.AnalysisCSV(&buf, analysis.ID, result); err != nil {
+			h.logger.Error("failed to export analysis", zap.Int64("id", analysis.ID), zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to export analysis"})
+			return
+		}
+		body = buf.Bytes()
+	case "pdf":
+		body = formatter.AnalysisPDF(analysis.ID, result)
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="analysis-%d.%s"`, analysis.ID, export.extension))
+	c.Data(http.StatusOK, export.contentType, body)
+}
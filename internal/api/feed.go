@@ -0,0 +1,130 @@
+package api
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+
+	"github.com/emirozbir/micro-sre/internal/database"
+	"github.com/emirozbir/micro-sre/internal/models"
+)
+
+const (
+	// feedBuffer is how many analyses a slow dashboard may fall behind by
+	// before further ones are dropped for it
+	feedBuffer = 16
+	// feedPingInterval keeps idle dashboard connections open through proxies
+	feedPingInterval = 30 * time.Second
+)
+
+// analysisFeed fans newly stored analyses out to live dashboards
+type analysisFeed struct {
+	mu          sync.Mutex
+	subscribers map[chan analysisSummary]struct{}
+}
+
+func newAnalysisFeed() *analysisFeed {
+	return &analysisFeed{subscribers: map[chan analysisSummary]struct{}{}}
+}
+
+// subscribe returns a channel receiving the analyses stored from now on
+func (f *analysisFeed) subscribe() chan analysisSummary {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	ch := make(chan analysisSummary, feedBuffer)
+	f.subscribers[ch] = struct{}{}
+	return ch
+}
+
+func (f *analysisFeed) unsubscribe(ch chan analysisSummary) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.subscribers, ch)
+}
+
+// publish sends a stored analysis to the subscribers, dropping it for those
+// whose buffer is full
+func (f *analysisFeed) publish(summary analysisSummary) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for ch := range f.subscribers {
+		select {
+		case ch <- summary:
+		default:
+		}
+	}
+}
+
+// publishAnalysis announces a just-stored analysis on the feed
+func (h *Handler) publishAnalysis(id int64, result *models.AnalysisResult) {
+	h.feed.publish(newAnalysisSummary(database.StoredAnalysis{
+		ID:              id,
+		CreatedAt:       time.Now(),
+		AlertName:       result.Alert.Name,
+		Cluster:         result.Alert.Cluster,
+		Namespace:       result.Alert.Namespace,
+		PodName:         result.Alert.Target(),
+		Severity:        result.Alert.Severity,
+		RootCause:       result.Analysis.RootCause,
+		Confidence:      result.Analysis.Confidence,
+		ConfidenceScore: result.Analysis.ConfidenceScore,
+	}))
+}
+
+// LiveAnalyses pushes each newly stored analysis over a websocket, as a
+// {"type": "analysis", "analysis": {...}} message with the summary the
+// analysis list embeds. Analyses stored again for the same alert keep their
+// ID, so clients replace the entry they already show.
+func (h *Handler) LiveAnalyses(c *gin.Context) {
+	conn, err := tailUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// The upgrader has already written an HTTP error
+		return
+	}
+	defer conn.Close()
+
+	analyses := h.feed.subscribe()
+	defer h.feed.unsubscribe(analyses)
+
+	// Clients only listen; reading detects them going away
+	gone := make(chan struct{})
+	go func() {
+		defer close(gone)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ping := time.NewTicker(feedPingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case summary := <-analyses:
+			if !callerAllows(c, summary.Namespace) {
+				continue
+			}
+			conn.SetWriteDeadline(time.Now().Add(tailWriteTimeout))
+			if err := conn.WriteJSON(gin.H{"type": "analysis", "analysis": summary}); err != nil {
+				h.logger.Debug("live analysis feed closed", zap.Error(err))
+				return
+			}
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(tailWriteTimeout)); err != nil {
+				return
+			}
+		case <-gone:
+			return
+		case <-h.stopping:
+			closeTail(conn, websocket.CloseGoingAway, "server is shutting down")
+			return
+		}
+	}
+}
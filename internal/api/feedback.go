@@ -0,0 +1,285 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/emirozbir/micro-sre/internal/agent"
+	"github.com/emirozbir/micro-sre/internal/database"
+	"github.com/emirozbir/micro-sre/internal/metrics"
+	"github.com/emirozbir/micro-sre/internal/models"
+)
+
+// maxFeedbackRecords caps the feedback collection
+const maxFeedbackRecords = 100
+
+// FeedbackRequest marks the root cause of an analysis version wrong
+type FeedbackRequest struct {
+	// Version is the version judged wrong; 0 is the latest
+	Version int    `json:"version" binding:"min=0"`
+	Hint    string `json:"hint" binding:"required"`
+}
+
+// SubmitFeedback records that the root cause of an analysis is wrong and
+// re-runs the analysis on the same data with the human hint. The
+// re-analysis is stored as the next version, linked to the version it
+// corrects.
+func (h *Handler) SubmitFeedback(c *gin.Context) {
+	var req FeedbackRequest
+	analysis, ok := h.analysisRequest(c, &req)
+	if !ok {
+		return
+	}
+	if !hasBundle(&analysis.AnalysisResult) || h.agent().Artifacts() == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": agent.ErrNoBundle.Error()})
+		return
+	}
+
+	version, rejected, ok := h.judgedVersion(c, analysis, req.Version)
+	if !ok {
+		return
+	}
+
+	feedback := &models.Feedback{
+		Version:           version,
+		RejectedRootCause: rejected,
+		Hint:              req.Hint,
+		Author:            h.actor(c),
+		CreatedAt:         time.Now(),
+	}
+
+	job := &analysisJob{Kind: jobKindFeedback, Namespace: analysis.Namespace, Target: analysis.AnalysisResult.Alert.Target()}
+	h.respond(c, job, func(ctx context.Context) (any, int64, error) {
+		result, err := h.agent().ReanalyzeWithFeedback(ctx, &analysis.AnalysisResult, feedback)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		corrected, err := h.saveAnalysisVersion(ctx, analysis.ID, result)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to save analysis version: %w", err)
+		}
+		_, err = h.db.SaveFeedback(database.FeedbackRecord{
+			AnalysisID:         analysis.ID,
+			Version:            feedback.Version,
+			CorrectedVersion:   corrected,
+			CreatedAt:          feedback.CreatedAt,
+			Author:             feedback.Author,
+			Rating:             database.RatingDown,
+			RejectedRootCause:  feedback.RejectedRootCause,
+			Hint:               feedback.Hint,
+			CorrectedRootCause: result.Analysis.RootCause,
+		})
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to save feedback: %w", err)
+		}
+
+		h.logger.Info("analysis corrected from feedback",
+			zap.Int64("id", analysis.ID),
+			zap.Int("version", feedback.Version),
+			zap.Int("corrected_version", corrected))
+		return gin.H{
+			"analysis_id":        analysis.ID,
+			"version":            corrected,
+			"corrects_version":   feedback.Version,
+			"root_cause_changed": result.Analysis.RootCause != feedback.RejectedRootCause,
+			"analysis":           result.Analysis,
+			"_links": gin.H{
+				"versions": gin.H{"href": analysisPath(routeAnalysisVersions, analysis.ID)},
+				"html":     gin.H{"href": analysisPath(routeAnalysisPage, analysis.ID), "type": "text/html"},
+			},
+		}, analysis.ID, nil
+	})
+}
+
+// RatingRequest rates the root cause of an analysis version
+type RatingRequest struct {
+	// Version is the version rated; 0 is the latest
+	Version int    `json:"version" binding:"min=0"`
+	Rating  string `json:"rating" binding:"required,oneof=up down"`
+	Comment string `json:"comment"`
+	// CorrectedRootCause is the actual root cause, when a human found it
+	CorrectedRootCause string `json:"corrected_root_cause"`
+}
+
+// RateAnalysis records a thumbs up or down for the root cause of an
+// analysis, with an optional comment and corrected root cause. Unlike a
+// correction through SubmitFeedback, nothing is re-analyzed.
+func (h *Handler) RateAnalysis(c *gin.Context) {
+	var req RatingRequest
+	analysis, ok := h.analysisRequest(c, &req)
+	if !ok {
+		return
+	}
+	version, rootCause, ok := h.judgedVersion(c, analysis, req.Version)
+	if !ok {
+		return
+	}
+
+	record := database.FeedbackRecord{
+		AnalysisID:         analysis.ID,
+		AlertName:          analysis.AlertName,
+		Namespace:          analysis.Namespace,
+		Version:            version,
+		CreatedAt:          time.Now(),
+		Author:             h.actor(c),
+		Rating:             req.Rating,
+		Comment:            req.Comment,
+		CorrectedRootCause: req.CorrectedRootCause,
+	}
+	if req.Rating == database.RatingDown {
+		record.RejectedRootCause = rootCause
+	}
+	id, err := h.db.SaveFeedback(record)
+	if err != nil {
+		h.logger.Error("failed to save rating", zap.Int64("id", analysis.ID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save rating"})
+		return
+	}
+	record.ID = id
+	metrics.FeedbackRatings.WithLabelValues(req.Rating).Inc()
+
+	c.JSON(http.StatusCreated, feedbackResponse(record))
+}
+
+// judgedVersion resolves the version of an analysis that feedback is about,
+// 0 standing for the latest, and returns its root cause. It answers the
+// request and returns false if the version is unknown or the analysis did
+// not complete.
+func (h *Handler) judgedVersion(c *gin.Context, analysis *database.StoredAnalysis, requested int) (int, string, bool) {
+	if !requireCompleted(c, analysis) {
+		return 0, "", false
+	}
+	versions, err := h.db.ListAnalysisVersions(analysis.ID)
+	if err != nil {
+		h.logger.Error("failed to list analysis versions", zap.Int64("id", analysis.ID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load analysis versions"})
+		return 0, "", false
+	}
+	if requested == 0 && len(versions) > 0 {
+		last := versions[len(versions)-1]
+		return last.Version, last.RootCause, true
+	}
+	if requested <= 1 {
+		return 1, analysis.RootCause, true
+	}
+	for _, v := range versions {
+		if v.Version == requested {
+			return v.Version, v.RootCause, true
+		}
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": "unknown analysis version"})
+	return 0, "", false
+}
+
+// ListFeedback returns the most recent ratings and corrections, optionally
+// for one analysis, alert or rating, as examples of rejected and corrected
+// root causes
+func (h *Handler) ListFeedback(c *gin.Context) {
+	limit := maxFeedbackRecords
+	if s := c.Query("limit"); s != "" {
+		if n, err := strconv.Atoi(s); err == nil && n > 0 && n < limit {
+			limit = n
+		}
+	}
+	filter := database.FeedbackFilter{
+		AlertName: c.Query("alert_name"),
+		Rating:    c.Query("rating"),
+		Limit:     limit,
+	}
+	if s := c.Query("analysis_id"); s != "" {
+		id, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid analysis_id"})
+			return
+		}
+		filter.AnalysisID = id
+	}
+
+	records, err := h.db.ListFeedback(filter)
+	if err != nil {
+		h.logger.Error("failed to list feedback", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list feedback"})
+		return
+	}
+
+	out := make([]gin.H, 0, len(records))
+	for _, f := range records {
+		out = append(out, feedbackResponse(f))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"count": len(out),
+		"_embedded": gin.H{
+			"feedback": out,
+		},
+	})
+}
+
+// FeedbackStats returns the share of up ratings over the last ?days= days
+// (default 30), optionally for one alert: the accuracy of the root causes
+// as judged by their readers
+func (h *Handler) FeedbackStats(c *gin.Context) {
+	days := defaultStatsDays
+	if s := c.Query("days"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxStatsDays {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("days must be between 1 and %d", maxStatsDays)})
+			return
+		}
+		days = n
+	}
+
+	up, down, err := h.db.CountRatings(c.Query("alert_name"), time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		h.logger.Error("failed to count ratings", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to count ratings"})
+		return
+	}
+
+	stats := gin.H{"days": days, "up": up, "down": down, "accuracy": nil}
+	if up+down > 0 {
+		stats["accuracy"] = float64(up) / float64(up+down)
+	}
+	c.JSON(http.StatusOK, stats)
+}
+
+// feedbackResponse renders a feedback record for the API
+func feedbackResponse(f database.FeedbackRecord) gin.H {
+	return gin.H{
+		"id":                   f.ID,
+		"analysis_id":          f.AnalysisID,
+		"alert_name":           f.AlertName,
+		"namespace":            f.Namespace,
+		"version":              f.Version,
+		"corrected_version":    f.CorrectedVersion,
+		"created_at":           f.CreatedAt,
+		"author":               f.Author,
+		"rating":               f.Rating,
+		"comment":              f.Comment,
+		"rejected_root_cause":  f.RejectedRootCause,
+		"hint":                 f.Hint,
+		"corrected_root_cause": f.CorrectedRootCause,
+		"_links": gin.H{
+			"analysis": gin.H{"href": analysisPath(routeAnalysis, f.AnalysisID)},
+			"versions": gin.H{"href": analysisPath(routeAnalysisVersions, f.AnalysisID)},
+		},
+	}
+}
+
+// hasBundle reports whether the collected pod data of an analysis was kept,
+// which re-running it requires
+func hasBundle(result *models.AnalysisResult) bool {
+	for _, ref := range result.Artifacts {
+		if ref.Name == models.ArtifactPodSpec {
+			return true
+		}
+	}
+	return false
+}
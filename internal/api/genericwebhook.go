@@ -0,0 +1,168 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"k8s.io/client-go/util/jsonpath"
+
+	"github.com/emirozbir/micro-sre/internal/config"
+	"github.com/emirozbir/micro-sre/internal/models"
+)
+
+// routeGenericWebhook receives payloads of monitoring systems mapped by
+// generic_webhooks
+const routeGenericWebhook = "/api/v1/webhook/generic"
+
+// defaultGenericSource is the mapping used without ?source
+const defaultGenericSource = "default"
+
+// ReceiveGenericWebhook analyzes the alerts of an arbitrary JSON payload,
+// mapped to namespace, pod and labels by the generic_webhooks entry named
+// by ?source (default "default"), like an AlertManager webhook
+func (h *Handler) ReceiveGenericWebhook(c *gin.Context) {
+	source := strings.ToLower(c.DefaultQuery("source", defaultGenericSource))
+	mapping, ok := h.agent().Config().GenericWebhooks[source]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("no generic webhook mapping named %q", source)})
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxWebhookBody))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read body"})
+		return
+	}
+	alerts, err := mapGenericAlerts(mapping, body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid webhook payload: " + err.Error()})
+		return
+	}
+
+	h.logger.Info("received generic webhook",
+		zap.String("source", source),
+		zap.Int("alert_count", len(alerts)))
+	for i := range alerts {
+		if !allowNamespace(c, alerts[i].GetNamespace()) {
+			return
+		}
+	}
+
+	webhook := models.AlertManagerWebhook{
+		Status:   "firing",
+		Receiver: "generic:" + source,
+		Alerts:   alerts,
+	}
+	w := webhookJob{Webhook: webhook}
+	job := &analysisJob{Kind: jobKindWebhook, Target: webhook.Receiver, webhook: &w}
+	h.respond(c, job, h.webhookTask(w))
+}
+
+// mapGenericAlerts evaluates a mapping against a payload. Alerts without
+// a fingerprint expression are fingerprinted by their content, so
+// redeliveries are recognized.
+func mapGenericAlerts(mapping config.GenericWebhookMapping, body []byte) ([]models.Alert, error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	// Numbers keep their text, so IDs don't turn into floats
+	decoder.UseNumber()
+	var payload any
+	if err := decoder.Decode(&payload); err != nil {
+		return nil, err
+	}
+
+	items := []any{payload}
+	if mapping.Alerts != "" {
+		var err error
+		if items, err = jsonpathValues(mapping.Alerts, payload); err != nil {
+			return nil, err
+		}
+	}
+
+	alerts := make([]models.Alert, 0, len(items))
+	for _, item := range items {
+		labels := map[string]string{}
+		for field, expr := range mapping.Expressions() {
+			if field == "alerts" || field == "fingerprint" {
+				continue
+			}
+			value, err := jsonpathText(expr, item)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", field, err)
+			}
+			if value != "" {
+				labels[strings.TrimPrefix(field, "labels.")] = value
+			}
+		}
+
+		fingerprint := ""
+		if mapping.Fingerprint != "" {
+			var err error
+			if fingerprint, err = jsonpathText(mapping.Fingerprint, item); err != nil {
+				return nil, fmt.Errorf("fingerprint: %w", err)
+			}
+		}
+		if fingerprint == "" {
+			data, _ := json.Marshal(item)
+			sum := sha256.Sum256(data)
+			fingerprint = hex.EncodeToString(sum[:8])
+		}
+
+		alerts = append(alerts, models.Alert{
+			Labels:      labels,
+			Annotations: map[string]string{},
+			StartsAt:    time.Now(),
+			Status:      "firing",
+			Fingerprint: "generic-" + fingerprint,
+		})
+	}
+	return alerts, nil
+}
+
+// jsonpathValues returns the values expr selects in data; a single list is
+// expanded into its elements
+func jsonpathValues(expr string, data any) ([]any, error) {
+	j := jsonpath.New("alerts").AllowMissingKeys(true)
+	if err := j.Parse(expr); err != nil {
+		return nil, err
+	}
+	results, err := j.FindResults(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var values []any
+	for _, result := range results {
+		for _, v := range result {
+			values = append(values, v.Interface())
+		}
+	}
+	if len(values) == 1 {
+		if list, ok := values[0].([]any); ok {
+			return list, nil
+		}
+	}
+	return values, nil
+}
+
+// jsonpathText returns the text expr selects in data; missing keys yield
+// an empty string
+func jsonpathText(expr string, data any) (string, error) {
+	j := jsonpath.New("field").AllowMissingKeys(true)
+	if err := j.Parse(expr); err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := j.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
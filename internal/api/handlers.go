@@ -2,43 +2,114 @@ package api
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"html/template"
+	"io/fs"
 	"math"
 	"net/http"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 
 	"github.com/emirozbir/micro-sre/internal/agent"
+	"github.com/emirozbir/micro-sre/internal/artifacts"
 	"github.com/emirozbir/micro-sre/internal/database"
+	"github.com/emirozbir/micro-sre/internal/metrics"
 	"github.com/emirozbir/micro-sre/internal/models"
+	"github.com/emirozbir/micro-sre/internal/opsgenie"
+	"github.com/emirozbir/micro-sre/internal/pagerduty"
+	"github.com/emirozbir/micro-sre/internal/pushgateway"
+	"github.com/emirozbir/micro-sre/internal/slack"
+	"github.com/emirozbir/micro-sre/internal/templates"
+	"github.com/emirozbir/micro-sre/internal/version"
 )
 
 type Handler struct {
-	agent  *agent.Agent
-	logger *zap.Logger
-	db     *database.DB
-	tmpl   *template.Template
+	// current is the agent requests are served by; ReloadConfig swaps it
+	current atomic.Pointer[agent.Agent]
+	logger  *zap.Logger
+	db      database.Store
+	tmpl    *template.Template
+	// static serves the web UI's stylesheets and scripts
+	static fs.FS
+
+	reanalysis   *reanalysisJobs
+	analysisJobs *analysisJobs
+	coalescer    *analysisCoalescer
+	feed         *analysisFeed
+	// pusher is nil unless export.pushgateway.url is set
+	pusher *pushgateway.Pusher
+	// slack is nil unless a Slack webhook or signing secret is set
+	slack *slack.Client
+	// pagerduty is nil unless a PagerDuty webhook secret or API token is set
+	pagerduty *pagerduty.Client
+	// opsgenie is nil unless an Opsgenie API key is set
+	opsgenie *opsgenie.Client
+	// tokens is nil unless server.oidc is configured
+	tokens *tokenVerifier
+	// limiter is nil unless server.rate_limit is configured
+	limiter *rateLimiter
+	// llmCheck caches the LLM credentials check of /readyz
+	llmCheck cachedCheck
+
+	// stopping is closed when the server starts shutting down, ending
+	// streams that would otherwise hold connections open
+	stopping chan struct{}
+	stopOnce sync.Once
+	// jobsCtx is cancelled when running jobs outlast the shutdown deadline
+	jobsCtx    context.Context
+	cancelJobs context.CancelFunc
 }
 
-func NewHandler(agent *agent.Agent, logger *zap.Logger, db *database.DB) *Handler {
+func NewHandler(agent *agent.Agent, logger *zap.Logger, db database.Store) *Handler {
 	// Parse templates with helper functions
 	funcMap := template.FuncMap{
 		"add": func(a, b int) int { return a + b },
 		"sub": func(a, b int) int { return a - b },
 	}
 
-	tmpl := template.Must(template.New("").Funcs(funcMap).ParseGlob("internal/templates/*.html"))
+	ui := templates.FS(agent.Config().Server.TemplatesDir)
+	tmpl := template.Must(template.New("").Funcs(funcMap).ParseFS(ui, "*.html"))
 
-	return &Handler{
-		agent:  agent,
+	jobsCtx, cancelJobs := context.WithCancel(context.Background())
+	h := &Handler{
 		logger: logger,
 		db:     db,
 		tmpl:   tmpl,
+		static: templates.Static(ui),
+
+		reanalysis:   newReanalysisJobs(),
+		analysisJobs: newAnalysisJobs(agent.Config().Agent.Jobs.QueueSize),
+		coalescer:    newAnalysisCoalescer(agent.Config().Agent.DedupWindow),
+		feed:         newAnalysisFeed(),
+		pusher:       pushgateway.New(agent.Config().Export.Pushgateway),
+		slack:        slack.New(agent.Config().Slack),
+		pagerduty:    pagerduty.New(agent.Config().PagerDuty),
+		opsgenie:     opsgenie.New(agent.Config().Opsgenie),
+		tokens:       newTokenVerifier(agent.Config().Server.OIDC),
+		limiter:      newRateLimiter(agent.Config().Server.RateLimit),
+
+		stopping:   make(chan struct{}),
+		jobsCtx:    jobsCtx,
+		cancelJobs: cancelJobs,
 	}
+	h.current.Store(agent)
+	h.seedBudget(agent.TokenBudget())
+	h.startJobWorkers(agent.Config().Agent.Jobs.Workers)
+	go h.pollJobs()
+	metrics.QueueDepth(func() int { return len(h.analysisJobs.queue) })
+	return h
+}
+
+// agent returns the agent for a request or job. Work that calls it once
+// and keeps the result runs on one configuration throughout a reload.
+func (h *Handler) agent() *agent.Agent {
+	return h.current.Load()
 }
 
 type AnalyzeAlertRequest struct {
@@ -46,6 +117,8 @@ type AnalyzeAlertRequest struct {
 	Namespace string `json:"namespace" binding:"required"`
 	Pod       string `json:"pod" binding:"required"`
 	Lookback  string `json:"lookback"`
+	Verbosity string `json:"verbosity" binding:"omitempty,oneof=brief standard deep"`
+	Cluster   string `json:"cluster"`
 }
 
 func (h *Handler) AnalyzeAlert(c *gin.Context) {
@@ -65,33 +138,38 @@ func (h *Handler) AnalyzeAlert(c *gin.Context) {
 		}
 	}
 
-	analysisReq := agent.AnalysisRequest{
+	if !h.clusterAllowed(c, req.Cluster) {
+		return
+	}
+
+	job := &analysisJob{Kind: jobKindAlert, Namespace: req.Namespace, Target: req.Pod, Lookback: lookback.String()}
+	h.respond(c, job, h.podTask(agent.AnalysisRequest{
 		AlertFingerprint: req.AlertID,
 		Namespace:        req.Namespace,
 		PodName:          req.Pod,
 		Lookback:         lookback,
-	}
-
-	result, err := h.agent.AnalyzeAlert(c.Request.Context(), analysisReq)
-	if err != nil {
-		h.logger.Error("analysis failed", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	// Save to database
-	if _, err := h.db.SaveAnalysis(result); err != nil {
-		h.logger.Error("failed to save analysis to database", zap.Error(err))
-		// Don't fail the request if DB save fails
-	}
-
-	c.JSON(http.StatusOK, result)
+		Verbosity:        req.Verbosity,
+		Cluster:          req.Cluster,
+	}))
 }
 
 type AnalyzePodRequest struct {
 	Namespace string `json:"namespace" binding:"required"`
 	Pod       string `json:"pod" binding:"required"`
 	Lookback  string `json:"lookback"`
+	Verbosity string `json:"verbosity" binding:"omitempty,oneof=brief standard deep"`
+	Cluster   string `json:"cluster"`
+}
+
+// clusterAllowed responds with 400 and reports false if a request names a
+// cluster other than cluster.name, the one this instance collects from
+func (h *Handler) clusterAllowed(c *gin.Context, cluster string) bool {
+	own := h.agent().Config().Cluster.Name
+	if cluster == "" || own == "" || cluster == own {
+		return true
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("this instance analyzes cluster %q, not %q", own, cluster)})
+	return false
 }
 
 func (h *Handler) AnalyzePod(c *gin.Context) {
@@ -111,36 +189,111 @@ func (h *Handler) AnalyzePod(c *gin.Context) {
 		}
 	}
 
-	analysisReq := agent.AnalysisRequest{
+	if !h.clusterAllowed(c, req.Cluster) {
+		return
+	}
+
+	job := &analysisJob{Kind: jobKindPod, Namespace: req.Namespace, Target: req.Pod, Lookback: lookback.String()}
+	h.respond(c, job, h.podTask(agent.AnalysisRequest{
 		Namespace: req.Namespace,
 		PodName:   req.Pod,
 		Lookback:  lookback,
+		Verbosity: req.Verbosity,
+		Cluster:   req.Cluster,
+	}))
+}
+
+type AnalyzeDeploymentRequest struct {
+	Namespace string `json:"namespace" binding:"required"`
+	// Name of a Deployment or StatefulSet
+	Name      string `json:"name" binding:"required"`
+	Lookback  string `json:"lookback"`
+	Verbosity string `json:"verbosity" binding:"omitempty,oneof=brief standard deep"`
+	Cluster   string `json:"cluster"`
+}
+
+func (h *Handler) AnalyzeDeployment(c *gin.Context) {
+	var req AnalyzeDeploymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
-	result, err := h.agent.AnalyzeAlert(c.Request.Context(), analysisReq)
-	if err != nil {
-		h.logger.Error("analysis failed", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	lookback := 1 * time.Hour
+	if req.Lookback != "" {
+		var err error
+		lookback, err = time.ParseDuration(req.Lookback)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid lookback duration"})
+			return
+		}
+	}
+
+	if !h.clusterAllowed(c, req.Cluster) {
+		return
+	}
+
+	job := &analysisJob{Kind: jobKindDeployment, Namespace: req.Namespace, Target: req.Name, Lookback: lookback.String()}
+	h.respond(c, job, func(ctx context.Context) (any, int64, error) {
+		runID := h.startAnalysis(ctx, h.agent().DeploymentSummary(req.Namespace, req.Name, req.Cluster))
+		result, err := h.agent().AnalyzeDeployment(ctx, req.Namespace, req.Name, lookback, req.Verbosity)
+		if err != nil {
+			h.failAnalysis(ctx, runID, err)
+			return nil, 0, err
+		}
+		if req.Cluster != "" {
+			result.Alert.Cluster = req.Cluster
+		}
+
+		id := h.saveAnalysis(ctx, runID, result)
+		return newAnalysisResponse(id, result), id, nil
+	})
+}
+
+type NamespaceHealthRequest struct {
+	Namespace string `json:"namespace" binding:"required"`
+	Lookback  string `json:"lookback"`
+}
+
+// AnalyzeNamespace produces a health report for a whole namespace
+func (h *Handler) AnalyzeNamespace(c *gin.Context) {
+	var req NamespaceHealthRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Save to database
-	if _, err := h.db.SaveAnalysis(result); err != nil {
-		h.logger.Error("failed to save analysis to database", zap.Error(err))
-		// Don't fail the request if DB save fails
+	lookback := 24 * time.Hour
+	if req.Lookback != "" {
+		var err error
+		lookback, err = time.ParseDuration(req.Lookback)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid lookback duration"})
+			return
+		}
 	}
 
-	c.JSON(http.StatusOK, result)
+	job := &analysisJob{Kind: jobKindNamespace, Namespace: req.Namespace, Lookback: lookback.String()}
+	h.respond(c, job, func(ctx context.Context) (any, int64, error) {
+		report, err := h.agent().NamespaceHealthReport(ctx, req.Namespace, lookback)
+		if err != nil {
+			return nil, 0, err
+		}
+		return report, 0, nil
+	})
 }
 
-func (h *Handler) Health(c *gin.Context) {
+// Version reports the server version and security-relevant modes
+func (h *Handler) Version(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
-		"status": "healthy",
-		"time":   time.Now(),
+		"version":   version.Version,
+		"read_only": h.agent().ReadOnly(),
 	})
 }
 
-// ReceiveAlertManagerWebhook handles incoming AlertManager webhook payloads
+// ReceiveAlertManagerWebhook handles incoming AlertManager webhook payloads.
+// Point AlertManager at ".../webhook/alertmanager?async=true" to have the
+// batch queued and acknowledged immediately instead.
 func (h *Handler) ReceiveAlertManagerWebhook(c *gin.Context) {
 	var webhook models.AlertManagerWebhook
 	if err := c.ShouldBindJSON(&webhook); err != nil {
@@ -154,23 +307,119 @@ func (h *Handler) ReceiveAlertManagerWebhook(c *gin.Context) {
 		zap.String("status", webhook.Status),
 		zap.Int("alert_count", len(webhook.Alerts)))
 
+	for i := range webhook.Alerts {
+		if !allowNamespace(c, webhook.Alerts[i].GetNamespace()) {
+			return
+		}
+	}
+
+	w := webhookJob{Webhook: webhook}
+	job := &analysisJob{Kind: jobKindWebhook, Target: webhook.Receiver, webhook: &w}
+	h.respond(c, job, h.webhookTask(w))
+}
+
+// EnqueueAlerts queues a job analyzing alerts raised by a background
+// trigger (the AlertManager poller, the pod watch) as if they had arrived in
+// one webhook to receiver. It returns false if the job queue is full and
+// the job could not be stored to be queued later.
+func (h *Handler) EnqueueAlerts(receiver string, alerts []models.Alert) bool {
+	return h.queueWebhook(jobKindTriggered, webhookJob{Webhook: models.AlertManagerWebhook{
+		Status:   "firing",
+		Receiver: receiver,
+		Alerts:   alerts,
+	}}, time.Now())
+}
+
+// webhookLookback returns the lookback of a webhook alert: the one its
+// hepsre.io/lookback annotation sets, else 1h, unless the severity's
+// collection profile widens or narrows it. Invalid annotations are logged
+// and ignored.
+func (h *Handler) webhookLookback(alert *models.Alert) time.Duration {
+	lookback, err := alert.Lookback()
+	if err != nil {
+		h.logger.Warn("ignoring alert annotation", zap.String("alert_name", alert.GetAlertName()), zap.Error(err))
+	}
+	if lookback > 0 {
+		return lookback
+	}
+	if profile := h.agent().Config().CollectionProfileFor(alert.GetSeverity()); profile.Lookback > 0 {
+		return profile.Lookback
+	}
+	return 1 * time.Hour
+}
+
+// skipAlerts separates the alerts whose authors opted out of analysis with
+// the hepsre.io/skip annotation, those alert_filters filter out and, once
+// cluster.name is set, those labelled with another cluster, whose pods
+// cannot be inspected through this instance's kubernetes connection
+func (h *Handler) skipAlerts(alerts []models.Alert) (kept []models.Alert, skipped []models.SkippedAlert) {
+	filter := h.agent().AlertFilter()
+	cluster := h.agent().Config().Cluster
+	for _, alert := range alerts {
+		reason := filter.Reject(&alert)
+		if alert.Skip() {
+			reason = models.AnnotationSkip + " annotation"
+		}
+		if other := alert.Labels[cluster.Label]; cluster.Name != "" && other != "" && other != cluster.Name {
+			reason = fmt.Sprintf("cluster %q is not analyzed by this instance of cluster %q", other, cluster.Name)
+		}
+		if reason != "" {
+			skipped = append(skipped, models.SkippedAlert{
+				Fingerprint: alert.Fingerprint,
+				AlertName:   alert.GetAlertName(),
+				Reason:      reason,
+			})
+			continue
+		}
+		kept = append(kept, alert)
+	}
+	return kept, skipped
+}
+
+// processWebhook analyzes every alert of a webhook payload in parallel,
+// alerts correlated into an incident together. Partial failures are
+// reported in the response rather than as an error.
+func (h *Handler) processWebhook(ctx context.Context, webhook models.AlertManagerWebhook) models.WebhookAnalysisResponse {
 	// Create context with timeout for batch processing (5 minutes)
-	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Minute)
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
 	defer cancel()
 
-	// Default lookback duration (1 hour)
-	lookback := 1 * time.Hour
-
 	// Prepare result structures
 	var (
-		results []models.AlertAnalysisResult
-		errors  []models.AlertAnalysisError
-		mu      sync.Mutex
-		wg      sync.WaitGroup
+		results   []models.AlertAnalysisResult
+		errors    []models.AlertAnalysisError
+		incidents []models.IncidentResult
+		mu        sync.Mutex
+		wg        sync.WaitGroup
 	)
 
-	// Process each alert in parallel
-	for _, alert := range webhook.Alerts {
+	// Alerts opted out of analysis or filtered out are reported as skipped
+	kept, skipped := h.skipAlerts(webhook.Alerts)
+
+	// Re-delivered alerts return their stored analysis
+	fresh, replayed := h.replayDelivered(kept)
+	results = append(results, replayed...)
+
+	// Alerts firing on several pods of one workload are analyzed once, as
+	// an incident
+	groups, alerts := h.correlateAlerts(ctx, fresh)
+	for _, group := range groups {
+		wg.Add(1)
+		go func(group alertGroup) {
+			defer wg.Done()
+			incident, groupResults, groupErrors := h.analyzeIncident(ctx, group)
+			mu.Lock()
+			if incident != nil {
+				incidents = append(incidents, *incident)
+			}
+			results = append(results, groupResults...)
+			errors = append(errors, groupErrors...)
+			mu.Unlock()
+		}(group)
+	}
+
+	// Process the remaining alerts in parallel
+	for _, alert := range alerts {
 		wg.Add(1)
 		go func(alert models.Alert) {
 			defer wg.Done()
@@ -197,16 +446,36 @@ func (h *Handler) ReceiveAlertManagerWebhook(c *gin.Context) {
 				return
 			}
 
+			// Skip targets that keep failing until their backoff expires
+			if failure := h.backingOff(namespace, podName); failure != nil {
+				retryAfter := failure.NextAttemptAt
+				mu.Lock()
+				errors = append(errors, models.AlertAnalysisError{
+					Fingerprint: alert.Fingerprint,
+					AlertName:   alertName,
+					Error:       fmt.Sprintf("backing off after %d consecutive failures: %s", failure.Failures, failure.LastError),
+					RetryAfter:  &retryAfter,
+				})
+				mu.Unlock()
+				return
+			}
+
 			// Create analysis request
 			analysisReq := agent.AnalysisRequest{
 				AlertFingerprint: alert.Fingerprint,
 				Namespace:        namespace,
 				PodName:          podName,
-				Lookback:         lookback,
+				Lookback:         h.webhookLookback(&alert),
+				Alert:            &alert,
+				GroupLabels:      webhook.GroupLabels,
+				Verbosity:        h.agent().Config().Report.VerbosityFor(alert.Labels),
 			}
 
-			// Perform analysis
-			result, err := h.agent.AnalyzeAlert(ctx, analysisReq)
+			// Perform analysis; identical alerts of a resent group share one run
+			id, result, shared, err := h.analyzePod(ctx, analysisReq)
+			if !shared {
+				h.recordAnalysisOutcome(namespace, podName, err)
+			}
 			if err != nil {
 				h.logger.Error("alert analysis failed",
 					zap.String("alert_name", alertName),
@@ -219,22 +488,18 @@ func (h *Handler) ReceiveAlertManagerWebhook(c *gin.Context) {
 					Fingerprint: alert.Fingerprint,
 					AlertName:   alertName,
 					Error:       err.Error(),
+					ErrorClass:  errorClass(err),
 				})
 				mu.Unlock()
 				return
 			}
 
-			// Save to database
-			if _, err := h.db.SaveAnalysis(result); err != nil {
-				h.logger.Error("failed to save analysis to database",
-					zap.String("alert_name", alertName),
-					zap.Error(err))
-				// Don't fail the analysis if DB save fails
-			}
+			h.recordDelivery(alert, id)
 
 			// Add successful result
 			mu.Lock()
 			results = append(results, models.AlertAnalysisResult{
+				ID:            id,
 				Fingerprint:   alert.Fingerprint,
 				AlertName:     alertName,
 				Namespace:     namespace,
@@ -243,6 +508,9 @@ func (h *Handler) ReceiveAlertManagerWebhook(c *gin.Context) {
 				Status:        alert.Status,
 				Analysis:      &result.Analysis,
 				CollectedData: &result.CollectedData,
+				Recurrence:    result.Recurrence,
+				Deduplicated:  shared,
+				Links:         analysisLinks(id, result),
 			})
 			mu.Unlock()
 
@@ -255,6 +523,7 @@ func (h *Handler) ReceiveAlertManagerWebhook(c *gin.Context) {
 
 	// Wait for all analyses to complete
 	wg.Wait()
+	h.deferOverBudget(webhook.Receiver, webhook.Alerts, errors)
 
 	// Build response
 	response := models.WebhookAnalysisResponse{
@@ -263,15 +532,21 @@ func (h *Handler) ReceiveAlertManagerWebhook(c *gin.Context) {
 		Failed:   len(errors),
 		Results:  results,
 		Errors:   errors,
+		Skipped:  skipped,
+
+		Incidents: incidents,
 	}
 
 	h.logger.Info("webhook processing completed",
 		zap.Int("received", response.Received),
 		zap.Int("analyzed", response.Analyzed),
-		zap.Int("failed", response.Failed))
+		zap.Int("replayed", len(replayed)),
+		zap.Int("failed", response.Failed),
+		zap.Int("skipped", len(skipped)),
+		zap.Int("incidents", len(incidents)))
 
 	// Return 200 even with partial failures
-	c.JSON(http.StatusOK, response)
+	return response
 }
 
 // ListAnalyses displays the HTML page with all analyses
@@ -287,8 +562,19 @@ func (h *Handler) ListAnalyses(c *gin.Context) {
 	perPage := 20
 	offset := (page - 1) * perPage
 
+	sort := c.Query("sort")
+	if !database.ValidSort(sort) {
+		sort = database.SortNewest
+	}
+	cluster := c.Query("cluster")
+	tag, _ := normalizeTag(c.Query("tag"))
+
 	// Get analyses from database
-	analyses, err := h.db.ListAnalyses(perPage, offset)
+	filter := database.AnalysisFilter{Cluster: cluster, NamespacePatterns: namespacePatterns(c), Sort: sort, Limit: perPage, Offset: offset}
+	if tag != "" {
+		filter.Tags = []string{tag}
+	}
+	analyses, err := h.db.FindAnalyses(filter)
 	if err != nil {
 		h.logger.Error("failed to list analyses", zap.Error(err))
 		c.String(http.StatusInternalServerError, "Failed to load analyses")
@@ -296,7 +582,7 @@ func (h *Handler) ListAnalyses(c *gin.Context) {
 	}
 
 	// Get total count
-	total, err := h.db.CountAnalyses()
+	total, err := h.db.CountFilteredAnalyses(filter)
 	if err != nil {
 		h.logger.Error("failed to count analyses", zap.Error(err))
 		c.String(http.StatusInternalServerError, "Failed to count analyses")
@@ -305,12 +591,43 @@ func (h *Handler) ListAnalyses(c *gin.Context) {
 
 	totalPages := int(math.Ceil(float64(total) / float64(perPage)))
 
+	// Targets whose analyses keep failing
+	attention, err := h.db.ListTargetFailures(h.agent().Config().Agent.FailureBackoff.AttentionThreshold)
+	if err != nil {
+		// Still render the history
+		h.logger.Warn("failed to list failing targets", zap.Error(err))
+	}
+
+	chart, err := h.dailyChart()
+	if err != nil {
+		h.logger.Warn("failed to load daily analysis stats", zap.Error(err))
+	}
+
+	clusters, err := h.db.ListClusters()
+	if err != nil {
+		h.logger.Warn("failed to list clusters", zap.Error(err))
+	}
+
+	tags, err := h.db.ListTags()
+	if err != nil {
+		h.logger.Warn("failed to list tags", zap.Error(err))
+	}
+
 	// Render template
 	data := gin.H{
-		"Analyses":   analyses,
-		"Total":      total,
-		"Page":       page,
-		"TotalPages": totalPages,
+		"Analyses":       analyses,
+		"Total":          total,
+		"Page":           page,
+		"TotalPages":     totalPages,
+		"Sort":           sort,
+		"Cluster":        cluster,
+		"Clusters":       clusters,
+		"Tag":            tag,
+		"Tags":           tags,
+		"NeedsAttention": attention,
+		"DailyChart":     chart,
+		"PerPage":        perPage,
+		"LiveLink":       routeAnalysesLive,
 	}
 
 	if err := h.tmpl.ExecuteTemplate(c.Writer, "list.html", data); err != nil {
@@ -335,14 +652,168 @@ func (h *Handler) GetAnalysis(c *gin.Context) {
 		return
 	}
 
-	if analysis == nil {
+	if analysis == nil || !callerAllows(c, analysis.Namespace) {
 		c.String(http.StatusNotFound, "Analysis not found")
 		return
 	}
 
+	analysis.Versions, err = h.db.ListAnalysisVersions(id)
+	if err != nil {
+		// Still render the original analysis
+		h.logger.Warn("failed to list analysis versions", zap.Int64("id", id), zap.Error(err))
+	}
+	analysis.Feedback, err = h.db.ListFeedback(database.FeedbackFilter{AnalysisID: id, Limit: maxFeedbackRecords})
+	if err != nil {
+		h.logger.Warn("failed to list feedback", zap.Int64("id", id), zap.Error(err))
+	}
+
 	// Render template
 	if err := h.tmpl.ExecuteTemplate(c.Writer, "detail.html", analysis); err != nil {
 		h.logger.Error("failed to render template", zap.Error(err))
 		c.String(http.StatusInternalServerError, "Failed to render page")
 	}
 }
+
+// GetAnalysisJSON returns a stored analysis as JSON
+func (h *Handler) GetAnalysisJSON(c *gin.Context) {
+	analysis, ok := h.loadAnalysis(c)
+	if !ok {
+		return
+	}
+
+	response := newAnalysisResponse(analysis.ID, &analysis.AnalysisResult)
+	response.Status = analysis.Status
+	response.Error = analysis.Error
+	response.Tags = analysis.Tags
+	if analysis.IncidentID != 0 {
+		response.IncidentID = analysis.IncidentID
+		response.Links["incident"] = models.Link{Href: analysisPath(routeIncident, analysis.IncidentID)}
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// maxSimilarAnalyses caps the similar analyses collection
+const maxSimilarAnalyses = 20
+
+// GetSimilarAnalyses returns earlier analyses of the same alert in the same
+// namespace
+func (h *Handler) GetSimilarAnalyses(c *gin.Context) {
+	analysis, ok := h.loadAnalysis(c)
+	if !ok {
+		return
+	}
+
+	similar, err := h.db.FindSimilar(analysis.ID, analysis.AlertName, analysis.Cluster, analysis.Namespace, maxSimilarAnalyses)
+	if err != nil {
+		h.logger.Error("failed to find similar analyses", zap.Int64("id", analysis.ID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load similar analyses"})
+		return
+	}
+
+	summaries := make([]analysisSummary, 0, len(similar))
+	for _, s := range similar {
+		summaries = append(summaries, newAnalysisSummary(s))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"count": len(summaries),
+		"_embedded": gin.H{
+			"analyses": summaries,
+		},
+		"_links": models.Links{
+			"self":     {Href: analysisPath(routeAnalysisSimilar, analysis.ID)},
+			"analysis": {Href: analysisPath(routeAnalysis, analysis.ID)},
+		},
+	})
+}
+
+// analysisRequest checks the caller, resolves the :id parameter to a stored
+// analysis and, unless req is nil, binds the JSON body into req. It writes
+// the error response and returns false if any of them fails.
+func (h *Handler) analysisRequest(c *gin.Context, req any) (*database.StoredAnalysis, bool) {
+	if !h.requireViewer(c) {
+		return nil, false
+	}
+	analysis, ok := h.loadAnalysis(c)
+	if !ok {
+		return nil, false
+	}
+	if req != nil {
+		if err := c.ShouldBindJSON(req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return nil, false
+		}
+	}
+	return analysis, true
+}
+
+// loadAnalysis resolves the :id parameter to a stored analysis, writing a
+// JSON error response and returning false if it cannot
+func (h *Handler) loadAnalysis(c *gin.Context) (*database.StoredAnalysis, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid analysis ID"})
+		return nil, false
+	}
+
+	analysis, err := h.db.GetAnalysis(id)
+	if err != nil {
+		h.logger.Error("failed to get analysis", zap.Int64("id", id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load analysis"})
+		return nil, false
+	}
+	// Analyses outside an OIDC caller's namespaces are not found for them
+	if analysis == nil || !callerAllows(c, analysis.Namespace) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "analysis not found"})
+		return nil, false
+	}
+
+	return analysis, true
+}
+
+// requireCompleted answers 409 and returns false if the analysis has no
+// result to work on, because it is still running or failed
+func requireCompleted(c *gin.Context, analysis *database.StoredAnalysis) bool {
+	if analysis.Status != database.AnalysisCompleted {
+		c.JSON(http.StatusConflict, gin.H{"error": "analysis is " + analysis.Status})
+		return false
+	}
+	return true
+}
+
+// GetArtifact returns a collected blob stored with an analysis
+func (h *Handler) GetArtifact(c *gin.Context) {
+	store := h.agent().Artifacts()
+	if store == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "artifact storage is disabled"})
+		return
+	}
+
+	analysis, ok := h.loadAnalysis(c)
+	if !ok {
+		return
+	}
+
+	name := c.Param("name")
+	for _, ref := range analysis.AnalysisResult.Artifacts {
+		if ref.Name != name {
+			continue
+		}
+
+		data, err := store.Get(c.Request.Context(), ref.ID)
+		if errors.Is(err, artifacts.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "artifact no longer available"})
+			return
+		}
+		if err != nil {
+			h.logger.Error("failed to read artifact", zap.String("artifact", ref.ID), zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to read artifact"})
+			return
+		}
+
+		c.Data(http.StatusOK, ref.ContentType, data)
+		return
+	}
+
+	c.JSON(http.StatusNotFound, gin.H{"error": "artifact not found"})
+}
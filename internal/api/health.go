@@ -0,0 +1,106 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// Probe routes
+const (
+	routeLivez   = "/livez"
+	routeReadyz  = "/readyz"
+	routeHealthz = "/healthz"
+)
+
+// Livez reports that the process is up. It checks no dependencies, so a
+// failing database or API server does not get the pod restarted.
+func (h *Handler) Livez(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status": "ok",
+		"time":   time.Now(),
+	})
+}
+
+// Readyz reports whether the server can analyze: the Kubernetes API server
+// and the database are reachable and, with server.readiness.check_llm, the
+// LLM credentials are valid. It answers 503 with the failed checks, and
+// while the server shuts down, so Kubernetes stops routing to it.
+func (h *Handler) Readyz(c *gin.Context) {
+	select {
+	case <-h.stopping:
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "shutting down"})
+		return
+	default:
+	}
+
+	cfg := h.agent().Config().Server.Readiness
+	ctx, cancel := context.WithTimeout(c.Request.Context(), cfg.Timeout)
+	defer cancel()
+
+	checks := map[string]func(context.Context) error{
+		"kubernetes": h.agent().Kubernetes().Ping,
+		"database":   h.db.Ping,
+	}
+	if cfg.CheckLLM {
+		checks["llm"] = func(ctx context.Context) error {
+			return h.llmCheck.run(ctx, cfg.LLMInterval, h.agent().CheckLLM)
+		}
+	}
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		results = map[string]string{}
+		ready   = true
+	)
+	for name, check := range checks {
+		wg.Add(1)
+		go func(name string, check func(context.Context) error) {
+			defer wg.Done()
+			err := check(ctx)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				h.logger.Warn("readiness check failed", zap.String("check", name), zap.Error(err))
+				results[name] = err.Error()
+				ready = false
+				return
+			}
+			results[name] = "ok"
+		}(name, check)
+	}
+	wg.Wait()
+
+	if !ready {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "not ready", "checks": results})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ready", "checks": results})
+}
+
+// cachedCheck remembers when a check last succeeded, for checks too
+// expensive to run on every probe. Failures are not remembered, so a
+// recovered dependency is noticed on the next probe.
+type cachedCheck struct {
+	mu     sync.Mutex
+	passed time.Time
+}
+
+// run succeeds if check passed within ttl, and runs check otherwise
+func (c *cachedCheck) run(ctx context.Context, ttl time.Duration, check func(context.Context) error) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.passed.IsZero() && time.Since(c.passed) < ttl {
+		return nil
+	}
+	if err := check(ctx); err != nil {
+		return err
+	}
+	c.passed = time.Now()
+	return nil
+}
@@ -0,0 +1,581 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/emirozbir/micro-sre/internal/database"
+	"github.com/emirozbir/micro-sre/internal/models"
+)
+
+// Incident list page size
+const incidentsPerPage = 20
+
+// maxIncidentAnalyses caps the analyses listed with an incident
+const maxIncidentAnalyses = 200
+
+// IncidentRequest groups analyses of one outage into a new incident
+type IncidentRequest struct {
+	// Title defaults to the alert and namespace of the first analysis
+	Title       string  `json:"title" binding:"max=255"`
+	AnalysisIDs []int64 `json:"analysis_ids" binding:"required,min=1,max=100"`
+}
+
+// IncidentUpdate renames an incident or changes its status; empty fields
+// are left alone
+type IncidentUpdate struct {
+	Title  string `json:"title" binding:"max=255"`
+	Status string `json:"status" binding:"omitempty,oneof=open resolved"`
+}
+
+// IncidentAnalysesRequest adds analyses to an incident
+type IncidentAnalysesRequest struct {
+	AnalysisIDs []int64 `json:"analysis_ids" binding:"required,min=1,max=100"`
+}
+
+// MergeIncidentsRequest names the incidents merged into another
+type MergeIncidentsRequest struct {
+	IncidentIDs []int64 `json:"incident_ids" binding:"required,min=1,max=100"`
+}
+
+// incidentResponse is an incident with hypermedia links and, when it is
+// requested alone, its analyses
+type incidentResponse struct {
+	ID            int64     `json:"id"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	Title         string    `json:"title"`
+	Status        string    `json:"status"`
+	Author        string    `json:"author,omitempty"`
+	AnalysisCount int       `json:"analysis_count"`
+	// Severity is the highest severity of the analyses, and Namespaces the
+	// namespaces they span
+	Severity   string   `json:"severity,omitempty"`
+	Namespaces []string `json:"namespaces,omitempty"`
+	Embedded   *struct {
+		Analyses []analysisSummary `json:"analyses"`
+	} `json:"_embedded,omitempty"`
+	Links models.Links `json:"_links"`
+}
+
+// incidentGroup is an incident with its analyses, as rendered by the
+// incidents page
+type incidentGroup struct {
+	database.Incident
+	Analyses   []database.StoredAnalysis
+	Severity   string
+	Namespaces []string
+}
+
+func newIncidentGroup(incident database.Incident, analyses []database.StoredAnalysis) incidentGroup {
+	g := incidentGroup{Incident: incident, Analyses: analyses}
+	seen := map[string]bool{}
+	for _, a := range analyses {
+		if models.SeverityRank(a.Severity) > models.SeverityRank(g.Severity) {
+			g.Severity = a.Severity
+		}
+		if !seen[a.Namespace] {
+			seen[a.Namespace] = true
+			g.Namespaces = append(g.Namespaces, a.Namespace)
+		}
+	}
+	sort.Strings(g.Namespaces)
+	return g
+}
+
+func newIncidentResponse(incident database.Incident) incidentResponse {
+	return incidentResponse{
+		ID:            incident.ID,
+		CreatedAt:     incident.CreatedAt,
+		UpdatedAt:     incident.UpdatedAt,
+		Title:         incident.Title,
+		Status:        incident.Status,
+		Author:        incident.Author,
+		AnalysisCount: incident.AnalysisCount,
+		Links: models.Links{
+			"self":     {Href: analysisPath(routeIncident, incident.ID)},
+			"html":     {Href: analysisPath(routeIncidentPage, incident.ID), Type: "text/html"},
+			"analyses": {Href: fmt.Sprintf("%s?incident=%d", routeAnalyses, incident.ID)},
+			"merge":    {Href: analysisPath(routeIncidentMerge, incident.ID)},
+		},
+	}
+}
+
+// loadIncident resolves the :id parameter to an incident, writing a JSON
+// error response and returning false if it cannot
+func (h *Handler) loadIncident(c *gin.Context) (*database.Incident, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid incident ID"})
+		return nil, false
+	}
+
+	incident, err := h.db.GetIncident(id)
+	if err != nil {
+		h.logger.Error("failed to get incident", zap.Int64("id", id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load incident"})
+		return nil, false
+	}
+	if incident == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "incident not found"})
+		return nil, false
+	}
+	visible, err := h.incidentVisible(c, id)
+	if err != nil {
+		h.logger.Error("failed to count incident analyses", zap.Int64("id", id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load incident"})
+		return nil, false
+	}
+	if !visible {
+		c.JSON(http.StatusNotFound, gin.H{"error": "incident not found"})
+		return nil, false
+	}
+	return incident, true
+}
+
+// incidentVisible reports whether the caller may see incident id: an OIDC
+// caller limited to some namespaces sees the incidents with an analysis in
+// one of them
+func (h *Handler) incidentVisible(c *gin.Context, id int64) (bool, error) {
+	patterns := namespacePatterns(c)
+	if patterns == nil {
+		return true, nil
+	}
+	n, err := h.db.CountFilteredAnalyses(database.AnalysisFilter{IncidentID: id, NamespacePatterns: patterns})
+	return n > 0, err
+}
+
+// loadGroupedAnalyses resolves analysis IDs to stored analyses, dropping
+// duplicates. It answers 400 and returns false if one does not exist.
+func (h *Handler) loadGroupedAnalyses(c *gin.Context, ids []int64) ([]*database.StoredAnalysis, bool) {
+	seen := make(map[int64]bool, len(ids))
+	analyses := make([]*database.StoredAnalysis, 0, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		analysis, err := h.db.GetAnalysis(id)
+		if err != nil {
+			h.logger.Error("failed to get analysis", zap.Int64("id", id), zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load analysis"})
+			return nil, false
+		}
+		if analysis == nil || !callerAllows(c, analysis.Namespace) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("analysis %d not found", id)})
+			return nil, false
+		}
+		analyses = append(analyses, analysis)
+	}
+	return analyses, true
+}
+
+// CreateIncident groups analyses of one outage, such as alerts on
+// different pods with one cause, into a new open incident. Analyses in
+// another incident are moved.
+func (h *Handler) CreateIncident(c *gin.Context) {
+	if !h.requireViewer(c) {
+		return
+	}
+	var req IncidentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	author := h.actor(c)
+	analyses, ok := h.loadGroupedAnalyses(c, req.AnalysisIDs)
+	if !ok {
+		return
+	}
+
+	incident := database.Incident{
+		CreatedAt: time.Now(),
+		Title:     req.Title,
+		Author:    author,
+	}
+	if incident.Title == "" {
+		incident.Title = analyses[0].AlertName + " in " + analyses[0].Namespace
+	}
+	ids := make([]int64, 0, len(analyses))
+	for _, a := range analyses {
+		ids = append(ids, a.ID)
+	}
+	id, err := h.db.CreateIncident(incident, ids)
+	if err != nil {
+		h.logger.Error("failed to create incident", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create incident"})
+		return
+	}
+	h.logger.Info("incident created", zap.Int64("id", id), zap.Int("analyses", len(ids)), zap.String("author", author))
+
+	h.respondIncident(c, http.StatusCreated, id)
+}
+
+// ListIncidents returns incidents, most recently updated first, optionally
+// with one status, with page pagination
+func (h *Handler) ListIncidents(c *gin.Context) {
+	filter, page, ok := incidentFilter(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be open or resolved, and page a positive number"})
+		return
+	}
+
+	incidents, err := h.db.ListIncidents(filter)
+	if err != nil {
+		h.logger.Error("failed to list incidents", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list incidents"})
+		return
+	}
+
+	out := make([]incidentResponse, 0, len(incidents))
+	for _, incident := range incidents {
+		visible, err := h.incidentVisible(c, incident.ID)
+		if err != nil {
+			h.logger.Error("failed to count incident analyses", zap.Int64("id", incident.ID), zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list incidents"})
+			return
+		}
+		if visible {
+			out = append(out, newIncidentResponse(incident))
+		}
+	}
+	links := models.Links{"self": {Href: incidentsPage(c, page)}}
+	if page > 1 {
+		links["prev"] = models.Link{Href: incidentsPage(c, page-1)}
+	}
+	if len(incidents) == filter.Limit {
+		links["next"] = models.Link{Href: incidentsPage(c, page+1)}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"count": len(out),
+		"page":  page,
+		"_embedded": gin.H{
+			"incidents": out,
+		},
+		"_links": links,
+	})
+}
+
+// incidentFilter reads the status and page of an incident list request,
+// reporting false if either is invalid
+func incidentFilter(c *gin.Context) (database.IncidentFilter, int, bool) {
+	filter := database.IncidentFilter{Status: c.Query("status"), Limit: incidentsPerPage}
+	switch filter.Status {
+	case "", database.IncidentOpen, database.IncidentResolved:
+	default:
+		return filter, 0, false
+	}
+	page := 1
+	if v := c.Query("page"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return filter, 0, false
+		}
+		page = n
+	}
+	filter.Offset = (page - 1) * filter.Limit
+	return filter, page, true
+}
+
+// incidentsPage links a page of the incident list, keeping its filter
+func incidentsPage(c *gin.Context, page int) string {
+	query := c.Request.URL.Query()
+	query.Set("page", strconv.Itoa(page))
+	return routeIncidents + "?" + query.Encode()
+}
+
+// GetIncident returns an incident with its analyses
+func (h *Handler) GetIncident(c *gin.Context) {
+	incident, ok := h.loadIncident(c)
+	if !ok {
+		return
+	}
+	h.respondIncident(c, http.StatusOK, incident.ID)
+}
+
+// UpdateIncident renames an incident or resolves or reopens it
+func (h *Handler) UpdateIncident(c *gin.Context) {
+	incident, ok := h.loadIncident(c)
+	if !ok {
+		return
+	}
+
+	var req IncidentUpdate
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Title == "" {
+		req.Title = incident.Title
+	}
+	if req.Status == "" {
+		req.Status = incident.Status
+	}
+
+	if _, err := h.db.UpdateIncident(incident.ID, req.Title, req.Status); err != nil {
+		h.logger.Error("failed to update incident", zap.Int64("id", incident.ID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update incident"})
+		return
+	}
+	h.logger.Info("incident updated", zap.Int64("id", incident.ID), zap.String("status", req.Status), zap.String("actor", h.actor(c)))
+
+	h.respondIncident(c, http.StatusOK, incident.ID)
+}
+
+// AddIncidentAnalyses adds analyses to an incident, moving them out of the
+// incidents they were in
+func (h *Handler) AddIncidentAnalyses(c *gin.Context) {
+	incident, ok := h.loadIncident(c)
+	if !ok {
+		return
+	}
+
+	if !h.requireViewer(c) {
+		return
+	}
+	var req IncidentAnalysesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	author := h.actor(c)
+	analyses, ok := h.loadGroupedAnalyses(c, req.AnalysisIDs)
+	if !ok {
+		return
+	}
+
+	ids := make([]int64, 0, len(analyses))
+	for _, a := range analyses {
+		ids = append(ids, a.ID)
+	}
+	if err := h.db.AddIncidentAnalyses(incident.ID, ids, author); err != nil {
+		h.logger.Error("failed to add analyses to incident", zap.Int64("id", incident.ID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to add analyses to incident"})
+		return
+	}
+	h.logger.Info("analyses added to incident", zap.Int64("id", incident.ID), zap.Int64s("analyses", ids), zap.String("author", author))
+
+	h.respondIncident(c, http.StatusOK, incident.ID)
+}
+
+// RemoveIncidentAnalysis takes an analysis out of an incident
+func (h *Handler) RemoveIncidentAnalysis(c *gin.Context) {
+	incident, ok := h.loadIncident(c)
+	if !ok {
+		return
+	}
+	analysisID, err := strconv.ParseInt(c.Param("analysis_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid analysis ID"})
+		return
+	}
+
+	removed, err := h.db.RemoveIncidentAnalysis(incident.ID, analysisID)
+	if err != nil {
+		h.logger.Error("failed to remove analysis from incident", zap.Int64("id", incident.ID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to remove analysis from incident"})
+		return
+	}
+	if !removed {
+		c.JSON(http.StatusNotFound, gin.H{"error": "analysis is not in the incident"})
+		return
+	}
+	h.logger.Info("analysis removed from incident", zap.Int64("id", incident.ID), zap.Int64("analysis_id", analysisID), zap.String("actor", h.actor(c)))
+
+	h.respondIncident(c, http.StatusOK, incident.ID)
+}
+
+// MergeIncidents moves the analyses of other incidents, found to be the
+// same outage, into this one and deletes them
+func (h *Handler) MergeIncidents(c *gin.Context) {
+	incident, ok := h.loadIncident(c)
+	if !ok {
+		return
+	}
+
+	var req MergeIncidentsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	for _, id := range req.IncidentIDs {
+		if id == incident.ID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "an incident cannot be merged into itself"})
+			return
+		}
+		other, err := h.db.GetIncident(id)
+		visible := false
+		if err == nil && other != nil {
+			visible, err = h.incidentVisible(c, id)
+		}
+		if err != nil {
+			h.logger.Error("failed to get incident", zap.Int64("id", id), zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load incident"})
+			return
+		}
+		if !visible {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("incident %d not found", id)})
+			return
+		}
+	}
+
+	n, err := h.db.MergeIncidents(incident.ID, req.IncidentIDs)
+	if err != nil {
+		h.logger.Error("failed to merge incidents", zap.Int64("id", incident.ID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to merge incidents"})
+		return
+	}
+	h.logger.Info("incidents merged",
+		zap.Int64("id", incident.ID),
+		zap.Int64s("merged", req.IncidentIDs),
+		zap.Int("count", n),
+		zap.String("actor", h.actor(c)))
+
+	h.respondIncident(c, http.StatusOK, incident.ID)
+}
+
+// DeleteIncident deletes an incident, leaving its analyses ungrouped
+func (h *Handler) DeleteIncident(c *gin.Context) {
+	incident, ok := h.loadIncident(c)
+	if !ok {
+		return
+	}
+
+	deleted, err := h.db.DeleteIncident(incident.ID)
+	if err != nil {
+		h.logger.Error("failed to delete incident", zap.Int64("id", incident.ID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete incident"})
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "incident not found"})
+		return
+	}
+
+	h.logger.Info("incident deleted", zap.Int64("id", incident.ID), zap.String("actor", h.actor(c)))
+	c.Status(http.StatusNoContent)
+}
+
+// loadIncidentGroup returns an incident with the analyses the caller may
+// see, or nil if it does not exist or the caller may see none of them
+func (h *Handler) loadIncidentGroup(c *gin.Context, id int64) (*incidentGroup, error) {
+	incident, err := h.db.GetIncident(id)
+	if err != nil || incident == nil {
+		return nil, err
+	}
+	filter := database.AnalysisFilter{IncidentID: id, NamespacePatterns: namespacePatterns(c), Limit: maxIncidentAnalyses}
+	analyses, err := h.db.FindAnalyses(filter)
+	if err != nil {
+		return nil, err
+	}
+	if filter.NamespacePatterns != nil && len(analyses) == 0 {
+		return nil, nil
+	}
+	group := newIncidentGroup(*incident, analyses)
+	return &group, nil
+}
+
+// respondIncident answers with an incident and its analyses
+func (h *Handler) respondIncident(c *gin.Context, status int, id int64) {
+	group, err := h.loadIncidentGroup(c, id)
+	if err != nil {
+		h.logger.Error("failed to load incident", zap.Int64("id", id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load incident"})
+		return
+	}
+	if group == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "incident not found"})
+		return
+	}
+
+	response := newIncidentResponse(group.Incident)
+	response.Severity = group.Severity
+	response.Namespaces = group.Namespaces
+	response.Embedded = &struct {
+		Analyses []analysisSummary `json:"analyses"`
+	}{Analyses: make([]analysisSummary, 0, len(group.Analyses))}
+	for _, a := range group.Analyses {
+		response.Embedded.Analyses = append(response.Embedded.Analyses, newAnalysisSummary(a))
+	}
+	c.JSON(status, response)
+}
+
+// IncidentsPage displays incidents with their analyses grouped under them,
+// most recently updated first
+func (h *Handler) IncidentsPage(c *gin.Context) {
+	filter, page, ok := incidentFilter(c)
+	if !ok {
+		c.String(http.StatusBadRequest, "Invalid status or page")
+		return
+	}
+
+	incidents, err := h.db.ListIncidents(filter)
+	if err != nil {
+		h.logger.Error("failed to list incidents", zap.Error(err))
+		c.String(http.StatusInternalServerError, "Failed to load incidents")
+		return
+	}
+	patterns := namespacePatterns(c)
+	groups := make([]incidentGroup, 0, len(incidents))
+	for _, incident := range incidents {
+		analyses, err := h.db.FindAnalyses(database.AnalysisFilter{IncidentID: incident.ID, NamespacePatterns: patterns, Limit: maxIncidentAnalyses})
+		if err != nil {
+			h.logger.Error("failed to list incident analyses", zap.Int64("id", incident.ID), zap.Error(err))
+			c.String(http.StatusInternalServerError, "Failed to load incidents")
+			return
+		}
+		// Incidents with none of a limited caller's analyses are hidden
+		if patterns != nil && len(analyses) == 0 {
+			continue
+		}
+		groups = append(groups, newIncidentGroup(incident, analyses))
+	}
+
+	h.renderIncidents(c, gin.H{
+		"Incidents": groups,
+		"Status":    filter.Status,
+		"Page":      page,
+		"HasNext":   len(incidents) == filter.Limit,
+	})
+}
+
+// IncidentPage displays one incident with its analyses
+func (h *Handler) IncidentPage(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.String(http.StatusBadRequest, "Invalid incident ID")
+		return
+	}
+
+	group, err := h.loadIncidentGroup(c, id)
+	if err != nil {
+		h.logger.Error("failed to load incident", zap.Int64("id", id), zap.Error(err))
+		c.String(http.StatusInternalServerError, "Failed to load incident")
+		return
+	}
+	if group == nil {
+		c.String(http.StatusNotFound, "Incident not found")
+		return
+	}
+
+	h.renderIncidents(c, gin.H{
+		"Incidents": []incidentGroup{*group},
+		"Single":    true,
+		"Page":      1,
+	})
+}
+
+func (h *Handler) renderIncidents(c *gin.Context, data gin.H) {
+	if err := h.tmpl.ExecuteTemplate(c.Writer, "incidents.html", data); err != nil {
+		h.logger.Error("failed to render template", zap.Error(err))
+		c.String(http.StatusInternalServerError, "Failed to render page")
+	}
+}
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+
+	"github.com/emirozbir/micro-sre/internal/database"
+	"github.com/emirozbir/micro-sre/internal/metrics"
+	"github.com/emirozbir/micro-sre/internal/models"
+	"github.com/emirozbir/micro-sre/internal/pushgateway"
+	"github.com/emirozbir/micro-sre/internal/requestid"
+	"github.com/emirozbir/micro-sre/internal/tracing"
+)
+
+// startAnalysis records an analysis of alert as running before its result
+// is known, so that it shows even if it fails. A failed write is logged and
+// yields ID 0, for which saveAnalysis stores a new analysis instead.
+func (h *Handler) startAnalysis(ctx context.Context, alert models.AlertSummary) int64 {
+	id, err := h.db.StartAnalysis(alert, database.AnalysisRunning)
+	if err != nil {
+		requestid.Logger(ctx, h.logger).Warn("failed to record analysis start", zap.Error(err))
+		return 0
+	}
+	return id
+}
+
+// failAnalysis records why the analysis started as id failed
+func (h *Handler) failAnalysis(ctx context.Context, id int64, analysisErr error) {
+	if id == 0 {
+		return
+	}
+	if _, err := h.db.SetAnalysisStatus(id, database.AnalysisFailed, analysisErr.Error()); err != nil {
+		requestid.Logger(ctx, h.logger).Warn("failed to record analysis failure", zap.Int64("analysis_id", id), zap.Error(err))
+	}
+}
+
+// saveAnalysis stores the result of the analysis started as runID, or a new
+// analysis if runID is 0, and pushes its incident record. A failed save is
+// logged and yields ID 0 rather than failing the analysis.
+func (h *Handler) saveAnalysis(ctx context.Context, runID int64, result *models.AnalysisResult) int64 {
+	_, span := tracing.Start(ctx, "save analysis")
+	result.RequestID = requestid.From(ctx)
+	var (
+		id  int64
+		err error
+	)
+	if runID != 0 {
+		id, err = h.db.CompleteAnalysis(runID, result)
+	} else {
+		id, err = h.db.SaveAnalysis(result)
+	}
+	span.SetAttributes(attribute.Int64("hepsre.analysis_id", id))
+	tracing.End(span, err)
+	if err != nil {
+		requestid.Logger(ctx, h.logger).Error("failed to save analysis to database", zap.Error(err))
+		return 0
+	}
+	metrics.Analyses.WithLabelValues(result.Analysis.Confidence).Inc()
+	metrics.ConfidenceScore.Observe(float64(result.Analysis.ConfidenceScore))
+	h.publishAnalysis(id, result)
+	if h.pusher != nil {
+		go h.pushIncident(result)
+	}
+	h.proposeRemediations(id, result)
+	return id
+}
+
+// saveAnalysisVersion stores result as the next version of analysis id
+func (h *Handler) saveAnalysisVersion(ctx context.Context, id int64, result *models.AnalysisResult) (int, error) {
+	_, span := tracing.Start(ctx, "save analysis version", trace.WithAttributes(attribute.Int64("hepsre.analysis_id", id)))
+	result.RequestID = requestid.From(ctx)
+	version, err := h.db.SaveAnalysisVersion(id, result)
+	span.SetAttributes(attribute.Int("hepsre.version", version))
+	tracing.End(span, err)
+	return version, err
+}
+
+// pushIncident pushes the incident record of a stored analysis. The
+// category is the alert name, as in the daily stats.
+func (h *Handler) pushIncident(result *models.AnalysisResult) {
+	alert := result.Alert
+	stored, err := h.db.CountIncidents(alert.Namespace, alert.Name, alert.Severity)
+	if err != nil {
+		h.logger.Warn("failed to count incidents for export", zap.Error(err))
+		return
+	}
+
+	timestamp := alert.StartedAt
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err = h.pusher.Push(ctx, pushgateway.Incident{
+		Namespace: alert.Namespace,
+		Category:  alert.Name,
+		Severity:  alert.Severity,
+		Timestamp: timestamp,
+		Stored:    stored,
+	})
+	if err != nil {
+		h.logger.Warn("failed to push incident record", zap.String("namespace", alert.Namespace), zap.Error(err))
+	}
+}
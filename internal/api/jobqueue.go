@@ -0,0 +1,272 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/emirozbir/micro-sre/internal/collectors"
+	"github.com/emirozbir/micro-sre/internal/config"
+	"github.com/emirozbir/micro-sre/internal/database"
+	"github.com/emirozbir/micro-sre/internal/models"
+)
+
+// jobPollInterval is how often stored jobs that are due are queued
+const jobPollInterval = 15 * time.Second
+
+// jobResumeGrace is how long a due stored job is left to the server that
+// queued it before another one takes it
+const jobResumeGrace = time.Minute
+
+// jobStaleAfter is how long a stored job may run before it is taken for
+// lost, as when the server running it stopped: well past jobTimeout
+const jobStaleAfter = 2 * jobTimeout
+
+// jobResumeBatch caps the stored jobs queued per poll
+const jobResumeBatch = 50
+
+// maxJobRetryBackoff caps the delay between attempts of a stored job
+const maxJobRetryBackoff = time.Hour
+
+// webhookJob is the input of a webhook job, stored with it so that any
+// server can run it: the alerts, and the Opsgenie alert or PagerDuty
+// incident to annotate with their analyses
+type webhookJob struct {
+	Webhook             models.AlertManagerWebhook `json:"webhook"`
+	OpsgenieAlertID     string                     `json:"opsgenie_alert_id,omitempty"`
+	PagerDutyIncidentID string                     `json:"pagerduty_incident_id,omitempty"`
+}
+
+// webhookTask analyzes the alerts of a webhook job and annotates the alert
+// or incident they came from
+func (h *Handler) webhookTask(w webhookJob) jobTask {
+	return func(ctx context.Context) (any, int64, error) {
+		response := h.processWebhook(ctx, w.Webhook)
+		if w.OpsgenieAlertID != "" && h.agent().Config().Opsgenie.PostNotes {
+			h.annotateOpsgenieAlert(ctx, w.OpsgenieAlertID, response)
+		}
+		if w.PagerDutyIncidentID != "" && h.agent().Config().PagerDuty.PostNotes {
+			h.postPagerDutyNote(ctx, w.PagerDutyIncidentID, response)
+		}
+		return response, 0, nil
+	}
+}
+
+// queueWebhook queues a webhook job raised in the background, or schedules
+// it for runAt if that is later. A stored job the queue has no room for is
+// queued by pollJobs later; otherwise it returns false if the queue is full
+// or the server is shutting down.
+func (h *Handler) queueWebhook(kind string, w webhookJob, runAt time.Time) bool {
+	job := &analysisJob{
+		ID:        newJobID(),
+		Kind:      kind,
+		Status:    jobQueued,
+		Stage:     "Queued",
+		Target:    w.Webhook.Receiver,
+		CreatedAt: time.Now(),
+		task:      h.webhookTask(w),
+		webhook:   &w,
+	}
+	h.storeJob(job, runAt)
+
+	var queued bool
+	if runAt.After(job.CreatedAt) {
+		job.Status = jobScheduled
+		job.Stage = "Scheduled for " + runAt.Format(time.RFC3339)
+		job.NextRunAt = &runAt
+		queued = h.analysisJobs.schedule(job)
+	} else {
+		queued = h.analysisJobs.enqueue(job)
+	}
+	return queued || job.stored
+}
+
+// storeJob keeps a webhook job in the jobs table, due at runAt, so that it
+// survives a restart. A failed write is logged and the job only kept in
+// memory.
+func (h *Handler) storeJob(job *analysisJob, runAt time.Time) {
+	payload, err := json.Marshal(job.webhook)
+	if err == nil {
+		err = h.db.SaveJob(database.QueuedJob{
+			ID:        job.ID,
+			Kind:      job.Kind,
+			Target:    job.Target,
+			Namespace: job.Namespace,
+			RequestID: job.RequestID,
+			Payload:   string(payload),
+			CreatedAt: job.CreatedAt,
+			NextRunAt: runAt,
+		})
+	}
+	if err != nil {
+		h.logger.Warn("failed to store job, it will not survive a restart", zap.String("job", job.ID), zap.Error(err))
+		return
+	}
+	job.stored = true
+}
+
+// unstoreJob removes a job that was not queued from the jobs table
+func (h *Handler) unstoreJob(job *analysisJob) {
+	if !job.stored {
+		return
+	}
+	if err := h.db.DeleteJob(job.ID); err != nil {
+		h.logger.Warn("failed to delete stored job", zap.String("job", job.ID), zap.Error(err))
+	}
+	job.stored = false
+}
+
+// claimJob marks a stored job as running before a worker starts it and
+// returns the attempt starting. It returns false, finishing the job, if
+// another server has it. A job that cannot be claimed for a database error
+// runs anyway, as attempt 0, which is not retried.
+func (h *Handler) claimJob(job *analysisJob) (int, bool) {
+	attempt, err := h.db.ClaimJob(job.ID, time.Now().Add(-jobStaleAfter))
+	if err != nil {
+		h.logger.Warn("failed to claim stored job", zap.String("job", job.ID), zap.Error(err))
+		return 0, true
+	}
+	if attempt > 0 {
+		return attempt, true
+	}
+
+	h.logger.Info("stored job is run by another server", zap.String("job", job.ID))
+	finished := time.Now()
+	h.analysisJobs.mu.Lock()
+	defer h.analysisJobs.mu.Unlock()
+	job.Status = jobFailed
+	job.Stage = ""
+	job.Error = "the job is run by another server"
+	job.FinishedAt = &finished
+	h.analysisJobs.changed(job)
+	h.analysisJobs.settle()
+	return 0, false
+}
+
+// retryJob settles a stored job after an attempt. While alerts fail for
+// reasons that may pass and attempts remain, the job is scheduled again
+// and retryJob reports true; otherwise it is deleted, unless the shutdown
+// cut it short, which leaves it to the next start.
+func (h *Handler) retryJob(job *analysisJob, attempt int, result any) bool {
+	if h.jobsCtx.Err() != nil {
+		if err := h.db.RetryJob(job.ID, time.Now(), "interrupted by shutdown"); err != nil {
+			h.logger.Warn("failed to requeue interrupted job", zap.String("job", job.ID), zap.Error(err))
+		}
+		return false
+	}
+
+	cfg := h.agent().Config().Agent.Jobs
+	failed := retryableFailures(result)
+	if failed > 0 && attempt > 0 && attempt < cfg.MaxAttempts {
+		delay := failureBackoff(config.FailureBackoffConfig{Initial: cfg.RetryBackoff, Max: maxJobRetryBackoff}, attempt)
+		next := time.Now().Add(delay)
+		err := h.db.RetryJob(job.ID, next, fmt.Sprintf("%d alerts failed", failed))
+		if err == nil {
+			h.logger.Info("retrying failed alerts of job",
+				zap.String("job", job.ID),
+				zap.Int("failed", failed),
+				zap.Int("attempt", attempt),
+				zap.Time("next_attempt", next))
+
+			h.analysisJobs.mu.Lock()
+			defer h.analysisJobs.mu.Unlock()
+			job.Status = jobScheduled
+			job.Stage = fmt.Sprintf("Retrying %d failed alerts at %s (attempt %d of %d)", failed, next.Format(time.RFC3339), attempt+1, cfg.MaxAttempts)
+			job.NextRunAt = &next
+			h.analysisJobs.changed(job)
+			h.analysisJobs.settle()
+			return true
+		}
+		h.logger.Warn("failed to schedule job retry", zap.String("job", job.ID), zap.Error(err))
+	}
+
+	if err := h.db.DeleteJob(job.ID); err != nil {
+		h.logger.Warn("failed to delete finished job", zap.String("job", job.ID), zap.Error(err))
+	}
+	return false
+}
+
+// retryableFailures counts the alerts of a webhook job result whose
+// analysis failed for a reason that may pass: a timeout, a refused
+// connection or an unclassified error. Alerts refused by the token budget
+// are deferred on their own.
+func retryableFailures(result any) int {
+	response, ok := result.(models.WebhookAnalysisResponse)
+	if !ok {
+		return 0
+	}
+	n := 0
+	for _, e := range response.Errors {
+		switch e.ErrorClass {
+		case collectors.ErrorTimeout, collectors.ErrorConnectionRefused, collectors.ErrorUnknown:
+			n++
+		}
+	}
+	return n
+}
+
+// pollJobs queues the stored jobs that are due, every jobPollInterval
+// until the server shuts down: the scheduled jobs of this server, and
+// those left by a server that stopped or had no room for them
+func (h *Handler) pollJobs() {
+	ticker := time.NewTicker(jobPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-h.stopping:
+			return
+		case now := <-ticker.C:
+			h.resumeJobs(now)
+		}
+	}
+}
+
+// resumeJobs queues the stored jobs due by now, until the queue is full
+func (h *Handler) resumeJobs(now time.Time) {
+	for _, job := range h.analysisJobs.due(now) {
+		if !h.analysisJobs.resume(job) {
+			return
+		}
+	}
+
+	stored, err := h.db.DueJobs(now.Add(-jobResumeGrace), now.Add(-jobStaleAfter), jobResumeBatch)
+	if err != nil {
+		h.logger.Warn("failed to list stored jobs", zap.Error(err))
+		return
+	}
+	for _, s := range stored {
+		known, finished := h.analysisJobs.finished(s.ID)
+		if known && !finished {
+			continue
+		}
+
+		var w webhookJob
+		if err := json.Unmarshal([]byte(s.Payload), &w); err != nil || finished {
+			// Not runnable, or left behind by a failed delete
+			if err := h.db.DeleteJob(s.ID); err != nil {
+				h.logger.Warn("failed to delete stored job", zap.String("job", s.ID), zap.Error(err))
+			}
+			continue
+		}
+
+		job := &analysisJob{
+			ID:        s.ID,
+			Kind:      s.Kind,
+			Namespace: s.Namespace,
+			Target:    s.Target,
+			CreatedAt: s.CreatedAt,
+			RequestID: s.RequestID,
+			Attempts:  s.Attempts,
+			task:      h.webhookTask(w),
+			webhook:   &w,
+			stored:    true,
+		}
+		if !h.analysisJobs.resume(job) {
+			return
+		}
+		h.logger.Info("resumed stored job", zap.String("job", job.ID), zap.String("kind", job.Kind), zap.Int("attempts", s.Attempts))
+	}
+}
@@ -0,0 +1,151 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// jobEventBuffer is how many events a slow stream may fall behind by before
+// further events are dropped for it
+const jobEventBuffer = 64
+
+// streamKeepalive is how often an idle stream sends a comment, so proxies
+// don't close it
+const streamKeepalive = 15 * time.Second
+
+// jobEvent is a server-sent event of an analysis job: "status" when the job
+// is started, deferred or queued again, "stage" as the agent moves to the
+// next stage and "token" as the LLM response streams in
+type jobEvent struct {
+	name string
+	data any
+}
+
+// subscribe returns the job and a channel of its events, closed once the
+// job finishes. Finished jobs have no channel.
+func (j *analysisJobs) subscribe(id string) (analysisJob, chan jobEvent, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	job, ok := j.jobs[id]
+	if !ok {
+		return analysisJob{}, nil, false
+	}
+	if job.FinishedAt != nil {
+		return *job, nil, true
+	}
+	ch := make(chan jobEvent, jobEventBuffer)
+	j.subscribers[id] = append(j.subscribers[id], ch)
+	return *job, ch, true
+}
+
+// unsubscribe stops sending events of job id to ch
+func (j *analysisJobs) unsubscribe(id string, ch chan jobEvent) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	subs := j.subscribers[id]
+	for i, sub := range subs {
+		if sub == ch {
+			j.subscribers[id] = append(subs[:i], subs[i+1:]...)
+			break
+		}
+	}
+	if len(j.subscribers[id]) == 0 {
+		delete(j.subscribers, id)
+	}
+}
+
+// publish sends an event of job id to its subscribers, dropping it for
+// those whose buffer is full; j.mu must be held
+func (j *analysisJobs) publish(id string, event jobEvent) {
+	for _, ch := range j.subscribers[id] {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// changed publishes the new status of a job, or ends its streams once it
+// has finished; j.mu must be held
+func (j *analysisJobs) changed(job *analysisJob) {
+	if job.FinishedAt == nil {
+		// The event is encoded after j.mu is released
+		snapshot := *job
+		j.publish(job.ID, jobEvent{name: "status", data: analysisJobBody(&snapshot)})
+		return
+	}
+	for _, ch := range j.subscribers[job.ID] {
+		close(ch)
+	}
+	delete(j.subscribers, job.ID)
+}
+
+// analysisJobBody is the job as returned by GetAnalysisJob
+func analysisJobBody(job *analysisJob) gin.H {
+	return gin.H{
+		"job":    job,
+		"_links": analysisJobLinks(job),
+	}
+}
+
+// StreamAnalysisJob streams the progress of an analysis job as server-sent
+// events: the job's current "status", then its "status", "stage" and
+// "token" events as they happen, and finally "done" with the finished job
+// and its result, like GetAnalysisJob returns it
+func (h *Handler) StreamAnalysisJob(c *gin.Context) {
+	id := c.Param("job")
+	job, events, ok := h.analysisJobs.subscribe(id)
+	if ok && !jobVisible(c, &job) {
+		if events != nil {
+			h.analysisJobs.unsubscribe(id, events)
+		}
+		ok = false
+	}
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "job not found"})
+		return
+	}
+
+	// Keep reverse proxies from buffering the stream
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+
+	if events == nil {
+		c.SSEvent("done", analysisJobBody(&job))
+		return
+	}
+	defer h.analysisJobs.unsubscribe(id, events)
+
+	c.SSEvent("status", analysisJobBody(&job))
+	c.Writer.Flush()
+
+	keepalive := time.NewTicker(streamKeepalive)
+	defer keepalive.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case event, open := <-events:
+			if !open {
+				job, _ := h.analysisJobs.snapshot(id)
+				c.SSEvent("done", analysisJobBody(&job))
+				return false
+			}
+			c.SSEvent(event.name, event.data)
+			return true
+		case <-keepalive.C:
+			fmt.Fprint(w, ": keepalive\n\n")
+			return true
+		case <-c.Request.Context().Done():
+			return false
+		case <-h.stopping:
+			// EventSource clients reconnect, to another replica if need be
+			return false
+		}
+	})
+}
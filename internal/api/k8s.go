@@ -0,0 +1,160 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/emirozbir/micro-sre/internal/collectors"
+)
+
+// maxCompletions caps the number of names returned by the autocomplete
+// endpoints
+const maxCompletions = 200
+
+// viewer returns the identity set by the authenticating proxy, if one is
+// configured. It reports false when the proxy header is required but
+// missing, or the request did not come through a trusted proxy.
+func (h *Handler) viewer(c *gin.Context) (collectors.Viewer, bool) {
+	cfg := h.agent().Config().Server
+	viewer := collectors.Viewer{}
+	if cfg.UserHeader == "" {
+		return viewer, true
+	}
+	// Any other client could name whichever user it likes
+	if !cfg.TrustsProxy(c.RemoteIP()) {
+		return viewer, false
+	}
+
+	viewer.User = c.GetHeader(cfg.UserHeader)
+	if viewer.User == "" {
+		return viewer, false
+	}
+	if cfg.GroupsHeader != "" {
+		for _, g := range strings.Split(c.GetHeader(cfg.GroupsHeader), ",") {
+			if g = strings.TrimSpace(g); g != "" {
+				viewer.Groups = append(viewer.Groups, g)
+			}
+		}
+	}
+	return viewer, true
+}
+
+// requireViewer answers 401 and reports false when the proxy identity a
+// configured server.user_header requires is missing
+func (h *Handler) requireViewer(c *gin.Context) bool {
+	if _, ok := h.viewer(c); !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthenticated"})
+		return false
+	}
+	return true
+}
+
+// ListNamespaces returns the allowed namespaces the viewer may list pods in,
+// and an OIDC caller may analyze, optionally filtered by a name prefix
+func (h *Handler) ListNamespaces(c *gin.Context) {
+	viewer, ok := h.viewer(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthenticated"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	k8s := h.agent().Kubernetes()
+	namespaces, err := k8s.ListNamespaces(ctx)
+	if err != nil {
+		h.logger.Error("failed to list namespaces", zap.Error(err))
+		c.JSON(http.StatusBadGateway, gin.H{"error": "failed to list namespaces"})
+		return
+	}
+
+	prefix := c.Query("prefix")
+	out := make([]string, 0, len(namespaces))
+	for _, ns := range namespaces {
+		if len(out) == maxCompletions {
+			break
+		}
+		if !strings.HasPrefix(ns, prefix) || !callerAllows(c, ns) {
+			continue
+		}
+		allowed, err := k8s.CanListPods(ctx, viewer, ns)
+		if err != nil {
+			h.logger.Error("failed to authorize namespace", zap.String("namespace", ns), zap.Error(err))
+			c.JSON(http.StatusBadGateway, gin.H{"error": "authorization check failed"})
+			return
+		}
+		if allowed {
+			out = append(out, ns)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"count":      len(out),
+		"namespaces": out,
+	})
+}
+
+// ListPods returns the pods of a namespace with their phase, optionally
+// filtered by a name prefix
+func (h *Handler) ListPods(c *gin.Context) {
+	namespace := c.Query("namespace")
+	if namespace == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "namespace is required"})
+		return
+	}
+
+	viewer, ok := h.viewer(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthenticated"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	k8s := h.agent().Kubernetes()
+	if !k8s.NamespaceAllowed(namespace) {
+		c.JSON(http.StatusForbidden, gin.H{"error": collectors.ErrNamespaceNotAllowed.Error()})
+		return
+	}
+	if !allowNamespace(c, namespace) {
+		return
+	}
+	allowed, err := k8s.CanListPods(ctx, viewer, namespace)
+	if err != nil {
+		h.logger.Error("failed to authorize pod list", zap.String("namespace", namespace), zap.Error(err))
+		c.JSON(http.StatusBadGateway, gin.H{"error": "authorization check failed"})
+		return
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		return
+	}
+
+	pods, err := k8s.ListPods(ctx, namespace)
+	if err != nil {
+		h.logger.Error("failed to list pods", zap.String("namespace", namespace), zap.Error(err))
+		c.JSON(http.StatusBadGateway, gin.H{"error": "failed to list pods"})
+		return
+	}
+
+	prefix := c.Query("prefix")
+	out := make([]gin.H, 0, len(pods))
+	for _, pod := range pods {
+		if len(out) == maxCompletions {
+			break
+		}
+		if strings.HasPrefix(pod.Name, prefix) {
+			out = append(out, gin.H{
+				"name":  pod.Name,
+				"phase": pod.Status.Phase,
+			})
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"namespace": namespace,
+		"count":     len(out),
+		"pods":      out,
+	})
+}
@@ -0,0 +1,193 @@
+package api
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/emirozbir/micro-sre/internal/database"
+	"github.com/emirozbir/micro-sre/internal/models"
+)
+
+// Route templates shared by SetupRoutes and the link builders, so that
+// clients follow "_links" instead of hardcoding paths.
+const (
+	routeAnalyses             = "/api/v1/analyses"
+	routeAnalysis             = "/api/v1/analyses/:id"
+	routeAnalysesLive         = "/api/v1/analyses/live"
+	routeAnalysisSimilar      = "/api/v1/analyses/:id/similar"
+	routeAnalysisVersions     = "/api/v1/analyses/:id/versions"
+	routeAnalysisFeedback     = "/api/v1/analyses/:id/feedback"
+	routeAnalysisRating       = "/api/v1/analyses/:id/rating"
+	routeAnalysisRerun        = "/api/v1/analyses/:id/rerun"
+	routeAnalysisExport       = "/api/v1/analyses/:id/export"
+	routeAnalysisTags         = "/api/v1/analyses/:id/tags"
+	routeAnalysisTag          = "/api/v1/analyses/:id/tags/:tag"
+	routeTags                 = "/api/v1/tags"
+	routeIncidents            = "/api/v1/incidents"
+	routeIncident             = "/api/v1/incidents/:id"
+	routeIncidentAnalyses     = "/api/v1/incidents/:id/analyses"
+	routeIncidentAnalysis     = "/api/v1/incidents/:id/analyses/:analysis_id"
+	routeIncidentMerge        = "/api/v1/incidents/:id/merge"
+	routeIncidentsPage        = "/incidents"
+	routeIncidentPage         = "/incidents/:id"
+	routeFeedback             = "/api/v1/feedback"
+	routeRecommendationDryRun = "/api/v1/analyses/:id/recommendations/:index/dry-run"
+	routeRemediations         = "/api/v1/remediations"
+	routeRemediation          = "/api/v1/remediations/:id"
+	routeRemediationApprove   = "/api/v1/remediations/:id/approve"
+	routeRemediationReject    = "/api/v1/remediations/:id/reject"
+	routeSlackInteractions    = "/api/v1/slack/interactions"
+	routeSlackCommand         = "/api/v1/slack/command"
+	routeReanalysisJobs       = "/api/v1/admin/reanalyze"
+	routeReanalysisJob        = "/api/v1/admin/reanalyze/:job"
+	routeChaos                = "/api/v1/admin/chaos"
+	routeBackup               = "/api/v1/admin/backup"
+	routeDeletedAnalyses      = "/api/v1/admin/analyses/deleted"
+	routeRestoreAnalyses      = "/api/v1/admin/analyses/restore"
+	routeAnalysisRestore      = "/api/v1/admin/analyses/:id/restore"
+	routeArtifact             = "/api/v1/analyses/:id/artifacts/:name"
+	routeAnalysisPage         = "/analyses/:id"
+	routeStatic               = "/static"
+	routeStaticFiles          = routeStatic + "/*filepath"
+	routeAnalysisJobs         = "/api/v1/analyze/jobs"
+	routeAnalysisJob          = "/api/v1/jobs/:job"
+	routeAnalysisJobStream    = "/api/v1/jobs/:job/stream"
+	routeNewAnalysisPage      = "/analyses/new"
+	routeAnalysisJobPage      = "/analyses/jobs/:job"
+	routePodLogTail           = "/api/v1/pods/:namespace/:pod/logs/tail"
+	routeK8sNamespaces        = "/api/v1/k8s/namespaces"
+	routeK8sPods              = "/api/v1/k8s/pods"
+	routeCollectorErrors      = "/api/v1/stats/collectors"
+	routeAnalysisStats        = "/api/v1/stats/analyses"
+	routePostmortem           = "/api/v1/reports/postmortem"
+	routeTokenBudget          = "/api/v1/stats/budget"
+	routeFeedbackStats        = "/api/v1/stats/feedback"
+	routeAudit                = "/api/v1/admin/audit"
+	routeReload               = "/api/v1/admin/reload"
+	routePagerDutyWebhook     = "/api/v1/webhook/pagerduty"
+)
+
+// analysisResponse is an analysis result decorated with its stored ID and
+// hypermedia links
+type analysisResponse struct {
+	ID int64 `json:"id,omitempty"`
+	// Status is the lifecycle state of a stored analysis, with the error of
+	// a failed one
+	Status string `json:"status,omitempty"`
+	Error  string `json:"error,omitempty"`
+	// Tags are the labels readers attached to a stored analysis, and
+	// IncidentID the incident grouping it
+	Tags       []string `json:"tags,omitempty"`
+	IncidentID int64    `json:"incident_id,omitempty"`
+	*models.AnalysisResult
+	Links models.Links `json:"_links,omitempty"`
+}
+
+// analysisSummary is the compact form used in embedded collections
+type analysisSummary struct {
+	ID              int64        `json:"id"`
+	CreatedAt       time.Time    `json:"created_at"`
+	AlertName       string       `json:"alert_name"`
+	Cluster         string       `json:"cluster,omitempty"`
+	Namespace       string       `json:"namespace"`
+	Pod             string       `json:"pod"`
+	Severity        string       `json:"severity"`
+	RootCause       string       `json:"root_cause"`
+	Confidence      string       `json:"confidence"`
+	ConfidenceScore int          `json:"confidence_score"`
+	Status          string       `json:"status"`
+	Error           string       `json:"error,omitempty"`
+	Tags            []string     `json:"tags,omitempty"`
+	Links           models.Links `json:"_links"`
+}
+
+func newAnalysisResponse(id int64, result *models.AnalysisResult) analysisResponse {
+	return analysisResponse{
+		ID:             id,
+		AnalysisResult: result,
+		Links:          analysisLinks(id, result),
+	}
+}
+
+func newAnalysisSummary(stored database.StoredAnalysis) analysisSummary {
+	return analysisSummary{
+		ID:              stored.ID,
+		CreatedAt:       stored.CreatedAt,
+		AlertName:       stored.AlertName,
+		Cluster:         stored.Cluster,
+		Namespace:       stored.Namespace,
+		Pod:             stored.PodName,
+		Severity:        stored.Severity,
+		RootCause:       stored.RootCause,
+		Confidence:      stored.Confidence,
+		ConfidenceScore: stored.ConfidenceScore,
+		Status:          stored.Status,
+		Error:           stored.Error,
+		Tags:            stored.Tags,
+		Links: models.Links{
+			"self": {Href: analysisPath(routeAnalysis, stored.ID)},
+			"html": {Href: analysisPath(routeAnalysisPage, stored.ID), Type: "text/html"},
+		},
+	}
+}
+
+// analysisLinks returns the links for a stored analysis. Analyses that
+// could not be saved have no ID and therefore no links.
+func analysisLinks(id int64, result *models.AnalysisResult) models.Links {
+	if id == 0 {
+		return nil
+	}
+
+	links := models.Links{
+		"self":     {Href: analysisPath(routeAnalysis, id)},
+		"html":     {Href: analysisPath(routeAnalysisPage, id), Type: "text/html"},
+		"similar":  {Href: analysisPath(routeAnalysisSimilar, id)},
+		"versions": {Href: analysisPath(routeAnalysisVersions, id)},
+		"feedback": {Href: analysisPath(routeAnalysisFeedback, id)},
+		"rating":   {Href: analysisPath(routeAnalysisRating, id)},
+		"tags":     {Href: analysisPath(routeAnalysisTags, id)},
+		"rerun":    {Href: analysisPath(routeAnalysisRerun, id)},
+		"export":   {Href: analysisPath(routeAnalysisExport, id) + "?format=markdown", Type: "text/markdown"},
+	}
+	if result != nil && len(result.Analysis.Recommendations) > 0 {
+		links["remediations"] = models.Link{Href: fmt.Sprintf("%s?analysis_id=%d", routeRemediations, id)}
+	}
+	if result != nil {
+		if result.Alert.Pod != "" {
+			links["live-logs"] = models.Link{
+				Href:  fillRoute(routePodLogTail, map[string]string{"namespace": result.Alert.Namespace, "pod": result.Alert.Pod}),
+				Title: "websocket",
+			}
+		}
+		for _, ref := range result.Artifacts {
+			links["artifact:"+ref.Name] = models.Link{
+				Href: fillRoute(routeArtifact, map[string]string{"id": fmt.Sprint(id), "name": ref.Name}),
+				Type: ref.ContentType,
+			}
+		}
+	}
+	return links
+}
+
+func analysisPath(route string, id int64) string {
+	return fillRoute(route, map[string]string{"id": fmt.Sprint(id)})
+}
+
+// fillRoute substitutes gin ":param" segments in a route template
+func fillRoute(route string, params map[string]string) string {
+	out := make([]byte, 0, len(route))
+	for i := 0; i < len(route); i++ {
+		if route[i] != ':' {
+			out = append(out, route[i])
+			continue
+		}
+		j := i + 1
+		for j < len(route) && route[j] != '/' {
+			j++
+		}
+		out = append(out, url.PathEscape(params[route[i+1:j]])...)
+		i = j - 1
+	}
+	return string(out)
+}
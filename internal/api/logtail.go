@@ -0,0 +1,144 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+
+	"github.com/emirozbir/micro-sre/internal/collectors"
+)
+
+const (
+	defaultTailLines = 100
+	maxTailLines     = 1000
+	tailWriteTimeout = 10 * time.Second
+)
+
+// The default origin check rejects cross-site pages, so a browser visiting
+// another site cannot open tails or feeds with the viewer's proxy
+// credentials
+var tailUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 4096,
+}
+
+// TailPodLogs proxies a live log tail of a pod over a websocket. Access is
+// checked against Kubernetes RBAC for the viewer before any line is sent;
+// refusals are reported in the close frame so browsers can display them.
+func (h *Handler) TailPodLogs(c *gin.Context) {
+	namespace, podName := c.Param("namespace"), c.Param("pod")
+	container := c.Query("container")
+	tailLines := int64(defaultTailLines)
+	if n, err := strconv.ParseInt(c.Query("tail"), 10, 64); err == nil && n > 0 && n <= maxTailLines {
+		tailLines = n
+	}
+
+	// Namespaces outside allowed_namespaces, or the OIDC caller's, are
+	// refused before the upgrade
+	if !h.agent().Kubernetes().NamespaceAllowed(namespace) {
+		c.JSON(http.StatusForbidden, gin.H{"error": collectors.ErrNamespaceNotAllowed.Error()})
+		return
+	}
+	if !allowNamespace(c, namespace) {
+		return
+	}
+
+	conn, err := tailUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// The upgrader has already written an HTTP error
+		return
+	}
+	defer conn.Close()
+
+	cfg := h.agent().Config().Server
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.MaxTailDuration)
+	defer cancel()
+
+	// Hijacked connections are not closed by the server's shutdown
+	go func() {
+		select {
+		case <-h.stopping:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	// A hijacked connection is not tied to the request context, so watch
+	// for the client going away ourselves
+	go func() {
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				cancel()
+				return
+			}
+		}
+	}()
+
+	viewer, ok := h.viewer(c)
+	if !ok {
+		closeTail(conn, websocket.ClosePolicyViolation, "unauthenticated")
+		return
+	}
+
+	k8s := h.agent().Kubernetes()
+	allowed, reason, err := k8s.CanTailPodLogs(ctx, viewer, namespace, podName)
+	if err != nil {
+		h.logger.Error("failed to authorize log tail", zap.String("namespace", namespace), zap.String("pod", podName), zap.Error(err))
+		closeTail(conn, websocket.CloseInternalServerErr, "authorization check failed")
+		return
+	}
+	if !allowed {
+		h.logger.Warn("log tail denied",
+			zap.String("user", viewer.User),
+			zap.String("namespace", namespace),
+			zap.String("pod", podName),
+			zap.String("reason", reason))
+		closeTail(conn, websocket.ClosePolicyViolation, "forbidden")
+		return
+	}
+
+	stream, err := k8s.StreamPodLogs(ctx, namespace, podName, container, tailLines)
+	if err != nil {
+		h.logger.Warn("failed to open log tail", zap.String("namespace", namespace), zap.String("pod", podName), zap.Error(err))
+		closeTail(conn, websocket.CloseInternalServerErr, "failed to open log stream")
+		return
+	}
+	defer stream.Close()
+
+	h.logger.Info("log tail started",
+		zap.String("user", viewer.User),
+		zap.String("namespace", namespace),
+		zap.String("pod", podName))
+
+	scanner := bufio.NewScanner(stream)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		conn.SetWriteDeadline(time.Now().Add(tailWriteTimeout))
+		if err := conn.WriteMessage(websocket.TextMessage, scanner.Bytes()); err != nil {
+			return
+		}
+	}
+
+	select {
+	case <-h.stopping:
+		closeTail(conn, websocket.CloseGoingAway, "server is shutting down")
+		return
+	default:
+	}
+	reason = "log stream ended"
+	if ctx.Err() == context.DeadlineExceeded {
+		reason = "maximum tail duration reached"
+	}
+	closeTail(conn, websocket.CloseNormalClosure, reason)
+}
+
+func closeTail(conn *websocket.Conn, code int, reason string) {
+	msg := websocket.FormatCloseMessage(code, reason)
+	_ = conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second))
+}
@@ -0,0 +1,30 @@
+package api
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/emirozbir/micro-sre/internal/metrics"
+)
+
+// routeMetrics serves the Prometheus metrics; like the probes, it is open
+// without an API key
+const routeMetrics = "/metrics"
+
+// instrument counts and times every request by its route template, so
+// analysis IDs do not become label values. Requests matching no route are
+// counted under "unmatched".
+func instrument(c *gin.Context) {
+	started := time.Now()
+	c.Next()
+
+	route := c.FullPath()
+	if route == "" {
+		route = "unmatched"
+	}
+	method := c.Request.Method
+	metrics.HTTPRequests.WithLabelValues(method, route, strconv.Itoa(c.Writer.Status())).Inc()
+	metrics.HTTPDuration.WithLabelValues(method, route).Observe(time.Since(started).Seconds())
+}
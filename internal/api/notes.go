@@ -0,0 +1,39 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/emirozbir/micro-sre/internal/models"
+)
+
+// analysisNote renders a webhook response as a plain text note for
+// incident tools, linking each analysis from dashboardURL when it is set.
+// PagerDuty and Opsgenie limit notes to 25,000 characters, which analyses
+// stay well under.
+func analysisNote(response models.WebhookAnalysisResponse, dashboardURL string) string {
+	var sb strings.Builder
+	for _, result := range response.Results {
+		if result.Analysis == nil {
+			continue
+		}
+		fmt.Fprintf(&sb, "hepsre analysis of %s/%s (confidence: %s)\n\nRoot cause: %s\n",
+			result.Namespace, result.Pod, result.Analysis.Confidence, result.Analysis.RootCause)
+		if len(result.Analysis.Recommendations) > 0 {
+			sb.WriteString("\nRecommendations:\n")
+			for _, rec := range result.Analysis.Recommendations {
+				fmt.Fprintf(&sb, "- [%s] %s\n", rec.Priority, rec.Action)
+				if rec.Command != "" {
+					fmt.Fprintf(&sb, "  $ %s\n", rec.Command)
+				}
+			}
+		}
+		if dashboardURL != "" && result.ID != 0 {
+			fmt.Fprintf(&sb, "\n%s%s\n", strings.TrimSuffix(dashboardURL, "/"), analysisPath(routeAnalysisPage, result.ID))
+		}
+	}
+	for _, failure := range response.Errors {
+		fmt.Fprintf(&sb, "hepsre could not analyze %s: %s\n", failure.AlertName, failure.Error)
+	}
+	return sb.String()
+}
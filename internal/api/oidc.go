@@ -0,0 +1,166 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/gin-gonic/gin"
+
+	"github.com/emirozbir/micro-sre/internal/config"
+)
+
+// identityContextKey is where authenticate stores the identity of a
+// request authenticated with an OIDC token
+const identityContextKey = "oidc_identity"
+
+// tokenIdentity is the caller named by a verified OIDC token
+type tokenIdentity struct {
+	User   string
+	Groups []string
+	// Namespaces are the glob patterns of the namespaces the caller may
+	// analyze
+	Namespaces []string
+}
+
+// allows reports whether the caller may analyze namespace
+func (i tokenIdentity) allows(namespace string) bool {
+	for _, pattern := range i.Namespaces {
+		if ok, _ := path.Match(pattern, namespace); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// tokenVerifier verifies OIDC bearer tokens. The issuer's discovery
+// document is fetched on first use, and again after a failed fetch, so the
+// server starts while the issuer is unreachable.
+type tokenVerifier struct {
+	cfg config.OIDCConfig
+
+	mu       sync.Mutex
+	verifier *oidc.IDTokenVerifier
+}
+
+func newTokenVerifier(cfg config.OIDCConfig) *tokenVerifier {
+	if !cfg.Enabled() {
+		return nil
+	}
+	return &tokenVerifier{cfg: cfg}
+}
+
+// verify checks the token's signature, issuer, audience and expiry and
+// returns the identity its claims describe
+func (v *tokenVerifier) verify(ctx context.Context, raw string) (tokenIdentity, error) {
+	verifier, err := v.load(ctx)
+	if err != nil {
+		return tokenIdentity{}, err
+	}
+	token, err := verifier.Verify(ctx, raw)
+	if err != nil {
+		return tokenIdentity{}, err
+	}
+
+	var claims map[string]any
+	if err := token.Claims(&claims); err != nil {
+		return tokenIdentity{}, fmt.Errorf("failed to decode claims: %w", err)
+	}
+
+	identity := tokenIdentity{User: token.Subject}
+	if user, ok := claims[v.cfg.UserClaim].(string); ok && user != "" {
+		identity.User = user
+	}
+	identity.Groups = claimStrings(claims[v.cfg.GroupsClaim])
+	identity.Namespaces = claimStrings(claims[v.cfg.NamespacesClaim])
+	for _, group := range identity.Groups {
+		// viper lowercases map keys
+		identity.Namespaces = append(identity.Namespaces, v.cfg.GroupNamespaces[strings.ToLower(group)]...)
+	}
+	return identity, nil
+}
+
+// load returns the verifier, fetching the issuer's discovery document if
+// it has not been yet
+func (v *tokenVerifier) load(ctx context.Context) (*oidc.IDTokenVerifier, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.verifier != nil {
+		return v.verifier, nil
+	}
+
+	provider, err := oidc.NewProvider(ctx, v.cfg.IssuerURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover OIDC issuer: %w", err)
+	}
+	v.verifier = provider.Verifier(&oidc.Config{ClientID: v.cfg.ClientID})
+	return v.verifier, nil
+}
+
+// claimStrings reads a claim holding a string list, or a single
+// space- or comma-separated string
+func claimStrings(claim any) []string {
+	switch claim := claim.(type) {
+	case []any:
+		out := make([]string, 0, len(claim))
+		for _, v := range claim {
+			if s, ok := v.(string); ok && s != "" {
+				out = append(out, s)
+			}
+		}
+		return out
+	case string:
+		return strings.FieldsFunc(claim, func(r rune) bool { return r == ' ' || r == ',' })
+	}
+	return nil
+}
+
+// isJWT reports whether a bearer token looks like a JWT rather than an API
+// key
+func isJWT(token string) bool {
+	return strings.Count(token, ".") == 2
+}
+
+// identity returns the OIDC identity a request was authenticated with
+func identity(c *gin.Context) (tokenIdentity, bool) {
+	v, ok := c.Get(identityContextKey)
+	if !ok {
+		return tokenIdentity{}, false
+	}
+	i, ok := v.(tokenIdentity)
+	return i, ok
+}
+
+// allowNamespace rejects, with 403, analysis requests for a namespace
+// outside the OIDC caller's namespaces. Requests authenticated otherwise
+// are not limited by namespace.
+func allowNamespace(c *gin.Context, namespace string) bool {
+	if !callerAllows(c, namespace) {
+		id, _ := identity(c)
+		c.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("%s may not analyze namespace %s", id.User, namespace)})
+		return false
+	}
+	return true
+}
+
+// callerAllows reports whether the caller may see namespace: any namespace
+// unless the caller is an OIDC identity limited to some
+func callerAllows(c *gin.Context, namespace string) bool {
+	id, ok := identity(c)
+	return !ok || id.allows(namespace)
+}
+
+// namespacePatterns returns the namespace patterns an OIDC caller is limited
+// to, for database.AnalysisFilter.NamespacePatterns, or nil for callers not
+// limited by namespace
+func namespacePatterns(c *gin.Context) []string {
+	id, ok := identity(c)
+	if !ok {
+		return nil
+	}
+	return append([]string{}, id.Namespaces...)
+}
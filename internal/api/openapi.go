@@ -0,0 +1,377 @@
+package api
+
+import (
+	"encoding/json"
+	"io/fs"
+	"net/http"
+	"path"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/emirozbir/micro-sre/internal/models"
+	"github.com/emirozbir/micro-sre/internal/opsgenie"
+	"github.com/emirozbir/micro-sre/internal/pagerduty"
+	"github.com/emirozbir/micro-sre/internal/version"
+)
+
+// Routes of the API description and its Swagger UI; both are public
+const (
+	routeOpenAPI = "/openapi.json"
+	routeAPIDocs = "/docs"
+)
+
+// apiOperation describes one endpoint in the OpenAPI document. Request and
+// Response are zero values of the types bound and returned by the handler;
+// a nil Response is documented as a free-form object.
+type apiOperation struct {
+	Method   string
+	Route    string
+	Tag      string
+	Summary  string
+	Query    []string
+	Request  any
+	Response any
+	// Async marks endpoints that take ?async=true
+	Async bool
+}
+
+// apiOperations are the documented endpoints. Schemas are generated from
+// the request and response types, so they follow the handlers.
+var apiOperations = []apiOperation{
+	{Method: http.MethodPost, Route: "/api/v1/analyze/pod", Tag: "analyze", Summary: "Analyze a pod", Request: AnalyzePodRequest{}, Response: analysisResponse{}, Async: true},
+	{Method: http.MethodPost, Route: "/api/v1/analyze/alert", Tag: "analyze", Summary: "Analyze an alert on a pod", Request: AnalyzeAlertRequest{}, Response: analysisResponse{}, Async: true},
+	{Method: http.MethodPost, Route: "/api/v1/analyze/deployment", Tag: "analyze", Summary: "Analyze a Deployment or StatefulSet", Request: AnalyzeDeploymentRequest{}, Response: analysisResponse{}, Async: true},
+	{Method: http.MethodPost, Route: "/api/v1/analyze/namespace", Tag: "analyze", Summary: "Report the health of a namespace", Request: NamespaceHealthRequest{}, Response: models.HealthReport{}, Async: true},
+	{Method: http.MethodPost, Route: routeAlertManagerWebhook, Tag: "analyze", Summary: "Analyze the alerts of an AlertManager webhook", Request: models.AlertManagerWebhook{}, Response: models.WebhookAnalysisResponse{}, Async: true},
+	{Method: http.MethodPost, Route: routePagerDutyWebhook, Tag: "analyze", Summary: "Analyze the incident of a PagerDuty V3 incident.triggered webhook", Request: pagerduty.Webhook{}, Response: models.WebhookAnalysisResponse{}, Async: true},
+	{Method: http.MethodPost, Route: routeOpsgenieWebhook, Tag: "analyze", Summary: "Analyze the alert of an Opsgenie webhook Create action", Request: opsgenie.Webhook{}, Response: models.WebhookAnalysisResponse{}, Async: true},
+	{Method: http.MethodPost, Route: routeGenericWebhook, Tag: "analyze", Summary: "Analyze the alerts of a JSON payload mapped by generic_webhooks", Query: []string{"source"}, Request: map[string]any{}, Response: models.WebhookAnalysisResponse{}, Async: true},
+	{Method: http.MethodPost, Route: routeAnalysisJobs, Tag: "jobs", Summary: "Start a pod analysis in the background", Request: AnalyzePodRequest{}, Response: struct {
+		ID     string       `json:"id"`
+		Status string       `json:"status"`
+		Links  models.Links `json:"_links"`
+	}{}},
+	{Method: http.MethodGet, Route: routeAnalysisJob, Tag: "jobs", Summary: "Get an analysis job", Response: struct {
+		Job   analysisJob  `json:"job"`
+		Links models.Links `json:"_links"`
+	}{}},
+	{Method: http.MethodGet, Route: routeAnalyses, Tag: "analyses", Summary: "Search stored analyses", Query: []string{"cluster", "namespace", "pod", "alert", "severity", "confidence", "status", "q", "tag", "incident", "since", "until", "sort", "page", "per_page"}, Response: struct {
+		Count      int `json:"count"`
+		Total      int `json:"total"`
+		Page       int `json:"page"`
+		PerPage    int `json:"per_page"`
+		TotalPages int `json:"total_pages"`
+		Embedded   struct {
+			Analyses []analysisSummary `json:"analyses"`
+		} `json:"_embedded"`
+		Links models.Links `json:"_links"`
+	}{}},
+	{Method: http.MethodDelete, Route: routeAnalyses, Tag: "analyses", Summary: "Delete the analyses matching the filters (admin)", Query: []string{"cluster", "namespace", "pod", "alert", "severity", "confidence", "status", "q", "tag", "incident", "since", "until"}},
+	{Method: http.MethodGet, Route: routeAnalysis, Tag: "analyses", Summary: "Get a stored analysis", Response: analysisResponse{}},
+	{Method: http.MethodDelete, Route: routeAnalysis, Tag: "analyses", Summary: "Delete a stored analysis (admin)"},
+	{Method: http.MethodGet, Route: routeDeletedAnalyses, Tag: "analyses", Summary: "List the deleted analyses that can be restored (admin)", Query: []string{"cluster", "namespace", "pod", "alert", "severity", "confidence", "status", "q", "tag", "incident", "since", "until", "deleted_since", "limit"}},
+	{Method: http.MethodPost, Route: routeRestoreAnalyses, Tag: "analyses", Summary: "Restore the analyses deleted since a time (admin)", Query: []string{"cluster", "namespace", "pod", "alert", "severity", "confidence", "status", "q", "tag", "incident", "since", "until", "deleted_since", "dry_run"}},
+	{Method: http.MethodPost, Route: routeAnalysisRestore, Tag: "analyses", Summary: "Restore a deleted analysis (admin)", Response: analysisSummary{}},
+	{Method: http.MethodGet, Route: routeAnalysisSimilar, Tag: "analyses", Summary: "List analyses similar to one"},
+	{Method: http.MethodGet, Route: routeAnalysisVersions, Tag: "analyses", Summary: "List the versions of an analysis"},
+	{Method: http.MethodGet, Route: routeAnalysisExport, Tag: "analyses", Summary: "Export an analysis as markdown, CSV or PDF", Query: []string{"format"}},
+	{Method: http.MethodPost, Route: routeAnalysisRerun, Tag: "analyses", Summary: "Analyze the target of an analysis again", Request: RerunRequest{}, Response: correctionResponse{}, Async: true},
+	{Method: http.MethodPost, Route: routeAnalysisFeedback, Tag: "feedback", Summary: "Correct the root cause of an analysis", Request: FeedbackRequest{}, Response: correctionResponse{}, Async: true},
+	{Method: http.MethodPost, Route: routeAnalysisRating, Tag: "feedback", Summary: "Rate the root cause of an analysis", Request: RatingRequest{}, Response: feedbackRecord{}},
+	{Method: http.MethodPost, Route: routeAnalysisTags, Tag: "analyses", Summary: "Tag an analysis", Request: TagsRequest{}, Response: tagsResponse{}},
+	{Method: http.MethodDelete, Route: routeAnalysisTag, Tag: "analyses", Summary: "Remove a tag from an analysis", Response: tagsResponse{}},
+	{Method: http.MethodGet, Route: routeTags, Tag: "analyses", Summary: "List the tags in use with their analysis counts"},
+	{Method: http.MethodGet, Route: routeIncidents, Tag: "incidents", Summary: "List incidents", Query: []string{"status", "page"}, Response: struct {
+		Count    int `json:"count"`
+		Page     int `json:"page"`
+		Embedded struct {
+			Incidents []incidentResponse `json:"incidents"`
+		} `json:"_embedded"`
+		Links models.Links `json:"_links"`
+	}{}},
+	{Method: http.MethodPost, Route: routeIncidents, Tag: "incidents", Summary: "Group analyses into an incident", Request: IncidentRequest{}, Response: incidentResponse{}},
+	{Method: http.MethodGet, Route: routeIncident, Tag: "incidents", Summary: "Get an incident with its analyses", Response: incidentResponse{}},
+	{Method: http.MethodPatch, Route: routeIncident, Tag: "incidents", Summary: "Rename, resolve or reopen an incident", Request: IncidentUpdate{}, Response: incidentResponse{}},
+	{Method: http.MethodDelete, Route: routeIncident, Tag: "incidents", Summary: "Delete an incident, ungrouping its analyses (admin)"},
+	{Method: http.MethodPost, Route: routeIncidentAnalyses, Tag: "incidents", Summary: "Add analyses to an incident", Request: IncidentAnalysesRequest{}, Response: incidentResponse{}},
+	{Method: http.MethodDelete, Route: routeIncidentAnalysis, Tag: "incidents", Summary: "Remove an analysis from an incident", Response: incidentResponse{}},
+	{Method: http.MethodPost, Route: routeIncidentMerge, Tag: "incidents", Summary: "Merge other incidents into an incident", Request: MergeIncidentsRequest{}, Response: incidentResponse{}},
+	{Method: http.MethodGet, Route: routeFeedback, Tag: "feedback", Summary: "List recent ratings and corrections", Query: []string{"analysis_id", "alert_name", "rating", "limit"}},
+	{Method: http.MethodGet, Route: routeRemediations, Tag: "remediations", Summary: "List remediation proposals", Query: []string{"status", "namespace", "analysis_id", "limit"}},
+	{Method: http.MethodGet, Route: routeRemediation, Tag: "remediations", Summary: "Get a remediation and its audit trail"},
+	{Method: http.MethodPost, Route: routeRemediationApprove, Tag: "remediations", Summary: "Approve and run a remediation (admin)", Request: RemediationDecision{}},
+	{Method: http.MethodPost, Route: routeRemediationReject, Tag: "remediations", Summary: "Reject a remediation (admin)", Request: RemediationDecision{}},
+	{Method: http.MethodGet, Route: routeK8sNamespaces, Tag: "kubernetes", Summary: "List namespaces", Query: []string{"prefix"}},
+	{Method: http.MethodGet, Route: routeK8sPods, Tag: "kubernetes", Summary: "List the pods of a namespace", Query: []string{"namespace", "prefix"}},
+	{Method: http.MethodGet, Route: routeCollectorErrors, Tag: "stats", Summary: "Count collector failures by error class"},
+	{Method: http.MethodGet, Route: routeAnalysisStats, Tag: "stats", Summary: "Daily analysis counts", Query: []string{"days", "namespace"}},
+	{Method: http.MethodGet, Route: routeTokenBudget, Tag: "stats", Summary: "Today's LLM token spend"},
+	{Method: http.MethodGet, Route: routeFeedbackStats, Tag: "stats", Summary: "Root-cause accuracy from ratings", Query: []string{"days", "alert_name"}},
+	{Method: http.MethodGet, Route: routePostmortem, Tag: "reports", Summary: "Daily or weekly postmortem digest", Query: []string{"period", "namespace", "format"}},
+	{Method: http.MethodGet, Route: routeLivez, Tag: "server", Summary: "Liveness probe"},
+	{Method: http.MethodGet, Route: routeReadyz, Tag: "server", Summary: "Readiness probe checking the API server, database and LLM"},
+	{Method: http.MethodGet, Route: "/version", Tag: "server", Summary: "Server version and read-only mode"},
+	{Method: http.MethodGet, Route: routeBackup, Tag: "server", Summary: "Stream a backup of the database as JSONL or SQL (admin)", Query: []string{"format"}},
+	{Method: http.MethodGet, Route: routeAudit, Tag: "server", Summary: "List who analyzed, re-ran, deleted or restored analyses, reloaded the configuration or decided remediations (admin)", Query: []string{"actor", "action", "since", "until", "limit", "before_id"}},
+}
+
+// correctionResponse documents the new version answered by SubmitFeedback
+// and RerunAnalysis; CorrectsVersion is set for feedback only
+type correctionResponse struct {
+	AnalysisID       int64           `json:"analysis_id"`
+	Version          int             `json:"version"`
+	CorrectsVersion  int             `json:"corrects_version,omitempty"`
+	RootCauseChanged bool            `json:"root_cause_changed"`
+	Analysis         models.Analysis `json:"analysis"`
+	Links            models.Links    `json:"_links"`
+}
+
+// feedbackRecord documents a rating or correction as feedbackResponse
+// renders it
+type feedbackRecord struct {
+	ID                 int64        `json:"id"`
+	AnalysisID         int64        `json:"analysis_id"`
+	AlertName          string       `json:"alert_name"`
+	Namespace          string       `json:"namespace"`
+	Version            int          `json:"version"`
+	CorrectedVersion   int          `json:"corrected_version"`
+	CreatedAt          time.Time    `json:"created_at"`
+	Author             string       `json:"author"`
+	Rating             string       `json:"rating"`
+	Comment            string       `json:"comment"`
+	RejectedRootCause  string       `json:"rejected_root_cause"`
+	Hint               string       `json:"hint"`
+	CorrectedRootCause string       `json:"corrected_root_cause"`
+	Links              models.Links `json:"_links"`
+}
+
+// integerParams are the path parameters holding numeric IDs
+var integerParams = map[string]bool{"id": true, "analysis_id": true, "index": true}
+
+// openAPIDocument builds the OpenAPI 3 description of apiOperations
+func openAPIDocument() gin.H {
+	schemas := gin.H{}
+	paths := gin.H{}
+	for _, op := range apiOperations {
+		path := openAPIPath(op.Route)
+		item, ok := paths[path].(gin.H)
+		if !ok {
+			item = gin.H{}
+			paths[path] = item
+		}
+
+		var params []gin.H
+		for _, segment := range strings.Split(op.Route, "/") {
+			if name, ok := strings.CutPrefix(segment, ":"); ok {
+				schema := gin.H{"type": "string"}
+				if integerParams[name] {
+					schema = gin.H{"type": "integer", "format": "int64"}
+				}
+				params = append(params, gin.H{"name": name, "in": "path", "required": true, "schema": schema})
+			}
+		}
+		for _, name := range op.Query {
+			params = append(params, gin.H{"name": name, "in": "query", "schema": gin.H{"type": "string"}})
+		}
+		if op.Async {
+			params = append(params, gin.H{"name": "async", "in": "query", "description": "Queue the analysis and answer 202 with the job to poll", "schema": gin.H{"type": "boolean"}})
+		}
+
+		response := gin.H{"type": "object"}
+		if op.Response != nil {
+			response = schemaFor(reflect.TypeOf(op.Response), schemas)
+		}
+		operation := gin.H{
+			"tags":        []string{op.Tag},
+			"summary":     op.Summary,
+			"operationId": operationID(op),
+			"responses": gin.H{
+				"200":     gin.H{"description": "OK", "content": gin.H{"application/json": gin.H{"schema": response}}},
+				"default": gin.H{"description": "Error", "content": gin.H{"application/json": gin.H{"schema": gin.H{"$ref": "#/components/schemas/Error"}}}},
+			},
+		}
+		if len(params) > 0 {
+			operation["parameters"] = params
+		}
+		if op.Request != nil {
+			operation["requestBody"] = gin.H{
+				"required": true,
+				"content":  gin.H{"application/json": gin.H{"schema": schemaFor(reflect.TypeOf(op.Request), schemas)}},
+			}
+		}
+		item[strings.ToLower(op.Method)] = operation
+	}
+
+	schemas["Error"] = gin.H{
+		"type":       "object",
+		"properties": gin.H{"error": gin.H{"type": "string"}, "error_class": gin.H{"type": "string"}},
+	}
+	return gin.H{
+		"openapi": "3.0.3",
+		"info": gin.H{
+			"title":   "hepsre API",
+			"version": version.Version,
+		},
+		"paths": paths,
+		"components": gin.H{
+			"schemas": schemas,
+			"securitySchemes": gin.H{
+				"apiKey": gin.H{"type": "apiKey", "in": "header", "name": apiKeyHeader},
+				"bearer": gin.H{"type": "http", "scheme": "bearer"},
+			},
+		},
+		"security": []gin.H{{"apiKey": []string{}}, {"bearer": []string{}}},
+	}
+}
+
+// openAPIPath turns a gin route template into an OpenAPI path
+func openAPIPath(route string) string {
+	segments := strings.Split(route, "/")
+	for i, s := range segments {
+		if name, ok := strings.CutPrefix(s, ":"); ok {
+			segments[i] = "{" + name + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// operationID names an operation after its method and route, e.g.
+// getApiV1AnalysesIdVersions
+func operationID(op apiOperation) string {
+	var sb strings.Builder
+	sb.WriteString(strings.ToLower(op.Method))
+	for _, part := range strings.FieldsFunc(op.Route, func(r rune) bool { return r == '/' || r == ':' || r == '-' || r == '_' }) {
+		sb.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return sb.String()
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schemaFor returns the JSON schema of t. Named structs are added to
+// schemas and referenced; embedded structs are flattened, as encoding/json
+// does.
+func schemaFor(t reflect.Type, schemas gin.H) gin.H {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case t == timeType:
+		return gin.H{"type": "string", "format": "date-time"}
+	case t.Kind() == reflect.Struct && t.Name() != "":
+		// Unexported handler types are published under exported names, and
+		// integration payloads under their package's, e.g. OpsgenieAlert
+		name := t.Name()
+		if pkg := path.Base(t.PkgPath()); pkg != "api" && pkg != "models" {
+			name = pkg + name
+		}
+		name = strings.ToUpper(name[:1]) + name[1:]
+		if _, ok := schemas[name]; !ok {
+			schemas[name] = gin.H{} // placeholder for recursive types
+			schemas[name] = structSchema(t, schemas)
+		}
+		return gin.H{"$ref": "#/components/schemas/" + name}
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		return structSchema(t, schemas)
+	case reflect.Bool:
+		return gin.H{"type": "boolean"}
+	case reflect.Int64, reflect.Uint64:
+		return gin.H{"type": "integer", "format": "int64"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return gin.H{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return gin.H{"type": "number"}
+	case reflect.String:
+		return gin.H{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return gin.H{"type": "array", "items": schemaFor(t.Elem(), schemas)}
+	case reflect.Map:
+		return gin.H{"type": "object", "additionalProperties": schemaFor(t.Elem(), schemas)}
+	}
+	return gin.H{}
+}
+
+// structSchema lists the JSON fields of a struct. Fields with
+// binding:"required" are required; oneof bindings become enums.
+func structSchema(t reflect.Type, schemas gin.H) gin.H {
+	properties := gin.H{}
+	var required []string
+	var addFields func(t reflect.Type)
+	addFields = func(t reflect.Type) {
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if name == "-" || (!f.IsExported() && !f.Anonymous) {
+				continue
+			}
+			ft := f.Type
+			for ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+				addFields(ft)
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+
+			schema := schemaFor(f.Type, schemas)
+			for _, rule := range strings.Split(f.Tag.Get("binding"), ",") {
+				if rule == "required" {
+					required = append(required, name)
+				}
+				if values, ok := strings.CutPrefix(rule, "oneof="); ok {
+					schema["enum"] = strings.Fields(values)
+				}
+			}
+			properties[name] = schema
+		}
+	}
+	addFields(t)
+
+	schema := gin.H{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// openAPI caches the document, which does not change while running
+var openAPI = sync.OnceValue(openAPIDocument)
+
+// OpenAPIJSON returns the OpenAPI 3 description of the API as indented
+// JSON, which the Go client in client/ is generated from
+func OpenAPIJSON() ([]byte, error) {
+	return json.MarshalIndent(openAPI(), "", "  ")
+}
+
+// OpenAPISpec serves the OpenAPI 3 description of the API
+func (h *Handler) OpenAPISpec(c *gin.Context) {
+	c.JSON(http.StatusOK, openAPI())
+}
+
+// swaggerUICDN serves Swagger UI when its files were not built into the
+// binary with make swagger-ui
+const swaggerUICDN = "https://unpkg.com/swagger-ui-dist@5.17.14"
+
+// APIDocs serves Swagger UI for the OpenAPI description, from the static
+// files when they include it
+func (h *Handler) APIDocs(c *gin.Context) {
+	assets := routeStatic + "/swagger-ui"
+	if _, err := fs.Stat(h.static, "swagger-ui/swagger-ui-bundle.js"); err != nil {
+		assets = swaggerUICDN
+	}
+	if err := h.tmpl.ExecuteTemplate(c.Writer, "docs.html", gin.H{"SpecURL": routeOpenAPI, "AssetsURL": assets}); err != nil {
+		h.logger.Error("failed to render template", zap.Error(err))
+	}
+}
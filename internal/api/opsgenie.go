@@ -0,0 +1,80 @@
+package api
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/emirozbir/micro-sre/internal/models"
+	"github.com/emirozbir/micro-sre/internal/opsgenie"
+	"github.com/emirozbir/micro-sre/internal/rules"
+)
+
+// routeOpsgenieWebhook receives Opsgenie webhook integration calls
+const routeOpsgenieWebhook = "/api/v1/webhook/opsgenie"
+
+// ReceiveOpsgenieWebhook analyzes the alert of a Create action like an
+// AlertManager alert, reading the namespace and pod from the alert's
+// details; other actions are acknowledged and ignored. Opsgenie sends the
+// API key as a custom header of the integration.
+func (h *Handler) ReceiveOpsgenieWebhook(c *gin.Context) {
+	var webhook opsgenie.Webhook
+	if err := c.ShouldBindJSON(&webhook); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid webhook payload: " + err.Error()})
+		return
+	}
+	if webhook.Action != opsgenie.ActionCreate {
+		c.JSON(http.StatusOK, gin.H{"status": "ignored", "action": webhook.Action})
+		return
+	}
+
+	alert := webhook.Alert.ToAlert()
+	h.logger.Info("received opsgenie webhook",
+		zap.String("alert_id", webhook.Alert.AlertID),
+		zap.String("namespace", alert.GetNamespace()),
+		zap.String("pod", alert.GetPodName()))
+	if !allowNamespace(c, alert.GetNamespace()) {
+		return
+	}
+
+	w := webhookJob{
+		Webhook: models.AlertManagerWebhook{
+			Status:   "firing",
+			Receiver: "opsgenie",
+			Alerts:   []models.Alert{alert},
+		},
+		OpsgenieAlertID: webhook.Alert.AlertID,
+	}
+	job := &analysisJob{Kind: jobKindWebhook, Namespace: alert.GetNamespace(), Target: "opsgenie:" + webhook.Alert.AlertID, webhook: &w}
+	h.respond(c, job, h.webhookTask(w))
+}
+
+// annotateOpsgenieAlert adds the analysis of an alert to it as a note and
+// tags it with the root cause category. Failures are logged: the analysis
+// is stored either way. Without opsgenie.api_key there is no client.
+func (h *Handler) annotateOpsgenieAlert(ctx context.Context, alertID string, response models.WebhookAnalysisResponse) {
+	if h.opsgenie == nil {
+		return
+	}
+	cfg := h.agent().Config().Opsgenie
+	if note := analysisNote(response, cfg.DashboardURL); note != "" {
+		if err := h.opsgenie.AddNote(ctx, alertID, note); err != nil {
+			h.logger.Error("failed to post opsgenie note", zap.String("alert_id", alertID), zap.Error(err))
+		}
+	}
+
+	var tags []string
+	for _, result := range response.Results {
+		if result.Analysis != nil {
+			tags = append(tags, cfg.TagPrefix+rules.Category(*result.Analysis))
+		}
+	}
+	if len(tags) == 0 {
+		return
+	}
+	if err := h.opsgenie.AddTags(ctx, alertID, tags...); err != nil {
+		h.logger.Error("failed to tag opsgenie alert", zap.String("alert_id", alertID), zap.Error(err))
+	}
+}
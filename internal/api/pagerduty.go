@@ -0,0 +1,80 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/emirozbir/micro-sre/internal/models"
+	"github.com/emirozbir/micro-sre/internal/pagerduty"
+)
+
+// ReceivePagerDutyWebhook analyzes the incident of an incident.triggered
+// webhook like an AlertManager alert, reading the namespace and pod from
+// the incident's custom details; other events are acknowledged and
+// ignored. With pagerduty.webhook_secret set, the signature is checked
+// instead of an API key. PagerDuty gives up on slow webhooks, so subscribe
+// with ?async=true.
+func (h *Handler) ReceivePagerDutyWebhook(c *gin.Context) {
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxWebhookBody))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read body"})
+		return
+	}
+	if h.pagerduty.CanVerify() {
+		if err := h.pagerduty.Verify(c.Request.Header, body); err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	var webhook pagerduty.Webhook
+	if err := json.Unmarshal(body, &webhook); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid webhook payload: " + err.Error()})
+		return
+	}
+	event := webhook.Event
+	if event.EventType != pagerduty.EventIncidentTriggered {
+		c.JSON(http.StatusOK, gin.H{"status": "ignored", "event_type": event.EventType})
+		return
+	}
+
+	alert := event.Alert()
+	h.logger.Info("received pagerduty webhook",
+		zap.String("incident", event.Data.ID),
+		zap.String("namespace", alert.GetNamespace()),
+		zap.String("pod", alert.GetPodName()))
+	if !allowNamespace(c, alert.GetNamespace()) {
+		return
+	}
+
+	w := webhookJob{
+		Webhook: models.AlertManagerWebhook{
+			Status:   "firing",
+			Receiver: "pagerduty",
+			Alerts:   []models.Alert{alert},
+		},
+		PagerDutyIncidentID: event.Data.ID,
+	}
+	job := &analysisJob{Kind: jobKindWebhook, Namespace: alert.GetNamespace(), Target: "pagerduty:" + event.Data.ID, webhook: &w}
+	h.respond(c, job, h.webhookTask(w))
+}
+
+// postPagerDutyNote adds the analysis of an incident to it as a note.
+// Failures are logged: the analysis is stored either way.
+func (h *Handler) postPagerDutyNote(ctx context.Context, incidentID string, response models.WebhookAnalysisResponse) {
+	if h.pagerduty == nil {
+		return
+	}
+	note := analysisNote(response, h.agent().Config().PagerDuty.DashboardURL)
+	if note == "" {
+		return
+	}
+	if err := h.pagerduty.AddNote(ctx, incidentID, note); err != nil {
+		h.logger.Error("failed to post pagerduty note", zap.String("incident", incidentID), zap.Error(err))
+	}
+}
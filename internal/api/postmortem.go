@@ -0,0 +1,121 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/emirozbir/micro-sre/internal/database"
+	"github.com/emirozbir/micro-sre/internal/formatter"
+	"github.com/emirozbir/micro-sre/internal/models"
+)
+
+// maxPostmortemAnalyses bounds the analyses loaded for a postmortem; the
+// most recent are kept
+const maxPostmortemAnalyses = 500
+
+// postmortemWindows are the lookbacks of the postmortem periods
+var postmortemWindows = map[string]time.Duration{
+	models.PostmortemDaily:  24 * time.Hour,
+	models.PostmortemWeekly: 7 * 24 * time.Hour,
+}
+
+// newPostmortemIncident summarizes a stored analysis for a postmortem
+func newPostmortemIncident(stored database.StoredAnalysis) models.PostmortemIncident {
+	result := stored.AnalysisResult
+	incident := models.PostmortemIncident{
+		ID:         stored.ID,
+		CreatedAt:  stored.CreatedAt,
+		AlertName:  stored.AlertName,
+		Namespace:  stored.Namespace,
+		Target:     result.Alert.Target(),
+		Severity:   stored.Severity,
+		RootCause:  stored.RootCause,
+		Confidence: stored.Confidence,
+		Recurring:  result.Recurrence != nil && result.Recurrence.Recurring,
+	}
+	if incident.Target == "" {
+		incident.Target = stored.PodName
+	}
+	for _, rec := range result.Analysis.Recommendations {
+		incident.Recommendations = append(incident.Recommendations, rec.Action)
+	}
+	return incident
+}
+
+// GetPostmortem summarizes the analyses of the last day or week
+// (?period=daily|weekly) into recurring causes, affected services and
+// action items. ?format=markdown renders the report as markdown.
+func (h *Handler) GetPostmortem(c *gin.Context) {
+	period := c.DefaultQuery("period", models.PostmortemDaily)
+	window, ok := postmortemWindows[period]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "period must be daily or weekly"})
+		return
+	}
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "markdown" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json or markdown"})
+		return
+	}
+	namespace := c.Query("namespace")
+
+	to := time.Now()
+	from := to.Add(-window)
+	stored, err := h.db.FindAnalyses(database.AnalysisFilter{
+		Namespace:         namespace,
+		NamespacePatterns: namespacePatterns(c),
+		Status:            database.AnalysisCompleted,
+		Since:             from,
+		Limit:             maxPostmortemAnalyses,
+	})
+	if err != nil {
+		h.logger.Error("failed to load analyses for postmortem", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load analyses"})
+		return
+	}
+
+	incidents := make([]models.PostmortemIncident, 0, len(stored))
+	for _, s := range stored {
+		incidents = append(incidents, newPostmortemIncident(s))
+	}
+
+	report, err := h.agent().Postmortem(c.Request.Context(), period, from, to, namespace, incidents)
+	if err != nil {
+		h.logger.Error("postmortem failed", zap.String("period", period), zap.Error(err))
+		analysisFailed(c, err)
+		return
+	}
+
+	if format == "markdown" {
+		c.Data(http.StatusOK, "text/markdown; charset=utf-8", []byte(formatter.PostmortemMarkdown(report)))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"report": report,
+		"_links": postmortemLinks(report),
+	})
+}
+
+// postmortemLinks links the markdown rendering and the analyses behind the
+// recurring causes
+func postmortemLinks(report *models.PostmortemReport) models.Links {
+	query := url.Values{"period": {report.Period}}
+	if report.Namespace != "" {
+		query.Set("namespace", report.Namespace)
+	}
+	links := models.Links{"self": {Href: routePostmortem + "?" + query.Encode()}}
+	query.Set("format", "markdown")
+	links["markdown"] = models.Link{Href: routePostmortem + "?" + query.Encode(), Type: "text/markdown"}
+
+	for _, cause := range report.RecurringCauses {
+		for _, id := range cause.AnalysisIDs {
+			links[fmt.Sprintf("analysis:%d", id)] = models.Link{Href: analysisPath(routeAnalysis, id)}
+		}
+	}
+	return links
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+
+	"github.com/emirozbir/micro-sre/internal/config"
+)
+
+// clientLimiterIdle is how long a client's limiter is kept after its last
+// request
+const clientLimiterIdle = 10 * time.Minute
+
+// clientLimiter is the token bucket of one client
+type clientLimiter struct {
+	limiter *rate.Limiter
+	seen    time.Time
+}
+
+// rateLimiter limits the requests per minute of each client, so a
+// misconfigured webhook sender cannot exhaust the LLM budget or load the
+// API server with collection calls
+type rateLimiter struct {
+	mu      sync.Mutex
+	limit   rate.Limit
+	burst   int
+	clients map[string]*clientLimiter
+	pruned  time.Time
+}
+
+// newRateLimiter returns nil when server.rate_limit.requests_per_minute is
+// unset
+func newRateLimiter(cfg config.RateLimitConfig) *rateLimiter {
+	if cfg.RequestsPerMinute <= 0 {
+		return nil
+	}
+	burst := cfg.Burst
+	if burst <= 0 {
+		burst = cfg.RequestsPerMinute
+	}
+	return &rateLimiter{
+		limit:   rate.Limit(float64(cfg.RequestsPerMinute) / 60),
+		burst:   burst,
+		clients: map[string]*clientLimiter{},
+	}
+}
+
+// reserve takes a token for client. It returns how long the client has to
+// wait for one when the bucket is empty.
+func (l *rateLimiter) reserve(client string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(l.pruned) > clientLimiterIdle {
+		for k, c := range l.clients {
+			if now.Sub(c.seen) > clientLimiterIdle {
+				delete(l.clients, k)
+			}
+		}
+		l.pruned = now
+	}
+
+	c, ok := l.clients[client]
+	if !ok {
+		c = &clientLimiter{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.clients[client] = c
+	}
+	c.seen = now
+
+	r := c.limiter.ReserveN(now, 1)
+	if delay := r.DelayFrom(now); delay > 0 {
+		r.CancelAt(now)
+		return false, delay
+	}
+	return true, 0
+}
+
+// clientKey identifies the client of a request for rate limiting: its API
+// key or OIDC user, else its address
+func clientKey(c *gin.Context) string {
+	if key, ok := apiKey(c); ok {
+		return "api-key:" + key.Name
+	}
+	if id, ok := identity(c); ok {
+		return "oidc:" + id.User
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// rateLimit answers 429 with a Retry-After header to clients over
+// server.rate_limit. It is a no-op while no limit is configured.
+func (h *Handler) rateLimit(c *gin.Context) {
+	if h.limiter == nil {
+		c.Next()
+		return
+	}
+
+	client := clientKey(c)
+	ok, wait := h.limiter.reserve(client)
+	if !ok {
+		h.logger.Warn("rate limited request", zap.String("client", client), zap.String("path", c.FullPath()))
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+			"error": fmt.Sprintf("rate limit of %d requests per minute exceeded", h.agent().Config().Server.RateLimit.RequestsPerMinute),
+		})
+		return
+	}
+	c.Next()
+}
@@ -0,0 +1,305 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/emirozbir/micro-sre/internal/agent"
+	"github.com/emirozbir/micro-sre/internal/database"
+)
+
+// Re-analysis job states
+const (
+	jobRunning   = "running"
+	jobCompleted = "completed"
+)
+
+// maxReanalysisBatch caps how many analyses one job re-runs
+const maxReanalysisBatch = 500
+
+// ReanalyzeRequest selects past analyses to re-run, either by ID or by filter
+type ReanalyzeRequest struct {
+	IDs       []int64 `json:"ids"`
+	AlertName string  `json:"alert_name"`
+	Namespace string  `json:"namespace"`
+	Since     string  `json:"since"` // duration, e.g. "720h"
+	Limit     int     `json:"limit"`
+}
+
+// reanalysisJob tracks a bulk re-analysis. ConfidenceBefore/After count the
+// confidence levels of the originals and of the new versions, which is the
+// quickest read on whether a prompt or model upgrade helped.
+type reanalysisJob struct {
+	ID               string              `json:"id"`
+	Status           string              `json:"status"`
+	Model            string              `json:"model"`
+	PromptVersion    string              `json:"prompt_version"`
+	StartedAt        time.Time           `json:"started_at"`
+	FinishedAt       *time.Time          `json:"finished_at,omitempty"`
+	Total            int                 `json:"total"`
+	Completed        int                 `json:"completed"`
+	Failed           int                 `json:"failed"`
+	RootCauseChanged int                 `json:"root_cause_changed"`
+	ConfidenceBefore map[string]int      `json:"confidence_before"`
+	ConfidenceAfter  map[string]int      `json:"confidence_after"`
+	Results          []reanalysisOutcome `json:"results"`
+}
+
+type reanalysisOutcome struct {
+	AnalysisID         int64  `json:"analysis_id"`
+	Version            int    `json:"version,omitempty"`
+	PreviousConfidence string `json:"previous_confidence,omitempty"`
+	Confidence         string `json:"confidence,omitempty"`
+	RootCauseChanged   bool   `json:"root_cause_changed"`
+	Error              string `json:"error,omitempty"`
+}
+
+// reanalysisJobs keeps bulk re-analysis jobs in memory
+type reanalysisJobs struct {
+	mu   sync.Mutex
+	jobs map[string]*reanalysisJob
+}
+
+func newReanalysisJobs() *reanalysisJobs {
+	return &reanalysisJobs{jobs: map[string]*reanalysisJob{}}
+}
+
+// snapshot returns a copy of the job that is safe to serialize
+func (j *reanalysisJobs) snapshot(id string) (reanalysisJob, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	job, ok := j.jobs[id]
+	if !ok {
+		return reanalysisJob{}, false
+	}
+	out := *job
+	out.ConfidenceBefore = copyCounts(job.ConfidenceBefore)
+	out.ConfidenceAfter = copyCounts(job.ConfidenceAfter)
+	out.Results = append([]reanalysisOutcome(nil), job.Results...)
+	return out, true
+}
+
+func copyCounts(m map[string]int) map[string]int {
+	out := make(map[string]int, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
+
+// StartReanalysis starts a background job re-running the selected analyses
+// with the current prompt and model
+func (h *Handler) StartReanalysis(c *gin.Context) {
+	var req ReanalyzeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ids := req.IDs
+	if len(ids) == 0 {
+		filter := database.AnalysisFilter{
+			AlertName: req.AlertName,
+			Namespace: req.Namespace,
+			Status:    database.AnalysisCompleted,
+			Limit:     req.Limit,
+		}
+		if req.Since != "" {
+			since, err := time.ParseDuration(req.Since)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid since duration"})
+				return
+			}
+			filter.Since = time.Now().Add(-since)
+		}
+		if filter.Limit <= 0 || filter.Limit > maxReanalysisBatch {
+			filter.Limit = maxReanalysisBatch
+		}
+
+		var err error
+		ids, err = h.db.FindAnalysisIDs(filter)
+		if err != nil {
+			h.logger.Error("failed to select analyses", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to select analyses"})
+			return
+		}
+	}
+	if len(ids) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "no analyses selected"})
+		return
+	}
+	if len(ids) > maxReanalysisBatch {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "too many analyses selected"})
+		return
+	}
+
+	model, promptVersion := h.agent().Generator()
+	job := &reanalysisJob{
+		ID:               newJobID(),
+		Status:           jobRunning,
+		Model:            model,
+		PromptVersion:    promptVersion,
+		StartedAt:        time.Now(),
+		Total:            len(ids),
+		ConfidenceBefore: map[string]int{},
+		ConfidenceAfter:  map[string]int{},
+	}
+
+	h.reanalysis.mu.Lock()
+	h.reanalysis.jobs[job.ID] = job
+	h.reanalysis.mu.Unlock()
+
+	h.logger.Info("starting bulk re-analysis",
+		zap.String("job", job.ID),
+		zap.Int("analyses", len(ids)),
+		zap.String("model", model),
+		zap.String("prompt_version", promptVersion))
+
+	go h.runReanalysis(job, ids)
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"id":    job.ID,
+		"total": job.Total,
+		"_links": gin.H{
+			"self": gin.H{"href": fillRoute(routeReanalysisJob, map[string]string{"job": job.ID})},
+		},
+	})
+}
+
+// GetReanalysis reports the progress of a bulk re-analysis job
+func (h *Handler) GetReanalysis(c *gin.Context) {
+	job, ok := h.reanalysis.snapshot(c.Param("job"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "job not found"})
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
+
+// runReanalysis re-runs the analyses one at a time to keep LLM usage
+// predictable
+func (h *Handler) runReanalysis(job *reanalysisJob, ids []int64) {
+	for _, id := range ids {
+		outcome := h.reanalyzeOne(id)
+
+		h.reanalysis.mu.Lock()
+		job.Results = append(job.Results, outcome)
+		if outcome.Error != "" {
+			job.Failed++
+		} else {
+			job.Completed++
+			job.ConfidenceBefore[outcome.PreviousConfidence]++
+			job.ConfidenceAfter[outcome.Confidence]++
+			if outcome.RootCauseChanged {
+				job.RootCauseChanged++
+			}
+		}
+		h.reanalysis.mu.Unlock()
+	}
+
+	finished := time.Now()
+	h.reanalysis.mu.Lock()
+	job.Status = jobCompleted
+	job.FinishedAt = &finished
+	h.reanalysis.mu.Unlock()
+
+	h.logger.Info("bulk re-analysis completed",
+		zap.String("job", job.ID),
+		zap.Int("completed", job.Completed),
+		zap.Int("failed", job.Failed))
+}
+
+func (h *Handler) reanalyzeOne(id int64) reanalysisOutcome {
+	outcome := reanalysisOutcome{AnalysisID: id}
+
+	stored, err := h.db.GetAnalysis(id)
+	if err != nil {
+		outcome.Error = err.Error()
+		return outcome
+	}
+	if stored == nil {
+		outcome.Error = "analysis not found"
+		return outcome
+	}
+	if stored.Status != database.AnalysisCompleted {
+		outcome.Error = "analysis is " + stored.Status
+		return outcome
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	result, err := h.agent().Reanalyze(ctx, &stored.AnalysisResult)
+	if err != nil {
+		if !errors.Is(err, agent.ErrNoBundle) {
+			h.logger.Error("re-analysis failed", zap.Int64("id", id), zap.Error(err))
+		}
+		outcome.Error = err.Error()
+		return outcome
+	}
+
+	version, err := h.saveAnalysisVersion(ctx, id, result)
+	if err != nil {
+		h.logger.Error("failed to save analysis version", zap.Int64("id", id), zap.Error(err))
+		outcome.Error = err.Error()
+		return outcome
+	}
+
+	outcome.Version = version
+	outcome.PreviousConfidence = stored.Confidence
+	outcome.Confidence = result.Analysis.Confidence
+	outcome.RootCauseChanged = result.Analysis.RootCause != stored.RootCause
+	return outcome
+}
+
+// GetAnalysisVersions lists the re-analyses of a stored analysis
+func (h *Handler) GetAnalysisVersions(c *gin.Context) {
+	analysis, ok := h.loadAnalysis(c)
+	if !ok {
+		return
+	}
+
+	versions, err := h.db.ListAnalysisVersions(analysis.ID)
+	if err != nil {
+		h.logger.Error("failed to list analysis versions", zap.Int64("id", analysis.ID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load analysis versions"})
+		return
+	}
+
+	out := make([]gin.H, 0, len(versions))
+	for _, v := range versions {
+		out = append(out, gin.H{
+			"version":        v.Version,
+			"created_at":     v.CreatedAt,
+			"model":          v.Model,
+			"prompt_version": v.PromptVersion,
+			"analysis":       v.AnalysisResult.Analysis,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"count": len(out),
+		"_embedded": gin.H{
+			"versions": out,
+		},
+		"_links": gin.H{
+			"self":     gin.H{"href": analysisPath(routeAnalysisVersions, analysis.ID)},
+			"original": gin.H{"href": analysisPath(routeAnalysis, analysis.ID)},
+		},
+	})
+}
+
+func newJobID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
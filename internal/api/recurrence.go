@@ -0,0 +1,33 @@
+package api
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/emirozbir/micro-sre/internal/agent"
+	"github.com/emirozbir/micro-sre/internal/models"
+)
+
+// findRecurrence looks up earlier analyses of the request's alert on its pod
+// within agent.recurrence.window. Lookup failures are logged and the
+// analysis proceeds as if the incident were new.
+func (h *Handler) findRecurrence(req agent.AnalysisRequest) *models.Recurrence {
+	cfg := h.agent().Config().Agent.Recurrence
+	if cfg.Window <= 0 {
+		return nil
+	}
+
+	recurrence, err := h.db.FindRecurrence(req.Namespace, req.PodName, req.AlertName(), time.Now().Add(-cfg.Window))
+	if err != nil {
+		h.logger.Warn("failed to look up recurrence",
+			zap.String("namespace", req.Namespace),
+			zap.String("pod", req.PodName),
+			zap.Error(err))
+		return nil
+	}
+	if recurrence != nil {
+		recurrence.Recurring = cfg.Threshold > 0 && recurrence.Count >= cfg.Threshold
+	}
+	return recurrence
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/emirozbir/micro-sre/internal/config"
+)
+
+// startupSections are read once when the server starts. Changes to them
+// are reported by ReloadConfig but take effect after a restart.
+var startupSections = map[string]bool{
+	"database": true,
+	"tracing":  true,
+	"slack":    true,
+	"export":   true,
+}
+
+// handlerSettings reports which settings the handler's own state is built
+// from in NewHandler, the OIDC verifier, rate limiter and Opsgenie and
+// PagerDuty clients, differ between before and after. Reloading them would
+// leave that state behind, e.g. a server switched from API keys to OIDC
+// without a verifier, so they are refused like read_only.
+func handlerSettings(before, after *config.Config) []string {
+	var changed []string
+	for name, equal := range map[string]bool{
+		"server.oidc":       reflect.DeepEqual(before.Server.OIDC, after.Server.OIDC),
+		"server.rate_limit": reflect.DeepEqual(before.Server.RateLimit, after.Server.RateLimit),
+		"opsgenie":          reflect.DeepEqual(before.Opsgenie, after.Opsgenie),
+		"pagerduty":         reflect.DeepEqual(before.PagerDuty, after.PagerDuty),
+	} {
+		if !equal {
+			changed = append(changed, name)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// reloadMu serializes reloads, so concurrent ones cannot interleave
+var reloadMu sync.Mutex
+
+// ReloadConfig re-reads the configuration file, found the same way as at
+// startup, and swaps in an agent built from it: alert filters, rules,
+// redaction, report verbosity, token budgets, the analyzer pipeline and the
+// collector settings change at once for new requests, while analyses
+// already running finish on the old configuration. An invalid file leaves
+// the running configuration in place.
+func (h *Handler) ReloadConfig(c *gin.Context) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	cfg, err := config.Load("")
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	current := h.agent()
+	if fixed := handlerSettings(current.Config(), cfg); len(fixed) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": strings.Join(fixed, ", ") + " cannot be reloaded, restart the server"})
+		return
+	}
+	reloaded, err := current.Reload(cfg)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	h.current.Store(reloaded)
+	// A budget enabled by the reload starts from today's stored spend
+	if current.TokenBudget() == nil {
+		h.seedBudget(reloaded.TokenBudget())
+	}
+
+	changed := changedSections(current.Config(), cfg)
+	restart := []string{}
+	for _, section := range changed {
+		if startupSections[section] {
+			restart = append(restart, section)
+		}
+	}
+	h.logger.Info("configuration reloaded", zap.Strings("changed", changed), zap.Strings("restart_required", restart))
+	c.JSON(http.StatusOK, gin.H{
+		"reloaded":         true,
+		"changed":          changed,
+		"restart_required": restart,
+	})
+}
+
+// changedSections lists the top-level configuration keys whose values
+// differ between before and after
+func changedSections(before, after *config.Config) []string {
+	changed := []string{}
+	b, a := reflect.ValueOf(before).Elem(), reflect.ValueOf(after).Elem()
+	for i := 0; i < b.NumField(); i++ {
+		if reflect.DeepEqual(b.Field(i).Interface(), a.Field(i).Interface()) {
+			continue
+		}
+		name, _, _ := strings.Cut(b.Type().Field(i).Tag.Get("mapstructure"), ",")
+		if name == "" {
+			name = b.Type().Field(i).Name
+		}
+		changed = append(changed, name)
+	}
+	sort.Strings(changed)
+	return changed
+}
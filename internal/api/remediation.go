@@ -0,0 +1,69 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/emirozbir/micro-sre/internal/collectors"
+	"github.com/emirozbir/micro-sre/internal/remediation"
+)
+
+// DryRunRecommendation dry-runs the command of one recommendation of a
+// stored analysis against the API server. Commands without -n run in the
+// analysis namespace. Nothing is persisted in the cluster.
+func (h *Handler) DryRunRecommendation(c *gin.Context) {
+	analysis, ok := h.loadAnalysis(c)
+	if !ok {
+		return
+	}
+
+	recs := analysis.AnalysisResult.Analysis.Recommendations
+	index, err := strconv.Atoi(c.Param("index"))
+	if err != nil || index < 0 || index >= len(recs) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "recommendation not found"})
+		return
+	}
+	command := recs[index].Command
+	if command == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "recommendation has no command"})
+		return
+	}
+
+	// The command's -n overrides the analysis namespace; invalid commands
+	// are refused by DryRun
+	target := analysis.Namespace
+	if cmd, err := remediation.Validate(command, h.agent().Config().Remediation.AllowedVerbs); err == nil && cmd.Namespace != "" {
+		target = cmd.Namespace
+	}
+	if !allowNamespace(c, target) {
+		return
+	}
+
+	result, err := h.agent().DryRun(c.Request.Context(), command, analysis.Namespace)
+	switch {
+	case errors.Is(err, remediation.ErrInvalidCommand):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, collectors.ErrReadOnly):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, collectors.ErrNamespaceNotAllowed):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		h.logger.Error("failed to dry-run recommendation", zap.Int64("id", analysis.ID), zap.Int("index", index), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to dry-run command"})
+		return
+	}
+
+	h.logger.Info("dry-ran recommendation",
+		zap.Int64("id", analysis.ID),
+		zap.String("command", command),
+		zap.String("status", result.Status),
+	)
+	c.JSON(http.StatusOK, result)
+}
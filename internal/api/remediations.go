@@ -0,0 +1,422 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/emirozbir/micro-sre/internal/database"
+	"github.com/emirozbir/micro-sre/internal/models"
+	"github.com/emirozbir/micro-sre/internal/slack"
+)
+
+const (
+	// maxRemediations caps the remediation collection
+	maxRemediations = 100
+	// remediationTimeout bounds an approved execution, which outlives the
+	// approving request
+	remediationTimeout = 30 * time.Second
+	// remediationProposer is the actor recorded for proposals
+	remediationProposer = "hepsre"
+
+	slackActionApprove = "remediation_approve"
+	slackActionReject  = "remediation_reject"
+)
+
+var (
+	errRemediationNotFound = errors.New("remediation not found")
+	errRemediationDecided  = errors.New("remediation was already decided")
+	errRemediationExpired  = errors.New("remediation approval window has passed")
+)
+
+// RemediationDecision approves or rejects a pending remediation on behalf
+// of the authenticated caller, who is recorded as its actor
+type RemediationDecision struct {
+	Reason string `json:"reason"`
+}
+
+// proposeRemediations records the recommendations of a stored analysis
+// that auto-remediation may execute, as pending remediations awaiting
+// approval, and announces them on Slack
+func (h *Handler) proposeRemediations(id int64, result *models.AnalysisResult) {
+	auto := h.agent().Config().Remediation.Auto
+	if !auto.Enabled {
+		return
+	}
+
+	for i, rec := range result.Analysis.Recommendations {
+		if rec.Command == "" || rec.Check == nil || !rec.Check.Valid {
+			continue
+		}
+		plan, err := h.agent().PlanRemediation(rec.Command, result.Alert.Namespace)
+		if err != nil {
+			h.logger.Debug("recommendation not proposed for remediation", zap.String("command", rec.Command), zap.Error(err))
+			continue
+		}
+		pending, err := h.db.HasPendingRemediation(plan.Namespace, rec.Command)
+		if err != nil {
+			h.logger.Warn("failed to check pending remediations", zap.Error(err))
+			continue
+		}
+		if pending {
+			continue
+		}
+
+		now := time.Now()
+		r := database.Remediation{
+			AnalysisID:     id,
+			Recommendation: i,
+			CreatedAt:      now,
+			ExpiresAt:      now.Add(auto.ApprovalTTL),
+			Namespace:      plan.Namespace,
+			Action:         plan.Action,
+			Target:         plan.Target,
+			Command:        rec.Command,
+		}
+		r.ID, err = h.db.CreateRemediation(r, remediationProposer)
+		if err != nil {
+			h.logger.Error("failed to save remediation", zap.Error(err))
+			continue
+		}
+		h.logger.Info("remediation proposed",
+			zap.Int64("remediation_id", r.ID),
+			zap.String("action", r.Action),
+			zap.String("target", r.Target),
+		)
+		if h.slack.CanPost() {
+			go h.announceRemediation(r, result.Analysis.RootCause)
+		}
+	}
+}
+
+// announceRemediation posts a pending remediation to Slack with approve
+// and reject buttons
+func (h *Handler) announceRemediation(r database.Remediation, rootCause string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	id := strconv.FormatInt(r.ID, 10)
+	err := h.slack.Post(ctx, slack.Message{
+		Text: fmt.Sprintf("Remediation #%d awaits approval: %s %s", r.ID, r.Action, r.Target),
+		Blocks: []slack.Block{
+			slack.Section(fmt.Sprintf("*Remediation #%d awaits approval*\n%s\n`%s`\nExpires %s",
+				r.ID, rootCause, r.Command, r.ExpiresAt.Format(time.RFC1123))),
+			slack.Buttons(
+				slack.Button("Approve", slackActionApprove, id, "primary"),
+				slack.Button("Reject", slackActionReject, id, "danger"),
+			),
+		},
+	})
+	if err != nil {
+		h.logger.Warn("failed to post remediation to slack", zap.Int64("remediation_id", r.ID), zap.Error(err))
+	}
+}
+
+// decideRemediation approves or rejects a pending remediation. An
+// approved remediation is executed right away and its result recorded.
+func (h *Handler) decideRemediation(id int64, approve bool, actor, reason string) (*database.Remediation, error) {
+	r, err := h.db.GetRemediation(id)
+	if err != nil {
+		return nil, err
+	}
+	if r == nil {
+		return nil, errRemediationNotFound
+	}
+	if r.Status != database.RemediationPending {
+		return r, errRemediationDecided
+	}
+	if time.Now().After(r.ExpiresAt) {
+		if _, err := h.db.TransitionRemediation(id, database.RemediationPending, database.RemediationExpired, remediationProposer, "not approved in time"); err != nil {
+			return nil, err
+		}
+		r.Status = database.RemediationExpired
+		return r, errRemediationExpired
+	}
+
+	to := database.RemediationRejected
+	if approve {
+		to = database.RemediationApproved
+	}
+	ok, err := h.db.TransitionRemediation(id, database.RemediationPending, to, actor, reason)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		// Decided concurrently
+		if r, err = h.db.GetRemediation(id); err != nil {
+			return nil, err
+		}
+		return r, errRemediationDecided
+	}
+	h.logger.Info("remediation decided",
+		zap.Int64("remediation_id", id),
+		zap.String("status", to),
+		zap.String("actor", actor),
+	)
+
+	if approve {
+		ctx, cancel := context.WithTimeout(context.Background(), remediationTimeout)
+		defer cancel()
+
+		to, message := database.RemediationExecuted, "applied"
+		if err := h.agent().ExecuteRemediation(ctx, r.Command, r.Namespace); err != nil {
+			h.logger.Error("remediation failed", zap.Int64("remediation_id", id), zap.Error(err))
+			to, message = database.RemediationFailed, err.Error()
+		}
+		// Approvals from Slack reach no audited route, so every execution
+		// is recorded here
+		h.recordAudit(database.AuditEvent{
+			Actor:    actor,
+			Action:   auditRemediationRun,
+			Resource: fillRoute(routeRemediation, map[string]string{"id": strconv.FormatInt(id, 10)}),
+			Detail:   fmt.Sprintf("%s in %s: %s", r.Command, r.Namespace, message),
+		})
+		if _, err := h.db.TransitionRemediation(id, database.RemediationApproved, to, actor, message); err != nil {
+			return nil, err
+		}
+	}
+
+	return h.db.GetRemediation(id)
+}
+
+// ListRemediations returns the most recent remediations, optionally
+// filtered by status, namespace and analysis
+func (h *Handler) ListRemediations(c *gin.Context) {
+	filter := database.RemediationFilter{
+		Status:    c.Query("status"),
+		Namespace: c.Query("namespace"),
+		Limit:     maxRemediations,
+	}
+	if s := c.Query("analysis_id"); s != "" {
+		id, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid analysis ID"})
+			return
+		}
+		filter.AnalysisID = id
+	}
+	if s := c.Query("limit"); s != "" {
+		if n, err := strconv.Atoi(s); err == nil && n > 0 && n < filter.Limit {
+			filter.Limit = n
+		}
+	}
+
+	records, err := h.db.ListRemediations(filter)
+	if err != nil {
+		h.logger.Error("failed to list remediations", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list remediations"})
+		return
+	}
+
+	out := make([]gin.H, 0, len(records))
+	for _, r := range records {
+		if callerAllows(c, r.Namespace) {
+			out = append(out, remediationJSON(r))
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"count": len(out),
+		"_embedded": gin.H{
+			"remediations": out,
+		},
+	})
+}
+
+// GetRemediation returns a remediation with its audit trail
+func (h *Handler) GetRemediation(c *gin.Context) {
+	r, ok := h.loadRemediation(c)
+	if !ok {
+		return
+	}
+
+	events, err := h.db.ListRemediationEvents(r.ID)
+	if err != nil {
+		h.logger.Error("failed to list remediation events", zap.Int64("remediation_id", r.ID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load remediation events"})
+		return
+	}
+	audit := make([]gin.H, 0, len(events))
+	for _, e := range events {
+		audit = append(audit, gin.H{
+			"event":      e.Event,
+			"actor":      e.Actor,
+			"message":    e.Message,
+			"created_at": e.CreatedAt,
+		})
+	}
+
+	body := remediationJSON(*r)
+	body["events"] = audit
+	c.JSON(http.StatusOK, body)
+}
+
+// ApproveRemediation approves a pending remediation and executes it
+func (h *Handler) ApproveRemediation(c *gin.Context) {
+	h.decide(c, true)
+}
+
+// RejectRemediation rejects a pending remediation
+func (h *Handler) RejectRemediation(c *gin.Context) {
+	h.decide(c, false)
+}
+
+func (h *Handler) decide(c *gin.Context, approve bool) {
+	if !h.requireViewer(c) {
+		return
+	}
+	r, ok := h.loadRemediation(c)
+	if !ok {
+		return
+	}
+
+	var req RemediationDecision
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	// The actor is the authenticated caller, never one the body declares
+	decided, err := h.decideRemediation(r.ID, approve, h.actor(c), req.Reason)
+	switch {
+	case errors.Is(err, errRemediationDecided), errors.Is(err, errRemediationExpired):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "status": decided.Status})
+		return
+	case err != nil:
+		h.logger.Error("failed to decide remediation", zap.Int64("remediation_id", r.ID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to decide remediation"})
+		return
+	}
+	c.JSON(http.StatusOK, remediationJSON(*decided))
+}
+
+// SlackInteraction handles the approve and reject buttons of remediation
+// messages. Slack wants an answer within three seconds, so the decision
+// runs in the background and its outcome replaces the original message.
+func (h *Handler) SlackInteraction(c *gin.Context) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read body"})
+		return
+	}
+	if err := h.slack.Verify(c.Request.Header, body); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid form body"})
+		return
+	}
+	interaction, err := slack.ParseInteraction(form.Get("payload"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	for _, action := range interaction.Actions {
+		if action.ActionID != slackActionApprove && action.ActionID != slackActionReject {
+			continue
+		}
+		id, err := strconv.ParseInt(action.Value, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid remediation ID"})
+			return
+		}
+		actor := "slack:" + interaction.User.Username
+		if interaction.User.Username == "" {
+			actor = "slack:" + interaction.User.ID
+		}
+		go h.decideFromSlack(id, action.ActionID == slackActionApprove, actor, interaction.ResponseURL)
+		break
+	}
+	c.Status(http.StatusOK)
+}
+
+func (h *Handler) decideFromSlack(id int64, approve bool, actor, responseURL string) {
+	r, err := h.decideRemediation(id, approve, actor, "via slack")
+	var text string
+	switch {
+	case err == nil:
+		text = fmt.Sprintf("Remediation #%d (%s %s) %s by %s", r.ID, r.Action, r.Target, r.Status, actor)
+		if r.Status == database.RemediationFailed {
+			text += ": " + r.Result
+		}
+	case r != nil:
+		text = fmt.Sprintf("Remediation #%d: %s (%s)", id, err, r.Status)
+	default:
+		h.logger.Error("failed to decide remediation", zap.Int64("remediation_id", id), zap.Error(err))
+		text = fmt.Sprintf("Remediation #%d: %s", id, err)
+	}
+
+	if responseURL == "" {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := h.slack.Respond(ctx, responseURL, slack.Message{Text: text, ReplaceOriginal: true}); err != nil {
+		h.logger.Warn("failed to respond to slack", zap.Int64("remediation_id", id), zap.Error(err))
+	}
+}
+
+func (h *Handler) loadRemediation(c *gin.Context) (*database.Remediation, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid remediation ID"})
+		return nil, false
+	}
+	r, err := h.db.GetRemediation(id)
+	if err != nil {
+		h.logger.Error("failed to get remediation", zap.Int64("remediation_id", id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load remediation"})
+		return nil, false
+	}
+	if r == nil || !callerAllows(c, r.Namespace) {
+		c.JSON(http.StatusNotFound, gin.H{"error": errRemediationNotFound.Error()})
+		return nil, false
+	}
+	return r, true
+}
+
+func remediationJSON(r database.Remediation) gin.H {
+	links := gin.H{
+		"self":     gin.H{"href": remediationPath(routeRemediation, r.ID)},
+		"analysis": gin.H{"href": analysisPath(routeAnalysis, r.AnalysisID)},
+	}
+	if r.Status == database.RemediationPending {
+		links["approve"] = gin.H{"href": remediationPath(routeRemediationApprove, r.ID)}
+		links["reject"] = gin.H{"href": remediationPath(routeRemediationReject, r.ID)}
+	}
+
+	out := gin.H{
+		"id":             r.ID,
+		"analysis_id":    r.AnalysisID,
+		"recommendation": r.Recommendation,
+		"created_at":     r.CreatedAt,
+		"expires_at":     r.ExpiresAt,
+		"namespace":      r.Namespace,
+		"action":         r.Action,
+		"target":         r.Target,
+		"command":        r.Command,
+		"status":         r.Status,
+		"_links":         links,
+	}
+	if r.DecidedBy != "" {
+		out["decided_by"] = r.DecidedBy
+		out["decided_at"] = r.DecidedAt
+	}
+	if r.Result != "" {
+		out["result"] = r.Result
+	}
+	return out
+}
+
+func remediationPath(route string, id int64) string {
+	return fillRoute(route, map[string]string{"id": fmt.Sprint(id)})
+}
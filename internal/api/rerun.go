@@ -0,0 +1,141 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/emirozbir/micro-sre/internal/agent"
+	"github.com/emirozbir/micro-sre/internal/database"
+	"github.com/emirozbir/micro-sre/internal/llm"
+	"github.com/emirozbir/micro-sre/internal/models"
+)
+
+// RerunRequest overrides the lookback or model of a re-run; both default to
+// those of the original analysis and llm.model
+type RerunRequest struct {
+	Lookback string `json:"lookback"`
+	Model    string `json:"model"`
+}
+
+// RerunAnalysis analyzes the pod or workload of a stored analysis again
+// against the live cluster and stores the result as a new version of it
+func (h *Handler) RerunAnalysis(c *gin.Context) {
+	analysis, ok := h.loadAnalysis(c)
+	if !ok || !requireCompleted(c, analysis) {
+		return
+	}
+
+	var req RerunRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Other models cost differently, and the provider may not serve them
+	if req.Model != "" && !h.agent().Config().LLM.AllowsModel(req.Model) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("model %q is not allowed: use llm.model or one of llm.rerun_models", req.Model)})
+		return
+	}
+
+	lookback, err := rerunLookback(analysis, req.Lookback)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid lookback duration"})
+		return
+	}
+
+	original := analysis.AnalysisResult
+	if original.Alert.Target() == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "analysis has no pod or workload to re-run"})
+		return
+	}
+
+	job := &analysisJob{Kind: jobKindRerun, Namespace: analysis.Namespace, Target: original.Alert.Target(), Lookback: lookback.String()}
+	h.respond(c, job, func(ctx context.Context) (any, int64, error) {
+		if req.Model != "" {
+			ctx = llm.WithModel(ctx, req.Model)
+		}
+
+		result, err := h.rerun(ctx, analysis, lookback)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		version, err := h.saveAnalysisVersion(ctx, analysis.ID, result)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to save analysis version: %w", err)
+		}
+
+		h.logger.Info("analysis re-run",
+			zap.Int64("id", analysis.ID),
+			zap.Int("version", version),
+			zap.String("model", result.Model))
+		return gin.H{
+			"analysis_id":        analysis.ID,
+			"version":            version,
+			"root_cause_changed": result.Analysis.RootCause != analysis.RootCause,
+			"analysis":           result.Analysis,
+			"_links": gin.H{
+				"versions": gin.H{"href": analysisPath(routeAnalysisVersions, analysis.ID)},
+				"html":     gin.H{"href": analysisPath(routeAnalysisPage, analysis.ID), "type": "text/html"},
+			},
+		}, analysis.ID, nil
+	})
+}
+
+// rerunLookback returns the lookback of a re-run: the requested one, or
+// that of the original analysis
+func rerunLookback(analysis *database.StoredAnalysis, requested string) (time.Duration, error) {
+	if requested != "" {
+		return time.ParseDuration(requested)
+	}
+	if lookback, err := time.ParseDuration(analysis.AnalysisResult.CollectedData.TimeRange); err == nil && lookback > 0 {
+		return lookback, nil
+	}
+	return time.Hour, nil
+}
+
+// rerun repeats the analysis of a stored pod or workload analysis. Pod
+// analyses of an alert keep it, so that runbooks and rules still match.
+func (h *Handler) rerun(ctx context.Context, analysis *database.StoredAnalysis, lookback time.Duration) (*models.AnalysisResult, error) {
+	original := analysis.AnalysisResult
+	if original.Alert.Pod == "" {
+		_, name, ok := strings.Cut(original.Alert.Workload, "/")
+		if !ok {
+			return nil, fmt.Errorf("analysis %d has no pod or workload to re-run", analysis.ID)
+		}
+		result, err := h.agent().AnalyzeDeployment(ctx, analysis.Namespace, name, lookback, original.Verbosity)
+		if err == nil && original.Alert.Cluster != "" {
+			result.Alert.Cluster = original.Alert.Cluster
+		}
+		return result, err
+	}
+
+	req := agent.AnalysisRequest{
+		Namespace:  analysis.Namespace,
+		PodName:    original.Alert.Pod,
+		Lookback:   lookback,
+		Verbosity:  original.Verbosity,
+		Cluster:    original.Alert.Cluster,
+		Recurrence: original.Recurrence,
+	}
+	if original.Alert.Name != (agent.AnalysisRequest{}).AlertName() {
+		req.Alert = &models.Alert{
+			Labels: map[string]string{
+				"alertname": original.Alert.Name,
+				"severity":  original.Alert.Severity,
+				"namespace": analysis.Namespace,
+				"pod":       original.Alert.Pod,
+			},
+			StartsAt: original.Alert.StartedAt,
+		}
+	}
+	return h.agent().AnalyzeAlert(ctx, req)
+}
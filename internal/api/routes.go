@@ -1,24 +1,158 @@
 package api
 
 import (
+	"net/http"
+
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/emirozbir/micro-sre/internal/metrics"
 )
 
 func SetupRoutes(handler *Handler) *gin.Engine {
-	r := gin.Default()
+	r := gin.New()
+	// Only the configured proxies may name the client in X-Forwarded-For;
+	// entries are validated when the configuration is loaded
+	if err := r.SetTrustedProxies(handler.agent().Config().Server.TrustedProxies); err != nil {
+		handler.logger.Error("invalid server.trusted_proxies, trusting none", zap.Error(err))
+		r.SetTrustedProxies(nil)
+	}
+	r.Use(gin.Recovery(), assignRequestID, handler.accessLog, instrument, traceRequest, handler.securityHeaders, handler.cors, handler.authenticate)
 
-	// Health check
-	r.GET("/healthz", handler.Health)
+	// Probes; /healthz is the old name of /livez
+	r.GET(routeLivez, handler.Livez)
+	r.GET(routeHealthz, handler.Livez)
+	r.GET(routeReadyz, handler.Readyz)
+	r.GET("/version", handler.Version)
+	r.GET(routeMetrics, gin.WrapH(metrics.Handler()))
+
+	// OpenAPI description of the API and its Swagger UI
+	r.GET(routeOpenAPI, handler.OpenAPISpec)
+	r.GET(routeAPIDocs, handler.APIDocs)
+
+	// Web UI
+	r.StaticFS(routeStatic, http.FS(handler.static))
 	r.GET("/analyses", handler.ListAnalyses)
-	r.GET("/analyses/:id", handler.GetAnalysis)
+	r.GET(routeAnalysisPage, handler.GetAnalysis)
+	r.GET(routeNewAnalysisPage, handler.NewAnalysisPage)
+	r.GET(routeAnalysisJobPage, handler.AnalysisJobPage)
+	r.GET(routeIncidentsPage, handler.IncidentsPage)
+	r.GET(routeIncidentPage, handler.IncidentPage)
 
-	// API v1
-	v1 := r.Group("/api/v1")
+	// API v1; analyses are rate limited per client and recorded in the
+	// audit log
+	v1 := r.Group("/api/v1", handler.rateLimit)
 	{
-		v1.POST("/analyze/alert", handler.AnalyzeAlert)
-		v1.POST("/analyze/pod", handler.AnalyzePod)
-		v1.POST("/webhook/alertmanager", handler.ReceiveAlertManagerWebhook)
+		v1.POST("/analyze/alert", handler.audit(auditAnalyze), handler.AnalyzeAlert)
+		v1.POST("/analyze/pod", handler.audit(auditAnalyze), handler.AnalyzePod)
+		v1.POST("/analyze/deployment", handler.audit(auditAnalyze), handler.AnalyzeDeployment)
+		v1.POST("/analyze/namespace", handler.audit(auditAnalyze), handler.AnalyzeNamespace)
+		v1.POST("/webhook/alertmanager", handler.audit(auditAnalyze), handler.verifyWebhook, handler.ReceiveAlertManagerWebhook)
+		v1.POST("/webhook/pagerduty", handler.audit(auditAnalyze), handler.ReceivePagerDutyWebhook)
+		v1.POST("/webhook/opsgenie", handler.audit(auditAnalyze), handler.ReceiveOpsgenieWebhook)
+		v1.POST("/webhook/generic", handler.audit(auditAnalyze), handler.ReceiveGenericWebhook)
 	}
 
+	// Analysis resources; paths are shared with the "_links" builders
+	r.GET(routeAnalyses, handler.SearchAnalyses)
+	r.GET(routeAnalysis, handler.GetAnalysisJSON)
+	r.GET(routeAnalysisSimilar, handler.GetSimilarAnalyses)
+	r.GET(routeArtifact, handler.GetArtifact)
+	r.GET(routeAnalysisVersions, handler.GetAnalysisVersions)
+	r.GET(routeAnalysisExport, handler.ExportAnalysis)
+
+	// Analyzing again against the live cluster stores a new version
+	r.POST(routeAnalysisRerun, handler.rateLimit, handler.audit(auditRerun), handler.RerunAnalysis)
+
+	// Deleting stale or mistaken analyses requires server.admin_token; they
+	// can be restored until the retention job purges them
+	r.DELETE(routeAnalyses, handler.audit(auditDelete), handler.requireAdmin, handler.DeleteAnalyses)
+	r.DELETE(routeAnalysis, handler.audit(auditDelete), handler.requireAdmin, handler.DeleteAnalysis)
+	r.GET(routeDeletedAnalyses, handler.requireAdmin, handler.ListDeletedAnalyses)
+	r.POST(routeRestoreAnalyses, handler.audit(auditRestore), handler.requireAdmin, handler.RestoreAnalyses)
+	r.POST(routeAnalysisRestore, handler.audit(auditRestore), handler.requireAdmin, handler.RestoreAnalysis)
+
+	// Human feedback: ratings, re-analysis with a hint, and the feedback so
+	// far
+	r.POST(routeAnalysisRating, handler.RateAnalysis)
+	r.POST(routeAnalysisFeedback, handler.rateLimit, handler.audit(auditFeedback), handler.SubmitFeedback)
+	r.GET(routeFeedback, handler.ListFeedback)
+
+	// Tags readers attach to analyses to find them again
+	r.POST(routeAnalysisTags, handler.TagAnalysis)
+	r.DELETE(routeAnalysisTag, handler.UntagAnalysis)
+	r.GET(routeTags, handler.ListTags)
+
+	// Incidents group the analyses of one outage; deleting one only
+	// ungroups its analyses but still requires server.admin_token
+	r.GET(routeIncidents, handler.ListIncidents)
+	r.POST(routeIncidents, handler.CreateIncident)
+	r.GET(routeIncident, handler.GetIncident)
+	r.PATCH(routeIncident, handler.UpdateIncident)
+	r.DELETE(routeIncident, handler.requireAdmin, handler.DeleteIncident)
+	r.POST(routeIncidentAnalyses, handler.AddIncidentAnalyses)
+	r.DELETE(routeIncidentAnalysis, handler.RemoveIncidentAnalysis)
+	r.POST(routeIncidentMerge, handler.MergeIncidents)
+
+	// Server-side dry-run of recommended commands (not in read-only mode)
+	r.POST(routeRecommendationDryRun, handler.DryRunRecommendation)
+
+	// Gated auto-remediation: proposals, approvals and their audit trail.
+	// Approving runs the command against the cluster, so deciding requires
+	// server.admin_token like deletes.
+	r.GET(routeRemediations, handler.ListRemediations)
+	r.GET(routeRemediation, handler.GetRemediation)
+	r.POST(routeRemediationApprove, handler.audit(auditRemediationDecide), handler.requireAdmin, handler.ApproveRemediation)
+	r.POST(routeRemediationReject, handler.audit(auditRemediationDecide), handler.requireAdmin, handler.RejectRemediation)
+	r.POST(routeSlackInteractions, handler.SlackInteraction)
+
+	// "/hepsre analyze <namespace> <pod> [lookback]" from Slack
+	r.POST(routeSlackCommand, handler.SlackCommand)
+
+	// Analysis jobs (web UI and ?async=true requests)
+	r.POST(routeAnalysisJobs, handler.rateLimit, handler.audit(auditAnalyze), handler.StartAnalysisJob)
+	r.GET(routeAnalysisJob, handler.GetAnalysisJob)
+	r.GET(routeAnalysisJobStream, handler.StreamAnalysisJob)
+
+	// Target autocomplete for the UI form and CLI completion
+	r.GET(routeK8sNamespaces, handler.ListNamespaces)
+	r.GET(routeK8sPods, handler.ListPods)
+
+	// Collector failure counts by error class, daily analysis rollups,
+	// today's LLM token spend and the accuracy rated by readers
+	r.GET(routeCollectorErrors, handler.CollectorErrors)
+	r.GET(routeAnalysisStats, handler.GetAnalysisStats)
+	r.GET(routeTokenBudget, handler.GetTokenBudget)
+	r.GET(routeFeedbackStats, handler.FeedbackStats)
+
+	// Daily and weekly postmortem digests of the stored analyses
+	r.GET(routePostmortem, handler.GetPostmortem)
+
+	// Live log tail and newly stored analyses for the dashboard (websockets)
+	r.GET(routePodLogTail, handler.TailPodLogs)
+	r.GET(routeAnalysesLive, handler.LiveAnalyses)
+
+	// Admin jobs; bulk re-analyses spend LLM tokens, so they require
+	// server.admin_token like deletes
+	r.POST(routeReanalysisJobs, handler.audit(auditReanalyze), handler.requireAdmin, handler.StartReanalysis)
+	r.GET(routeReanalysisJob, handler.requireAdmin, handler.GetReanalysis)
+
+	// Database backups hold every analysis, so they require
+	// server.admin_token like deletes
+	r.GET(routeBackup, handler.audit(auditBackup), handler.requireAdmin, handler.Backup)
+
+	// Runtime configuration reload, like deletes, requires
+	// server.admin_token
+	r.POST(routeReload, handler.audit(auditConfigReload), handler.requireAdmin, handler.ReloadConfig)
+
+	// Who analyzed, re-ran, deleted or restored analyses, reloaded the
+	// configuration or decided remediations
+	r.GET(routeAudit, handler.requireAdmin, handler.ListAuditEvents)
+
+	// Simulated collector and LLM faults (chaos.enabled only); injecting
+	// them requires server.admin_token
+	r.GET(routeChaos, handler.GetChaos)
+	r.PUT(routeChaos, handler.audit(auditChaos), handler.requireAdmin, handler.SetChaos)
+
 	return r
 }
@@ -0,0 +1,180 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/emirozbir/micro-sre/internal/database"
+	"github.com/emirozbir/micro-sre/internal/models"
+)
+
+// Analysis search page sizes
+const (
+	defaultSearchPerPage = 20
+	maxSearchPerPage     = 100
+)
+
+// parseSearchTime parses a date range bound given as RFC 3339 or as a
+// date; a date bound to "until" covers that whole day
+func parseSearchTime(value string, until bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	day, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if until {
+		day = day.AddDate(0, 0, 1)
+	}
+	return day, nil
+}
+
+// analysisFilter reads the search filters of a request: cluster,
+// namespace, pod, alert, severity, confidence, status, root-cause text (q),
+// tags (tag, repeated to require several), incident and date range
+// (since, until). It responds with 400 and reports false if a filter is
+// invalid.
+func analysisFilter(c *gin.Context) (database.AnalysisFilter, bool) {
+	filter := database.AnalysisFilter{
+		Cluster:    c.Query("cluster"),
+		Namespace:  c.Query("namespace"),
+		PodName:    c.Query("pod"),
+		AlertName:  c.Query("alert"),
+		Severity:   c.Query("severity"),
+		Confidence: c.Query("confidence"),
+		Status:     c.Query("status"),
+		RootCause:  c.Query("q"),
+		// Only the namespaces an OIDC caller may see
+		NamespacePatterns: namespacePatterns(c),
+	}
+	switch filter.Status {
+	case "", database.AnalysisPending, database.AnalysisRunning, database.AnalysisCompleted, database.AnalysisFailed:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be pending, running, completed or failed"})
+		return filter, false
+	}
+	tags, ok := normalizeTags(c, c.QueryArray("tag"))
+	if !ok {
+		return filter, false
+	}
+	filter.Tags = tags
+	if v := c.Query("incident"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "incident must be an incident ID"})
+			return filter, false
+		}
+		filter.IncidentID = id
+	}
+	for _, bound := range []struct {
+		param string
+		until bool
+		dst   *time.Time
+	}{
+		{"since", false, &filter.Since},
+		{"until", true, &filter.Until},
+	} {
+		if v := c.Query(bound.param); v != "" {
+			t, err := parseSearchTime(v, bound.until)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": bound.param + " must be an RFC 3339 time or a YYYY-MM-DD date"})
+				return filter, false
+			}
+			*bound.dst = t
+		}
+	}
+	return filter, true
+}
+
+// SearchAnalyses lists stored analyses as JSON, newest first, filtered as
+// described by analysisFilter, with page and per_page pagination
+func (h *Handler) SearchAnalyses(c *gin.Context) {
+	filter, ok := analysisFilter(c)
+	if !ok {
+		return
+	}
+	filter.Sort = c.Query("sort")
+	filter.Limit = defaultSearchPerPage
+	if !database.ValidSort(filter.Sort) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sort must be confidence or -confidence"})
+		return
+	}
+
+	page := 1
+	if v := c.Query("page"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "page must be a positive number"})
+			return
+		}
+		page = n
+	}
+	if v := c.Query("per_page"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxSearchPerPage {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("per_page must be between 1 and %d", maxSearchPerPage)})
+			return
+		}
+		filter.Limit = n
+	}
+	filter.Offset = (page - 1) * filter.Limit
+
+	total, err := h.db.CountFilteredAnalyses(filter)
+	if err != nil {
+		h.logger.Error("failed to count analyses", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to search analyses"})
+		return
+	}
+	stored, err := h.db.FindAnalyses(filter)
+	if err != nil {
+		h.logger.Error("failed to search analyses", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to search analyses"})
+		return
+	}
+
+	analyses := make([]analysisSummary, 0, len(stored))
+	for _, s := range stored {
+		analyses = append(analyses, newAnalysisSummary(s))
+	}
+	totalPages := int(math.Ceil(float64(total) / float64(filter.Limit)))
+
+	c.JSON(http.StatusOK, gin.H{
+		"count":       len(analyses),
+		"total":       total,
+		"page":        page,
+		"per_page":    filter.Limit,
+		"total_pages": totalPages,
+		"_embedded": gin.H{
+			"analyses": analyses,
+		},
+		"_links": searchLinks(c.Request.URL.Query(), page, totalPages),
+	})
+}
+
+// searchLinks links the current, first, previous and next pages of a
+// search, keeping its filters
+func searchLinks(query url.Values, page, totalPages int) models.Links {
+	pageLink := func(p int) models.Link {
+		query.Set("page", strconv.Itoa(p))
+		return models.Link{Href: routeAnalyses + "?" + query.Encode()}
+	}
+	links := models.Links{
+		"self":  pageLink(page),
+		"first": pageLink(1),
+	}
+	if page > 1 {
+		links["prev"] = pageLink(min(page-1, max(totalPages, 1)))
+	}
+	if page < totalPages {
+		links["next"] = pageLink(page + 1)
+	}
+	return links
+}
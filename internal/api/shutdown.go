@@ -0,0 +1,44 @@
+package api
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+// StopStreams ends job event streams, live feeds and log tails. Register it
+// with http.Server.RegisterOnShutdown so long-lived connections don't hold
+// up the shutdown.
+func (h *Handler) StopStreams() {
+	h.stopOnce.Do(func() { close(h.stopping) })
+}
+
+// Shutdown stops accepting analysis jobs and waits for the queued and
+// running ones to finish. Jobs still running when ctx is done are
+// cancelled. Jobs deferred by the token budget are not waited for.
+func (h *Handler) Shutdown(ctx context.Context) error {
+	h.StopStreams()
+
+	if n := h.analysisJobs.active(); n > 0 {
+		h.logger.Info("Waiting for analysis jobs to finish", zap.Int("jobs", n))
+	}
+	select {
+	case <-h.analysisJobs.close():
+		return nil
+	case <-ctx.Done():
+		n := h.analysisJobs.active()
+		h.cancelJobs()
+		return fmt.Errorf("cancelled %d unfinished analysis jobs: %w", n, ctx.Err())
+	}
+}
+
+// queueRefusal explains why a job was not queued
+func (h *Handler) queueRefusal() string {
+	select {
+	case <-h.stopping:
+		return "server is shutting down"
+	default:
+		return "analysis queue is full"
+	}
+}
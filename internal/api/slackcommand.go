@@ -0,0 +1,147 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+
+	"github.com/emirozbir/micro-sre/internal/agent"
+	"github.com/emirozbir/micro-sre/internal/database"
+	"github.com/emirozbir/micro-sre/internal/models"
+	"github.com/emirozbir/micro-sre/internal/requestid"
+	"github.com/emirozbir/micro-sre/internal/slack"
+)
+
+// slackCommandUsage is the reply to malformed slash commands
+const slackCommandUsage = "Usage: `/hepsre analyze <namespace> <pod> [lookback]`, e.g. `/hepsre analyze prod my-pod 2h`"
+
+// maxSlackRecommendations bounds the recommendations in a Slack reply
+const maxSlackRecommendations = 5
+
+// SlackCommand handles the hepsre slash command. Slack wants an answer
+// within three seconds, so the analysis is queued as a job, the command is
+// acknowledged right away, and the analysis is posted to the command's
+// response_url once it completes.
+func (h *Handler) SlackCommand(c *gin.Context) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read body"})
+		return
+	}
+	if err := h.slack.Verify(c.Request.Header, body); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid form body"})
+		return
+	}
+
+	args := strings.Fields(form.Get("text"))
+	if len(args) < 3 || len(args) > 4 || args[0] != "analyze" {
+		c.JSON(http.StatusOK, slack.Message{Text: slackCommandUsage})
+		return
+	}
+	namespace, pod := args[1], args[2]
+	lookback := 1 * time.Hour
+	if len(args) == 4 {
+		if lookback, err = time.ParseDuration(args[3]); err != nil || lookback <= 0 {
+			c.JSON(http.StatusOK, slack.Message{Text: fmt.Sprintf("Invalid lookback %q. %s", args[3], slackCommandUsage)})
+			return
+		}
+	}
+
+	responseURL := form.Get("response_url")
+	req := agent.AnalysisRequest{Namespace: namespace, PodName: pod, Lookback: lookback}
+	job := &analysisJob{
+		ID:          newJobID(),
+		Kind:        jobKindPod,
+		Status:      jobQueued,
+		Stage:       "Queued",
+		Namespace:   namespace,
+		Target:      pod,
+		Lookback:    lookback.String(),
+		CreatedAt:   time.Now(),
+		spanContext: trace.SpanContextFromContext(c.Request.Context()),
+		RequestID:   requestid.From(c.Request.Context()),
+		task: func(ctx context.Context) (any, int64, error) {
+			id, result, _, err := h.analyzePod(ctx, req)
+			h.respondToSlackCommand(responseURL, namespace, pod, id, result, err)
+			if err != nil {
+				return nil, 0, err
+			}
+			return newAnalysisResponse(id, result), id, nil
+		},
+	}
+	if !h.analysisJobs.enqueue(job) {
+		c.JSON(http.StatusOK, slack.Message{Text: "The analysis queue is full, try again in a minute."})
+		return
+	}
+
+	h.logger.Info("slack command queued analysis",
+		zap.String("user", form.Get("user_name")),
+		zap.String("namespace", namespace),
+		zap.String("pod", pod))
+	h.recordAudit(database.AuditEvent{
+		Actor:     "slack:" + form.Get("user_name"),
+		Action:    auditAnalyze,
+		Resource:  c.Request.Method + " " + c.Request.URL.RequestURI(),
+		Status:    http.StatusOK,
+		ClientIP:  c.ClientIP(),
+		RequestID: job.RequestID,
+		Detail:    namespace + "/" + pod,
+	})
+	c.JSON(http.StatusOK, slack.Message{Text: fmt.Sprintf("Analyzing %s/%s over the last %s…", namespace, pod, lookback)})
+}
+
+// respondToSlackCommand posts the outcome of a slash command analysis to
+// the channel it was typed in
+func (h *Handler) respondToSlackCommand(responseURL, namespace, pod string, id int64, result *models.AnalysisResult, err error) {
+	msg := slack.Message{ResponseType: "in_channel"}
+	if err != nil {
+		msg = slack.Message{Text: fmt.Sprintf("Analysis of %s/%s failed: %s", namespace, pod, err)}
+	} else {
+		msg.Text = fmt.Sprintf("Analysis #%d of %s/%s: %s", id, namespace, pod, result.Analysis.RootCause)
+		msg.Blocks = slackAnalysisBlocks(namespace, pod, id, result.Analysis)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := h.slack.Respond(ctx, responseURL, msg); err != nil {
+		h.logger.Warn("failed to respond to slack command", zap.String("namespace", namespace), zap.String("pod", pod), zap.Error(err))
+	}
+}
+
+// slackAnalysisBlocks formats an analysis as Slack blocks: the root cause
+// with its confidence, then the top recommendations
+func slackAnalysisBlocks(namespace, pod string, id int64, analysis models.Analysis) []slack.Block {
+	blocks := []slack.Block{
+		slack.Section(fmt.Sprintf("*Analysis #%d of %s/%s* (confidence: %s)\n*Root cause:* %s",
+			id, namespace, pod, analysis.Confidence, analysis.RootCause)),
+	}
+	if len(analysis.Recommendations) == 0 {
+		return blocks
+	}
+
+	var sb strings.Builder
+	sb.WriteString("*Recommendations:*")
+	for i, rec := range analysis.Recommendations {
+		if i == maxSlackRecommendations {
+			break
+		}
+		fmt.Fprintf(&sb, "\n• [%s] %s", rec.Priority, rec.Action)
+		if rec.Command != "" {
+			fmt.Fprintf(&sb, "\n  `%s`", rec.Command)
+		}
+	}
+	return append(blocks, slack.Section(sb.String()))
+}
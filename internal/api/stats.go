@@ -0,0 +1,164 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/emirozbir/micro-sre/internal/database"
+)
+
+// Stats query limits
+const (
+	defaultStatsDays = 30
+	maxStatsDays     = 365
+	chartDays        = 14
+)
+
+// dayCount is the number of analyses created on a day
+type dayCount struct {
+	Day   string `json:"day"`
+	Count int    `json:"count"`
+}
+
+// llmSpend is the tokens the LLM spent and their estimated USD cost
+type llmSpend struct {
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	CostUSD          float64 `json:"cost_usd"`
+}
+
+func (s *llmSpend) add(r database.DailyStat) {
+	s.PromptTokens += r.PromptTokens
+	s.CompletionTokens += r.CompletionTokens
+	s.CostUSD += r.Cost
+}
+
+// addSpend adds the spend of a rollup row to the spend under key
+func addSpend(spend map[string]llmSpend, key string, r database.DailyStat) {
+	s := spend[key]
+	s.add(r)
+	spend[key] = s
+}
+
+// daySpend is the LLM spend of a day
+type daySpend struct {
+	Day string `json:"day"`
+	llmSpend
+}
+
+// analysisStats summarizes the daily rollups of a period
+type analysisStats struct {
+	Since       string         `json:"since"`
+	Total       int            `json:"total"`
+	ByDay       []dayCount     `json:"by_day"`
+	ByNamespace map[string]int `json:"by_namespace"`
+	BySeverity  map[string]int `json:"by_severity"`
+	ByCategory  map[string]int `json:"by_category"`
+	// LLM is the spend of the period, including re-analyses
+	LLM            llmSpend            `json:"llm"`
+	LLMByDay       []daySpend          `json:"llm_by_day"`
+	LLMByNamespace map[string]llmSpend `json:"llm_by_namespace"`
+	LLMByModel     map[string]llmSpend `json:"llm_by_model"`
+}
+
+// summarizeStats totals daily rollups over the last days days; days without
+// analyses are included with a zero count and spend
+func summarizeStats(rows []database.DailyStat, days int) analysisStats {
+	start := time.Now().UTC().AddDate(0, 0, -(days - 1))
+	stats := analysisStats{
+		Since:          start.Format("2006-01-02"),
+		ByNamespace:    map[string]int{},
+		BySeverity:     map[string]int{},
+		ByCategory:     map[string]int{},
+		LLMByNamespace: map[string]llmSpend{},
+		LLMByModel:     map[string]llmSpend{},
+	}
+
+	perDay := map[string]int{}
+	spendPerDay := map[string]llmSpend{}
+	for _, r := range rows {
+		if r.Count > 0 {
+			stats.Total += r.Count
+			perDay[r.Day] += r.Count
+			stats.ByNamespace[r.Namespace] += r.Count
+			stats.BySeverity[r.Severity] += r.Count
+			stats.ByCategory[r.Category] += r.Count
+		}
+
+		// Analyses answered without the LLM spent nothing
+		if r.PromptTokens+r.CompletionTokens == 0 {
+			continue
+		}
+		stats.LLM.add(r)
+		addSpend(spendPerDay, r.Day, r)
+		addSpend(stats.LLMByNamespace, r.Namespace, r)
+		addSpend(stats.LLMByModel, r.Model, r)
+	}
+	for i := 0; i < days; i++ {
+		day := start.AddDate(0, 0, i).Format("2006-01-02")
+		stats.ByDay = append(stats.ByDay, dayCount{Day: day, Count: perDay[day]})
+		stats.LLMByDay = append(stats.LLMByDay, daySpend{Day: day, llmSpend: spendPerDay[day]})
+	}
+	return stats
+}
+
+// GetAnalysisStats reports analysis counts by day, namespace, severity and
+// category, and the LLM tokens and cost by day, namespace and model, from
+// the daily rollups. They lag behind new analyses by up to
+// database.rollup_interval.
+func (h *Handler) GetAnalysisStats(c *gin.Context) {
+	days := defaultStatsDays
+	if v := c.Query("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxStatsDays {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "days must be between 1 and 365"})
+			return
+		}
+		days = n
+	}
+
+	since := time.Now().UTC().AddDate(0, 0, -(days - 1))
+	rows, err := h.db.ListDailyStats(since, c.Query("namespace"))
+	if err != nil {
+		h.logger.Error("failed to load analysis stats", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load analysis stats"})
+		return
+	}
+
+	c.JSON(http.StatusOK, summarizeStats(rows, days))
+}
+
+// chartBar is one day of the history page chart; Height is a percentage of
+// the busiest day
+type chartBar struct {
+	Day    string
+	Count  int
+	Height int
+}
+
+// dailyChart returns the bars of the analyses-per-day chart
+func (h *Handler) dailyChart() ([]chartBar, error) {
+	since := time.Now().UTC().AddDate(0, 0, -(chartDays - 1))
+	rows, err := h.db.ListDailyStats(since, "")
+	if err != nil {
+		return nil, err
+	}
+
+	stats := summarizeStats(rows, chartDays)
+	if stats.Total == 0 {
+		return nil, nil
+	}
+	busiest := 0
+	for _, d := range stats.ByDay {
+		busiest = max(busiest, d.Count)
+	}
+	bars := make([]chartBar, 0, len(stats.ByDay))
+	for _, d := range stats.ByDay {
+		bars = append(bars, chartBar{Day: d.Day, Count: d.Count, Height: d.Count * 100 / busiest})
+	}
+	return bars, nil
+}
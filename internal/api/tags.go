@@ -0,0 +1,155 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/emirozbir/micro-sre/internal/models"
+)
+
+// tagPattern is the form of tags once normalized: lowercase letters,
+// digits and the separators of names like "team:payments" or
+// "postmortem-filed", up to 64 characters. Tags have no slashes, being
+// path segments of routeAnalysisTag.
+var tagPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._:-]{0,63}$`)
+
+// normalizeTag trims and lowercases a tag, reporting false if it is not
+// a valid tag then
+func normalizeTag(tag string) (string, bool) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	return tag, tagPattern.MatchString(tag)
+}
+
+// normalizeTags normalizes tags and drops duplicates. It responds with 400
+// and reports false if one is invalid.
+func normalizeTags(c *gin.Context, tags []string) ([]string, bool) {
+	seen := make(map[string]bool, len(tags))
+	out := make([]string, 0, len(tags))
+	for _, t := range tags {
+		tag, ok := normalizeTag(t)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid tag %q: tags are up to 64 letters, digits and . _ : -", t)})
+			return nil, false
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			out = append(out, tag)
+		}
+	}
+	return out, true
+}
+
+// TagsRequest attaches up to 20 tags to an analysis
+type TagsRequest struct {
+	Tags []string `json:"tags" binding:"required,min=1,max=20"`
+}
+
+// tagsResponse is the tags of an analysis
+type tagsResponse struct {
+	AnalysisID int64        `json:"analysis_id"`
+	Tags       []string     `json:"tags"`
+	Links      models.Links `json:"_links"`
+}
+
+// TagAnalysis attaches tags, such as "postmortem-filed", "false-positive"
+// or a team name, to an analysis and returns all of its tags. Tags are
+// lowercased; attaching one the analysis has is a no-op.
+func (h *Handler) TagAnalysis(c *gin.Context) {
+	var req TagsRequest
+	analysis, ok := h.analysisRequest(c, &req)
+	if !ok {
+		return
+	}
+	tags, ok := normalizeTags(c, req.Tags)
+	if !ok {
+		return
+	}
+
+	author := h.actor(c)
+	if err := h.db.AddAnalysisTags(analysis.ID, tags, author); err != nil {
+		h.logger.Error("failed to tag analysis", zap.Int64("id", analysis.ID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to tag analysis"})
+		return
+	}
+	h.logger.Info("analysis tagged", zap.Int64("id", analysis.ID), zap.Strings("tags", tags), zap.String("author", author))
+
+	h.respondTags(c, analysis.ID)
+}
+
+// UntagAnalysis detaches a tag from an analysis and returns its remaining
+// tags
+func (h *Handler) UntagAnalysis(c *gin.Context) {
+	analysis, ok := h.analysisRequest(c, nil)
+	if !ok {
+		return
+	}
+
+	tag, _ := normalizeTag(c.Param("tag"))
+	removed, err := h.db.RemoveAnalysisTag(analysis.ID, tag)
+	if err != nil {
+		h.logger.Error("failed to remove tag", zap.Int64("id", analysis.ID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to remove tag"})
+		return
+	}
+	if !removed {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("analysis has no tag %q", tag)})
+		return
+	}
+	h.logger.Info("analysis untagged", zap.Int64("id", analysis.ID), zap.String("tag", tag), zap.String("actor", h.actor(c)))
+
+	h.respondTags(c, analysis.ID)
+}
+
+// respondTags answers with the current tags of an analysis
+func (h *Handler) respondTags(c *gin.Context, id int64) {
+	tags, err := h.db.ListAnalysisTags(id)
+	if err != nil {
+		h.logger.Error("failed to list tags", zap.Int64("id", id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list tags"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tagsResponse{
+		AnalysisID: id,
+		Tags:       append([]string{}, tags[id]...),
+		Links: models.Links{
+			"analysis": {Href: analysisPath(routeAnalysis, id)},
+			"html":     {Href: analysisPath(routeAnalysisPage, id), Type: "text/html"},
+		},
+	})
+}
+
+// ListTags returns the tags in use with the number of analyses carrying
+// each, most used first
+func (h *Handler) ListTags(c *gin.Context) {
+	counts, err := h.db.ListTags()
+	if err != nil {
+		h.logger.Error("failed to list tags", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list tags"})
+		return
+	}
+
+	tags := make([]gin.H, 0, len(counts))
+	for _, t := range counts {
+		tags = append(tags, gin.H{
+			"tag":   t.Tag,
+			"count": t.Count,
+			"_links": models.Links{
+				"analyses": {Href: routeAnalyses + "?tag=" + url.QueryEscape(t.Tag)},
+			},
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"count": len(tags),
+		"_embedded": gin.H{
+			"tags": tags,
+		},
+	})
+}
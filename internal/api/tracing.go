@@ -0,0 +1,36 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/emirozbir/micro-sre/internal/tracing"
+)
+
+// traceRequest spans every request, continuing the caller's trace when it
+// sends a traceparent header. Spans are named by route template, like the
+// request metrics.
+func traceRequest(c *gin.Context) {
+	ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+
+	route := c.FullPath()
+	if route == "" {
+		route = "unmatched"
+	}
+	ctx, span := tracing.Start(ctx, c.Request.Method+" "+route, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(
+		attribute.String("http.request.method", c.Request.Method),
+		attribute.String("http.route", route),
+	))
+	defer span.End()
+
+	c.Request = c.Request.WithContext(ctx)
+	c.Next()
+
+	span.SetAttributes(attribute.Int("http.response.status_code", c.Writer.Status()))
+	if err := c.Errors.Last(); err != nil {
+		span.RecordError(err)
+	}
+}
@@ -0,0 +1,141 @@
+package artifacts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/emirozbir/micro-sre/internal/encryption"
+)
+
+// FilesystemStore keeps artifacts as files under a root directory, sharded
+// by the first two characters of the ID
+type FilesystemStore struct {
+	root   string
+	cipher *encryption.Cipher
+}
+
+func NewFilesystemStore(root string) (*FilesystemStore, error) {
+	if root == "" {
+		return nil, fmt.Errorf("artifacts.path must be set for the filesystem backend")
+	}
+	if err := os.MkdirAll(root, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create artifacts directory: %w", err)
+	}
+	return &FilesystemStore{root: root}, nil
+}
+
+func (s *FilesystemStore) path(id string) string {
+	return filepath.Join(s.root, id[:2], id+".gz")
+}
+
+func (s *FilesystemStore) Put(ctx context.Context, data []byte) (string, error) {
+	id := ID(data)
+	path := s.path(id)
+
+	// Content-addressed: an existing file already holds these bytes and
+	// only gets its age refreshed
+	now := time.Now()
+	if err := os.Chtimes(path, now, now); err == nil {
+		return id, nil
+	}
+
+	encoded, err := encode(data, s.cipher)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return "", fmt.Errorf("failed to create artifact directory: %w", err)
+	}
+
+	// Write to a temporary file and rename so readers never see partial data
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".artifact-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create artifact: %w", err)
+	}
+	if _, err := tmp.Write(encoded); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("failed to write artifact: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("failed to write artifact: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("failed to store artifact: %w", err)
+	}
+
+	return id, nil
+}
+
+func (s *FilesystemStore) Get(ctx context.Context, id string) ([]byte, error) {
+	if !validID(id) {
+		return nil, ErrNotFound
+	}
+
+	encoded, err := os.ReadFile(s.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read artifact: %w", err)
+	}
+
+	return decode(encoded, s.cipher)
+}
+
+func (s *FilesystemStore) Delete(ctx context.Context, id string) error {
+	if !validID(id) {
+		return ErrNotFound
+	}
+
+	err := os.Remove(s.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// Prune deletes the artifacts whose files were last written before before
+func (s *FilesystemStore) Prune(ctx context.Context, before time.Time) (int, error) {
+	n := 0
+	err := filepath.WalkDir(s.root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(d.Name(), ".gz") {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		info, err := d.Info()
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !info.ModTime().Before(before) {
+			return nil
+		}
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		n++
+		return nil
+	})
+	if err != nil {
+		return n, fmt.Errorf("failed to prune artifacts: %w", err)
+	}
+	return n, nil
+}
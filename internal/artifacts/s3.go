@@ -0,0 +1,305 @@
+package artifacts
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/emirozbir/micro-sre/internal/config"
+	"github.com/emirozbir/micro-sre/internal/encryption"
+)
+
+// S3Store keeps artifacts in an S3-compatible bucket (AWS S3, MinIO, Ceph)
+// using plain SigV4-signed HTTP requests
+type S3Store struct {
+	endpoint     *url.URL
+	bucket       string
+	prefix       string
+	region       string
+	accessKey    string
+	secretKey    string
+	usePathStyle bool
+	client       *http.Client
+	cipher       *encryption.Cipher
+}
+
+func NewS3Store(cfg config.S3Config) (*S3Store, error) {
+	if cfg.Bucket == "" {
+		return nil, fmt.Errorf("artifacts.s3.bucket must be set for the s3 backend")
+	}
+	if cfg.AccessKeyID == "" || cfg.SecretAccessKey == "" {
+		return nil, fmt.Errorf("artifacts.s3 credentials not configured")
+	}
+
+	region := cfg.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	endpoint := cfg.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", region)
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid artifacts.s3.endpoint: %w", err)
+	}
+
+	return &S3Store{
+		endpoint:     u,
+		bucket:       cfg.Bucket,
+		prefix:       strings.Trim(cfg.Prefix, "/"),
+		region:       region,
+		accessKey:    cfg.AccessKeyID,
+		secretKey:    cfg.SecretAccessKey,
+		usePathStyle: cfg.UsePathStyle,
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}, nil
+}
+
+// keyPrefix is the part of object keys before the artifact ID
+func (s *S3Store) keyPrefix() string {
+	if s.prefix == "" {
+		return ""
+	}
+	return s.prefix + "/"
+}
+
+// objectURL is the URL of the object with key, or of the bucket if key is
+// empty
+func (s *S3Store) objectURL(key string) *url.URL {
+	u := *s.endpoint
+	if s.usePathStyle {
+		u.Path = "/" + s.bucket + "/" + key
+	} else {
+		u.Host = s.bucket + "." + u.Host
+		u.Path = "/" + key
+	}
+	return &u
+}
+
+func (s *S3Store) artifactURL(id string) *url.URL {
+	return s.objectURL(s.keyPrefix() + id + ".gz")
+}
+
+// Put uploads the artifact even if the bucket holds it, which refreshes
+// the LastModified time Prune goes by
+func (s *S3Store) Put(ctx context.Context, data []byte) (string, error) {
+	id := ID(data)
+
+	encoded, err := encode(data, s.cipher)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := s.do(ctx, http.MethodPut, s.artifactURL(id), encoded)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", s.responseError(resp)
+	}
+	return id, nil
+}
+
+func (s *S3Store) Get(ctx context.Context, id string) ([]byte, error) {
+	if !validID(id) {
+		return nil, ErrNotFound
+	}
+
+	resp, err := s.do(ctx, http.MethodGet, s.artifactURL(id), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, s.responseError(resp)
+	}
+
+	encoded, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read artifact: %w", err)
+	}
+	return decode(encoded, s.cipher)
+}
+
+func (s *S3Store) Delete(ctx context.Context, id string) error {
+	if !validID(id) {
+		return ErrNotFound
+	}
+
+	resp, err := s.do(ctx, http.MethodDelete, s.artifactURL(id), nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return s.responseError(resp)
+	}
+	return nil
+}
+
+// listBucketResult is the part of a ListObjectsV2 response Prune reads
+type listBucketResult struct {
+	Contents []struct {
+		Key          string    `xml:"Key"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// Prune deletes the artifacts last modified before before, listing the
+// objects under the prefix a page at a time
+func (s *S3Store) Prune(ctx context.Context, before time.Time) (int, error) {
+	n := 0
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {s.keyPrefix()}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		u := s.objectURL("")
+		// Encode sorts the parameters, as the signature requires
+		u.RawQuery = query.Encode()
+
+		page, err := s.list(ctx, u)
+		if err != nil {
+			return n, err
+		}
+		for _, object := range page.Contents {
+			id := strings.TrimSuffix(strings.TrimPrefix(object.Key, s.keyPrefix()), ".gz")
+			if !validID(id) || !object.LastModified.Before(before) {
+				continue
+			}
+			if err := s.Delete(ctx, id); err != nil {
+				return n, fmt.Errorf("failed to delete artifact %s: %w", id, err)
+			}
+			n++
+		}
+
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			return n, nil
+		}
+		token = page.NextContinuationToken
+	}
+}
+
+func (s *S3Store) list(ctx context.Context, u *url.URL) (*listBucketResult, error) {
+	resp, err := s.do(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, s.responseError(resp)
+	}
+	var page listBucketResult
+	if err := xml.NewDecoder(resp.Body).Decode(&page); err != nil {
+		return nil, fmt.Errorf("failed to decode s3 object list: %w", err)
+	}
+	return &page, nil
+}
+
+func (s *S3Store) responseError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("s3 returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+}
+
+func (s *S3Store) do(ctx context.Context, method string, u *url.URL, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create s3 request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/gzip")
+	}
+	s.sign(req, body, time.Now().UTC())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("s3 request failed: %w", err)
+	}
+	return resp, nil
+}
+
+// sign adds an AWS Signature Version 4 Authorization header to req
+func (s *S3Store) sign(req *http.Request, body []byte, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("Host", req.URL.Host)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	signedHeaders := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+	if req.Header.Get("Content-Type") != "" {
+		signedHeaders = []string{"content-type", "host", "x-amz-content-sha256", "x-amz-date"}
+	}
+
+	var canonicalHeaders strings.Builder
+	for _, h := range signedHeaders {
+		value := req.Header.Get(h)
+		if h == "host" {
+			value = req.URL.Host
+		}
+		canonicalHeaders.WriteString(h + ":" + strings.TrimSpace(value) + "\n")
+	}
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		strings.Join(signedHeaders, ";"),
+		payloadHash,
+	}, "\n")
+
+	scope := fmt.Sprintf("%s/%s/s3/aws4_request", date, s.region)
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.secretKey), date)
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.accessKey, scope, strings.Join(signedHeaders, ";"), signature))
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
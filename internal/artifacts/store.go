@@ -0,0 +1,116 @@
+package artifacts
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/emirozbir/micro-sre/internal/config"
+	"github.com/emirozbir/micro-sre/internal/encryption"
+)
+
+// ErrNotFound is returned when an artifact does not exist in the store
+var ErrNotFound = errors.New("artifact not found")
+
+// Store keeps large collected blobs (full logs, event lists, pod specs)
+// outside the database. Artifacts are content-addressed and gzip-compressed
+// at rest, then sealed when an encryption key is configured; callers always
+// see the plain bytes. Putting bytes the
+// store already holds refreshes their age, so Prune, which deletes the
+// artifacts last written before a time, spares the ones still in use.
+type Store interface {
+	Put(ctx context.Context, data []byte) (string, error)
+	Get(ctx context.Context, id string) ([]byte, error)
+	Delete(ctx context.Context, id string) error
+	Prune(ctx context.Context, before time.Time) (int, error)
+}
+
+// NewStore returns the store selected by artifacts.backend, sealing
+// artifacts with cipher unless it is nil, or nil if artifact storage is
+// disabled
+func NewStore(cfg config.ArtifactsConfig, cipher *encryption.Cipher) (Store, error) {
+	switch cfg.Backend {
+	case "":
+		return nil, nil
+	case "filesystem":
+		s, err := NewFilesystemStore(cfg.Path)
+		if err != nil {
+			return nil, err
+		}
+		s.cipher = cipher
+		return s, nil
+	case "s3":
+		s, err := NewS3Store(cfg.S3)
+		if err != nil {
+			return nil, err
+		}
+		s.cipher = cipher
+		return s, nil
+	default:
+		return nil, fmt.Errorf("unknown artifacts backend: %s", cfg.Backend)
+	}
+}
+
+// ID returns the content address of data
+func ID(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return nil, fmt.Errorf("failed to compress artifact: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress artifact: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// encode compresses an artifact and seals it if cipher is not nil
+func encode(data []byte, cipher *encryption.Cipher) ([]byte, error) {
+	compressed, err := compress(data)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.Seal(compressed), nil
+}
+
+// decode opens an artifact, sealed or not, and decompresses it
+func decode(data []byte, cipher *encryption.Cipher) ([]byte, error) {
+	compressed, err := cipher.Open(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read artifact: %w", err)
+	}
+	return decompress(compressed)
+}
+
+func decompress(data []byte) ([]byte, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress artifact: %w", err)
+	}
+	defer zr.Close()
+
+	out, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress artifact: %w", err)
+	}
+	return out, nil
+}
+
+func validID(id string) bool {
+	if len(id) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil
+}
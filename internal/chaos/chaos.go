@@ -0,0 +1,163 @@
+// Package chaos injects simulated latency and failures into collector and
+// LLM calls, so operators can check how the server degrades (partial
+// results, retries, webhook backoff) before a real outage does it for them.
+// It must never be enabled in production.
+package chaos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/emirozbir/micro-sre/internal/collectors"
+	"github.com/emirozbir/micro-sre/internal/config"
+	"github.com/emirozbir/micro-sre/internal/llm"
+)
+
+// Fault targets besides the collector data source names
+const (
+	TargetLLM = "llm"
+	TargetAll = "*"
+)
+
+var errSimulated = errors.New("simulated error")
+
+// Injector applies the configured faults. A nil or disabled Injector never
+// injects anything. It is safe for concurrent use.
+type Injector struct {
+	enabled bool
+
+	mu     sync.RWMutex
+	faults []config.ChaosFault
+}
+
+// New returns an injector with the configured faults, or an error if a
+// fault is invalid
+func New(cfg config.ChaosConfig) (*Injector, error) {
+	i := &Injector{enabled: cfg.Enabled}
+	if err := i.SetFaults(cfg.Faults); err != nil {
+		return nil, err
+	}
+	return i, nil
+}
+
+// Enabled reports whether chaos.enabled is set
+func (i *Injector) Enabled() bool {
+	return i != nil && i.enabled
+}
+
+// Faults returns the active faults
+func (i *Injector) Faults() []config.ChaosFault {
+	if i == nil {
+		return nil
+	}
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return append([]config.ChaosFault{}, i.faults...)
+}
+
+// SetFaults validates and replaces the active faults
+func (i *Injector) SetFaults(faults []config.ChaosFault) error {
+	for _, f := range faults {
+		if err := validate(f); err != nil {
+			return err
+		}
+	}
+	i.mu.Lock()
+	i.faults = append([]config.ChaosFault(nil), faults...)
+	i.mu.Unlock()
+	return nil
+}
+
+func validate(f config.ChaosFault) error {
+	if _, ok := collectors.SourceVersions[f.Target]; !ok && f.Target != TargetLLM && f.Target != TargetAll {
+		return fmt.Errorf("unknown chaos target %q", f.Target)
+	}
+	if f.ErrorRate < 0 || f.ErrorRate > 1 {
+		return fmt.Errorf("chaos target %q: error_rate must be between 0 and 1", f.Target)
+	}
+	if f.Latency < 0 {
+		return fmt.Errorf("chaos target %q: latency must not be negative", f.Target)
+	}
+	if f.ErrorRate > 0 && faultError(f.ErrorClass) == nil {
+		return fmt.Errorf("chaos target %q: unknown error_class %q", f.Target, f.ErrorClass)
+	}
+	return nil
+}
+
+// Inject delays a call to target by the latency of its faults and fails it
+// with their error rate. It returns nil when nothing is injected, and
+// ctx.Err() if the context is done during the delay.
+func (i *Injector) Inject(ctx context.Context, target string) error {
+	if !i.Enabled() {
+		return nil
+	}
+
+	i.mu.RLock()
+	var matched []config.ChaosFault
+	for _, f := range i.faults {
+		if f.Target == target || (f.Target == TargetAll && target != TargetLLM) {
+			matched = append(matched, f)
+		}
+	}
+	i.mu.RUnlock()
+
+	for _, f := range matched {
+		if f.Latency > 0 {
+			select {
+			case <-time.After(f.Latency):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		if f.ErrorRate > 0 && rand.Float64() < f.ErrorRate {
+			return fmt.Errorf("chaos: injected failure into %s: %w", target, faultError(f.ErrorClass))
+		}
+	}
+	return nil
+}
+
+// faultError returns an error that collectors.ClassifyError puts in class,
+// so injected failures take the same paths as real ones, or nil for an
+// unknown class. An empty class means unknown.
+func faultError(class string) error {
+	switch class {
+	case collectors.ErrorTimeout:
+		return context.DeadlineExceeded
+	case collectors.ErrorConnectionRefused:
+		return syscall.ECONNREFUSED
+	case collectors.ErrorForbidden:
+		return &collectors.StatusError{Service: "chaos", Code: http.StatusForbidden}
+	case collectors.ErrorNotFound:
+		return collectors.ErrNotFound
+	case collectors.ErrorUnknown, "":
+		return errSimulated
+	}
+	return nil
+}
+
+// WrapClient injects the "llm" faults into an LLM client. It returns c
+// unchanged if chaos is disabled.
+func (i *Injector) WrapClient(c llm.Client) llm.Client {
+	if !i.Enabled() {
+		return c
+	}
+	return &chaosClient{Client: c, injector: i}
+}
+
+type chaosClient struct {
+	llm.Client
+	injector *Injector
+}
+
+func (c *chaosClient) Analyze(ctx context.Context, prompt string) (string, error) {
+	if err := c.injector.Inject(ctx, TargetLLM); err != nil {
+		return "", err
+	}
+	return c.Client.Analyze(ctx, prompt)
+}
@@ -0,0 +1,80 @@
+package collectors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ErrNamespaceNotAllowed is returned for namespaces outside the configured
+// allowlist
+var ErrNamespaceNotAllowed = errors.New("namespace is not allowed")
+
+// Viewer identifies the person a request is served for. An empty User means
+// the request is checked against the server's own permissions.
+type Viewer struct {
+	User   string
+	Groups []string
+}
+
+// NamespaceAllowed reports whether the namespace matches the configured
+// allowlist. Entries are glob patterns; an empty allowlist allows all.
+func (k *KubernetesCollector) NamespaceAllowed(namespace string) bool {
+	allowed := k.config.Kubernetes.AllowedNamespaces
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, pattern := range allowed {
+		if ok, _ := path.Match(pattern, namespace); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// CanListPods checks whether the viewer may list pods in the namespace.
+// Requests without a proxied user are served with the server's own
+// permissions, which the list call itself enforces.
+func (k *KubernetesCollector) CanListPods(ctx context.Context, viewer Viewer, namespace string) (bool, error) {
+	if viewer.User == "" {
+		return true, nil
+	}
+	allowed, _, err := k.reviewAccess(ctx, viewer, &authorizationv1.ResourceAttributes{
+		Namespace: namespace,
+		Verb:      "list",
+		Resource:  "pods",
+	})
+	return allowed, err
+}
+
+// reviewAccess asks the API server whether the viewer may perform the
+// action. Viewers supplied by an authenticating proxy are checked with a
+// SubjectAccessReview, anonymous viewers with a SelfSubjectAccessReview of
+// the server's service account.
+func (k *KubernetesCollector) reviewAccess(ctx context.Context, viewer Viewer, attrs *authorizationv1.ResourceAttributes) (bool, string, error) {
+	if viewer.User == "" {
+		review, err := k.clientset.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, &authorizationv1.SelfSubjectAccessReview{
+			Spec: authorizationv1.SelfSubjectAccessReviewSpec{ResourceAttributes: attrs},
+		}, metav1.CreateOptions{})
+		if err != nil {
+			return false, "", fmt.Errorf("failed to review access: %w", err)
+		}
+		return review.Status.Allowed, review.Status.Reason, nil
+	}
+
+	review, err := k.clientset.AuthorizationV1().SubjectAccessReviews().Create(ctx, &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			ResourceAttributes: attrs,
+			User:               viewer.User,
+			Groups:             viewer.Groups,
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return false, "", fmt.Errorf("failed to review access: %w", err)
+	}
+	return review.Status.Allowed, review.Status.Reason, nil
+}
@@ -0,0 +1,212 @@
+package collectors
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/emirozbir/micro-sre/internal/models"
+)
+
+// podSecurityLabelPrefix is the prefix of the Pod Security Admission
+// namespace labels (enforce, audit, warn and their -version variants)
+const podSecurityLabelPrefix = "pod-security.kubernetes.io/"
+
+// securityContextHints identify container creation errors caused by the
+// pod or container security context
+var securityContextHints = []string{
+	"runasnonroot", "run as root", "non-numeric user", "privileged",
+	"capabilit", "seccomp", "apparmor", "selinux", "securitycontext",
+}
+
+// GetAdmissionContext looks for reasons pods of the workload could not be
+// created or started: FailedCreate events on the owning controllers (Pod
+// Security Admission, webhook denials, quota) and security-context errors
+// on the pod's containers. pod is nil when the pod does not exist, in which
+// case controllers are matched by podName. It returns nil when no failure
+// is found.
+func (k *KubernetesCollector) GetAdmissionContext(ctx context.Context, namespace, podName string, pod *corev1.Pod, since time.Time) (*models.AdmissionContext, error) {
+	admission, err := k.admissionContext(ctx, namespace, controllerMatcher(podName, pod), since)
+	if err != nil {
+		return nil, err
+	}
+	if pod == nil {
+		if admission != nil {
+			admission.PodMissing = true
+		}
+		return admission, nil
+	}
+
+	failures := securityContextFailures(pod)
+	if len(failures) == 0 {
+		return admission, nil
+	}
+	if admission == nil {
+		admission = &models.AdmissionContext{}
+	}
+	admission.Failures = append(failures, admission.Failures...)
+	return admission, nil
+}
+
+// GetWorkloadAdmissionContext returns the admission failures recorded on a
+// workload and the ReplicaSets it created
+func (k *KubernetesCollector) GetWorkloadAdmissionContext(ctx context.Context, namespace, name string, since time.Time) (*models.AdmissionContext, error) {
+	return k.admissionContext(ctx, namespace, func(object string) bool {
+		return object == name || strings.HasPrefix(object, name+"-")
+	}, since)
+}
+
+func (k *KubernetesCollector) admissionContext(ctx context.Context, namespace string, matches func(string) bool, since time.Time) (*models.AdmissionContext, error) {
+	k.progress.Update(fmt.Sprintf("Checking admission failures in namespace %s...", namespace))
+
+	events, cached := k.cachedNamespaceEvents(namespace)
+	if cached {
+		events = filterEventsByReason(events, "FailedCreate")
+	} else {
+		eventList, err := k.clientset.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{
+			FieldSelector: "reason=FailedCreate",
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get FailedCreate events: %w", err)
+		}
+		events = eventList.Items
+	}
+
+	admission := &models.AdmissionContext{}
+	for _, event := range events {
+		ts := eventTime(event)
+		if ts.Before(since) || !matches(event.InvolvedObject.Name) {
+			continue
+		}
+		admission.Failures = append(admission.Failures, models.AdmissionFailure{
+			Kind:     event.InvolvedObject.Kind,
+			Name:     event.InvolvedObject.Name,
+			Category: classifyAdmissionMessage(event.Message),
+			Reason:   event.Reason,
+			Message:  event.Message,
+			Count:    event.Count,
+			LastSeen: ts,
+		})
+	}
+
+	if len(admission.Failures) == 0 {
+		return nil, nil
+	}
+	sort.Slice(admission.Failures, func(i, j int) bool {
+		return admission.Failures[i].LastSeen.After(admission.Failures[j].LastSeen)
+	})
+
+	ns, err := k.clientset.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{})
+	if err != nil {
+		// The failures are still useful without the namespace policy
+		admission.PodSecurityError = err.Error()
+	} else {
+		for key, value := range ns.Labels {
+			if strings.HasPrefix(key, podSecurityLabelPrefix) {
+				if admission.PodSecurity == nil {
+					admission.PodSecurity = map[string]string{}
+				}
+				admission.PodSecurity[strings.TrimPrefix(key, podSecurityLabelPrefix)] = value
+			}
+		}
+	}
+
+	return admission, nil
+}
+
+// controllerMatcher returns a predicate selecting events about the
+// controllers that own (or would own) the pod. For an existing pod these are
+// its controller, sibling ReplicaSets of the same Deployment and the
+// workload itself; for a missing pod, controllers whose generated pod names
+// would prefix podName.
+func controllerMatcher(podName string, pod *corev1.Pod) func(string) bool {
+	if pod == nil {
+		return func(name string) bool {
+			return strings.HasPrefix(podName, name+"-")
+		}
+	}
+
+	owner := metav1.GetControllerOf(pod)
+	if owner == nil {
+		return func(string) bool { return false }
+	}
+	base := owner.Name
+	if owner.Kind == "ReplicaSet" {
+		if i := strings.LastIndex(base, "-"); i > 0 {
+			base = base[:i]
+		}
+	}
+	return func(name string) bool {
+		return name == owner.Name || name == base || strings.HasPrefix(name, base+"-")
+	}
+}
+
+// securityContextFailures reports containers that cannot be created because
+// of their security context, e.g. runAsNonRoot with an image running as root
+func securityContextFailures(pod *corev1.Pod) []models.AdmissionFailure {
+	var failures []models.AdmissionFailure
+	statuses := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+	for _, status := range statuses {
+		waiting := status.State.Waiting
+		if waiting == nil || (waiting.Reason != "CreateContainerConfigError" && waiting.Reason != "CreateContainerError") {
+			continue
+		}
+		if classifyAdmissionMessage(waiting.Message) != models.AdmissionSecurityContext {
+			continue
+		}
+		failures = append(failures, models.AdmissionFailure{
+			Kind:     "Container",
+			Name:     status.Name,
+			Category: models.AdmissionSecurityContext,
+			Reason:   waiting.Reason,
+			Message:  waiting.Message,
+			Count:    1,
+			LastSeen: time.Now(),
+		})
+	}
+	return failures
+}
+
+func classifyAdmissionMessage(message string) string {
+	lower := strings.ToLower(message)
+	switch {
+	case strings.Contains(lower, "violates podsecurity"):
+		return models.AdmissionPodSecurity
+	case strings.Contains(lower, "admission webhook") && strings.Contains(lower, "denied"):
+		return models.AdmissionWebhook
+	case strings.Contains(lower, "exceeded quota"), strings.Contains(lower, "must specify limits"), strings.Contains(lower, "must specify requests"):
+		return models.AdmissionQuota
+	}
+	for _, hint := range securityContextHints {
+		if strings.Contains(lower, hint) {
+			return models.AdmissionSecurityContext
+		}
+	}
+	return models.AdmissionOther
+}
+
+func filterEventsByReason(events []corev1.Event, reason string) []corev1.Event {
+	var out []corev1.Event
+	for _, event := range events {
+		if event.Reason == reason {
+			out = append(out, event)
+		}
+	}
+	return out
+}
+
+// eventTime returns the most recent timestamp recorded on the event
+func eventTime(event corev1.Event) time.Time {
+	if !event.LastTimestamp.IsZero() {
+		return event.LastTimestamp.Time
+	}
+	if event.Series != nil && !event.Series.LastObservedTime.IsZero() {
+		return event.Series.LastObservedTime.Time
+	}
+	return event.EventTime.Time
+}
@@ -0,0 +1,95 @@
+package collectors
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/emirozbir/micro-sre/internal/config"
+	"github.com/emirozbir/micro-sre/internal/models"
+)
+
+// AlertFilter decides which alerts are analyzed, from alert_filters
+type AlertFilter struct {
+	allow []alertFilterRule
+	deny  []alertFilterRule
+}
+
+type alertFilterRule struct {
+	config.AlertFilterRule
+	matchers AlertMatchers
+}
+
+// NewAlertFilter compiles the rules of cfg. The zero config keeps every
+// alert.
+func NewAlertFilter(cfg config.AlertFilterConfig) (*AlertFilter, error) {
+	compile := func(kind string, rules []config.AlertFilterRule) ([]alertFilterRule, error) {
+		compiled := make([]alertFilterRule, 0, len(rules))
+		for i, rule := range rules {
+			for _, patterns := range [][]string{rule.Namespaces, rule.AlertNames, rule.Severities} {
+				for _, pattern := range patterns {
+					if _, err := path.Match(pattern, ""); err != nil {
+						return nil, fmt.Errorf("alert_filters.%s[%d]: invalid pattern %q: %w", kind, i, pattern, err)
+					}
+				}
+			}
+			matchers, err := ParseAlertMatchers(rule.Matchers)
+			if err != nil {
+				return nil, fmt.Errorf("alert_filters.%s[%d]: %w", kind, i, err)
+			}
+			compiled = append(compiled, alertFilterRule{AlertFilterRule: rule, matchers: matchers})
+		}
+		return compiled, nil
+	}
+
+	allow, err := compile("allow", cfg.Allow)
+	if err != nil {
+		return nil, err
+	}
+	deny, err := compile("deny", cfg.Deny)
+	if err != nil {
+		return nil, err
+	}
+	return &AlertFilter{allow: allow, deny: deny}, nil
+}
+
+// Reject returns why an alert is filtered out, or "" if it is analyzed
+func (f *AlertFilter) Reject(alert *models.Alert) string {
+	if f == nil {
+		return ""
+	}
+	for i, rule := range f.deny {
+		if rule.matches(alert) {
+			return fmt.Sprintf("matches alert_filters.deny[%d]", i)
+		}
+	}
+	if len(f.allow) == 0 {
+		return ""
+	}
+	for _, rule := range f.allow {
+		if rule.matches(alert) {
+			return ""
+		}
+	}
+	return "matches no alert_filters.allow rule"
+}
+
+func (r alertFilterRule) matches(alert *models.Alert) bool {
+	return matchAny(r.Namespaces, alert.GetNamespace()) &&
+		matchAny(r.AlertNames, alert.GetAlertName()) &&
+		matchAny(r.Severities, alert.GetSeverity()) &&
+		r.matchers.Matches(alert.Labels)
+}
+
+// matchAny reports whether value matches one of the glob patterns, or
+// there are none
+func matchAny(patterns []string, value string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, value); ok {
+			return true
+		}
+	}
+	return false
+}
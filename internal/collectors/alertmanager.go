@@ -2,9 +2,15 @@ package collectors
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/emirozbir/micro-sre/internal/config"
@@ -14,28 +20,94 @@ import (
 type AlertManagerCollector struct {
 	baseURL string
 	client  *http.Client
+	auth    config.AlertManagerAuthConfig
 }
 
-func NewAlertManagerCollector(cfg *config.Config) *AlertManagerCollector {
+func NewAlertManagerCollector(cfg *config.Config) (*AlertManagerCollector, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	tlsConfig, err := alertManagerTLSConfig(cfg.AlertManager.Auth.TLS)
+	if err != nil {
+		return nil, err
+	}
+	transport.TLSClientConfig = tlsConfig
+
 	return &AlertManagerCollector{
-		baseURL: cfg.AlertManager.URL,
+		baseURL: strings.TrimSuffix(cfg.AlertManager.URL, "/"),
 		client: &http.Client{
-			Timeout: 10 * time.Second,
+			Timeout:   10 * time.Second,
+			Transport: transport,
 		},
+		auth: cfg.AlertManager.Auth,
+	}, nil
+}
+
+// alertManagerTLSConfig builds the client TLS configuration, including the
+// client certificate used for mTLS when configured
+func alertManagerTLSConfig(cfg config.TLSClientConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+	}
+
+	if cfg.CAFile != "" {
+		caCert, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read alertmanager CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("no valid certificates in alertmanager CA file %s", cfg.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if cfg.CertFile != "" || cfg.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load alertmanager client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
 	}
+
+	return tlsConfig, nil
+}
+
+// newRequest creates a GET request against the AlertManager API with the
+// configured credentials attached
+func (a *AlertManagerCollector) newRequest(ctx context.Context, url string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	switch {
+	case a.auth.BearerToken != "":
+		req.Header.Set("Authorization", "Bearer "+a.auth.BearerToken)
+	case a.auth.BearerTokenFile != "":
+		// Re-read on every request so rotated tokens are picked up
+		token, err := os.ReadFile(a.auth.BearerTokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read alertmanager bearer token file: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	case a.auth.Username != "":
+		req.SetBasicAuth(a.auth.Username, a.auth.Password)
+	}
+
+	return req, nil
 }
 
 type AlertManagerResponse struct {
-	Status string          `json:"status"`
-	Data   []models.Alert  `json:"data"`
+	Status string         `json:"status"`
+	Data   []models.Alert `json:"data"`
 }
 
 func (a *AlertManagerCollector) GetAlerts(ctx context.Context) ([]models.Alert, error) {
 	url := fmt.Sprintf("%s/api/v2/alerts", a.baseURL)
 
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	req, err := a.newRequest(ctx, url)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, err
 	}
 
 	resp, err := a.client.Do(req)
@@ -45,17 +117,57 @@ func (a *AlertManagerCollector) GetAlerts(ctx context.Context) ([]models.Alert,
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("alertmanager returned status %d", resp.StatusCode)
+		return nil, &StatusError{Service: "alertmanager", Code: resp.StatusCode}
 	}
 
-	var alerts []models.Alert
-	if err := json.NewDecoder(resp.Body).Decode(&alerts); err != nil {
+	var gettable []amAlert
+	if err := json.NewDecoder(resp.Body).Decode(&gettable); err != nil {
 		return nil, fmt.Errorf("failed to decode alerts: %w", err)
 	}
 
+	alerts := make([]models.Alert, 0, len(gettable))
+	for _, g := range gettable {
+		alerts = append(alerts, g.toAlert())
+	}
+
 	return alerts, nil
 }
 
+// amAlert mirrors the gettableAlert object of the AlertManager v2 API, whose
+// status is an object rather than the plain string used in webhooks
+type amAlert struct {
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations"`
+	StartsAt     time.Time         `json:"startsAt"`
+	EndsAt       time.Time         `json:"endsAt"`
+	Fingerprint  string            `json:"fingerprint"`
+	GeneratorURL string            `json:"generatorURL"`
+	Status       struct {
+		State       string   `json:"state"`
+		SilencedBy  []string `json:"silencedBy"`
+		InhibitedBy []string `json:"inhibitedBy"`
+	} `json:"status"`
+}
+
+func (g amAlert) toAlert() models.Alert {
+	// The v2 API reports firing alerts as "active"; normalize to the webhook
+	// vocabulary so callers can treat both sources the same way
+	status := g.Status.State
+	if status == "active" {
+		status = "firing"
+	}
+
+	return models.Alert{
+		Labels:       g.Labels,
+		Annotations:  g.Annotations,
+		StartsAt:     g.StartsAt,
+		EndsAt:       g.EndsAt,
+		Status:       status,
+		Fingerprint:  g.Fingerprint,
+		GeneratorURL: g.GeneratorURL,
+	}
+}
+
 func (a *AlertManagerCollector) GetActiveAlerts(ctx context.Context) ([]models.Alert, error) {
 	alerts, err := a.GetAlerts(ctx)
 	if err != nil {
@@ -87,3 +199,201 @@ func (a *AlertManagerCollector) GetAlertsByNamespace(ctx context.Context, namesp
 
 	return filtered, nil
 }
+
+// amSilence mirrors the gettableSilence object of the AlertManager v2 API
+type amSilence struct {
+	ID       string      `json:"id"`
+	Matchers []amMatcher `json:"matchers"`
+	StartsAt time.Time   `json:"startsAt"`
+	EndsAt   time.Time   `json:"endsAt"`
+	Status   struct {
+		State string `json:"state"`
+	} `json:"status"`
+	CreatedBy string `json:"createdBy"`
+	Comment   string `json:"comment"`
+}
+
+type amMatcher struct {
+	Name    string `json:"name"`
+	Value   string `json:"value"`
+	IsRegex bool   `json:"isRegex"`
+	IsEqual *bool  `json:"isEqual,omitempty"`
+}
+
+func (m amMatcher) matches(labels map[string]string) bool {
+	value := labels[m.Name]
+
+	matched := value == m.Value
+	if m.IsRegex {
+		re, err := regexp.Compile("^(?:" + m.Value + ")$")
+		if err != nil {
+			return false
+		}
+		matched = re.MatchString(value)
+	}
+
+	// isEqual was added in AlertManager 0.22 and defaults to true
+	if m.IsEqual != nil && !*m.IsEqual {
+		return !matched
+	}
+	return matched
+}
+
+func (s amSilence) matches(labels map[string]string) bool {
+	if len(s.Matchers) == 0 {
+		return false
+	}
+	for _, m := range s.Matchers {
+		if !m.matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+// GetSilencesForLabels returns the silences whose matchers select the given
+// label set. Active silences are always returned; expired silences are only
+// returned if they ended after since, so the caller can report that an alert
+// "was silenced" during the analysis window.
+func (a *AlertManagerCollector) GetSilencesForLabels(ctx context.Context, labels map[string]string, since time.Time) ([]models.Silence, error) {
+	url := fmt.Sprintf("%s/api/v2/silences", a.baseURL)
+
+	req, err := a.newRequest(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch silences: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &StatusError{Service: "alertmanager", Code: resp.StatusCode}
+	}
+
+	var silences []amSilence
+	if err := json.NewDecoder(resp.Body).Decode(&silences); err != nil {
+		return nil, fmt.Errorf("failed to decode silences: %w", err)
+	}
+
+	var matched []models.Silence
+	for _, s := range silences {
+		switch s.Status.State {
+		case "active":
+		case "expired":
+			if s.EndsAt.Before(since) {
+				continue
+			}
+		default:
+			continue
+		}
+
+		if !s.matches(labels) {
+			continue
+		}
+
+		matched = append(matched, models.Silence{
+			ID:        s.ID,
+			State:     s.Status.State,
+			CreatedBy: s.CreatedBy,
+			Comment:   s.Comment,
+			StartsAt:  s.StartsAt,
+			EndsAt:    s.EndsAt,
+		})
+	}
+
+	return matched, nil
+}
+
+// GetRelatedAlerts returns the firing alerts, other than the one identified by
+// fingerprint, that share the given namespace or carry every group label of
+// the alert's AlertManager group.
+func (a *AlertManagerCollector) GetRelatedAlerts(ctx context.Context, fingerprint, namespace string, groupLabels map[string]string) ([]models.Alert, error) {
+	alerts, err := a.GetActiveAlerts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var related []models.Alert
+	for _, alert := range alerts {
+		if fingerprint != "" && alert.Fingerprint == fingerprint {
+			continue
+		}
+		if (namespace != "" && alert.GetNamespace() == namespace) || hasLabels(alert.Labels, groupLabels) {
+			related = append(related, alert)
+		}
+	}
+
+	return related, nil
+}
+
+func hasLabels(labels, subset map[string]string) bool {
+	if len(subset) == 0 {
+		return false
+	}
+	for k, v := range subset {
+		if labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// AlertMatchers selects alerts by label, all matchers having to match
+type AlertMatchers []amMatcher
+
+// Matches reports whether every matcher selects the label set
+func (m AlertMatchers) Matches(labels map[string]string) bool {
+	for _, matcher := range m {
+		if !matcher.matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+// matcherOperators are the AlertManager matcher operators, longest first
+var matcherOperators = []string{"!=", "=~", "!~", "="}
+
+// ParseAlertMatchers parses AlertManager-style matchers such as
+// `severity=~"critical|warning"` or `namespace!=kube-system`
+func ParseAlertMatchers(matchers []string) (AlertMatchers, error) {
+	parsed := make(AlertMatchers, 0, len(matchers))
+	for _, s := range matchers {
+		m, err := parseAlertMatcher(s)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, m)
+	}
+	return parsed, nil
+}
+
+func parseAlertMatcher(s string) (amMatcher, error) {
+	for _, op := range matcherOperators {
+		i := strings.Index(s, op)
+		if i <= 0 {
+			continue
+		}
+		name := strings.TrimSpace(s[:i])
+		value := strings.TrimSpace(s[i+len(op):])
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		}
+
+		m := amMatcher{Name: name, Value: value, IsRegex: strings.HasSuffix(op, "~")}
+		if strings.HasPrefix(op, "!") {
+			isEqual := false
+			m.IsEqual = &isEqual
+		}
+		if m.IsRegex {
+			if _, err := regexp.Compile("^(?:" + value + ")$"); err != nil {
+				return amMatcher{}, fmt.Errorf("invalid matcher %q: %w", s, err)
+			}
+		}
+		return m, nil
+	}
+	return amMatcher{}, fmt.Errorf("invalid matcher %q: expected name=value, name!=value, name=~regex or name!~regex", s)
+}
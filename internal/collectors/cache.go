@@ -0,0 +1,138 @@
+package collectors
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/emirozbir/micro-sre/internal/config"
+)
+
+// eventObjectIndex indexes events by the object they are about
+const eventObjectIndex = "involvedObject"
+
+// informerCache serves pod and event reads from shared informers so that
+// bursts of analyses do not each issue fresh GET/LIST calls. Lookups report
+// a miss until the informers have synced, and callers fall back to the API.
+type informerCache struct {
+	scopes map[string]*informerScope
+	stopCh chan struct{}
+}
+
+type informerScope struct {
+	pods   cache.SharedIndexInformer
+	events cache.SharedIndexInformer
+}
+
+func newInformerCache(clientset kubernetes.Interface, cfg config.KubernetesCacheConfig) (*informerCache, error) {
+	namespaces := cfg.Namespaces
+	if len(namespaces) == 0 {
+		namespaces = []string{metav1.NamespaceAll}
+	}
+
+	c := &informerCache{
+		scopes: make(map[string]*informerScope, len(namespaces)),
+		stopCh: make(chan struct{}),
+	}
+
+	for _, ns := range namespaces {
+		factory := informers.NewSharedInformerFactoryWithOptions(clientset, cfg.Resync, informers.WithNamespace(ns))
+
+		scope := &informerScope{
+			pods:   factory.Core().V1().Pods().Informer(),
+			events: factory.Core().V1().Events().Informer(),
+		}
+		if err := scope.events.AddIndexers(cache.Indexers{eventObjectIndex: indexEventByObject}); err != nil {
+			return nil, fmt.Errorf("failed to add event index: %w", err)
+		}
+		// Managed fields are never used and dominate the size of cached objects
+		for _, inf := range []cache.SharedIndexInformer{scope.pods, scope.events} {
+			if err := inf.SetTransform(stripManagedFields); err != nil {
+				return nil, fmt.Errorf("failed to set informer transform: %w", err)
+			}
+		}
+
+		factory.Start(c.stopCh)
+		c.scopes[ns] = scope
+	}
+
+	return c, nil
+}
+
+func (c *informerCache) scope(namespace string) *informerScope {
+	if s, ok := c.scopes[metav1.NamespaceAll]; ok {
+		return s
+	}
+	return c.scopes[namespace]
+}
+
+// getPod returns a copy of the cached pod
+func (c *informerCache) getPod(namespace, name string) (*corev1.Pod, bool) {
+	s := c.scope(namespace)
+	if s == nil || !s.pods.HasSynced() {
+		return nil, false
+	}
+
+	obj, exists, err := s.pods.GetIndexer().GetByKey(namespace + "/" + name)
+	if err != nil || !exists {
+		return nil, false
+	}
+	return obj.(*corev1.Pod).DeepCopy(), true
+}
+
+// objectEvents returns the cached events about the given object
+func (c *informerCache) objectEvents(namespace, kind, name string) ([]corev1.Event, bool) {
+	return c.events(namespace, eventObjectIndex, objectKey(namespace, kind, name))
+}
+
+// namespaceEvents returns all cached events in a namespace
+func (c *informerCache) namespaceEvents(namespace string) ([]corev1.Event, bool) {
+	return c.events(namespace, cache.NamespaceIndex, namespace)
+}
+
+func (c *informerCache) events(namespace, index, key string) ([]corev1.Event, bool) {
+	s := c.scope(namespace)
+	if s == nil || !s.events.HasSynced() {
+		return nil, false
+	}
+
+	objs, err := s.events.GetIndexer().ByIndex(index, key)
+	if err != nil {
+		return nil, false
+	}
+
+	events := make([]corev1.Event, 0, len(objs))
+	for _, obj := range objs {
+		events = append(events, *obj.(*corev1.Event).DeepCopy())
+	}
+	return events, true
+}
+
+func (c *informerCache) stop() {
+	close(c.stopCh)
+}
+
+func indexEventByObject(obj interface{}) ([]string, error) {
+	event, ok := obj.(*corev1.Event)
+	if !ok {
+		return nil, nil
+	}
+	ref := event.InvolvedObject
+	return []string{objectKey(event.Namespace, ref.Kind, ref.Name)}, nil
+}
+
+func objectKey(namespace, kind, name string) string {
+	return namespace + "/" + kind + "/" + name
+}
+
+func stripManagedFields(obj interface{}) (interface{}, error) {
+	if accessor, err := meta.Accessor(obj); err == nil {
+		accessor.SetManagedFields(nil)
+	}
+	return obj, nil
+}
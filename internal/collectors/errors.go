@@ -0,0 +1,82 @@
+package collectors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// Collector error classes. They decide how an analysis reacts to a failed
+// collector: not-found data is skipped, transient failures (timeout,
+// connection refused) are retried, and everything else degrades the
+// analysis to work without that source.
+const (
+	ErrorNotFound          = "not_found"
+	ErrorForbidden         = "forbidden"
+	ErrorTimeout           = "timeout"
+	ErrorConnectionRefused = "connection_refused"
+	ErrorUnknown           = "unknown"
+)
+
+// ErrNotFound marks lookups of objects that do not exist outside the
+// Kubernetes API, such as Prometheus alerting rules
+var ErrNotFound = errors.New("not found")
+
+// StatusError is a non-success HTTP response from AlertManager or Prometheus
+type StatusError struct {
+	Service string
+	Code    int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s returned status %d", e.Service, e.Code)
+}
+
+// ClassifyError returns the class of a collector error, or "" for nil
+func ClassifyError(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	var status *StatusError
+	if errors.As(err, &status) {
+		switch status.Code {
+		case http.StatusNotFound:
+			return ErrorNotFound
+		case http.StatusUnauthorized, http.StatusForbidden:
+			return ErrorForbidden
+		case http.StatusRequestTimeout, http.StatusGatewayTimeout:
+			return ErrorTimeout
+		}
+		return ErrorUnknown
+	}
+
+	var netErr net.Error
+	switch {
+	case errors.Is(err, ErrNotFound), apierrors.IsNotFound(err):
+		return ErrorNotFound
+	case apierrors.IsForbidden(err), apierrors.IsUnauthorized(err):
+		return ErrorForbidden
+	case errors.Is(err, context.DeadlineExceeded), apierrors.IsTimeout(err), apierrors.IsServerTimeout(err):
+		return ErrorTimeout
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return ErrorTimeout
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return ErrorConnectionRefused
+	}
+	return ErrorUnknown
+}
+
+// Retryable reports whether the error is transient and worth another try
+func Retryable(err error) bool {
+	switch ClassifyError(err) {
+	case ErrorTimeout, ErrorConnectionRefused:
+		return true
+	}
+	return false
+}
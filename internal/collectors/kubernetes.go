@@ -4,10 +4,12 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"sort"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
@@ -17,9 +19,13 @@ import (
 )
 
 type KubernetesCollector struct {
-	clientset *kubernetes.Clientset
-	config    *config.Config
-	progress  ui.ProgressReporter
+	clientset     *kubernetes.Clientset
+	restConfig    *rest.Config
+	dynamicClient dynamic.Interface
+	config        *config.Config
+	progress      ui.ProgressReporter
+	// cache is nil unless kubernetes.cache.enabled is set
+	cache *informerCache
 }
 
 // noOpProgress is a default no-op progress reporter
@@ -33,20 +39,14 @@ func NewKubernetesCollector(cfg *config.Config) (*KubernetesCollector, error) {
 	var err error
 
 	if cfg.Kubernetes.Kubeconfig != "" {
-		// Use kubeconfig file
-		k8sConfig, err = clientcmd.BuildConfigFromFlags("", cfg.Kubernetes.Kubeconfig)
+		// Use kubeconfig file, in kubernetes.context if set
+		k8sConfig, err = kubeconfigLoader(cfg).ClientConfig()
 	} else {
 		// Use in-cluster config
 		k8sConfig, err = rest.InClusterConfig()
 		if err != nil {
 			// Fallback to default kubeconfig
-			loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
-			configOverrides := &clientcmd.ConfigOverrides{}
-			if cfg.Kubernetes.Context != "" {
-				configOverrides.CurrentContext = cfg.Kubernetes.Context
-			}
-			k8sConfig, err = clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
-				loadingRules, configOverrides).ClientConfig()
+			k8sConfig, err = kubeconfigLoader(cfg).ClientConfig()
 		}
 	}
 
@@ -54,18 +54,82 @@ func NewKubernetesCollector(cfg *config.Config) (*KubernetesCollector, error) {
 		return nil, fmt.Errorf("failed to create kubernetes config: %w", err)
 	}
 
+	if cfg.ReadOnly {
+		enforceReadOnly(k8sConfig)
+	}
+
 	clientset, err := kubernetes.NewForConfig(k8sConfig)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create kubernetes client: %w", err)
 	}
 
+	dynamicClient, err := dynamic.NewForConfig(k8sConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kubernetes dynamic client: %w", err)
+	}
+
+	var informers *informerCache
+	if cfg.Kubernetes.Cache.Enabled {
+		informers, err = newInformerCache(clientset, cfg.Kubernetes.Cache)
+		if err != nil {
+			return nil, fmt.Errorf("failed to start informer cache: %w", err)
+		}
+	}
+
 	return &KubernetesCollector{
-		clientset: clientset,
-		config:    cfg,
-		progress:  &noOpProgress{},
+		clientset:     clientset,
+		restConfig:    k8sConfig,
+		dynamicClient: dynamicClient,
+		config:        cfg,
+		progress:      &noOpProgress{},
+		cache:         informers,
 	}, nil
 }
 
+// DefaultNamespace returns the namespace kubectl would use with the
+// kubeconfig the collector connects with: the namespace of the current
+// context, the pod's own namespace in-cluster, or "default"
+func DefaultNamespace(cfg *config.Config) (string, error) {
+	namespace, _, err := kubeconfigLoader(cfg).Namespace()
+	if err != nil {
+		return "", fmt.Errorf("failed to read the current namespace: %w", err)
+	}
+	return namespace, nil
+}
+
+// kubeconfigLoader loads kubernetes.kubeconfig, or else the kubeconfig
+// kubectl uses ($KUBECONFIG or ~/.kube/config), in kubernetes.context if
+// set, or else its current context
+func kubeconfigLoader(cfg *config.Config) clientcmd.ClientConfig {
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	loadingRules.ExplicitPath = cfg.Kubernetes.Kubeconfig
+	configOverrides := &clientcmd.ConfigOverrides{}
+	if cfg.Kubernetes.Context != "" {
+		configOverrides.CurrentContext = cfg.Kubernetes.Context
+	}
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, configOverrides)
+}
+
+// WithConfig returns a collector with cfg that shares the connection and
+// informer cache of k, for reloading settings such as allowed_namespaces
+func (k *KubernetesCollector) WithConfig(cfg *config.Config) *KubernetesCollector {
+	reconfigured := *k
+	reconfigured.config = cfg
+	return &reconfigured
+}
+
+// Ping checks that the API server is reachable and ready
+func (k *KubernetesCollector) Ping(ctx context.Context) error {
+	return k.clientset.Discovery().RESTClient().Get().AbsPath("/readyz").Do(ctx).Error()
+}
+
+// Close stops the informer cache, if any
+func (k *KubernetesCollector) Close() {
+	if k.cache != nil {
+		k.cache.stop()
+	}
+}
+
 // SetProgressReporter sets the progress reporter for the collector
 func (k *KubernetesCollector) SetProgressReporter(reporter ui.ProgressReporter) {
 	k.progress = reporter
@@ -75,45 +139,93 @@ type PodInfo struct {
 	Pod    *corev1.Pod
 	Logs   string
 	Events []corev1.Event
+	// LogsError and EventsError record non-fatal collection failures
+	LogsError   error
+	EventsError error
 }
 
 func (k *KubernetesCollector) GetPodInfo(ctx context.Context, namespace, podName string, lookback time.Duration) (*PodInfo, error) {
 	k.progress.Update(fmt.Sprintf("Fetching pod metadata for %s/%s...", namespace, podName))
-	pod, err := k.clientset.CoreV1().Pods(namespace).Get(ctx, podName, metav1.GetOptions{})
+	pod, err := k.GetPod(ctx, namespace, podName)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get pod: %w", err)
+		return nil, err
 	}
 
-	logs, err := k.GetPodLogs(ctx, namespace, podName, lookback)
-	if err != nil {
+	logs, logsErr := k.GetPodLogs(ctx, namespace, podName, lookback, LogOptions{})
+	if logsErr != nil {
 		// Log error but continue
-		logs = fmt.Sprintf("Error fetching logs: %v", err)
+		logs = fmt.Sprintf("Error fetching logs: %v", logsErr)
 	}
 
-	events, err := k.GetPodEvents(ctx, namespace, podName, lookback)
-	if err != nil {
+	events, eventsErr := k.GetPodEvents(ctx, namespace, podName, lookback)
+	if eventsErr != nil {
 		// Log error but continue
 		events = []corev1.Event{}
 	}
 
 	return &PodInfo{
-		Pod:    pod,
-		Logs:   logs,
-		Events: events,
+		Pod:         pod,
+		Logs:        logs,
+		Events:      events,
+		LogsError:   logsErr,
+		EventsError: eventsErr,
 	}, nil
 }
 
-func (k *KubernetesCollector) GetPodLogs(ctx context.Context, namespace, podName string, lookback time.Duration) (string, error) {
+// LogOptions tunes a log fetch; zero fields fall back to log_collection.
+// Container selects the container; empty lets Kubernetes pick the pod's
+// only or default container.
+type LogOptions struct {
+	TailLines       int64
+	IncludePrevious *bool
+	Container       string
+}
+
+// GetPodLogs returns the logs of the pod's main container from the lookback
+// window. With previous logs enabled, the logs of the container's previous
+// instance (before its last restart) are prepended when there is one.
+func (k *KubernetesCollector) GetPodLogs(ctx context.Context, namespace, podName string, lookback time.Duration, opts LogOptions) (string, error) {
 	k.progress.Update(fmt.Sprintf("Fetching logs for pod %s/%s (last %s)...", namespace, podName, lookback))
 	sinceTime := metav1.NewTime(time.Now().Add(-lookback))
 
-	opts := &corev1.PodLogOptions{
+	tailLines := opts.TailLines
+	if tailLines <= 0 {
+		tailLines = k.config.LogCollection.TailLines
+	}
+	includePrevious := k.config.LogCollection.IncludePrevious
+	if opts.IncludePrevious != nil {
+		includePrevious = *opts.IncludePrevious
+	}
+
+	// Get the main container logs
+	logs, err := k.readPodLogs(ctx, namespace, podName, &corev1.PodLogOptions{
+		Container:  opts.Container,
 		SinceTime:  &sinceTime,
-		TailLines:  &k.config.LogCollection.TailLines,
+		TailLines:  &tailLines,
 		Timestamps: true,
+	})
+	if err != nil {
+		return "", err
+	}
+	if !includePrevious {
+		return logs, nil
 	}
 
-	// Get the main container logs
+	previous, err := k.readPodLogs(ctx, namespace, podName, &corev1.PodLogOptions{
+		Container:  opts.Container,
+		SinceTime:  &sinceTime,
+		TailLines:  &tailLines,
+		Timestamps: true,
+		Previous:   true,
+	})
+	if err != nil || previous == "" {
+		// The container has not restarted
+		return logs, nil
+	}
+	return "=== previous container instance ===\n" + previous + "=== current container instance ===\n" + logs, nil
+}
+
+func (k *KubernetesCollector) readPodLogs(ctx context.Context, namespace, podName string, opts *corev1.PodLogOptions) (string, error) {
 	req := k.clientset.CoreV1().Pods(namespace).GetLogs(podName, opts)
 	podLogs, err := req.Stream(ctx)
 	if err != nil {
@@ -131,19 +243,15 @@ func (k *KubernetesCollector) GetPodLogs(ctx context.Context, namespace, podName
 
 func (k *KubernetesCollector) GetPodEvents(ctx context.Context, namespace, podName string, lookback time.Duration) ([]corev1.Event, error) {
 	k.progress.Update(fmt.Sprintf("Fetching Kubernetes events for pod %s/%s...", namespace, podName))
-	fieldSelector := fmt.Sprintf("involvedObject.name=%s,involvedObject.kind=Pod", podName)
-
-	eventList, err := k.clientset.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{
-		FieldSelector: fieldSelector,
-	})
+	events, err := k.listObjectEvents(ctx, namespace, "Pod", podName)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get events: %w", err)
+		return nil, err
 	}
 
 	// Filter events by time
 	cutoff := time.Now().Add(-lookback)
 	var filteredEvents []corev1.Event
-	for _, event := range eventList.Items {
+	for _, event := range events {
 		if event.LastTimestamp.Time.After(cutoff) {
 			filteredEvents = append(filteredEvents, event)
 		}
@@ -153,14 +261,18 @@ func (k *KubernetesCollector) GetPodEvents(ctx context.Context, namespace, podNa
 }
 
 func (k *KubernetesCollector) GetNamespaceEvents(ctx context.Context, namespace string, lookback time.Duration) ([]corev1.Event, error) {
-	eventList, err := k.clientset.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{})
-	if err != nil {
-		return nil, fmt.Errorf("failed to get namespace events: %w", err)
+	events, cached := k.cachedNamespaceEvents(namespace)
+	if !cached {
+		eventList, err := k.clientset.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get namespace events: %w", err)
+		}
+		events = eventList.Items
 	}
 
 	cutoff := time.Now().Add(-lookback)
 	var filteredEvents []corev1.Event
-	for _, event := range eventList.Items {
+	for _, event := range events {
 		if event.LastTimestamp.Time.After(cutoff) {
 			// Filter by event type if configured
 			if len(k.config.EventCollection.EventTypes) > 0 {
@@ -182,10 +294,74 @@ func (k *KubernetesCollector) GetNamespaceEvents(ctx context.Context, namespace
 	return filteredEvents, nil
 }
 
+// listObjectEvents returns the events about one object, from the informer
+// cache when it is available
+func (k *KubernetesCollector) listObjectEvents(ctx context.Context, namespace, kind, name string) ([]corev1.Event, error) {
+	if k.cache != nil {
+		if events, ok := k.cache.objectEvents(namespace, kind, name); ok {
+			return events, nil
+		}
+	}
+
+	eventList, err := k.clientset.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{
+		FieldSelector: fmt.Sprintf("involvedObject.name=%s,involvedObject.kind=%s", name, kind),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get events: %w", err)
+	}
+	return eventList.Items, nil
+}
+
+func (k *KubernetesCollector) cachedNamespaceEvents(namespace string) ([]corev1.Event, bool) {
+	if k.cache == nil {
+		return nil, false
+	}
+	return k.cache.namespaceEvents(namespace)
+}
+
 func (k *KubernetesCollector) GetPod(ctx context.Context, namespace, podName string) (*corev1.Pod, error) {
+	if k.cache != nil {
+		if pod, ok := k.cache.getPod(namespace, podName); ok {
+			return pod, nil
+		}
+	}
+
 	pod, err := k.clientset.CoreV1().Pods(namespace).Get(ctx, podName, metav1.GetOptions{})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get pod: %w", err)
 	}
 	return pod, nil
 }
+
+// ListNamespaces returns the names of the allowed namespaces, sorted
+func (k *KubernetesCollector) ListNamespaces(ctx context.Context) ([]string, error) {
+	list, err := k.clientset.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list namespaces: %w", err)
+	}
+
+	names := make([]string, 0, len(list.Items))
+	for _, ns := range list.Items {
+		if k.NamespaceAllowed(ns.Name) {
+			names = append(names, ns.Name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// ListPods returns the pods of an allowed namespace, sorted by name
+func (k *KubernetesCollector) ListPods(ctx context.Context, namespace string) ([]corev1.Pod, error) {
+	if !k.NamespaceAllowed(namespace) {
+		return nil, ErrNamespaceNotAllowed
+	}
+
+	list, err := k.clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+
+	pods := list.Items
+	sort.Slice(pods, func(i, j int) bool { return pods[i].Name < pods[j].Name })
+	return pods, nil
+}
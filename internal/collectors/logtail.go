@@ -0,0 +1,41 @@
+package collectors
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// CanTailPodLogs checks with the API server whether the viewer may read the
+// pod's logs
+func (k *KubernetesCollector) CanTailPodLogs(ctx context.Context, viewer Viewer, namespace, podName string) (bool, string, error) {
+	attrs := &authorizationv1.ResourceAttributes{
+		Namespace:   namespace,
+		Verb:        "get",
+		Resource:    "pods",
+		Subresource: "log",
+		Name:        podName,
+	}
+
+	return k.reviewAccess(ctx, viewer, attrs)
+}
+
+// StreamPodLogs follows a container's logs starting with the last tailLines
+// lines. The stream ends when ctx is cancelled or the container exits.
+func (k *KubernetesCollector) StreamPodLogs(ctx context.Context, namespace, podName, container string, tailLines int64) (io.ReadCloser, error) {
+	opts := &corev1.PodLogOptions{
+		Container:  container,
+		Follow:     true,
+		TailLines:  &tailLines,
+		Timestamps: true,
+	}
+
+	stream, err := k.clientset.CoreV1().Pods(namespace).GetLogs(podName, opts).Stream(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stream pod logs: %w", err)
+	}
+	return stream, nil
+}
@@ -0,0 +1,197 @@
+package collectors
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/emirozbir/micro-sre/internal/models"
+)
+
+// restartIssueThreshold is the total restart count at which a workload is
+// reported even if all its pods are currently ready
+const restartIssueThreshold = 5
+
+// ScanNamespace groups the pods of a namespace by workload and flags pods
+// that are not ready, pending workloads, high restart counts and recent
+// Warning events. Warnings that cannot be tied to a workload are returned
+// separately.
+func (k *KubernetesCollector) ScanNamespace(ctx context.Context, namespace string, lookback time.Duration) ([]models.WorkloadHealth, []models.WarningEvent, error) {
+	k.progress.Update(fmt.Sprintf("Scanning namespace %s...", namespace))
+
+	deployments, err := k.clientset.AppsV1().Deployments(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list deployments: %w", err)
+	}
+	statefulSets, err := k.clientset.AppsV1().StatefulSets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list statefulsets: %w", err)
+	}
+	pods, err := k.clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+
+	type controller struct {
+		status   *models.WorkloadStatus
+		selector labels.Selector
+	}
+	var controllers []controller
+	for i := range deployments.Items {
+		d := &deployments.Items[i]
+		if selector, err := metav1.LabelSelectorAsSelector(d.Spec.Selector); err == nil {
+			controllers = append(controllers, controller{deploymentStatus(d), selector})
+		}
+	}
+	for i := range statefulSets.Items {
+		s := &statefulSets.Items[i]
+		if selector, err := metav1.LabelSelectorAsSelector(s.Spec.Selector); err == nil {
+			controllers = append(controllers, controller{statefulSetStatus(s), selector})
+		}
+	}
+
+	workloads := map[string]*models.WorkloadHealth{}
+	var order []string
+	workload := func(kind, name string) *models.WorkloadHealth {
+		key := kind + "/" + name
+		w, ok := workloads[key]
+		if !ok {
+			w = &models.WorkloadHealth{Kind: kind, Name: name}
+			workloads[key] = w
+			order = append(order, key)
+		}
+		return w
+	}
+	for _, c := range controllers {
+		w := workload(c.status.Kind, c.status.Name)
+		w.Desired, w.Ready = c.status.Desired, c.status.Ready
+	}
+
+	// Object (Kind/Name) of a pod or workload -> owning workload key
+	owners := map[string]string{}
+	for _, key := range order {
+		owners[key] = key
+	}
+
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		health := podHealth(pod)
+		var w *models.WorkloadHealth
+		for _, c := range controllers {
+			if c.selector.Matches(labels.Set(pod.Labels)) {
+				w = workload(c.status.Kind, c.status.Name)
+				break
+			}
+		}
+		if w == nil {
+			// Jobs, DaemonSets and bare pods are sized by their pods
+			kind, name := "Pod", pod.Name
+			if ref := metav1.GetControllerOf(pod); ref != nil {
+				kind, name = ref.Kind, ref.Name
+			}
+			w = workload(kind, name)
+			w.Desired++
+			if health.Ready || health.Phase == string(corev1.PodSucceeded) {
+				w.Ready++
+			}
+		}
+		w.Pods = append(w.Pods, health)
+		w.Restarts += health.Restarts
+		owners["Pod/"+pod.Name] = w.Key()
+	}
+
+	events, err := k.GetNamespaceEvents(ctx, namespace, lookback)
+	if err != nil {
+		return nil, nil, err
+	}
+	var other []models.WarningEvent
+	for _, event := range events {
+		if event.Type != corev1.EventTypeWarning {
+			continue
+		}
+		obj := event.InvolvedObject.Kind + "/" + event.InvolvedObject.Name
+		warning := models.WarningEvent{
+			Object:   obj,
+			Reason:   event.Reason,
+			Message:  event.Message,
+			Count:    event.Count,
+			LastSeen: event.LastTimestamp.Time,
+		}
+
+		key, ok := owners[obj]
+		if !ok && event.InvolvedObject.Kind == "ReplicaSet" {
+			// ReplicaSets are named <deployment>-<pod-template-hash>
+			if i := strings.LastIndex(event.InvolvedObject.Name, "-"); i > 0 {
+				key, ok = owners["Deployment/"+event.InvolvedObject.Name[:i]]
+			}
+		}
+		if !ok {
+			other = append(other, warning)
+			continue
+		}
+		w := workloads[key]
+		w.Warnings = append(w.Warnings, warning)
+	}
+
+	out := make([]models.WorkloadHealth, 0, len(order))
+	for _, key := range order {
+		w := workloads[key]
+		w.Issues = workloadIssues(w)
+		sortWarnings(w.Warnings)
+		out = append(out, *w)
+	}
+	sortWarnings(other)
+
+	// Unhealthy workloads first, then by name
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Healthy() != out[j].Healthy() {
+			return !out[i].Healthy()
+		}
+		return out[i].Key() < out[j].Key()
+	})
+
+	return out, other, nil
+}
+
+func workloadIssues(w *models.WorkloadHealth) []string {
+	var issues []string
+	if w.Ready < w.Desired {
+		issues = append(issues, fmt.Sprintf("%d/%d ready", w.Ready, w.Desired))
+	}
+
+	var pending, failing int
+	for _, p := range w.Pods {
+		switch {
+		case p.Phase == string(corev1.PodPending):
+			pending++
+		case p.Phase == string(corev1.PodSucceeded):
+		case !p.Healthy():
+			failing++
+		}
+	}
+	if pending > 0 {
+		issues = append(issues, fmt.Sprintf("%d pending", pending))
+	}
+	if failing > 0 {
+		issues = append(issues, fmt.Sprintf("%d unhealthy", failing))
+	}
+	if w.Restarts >= restartIssueThreshold {
+		issues = append(issues, fmt.Sprintf("%d restarts", w.Restarts))
+	}
+	if len(w.Warnings) > 0 {
+		issues = append(issues, fmt.Sprintf("%d warning events", len(w.Warnings)))
+	}
+	return issues
+}
+
+func sortWarnings(warnings []models.WarningEvent) {
+	sort.SliceStable(warnings, func(i, j int) bool {
+		return warnings[i].LastSeen.After(warnings[j].LastSeen)
+	})
+}
@@ -0,0 +1,179 @@
+package collectors
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/emirozbir/micro-sre/internal/models"
+)
+
+// oomCorrelationWindow is how far around the kill time node events are
+// considered related
+const oomCorrelationWindow = 5 * time.Minute
+
+// nodeMemoryReasons are node event reasons that indicate node-level memory
+// exhaustion rather than a container hitting its own limit
+var nodeMemoryReasons = map[string]bool{
+	"SystemOOM":                 true,
+	"EvictionThresholdMet":      true,
+	"NodeHasInsufficientMemory": true,
+	"Evicted":                   true,
+	"MemoryPressure":            true,
+}
+
+// GetOOMContext inspects the pod for OOM-killed containers or eviction and
+// correlates the kill with node events and node memory state to tell a
+// cgroup limit OOM apart from node-level memory pressure. It returns nil if
+// the pod shows no sign of either.
+func (k *KubernetesCollector) GetOOMContext(ctx context.Context, pod *corev1.Pod) (*models.OOMContext, error) {
+	oom := findOOMKill(pod)
+	if oom == nil {
+		return nil, nil
+	}
+	if pod.Spec.NodeName == "" {
+		oom.Classification = models.OOMUnknown
+		return oom, nil
+	}
+
+	k.progress.Update(fmt.Sprintf("Correlating OOM kill with node %s...", pod.Spec.NodeName))
+	oom.NodeName = pod.Spec.NodeName
+
+	// Node events are recorded in the default namespace
+	nodeEvents, err := k.listObjectEvents(ctx, metav1.NamespaceDefault, "Node", pod.Spec.NodeName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get node events: %w", err)
+	}
+
+	for _, event := range nodeEvents {
+		ts := event.LastTimestamp.Time
+		if ts.IsZero() {
+			ts = event.EventTime.Time
+		}
+		if ts.Before(oom.KilledAt.Add(-oomCorrelationWindow)) || ts.After(oom.KilledAt.Add(oomCorrelationWindow)) {
+			continue
+		}
+		if !nodeMemoryReasons[event.Reason] {
+			continue
+		}
+		oom.NodeEvents = append(oom.NodeEvents, models.EventEntry{
+			Type:      event.Type,
+			Reason:    event.Reason,
+			Message:   event.Message,
+			Timestamp: ts,
+		})
+	}
+
+	node, err := k.clientset.CoreV1().Nodes().Get(ctx, pod.Spec.NodeName, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get node: %w", err)
+	}
+	for _, cond := range node.Status.Conditions {
+		if cond.Type == corev1.NodeMemoryPressure && cond.Status == corev1.ConditionTrue {
+			oom.NodeMemoryPressure = true
+		}
+	}
+	if allocatable, ok := node.Status.Allocatable[corev1.ResourceMemory]; ok {
+		oom.NodeAllocatableBytes = allocatable.Value()
+	}
+
+	if k.config.Kubernetes.KubeletSummary {
+		if err := k.addKubeletSummary(ctx, oom); err != nil {
+			// The summary API is best effort; it requires nodes/proxy access
+			oom.SummaryError = err.Error()
+		}
+	}
+
+	switch {
+	case oom.Evicted || oom.NodeMemoryPressure || len(oom.NodeEvents) > 0:
+		oom.Classification = models.OOMNodePressure
+	case oom.MemoryLimit != "":
+		oom.Classification = models.OOMCgroupLimit
+	default:
+		oom.Classification = models.OOMUnknown
+	}
+
+	return oom, nil
+}
+
+func findOOMKill(pod *corev1.Pod) *models.OOMContext {
+	if pod.Status.Reason == "Evicted" {
+		oom := &models.OOMContext{
+			Evicted:  true,
+			Message:  pod.Status.Message,
+			KilledAt: time.Now(),
+		}
+		// The eviction time is not recorded on the pod; the Ready condition
+		// flips when the kubelet stops the containers
+		for _, cond := range pod.Status.Conditions {
+			if cond.Type == corev1.PodReady && !cond.LastTransitionTime.IsZero() {
+				oom.KilledAt = cond.LastTransitionTime.Time
+			}
+		}
+		return oom
+	}
+
+	for _, cs := range pod.Status.ContainerStatuses {
+		terminated := cs.LastTerminationState.Terminated
+		if cs.State.Terminated != nil {
+			terminated = cs.State.Terminated
+		}
+		if terminated == nil || terminated.Reason != "OOMKilled" {
+			continue
+		}
+
+		oom := &models.OOMContext{
+			Container: cs.Name,
+			KilledAt:  terminated.FinishedAt.Time,
+		}
+		for _, c := range pod.Spec.Containers {
+			if c.Name != cs.Name {
+				continue
+			}
+			if limit, ok := c.Resources.Limits[corev1.ResourceMemory]; ok {
+				oom.MemoryLimit = limit.String()
+			}
+			if request, ok := c.Resources.Requests[corev1.ResourceMemory]; ok {
+				oom.MemoryRequest = request.String()
+			}
+		}
+		return oom
+	}
+
+	return nil
+}
+
+// addKubeletSummary reads current node memory usage from the kubelet summary
+// API through the apiserver node proxy
+func (k *KubernetesCollector) addKubeletSummary(ctx context.Context, oom *models.OOMContext) error {
+	raw, err := k.clientset.CoreV1().RESTClient().Get().
+		AbsPath("/api/v1/nodes", oom.NodeName, "proxy", "stats", "summary").
+		DoRaw(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get kubelet summary: %w", err)
+	}
+
+	var summary struct {
+		Node struct {
+			Memory struct {
+				AvailableBytes  *int64 `json:"availableBytes"`
+				WorkingSetBytes *int64 `json:"workingSetBytes"`
+			} `json:"memory"`
+		} `json:"node"`
+	}
+	if err := json.Unmarshal(raw, &summary); err != nil {
+		return fmt.Errorf("failed to decode kubelet summary: %w", err)
+	}
+
+	if summary.Node.Memory.AvailableBytes != nil {
+		oom.NodeAvailableBytes = *summary.Node.Memory.AvailableBytes
+	}
+	if summary.Node.Memory.WorkingSetBytes != nil {
+		oom.NodeWorkingSetBytes = *summary.Node.Memory.WorkingSetBytes
+	}
+	return nil
+}
@@ -0,0 +1,260 @@
+package collectors
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/emirozbir/micro-sre/internal/config"
+	"github.com/emirozbir/micro-sre/internal/models"
+)
+
+// ErrPodNotRunning is returned when probes are requested for a pod that
+// cannot host an ephemeral container
+var ErrPodNotRunning = errors.New("pod is not running")
+
+// probeBaselineHost is always resolved so that a cluster DNS outage is
+// distinguishable from a missing dependency
+const probeBaselineHost = "kubernetes.default.svc"
+
+// probeHostPattern restricts probe targets to plain DNS names and IPs, since
+// they are interpolated into the probe script
+var probeHostPattern = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9.-]*[A-Za-z0-9])?$`)
+
+// ProbeCollector runs DNS and TCP connectivity checks from inside a pod's
+// network namespace using an ephemeral debug container. It requires the
+// Mutator capability and therefore cannot be built in read-only mode.
+type ProbeCollector struct {
+	mutator Mutator
+	config  config.ProbeConfig
+}
+
+func NewProbeCollector(m Mutator, cfg config.ProbeConfig) *ProbeCollector {
+	return &ProbeCollector{mutator: m, config: cfg}
+}
+
+// Probe resolves the cluster DNS baseline and the dependencies declared in
+// the pod's dependency annotation, and TCP-connects to those with a port
+func (p *ProbeCollector) Probe(ctx context.Context, pod *corev1.Pod) (*models.ProbeReport, error) {
+	if pod.Status.Phase != corev1.PodRunning {
+		return nil, ErrPodNotRunning
+	}
+
+	targets, invalid := p.targets(pod)
+	script := probeScript(targets, p.config.ConnectTimeout)
+
+	ctx, cancel := context.WithTimeout(ctx, p.config.Timeout)
+	defer cancel()
+
+	name := "hepsre-probe-" + randomSuffix()
+	pods := p.mutator.Clientset().CoreV1().Pods(pod.Namespace)
+
+	current, err := pods.Get(ctx, pod.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pod: %w", err)
+	}
+	current.Spec.EphemeralContainers = append(current.Spec.EphemeralContainers, corev1.EphemeralContainer{
+		EphemeralContainerCommon: corev1.EphemeralContainerCommon{
+			Name:                     name,
+			Image:                    p.config.Image,
+			Command:                  []string{"sh", "-c", script},
+			ImagePullPolicy:          corev1.PullIfNotPresent,
+			TerminationMessagePolicy: corev1.TerminationMessageReadFile,
+		},
+	})
+	if _, err := pods.UpdateEphemeralContainers(ctx, pod.Name, current, metav1.UpdateOptions{}); err != nil {
+		return nil, fmt.Errorf("failed to add probe container: %w", err)
+	}
+
+	if err := p.waitForCompletion(ctx, pod.Namespace, pod.Name, name); err != nil {
+		return nil, err
+	}
+
+	stream, err := pods.GetLogs(pod.Name, &corev1.PodLogOptions{Container: name}).Stream(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read probe output: %w", err)
+	}
+	defer stream.Close()
+
+	report := &models.ProbeReport{
+		Container: name,
+		Image:     p.config.Image,
+		Results:   invalid,
+	}
+	results, err := parseProbeOutput(stream)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read probe output: %w", err)
+	}
+	report.Results = append(report.Results, results...)
+
+	return report, nil
+}
+
+// probeTarget is a host and, for TCP checks, a port
+type probeTarget struct {
+	host string
+	port int
+}
+
+// targets parses the dependency annotation, a comma-separated list of
+// host, host:port or URL entries. Invalid entries are reported as failed
+// results instead of being probed.
+func (p *ProbeCollector) targets(pod *corev1.Pod) ([]probeTarget, []models.ProbeResult) {
+	targets := []probeTarget{{host: probeBaselineHost}}
+	var invalid []models.ProbeResult
+
+	for _, entry := range strings.Split(pod.Annotations[p.config.DependencyAnnotation], ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		target, err := parseProbeTarget(entry)
+		if err != nil {
+			invalid = append(invalid, models.ProbeResult{
+				Kind:   models.ProbeDNS,
+				Target: entry,
+				Detail: err.Error(),
+			})
+			continue
+		}
+		targets = append(targets, target)
+	}
+
+	return targets, invalid
+}
+
+func parseProbeTarget(entry string) (probeTarget, error) {
+	if strings.Contains(entry, "://") {
+		u, err := url.Parse(entry)
+		if err != nil {
+			return probeTarget{}, fmt.Errorf("invalid dependency URL: %w", err)
+		}
+		host, port := u.Hostname(), u.Port()
+		if port == "" {
+			switch u.Scheme {
+			case "https":
+				port = "443"
+			case "http":
+				port = "80"
+			}
+		}
+		entry = host
+		if port != "" {
+			entry = net.JoinHostPort(host, port)
+		}
+	}
+
+	target := probeTarget{host: entry}
+	if host, port, err := net.SplitHostPort(entry); err == nil {
+		n, err := strconv.Atoi(port)
+		if err != nil || n <= 0 || n > 65535 {
+			return probeTarget{}, fmt.Errorf("invalid port %q", port)
+		}
+		target = probeTarget{host: host, port: n}
+	}
+	if !probeHostPattern.MatchString(target.host) {
+		return probeTarget{}, fmt.Errorf("invalid host %q", target.host)
+	}
+	return target, nil
+}
+
+// probeScript builds a busybox-compatible script printing one result line
+// per check: "<kind> <target> <ok|fail> <detail>"
+func probeScript(targets []probeTarget, connectTimeout time.Duration) string {
+	seconds := int(connectTimeout.Seconds())
+	if seconds < 1 {
+		seconds = 1
+	}
+
+	var sb strings.Builder
+	resolved := map[string]bool{}
+	for _, t := range targets {
+		if !resolved[t.host] {
+			resolved[t.host] = true
+			sb.WriteString(fmt.Sprintf(
+				`if out=$(nslookup %[1]s 2>&1); then echo "dns %[1]s ok $(echo "$out" | awk '/^Address/ {print $NF}' | tail -n +2 | tr '\n' ' ')"; else echo "dns %[1]s fail $(echo "$out" | tail -n 1)"; fi`+"\n",
+				t.host))
+		}
+		if t.port > 0 {
+			sb.WriteString(fmt.Sprintf(
+				`if nc -z -w %[3]d %[1]s %[2]d 2>/dev/null; then echo "tcp %[1]s:%[2]d ok"; else echo "tcp %[1]s:%[2]d fail connect failed or timed out"; fi`+"\n",
+				t.host, t.port, seconds))
+		}
+	}
+	return sb.String()
+}
+
+func parseProbeOutput(r io.Reader) ([]models.ProbeResult, error) {
+	var results []models.ProbeResult
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.SplitN(strings.TrimSpace(scanner.Text()), " ", 4)
+		if len(fields) < 3 || (fields[0] != models.ProbeDNS && fields[0] != models.ProbeTCP) {
+			continue
+		}
+		result := models.ProbeResult{
+			Kind:   fields[0],
+			Target: fields[1],
+			OK:     fields[2] == "ok",
+		}
+		if len(fields) == 4 {
+			result.Detail = strings.TrimSpace(fields[3])
+		}
+		// Some nslookup builds exit 0 even when nothing resolved
+		if result.Kind == models.ProbeDNS && result.OK && result.Detail == "" {
+			result.OK = false
+			result.Detail = "no addresses returned"
+		}
+		results = append(results, result)
+	}
+	return results, scanner.Err()
+}
+
+// waitForCompletion polls the pod until the probe container has terminated
+func (p *ProbeCollector) waitForCompletion(ctx context.Context, namespace, podName, container string) error {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		pod, err := p.mutator.Clientset().CoreV1().Pods(namespace).Get(ctx, podName, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to get pod: %w", err)
+		}
+		for _, status := range pod.Status.EphemeralContainerStatuses {
+			if status.Name != container {
+				continue
+			}
+			if status.State.Terminated != nil {
+				return nil
+			}
+			if w := status.State.Waiting; w != nil && (w.Reason == "ErrImagePull" || w.Reason == "ImagePullBackOff") {
+				return fmt.Errorf("probe image %s cannot be pulled: %s", p.config.Image, w.Message)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("probe container did not finish: %w", ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+func randomSuffix() string {
+	b := make([]byte, 3)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,194 @@
+package collectors
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/emirozbir/micro-sre/internal/config"
+	"github.com/emirozbir/micro-sre/internal/models"
+)
+
+// maxMetricSeries caps how many series of an alert expression are kept
+const maxMetricSeries = 5
+
+type PrometheusCollector struct {
+	baseURL string
+	step    time.Duration
+	client  *http.Client
+}
+
+func NewPrometheusCollector(cfg *config.Config) *PrometheusCollector {
+	step := cfg.Prometheus.Step
+	if step <= 0 {
+		step = time.Minute
+	}
+
+	return &PrometheusCollector{
+		baseURL: strings.TrimSuffix(cfg.Prometheus.URL, "/"),
+		step:    step,
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+}
+
+type prometheusResponse struct {
+	Status    string          `json:"status"`
+	Data      json.RawMessage `json:"data"`
+	ErrorType string          `json:"errorType"`
+	Error     string          `json:"error"`
+}
+
+func (p *PrometheusCollector) get(ctx context.Context, baseURL, path string, params url.Values, out interface{}) error {
+	u := fmt.Sprintf("%s%s?%s", baseURL, path, params.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to query prometheus: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var body prometheusResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return fmt.Errorf("failed to decode prometheus response (status %d): %w", resp.StatusCode, err)
+	}
+	if body.Status != "success" {
+		return fmt.Errorf("prometheus query failed: %s: %s", body.ErrorType, body.Error)
+	}
+
+	if err := json.Unmarshal(body.Data, out); err != nil {
+		return fmt.Errorf("failed to decode prometheus data: %w", err)
+	}
+	return nil
+}
+
+// GetAlertMetrics resolves the alert's PromQL expression, from its
+// generatorURL or by looking up the alerting rule by name, and returns the
+// expression's values over the lookback window.
+func (p *PrometheusCollector) GetAlertMetrics(ctx context.Context, alert *models.Alert, lookback time.Duration) (*models.AlertMetrics, error) {
+	baseURL := p.baseURL
+	expr := ""
+
+	if alert.GeneratorURL != "" {
+		if u, err := url.Parse(alert.GeneratorURL); err == nil {
+			expr = u.Query().Get("g0.expr")
+			if baseURL == "" {
+				baseURL = fmt.Sprintf("%s://%s", u.Scheme, u.Host)
+			}
+		}
+	}
+
+	if baseURL == "" {
+		return nil, fmt.Errorf("prometheus URL not configured and alert has no generatorURL")
+	}
+
+	if expr == "" {
+		var err error
+		expr, err = p.getRuleExpression(ctx, baseURL, alert.GetAlertName())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	end := time.Now()
+	start := end.Add(-lookback)
+	if !alert.StartsAt.IsZero() && alert.StartsAt.Before(start) {
+		start = alert.StartsAt.Add(-lookback)
+	}
+
+	params := url.Values{}
+	params.Set("query", expr)
+	params.Set("start", strconv.FormatInt(start.Unix(), 10))
+	params.Set("end", strconv.FormatInt(end.Unix(), 10))
+	params.Set("step", strconv.FormatFloat(p.step.Seconds(), 'f', -1, 64))
+
+	var data struct {
+		ResultType string `json:"resultType"`
+		Result     []struct {
+			Metric map[string]string `json:"metric"`
+			Values [][2]interface{}  `json:"values"`
+		} `json:"result"`
+	}
+	if err := p.get(ctx, baseURL, "/api/v1/query_range", params, &data); err != nil {
+		return nil, err
+	}
+
+	metrics := &models.AlertMetrics{
+		Expression:  expr,
+		TotalSeries: len(data.Result),
+	}
+
+	for _, r := range data.Result {
+		series := models.MetricSeries{Labels: r.Metric}
+		for _, v := range r.Values {
+			ts, ok := v[0].(float64)
+			if !ok {
+				continue
+			}
+			raw, ok := v[1].(string)
+			if !ok {
+				continue
+			}
+			value, err := strconv.ParseFloat(raw, 64)
+			if err != nil {
+				continue
+			}
+			series.Points = append(series.Points, models.MetricPoint{
+				Timestamp: time.Unix(int64(ts), 0),
+				Value:     value,
+			})
+		}
+		metrics.Series = append(metrics.Series, series)
+	}
+
+	// Keep the series with the highest peak, which are the ones most likely
+	// to have triggered the alert
+	sort.SliceStable(metrics.Series, func(i, j int) bool {
+		return metrics.Series[i].Max() > metrics.Series[j].Max()
+	})
+	if len(metrics.Series) > maxMetricSeries {
+		metrics.Series = metrics.Series[:maxMetricSeries]
+	}
+
+	return metrics, nil
+}
+
+func (p *PrometheusCollector) getRuleExpression(ctx context.Context, baseURL, alertName string) (string, error) {
+	params := url.Values{}
+	params.Set("type", "alert")
+
+	var data struct {
+		Groups []struct {
+			Rules []struct {
+				Name  string `json:"name"`
+				Query string `json:"query"`
+				Type  string `json:"type"`
+			} `json:"rules"`
+		} `json:"groups"`
+	}
+	if err := p.get(ctx, baseURL, "/api/v1/rules", params, &data); err != nil {
+		return "", err
+	}
+
+	for _, g := range data.Groups {
+		for _, r := range g.Rules {
+			if r.Type == "alerting" && r.Name == alertName {
+				return r.Query, nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("alerting rule %q %w in prometheus", alertName, ErrNotFound)
+}
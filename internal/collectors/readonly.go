@@ -0,0 +1,92 @@
+package collectors
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// ErrReadOnly is returned when a mutating capability is requested while the
+// server runs in read-only mode
+var ErrReadOnly = errors.New("operation not permitted in read-only mode")
+
+// Mutator is the capability required by anything that changes cluster state
+// or execs into pods (remediation, probe collectors). It can only be obtained
+// through KubernetesCollector.Mutator, which refuses in read-only mode.
+type Mutator interface {
+	Clientset() kubernetes.Interface
+	RESTConfig() *rest.Config
+}
+
+type mutator struct {
+	clientset  *kubernetes.Clientset
+	restConfig *rest.Config
+}
+
+func (m *mutator) Clientset() kubernetes.Interface { return m.clientset }
+func (m *mutator) RESTConfig() *rest.Config        { return m.restConfig }
+
+// Mutator returns the mutating capability, or ErrReadOnly when read-only
+// mode is enabled
+func (k *KubernetesCollector) Mutator() (Mutator, error) {
+	if k.config.ReadOnly {
+		return nil, ErrReadOnly
+	}
+	return &mutator{clientset: k.clientset, restConfig: k.restConfig}, nil
+}
+
+// readOnlyRoundTripper rejects every Kubernetes API request that could
+// change cluster state, as a second line of defense behind the Mutator
+// capability
+type readOnlyRoundTripper struct {
+	next http.RoundTripper
+}
+
+// streamingSubresources can be reached with GET but still run code in pods
+var streamingSubresources = []string{"/exec", "/attach", "/portforward", "/proxy"}
+
+// accessReviewPaths are POST endpoints that only evaluate permissions and
+// persist nothing
+var accessReviewPaths = []string{
+	"/apis/authorization.k8s.io/v1/selfsubjectaccessreviews",
+	"/apis/authorization.k8s.io/v1/subjectaccessreviews",
+}
+
+func (rt *readOnlyRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+	case http.MethodPost:
+		if !isAccessReview(req.URL.Path) {
+			return nil, fmt.Errorf("%w: %s %s", ErrReadOnly, req.Method, req.URL.Path)
+		}
+	default:
+		return nil, fmt.Errorf("%w: %s %s", ErrReadOnly, req.Method, req.URL.Path)
+	}
+
+	for _, sub := range streamingSubresources {
+		if strings.HasSuffix(req.URL.Path, sub) {
+			return nil, fmt.Errorf("%w: %s %s", ErrReadOnly, req.Method, req.URL.Path)
+		}
+	}
+
+	return rt.next.RoundTrip(req)
+}
+
+func isAccessReview(path string) bool {
+	for _, p := range accessReviewPaths {
+		if path == p {
+			return true
+		}
+	}
+	return false
+}
+
+func enforceReadOnly(cfg *rest.Config) {
+	cfg.Wrap(func(rt http.RoundTripper) http.RoundTripper {
+		return &readOnlyRoundTripper{next: rt}
+	})
+}
@@ -0,0 +1,150 @@
+package collectors
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/emirozbir/micro-sre/internal/models"
+)
+
+var (
+	argoRolloutGVR   = schema.GroupVersionResource{Group: "argoproj.io", Version: "v1alpha1", Resource: "rollouts"}
+	flaggerCanaryGVR = schema.GroupVersionResource{Group: "flagger.app", Version: "v1beta1", Resource: "canaries"}
+)
+
+// GetRolloutInfo detects whether the pod is managed by an Argo Rollout or a
+// Flagger Canary and returns the current progressive delivery state. It
+// returns nil without error for pods that are not part of a rollout, or when
+// the rollout CRDs are not installed in the cluster.
+func (k *KubernetesCollector) GetRolloutInfo(ctx context.Context, pod *corev1.Pod) (*models.RolloutInfo, error) {
+	k.progress.Update(fmt.Sprintf("Checking rollout status for pod %s/%s...", pod.Namespace, pod.Name))
+
+	rsName := ownerName(pod.OwnerReferences, "ReplicaSet")
+	if rsName == "" {
+		return nil, nil
+	}
+
+	rs, err := k.clientset.AppsV1().ReplicaSets(pod.Namespace).Get(ctx, rsName, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get replicaset: %w", err)
+	}
+
+	if name := ownerName(rs.OwnerReferences, "Rollout"); name != "" {
+		return k.getArgoRollout(ctx, pod.Namespace, name)
+	}
+
+	if name := ownerName(rs.OwnerReferences, "Deployment"); name != "" {
+		return k.getFlaggerCanary(ctx, pod.Namespace, name)
+	}
+
+	return nil, nil
+}
+
+func (k *KubernetesCollector) getArgoRollout(ctx context.Context, namespace, name string) (*models.RolloutInfo, error) {
+	obj, err := k.dynamicClient.Resource(argoRolloutGVR).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get argo rollout: %w", err)
+	}
+
+	info := &models.RolloutInfo{
+		Kind:      "ArgoRollout",
+		Name:      name,
+		Namespace: namespace,
+	}
+	info.Phase, _, _ = unstructured.NestedString(obj.Object, "status", "phase")
+	info.Message, _, _ = unstructured.NestedString(obj.Object, "status", "message")
+	info.Aborted, _, _ = unstructured.NestedBool(obj.Object, "status", "abort")
+
+	if step, found, _ := unstructured.NestedInt64(obj.Object, "status", "currentStepIndex"); found {
+		info.CurrentStep = int(step)
+	}
+	if steps, found, _ := unstructured.NestedSlice(obj.Object, "spec", "strategy", "canary", "steps"); found {
+		info.TotalSteps = len(steps)
+	}
+	if weight, found, _ := unstructured.NestedInt64(obj.Object, "status", "canary", "weights", "canary", "weight"); found {
+		info.CanaryWeight = int(weight)
+	}
+
+	if info.Aborted || info.Phase == "Degraded" {
+		info.RollbackCommands = []string{
+			fmt.Sprintf("kubectl argo rollouts abort %s -n %s", name, namespace),
+			fmt.Sprintf("kubectl argo rollouts undo %s -n %s", name, namespace),
+		}
+	}
+
+	return info, nil
+}
+
+func (k *KubernetesCollector) getFlaggerCanary(ctx context.Context, namespace, deployment string) (*models.RolloutInfo, error) {
+	list, err := k.dynamicClient.Resource(flaggerCanaryGVR).Namespace(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to list flagger canaries: %w", err)
+	}
+
+	for _, item := range list.Items {
+		target, _, _ := unstructured.NestedString(item.Object, "spec", "targetRef", "name")
+		// Flagger copies the target deployment to <target>-primary
+		if target != deployment && target+"-primary" != deployment {
+			continue
+		}
+
+		info := &models.RolloutInfo{
+			Kind:      "FlaggerCanary",
+			Name:      item.GetName(),
+			Namespace: namespace,
+			Primary:   strings.HasSuffix(deployment, "-primary"),
+		}
+		info.Phase, _, _ = unstructured.NestedString(item.Object, "status", "phase")
+		if weight, found, _ := unstructured.NestedInt64(item.Object, "status", "canaryWeight"); found {
+			info.CanaryWeight = int(weight)
+		}
+		if checks, found, _ := unstructured.NestedInt64(item.Object, "status", "failedChecks"); found {
+			info.FailedChecks = int(checks)
+		}
+		if iterations, found, _ := unstructured.NestedInt64(item.Object, "status", "iterations"); found {
+			info.CurrentStep = int(iterations)
+		}
+		if conditions, found, _ := unstructured.NestedSlice(item.Object, "status", "conditions"); found {
+			for _, c := range conditions {
+				if cond, ok := c.(map[string]interface{}); ok {
+					if msg, ok := cond["message"].(string); ok && msg != "" {
+						info.Message = msg
+					}
+				}
+			}
+		}
+
+		if info.Phase == "Failed" || info.FailedChecks > 0 {
+			info.RollbackCommands = []string{
+				fmt.Sprintf("kubectl rollout undo deployment/%s -n %s", target, namespace),
+				fmt.Sprintf("kubectl describe canary %s -n %s", item.GetName(), namespace),
+			}
+		}
+
+		return info, nil
+	}
+
+	return nil, nil
+}
+
+func ownerName(refs []metav1.OwnerReference, kind string) string {
+	for _, ref := range refs {
+		if ref.Kind == kind {
+			return ref.Name
+		}
+	}
+	return ""
+}
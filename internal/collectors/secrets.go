@@ -0,0 +1,68 @@
+package collectors
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// GetSecretValues returns the values of the Secret keys the pods' containers
+// read into their environment, through env valueFrom.secretKeyRef or envFrom
+// secretRef, so that they can be redacted from prompts. The values are never
+// stored. Secrets that no longer exist are skipped.
+func (k *KubernetesCollector) GetSecretValues(ctx context.Context, pods ...*corev1.Pod) ([]string, error) {
+	type secretName struct{ namespace, name string }
+	// keys maps each referenced Secret to the keys read from it; a nil
+	// entry means envFrom reads every key
+	keys := map[secretName]map[string]bool{}
+	for _, pod := range pods {
+		if pod == nil {
+			continue
+		}
+		containers := append(append([]corev1.Container(nil), pod.Spec.InitContainers...), pod.Spec.Containers...)
+		for _, c := range containers {
+			for _, from := range c.EnvFrom {
+				if from.SecretRef != nil {
+					keys[secretName{pod.Namespace, from.SecretRef.Name}] = nil
+				}
+			}
+		}
+		for _, c := range containers {
+			for _, env := range c.Env {
+				if env.ValueFrom == nil || env.ValueFrom.SecretKeyRef == nil {
+					continue
+				}
+				ref := secretName{pod.Namespace, env.ValueFrom.SecretKeyRef.Name}
+				names, seen := keys[ref]
+				if seen && names == nil {
+					continue
+				}
+				if names == nil {
+					names = map[string]bool{}
+					keys[ref] = names
+				}
+				names[env.ValueFrom.SecretKeyRef.Key] = true
+			}
+		}
+	}
+
+	var values []string
+	for ref, names := range keys {
+		secret, err := k.clientset.CoreV1().Secrets(ref.namespace).Get(ctx, ref.name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get secret %s/%s: %w", ref.namespace, ref.name, err)
+		}
+		for key, value := range secret.Data {
+			if names == nil || names[key] {
+				values = append(values, string(value))
+			}
+		}
+	}
+	return values, nil
+}
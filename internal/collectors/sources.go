@@ -0,0 +1,33 @@
+package collectors
+
+// Data source names recorded in the per-analysis manifest
+const (
+	SourcePod           = "kubernetes/pod"
+	SourceWorkload      = "kubernetes/workload"
+	SourceLogs          = "kubernetes/logs"
+	SourceEvents        = "kubernetes/events"
+	SourceRollout       = "kubernetes/rollout"
+	SourceNode          = "kubernetes/node"
+	SourceProbes        = "kubernetes/probe"
+	SourceAdmission     = "kubernetes/admission"
+	SourceSilences      = "alertmanager/silences"
+	SourceRelatedAlerts = "alertmanager/alerts"
+	SourceMetrics       = "prometheus/query_range"
+)
+
+// SourceVersions is the implementation version of each data source. Bump an
+// entry whenever the shape or meaning of what the collector returns changes,
+// so stored analyses can be compared across releases.
+var SourceVersions = map[string]string{
+	SourcePod:           "1",
+	SourceWorkload:      "1",
+	SourceLogs:          "2",
+	SourceEvents:        "1",
+	SourceRollout:       "1",
+	SourceNode:          "1",
+	SourceProbes:        "1",
+	SourceAdmission:     "1",
+	SourceSilences:      "1",
+	SourceRelatedAlerts: "1",
+	SourceMetrics:       "1",
+}
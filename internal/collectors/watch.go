@@ -0,0 +1,138 @@
+package collectors
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Pod watch trigger reasons
+const (
+	TriggerCrashLoop = "CrashLoopBackOff"
+	TriggerOOMKilled = "OOMKilled"
+	TriggerRestarts  = "RestartThreshold"
+)
+
+// PodTrigger is a container state change that warrants an analysis
+type PodTrigger struct {
+	Namespace string
+	Pod       string
+	Container string
+	Reason    string
+	Restarts  int32
+}
+
+// WatchPods watches the pods of the given namespaces (all if empty) and
+// calls onTrigger when a container enters CrashLoopBackOff, is OOMKilled or
+// reaches restartThreshold restarts (0 disables the threshold). Pods that
+// were already failing when the watch started trigger on their next change.
+// WatchPods blocks until ctx is done.
+func (k *KubernetesCollector) WatchPods(ctx context.Context, namespaces []string, restartThreshold int32, onTrigger func(PodTrigger)) error {
+	if len(namespaces) == 0 {
+		namespaces = []string{metav1.NamespaceAll}
+	}
+
+	for _, ns := range namespaces {
+		factory := informers.NewSharedInformerFactoryWithOptions(k.clientset, 0, informers.WithNamespace(ns))
+		pods := factory.Core().V1().Pods().Informer()
+		if err := pods.SetTransform(stripManagedFields); err != nil {
+			return fmt.Errorf("failed to set informer transform: %w", err)
+		}
+		_, err := pods.AddEventHandler(cache.ResourceEventHandlerFuncs{
+			UpdateFunc: func(oldObj, newObj interface{}) {
+				oldPod, ok := oldObj.(*corev1.Pod)
+				if !ok {
+					return
+				}
+				newPod, ok := newObj.(*corev1.Pod)
+				if !ok {
+					return
+				}
+				if trigger, ok := podTrigger(oldPod, newPod, restartThreshold); ok {
+					onTrigger(trigger)
+				}
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to add pod watch handler: %w", err)
+		}
+		factory.Start(ctx.Done())
+	}
+
+	<-ctx.Done()
+	return nil
+}
+
+// podTrigger compares two versions of a pod and returns the first trigger
+// found, OOM kills taking precedence over crash loops over restart counts
+func podTrigger(oldPod, newPod *corev1.Pod, restartThreshold int32) (PodTrigger, bool) {
+	previous := make(map[string]corev1.ContainerStatus, len(oldPod.Status.ContainerStatuses))
+	for _, cs := range oldPod.Status.ContainerStatuses {
+		previous[cs.Name] = cs
+	}
+
+	var found *PodTrigger
+	for _, cs := range newPod.Status.ContainerStatuses {
+		old := previous[cs.Name]
+		reason := ""
+		switch {
+		case oomKilled(cs) && (cs.RestartCount > old.RestartCount || !oomKilled(old)):
+			reason = TriggerOOMKilled
+		case waitingReason(cs) == TriggerCrashLoop && waitingReason(old) != TriggerCrashLoop:
+			reason = TriggerCrashLoop
+		case restartThreshold > 0 && old.RestartCount < restartThreshold && cs.RestartCount >= restartThreshold:
+			reason = TriggerRestarts
+		default:
+			continue
+		}
+
+		if found == nil || triggerRank(reason) < triggerRank(found.Reason) {
+			found = &PodTrigger{
+				Namespace: newPod.Namespace,
+				Pod:       newPod.Name,
+				Container: cs.Name,
+				Reason:    reason,
+				Restarts:  cs.RestartCount,
+			}
+		}
+	}
+
+	if found == nil {
+		return PodTrigger{}, false
+	}
+	return *found, true
+}
+
+func triggerRank(reason string) int {
+	switch reason {
+	case TriggerOOMKilled:
+		return 0
+	case TriggerCrashLoop:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// oomKilled reports whether the container's current or last termination
+// was an OOM kill
+func oomKilled(cs corev1.ContainerStatus) bool {
+	if t := cs.State.Terminated; t != nil && t.Reason == TriggerOOMKilled {
+		return true
+	}
+	if t := cs.LastTerminationState.Terminated; t != nil && t.Reason == TriggerOOMKilled {
+		return true
+	}
+	return false
+}
+
+func waitingReason(cs corev1.ContainerStatus) string {
+	if cs.State.Waiting == nil {
+		return ""
+	}
+	return cs.State.Waiting.Reason
+}
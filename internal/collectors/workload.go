@@ -0,0 +1,200 @@
+package collectors
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/emirozbir/micro-sre/internal/models"
+)
+
+// GetPodWorkload returns the name of the Deployment or StatefulSet the pod
+// belongs to, or "" if it has neither
+func (k *KubernetesCollector) GetPodWorkload(ctx context.Context, namespace, podName string) (string, error) {
+	pod, err := k.GetPod(ctx, namespace, podName)
+	if err != nil {
+		return "", err
+	}
+	return podWorkload(pod), nil
+}
+
+// podWorkload derives the owning workload from the pod's owner references.
+// A Deployment's ReplicaSet is named after it plus the pod template hash,
+// which saves looking the ReplicaSet up.
+func podWorkload(pod *corev1.Pod) string {
+	if name := ownerName(pod.OwnerReferences, "StatefulSet"); name != "" {
+		return name
+	}
+	rs := ownerName(pod.OwnerReferences, "ReplicaSet")
+	hash := pod.Labels["pod-template-hash"]
+	if rs == "" || hash == "" || !strings.HasSuffix(rs, "-"+hash) {
+		return ""
+	}
+	return strings.TrimSuffix(rs, "-"+hash)
+}
+
+// GetWorkload looks up a Deployment, or a StatefulSet if no Deployment has
+// that name, and returns its status together with its pods
+func (k *KubernetesCollector) GetWorkload(ctx context.Context, namespace, name string) (*models.WorkloadStatus, []corev1.Pod, error) {
+	k.progress.Update(fmt.Sprintf("Fetching workload %s/%s...", namespace, name))
+
+	var (
+		status   *models.WorkloadStatus
+		selector *metav1.LabelSelector
+	)
+
+	deployment, err := k.clientset.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+	switch {
+	case err == nil:
+		status, selector = deploymentStatus(deployment), deployment.Spec.Selector
+	case apierrors.IsNotFound(err):
+		sts, err := k.clientset.AppsV1().StatefulSets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return nil, nil, fmt.Errorf("no deployment or statefulset named %s in namespace %s", name, namespace)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get statefulset: %w", err)
+		}
+		status, selector = statefulSetStatus(sts), sts.Spec.Selector
+	default:
+		return nil, nil, fmt.Errorf("failed to get deployment: %w", err)
+	}
+
+	labelSelector, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid workload selector: %w", err)
+	}
+
+	podList, err := k.clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: labelSelector.String(),
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list workload pods: %w", err)
+	}
+
+	for i := range podList.Items {
+		status.Pods = append(status.Pods, podHealth(&podList.Items[i]))
+	}
+
+	return status, podList.Items, nil
+}
+
+func deploymentStatus(d *appsv1.Deployment) *models.WorkloadStatus {
+	status := &models.WorkloadStatus{
+		Kind:      "Deployment",
+		Name:      d.Name,
+		Namespace: d.Namespace,
+		Ready:     d.Status.ReadyReplicas,
+		Updated:   d.Status.UpdatedReplicas,
+		Available: d.Status.AvailableReplicas,
+	}
+	if d.Spec.Replicas != nil {
+		status.Desired = *d.Spec.Replicas
+	}
+	for _, c := range d.Status.Conditions {
+		status.Conditions = append(status.Conditions, models.WorkloadCondition{
+			Type:    string(c.Type),
+			Status:  string(c.Status),
+			Reason:  c.Reason,
+			Message: c.Message,
+		})
+	}
+	return status
+}
+
+func statefulSetStatus(s *appsv1.StatefulSet) *models.WorkloadStatus {
+	status := &models.WorkloadStatus{
+		Kind:      "StatefulSet",
+		Name:      s.Name,
+		Namespace: s.Namespace,
+		Ready:     s.Status.ReadyReplicas,
+		Updated:   s.Status.UpdatedReplicas,
+		Available: s.Status.AvailableReplicas,
+	}
+	if s.Spec.Replicas != nil {
+		status.Desired = *s.Spec.Replicas
+	}
+	for _, c := range s.Status.Conditions {
+		status.Conditions = append(status.Conditions, models.WorkloadCondition{
+			Type:    string(c.Type),
+			Status:  string(c.Status),
+			Reason:  c.Reason,
+			Message: c.Message,
+		})
+	}
+	return status
+}
+
+// podHealth summarizes a pod's readiness, restarts and the most telling
+// container state reason
+func podHealth(pod *corev1.Pod) models.PodHealth {
+	health := models.PodHealth{
+		Name:   pod.Name,
+		Phase:  string(pod.Status.Phase),
+		Node:   pod.Spec.NodeName,
+		Reason: pod.Status.Reason,
+	}
+	for _, c := range pod.Status.Conditions {
+		if c.Type == corev1.PodReady {
+			health.Ready = c.Status == corev1.ConditionTrue
+		}
+	}
+	for _, cs := range pod.Status.ContainerStatuses {
+		health.Restarts += cs.RestartCount
+		if health.Reason != "" {
+			continue
+		}
+		switch {
+		case cs.State.Waiting != nil && cs.State.Waiting.Reason != "ContainerCreating":
+			health.Reason = cs.State.Waiting.Reason
+		case cs.State.Terminated != nil:
+			health.Reason = cs.State.Terminated.Reason
+		case !cs.Ready && cs.LastTerminationState.Terminated != nil:
+			health.Reason = cs.LastTerminationState.Terminated.Reason
+		}
+	}
+	return health
+}
+
+// RankPods returns the health of the pods of an allowed namespace matching
+// the label selector ("" for all), worst first: unhealthy before healthy,
+// not ready before ready, then by restarts
+func (k *KubernetesCollector) RankPods(ctx context.Context, namespace, selector string) ([]models.PodHealth, error) {
+	if !k.NamespaceAllowed(namespace) {
+		return nil, ErrNamespaceNotAllowed
+	}
+	if _, err := labels.Parse(selector); err != nil {
+		return nil, fmt.Errorf("invalid label selector: %w", err)
+	}
+
+	list, err := k.clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+
+	pods := make([]models.PodHealth, 0, len(list.Items))
+	for i := range list.Items {
+		pods = append(pods, podHealth(&list.Items[i]))
+	}
+	sort.SliceStable(pods, func(i, j int) bool {
+		a, b := pods[i], pods[j]
+		if a.Healthy() != b.Healthy() {
+			return !a.Healthy()
+		}
+		if a.Ready != b.Ready {
+			return !a.Ready
+		}
+		if a.Restarts != b.Restarts {
+			return a.Restarts > b.Restarts
+		}
+		return a.Name < b.Name
+	})
+	return pods, nil
+}
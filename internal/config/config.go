@@ -1,31 +1,430 @@
 package config
 
 import (
+	"fmt"
+	"net"
 	"os"
+	"path"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/spf13/viper"
+	"k8s.io/client-go/util/jsonpath"
 )
 
 type Config struct {
+	// ReadOnly guarantees that no mutating Kubernetes call is made and
+	// disables remediation and exec-based collectors
+	ReadOnly        bool                  `mapstructure:"read_only"`
+	Cluster         ClusterConfig         `mapstructure:"cluster"`
 	AlertManager    AlertManagerConfig    `mapstructure:"alertmanager"`
 	Kubernetes      KubernetesConfig      `mapstructure:"kubernetes"`
+	Prometheus      PrometheusConfig      `mapstructure:"prometheus"`
 	LogCollection   LogCollectionConfig   `mapstructure:"log_collection"`
 	EventCollection EventCollectionConfig `mapstructure:"event_collection"`
 	LLM             LLMConfig             `mapstructure:"llm"`
 	Agent           AgentConfig           `mapstructure:"agent"`
 	Server          ServerConfig          `mapstructure:"server"`
 	Database        DatabaseConfig        `mapstructure:"database"`
+	Artifacts       ArtifactsConfig       `mapstructure:"artifacts"`
+	Encryption      EncryptionConfig      `mapstructure:"encryption"`
+	Probes          ProbeConfig           `mapstructure:"probes"`
+	Rules           RulesConfig           `mapstructure:"rules"`
+	Report          ReportConfig          `mapstructure:"report"`
+	Redaction       RedactionConfig       `mapstructure:"redaction"`
+	Export          ExportConfig          `mapstructure:"export"`
+	Chaos           ChaosConfig           `mapstructure:"chaos"`
+	Remediation     RemediationConfig     `mapstructure:"remediation"`
+	Slack           SlackConfig           `mapstructure:"slack"`
+	PagerDuty       PagerDutyConfig       `mapstructure:"pagerduty"`
+	Opsgenie        OpsgenieConfig        `mapstructure:"opsgenie"`
+	Tracing         TracingConfig         `mapstructure:"tracing"`
+	// CollectionProfiles maps alert severities to collection settings
+	CollectionProfiles map[string]CollectionProfile `mapstructure:"collection_profiles"`
+	// AlertFilters select the webhook, polled and watched alerts analyzed
+	AlertFilters AlertFilterConfig `mapstructure:"alert_filters"`
+	// GenericWebhooks maps source names to the payload mappings of
+	// /api/v1/webhook/generic?source=<name>
+	GenericWebhooks map[string]GenericWebhookMapping `mapstructure:"generic_webhooks"`
+}
+
+// CollectionProfile tunes collection for alerts of one severity. Zero fields
+// fall back to the global settings. Collectors lists the data sources to
+// query by their manifest name (e.g. "prometheus/query_range"); empty
+// queries all of them.
+type CollectionProfile struct {
+	Lookback        time.Duration `mapstructure:"lookback"`
+	TailLines       int64         `mapstructure:"tail_lines"`
+	IncludePrevious *bool         `mapstructure:"include_previous"`
+	Collectors      []string      `mapstructure:"collectors"`
+}
+
+// Enabled reports whether the profile queries a data source
+func (p CollectionProfile) Enabled(source string) bool {
+	if len(p.Collectors) == 0 {
+		return true
+	}
+	for _, c := range p.Collectors {
+		if c == source {
+			return true
+		}
+	}
+	return false
+}
+
+// CollectionProfileFor returns the collection profile of an alert severity,
+// or a zero profile if there is none
+func (c *Config) CollectionProfileFor(severity string) CollectionProfile {
+	return c.CollectionProfiles[strings.ToLower(severity)]
 }
 
 type AlertManagerConfig struct {
-	URL          string        `mapstructure:"url"`
-	PollInterval time.Duration `mapstructure:"poll_interval"`
+	URL          string                 `mapstructure:"url"`
+	PollInterval time.Duration          `mapstructure:"poll_interval"`
+	Poll         AlertManagerPollConfig `mapstructure:"poll"`
+	Auth         AlertManagerAuthConfig `mapstructure:"auth"`
+}
+
+// AlertManagerPollConfig controls the poller that analyzes firing alerts
+// every poll_interval, for setups without a webhook receiver. Matchers use
+// the AlertManager syntax, e.g. `severity=~"critical|warning"`.
+type AlertManagerPollConfig struct {
+	Enabled  bool     `mapstructure:"enabled"`
+	Matchers []string `mapstructure:"matchers"`
+}
+
+// AlertManagerAuthConfig holds the credentials used to reach AlertManager.
+// At most one of bearer token, bearer token file and basic auth is used, in
+// that order of precedence.
+type AlertManagerAuthConfig struct {
+	Username        string          `mapstructure:"username"`
+	Password        string          `mapstructure:"password"`
+	BearerToken     string          `mapstructure:"bearer_token"`
+	BearerTokenFile string          `mapstructure:"bearer_token_file"`
+	TLS             TLSClientConfig `mapstructure:"tls"`
+}
+
+type TLSClientConfig struct {
+	CAFile             string `mapstructure:"ca_file"`
+	CertFile           string `mapstructure:"cert_file"`
+	KeyFile            string `mapstructure:"key_file"`
+	InsecureSkipVerify bool   `mapstructure:"insecure_skip_verify"`
+}
+
+// PrometheusConfig configures the alert-expression collector. If URL is
+// empty, the host of the alert's generatorURL is used instead.
+type PrometheusConfig struct {
+	Enabled bool          `mapstructure:"enabled"`
+	URL     string        `mapstructure:"url"`
+	Step    time.Duration `mapstructure:"step"`
 }
 
 type KubernetesConfig struct {
 	Kubeconfig string `mapstructure:"kubeconfig"`
 	Context    string `mapstructure:"context"`
+	// KubeletSummary enables reading node memory usage from the kubelet
+	// summary API (requires nodes/proxy get permission)
+	KubeletSummary bool                  `mapstructure:"kubelet_summary"`
+	Cache          KubernetesCacheConfig `mapstructure:"cache"`
+	Watch          KubernetesWatchConfig `mapstructure:"watch"`
+	// AllowedNamespaces limits the namespaces the web UI and the
+	// autocomplete API expose; entries are glob patterns, empty allows all
+	AllowedNamespaces []string `mapstructure:"allowed_namespaces"`
+}
+
+// KubernetesCacheConfig enables serving pod and event reads from shared
+// informers instead of per-analysis GET/LIST calls
+type KubernetesCacheConfig struct {
+	Enabled bool          `mapstructure:"enabled"`
+	Resync  time.Duration `mapstructure:"resync"`
+	// Namespaces limits the cache; empty caches all namespaces
+	Namespaces []string `mapstructure:"namespaces"`
+}
+
+// KubernetesWatchConfig enables analyzing pods as they start failing,
+// without AlertManager: when a container enters CrashLoopBackOff, is
+// OOMKilled or reaches RestartThreshold restarts. A pod is analyzed at most
+// once per Cooldown.
+type KubernetesWatchConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Namespaces limits the watch; empty watches all namespaces
+	Namespaces       []string      `mapstructure:"namespaces"`
+	RestartThreshold int32         `mapstructure:"restart_threshold"`
+	Cooldown         time.Duration `mapstructure:"cooldown"`
+	// Severity is the severity label of the analyses, which selects the
+	// collection profile
+	Severity string `mapstructure:"severity"`
+}
+
+// ProbeConfig configures the opt-in DNS/connectivity probe collector, which
+// adds an ephemeral debug container to the analyzed pod. It is unavailable
+// in read-only mode.
+type ProbeConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Image   string `mapstructure:"image"`
+	// DependencyAnnotation lists host, host:port or URL dependencies to probe
+	DependencyAnnotation string        `mapstructure:"dependency_annotation"`
+	Timeout              time.Duration `mapstructure:"timeout"`
+	ConnectTimeout       time.Duration `mapstructure:"connect_timeout"`
+}
+
+// RulesConfig controls the rule-based pre-classifier. Conclusive matches
+// answer the analysis without the LLM unless AnswerWithoutLLM is off, in
+// which case every match only seeds the prompt.
+type RulesConfig struct {
+	Enabled          bool `mapstructure:"enabled"`
+	AnswerWithoutLLM bool `mapstructure:"answer_without_llm"`
+}
+
+// Report verbosity levels
+const (
+	VerbosityBrief    = "brief"
+	VerbosityStandard = "standard"
+	VerbosityDeep     = "deep"
+)
+
+// ReportConfig controls how detailed analyses are. Brief reports suit chat
+// notifications, deep ones postmortems. Verbosity is the default; requests
+// may override it, and webhook alerts take the first matching route.
+type ReportConfig struct {
+	Verbosity      string        `mapstructure:"verbosity"`
+	BriefMaxTokens int           `mapstructure:"brief_max_tokens"`
+	DeepMaxTokens  int           `mapstructure:"deep_max_tokens"`
+	Routes         []ReportRoute `mapstructure:"routes"`
+}
+
+// ReportRoute selects a verbosity for webhook alerts whose labels contain
+// every Match entry
+type ReportRoute struct {
+	Match     map[string]string `mapstructure:"match"`
+	Verbosity string            `mapstructure:"verbosity"`
+}
+
+// ValidVerbosity reports whether v is a known verbosity level
+func ValidVerbosity(v string) bool {
+	switch v {
+	case VerbosityBrief, VerbosityStandard, VerbosityDeep:
+		return true
+	}
+	return false
+}
+
+// VerbosityFor returns the verbosity of the first route matching the alert
+// labels, or the default verbosity
+func (r ReportConfig) VerbosityFor(labels map[string]string) string {
+	for _, route := range r.Routes {
+		matched := true
+		for k, v := range route.Match {
+			if labels[k] != v {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return route.Verbosity
+		}
+	}
+	return r.Verbosity
+}
+
+// RedactionConfig controls scrubbing of sensitive data from prompts before
+// they leave the cluster. Detectors selects built-in detectors (tokens,
+// jwt, aws_key, email, ip); empty runs all of them. EnvValues also redacts
+// the literal env var values of the analyzed pods wherever they appear, and
+// SecretValues the values of the Secrets they read into their environment.
+type RedactionConfig struct {
+	Enabled      bool               `mapstructure:"enabled"`
+	Detectors    []string           `mapstructure:"detectors"`
+	Patterns     []RedactionPattern `mapstructure:"patterns"`
+	EnvValues    bool               `mapstructure:"env_values"`
+	SecretValues bool               `mapstructure:"secret_values"`
+}
+
+// RedactionPattern is an additional named regular expression to redact
+type RedactionPattern struct {
+	Name  string `mapstructure:"name"`
+	Regex string `mapstructure:"regex"`
+}
+
+// TracingConfig exports OpenTelemetry spans of requests and analyses over
+// OTLP/HTTP. Endpoint is the collector's traces URL, e.g.
+// "http://otel-collector:4318/v1/traces"; when empty the standard
+// OTEL_EXPORTER_OTLP_* environment variables apply. SampleRatio is the
+// fraction of new traces kept; traces started by a caller follow its
+// sampling decision.
+type TracingConfig struct {
+	Enabled     bool    `mapstructure:"enabled"`
+	Endpoint    string  `mapstructure:"endpoint"`
+	ServiceName string  `mapstructure:"service_name"`
+	SampleRatio float64 `mapstructure:"sample_ratio"`
+}
+
+// ClusterConfig identifies the cluster of an analysis: the one given in the
+// request, else the value of the alert's Label, else Name
+type ClusterConfig struct {
+	Name  string `mapstructure:"name"`
+	Label string `mapstructure:"label"`
+}
+
+// ChaosConfig injects simulated failures into collectors and the LLM to
+// test degradation behavior. Faults can only be injected, from config or
+// the admin endpoint, when Enabled is set; never set it in production.
+type ChaosConfig struct {
+	Enabled bool         `mapstructure:"enabled"`
+	Faults  []ChaosFault `mapstructure:"faults"`
+}
+
+// ChaosFault delays the calls to one target and fails a fraction of them
+// with an error of the given class (timeout, connection_refused, forbidden,
+// not_found, unknown). Target is a data source name, "llm", or "*" for
+// every collector.
+type ChaosFault struct {
+	Target     string        `mapstructure:"target"`
+	Latency    time.Duration `mapstructure:"latency"`
+	ErrorRate  float64       `mapstructure:"error_rate"`
+	ErrorClass string        `mapstructure:"error_class"`
+}
+
+// RemediationConfig controls how recommended kubectl commands are checked.
+// Commands whose verb is not in AllowedVerbs are marked invalid and are
+// never dry-run.
+type RemediationConfig struct {
+	AllowedVerbs []string              `mapstructure:"allowed_verbs"`
+	Auto         AutoRemediationConfig `mapstructure:"auto"`
+}
+
+// AutoRemediationConfig enables executing recommended actions (restart,
+// scale, delete_pod) after a human approves them. It is strictly opt-in:
+// nothing is proposed unless Enabled is set and the namespace matches one
+// of Namespaces (glob patterns; empty matches none). Pending proposals
+// expire after ApprovalTTL; scaling above MaxReplicas is never proposed.
+type AutoRemediationConfig struct {
+	Enabled     bool          `mapstructure:"enabled"`
+	Namespaces  []string      `mapstructure:"namespaces"`
+	Actions     []string      `mapstructure:"actions"`
+	MaxReplicas int           `mapstructure:"max_replicas"`
+	ApprovalTTL time.Duration `mapstructure:"approval_ttl"`
+}
+
+// NamespaceEnabled reports whether auto-remediation is enabled and the
+// namespace opted in
+func (c AutoRemediationConfig) NamespaceEnabled(namespace string) bool {
+	if !c.Enabled {
+		return false
+	}
+	for _, pattern := range c.Namespaces {
+		if ok, _ := path.Match(pattern, namespace); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// SlackConfig connects hepsre to a Slack app. WebhookURL is an incoming
+// webhook messages are posted to; SigningSecret verifies the requests Slack
+// sends back (button clicks). Both are optional.
+type SlackConfig struct {
+	WebhookURL    string        `mapstructure:"webhook_url"`
+	SigningSecret string        `mapstructure:"signing_secret"`
+	Timeout       time.Duration `mapstructure:"timeout"`
+}
+
+// PagerDutyConfig connects hepsre to PagerDuty. WebhookSecret verifies the
+// V3 webhooks sent to /api/v1/webhook/pagerduty; with PostNotes, an API
+// token and FromEmail (the requester the notes API requires), analyses are
+// added to the incident as notes, linking to DashboardURL when it is set.
+type PagerDutyConfig struct {
+	WebhookSecret string        `mapstructure:"webhook_secret"`
+	APIToken      string        `mapstructure:"api_token"`
+	APIURL        string        `mapstructure:"api_url"`
+	FromEmail     string        `mapstructure:"from_email"`
+	PostNotes     bool          `mapstructure:"post_notes"`
+	DashboardURL  string        `mapstructure:"dashboard_url"`
+	Timeout       time.Duration `mapstructure:"timeout"`
+}
+
+// OpsgenieConfig connects hepsre to Opsgenie, whose webhook integration
+// posts to /api/v1/webhook/opsgenie. With PostNotes and an API key of an
+// API integration, analyses are added to the alert as notes, linking to
+// DashboardURL when it is set, and the alert is tagged with TagPrefix
+// followed by the root cause category.
+type OpsgenieConfig struct {
+	APIKey       string        `mapstructure:"api_key"`
+	APIURL       string        `mapstructure:"api_url"`
+	PostNotes    bool          `mapstructure:"post_notes"`
+	TagPrefix    string        `mapstructure:"tag_prefix"`
+	DashboardURL string        `mapstructure:"dashboard_url"`
+	Timeout      time.Duration `mapstructure:"timeout"`
+}
+
+// AlertFilterConfig keeps noisy or irrelevant alerts from using the LLM
+// budget. An alert is analyzed if it matches an Allow rule (or there are
+// none) and no Deny rule.
+type AlertFilterConfig struct {
+	Allow []AlertFilterRule `mapstructure:"allow"`
+	Deny  []AlertFilterRule `mapstructure:"deny"`
+}
+
+// AlertFilterRule matches alerts whose namespace, alert name and severity
+// are among the listed ones (glob patterns; an empty list matches all) and
+// whose labels match every AlertManager-style matcher, e.g.
+// `team!="platform"`
+type AlertFilterRule struct {
+	Namespaces []string `mapstructure:"namespaces"`
+	AlertNames []string `mapstructure:"alertnames"`
+	Severities []string `mapstructure:"severities"`
+	Matchers   []string `mapstructure:"matchers"`
+}
+
+// GenericWebhookMapping turns the JSON payload of an arbitrary monitoring
+// system into alerts. Fields are kubectl-style JSONPath expressions, e.g.
+// "{.tags.kube_namespace}". Alerts selects the list of alerts in a payload;
+// empty treats the whole payload as one alert. The other expressions are
+// evaluated against each alert; Labels adds labels by name.
+type GenericWebhookMapping struct {
+	Alerts      string            `mapstructure:"alerts"`
+	Namespace   string            `mapstructure:"namespace"`
+	Pod         string            `mapstructure:"pod"`
+	AlertName   string            `mapstructure:"alertname"`
+	Severity    string            `mapstructure:"severity"`
+	Fingerprint string            `mapstructure:"fingerprint"`
+	Labels      map[string]string `mapstructure:"labels"`
+}
+
+// Expressions returns the JSONPath expressions of the mapping by field
+// name, skipping empty ones
+func (m GenericWebhookMapping) Expressions() map[string]string {
+	exprs := map[string]string{}
+	for name, expr := range map[string]string{
+		"alerts":      m.Alerts,
+		"namespace":   m.Namespace,
+		"pod":         m.Pod,
+		"alertname":   m.AlertName,
+		"severity":    m.Severity,
+		"fingerprint": m.Fingerprint,
+	} {
+		if expr != "" {
+			exprs[name] = expr
+		}
+	}
+	for name, expr := range m.Labels {
+		exprs["labels."+name] = expr
+	}
+	return exprs
+}
+
+// ExportConfig configures pushing incident records to external systems
+type ExportConfig struct {
+	Pushgateway PushgatewayConfig `mapstructure:"pushgateway"`
+}
+
+// PushgatewayConfig enables pushing incident records to a Prometheus
+// Pushgateway when URL is set
+type PushgatewayConfig struct {
+	URL     string        `mapstructure:"url"`
+	Job     string        `mapstructure:"job"`
+	Timeout time.Duration `mapstructure:"timeout"`
 }
 
 type LogCollectionConfig struct {
@@ -47,20 +446,383 @@ type LLMConfig struct {
 	Model       string  `mapstructure:"model"`
 	MaxTokens   int     `mapstructure:"max_tokens"`
 	Temperature float32 `mapstructure:"temperature"`
+	// MaxConcurrentRequests bounds in-flight requests to the provider;
+	// waiting analyses are served fairly across namespaces. 0 is unlimited.
+	MaxConcurrentRequests int             `mapstructure:"max_concurrent_requests"`
+	Budget                LLMBudgetConfig `mapstructure:"budget"`
+	// Prices are the USD prices of the models, by lowercase model name,
+	// that the estimated cost of analyses is computed from
+	Prices map[string]LLMPrice `mapstructure:"prices"`
+	// RerunModels are the models a re-run may ask for besides Model
+	RerunModels []string `mapstructure:"rerun_models"`
+}
+
+// AllowsModel reports whether a re-run may use model: Model or one of
+// RerunModels
+func (c LLMConfig) AllowsModel(model string) bool {
+	return model == c.Model || slices.Contains(c.RerunModels, model)
+}
+
+// LLMPrice is the USD price of a million prompt (input) and response
+// (output) tokens of a model
+type LLMPrice struct {
+	Input  float64 `mapstructure:"input"`
+	Output float64 `mapstructure:"output"`
+}
+
+// Cost estimates the USD cost of tokens of model; models without a price
+// cost nothing
+func (c LLMConfig) Cost(model string, input, output int) float64 {
+	price := c.Prices[strings.ToLower(model)]
+	return (float64(input)*price.Input + float64(output)*price.Output) / 1e6
+}
+
+// Token budget actions
+const (
+	// BudgetFallback answers over-budget analyses without the LLM
+	BudgetFallback = "fallback"
+	// BudgetDefer fails over-budget analyses; queued ones run again when
+	// the budget resets
+	BudgetDefer = "defer"
+)
+
+// LLMBudgetConfig caps the tokens (prompt and response) spent per UTC day,
+// in total and per namespace. Zero limits are unlimited.
+type LLMBudgetConfig struct {
+	DailyTokens          int `mapstructure:"daily_tokens"`
+	NamespaceDailyTokens int `mapstructure:"namespace_daily_tokens"`
+	// Namespaces overrides NamespaceDailyTokens for some namespaces
+	Namespaces map[string]int `mapstructure:"namespaces"`
+	// OnExceeded is BudgetFallback or BudgetDefer
+	OnExceeded string `mapstructure:"on_exceeded"`
+}
+
+// Enabled reports whether any token limit is set
+func (c LLMBudgetConfig) Enabled() bool {
+	return c.DailyTokens > 0 || c.NamespaceDailyTokens > 0 || len(c.Namespaces) > 0
 }
 
 type AgentConfig struct {
-	MaxParallelFetches int           `mapstructure:"max_parallel_fetches"`
-	AnalysisTimeout    time.Duration `mapstructure:"analysis_timeout"`
+	MaxParallelFetches int                  `mapstructure:"max_parallel_fetches"`
+	AnalysisTimeout    time.Duration        `mapstructure:"analysis_timeout"`
+	FailureBackoff     FailureBackoffConfig `mapstructure:"failure_backoff"`
+	// DedupWindow is how long a finished analysis is reused for identical
+	// requests (same namespace, pod, lookback and verbosity)
+	DedupWindow time.Duration `mapstructure:"dedup_window"`
+	Jobs        JobsConfig    `mapstructure:"jobs"`
+	// IncidentMinAlerts is how many alerts of one webhook must fire on pods
+	// of the same workload for them to be analyzed once, as an incident;
+	// 0 analyzes every alert on its own
+	IncidentMinAlerts int              `mapstructure:"incident_min_alerts"`
+	Recurrence        RecurrenceConfig `mapstructure:"recurrence"`
+	// Analyzers is the analysis pipeline, in order: rules, heuristics,
+	// llm or a registered custom analyzer. Empty runs rules then llm.
+	Analyzers  []string         `mapstructure:"analyzers"`
+	Escalation EscalationConfig `mapstructure:"escalation"`
+}
+
+// EscalationConfig controls the re-analysis of pod alerts whose LLM
+// analysis scored below MinScore, with the pod's controller and then its
+// namespace added to the prompt
+type EscalationConfig struct {
+	Enabled  bool `mapstructure:"enabled"`
+	MinScore int  `mapstructure:"min_score"`
+}
+
+// RecurrenceConfig controls how earlier analyses of the same alert on the
+// same pod are looked up. Window 0 disables the lookup; Threshold earlier
+// analyses within the window flag the incident as recurring.
+type RecurrenceConfig struct {
+	Window    time.Duration `mapstructure:"window"`
+	Threshold int           `mapstructure:"threshold"`
+}
+
+// JobsConfig sizes the queue of asynchronous analysis jobs
+// (?async=true requests and analyses started from the web UI). Webhook jobs
+// are also stored in the database until they finish, and attempted up to
+// MaxAttempts times while alerts fail for reasons that may pass, waiting
+// RetryBackoff before the second attempt and twice as long before each
+// following one.
+type JobsConfig struct {
+	Workers      int           `mapstructure:"workers"`
+	QueueSize    int           `mapstructure:"queue_size"`
+	MaxAttempts  int           `mapstructure:"max_attempts"`
+	RetryBackoff time.Duration `mapstructure:"retry_backoff"`
+}
+
+// FailureBackoffConfig controls how webhook analyses of a target that keeps
+// failing are spaced out. The delay starts at Initial and doubles with every
+// consecutive failure up to Max, or without a cap when Max is 0; after
+// AttentionThreshold failures the target is listed as needing attention in
+// the UI.
+type FailureBackoffConfig struct {
+	Initial            time.Duration `mapstructure:"initial"`
+	Max                time.Duration `mapstructure:"max"`
+	AttentionThreshold int           `mapstructure:"attention_threshold"`
 }
 
 type ServerConfig struct {
 	Port int    `mapstructure:"port"`
 	Host string `mapstructure:"host"`
+	// UserHeader and GroupsHeader name the identity headers set by an
+	// authenticating reverse proxy; when set, live log tails are authorized
+	// for that user instead of the server's service account. They are only
+	// read from requests sent by one of TrustedProxies.
+	UserHeader   string `mapstructure:"user_header"`
+	GroupsHeader string `mapstructure:"groups_header"`
+	// MaxTailDuration bounds how long a live log tail stays open
+	MaxTailDuration time.Duration `mapstructure:"max_tail_duration"`
+	// ShutdownTimeout bounds how long in-flight requests and analysis jobs
+	// may run on after SIGTERM before they are cancelled
+	ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout"`
+	// TemplatesDir replaces the web UI templates and static assets built
+	// into the binary with those of a directory laid out like
+	// internal/templates
+	TemplatesDir string `mapstructure:"templates_dir"`
+	// AdminToken is the bearer token required to delete analyses; the
+	// delete endpoints are disabled while it is empty
+	AdminToken string `mapstructure:"admin_token"`
+	// APIKeys are the keys accepted in the X-API-Key header. Once any is
+	// configured, the API and the dashboard require one.
+	APIKeys []APIKeyConfig `mapstructure:"api_keys"`
+	// OIDC accepts bearer JWTs from an OIDC issuer in addition to API keys
+	OIDC OIDCConfig `mapstructure:"oidc"`
+	// RateLimit limits the requests each client may make to the analyze
+	// endpoints
+	RateLimit RateLimitConfig `mapstructure:"rate_limit"`
+	// TrustedProxies are the addresses or CIDR ranges of reverse proxies
+	// whose X-Forwarded-For header names the client, for rate limiting and
+	// logs. Empty trusts none and uses the connection's address.
+	TrustedProxies []string `mapstructure:"trusted_proxies"`
+	// Webhook authenticates the AlertManager webhook on its own, for
+	// senders that cannot present an API key
+	Webhook WebhookAuthConfig `mapstructure:"webhook"`
+	// CORS lets browser apps on other origins call the JSON API
+	CORS CORSConfig `mapstructure:"cors"`
+	// SecurityHeaders are sent with every response
+	SecurityHeaders SecurityHeadersConfig `mapstructure:"security_headers"`
+	// TLS serves HTTPS directly instead of behind a terminating proxy
+	TLS ServerTLSConfig `mapstructure:"tls"`
+	// Readiness configures the dependency checks of /readyz
+	Readiness ReadinessConfig `mapstructure:"readiness"`
+}
+
+// TrustsProxy reports whether ip, the address a request came from, is one
+// of TrustedProxies
+func (s ServerConfig) TrustsProxy(ip string) bool {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+	for _, proxy := range s.TrustedProxies {
+		if _, network, err := net.ParseCIDR(proxy); err == nil {
+			if network.Contains(addr) {
+				return true
+			}
+		} else if addr.Equal(net.ParseIP(proxy)) {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadinessConfig bounds the checks of /readyz by Timeout. The LLM
+// credentials are only checked with CheckLLM, and once they passed, not
+// again for LLMInterval, since the provider rate limits the lookups.
+type ReadinessConfig struct {
+	CheckLLM    bool          `mapstructure:"check_llm"`
+	Timeout     time.Duration `mapstructure:"timeout"`
+	LLMInterval time.Duration `mapstructure:"llm_interval"`
+}
+
+// ServerTLSConfig serves HTTPS with CertFile and KeyFile once both are
+// set. With ClientCAFile, client certificates signed by those CAs are
+// verified; ClientAuth is "require" (the default) or "request", which also
+// lets clients without a certificate through to API key authentication.
+// The files are re-read when they change, checked every ReloadInterval, so
+// renewed certificates are picked up without a restart.
+type ServerTLSConfig struct {
+	CertFile       string        `mapstructure:"cert_file"`
+	KeyFile        string        `mapstructure:"key_file"`
+	ClientCAFile   string        `mapstructure:"client_ca_file"`
+	ClientAuth     string        `mapstructure:"client_auth"`
+	MinVersion     string        `mapstructure:"min_version"`
+	ReloadInterval time.Duration `mapstructure:"reload_interval"`
+}
+
+// Enabled reports whether HTTPS is served
+func (t ServerTLSConfig) Enabled() bool {
+	return t.CertFile != "" && t.KeyFile != ""
+}
+
+// CORSConfig answers cross-origin requests from AllowedOrigins ("*" for
+// any, or patterns like "https://*.example.com"). Credentials (the
+// dashboard cookie) are only allowed with explicit origins.
+type CORSConfig struct {
+	AllowedOrigins   []string      `mapstructure:"allowed_origins"`
+	AllowedMethods   []string      `mapstructure:"allowed_methods"`
+	AllowedHeaders   []string      `mapstructure:"allowed_headers"`
+	AllowCredentials bool          `mapstructure:"allow_credentials"`
+	MaxAge           time.Duration `mapstructure:"max_age"`
+}
+
+// SecurityHeadersConfig sets the standard browser security headers.
+// FrameOptions is DENY or SAMEORIGIN; an empty ContentSecurityPolicy or a
+// zero HSTSMaxAge leaves that header out (HSTS only makes sense behind
+// HTTPS).
+type SecurityHeadersConfig struct {
+	Enabled               bool          `mapstructure:"enabled"`
+	FrameOptions          string        `mapstructure:"frame_options"`
+	ContentSecurityPolicy string        `mapstructure:"content_security_policy"`
+	HSTSMaxAge            time.Duration `mapstructure:"hsts_max_age"`
+}
+
+// WebhookAuthConfig verifies AlertManager webhook requests by an
+// HMAC-SHA256 signature of the body in SignatureHeader ("sha256=<hex>"),
+// or by HTTP basic auth, which AlertManager sends with basic_auth in its
+// http_config. Any listed secret or credential is accepted, so a new one
+// can be added before the old one is removed.
+type WebhookAuthConfig struct {
+	Secrets         []string              `mapstructure:"secrets"`
+	SignatureHeader string                `mapstructure:"signature_header"`
+	BasicAuth       []BasicAuthCredential `mapstructure:"basic_auth"`
+}
+
+// Enabled reports whether webhook requests are verified
+func (w WebhookAuthConfig) Enabled() bool {
+	return len(w.Secrets) > 0 || len(w.BasicAuth) > 0
+}
+
+// BasicAuthCredential is a username and password pair
+type BasicAuthCredential struct {
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+}
+
+// RateLimitConfig allows each client, identified by its API key, OIDC user
+// or address, RequestsPerMinute requests to the analyze endpoints, with
+// bursts of up to Burst (RequestsPerMinute if unset). Zero disables the
+// limit.
+type RateLimitConfig struct {
+	RequestsPerMinute int `mapstructure:"requests_per_minute"`
+	Burst             int `mapstructure:"burst"`
+}
+
+// OIDCConfig accepts bearer JWTs issued by IssuerURL for ClientID. Token
+// holders get Scopes and may analyze the namespaces listed in their
+// NamespacesClaim plus those GroupNamespaces maps their GroupsClaim groups
+// to; namespace entries are glob patterns.
+type OIDCConfig struct {
+	IssuerURL       string              `mapstructure:"issuer_url"`
+	ClientID        string              `mapstructure:"client_id"`
+	UserClaim       string              `mapstructure:"user_claim"`
+	GroupsClaim     string              `mapstructure:"groups_claim"`
+	NamespacesClaim string              `mapstructure:"namespaces_claim"`
+	GroupNamespaces map[string][]string `mapstructure:"group_namespaces"`
+	Scopes          []string            `mapstructure:"scopes"`
+}
+
+// Enabled reports whether bearer JWTs are accepted
+func (o OIDCConfig) Enabled() bool {
+	return o.IssuerURL != ""
+}
+
+// Allows reports whether token holders are granted scope
+func (o OIDCConfig) Allows(scope string) bool {
+	return APIKeyConfig{Scopes: o.Scopes}.Allows(scope)
+}
+
+// API key scopes. Each scope includes the ones before it: analyze keys can
+// read, admin keys can do everything.
+const (
+	ScopeRead    = "read"
+	ScopeAnalyze = "analyze"
+	ScopeAdmin   = "admin"
+)
+
+// scopeRanks orders the scopes
+var scopeRanks = map[string]int{ScopeRead: 1, ScopeAnalyze: 2, ScopeAdmin: 3}
+
+// APIKeyConfig is a static API key. The key is read from the KeyEnv
+// environment variable when Key is empty.
+type APIKeyConfig struct {
+	Name   string   `mapstructure:"name"`
+	Key    string   `mapstructure:"key"`
+	KeyEnv string   `mapstructure:"key_env"`
+	Scopes []string `mapstructure:"scopes"`
+}
+
+// Allows reports whether the key grants scope
+func (k APIKeyConfig) Allows(scope string) bool {
+	for _, s := range k.Scopes {
+		if scopeRanks[s] >= scopeRanks[scope] {
+			return true
+		}
+	}
+	return false
 }
 
 type DatabaseConfig struct {
-	Path string `mapstructure:"path"`
+	// Driver is "sqlite" (the default), storing in the file at Path, or
+	// "postgres" or "mysql", connecting to DSN with the pool limits below (0
+	// for no limit)
+	Driver          string        `mapstructure:"driver"`
+	Path            string        `mapstructure:"path"`
+	DSN             string        `mapstructure:"dsn"`
+	MaxOpenConns    int           `mapstructure:"max_open_conns"`
+	MaxIdleConns    int           `mapstructure:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `mapstructure:"conn_max_lifetime"`
+	// RollupInterval is how often the daily stats rollup runs; RollupDays is
+	// how many recent days each run recomputes
+	RollupInterval time.Duration   `mapstructure:"rollup_interval"`
+	RollupDays     int             `mapstructure:"rollup_days"`
+	Retention      RetentionConfig `mapstructure:"retention"`
+}
+
+// RetentionConfig bounds the stored history. Every Interval, analyses older
+// than MaxAge and those beyond the newest MaxRows are pruned (0 keeps them
+// all), deleted analyses are purged once RestoreWindow has passed (0 keeps
+// them restorable), and every VacuumInterval a SQLite database is vacuumed
+// to return the freed space to the file system.
+type RetentionConfig struct {
+	MaxAge         time.Duration `mapstructure:"max_age"`
+	MaxRows        int           `mapstructure:"max_rows"`
+	RestoreWindow  time.Duration `mapstructure:"restore_window"`
+	Interval       time.Duration `mapstructure:"interval"`
+	VacuumInterval time.Duration `mapstructure:"vacuum_interval"`
+}
+
+// ArtifactsConfig selects where large collected blobs (full logs, events,
+// pod specs) are kept. An empty backend disables artifact storage. Blobs
+// over MaxSize bytes are cut down to their newest logs and events, or
+// skipped; blobs not written for MaxAge are removed every PruneInterval.
+// Zero MaxSize and MaxAge disable the limits.
+type ArtifactsConfig struct {
+	Backend       string        `mapstructure:"backend"`
+	Path          string        `mapstructure:"path"`
+	MaxSize       int           `mapstructure:"max_size"`
+	MaxAge        time.Duration `mapstructure:"max_age"`
+	PruneInterval time.Duration `mapstructure:"prune_interval"`
+	S3            S3Config      `mapstructure:"s3"`
+}
+
+type S3Config struct {
+	Endpoint        string `mapstructure:"endpoint"`
+	Bucket          string `mapstructure:"bucket"`
+	Prefix          string `mapstructure:"prefix"`
+	Region          string `mapstructure:"region"`
+	AccessKeyID     string `mapstructure:"access_key_id"`
+	SecretAccessKey string `mapstructure:"secret_access_key"`
+	UsePathStyle    bool   `mapstructure:"use_path_style"`
+}
+
+// EncryptionConfig holds the key sealing stored analysis results and
+// artifacts, which may contain log excerpts. Key is 32 random bytes, base64
+// encoded; KeyFile is a file holding it, such as a secret mounted from a
+// KMS. No key stores them in the clear.
+type EncryptionConfig struct {
+	Key     string `mapstructure:"key"`
+	KeyFile string `mapstructure:"key_file"`
 }
 
 func Load(configPath string) (*Config, error) {
@@ -69,13 +831,101 @@ func Load(configPath string) (*Config, error) {
 	// Set defaults
 	v.SetDefault("server.port", 8080)
 	v.SetDefault("server.host", "0.0.0.0")
+	v.SetDefault("server.max_tail_duration", "15m")
+	v.SetDefault("server.shutdown_timeout", "25s")
+	v.SetDefault("server.oidc.user_claim", "email")
+	v.SetDefault("server.oidc.groups_claim", "groups")
+	v.SetDefault("server.oidc.namespaces_claim", "namespaces")
+	v.SetDefault("server.oidc.scopes", []string{ScopeAnalyze})
+	v.SetDefault("server.webhook.signature_header", "X-Hepsre-Signature")
+	v.SetDefault("server.cors.allowed_methods", []string{"GET", "POST", "PUT", "PATCH", "DELETE"})
+	v.SetDefault("server.cors.allowed_headers", []string{"Content-Type", "Authorization", "X-API-Key"})
+	v.SetDefault("server.cors.max_age", "10m")
+	v.SetDefault("server.security_headers.enabled", true)
+	v.SetDefault("server.security_headers.frame_options", "DENY")
+	v.SetDefault("server.tls.client_auth", "require")
+	v.SetDefault("server.tls.min_version", "1.2")
+	v.SetDefault("server.tls.reload_interval", "1m")
+	v.SetDefault("server.readiness.timeout", "5s")
+	v.SetDefault("server.readiness.llm_interval", "5m")
 	v.SetDefault("alertmanager.poll_interval", "30s")
+	v.SetDefault("alertmanager.poll.enabled", false)
 	v.SetDefault("log_collection.default_lookback", "1h")
+	v.SetDefault("prometheus.step", "1m")
+	v.SetDefault("kubernetes.cache.resync", "10m")
+	v.SetDefault("kubernetes.watch.enabled", false)
+	v.SetDefault("kubernetes.watch.restart_threshold", 5)
+	v.SetDefault("kubernetes.watch.cooldown", "30m")
+	v.SetDefault("kubernetes.watch.severity", "warning")
 	v.SetDefault("llm.provider", "anthropic")
 	v.SetDefault("llm.model", "claude-sonnet-4-5")
 	v.SetDefault("llm.max_tokens", 4096)
 	v.SetDefault("llm.temperature", 0.2)
+	v.SetDefault("llm.max_concurrent_requests", 4)
+	v.SetDefault("llm.budget.daily_tokens", 0)
+	v.SetDefault("llm.budget.namespace_daily_tokens", 0)
+	v.SetDefault("llm.budget.on_exceeded", BudgetFallback)
+	v.SetDefault("database.driver", "sqlite")
 	v.SetDefault("database.path", "./hepsre.db")
+	v.SetDefault("database.max_open_conns", 10)
+	v.SetDefault("database.max_idle_conns", 5)
+	v.SetDefault("database.conn_max_lifetime", "30m")
+	v.SetDefault("database.rollup_interval", "10m")
+	v.SetDefault("database.rollup_days", 2)
+	v.SetDefault("database.retention.restore_window", "168h")
+	v.SetDefault("database.retention.interval", "1h")
+	v.SetDefault("database.retention.vacuum_interval", "24h")
+	v.SetDefault("artifacts.path", "./artifacts")
+	v.SetDefault("artifacts.max_size", 5<<20)
+	v.SetDefault("artifacts.prune_interval", "1h")
+	v.SetDefault("probes.image", "busybox:1.36")
+	v.SetDefault("probes.dependency_annotation", "hepsre.io/dependencies")
+	v.SetDefault("probes.timeout", "45s")
+	v.SetDefault("probes.connect_timeout", "3s")
+	v.SetDefault("agent.max_parallel_fetches", 5)
+	v.SetDefault("rules.enabled", true)
+	v.SetDefault("rules.answer_without_llm", true)
+	v.SetDefault("agent.failure_backoff.initial", "1m")
+	v.SetDefault("agent.failure_backoff.max", "1h")
+	v.SetDefault("agent.failure_backoff.attention_threshold", 3)
+	v.SetDefault("agent.dedup_window", "1m")
+	v.SetDefault("agent.incident_min_alerts", 3)
+	v.SetDefault("agent.recurrence.window", "168h")
+	v.SetDefault("agent.recurrence.threshold", 3)
+	v.SetDefault("agent.analyzers", []string{"rules", "llm"})
+	v.SetDefault("agent.escalation.enabled", true)
+	v.SetDefault("agent.escalation.min_score", 40)
+	v.SetDefault("agent.jobs.workers", 4)
+	v.SetDefault("agent.jobs.queue_size", 100)
+	v.SetDefault("agent.jobs.max_attempts", 3)
+	v.SetDefault("agent.jobs.retry_backoff", "2m")
+	v.SetDefault("report.verbosity", VerbosityStandard)
+	v.SetDefault("report.brief_max_tokens", 1024)
+	v.SetDefault("report.deep_max_tokens", 8192)
+	v.SetDefault("redaction.enabled", true)
+	v.SetDefault("redaction.env_values", true)
+	v.SetDefault("redaction.secret_values", true)
+	v.SetDefault("export.pushgateway.job", "hepsre")
+	v.SetDefault("export.pushgateway.timeout", "5s")
+	v.SetDefault("chaos.enabled", false)
+	v.SetDefault("remediation.allowed_verbs", []string{
+		"get", "describe", "logs", "top", "rollout", "scale", "set", "delete",
+		"patch", "label", "annotate", "cordon", "uncordon", "argo",
+	})
+	v.SetDefault("remediation.auto.enabled", false)
+	v.SetDefault("remediation.auto.actions", []string{"restart", "scale", "delete_pod"})
+	v.SetDefault("remediation.auto.max_replicas", 10)
+	v.SetDefault("remediation.auto.approval_ttl", "1h")
+	v.SetDefault("slack.timeout", "5s")
+	v.SetDefault("pagerduty.api_url", "https://api.pagerduty.com")
+	v.SetDefault("pagerduty.timeout", "10s")
+	v.SetDefault("opsgenie.api_url", "https://api.opsgenie.com")
+	v.SetDefault("opsgenie.tag_prefix", "root-cause:")
+	v.SetDefault("opsgenie.timeout", "10s")
+	v.SetDefault("cluster.label", "cluster")
+	v.SetDefault("tracing.enabled", false)
+	v.SetDefault("tracing.service_name", "hepsre")
+	v.SetDefault("tracing.sample_ratio", 1.0)
 
 	// Read from environment variables
 	v.AutomaticEnv()
@@ -88,6 +938,10 @@ func Load(configPath string) (*Config, error) {
 		v.SetConfigType("yaml")
 		v.AddConfigPath("./config")
 		v.AddConfigPath(".")
+		// For the CLI run from anywhere, e.g. as a kubectl plugin
+		if dir, err := os.UserConfigDir(); err == nil {
+			v.AddConfigPath(path.Join(dir, "hepsre"))
+		}
 	}
 
 	if err := v.ReadInConfig(); err != nil {
@@ -100,6 +954,17 @@ func Load(configPath string) (*Config, error) {
 	if err := v.Unmarshal(&config); err != nil {
 		return nil, err
 	}
+	if !ValidVerbosity(config.Report.Verbosity) {
+		return nil, fmt.Errorf("invalid report.verbosity %q", config.Report.Verbosity)
+	}
+	for _, route := range config.Report.Routes {
+		if !ValidVerbosity(route.Verbosity) {
+			return nil, fmt.Errorf("invalid report.routes verbosity %q", route.Verbosity)
+		}
+	}
+	if b := config.LLM.Budget.OnExceeded; b != BudgetFallback && b != BudgetDefer {
+		return nil, fmt.Errorf("invalid llm.budget.on_exceeded %q: use fallback or defer", b)
+	}
 
 	// Override with environment variable if set
 	if apiKey := os.Getenv("ANTHROPIC_API_KEY"); apiKey != "" {
@@ -108,6 +973,144 @@ func Load(configPath string) (*Config, error) {
 	if apiKey := os.Getenv("OPENAI_API_KEY"); apiKey != "" && config.LLM.Provider == "openai" {
 		config.LLM.APIKey = apiKey
 	}
+	if token := os.Getenv("HEPSRE_ADMIN_TOKEN"); token != "" {
+		config.Server.AdminToken = token
+	}
+	for i := range config.Server.APIKeys {
+		key := &config.Server.APIKeys[i]
+		if key.Key == "" && key.KeyEnv != "" {
+			key.Key = os.Getenv(key.KeyEnv)
+		}
+		if key.Name == "" || key.Key == "" {
+			return nil, fmt.Errorf("server.api_keys entry %d needs a name and a key", i+1)
+		}
+		for _, scope := range key.Scopes {
+			if scopeRanks[scope] == 0 {
+				return nil, fmt.Errorf("invalid scope %q for API key %s: use read, analyze or admin", scope, key.Name)
+			}
+		}
+	}
+	if oidc := config.Server.OIDC; oidc.Enabled() {
+		if oidc.ClientID == "" {
+			return nil, fmt.Errorf("server.oidc requires a client_id")
+		}
+		for _, scope := range oidc.Scopes {
+			if scopeRanks[scope] == 0 {
+				return nil, fmt.Errorf("invalid server.oidc scope %q: use read, analyze or admin", scope)
+			}
+		}
+	}
+	serverTLS := config.Server.TLS
+	if (serverTLS.CertFile == "") != (serverTLS.KeyFile == "") {
+		return nil, fmt.Errorf("server.tls requires both cert_file and key_file")
+	}
+	if serverTLS.ClientCAFile != "" && !serverTLS.Enabled() {
+		return nil, fmt.Errorf("server.tls.client_ca_file requires cert_file and key_file")
+	}
+	if serverTLS.ClientAuth != "require" && serverTLS.ClientAuth != "request" {
+		return nil, fmt.Errorf("invalid server.tls.client_auth %q: use require or request", serverTLS.ClientAuth)
+	}
+	if serverTLS.MinVersion != "1.2" && serverTLS.MinVersion != "1.3" {
+		return nil, fmt.Errorf("invalid server.tls.min_version %q: use 1.2 or 1.3", serverTLS.MinVersion)
+	}
+	for _, proxy := range config.Server.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			return nil, fmt.Errorf("invalid server.trusted_proxies entry %q: use an IP address or CIDR range", proxy)
+		}
+	}
+	if config.Server.UserHeader != "" && len(config.Server.TrustedProxies) == 0 {
+		return nil, fmt.Errorf("server.user_header requires server.trusted_proxies: the identity headers are only read from the authenticating proxy")
+	}
+	for _, origin := range config.Server.CORS.AllowedOrigins {
+		if origin == "*" && config.Server.CORS.AllowCredentials {
+			return nil, fmt.Errorf("server.cors.allow_credentials requires explicit allowed_origins, not \"*\"")
+		}
+		if _, err := path.Match(origin, ""); err != nil {
+			return nil, fmt.Errorf("invalid server.cors origin %q: %w", origin, err)
+		}
+	}
+	for _, secret := range config.Server.Webhook.Secrets {
+		if secret == "" {
+			return nil, fmt.Errorf("server.webhook.secrets must not contain empty secrets")
+		}
+	}
+	for _, cred := range config.Server.Webhook.BasicAuth {
+		if cred.Username == "" || cred.Password == "" {
+			return nil, fmt.Errorf("server.webhook.basic_auth entries require a username and password")
+		}
+	}
+	if secret := os.Getenv("WEBHOOK_SECRET"); secret != "" {
+		config.Server.Webhook.Secrets = append([]string{secret}, config.Server.Webhook.Secrets...)
+	}
+	if token := os.Getenv("PAGERDUTY_API_TOKEN"); token != "" {
+		config.PagerDuty.APIToken = token
+	}
+	if config.PagerDuty.PostNotes && (config.PagerDuty.APIToken == "" || config.PagerDuty.FromEmail == "") {
+		return nil, fmt.Errorf("pagerduty.post_notes requires an api_token and from_email")
+	}
+	for source, mapping := range config.GenericWebhooks {
+		if mapping.Namespace == "" || mapping.Pod == "" {
+			return nil, fmt.Errorf("generic_webhooks.%s requires namespace and pod expressions", source)
+		}
+		for field, expr := range mapping.Expressions() {
+			if err := jsonpath.New(field).Parse(expr); err != nil {
+				return nil, fmt.Errorf("invalid generic_webhooks.%s.%s expression %q: %w", source, field, expr, err)
+			}
+		}
+	}
+	if key := os.Getenv("OPSGENIE_API_KEY"); key != "" {
+		config.Opsgenie.APIKey = key
+	}
+	if config.Opsgenie.PostNotes && config.Opsgenie.APIKey == "" {
+		return nil, fmt.Errorf("opsgenie.post_notes requires an api_key")
+	}
+	if password := os.Getenv("ALERTMANAGER_PASSWORD"); password != "" {
+		config.AlertManager.Auth.Password = password
+	}
+	if token := os.Getenv("ALERTMANAGER_BEARER_TOKEN"); token != "" {
+		config.AlertManager.Auth.BearerToken = token
+	}
+	if dsn := os.Getenv("DATABASE_DSN"); dsn != "" {
+		config.Database.DSN = dsn
+	}
+	switch config.Database.Driver {
+	case "sqlite":
+	case "postgres", "mysql":
+		if config.Database.DSN == "" {
+			return nil, fmt.Errorf("database.driver %s requires a dsn", config.Database.Driver)
+		}
+	default:
+		return nil, fmt.Errorf("invalid database.driver %q: use sqlite, postgres or mysql", config.Database.Driver)
+	}
+	if r := config.Database.Retention; r.MaxAge < 0 || r.MaxRows < 0 || r.RestoreWindow < 0 {
+		return nil, fmt.Errorf("database.retention max_age, max_rows and restore_window must not be negative")
+	}
+	if j := config.Agent.Jobs; j.MaxAttempts < 1 || j.RetryBackoff < 0 {
+		return nil, fmt.Errorf("agent.jobs.max_attempts must be at least 1 and retry_backoff not negative")
+	}
+	if b := config.Agent.FailureBackoff; b.Initial < 0 || b.Max < 0 {
+		return nil, fmt.Errorf("agent.failure_backoff initial and max must not be negative")
+	}
+	for model, price := range config.LLM.Prices {
+		if price.Input < 0 || price.Output < 0 {
+			return nil, fmt.Errorf("llm.prices.%s must not be negative", model)
+		}
+	}
+	if a := config.Artifacts; a.MaxSize < 0 || a.MaxAge < 0 {
+		return nil, fmt.Errorf("artifacts max_size and max_age must not be negative")
+	}
+	if key := os.Getenv("HEPSRE_ENCRYPTION_KEY"); key != "" {
+		config.Encryption.Key = key
+	}
+	if config.Encryption.Key != "" && config.Encryption.KeyFile != "" {
+		return nil, fmt.Errorf("encryption requires either a key or a key_file, not both")
+	}
+	if key := os.Getenv("AWS_ACCESS_KEY_ID"); key != "" {
+		config.Artifacts.S3.AccessKeyID = key
+	}
+	if secret := os.Getenv("AWS_SECRET_ACCESS_KEY"); secret != "" {
+		config.Artifacts.S3.SecretAccessKey = secret
+	}
 
 	return &config, nil
 }
@@ -0,0 +1,105 @@
+package database
+
+import (
+	"fmt"
+	"time"
+)
+
+// AuditEvent records who did a security-relevant action: started an
+// analysis, re-ran, deleted or restored analyses, reloaded the
+// configuration or decided a remediation. Audit events are kept when the
+// analyses they name are deleted.
+type AuditEvent struct {
+	ID        int64
+	CreatedAt time.Time
+	// Actor is the proxy user, OIDC user, API key ("api-key:<name>"),
+	// Slack user ("slack:<name>") or, failing those, the client address
+	Actor  string
+	Action string
+	// Resource is the request method and path, or what the action applied
+	// to when it was not taken through the API
+	Resource string
+	// Status is the HTTP status answered, 0 outside of a request
+	Status    int
+	ClientIP  string
+	RequestID string
+	Detail    string
+}
+
+// AuditFilter selects audit events; zero fields match all. Until is
+// exclusive.
+type AuditFilter struct {
+	Actor  string
+	Action string
+	Since  time.Time
+	Until  time.Time
+	Limit  int
+	// BeforeID pages back from the event with that ID
+	BeforeID int64
+}
+
+// SaveAuditEvent records an audit event and returns its ID
+func (db *DB) SaveAuditEvent(e AuditEvent) (int64, error) {
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now()
+	}
+	id, err := db.conn.dialect.insertID(db.conn, `
+		INSERT INTO audit_events (created_at, actor, action, resource, status, client_ip, request_id, detail)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		e.CreatedAt, e.Actor, e.Action, e.Resource, e.Status, e.ClientIP, e.RequestID, e.Detail,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert audit event: %w", err)
+	}
+	return id, nil
+}
+
+// ListAuditEvents returns the most recent audit events matching the filter
+func (db *DB) ListAuditEvents(filter AuditFilter) ([]AuditEvent, error) {
+	query := `
+		SELECT id, created_at, actor, action, resource, status, client_ip, request_id, detail
+		FROM audit_events
+		WHERE (? = '' OR actor = ?) AND (? = '' OR action = ?)`
+	args := []any{filter.Actor, filter.Actor, filter.Action, filter.Action}
+	if !filter.Since.IsZero() {
+		query += " AND created_at >= ?"
+		args = append(args, filter.Since.Local())
+	}
+	if !filter.Until.IsZero() {
+		query += " AND created_at < ?"
+		args = append(args, filter.Until.Local())
+	}
+	if filter.BeforeID > 0 {
+		query += " AND id < ?"
+		args = append(args, filter.BeforeID)
+	}
+	query += " ORDER BY id DESC LIMIT ?"
+	args = append(args, filter.Limit)
+
+	rows, err := db.conn.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []AuditEvent
+	for rows.Next() {
+		var e AuditEvent
+		err := rows.Scan(
+			&e.ID,
+			&e.CreatedAt,
+			&e.Actor,
+			&e.Action,
+			&e.Resource,
+			&e.Status,
+			&e.ClientIP,
+			&e.RequestID,
+			&e.Detail,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
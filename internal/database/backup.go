@@ -0,0 +1,581 @@
+package database
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Backup formats. JSONL backups load into any dialect, SQL backups into
+// the dialect they were exported from, also with its own client (sqlite3,
+// psql, mysql) once the schema is migrated.
+const (
+	BackupJSONL = "jsonl"
+	BackupSQL   = "sql"
+)
+
+// backupFormat names backups in their first line
+const backupFormat = "hepsre-backup"
+
+// backupTables are the tables a backup holds, with whether their id column
+// is auto-incremented. A backup records its schema version instead of
+// schema_migrations.
+var backupTables = []struct {
+	name   string
+	serial bool
+}{
+	{"analyses", true},
+	{"analysis_versions", true},
+	{"feedback", true},
+	{"analysis_tags", false},
+	{"incidents", true},
+	{"incident_analyses", false},
+	{"analysis_embeddings", false},
+	{"alert_deliveries", false},
+	{"remediations", true},
+	{"remediation_events", true},
+	{"audit_events", true},
+	{"target_failures", false},
+	{"jobs", false},
+	{"daily_stats", false},
+}
+
+// backupHeader is the first line of a backup: the JSON object of a JSONL
+// backup, or a comment of key=value fields in a SQL backup
+type backupHeader struct {
+	Format        string    `json:"format"`
+	SchemaVersion int       `json:"schema_version"`
+	Dialect       string    `json:"dialect"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// backupRow is a line of a JSONL backup after the header. The last line
+// has End set and counts the rows, so that imports tell a backup cut short
+// from a complete one.
+type backupRow struct {
+	Table string         `json:"table,omitempty"`
+	Row   map[string]any `json:"row,omitempty"`
+	End   bool           `json:"end,omitempty"`
+	Rows  int            `json:"rows,omitempty"`
+}
+
+// backupEnd is the last line of a SQL backup, followed by the row count
+const backupEnd = "-- end of " + backupFormat + " rows="
+
+// Export writes a backup of the database in format, BackupJSONL or
+// BackupSQL, from one consistent snapshot. It ends with the number of rows
+// written, which a backup cut short by an error lacks.
+func (db *DB) Export(ctx context.Context, w io.Writer, format string) error {
+	if format != BackupJSONL && format != BackupSQL {
+		return fmt.Errorf("unknown backup format %q: use %s or %s", format, BackupJSONL, BackupSQL)
+	}
+	current, _, err := db.SchemaVersion()
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.conn.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	d := db.conn.dialect
+	bw := bufio.NewWriter(w)
+	header := backupHeader{Format: backupFormat, SchemaVersion: current, Dialect: d.name, CreatedAt: time.Now()}
+	enc := json.NewEncoder(bw)
+	if format == BackupJSONL {
+		err = enc.Encode(header)
+	} else {
+		_, err = fmt.Fprintf(bw, "-- %s schema_version=%d dialect=%s created_at=%s\n",
+			header.Format, header.SchemaVersion, header.Dialect, header.CreatedAt.Format(time.RFC3339))
+	}
+	if err != nil {
+		return err
+	}
+
+	n := 0
+	for _, table := range backupTables {
+		rows, err := tx.QueryContext(ctx, "SELECT * FROM "+table.name+" ORDER BY 1")
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", table.name, err)
+		}
+		columns, err := rows.Columns()
+		if err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to read %s: %w", table.name, err)
+		}
+		values := make([]any, len(columns))
+		dest := make([]any, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		insert := "INSERT INTO " + table.name + " (" + strings.Join(columns, ", ") + ") VALUES ("
+
+		for rows.Next() {
+			if err := rows.Scan(dest...); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan %s: %w", table.name, err)
+			}
+			// Vectors are backed up as numbers, the same in every dialect
+			vectors := make(map[int][]float32)
+			for i, column := range columns {
+				if embeddingColumns[table.name] != column {
+					continue
+				}
+				if vectors[i], err = db.decodeVector(values[i]); err != nil {
+					rows.Close()
+					return fmt.Errorf("failed to read %s.%s: %w", table.name, column, err)
+				}
+			}
+			if format == BackupJSONL {
+				row := make(map[string]any, len(columns))
+				for i, column := range columns {
+					if v, ok := vectors[i]; ok {
+						row[column] = v
+					} else {
+						row[column] = backupValue(values[i])
+					}
+				}
+				err = enc.Encode(backupRow{Table: table.name, Row: row})
+			} else {
+				literals := make([]string, len(values))
+				for i, v := range values {
+					if vector, ok := vectors[i]; ok {
+						literals[i] = db.vectorLiteral(vector)
+					} else {
+						literals[i] = d.literal(backupValue(v))
+					}
+				}
+				_, err = bw.WriteString(insert + strings.Join(literals, ", ") + ");\n")
+			}
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			n++
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", table.name, err)
+		}
+
+		if format == BackupSQL && table.serial && d.resetSequence != "" {
+			if _, err := fmt.Fprintf(bw, d.resetSequence+";\n", table.name); err != nil {
+				return err
+			}
+		}
+	}
+
+	if format == BackupJSONL {
+		err = enc.Encode(backupRow{End: true, Rows: n})
+	} else {
+		_, err = fmt.Fprintf(bw, "%s%d\n", backupEnd, n)
+	}
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// backupValue converts a scanned column to a string, number or time; some
+// drivers scan text as bytes
+func backupValue(v any) any {
+	if b, ok := v.([]byte); ok {
+		return string(b)
+	}
+	return v
+}
+
+// literal renders a backed up value as a SQL literal of the dialect
+func (d *dialect) literal(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "NULL"
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case bool:
+		if v {
+			return "1"
+		}
+		return "0"
+	case time.Time:
+		if v.IsZero() && d.zeroTime != "" {
+			return d.quote(d.zeroTime)
+		}
+		if d.duplicateKey {
+			v = v.UTC()
+		}
+		return d.quote(v.Format(d.timeLiteral))
+	default:
+		return d.quote(fmt.Sprint(v))
+	}
+}
+
+// quote renders a string literal
+func (d *dialect) quote(s string) string {
+	if d.backslashEscapes {
+		s = strings.ReplaceAll(s, `\`, `\\`)
+	}
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// Import loads a backup written by Export and returns the number of rows
+// loaded. The database must have no rows in the backed up tables. It is
+// migrated to the schema version of the backup for loading and back to the
+// latest afterwards, so backups of older releases load too; a backup of a
+// newer schema is rejected. SQL backups only load into their own dialect.
+func (db *DB) Import(ctx context.Context, r io.Reader) (int, error) {
+	br := bufio.NewReader(r)
+	first, err := br.ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return 0, fmt.Errorf("failed to read backup: %w", err)
+	}
+	header, format, err := parseBackupHeader(first)
+	if err != nil {
+		return 0, err
+	}
+
+	d := db.conn.dialect
+	current, latest, err := db.SchemaVersion()
+	if err != nil {
+		return 0, err
+	}
+	if header.SchemaVersion > latest {
+		return 0, fmt.Errorf("the backup has schema version %d, newer than this build's %d: import it with a newer release", header.SchemaVersion, latest)
+	}
+	if format == BackupSQL && header.Dialect != d.name {
+		return 0, fmt.Errorf("a SQL backup of %s cannot be loaded into %s: export a %s backup instead", header.Dialect, d.name, BackupJSONL)
+	}
+	for _, table := range backupTables {
+		var one int
+		err := db.conn.QueryRowContext(ctx, "SELECT 1 FROM "+table.name+" LIMIT 1").Scan(&one)
+		if err == nil {
+			return 0, fmt.Errorf("the database already has rows in %s: import into an empty database", table.name)
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return 0, fmt.Errorf("failed to check %s: %w", table.name, err)
+		}
+	}
+
+	if header.SchemaVersion != current {
+		if err := db.Migrate(header.SchemaVersion); err != nil {
+			return 0, err
+		}
+	}
+
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var n int
+	if format == BackupJSONL {
+		n, err = db.importJSONL(ctx, tx, br)
+	} else {
+		n, err = importSQL(ctx, tx, br)
+	}
+	if err != nil {
+		return 0, err
+	}
+	if d.resetSequence != "" {
+		for _, table := range backupTables {
+			if !table.serial {
+				continue
+			}
+			if _, err := tx.ExecContext(ctx, fmt.Sprintf(d.resetSequence, table.name)); err != nil {
+				return 0, fmt.Errorf("failed to reset the id sequence of %s: %w", table.name, err)
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit import: %w", err)
+	}
+
+	if header.SchemaVersion < latest {
+		if err := db.Migrate(-1); err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// parseBackupHeader reads the first line of a backup and returns it with
+// the format of the backup
+func parseBackupHeader(line string) (backupHeader, string, error) {
+	var header backupHeader
+	format := BackupJSONL
+	if fields, ok := strings.CutPrefix(strings.TrimSpace(line), "-- "+backupFormat+" "); ok {
+		format = BackupSQL
+		header.Format = backupFormat
+		for _, field := range strings.Fields(fields) {
+			key, value, _ := strings.Cut(field, "=")
+			switch key {
+			case "schema_version":
+				header.SchemaVersion, _ = strconv.Atoi(value)
+			case "dialect":
+				header.Dialect = value
+			case "created_at":
+				header.CreatedAt, _ = time.Parse(time.RFC3339, value)
+			}
+		}
+	} else if err := json.Unmarshal([]byte(line), &header); err != nil {
+		header.Format = ""
+	}
+	if header.Format != backupFormat || header.SchemaVersion <= 0 {
+		return header, "", fmt.Errorf("not a backup: it should start with a %s header", backupFormat)
+	}
+	return header, format, nil
+}
+
+// errTruncatedBackup is returned for backups without their last line
+var errTruncatedBackup = errors.New("the backup is incomplete: it lacks its last line, export it again")
+
+// importJSONL inserts the rows of a JSONL backup
+func (db *DB) importJSONL(ctx context.Context, tx *sqlTx, r io.Reader) (int, error) {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	timeColumns := map[string]map[string]bool{}
+	statements := map[string]*sql.Stmt{}
+	defer func() {
+		for _, stmt := range statements {
+			stmt.Close()
+		}
+	}()
+
+	n := 0
+	for {
+		var line backupRow
+		if err := dec.Decode(&line); errors.Is(err, io.EOF) {
+			return n, errTruncatedBackup
+		} else if err != nil {
+			return n, fmt.Errorf("invalid backup line %d: %w", n+2, err)
+		}
+		if line.End {
+			if line.Rows != n {
+				return n, fmt.Errorf("the backup has %d rows but should have %d", n, line.Rows)
+			}
+			return n, nil
+		}
+
+		times, ok := timeColumns[line.Table]
+		if !ok {
+			var err error
+			if times, err = tableTimeColumns(ctx, tx, line.Table); err != nil {
+				return n, err
+			}
+			timeColumns[line.Table] = times
+		}
+
+		columns := make([]string, 0, len(line.Row))
+		for column := range line.Row {
+			if _, ok := times[column]; !ok {
+				return n, fmt.Errorf("backup line %d: %s has no column %s", n+2, line.Table, column)
+			}
+			columns = append(columns, column)
+		}
+		sort.Strings(columns)
+		args := make([]any, len(columns))
+		for i, column := range columns {
+			var v any
+			var err error
+			if embeddingColumns[line.Table] == column {
+				v, err = db.importVector(line.Row[column])
+			} else {
+				v, err = importValue(line.Row[column], times[column])
+			}
+			if err != nil {
+				return n, fmt.Errorf("backup line %d: %s.%s: %w", n+2, line.Table, column, err)
+			}
+			args[i] = v
+		}
+
+		query := "INSERT INTO " + line.Table + " (" + strings.Join(columns, ", ") + ") VALUES (" +
+			strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ") + ")"
+		stmt, ok := statements[query]
+		if !ok {
+			var err error
+			if stmt, err = tx.PrepareContext(ctx, tx.dialect.rebind(query)); err != nil {
+				return n, fmt.Errorf("failed to prepare insert into %s: %w", line.Table, err)
+			}
+			statements[query] = stmt
+		}
+		if _, err := stmt.ExecContext(ctx, args...); err != nil {
+			return n, fmt.Errorf("backup line %d: failed to insert into %s: %w", n+2, line.Table, err)
+		}
+		n++
+	}
+}
+
+// tableTimeColumns returns the columns of a backed up table, true for
+// those holding times
+func tableTimeColumns(ctx context.Context, tx *sqlTx, table string) (map[string]bool, error) {
+	known := false
+	for _, t := range backupTables {
+		known = known || t.name == table
+	}
+	if !known {
+		return nil, fmt.Errorf("the backup has rows of unknown table %q", table)
+	}
+
+	rows, err := tx.QueryContext(ctx, "SELECT * FROM "+table+" WHERE 1 = 0")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the columns of %s: %w", table, err)
+	}
+	defer rows.Close()
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the columns of %s: %w", table, err)
+	}
+
+	columns := make(map[string]bool, len(types))
+	for _, t := range types {
+		name := strings.ToUpper(t.DatabaseTypeName())
+		columns[t.Name()] = strings.Contains(name, "TIME") || strings.Contains(name, "DATE")
+	}
+	return columns, nil
+}
+
+// importValue converts a JSON value of a backup to a query argument
+func importValue(v any, isTime bool) (any, error) {
+	switch v := v.(type) {
+	case nil, bool:
+		return v, nil
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i, nil
+		}
+		return v.Float64()
+	case string:
+		if !isTime {
+			return v, nil
+		}
+		t, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return nil, err
+		}
+		if t.IsZero() {
+			return t, nil
+		}
+		return t.Local(), nil
+	default:
+		return nil, fmt.Errorf("unexpected value %v", v)
+	}
+}
+
+// importVector converts a vector of a backup, an array of numbers, to a
+// query argument
+func (db *DB) importVector(v any) (any, error) {
+	numbers, ok := v.([]any)
+	if !ok {
+		return nil, fmt.Errorf("unexpected vector %v", v)
+	}
+	vector := make([]float32, len(numbers))
+	for i, n := range numbers {
+		number, ok := n.(json.Number)
+		if !ok {
+			return nil, fmt.Errorf("unexpected vector element %v", n)
+		}
+		f, err := strconv.ParseFloat(number.String(), 32)
+		if err != nil {
+			return nil, err
+		}
+		vector[i] = float32(f)
+	}
+	return db.encodeVector(vector), nil
+}
+
+// importSQL runs the statements of a SQL backup and returns the number of
+// rows they inserted
+func importSQL(ctx context.Context, tx *sqlTx, r *bufio.Reader) (int, error) {
+	n := 0
+	for {
+		statement, err := nextStatement(r, tx.dialect.backslashEscapes)
+		if errors.Is(err, io.EOF) {
+			return n, errTruncatedBackup
+		}
+		if err != nil {
+			return n, fmt.Errorf("failed to read backup: %w", err)
+		}
+		if count, ok := strings.CutPrefix(statement, backupEnd); ok {
+			if rows, _ := strconv.Atoi(count); rows != n {
+				return n, fmt.Errorf("the backup has %d rows but should have %d", n, rows)
+			}
+			return n, nil
+		}
+		if strings.HasPrefix(statement, "--") {
+			continue
+		}
+		// Run as written: literals hold the values, not placeholders
+		res, err := tx.Tx.ExecContext(ctx, statement)
+		if err != nil {
+			return n, fmt.Errorf("failed to run %.80s: %w", statement, err)
+		}
+		if strings.HasPrefix(statement, "INSERT") {
+			rows, err := res.RowsAffected()
+			if err != nil {
+				return n, err
+			}
+			n += int(rows)
+		}
+	}
+}
+
+// nextStatement reads the next statement of a SQL backup without its
+// semicolon, respecting string literals, or the next "--" comment line.
+// It returns io.EOF when there are none left.
+func nextStatement(r *bufio.Reader, backslashEscapes bool) (string, error) {
+	var sb strings.Builder
+	quoted := false
+	for {
+		c, err := r.ReadByte()
+		if errors.Is(err, io.EOF) {
+			if quoted {
+				return "", io.ErrUnexpectedEOF
+			}
+			if s := strings.TrimSpace(sb.String()); s != "" {
+				return s, nil
+			}
+			return "", io.EOF
+		}
+		if err != nil {
+			return "", err
+		}
+
+		switch {
+		case quoted && c == '\\' && backslashEscapes:
+			sb.WriteByte(c)
+			if c, err = r.ReadByte(); err != nil {
+				return "", io.ErrUnexpectedEOF
+			}
+		case c == '\'':
+			quoted = !quoted
+		case !quoted && c == ';':
+			if s := strings.TrimSpace(sb.String()); s != "" {
+				return s, nil
+			}
+			continue
+		case !quoted && c == '-' && strings.TrimSpace(sb.String()) == "":
+			if next, err := r.Peek(1); err == nil && next[0] == '-' {
+				comment, err := r.ReadString('\n')
+				if err != nil && !errors.Is(err, io.EOF) {
+					return "", err
+				}
+				return strings.TrimSpace("-" + comment), nil
+			}
+		}
+		sb.WriteByte(c)
+	}
+}
@@ -1,77 +1,223 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"path"
+	"strings"
 	"time"
 
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/emirozbir/micro-sre/internal/config"
+	"github.com/emirozbir/micro-sre/internal/encryption"
 	"github.com/emirozbir/micro-sre/internal/models"
+	_ "github.com/mattn/go-sqlite3"
 )
 
-const schema = `
-CREATE TABLE IF NOT EXISTS analyses (
-	id INTEGER PRIMARY KEY AUTOINCREMENT,
-	created_at DATETIME NOT NULL,
-	alert_name TEXT NOT NULL,
-	namespace TEXT NOT NULL,
-	pod_name TEXT NOT NULL,
-	severity TEXT NOT NULL,
-	alert_started_at DATETIME NOT NULL,
-	root_cause TEXT NOT NULL,
-	confidence TEXT NOT NULL,
-	analysis_json TEXT NOT NULL,
-	UNIQUE(namespace, pod_name, alert_started_at)
-);
-
-CREATE INDEX IF NOT EXISTS idx_created_at ON analyses(created_at DESC);
-CREATE INDEX IF NOT EXISTS idx_namespace_pod ON analyses(namespace, pod_name);
-CREATE INDEX IF NOT EXISTS idx_severity ON analyses(severity);
-`
-
+// DB is the Store on a SQL database: SQLite, PostgreSQL or MySQL
 type DB struct {
-	conn *sql.DB
+	conn *sqlConn
+	// cipher seals the analysis_json columns; nil stores them in the clear
+	cipher *encryption.Cipher
+	// pgvector is set when embeddings are pgvector vectors rather than bytes
+	pgvector bool
 }
 
+// Analysis lifecycle states. Analyses are recorded as running when they
+// start and end completed or failed; pending ones wait to start. Only
+// completed analyses carry a root cause.
+const (
+	AnalysisPending   = "pending"
+	AnalysisRunning   = "running"
+	AnalysisCompleted = "completed"
+	AnalysisFailed    = "failed"
+)
+
 type StoredAnalysis struct {
 	ID              int64
 	CreatedAt       time.Time
 	AlertName       string
+	Cluster         string
 	Namespace       string
 	PodName         string
 	Severity        string
 	AlertStartedAt  time.Time
 	RootCause       string
 	Confidence      string
+	ConfidenceScore int
+	// Status is the lifecycle state; Error says why a failed analysis failed
+	Status         string
+	Error          string
+	AnalysisResult models.AnalysisResult
+	// Versions holds re-analyses and Feedback the ratings and corrections,
+	// newest first; they are only populated on request
+	Versions []AnalysisVersion
+	Feedback []FeedbackRecord
+	// Tags are the labels readers attached, sorted
+	Tags []string
+	// IncidentID is the incident grouping the analysis, 0 if none; it is
+	// only populated by GetAnalysis
+	IncidentID int64
+	// DeletedAt is when a deleted analysis was deleted; it is only
+	// populated by FindAnalyses
+	DeletedAt time.Time
+}
+
+// AnalysisVersion is a re-analysis of a stored analysis. The original
+// analysis is version 1; re-analyses are numbered from 2.
+type AnalysisVersion struct {
+	ID              int64
+	AnalysisID      int64
+	Version         int
+	CreatedAt       time.Time
+	Model           string
+	PromptVersion   string
+	RootCause       string
+	Confidence      string
+	ConfidenceScore int
 	AnalysisResult  models.AnalysisResult
 }
 
-// New creates a new database connection and initializes the schema
+// AnalysisFilter selects stored analyses, leaving out deleted ones unless
+// Deleted is set. RootCause matches analyses whose root cause contains the
+// text, ignoring case; Until is exclusive.
+type AnalysisFilter struct {
+	AlertName  string
+	Cluster    string
+	Namespace  string
+	PodName    string
+	Severity   string
+	Confidence string
+	Status     string
+	RootCause  string
+	// NamespacePatterns, unless nil, selects analyses whose namespace
+	// matches one of the glob patterns (path.Match syntax); an empty list
+	// selects none
+	NamespacePatterns []string
+	// Tags selects analyses carrying all of the tags
+	Tags []string
+	// IncidentID selects the analyses grouped in an incident
+	IncidentID int64
+	Since      time.Time
+	Until      time.Time
+	// Deleted selects the deleted analyses instead, those deleted since
+	// DeletedSince if it is set
+	Deleted      bool
+	DeletedSince time.Time
+	// Sort is one of the analysis list sort orders; newest first by default
+	Sort   string
+	Limit  int
+	Offset int
+}
+
+// Open connects to the database selected by cfg.Driver, SQLite unless it
+// is "postgres" or "mysql", and initializes the schema. The pool limits
+// only apply to PostgreSQL and MySQL. Analysis results are sealed with
+// cipher unless it is nil.
+func Open(cfg config.DatabaseConfig, cipher *encryption.Cipher) (Store, error) {
+	db, err := open(cfg)
+	if err != nil {
+		return nil, err
+	}
+	db.cipher = cipher
+	return db, nil
+}
+
+func open(cfg config.DatabaseConfig) (*DB, error) {
+	d, dsn := postgresDialect, cfg.DSN
+	switch cfg.Driver {
+	case "postgres":
+	case "mysql":
+		var err error
+		if dsn, err = mysqlDSN(cfg.DSN); err != nil {
+			return nil, err
+		}
+		d = mysqlDialect
+	default:
+		return New(cfg.Path)
+	}
+
+	conn, err := sql.Open(d.driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	conn.SetMaxOpenConns(cfg.MaxOpenConns)
+	conn.SetMaxIdleConns(cfg.MaxIdleConns)
+	conn.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	return initialize(conn, d)
+}
+
+// sqlitePragmas are set on every connection of the pool: foreign keys,
+// WAL mode, so that reads run alongside the writer, and NORMAL sync, which
+// in WAL mode is safe from corruption and only syncs at checkpoints. The
+// busy timeout makes writes wait for other processes, such as "hepsre
+// migrate", rather than fail; writes of this one queue on the write lock.
+const sqlitePragmas = "_foreign_keys=on&_journal_mode=WAL&_synchronous=NORMAL&_busy_timeout=5000"
+
+// New creates a new SQLite database connection and initializes the schema
 func New(dbPath string) (*DB, error) {
-	conn, err := sql.Open("sqlite3", dbPath)
+	separator := "?"
+	if strings.Contains(dbPath, "?") {
+		separator = "&"
+	}
+	conn, err := sql.Open(sqliteDialect.driver, dbPath+separator+sqlitePragmas)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
-	// Enable foreign keys and WAL mode for better performance
-	if _, err := conn.Exec("PRAGMA foreign_keys = ON"); err != nil {
+	if err := upgradeLegacySQLite(conn); err != nil {
 		conn.Close()
-		return nil, fmt.Errorf("failed to enable foreign keys: %w", err)
+		return nil, fmt.Errorf("failed to migrate schema: %w", err)
 	}
 
-	if _, err := conn.Exec("PRAGMA journal_mode = WAL"); err != nil {
-		conn.Close()
-		return nil, fmt.Errorf("failed to enable WAL mode: %w", err)
+	return initialize(conn, sqliteDialect)
+}
+
+// legacySQLiteColumns were added to SQLite databases by ALTER statements
+// before the schema was versioned
+var legacySQLiteColumns = []string{
+	`ALTER TABLE analyses ADD COLUMN confidence_score INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE analysis_versions ADD COLUMN confidence_score INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE analyses ADD COLUMN cluster TEXT NOT NULL DEFAULT ''`,
+}
+
+// upgradeLegacySQLite adds the columns of the first versioned migration to
+// a database created before schema_migrations existed, whose tables that
+// migration skips. A "duplicate column name" error means the column is
+// already there.
+func upgradeLegacySQLite(conn *sql.DB) error {
+	var legacy bool
+	err := conn.QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = 'analyses')
+		   AND NOT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations')
+	`).Scan(&legacy)
+	if err != nil || !legacy {
+		return err
 	}
 
-	// Create schema
-	if _, err := conn.Exec(schema); err != nil {
+	for _, statement := range legacySQLiteColumns {
+		if _, err := conn.Exec(statement); err != nil && !strings.Contains(err.Error(), "duplicate column name") {
+			return err
+		}
+	}
+	return nil
+}
+
+// initialize applies the pending schema migrations of the dialect on conn.
+// conn is closed if it fails.
+func initialize(conn *sql.DB, d *dialect) (*DB, error) {
+	db := &DB{conn: &sqlConn{DB: conn, dialect: d}}
+	if err := db.Migrate(-1); err != nil {
 		conn.Close()
-		return nil, fmt.Errorf("failed to create schema: %w", err)
+		return nil, err
 	}
+	return db, nil
+}
 
-	return &DB{conn: conn}, nil
+// Ping checks that the database is reachable
+func (db *DB) Ping(ctx context.Context) error {
+	return db.conn.PingContext(ctx)
 }
 
 // Close closes the database connection
@@ -79,54 +225,177 @@ func (db *DB) Close() error {
 	return db.conn.Close()
 }
 
-// SaveAnalysis saves an analysis result to the database
+// SaveAnalysis saves a completed analysis result to the database
 func (db *DB) SaveAnalysis(result *models.AnalysisResult) (int64, error) {
-	analysisJSON, err := json.Marshal(result)
+	return db.insertAnalysis(db.conn, 0, result)
+}
+
+// insertAnalysis stores a completed analysis as row id, or as a new row if
+// id is 0, updating the analysis of the same alert start on the pod if
+// there is one, deleted or not, and returns the ID of the row written
+func (db *DB) insertAnalysis(q execQuerier, id int64, result *models.AnalysisResult) (int64, error) {
+	analysisJSON, err := db.marshalAnalysis(result)
 	if err != nil {
 		return 0, fmt.Errorf("failed to marshal analysis: %w", err)
 	}
 
-	query := `
-		INSERT INTO analyses (
-			created_at, alert_name, namespace, pod_name, severity,
-			alert_started_at, root_cause, confidence, analysis_json
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
-		ON CONFLICT(namespace, pod_name, alert_started_at)
-		DO UPDATE SET
-			created_at = excluded.created_at,
-			alert_name = excluded.alert_name,
-			severity = excluded.severity,
-			root_cause = excluded.root_cause,
-			confidence = excluded.confidence,
-			analysis_json = excluded.analysis_json
-	`
-
-	res, err := db.conn.Exec(
-		query,
+	usage := llmUsage(result)
+	columns := "created_at, alert_name, cluster, namespace, pod_name, severity, alert_started_at, root_cause, confidence, confidence_score, status, error_message, provider, model, prompt_tokens, completion_tokens, cost, analysis_json, deleted_at"
+	values := "?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?"
+	args := []any{
 		time.Now(),
 		result.Alert.Name,
+		result.Alert.Cluster,
 		result.Alert.Namespace,
-		result.Alert.Pod,
+		result.Alert.Target(),
 		result.Alert.Severity,
 		result.Alert.StartedAt,
 		result.Analysis.RootCause,
 		result.Analysis.Confidence,
-		string(analysisJSON),
-	)
+		result.Analysis.ConfidenceScore,
+		AnalysisCompleted,
+		"",
+		result.Provider,
+		result.Model,
+		usage.PromptTokens,
+		usage.CompletionTokens,
+		usage.CostUSD,
+		analysisJSON,
+		nil,
+	}
+	if id != 0 {
+		columns = "id, " + columns
+		values = "?, " + values
+		args = append([]any{id}, args...)
+	}
+
+	query := "INSERT INTO analyses (" + columns + ") VALUES (" + values + ")" +
+		db.conn.dialect.upsert([]string{"cluster", "namespace", "pod_name", "alert_started_at"}, "id",
+			"created_at", "alert_name", "severity", "root_cause",
+			"confidence", "confidence_score", "status", "error_message", "provider", "model",
+			"prompt_tokens", "completion_tokens", "cost", "analysis_json", "deleted_at")
+
+	id, err = db.conn.dialect.insertID(q, query, args...)
 	if err != nil {
 		return 0, fmt.Errorf("failed to insert analysis: %w", err)
 	}
 
-	return res.LastInsertId()
+	return id, nil
+}
+
+// marshalAnalysis encodes an analysis result for the analysis_json
+// columns, sealed when an encryption key is configured
+func (db *DB) marshalAnalysis(result *models.AnalysisResult) (string, error) {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return "", err
+	}
+	return string(db.cipher.Seal(data)), nil
+}
+
+// unmarshalAnalysis decodes an analysis_json column, sealed or not
+func (db *DB) unmarshalAnalysis(column string, result *models.AnalysisResult) error {
+	data, err := db.cipher.Open([]byte(column))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, result)
+}
+
+// llmUsage returns the LLM usage of result, zero when the LLM was not
+// called
+func llmUsage(result *models.AnalysisResult) models.LLMUsage {
+	if result.Usage == nil {
+		return models.LLMUsage{}
+	}
+	return *result.Usage
+}
+
+// StartAnalysis records an analysis of alert in the given state, pending or
+// running, before it has a result, and returns its ID. alert.StartedAt
+// should be the time the analysis was requested, which keeps the record
+// apart from the finished analyses of the pod.
+func (db *DB) StartAnalysis(alert models.AlertSummary, status string) (int64, error) {
+	analysisJSON, err := db.marshalAnalysis(&models.AnalysisResult{Alert: alert})
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal analysis: %w", err)
+	}
+
+	id, err := db.conn.dialect.insertID(db.conn, `
+		INSERT INTO analyses (
+			created_at, alert_name, cluster, namespace, pod_name, severity,
+			alert_started_at, root_cause, confidence, confidence_score, status,
+			error_message, analysis_json
+		) VALUES (?, ?, ?, ?, ?, ?, ?, '', '', 0, ?, '', ?)`,
+		time.Now(),
+		alert.Name,
+		alert.Cluster,
+		alert.Namespace,
+		alert.Target(),
+		alert.Severity,
+		alert.StartedAt,
+		status,
+		analysisJSON,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to record analysis start: %w", err)
+	}
+	return id, nil
+}
+
+// SetAnalysisStatus moves an analysis that has not completed to the given
+// state, with the error message of a failure. It reports whether the
+// analysis was found unfinished.
+func (db *DB) SetAnalysisStatus(id int64, status, message string) (bool, error) {
+	res, err := db.conn.Exec(
+		"UPDATE analyses SET status = ?, error_message = ? WHERE id = ? AND status != ?",
+		status, message, id, AnalysisCompleted,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to update analysis status: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to update analysis status: %w", err)
+	}
+	return n > 0, nil
+}
+
+// CompleteAnalysis stores the result of the analysis started as id and
+// returns its ID: id itself, or that of the earlier analysis of the same
+// alert start on the pod, which the result then replaces instead
+func (db *DB) CompleteAnalysis(id int64, result *models.AnalysisResult) (int64, error) {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// The started record is written again under the same ID, so that the
+	// upsert settles a clash with an earlier analysis
+	if _, err := tx.Exec("DELETE FROM analyses WHERE id = ? AND status != ?", id, AnalysisCompleted); err != nil {
+		return 0, fmt.Errorf("failed to replace started analysis: %w", err)
+	}
+	id, err = db.insertAnalysis(tx, id, result)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit analysis: %w", err)
+	}
+	return id, nil
 }
 
-// GetAnalysis retrieves a single analysis by ID
+// GetAnalysis retrieves a single analysis by ID, or nil if it does not
+// exist or was deleted
 func (db *DB) GetAnalysis(id int64) (*StoredAnalysis, error) {
 	query := `
-		SELECT id, created_at, alert_name, namespace, pod_name, severity,
-		       alert_started_at, root_cause, confidence, analysis_json
+		SELECT id, created_at, alert_name, cluster, namespace, pod_name, severity,
+		       alert_started_at, root_cause, confidence, confidence_score, status,
+		       error_message, analysis_json
 		FROM analyses
-		WHERE id = ?
+		WHERE id = ? AND deleted_at IS NULL
 	`
 
 	var stored StoredAnalysis
@@ -136,12 +405,16 @@ func (db *DB) GetAnalysis(id int64) (*StoredAnalysis, error) {
 		&stored.ID,
 		&stored.CreatedAt,
 		&stored.AlertName,
+		&stored.Cluster,
 		&stored.Namespace,
 		&stored.PodName,
 		&stored.Severity,
 		&stored.AlertStartedAt,
 		&stored.RootCause,
 		&stored.Confidence,
+		&stored.ConfidenceScore,
+		&stored.Status,
+		&stored.Error,
 		&analysisJSON,
 	)
 	if err == sql.ErrNoRows {
@@ -151,20 +424,56 @@ func (db *DB) GetAnalysis(id int64) (*StoredAnalysis, error) {
 		return nil, fmt.Errorf("failed to query analysis: %w", err)
 	}
 
-	if err := json.Unmarshal([]byte(analysisJSON), &stored.AnalysisResult); err != nil {
+	if err := db.unmarshalAnalysis(analysisJSON, &stored.AnalysisResult); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal analysis: %w", err)
 	}
 
+	tags, err := db.ListAnalysisTags(id)
+	if err != nil {
+		return nil, err
+	}
+	stored.Tags = tags[id]
+	if stored.IncidentID, err = db.analysisIncident(id); err != nil {
+		return nil, err
+	}
+
 	return &stored, nil
 }
 
-// ListAnalyses retrieves all analyses with pagination
-func (db *DB) ListAnalyses(limit, offset int) ([]StoredAnalysis, error) {
+// Analysis list sort orders
+const (
+	SortNewest        = ""
+	SortConfidence    = "confidence"
+	SortConfidenceAsc = "-confidence"
+)
+
+var sortOrders = map[string]string{
+	SortNewest:        "created_at DESC",
+	SortConfidence:    "confidence_score DESC, created_at DESC",
+	SortConfidenceAsc: "confidence_score ASC, created_at DESC",
+}
+
+// ValidSort reports whether sort is a known analysis list order
+func ValidSort(sort string) bool {
+	_, ok := sortOrders[sort]
+	return ok
+}
+
+// ListAnalyses retrieves the analyses that were not deleted, with
+// pagination. Unknown sort orders fall back to newest first.
+func (db *DB) ListAnalyses(limit, offset int, sort string) ([]StoredAnalysis, error) {
+	order, ok := sortOrders[sort]
+	if !ok {
+		order = sortOrders[SortNewest]
+	}
+
 	query := `
-		SELECT id, created_at, alert_name, namespace, pod_name, severity,
-		       alert_started_at, root_cause, confidence, analysis_json
+		SELECT id, created_at, alert_name, cluster, namespace, pod_name, severity,
+		       alert_started_at, root_cause, confidence, confidence_score, status,
+		       error_message, analysis_json
 		FROM analyses
-		ORDER BY created_at DESC
+		WHERE deleted_at IS NULL
+		ORDER BY ` + order + `
 		LIMIT ? OFFSET ?
 	`
 
@@ -183,19 +492,400 @@ func (db *DB) ListAnalyses(limit, offset int) ([]StoredAnalysis, error) {
 			&stored.ID,
 			&stored.CreatedAt,
 			&stored.AlertName,
+			&stored.Cluster,
+			&stored.Namespace,
+			&stored.PodName,
+			&stored.Severity,
+			&stored.AlertStartedAt,
+			&stored.RootCause,
+			&stored.Confidence,
+			&stored.ConfidenceScore,
+			&stored.Status,
+			&stored.Error,
+			&analysisJSON,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		if err := db.unmarshalAnalysis(analysisJSON, &stored.AnalysisResult); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal analysis: %w", err)
+		}
+
+		analyses = append(analyses, stored)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if err := db.withTags(analyses); err != nil {
+		return nil, err
+	}
+	return analyses, nil
+}
+
+// likeEscaper escapes the LIKE wildcards of a search text with "!", which
+// unlike a backslash needs no quoting in any SQL dialect
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+// globToLike turns a namespace glob pattern into a LIKE pattern escaped with
+// "!". LIKE has no character classes, so a [...] class matches any
+// character and FindAnalyses drops the extra matches.
+func globToLike(pattern string) string {
+	var b strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		switch ch := pattern[i]; ch {
+		case '*':
+			b.WriteByte('%')
+		case '?':
+			b.WriteByte('_')
+		case '[':
+			if end := strings.IndexByte(pattern[i+1:], ']'); end >= 0 {
+				i += end + 1
+			}
+			b.WriteByte('_')
+		case '\\':
+			if i+1 < len(pattern) {
+				i++
+			}
+			b.WriteString(likeEscaper.Replace(pattern[i : i+1]))
+		default:
+			b.WriteString(likeEscaper.Replace(string(ch)))
+		}
+	}
+	return b.String()
+}
+
+// matchesNamespace reports whether namespace matches NamespacePatterns
+func (filter AnalysisFilter) matchesNamespace(namespace string) bool {
+	if filter.NamespacePatterns == nil {
+		return true
+	}
+	for _, pattern := range filter.NamespacePatterns {
+		if ok, _ := path.Match(pattern, namespace); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// conditions renders the filter as WHERE conditions
+func (filter AnalysisFilter) conditions() (string, []interface{}) {
+	query := " WHERE deleted_at IS NULL"
+	var args []interface{}
+	if filter.Deleted {
+		query = " WHERE deleted_at IS NOT NULL"
+		if !filter.DeletedSince.IsZero() {
+			query += " AND deleted_at >= ?"
+			args = append(args, filter.DeletedSince.Local())
+		}
+	}
+	for _, c := range []struct {
+		column, value string
+	}{
+		{"alert_name", filter.AlertName},
+		{"cluster", filter.Cluster},
+		{"namespace", filter.Namespace},
+		{"pod_name", filter.PodName},
+		{"severity", filter.Severity},
+		{"confidence", filter.Confidence},
+		{"status", filter.Status},
+	} {
+		if c.value != "" {
+			query += " AND " + c.column + " = ?"
+			args = append(args, c.value)
+		}
+	}
+	if filter.NamespacePatterns != nil {
+		query += " AND (1 = 0"
+		for _, pattern := range filter.NamespacePatterns {
+			query += ` OR namespace LIKE ? ESCAPE '!'`
+			args = append(args, globToLike(pattern))
+		}
+		query += ")"
+	}
+	if filter.RootCause != "" {
+		query += ` AND LOWER(root_cause) LIKE LOWER(?) ESCAPE '!'`
+		args = append(args, "%"+likeEscaper.Replace(filter.RootCause)+"%")
+	}
+	for _, tag := range filter.Tags {
+		query += " AND id IN (SELECT analysis_id FROM analysis_tags WHERE tag = ?)"
+		args = append(args, tag)
+	}
+	if filter.IncidentID != 0 {
+		query += " AND id IN (SELECT analysis_id FROM incident_analyses WHERE incident_id = ?)"
+		args = append(args, filter.IncidentID)
+	}
+	if !filter.Since.IsZero() {
+		query += " AND created_at >= ?"
+		args = append(args, filter.Since.Local())
+	}
+	if !filter.Until.IsZero() {
+		query += " AND created_at < ?"
+		args = append(args, filter.Until.Local())
+	}
+	return query, args
+}
+
+// where renders the filter as WHERE conditions, ordering and limit
+func (filter AnalysisFilter) where() (string, []interface{}) {
+	query, args := filter.conditions()
+	order, ok := sortOrders[filter.Sort]
+	if !ok {
+		order = sortOrders[SortNewest]
+	}
+	query += " ORDER BY " + order
+	if filter.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, filter.Limit)
+		if filter.Offset > 0 {
+			query += " OFFSET ?"
+			args = append(args, filter.Offset)
+		}
+	}
+	return query, args
+}
+
+// CountFilteredAnalyses counts the analyses matching the filter, ignoring
+// its limit and offset
+func (db *DB) CountFilteredAnalyses(filter AnalysisFilter) (int, error) {
+	where, args := filter.conditions()
+	var count int
+	if err := db.conn.QueryRow("SELECT COUNT(*) FROM analyses"+where, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count analyses: %w", err)
+	}
+	return count, nil
+}
+
+// FindAnalyses returns the analyses matching the filter, most recent first
+func (db *DB) FindAnalyses(filter AnalysisFilter) ([]StoredAnalysis, error) {
+	where, args := filter.where()
+	query := `
+		SELECT id, created_at, alert_name, cluster, namespace, pod_name, severity,
+		       alert_started_at, root_cause, confidence, confidence_score, status,
+		       error_message, analysis_json, deleted_at
+		FROM analyses` + where
+
+	rows, err := db.conn.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query analyses: %w", err)
+	}
+	defer rows.Close()
+
+	var analyses []StoredAnalysis
+	for rows.Next() {
+		var stored StoredAnalysis
+		var analysisJSON string
+		var deletedAt sql.NullTime
+
+		err := rows.Scan(
+			&stored.ID,
+			&stored.CreatedAt,
+			&stored.AlertName,
+			&stored.Cluster,
+			&stored.Namespace,
+			&stored.PodName,
+			&stored.Severity,
+			&stored.AlertStartedAt,
+			&stored.RootCause,
+			&stored.Confidence,
+			&stored.ConfidenceScore,
+			&stored.Status,
+			&stored.Error,
+			&analysisJSON,
+			&deletedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		stored.DeletedAt = deletedAt.Time
+		if !filter.matchesNamespace(stored.Namespace) {
+			continue
+		}
+
+		if err := db.unmarshalAnalysis(analysisJSON, &stored.AnalysisResult); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal analysis: %w", err)
+		}
+
+		analyses = append(analyses, stored)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if err := db.withTags(analyses); err != nil {
+		return nil, err
+	}
+	return analyses, nil
+}
+
+// FindAnalysisIDs returns the IDs of analyses matching the filter, most
+// recent first
+func (db *DB) FindAnalysisIDs(filter AnalysisFilter) ([]int64, error) {
+	where, args := filter.where()
+	query := "SELECT id FROM analyses" + where
+
+	rows, err := db.conn.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query analyses: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// SaveAnalysisVersion stores a re-analysis of the given analysis as its
+// next version and returns the version number
+func (db *DB) SaveAnalysisVersion(analysisID int64, result *models.AnalysisResult) (int, error) {
+	analysisJSON, err := db.marshalAnalysis(result)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal analysis: %w", err)
+	}
+
+	// INSERT ... SELECT rather than a subquery in VALUES, which MySQL
+	// rejects on the table being inserted into
+	query := `
+		INSERT INTO analysis_versions (
+			analysis_id, version, created_at, model, prompt_version,
+			root_cause, confidence, confidence_score, provider, prompt_tokens,
+			completion_tokens, cost, analysis_json
+		)
+		SELECT ?, COALESCE(MAX(version), 1) + 1, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?
+		FROM analysis_versions WHERE analysis_id = ?
+	`
+
+	usage := llmUsage(result)
+	id, err := db.conn.dialect.insertID(
+		db.conn,
+		query,
+		analysisID,
+		time.Now(),
+		result.Model,
+		result.PromptVersion,
+		result.Analysis.RootCause,
+		result.Analysis.Confidence,
+		result.Analysis.ConfidenceScore,
+		result.Provider,
+		usage.PromptTokens,
+		usage.CompletionTokens,
+		usage.CostUSD,
+		analysisJSON,
+		analysisID,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert analysis version: %w", err)
+	}
+
+	var version int
+	if err := db.conn.QueryRow("SELECT version FROM analysis_versions WHERE id = ?", id).Scan(&version); err != nil {
+		return 0, fmt.Errorf("failed to read analysis version: %w", err)
+	}
+
+	return version, nil
+}
+
+// ListAnalysisVersions returns the re-analyses of an analysis, oldest first
+func (db *DB) ListAnalysisVersions(analysisID int64) ([]AnalysisVersion, error) {
+	query := `
+		SELECT id, analysis_id, version, created_at, model, prompt_version,
+		       root_cause, confidence, confidence_score, analysis_json
+		FROM analysis_versions
+		WHERE analysis_id = ?
+		ORDER BY version ASC
+	`
+
+	rows, err := db.conn.Query(query, analysisID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query analysis versions: %w", err)
+	}
+	defer rows.Close()
+
+	var versions []AnalysisVersion
+	for rows.Next() {
+		var v AnalysisVersion
+		var analysisJSON string
+
+		err := rows.Scan(
+			&v.ID,
+			&v.AnalysisID,
+			&v.Version,
+			&v.CreatedAt,
+			&v.Model,
+			&v.PromptVersion,
+			&v.RootCause,
+			&v.Confidence,
+			&v.ConfidenceScore,
+			&analysisJSON,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		if err := db.unmarshalAnalysis(analysisJSON, &v.AnalysisResult); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal analysis: %w", err)
+		}
+
+		versions = append(versions, v)
+	}
+
+	return versions, rows.Err()
+}
+
+// FindSimilar returns the most recent analyses for the same alert in the
+// same cluster and namespace, excluding the given analysis
+func (db *DB) FindSimilar(id int64, alertName, cluster, namespace string, limit int) ([]StoredAnalysis, error) {
+	query := `
+		SELECT id, created_at, alert_name, cluster, namespace, pod_name, severity,
+		       alert_started_at, root_cause, confidence, confidence_score, status,
+		       error_message, analysis_json
+		FROM analyses
+		WHERE alert_name = ? AND cluster = ? AND namespace = ? AND id != ? AND status = ?
+		      AND deleted_at IS NULL
+		ORDER BY created_at DESC
+		LIMIT ?
+	`
+
+	rows, err := db.conn.Query(query, alertName, cluster, namespace, id, AnalysisCompleted, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query similar analyses: %w", err)
+	}
+	defer rows.Close()
+
+	var analyses []StoredAnalysis
+	for rows.Next() {
+		var stored StoredAnalysis
+		var analysisJSON string
+
+		err := rows.Scan(
+			&stored.ID,
+			&stored.CreatedAt,
+			&stored.AlertName,
+			&stored.Cluster,
 			&stored.Namespace,
 			&stored.PodName,
 			&stored.Severity,
 			&stored.AlertStartedAt,
 			&stored.RootCause,
 			&stored.Confidence,
+			&stored.ConfidenceScore,
+			&stored.Status,
+			&stored.Error,
 			&analysisJSON,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan row: %w", err)
 		}
 
-		if err := json.Unmarshal([]byte(analysisJSON), &stored.AnalysisResult); err != nil {
+		if err := db.unmarshalAnalysis(analysisJSON, &stored.AnalysisResult); err != nil {
 			return nil, fmt.Errorf("failed to unmarshal analysis: %w", err)
 		}
 
@@ -205,15 +895,161 @@ func (db *DB) ListAnalyses(limit, offset int) ([]StoredAnalysis, error) {
 	return analyses, rows.Err()
 }
 
-// CountAnalyses returns the total number of analyses
+// FindRecurrence counts the analyses of an alert on a pod created since the
+// given time and returns it with the most recent one, or nil if there are
+// none
+func (db *DB) FindRecurrence(namespace, podName, alertName string, since time.Time) (*models.Recurrence, error) {
+	query := `
+		SELECT id, created_at, root_cause, COUNT(*) OVER ()
+		FROM analyses
+		WHERE namespace = ? AND pod_name = ? AND alert_name = ? AND created_at >= ? AND status = ?
+		      AND deleted_at IS NULL
+		ORDER BY created_at DESC
+		LIMIT 1
+	`
+
+	r := &models.Recurrence{Since: since}
+	err := db.conn.QueryRow(query, namespace, podName, alertName, since, AnalysisCompleted).Scan(
+		&r.LastAnalysisID,
+		&r.LastSeen,
+		&r.LastRootCause,
+		&r.Count,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query recurrence: %w", err)
+	}
+	return r, nil
+}
+
+// ListClusters returns the clusters analyses were recorded under, sorted
+func (db *DB) ListClusters() ([]string, error) {
+	rows, err := db.conn.Query("SELECT DISTINCT cluster FROM analyses WHERE cluster != '' AND deleted_at IS NULL ORDER BY cluster")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list clusters: %w", err)
+	}
+	defer rows.Close()
+
+	var clusters []string
+	for rows.Next() {
+		var cluster string
+		if err := rows.Scan(&cluster); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		clusters = append(clusters, cluster)
+	}
+	return clusters, rows.Err()
+}
+
+// CountAnalyses returns the number of analyses that were not deleted
 func (db *DB) CountAnalyses() (int, error) {
 	var count int
-	err := db.conn.QueryRow("SELECT COUNT(*) FROM analyses").Scan(&count)
+	err := db.conn.QueryRow("SELECT COUNT(*) FROM analyses WHERE deleted_at IS NULL").Scan(&count)
 	return count, err
 }
 
-// DeleteAnalysis deletes an analysis by ID
-func (db *DB) DeleteAnalysis(id int64) error {
-	_, err := db.conn.Exec("DELETE FROM analyses WHERE id = ?", id)
-	return err
+// CountIncidents returns the number of analyses of an alert in a namespace
+// with the given severity
+func (db *DB) CountIncidents(namespace, alertName, severity string) (int, error) {
+	var count int
+	err := db.conn.QueryRow(
+		"SELECT COUNT(*) FROM analyses WHERE namespace = ? AND alert_name = ? AND severity = ? AND status = ? AND deleted_at IS NULL",
+		namespace, alertName, severity, AnalysisCompleted,
+	).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count incidents: %w", err)
+	}
+	return count, nil
+}
+
+// DeleteAnalysis marks an analysis deleted, hiding it until it is restored
+// or purged, and reports whether it existed and was not deleted yet
+func (db *DB) DeleteAnalysis(id int64) (bool, error) {
+	n, err := db.setDeletedAt(time.Now(), " WHERE id = ? AND deleted_at IS NULL", []interface{}{id})
+	return n > 0, err
+}
+
+// DeleteAnalyses marks the analyses matching the filter deleted, ignoring
+// its sort, limit and offset, and returns the number of analyses deleted
+func (db *DB) DeleteAnalyses(filter AnalysisFilter) (int, error) {
+	filter.Deleted = false
+	where, args := filter.conditions()
+	return db.setDeletedAt(time.Now(), where, args)
+}
+
+// RestoreAnalysis brings back a deleted analysis and reports whether it was
+// deleted
+func (db *DB) RestoreAnalysis(id int64) (bool, error) {
+	n, err := db.setDeletedAt(nil, " WHERE id = ? AND deleted_at IS NOT NULL", []interface{}{id})
+	return n > 0, err
+}
+
+// RestoreAnalyses brings back the deleted analyses matching the filter,
+// ignoring its sort, limit and offset, and returns the number restored
+func (db *DB) RestoreAnalyses(filter AnalysisFilter) (int, error) {
+	filter.Deleted = true
+	where, args := filter.conditions()
+	return db.setDeletedAt(nil, where, args)
+}
+
+// setDeletedAt sets the deletion time of the analyses selected by where,
+// nil to restore them, and returns the number changed
+func (db *DB) setDeletedAt(deletedAt any, where string, args []interface{}) (int, error) {
+	res, err := db.conn.Exec("UPDATE analyses SET deleted_at = ?"+where, append([]interface{}{deletedAt}, args...)...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update analyses: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count updated analyses: %w", err)
+	}
+	return int(n), nil
+}
+
+// PurgeDeletedAnalyses deletes for good the analyses deleted before before,
+// with their versions, feedback, tags, incident membership, embeddings and
+// alert deliveries, and returns the number purged
+func (db *DB) PurgeDeletedAnalyses(before time.Time) (int, error) {
+	return db.deleteAnalyses(" WHERE deleted_at < ?", []interface{}{before.Local()})
+}
+
+// deleteAnalyses deletes the analyses selected by where and the records
+// that refer to them, in one transaction. Remediations are kept as the
+// audit trail of what was run.
+func (db *DB) deleteAnalyses(where string, args []interface{}) (int, error) {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	ids := "SELECT id FROM analyses" + where
+	for _, table := range []string{"feedback", "alert_deliveries", "analysis_versions", "analysis_embeddings"} {
+		if _, err := tx.Exec("DELETE FROM "+table+" WHERE analysis_id IN ("+ids+")", args...); err != nil {
+			return 0, fmt.Errorf("failed to delete from %s: %w", table, err)
+		}
+	}
+	res, err := tx.Exec("DELETE FROM analyses"+where, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete analyses: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count deleted analyses: %w", err)
+	}
+	// Swept after the analyses rather than selected by where, which MySQL
+	// does not allow to read the table a DELETE writes. Incidents are kept,
+	// even when left empty.
+	for _, table := range []string{"analysis_tags", "incident_analyses"} {
+		if _, err := tx.Exec("DELETE FROM " + table + " WHERE analysis_id NOT IN (SELECT id FROM analyses)"); err != nil {
+			return 0, fmt.Errorf("failed to delete from %s: %w", table, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit deletion: %w", err)
+	}
+	return int(n), nil
 }
@@ -0,0 +1,48 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// deliveryKey formats an alert's start time for the alert_deliveries key,
+// so that re-deliveries match regardless of the parsed time zone
+func deliveryKey(startsAt time.Time) string {
+	return startsAt.UTC().Format(time.RFC3339Nano)
+}
+
+// GetDeliveredAnalysisID returns the ID of the analysis stored for an alert
+// (its fingerprint and start time), or 0 if it was not analyzed yet or the
+// analysis was deleted
+func (db *DB) GetDeliveredAnalysisID(fingerprint string, startsAt time.Time) (int64, error) {
+	var id int64
+	err := db.conn.QueryRow(`
+		SELECT d.analysis_id FROM alert_deliveries d
+		JOIN analyses a ON a.id = d.analysis_id
+		WHERE d.fingerprint = ? AND d.starts_at = ? AND a.deleted_at IS NULL`,
+		fingerprint, deliveryKey(startsAt),
+	).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get alert delivery: %w", err)
+	}
+	return id, nil
+}
+
+// SaveDelivery records the analysis stored for an alert, the idempotency
+// key of re-delivered webhooks
+func (db *DB) SaveDelivery(fingerprint string, startsAt time.Time, analysisID int64) error {
+	_, err := db.conn.Exec(`
+		INSERT INTO alert_deliveries (fingerprint, starts_at, analysis_id, created_at)
+		VALUES (?, ?, ?, ?)`+
+		db.conn.dialect.upsert([]string{"fingerprint", "starts_at"}, "", "analysis_id"),
+		fingerprint, deliveryKey(startsAt), analysisID, time.Now(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save alert delivery: %w", err)
+	}
+	return nil
+}
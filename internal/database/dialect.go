@@ -0,0 +1,228 @@
+package database
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// dialect adapts the queries of DB, written for SQLite with ? placeholders,
+// to a database engine
+type dialect struct {
+	// driver is the database/sql driver name
+	driver string
+	// name is the directory of the dialect's schema migrations
+	name string
+	// timestamp is the column type of times. SQLite has no time type: the
+	// driver writes times as text in their own zone, and compares them as
+	// text, so times are written and bound in local time, the zone of
+	// time.Now, and the utcDay of a row is converted from its offset.
+	// PostgreSQL's TIMESTAMPTZ compares instants whatever the zone, and
+	// MySQL's DATETIME is written and read in UTC (see mysqlDSN); binding
+	// local times is harmless there.
+	timestamp string
+	// numberedParams rewrites ? placeholders as $1, $2, ...
+	numberedParams bool
+	// utcDay is the expression of the UTC day (YYYY-MM-DD) of created_at
+	utcDay string
+	// duplicateKey switches upserts and inserted IDs from ON CONFLICT and
+	// RETURNING to MySQL's ON DUPLICATE KEY UPDATE and LAST_INSERT_ID
+	duplicateKey bool
+	// vacuum reclaims the space of deleted rows, where the engine does not
+	// on its own
+	vacuum []string
+	// singleWriter marks engines that run one write transaction at a time:
+	// writes queue on the connection's write lock instead of failing with
+	// "database is locked"
+	singleWriter bool
+	// timeLiteral is the layout of times in SQL backups, and zeroTime the
+	// literal of unset times where the layout does not fit them
+	timeLiteral string
+	zeroTime    string
+	// backslashEscapes marks string literals in which a backslash escapes
+	// the next character
+	backslashEscapes bool
+	// resetSequence is the statement, with the table for %[1]s, that moves
+	// the id sequence of a table past the ids loaded by an import, where
+	// explicit ids do not advance it
+	resetSequence string
+}
+
+var sqliteDialect = &dialect{
+	driver:    "sqlite3",
+	name:      "sqlite",
+	timestamp: "DATETIME",
+	utcDay:    "date(created_at)",
+	vacuum:    []string{"VACUUM", "ANALYZE"},
+	// WAL lets reads run alongside the writer
+	singleWriter: true,
+	// The layout the driver writes times in, so that they still compare
+	// as text
+	timeLiteral: "2006-01-02 15:04:05.999999999-07:00",
+}
+
+// upsert renders the clause that makes an INSERT update the columns of the
+// row conflicting with it on key instead. id names the auto-increment
+// column when the caller reads the ID of an updated row with insertID.
+func (d *dialect) upsert(key []string, id string, columns ...string) string {
+	set := make([]string, 0, len(columns)+1)
+	if d.duplicateKey {
+		if id != "" {
+			// Makes LAST_INSERT_ID report the updated row
+			set = append(set, id+" = LAST_INSERT_ID("+id+")")
+		}
+		for _, column := range columns {
+			set = append(set, column+" = VALUES("+column+")")
+		}
+		return " ON DUPLICATE KEY UPDATE " + strings.Join(set, ", ")
+	}
+
+	for _, column := range columns {
+		set = append(set, column+" = excluded."+column)
+	}
+	return " ON CONFLICT(" + strings.Join(key, ", ") + ") DO UPDATE SET " + strings.Join(set, ", ")
+}
+
+// valueRows renders the placeholders of a multi-row INSERT of n rows of
+// width columns, as "(?, ?), (?, ?)"
+func valueRows(n, width int) string {
+	row := "(" + strings.TrimSuffix(strings.Repeat("?, ", width), ", ") + ")"
+	return strings.TrimSuffix(strings.Repeat(row+", ", n), ", ")
+}
+
+// execQuerier is a sqlConn or a sqlTx
+type execQuerier interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// insertID runs an INSERT into a table with an auto-increment id column
+// and returns the id of the inserted (or upserted) row: with RETURNING
+// rather than LastInsertId, which is not updated when an upsert takes the
+// update path, and which PostgreSQL does not support
+func (d *dialect) insertID(q execQuerier, query string, args ...any) (int64, error) {
+	if d.duplicateKey {
+		res, err := q.Exec(query, args...)
+		if err != nil {
+			return 0, err
+		}
+		return res.LastInsertId()
+	}
+
+	// The row is written as it is scanned, so an insert outside a
+	// transaction holds the write lock until then
+	if c, ok := q.(*sqlConn); ok {
+		defer c.lockWrite()()
+	}
+	var id int64
+	err := q.QueryRow(query+" RETURNING id", args...).Scan(&id)
+	return id, err
+}
+
+// rebind rewrites the ? placeholders of query for the dialect, leaving
+// quoted strings alone
+func (d *dialect) rebind(query string) string {
+	if !d.numberedParams {
+		return query
+	}
+
+	var sb strings.Builder
+	n := 0
+	quoted := false
+	for _, r := range query {
+		switch {
+		case r == '\'':
+			quoted = !quoted
+		case r == '?' && !quoted:
+			n++
+			sb.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// sqlConn runs queries written for SQLite on the connection pool of a
+// dialect
+type sqlConn struct {
+	*sql.DB
+	dialect *dialect
+	// writer is held by each write of a single-writer dialect, from its
+	// statement or Begin until Commit or Rollback. Reads do not take it.
+	writer sync.Mutex
+}
+
+// lockWrite waits for the write lock of a single-writer dialect and
+// returns its release
+func (c *sqlConn) lockWrite() func() {
+	if !c.dialect.singleWriter {
+		return func() {}
+	}
+	c.writer.Lock()
+	return c.writer.Unlock
+}
+
+func (c *sqlConn) Exec(query string, args ...any) (sql.Result, error) {
+	defer c.lockWrite()()
+	return c.DB.Exec(c.dialect.rebind(query), args...)
+}
+
+func (c *sqlConn) Query(query string, args ...any) (*sql.Rows, error) {
+	return c.DB.Query(c.dialect.rebind(query), args...)
+}
+
+func (c *sqlConn) QueryRow(query string, args ...any) *sql.Row {
+	return c.DB.QueryRow(c.dialect.rebind(query), args...)
+}
+
+// Begin starts a write transaction, once the write lock is free
+func (c *sqlConn) Begin() (*sqlTx, error) {
+	unlock := c.lockWrite()
+	tx, err := c.DB.Begin()
+	if err != nil {
+		unlock()
+		return nil, err
+	}
+	return &sqlTx{Tx: tx, dialect: c.dialect, unlock: unlock}, nil
+}
+
+// sqlTx is a transaction of a sqlConn
+type sqlTx struct {
+	*sql.Tx
+	dialect *dialect
+	// unlock releases the write lock when the transaction ends
+	unlock func()
+}
+
+func (t *sqlTx) Commit() error {
+	defer t.release()
+	return t.Tx.Commit()
+}
+
+func (t *sqlTx) Rollback() error {
+	defer t.release()
+	return t.Tx.Rollback()
+}
+
+// release hands the write lock on, once: transactions are rolled back in
+// a defer after they commit
+func (t *sqlTx) release() {
+	if t.unlock != nil {
+		t.unlock()
+		t.unlock = nil
+	}
+}
+
+func (t *sqlTx) Exec(query string, args ...any) (sql.Result, error) {
+	return t.Tx.Exec(t.dialect.rebind(query), args...)
+}
+
+func (t *sqlTx) Query(query string, args ...any) (*sql.Rows, error) {
+	return t.Tx.Query(t.dialect.rebind(query), args...)
+}
+
+func (t *sqlTx) QueryRow(query string, args ...any) *sql.Row {
+	return t.Tx.QueryRow(t.dialect.rebind(query), args...)
+}
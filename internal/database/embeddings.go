@@ -0,0 +1,369 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Embedding is the vector an embedding model computed for an analysis.
+// Only embeddings of the same model and dimensions are compared.
+type Embedding struct {
+	AnalysisID int64
+	Model      string
+	Vector     []float32
+	CreatedAt  time.Time
+}
+
+// SimilarAnalysis is an analysis found by embedding, with the cosine
+// similarity of its embedding to the one searched for, from -1 to 1
+type SimilarAnalysis struct {
+	StoredAnalysis
+	Similarity float64
+}
+
+// embeddingColumns are the vector columns of the backed up tables, which
+// backups hold as arrays of numbers
+var embeddingColumns = map[string]string{"analysis_embeddings": "embedding"}
+
+// SaveEmbedding stores the embedding of an analysis, replacing the one it
+// had
+func (db *DB) SaveEmbedding(e Embedding) error {
+	if len(e.Vector) == 0 {
+		return fmt.Errorf("failed to save embedding: the vector is empty")
+	}
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now()
+	}
+
+	query := "INSERT INTO analysis_embeddings (analysis_id, model, dimensions, created_at, embedding) VALUES (?, ?, ?, ?, " + db.vectorParam() + ")" +
+		db.conn.dialect.upsert([]string{"analysis_id"}, "", "model", "dimensions", "created_at", "embedding")
+	if _, err := db.conn.Exec(query, e.AnalysisID, e.Model, len(e.Vector), e.CreatedAt, db.encodeVector(e.Vector)); err != nil {
+		return fmt.Errorf("failed to save embedding: %w", err)
+	}
+	return nil
+}
+
+// GetEmbedding returns the embedding of an analysis, or nil if it has none
+func (db *DB) GetEmbedding(analysisID int64) (*Embedding, error) {
+	e := Embedding{AnalysisID: analysisID}
+	var raw any
+	err := db.conn.QueryRow(
+		"SELECT model, created_at, "+db.vectorColumn("embedding")+" FROM analysis_embeddings WHERE analysis_id = ?",
+		analysisID,
+	).Scan(&e.Model, &e.CreatedAt, &raw)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get embedding: %w", err)
+	}
+	if e.Vector, err = db.decodeVector(raw); err != nil {
+		return nil, fmt.Errorf("failed to decode embedding: %w", err)
+	}
+	return &e, nil
+}
+
+// FindSimilarByEmbedding returns the completed analyses whose embeddings
+// are closest to query by cosine similarity, most similar first, leaving
+// out query.AnalysisID. With pgvector the database ranks them; otherwise
+// the embeddings of the model are read and ranked here.
+func (db *DB) FindSimilarByEmbedding(query Embedding, limit int) ([]SimilarAnalysis, error) {
+	if len(query.Vector) == 0 || limit <= 0 {
+		return nil, nil
+	}
+	if db.pgvector {
+		return db.rankSimilarInDB(query, limit)
+	}
+
+	rows, err := db.conn.Query(`
+		SELECT e.analysis_id, e.embedding
+		FROM analysis_embeddings e JOIN analyses a ON a.id = e.analysis_id
+		WHERE e.model = ? AND e.dimensions = ? AND e.analysis_id != ? AND a.status = ? AND a.deleted_at IS NULL`,
+		query.Model, len(query.Vector), query.AnalysisID, AnalysisCompleted,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query embeddings: %w", err)
+	}
+	defer rows.Close()
+
+	var matches []similarMatch
+	for rows.Next() {
+		var m similarMatch
+		var raw []byte
+		if err := rows.Scan(&m.id, &raw); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		vector, err := db.decodeVector(raw)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode embedding of analysis %d: %w", m.id, err)
+		}
+		m.similarity = cosineSimilarity(query.Vector, vector)
+		matches = append(matches, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].similarity != matches[j].similarity {
+			return matches[i].similarity > matches[j].similarity
+		}
+		return matches[i].id > matches[j].id
+	})
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return db.similarAnalyses(matches)
+}
+
+// similarMatch is an analysis ID with the similarity of its embedding
+type similarMatch struct {
+	id         int64
+	similarity float64
+}
+
+// similarAnalyses loads the analyses of matches, in their order
+func (db *DB) similarAnalyses(matches []similarMatch) ([]SimilarAnalysis, error) {
+	if len(matches) == 0 {
+		return nil, nil
+	}
+	ids := make([]any, len(matches))
+	for i, m := range matches {
+		ids[i] = m.id
+	}
+	analyses, err := db.analysesByID(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	similar := make([]SimilarAnalysis, 0, len(matches))
+	for _, m := range matches {
+		// Deleted since the embeddings were read
+		if a, ok := analyses[m.id]; ok {
+			similar = append(similar, SimilarAnalysis{StoredAnalysis: a, Similarity: m.similarity})
+		}
+	}
+	return similar, nil
+}
+
+// rankSimilarInDB ranks the embeddings by pgvector's cosine distance
+func (db *DB) rankSimilarInDB(query Embedding, limit int) ([]SimilarAnalysis, error) {
+	vector := db.encodeVector(query.Vector)
+	rows, err := db.conn.Query(`
+		SELECT a.id, 1 - (e.embedding <=> CAST(? AS vector))
+		FROM analysis_embeddings e JOIN analyses a ON a.id = e.analysis_id
+		WHERE e.model = ? AND e.dimensions = ? AND e.analysis_id != ? AND a.status = ? AND a.deleted_at IS NULL
+		ORDER BY e.embedding <=> CAST(? AS vector), a.id DESC
+		LIMIT ?`,
+		vector, query.Model, len(query.Vector), query.AnalysisID, AnalysisCompleted, vector, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query similar embeddings: %w", err)
+	}
+	defer rows.Close()
+
+	var matches []similarMatch
+	for rows.Next() {
+		var m similarMatch
+		if err := rows.Scan(&m.id, &m.similarity); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		// Zero vectors have no direction: their distance is NaN
+		if math.IsNaN(m.similarity) {
+			m.similarity = 0
+		}
+		matches = append(matches, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return db.similarAnalyses(matches)
+}
+
+// analysesByID returns the analyses with the given IDs that were not
+// deleted, with their tags
+func (db *DB) analysesByID(ids []any) (map[int64]StoredAnalysis, error) {
+	rows, err := db.conn.Query(`
+		SELECT id, created_at, alert_name, cluster, namespace, pod_name, severity,
+		       alert_started_at, root_cause, confidence, confidence_score, status,
+		       error_message, analysis_json
+		FROM analyses
+		WHERE id IN (`+strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ")+`) AND deleted_at IS NULL`,
+		ids...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query analyses: %w", err)
+	}
+	defer rows.Close()
+
+	var analyses []StoredAnalysis
+	for rows.Next() {
+		var stored StoredAnalysis
+		var analysisJSON string
+
+		err := rows.Scan(
+			&stored.ID,
+			&stored.CreatedAt,
+			&stored.AlertName,
+			&stored.Cluster,
+			&stored.Namespace,
+			&stored.PodName,
+			&stored.Severity,
+			&stored.AlertStartedAt,
+			&stored.RootCause,
+			&stored.Confidence,
+			&stored.ConfidenceScore,
+			&stored.Status,
+			&stored.Error,
+			&analysisJSON,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		if err := db.unmarshalAnalysis(analysisJSON, &stored.AnalysisResult); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal analysis: %w", err)
+		}
+
+		analyses = append(analyses, stored)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if err := db.withTags(analyses); err != nil {
+		return nil, err
+	}
+
+	byID := make(map[int64]StoredAnalysis, len(analyses))
+	for _, a := range analyses {
+		byID[a.ID] = a
+	}
+	return byID, nil
+}
+
+// cosineSimilarity returns the cosine of the angle between two vectors of
+// the same length, 0 if either is zero
+func cosineSimilarity(a, b []float32) float64 {
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(normA*normB)
+}
+
+// detectVectorType records whether the embedding column is a pgvector
+// vector, which only PostgreSQL databases with the extension have
+func (db *DB) detectVectorType() error {
+	if db.conn.dialect.name != postgresDialect.name {
+		return nil
+	}
+	var udt string
+	err := db.conn.QueryRow(`
+		SELECT udt_name FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = 'analysis_embeddings' AND column_name = 'embedding'`,
+	).Scan(&udt)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to read the embedding column type: %w", err)
+	}
+	db.pgvector = udt == "vector"
+	return nil
+}
+
+// vectorParam is the placeholder of a vector written with encodeVector
+func (db *DB) vectorParam() string {
+	if db.pgvector {
+		return "CAST(? AS vector)"
+	}
+	return "?"
+}
+
+// vectorColumn selects a vector column so that it scans into what
+// decodeVector reads
+func (db *DB) vectorColumn(column string) string {
+	if db.pgvector {
+		return "CAST(" + column + " AS TEXT)"
+	}
+	return column
+}
+
+// encodeVector renders a vector as a query argument: pgvector's text form,
+// "[1,2,3]", or little-endian float32s
+func (db *DB) encodeVector(v []float32) any {
+	if db.pgvector {
+		parts := make([]string, len(v))
+		for i, f := range v {
+			parts[i] = strconv.FormatFloat(float64(f), 'g', -1, 32)
+		}
+		return "[" + strings.Join(parts, ",") + "]"
+	}
+
+	b := make([]byte, 4*len(v))
+	for i, f := range v {
+		binary.LittleEndian.PutUint32(b[4*i:], math.Float32bits(f))
+	}
+	return b
+}
+
+// vectorLiteral renders a vector as a SQL literal for backups
+func (db *DB) vectorLiteral(v []float32) string {
+	encoded := db.encodeVector(v)
+	if text, ok := encoded.(string); ok {
+		return db.conn.dialect.quote(text)
+	}
+	hexBytes := hex.EncodeToString(encoded.([]byte))
+	if db.conn.dialect.name == postgresDialect.name {
+		return "decode('" + hexBytes + "', 'hex')"
+	}
+	return "X'" + hexBytes + "'"
+}
+
+// decodeVector reads a vector column scanned as written by encodeVector
+func (db *DB) decodeVector(raw any) ([]float32, error) {
+	var b []byte
+	switch raw := raw.(type) {
+	case []byte:
+		b = raw
+	case string:
+		b = []byte(raw)
+	default:
+		return nil, fmt.Errorf("unexpected vector value %T", raw)
+	}
+
+	if db.pgvector {
+		text := strings.TrimSpace(string(b))
+		if !strings.HasPrefix(text, "[") || !strings.HasSuffix(text, "]") {
+			return nil, fmt.Errorf("malformed vector")
+		}
+		fields := strings.Split(text[1:len(text)-1], ",")
+		v := make([]float32, len(fields))
+		for i, field := range fields {
+			f, err := strconv.ParseFloat(strings.TrimSpace(field), 32)
+			if err != nil {
+				return nil, fmt.Errorf("malformed vector: %w", err)
+			}
+			v[i] = float32(f)
+		}
+		return v, nil
+	}
+
+	if len(b)%4 != 0 {
+		return nil, fmt.Errorf("malformed vector of %d bytes", len(b))
+	}
+	v := make([]float32, len(b)/4)
+	for i := range v {
+		v[i] = math.Float32frombits(binary.LittleEndian.Uint32(b[4*i:]))
+	}
+	return v, nil
+}
@@ -0,0 +1,124 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// TargetFailure tracks consecutive analysis failures of one target (a pod or
+// workload) so webhook deliveries can back off instead of retrying it
+type TargetFailure struct {
+	Namespace      string
+	Target         string
+	Failures       int
+	FirstFailureAt time.Time
+	LastFailureAt  time.Time
+	NextAttemptAt  time.Time
+	LastError      string
+}
+
+const targetFailureColumns = `namespace, target, failures, first_failure_at, last_failure_at, next_attempt_at, last_error`
+
+func scanTargetFailure(row interface{ Scan(...any) error }) (*TargetFailure, error) {
+	var f TargetFailure
+	err := row.Scan(
+		&f.Namespace,
+		&f.Target,
+		&f.Failures,
+		&f.FirstFailureAt,
+		&f.LastFailureAt,
+		&f.NextAttemptAt,
+		&f.LastError,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// GetTargetFailure returns the failure state of a target, or nil if its last
+// analysis succeeded
+func (db *DB) GetTargetFailure(namespace, target string) (*TargetFailure, error) {
+	row := db.conn.QueryRow(`SELECT `+targetFailureColumns+` FROM target_failures WHERE namespace = ? AND target = ?`, namespace, target)
+	f, err := scanTargetFailure(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get target failure: %w", err)
+	}
+	return f, nil
+}
+
+// RecordTargetFailure counts another consecutive failure of the target and
+// schedules its next attempt after backoff(failures)
+func (db *DB) RecordTargetFailure(namespace, target, message string, backoff func(failures int) time.Duration) (*TargetFailure, error) {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	row := tx.QueryRow(`SELECT `+targetFailureColumns+` FROM target_failures WHERE namespace = ? AND target = ?`, namespace, target)
+	f, err := scanTargetFailure(row)
+	switch {
+	case err == sql.ErrNoRows:
+		f = &TargetFailure{Namespace: namespace, Target: target, FirstFailureAt: now}
+	case err != nil:
+		return nil, fmt.Errorf("failed to get target failure: %w", err)
+	}
+
+	f.Failures++
+	f.LastFailureAt = now
+	f.NextAttemptAt = now.Add(backoff(f.Failures))
+	f.LastError = message
+
+	_, err = tx.Exec(`
+		INSERT INTO target_failures (`+targetFailureColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`+
+		tx.dialect.upsert([]string{"namespace", "target"}, "",
+			"failures", "last_failure_at", "next_attempt_at", "last_error"),
+		f.Namespace, f.Target, f.Failures, f.FirstFailureAt, f.LastFailureAt, f.NextAttemptAt, f.LastError)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record target failure: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit target failure: %w", err)
+	}
+	return f, nil
+}
+
+// ClearTargetFailure resets the failure state of a target after a
+// successful analysis
+func (db *DB) ClearTargetFailure(namespace, target string) error {
+	_, err := db.conn.Exec("DELETE FROM target_failures WHERE namespace = ? AND target = ?", namespace, target)
+	return err
+}
+
+// ListTargetFailures returns targets with at least minFailures consecutive
+// failures, most recent first
+func (db *DB) ListTargetFailures(minFailures int) ([]TargetFailure, error) {
+	rows, err := db.conn.Query(`
+		SELECT `+targetFailureColumns+`
+		FROM target_failures
+		WHERE failures >= ?
+		ORDER BY last_failure_at DESC
+	`, minFailures)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query target failures: %w", err)
+	}
+	defer rows.Close()
+
+	var failures []TargetFailure
+	for rows.Next() {
+		f, err := scanTargetFailure(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		failures = append(failures, *f)
+	}
+	return failures, rows.Err()
+}
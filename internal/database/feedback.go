@@ -0,0 +1,127 @@
+package database
+
+import (
+	"fmt"
+	"time"
+)
+
+// Feedback ratings. Corrections are down ratings.
+const (
+	RatingUp   = "up"
+	RatingDown = "down"
+)
+
+// FeedbackRecord is a human judgement of an analysis version: a rating with
+// a comment and, optionally, the root cause the human found, or a
+// correction, linking the version marked wrong to the re-analysis run with
+// the human hint. Records double as few-shot examples: the data of the
+// analysis, the rejected cause and the corrected one.
+type FeedbackRecord struct {
+	ID         int64
+	AnalysisID int64
+	AlertName  string
+	Namespace  string
+	Version    int
+	// CorrectedVersion is the re-analysis of a correction, 0 for ratings
+	CorrectedVersion   int
+	CreatedAt          time.Time
+	Author             string
+	Rating             string
+	Comment            string
+	RejectedRootCause  string
+	Hint               string
+	CorrectedRootCause string
+}
+
+// FeedbackFilter selects feedback records; zero fields match all
+type FeedbackFilter struct {
+	AnalysisID int64
+	AlertName  string
+	Rating     string
+	Limit      int
+}
+
+// SaveFeedback stores a feedback record and returns its ID
+func (db *DB) SaveFeedback(f FeedbackRecord) (int64, error) {
+	id, err := db.conn.dialect.insertID(db.conn, `
+		INSERT INTO feedback (
+			analysis_id, version, corrected_version, created_at, author, rating,
+			comment, rejected_root_cause, hint, corrected_root_cause
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		f.AnalysisID, f.Version, f.CorrectedVersion, f.CreatedAt, f.Author, f.Rating,
+		f.Comment, f.RejectedRootCause, f.Hint, f.CorrectedRootCause,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert feedback: %w", err)
+	}
+	return id, nil
+}
+
+// ListFeedback returns the most recent feedback records matching the
+// filter
+func (db *DB) ListFeedback(filter FeedbackFilter) ([]FeedbackRecord, error) {
+	query := `
+		SELECT f.id, f.analysis_id, a.alert_name, a.namespace, f.version,
+		       f.corrected_version, f.created_at, f.author, f.rating, f.comment,
+		       f.rejected_root_cause, f.hint, f.corrected_root_cause
+		FROM feedback f
+		JOIN analyses a ON a.id = f.analysis_id
+		WHERE (? = 0 OR f.analysis_id = ?) AND (? = '' OR a.alert_name = ?) AND (? = '' OR f.rating = ?)
+		      AND a.deleted_at IS NULL
+		ORDER BY f.created_at DESC
+		LIMIT ?
+	`
+
+	rows, err := db.conn.Query(query,
+		filter.AnalysisID, filter.AnalysisID,
+		filter.AlertName, filter.AlertName,
+		filter.Rating, filter.Rating,
+		filter.Limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query feedback: %w", err)
+	}
+	defer rows.Close()
+
+	var records []FeedbackRecord
+	for rows.Next() {
+		var f FeedbackRecord
+		err := rows.Scan(
+			&f.ID,
+			&f.AnalysisID,
+			&f.AlertName,
+			&f.Namespace,
+			&f.Version,
+			&f.CorrectedVersion,
+			&f.CreatedAt,
+			&f.Author,
+			&f.Rating,
+			&f.Comment,
+			&f.RejectedRootCause,
+			&f.Hint,
+			&f.CorrectedRootCause,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		records = append(records, f)
+	}
+
+	return records, rows.Err()
+}
+
+// CountRatings returns the number of up and down ratings given since since,
+// optionally for one alert
+func (db *DB) CountRatings(alertName string, since time.Time) (up, down int, err error) {
+	err = db.conn.QueryRow(`
+		SELECT COALESCE(SUM(CASE WHEN f.rating = ? THEN 1 ELSE 0 END), 0),
+		       COALESCE(SUM(CASE WHEN f.rating = ? THEN 1 ELSE 0 END), 0)
+		FROM feedback f
+		JOIN analyses a ON a.id = f.analysis_id
+		WHERE (? = '' OR a.alert_name = ?) AND f.created_at >= ? AND a.deleted_at IS NULL
+	`, RatingUp, RatingDown, alertName, alertName, since.Local()).Scan(&up, &down)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to count ratings: %w", err)
+	}
+	return up, down, nil
+}
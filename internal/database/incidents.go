@@ -0,0 +1,288 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Incident statuses
+const (
+	IncidentOpen     = "open"
+	IncidentResolved = "resolved"
+)
+
+// Incident groups the analyses of one outage, such as alerts on different
+// pods with one cause. An analysis belongs to at most one incident.
+type Incident struct {
+	ID        int64
+	CreatedAt time.Time
+	// UpdatedAt is when the incident or its analyses last changed
+	UpdatedAt time.Time
+	Title     string
+	Status    string
+	Author    string
+	// AnalysisCount is the number of analyses grouped, leaving out deleted
+	// ones
+	AnalysisCount int
+}
+
+// IncidentFilter selects incidents; zero fields match all
+type IncidentFilter struct {
+	Status string
+	Limit  int
+	Offset int
+}
+
+const incidentColumns = `id, created_at, updated_at, title, status, author,
+	(SELECT COUNT(*) FROM incident_analyses ia JOIN analyses a ON a.id = ia.analysis_id
+	 WHERE ia.incident_id = incidents.id AND a.deleted_at IS NULL)`
+
+func scanIncident(row interface{ Scan(...any) error }) (*Incident, error) {
+	var i Incident
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Status,
+		&i.Author,
+		&i.AnalysisCount,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &i, nil
+}
+
+// CreateIncident stores an open incident grouping the given analyses,
+// moving them out of the incidents they were in, and returns its ID
+func (db *DB) CreateIncident(incident Incident, analysisIDs []int64) (int64, error) {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	id, err := tx.dialect.insertID(tx, `
+		INSERT INTO incidents (created_at, updated_at, title, status, author)
+		VALUES (?, ?, ?, ?, ?)`,
+		incident.CreatedAt, incident.CreatedAt, incident.Title, IncidentOpen, incident.Author,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert incident: %w", err)
+	}
+	if err := linkIncidentAnalyses(tx, id, analysisIDs, incident.CreatedAt, incident.Author); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit incident: %w", err)
+	}
+	return id, nil
+}
+
+// linkIncidentAnalyses adds analyses to an incident in one statement,
+// moving them out of the incidents they were in
+func linkIncidentAnalyses(tx *sqlTx, incidentID int64, analysisIDs []int64, now time.Time, author string) error {
+	// An upsert may not touch a row twice
+	seen := make(map[int64]bool, len(analysisIDs))
+	args := make([]any, 0, 4*len(analysisIDs))
+	for _, analysisID := range analysisIDs {
+		if !seen[analysisID] {
+			seen[analysisID] = true
+			args = append(args, analysisID, incidentID, now, author)
+		}
+	}
+	if len(args) == 0 {
+		return nil
+	}
+
+	query := "INSERT INTO incident_analyses (analysis_id, incident_id, added_at, author) VALUES " + valueRows(len(seen), 4) +
+		tx.dialect.upsert([]string{"analysis_id"}, "", "incident_id", "added_at", "author")
+	if _, err := tx.Exec(query, args...); err != nil {
+		return fmt.Errorf("failed to link analyses to incident: %w", err)
+	}
+	return nil
+}
+
+// GetIncident returns an incident by ID, or nil if it does not exist
+func (db *DB) GetIncident(id int64) (*Incident, error) {
+	row := db.conn.QueryRow(`SELECT `+incidentColumns+` FROM incidents WHERE id = ?`, id)
+	i, err := scanIncident(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get incident: %w", err)
+	}
+	return i, nil
+}
+
+// ListIncidents returns the incidents matching the filter, most recently
+// updated first
+func (db *DB) ListIncidents(filter IncidentFilter) ([]Incident, error) {
+	rows, err := db.conn.Query(`
+		SELECT `+incidentColumns+` FROM incidents
+		WHERE (? = '' OR status = ?)
+		ORDER BY updated_at DESC, id DESC
+		LIMIT ? OFFSET ?`,
+		filter.Status, filter.Status, filter.Limit, filter.Offset,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query incidents: %w", err)
+	}
+	defer rows.Close()
+
+	var out []Incident
+	for rows.Next() {
+		i, err := scanIncident(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		out = append(out, *i)
+	}
+	return out, rows.Err()
+}
+
+// UpdateIncident changes the title and status of an incident and reports
+// whether it exists
+func (db *DB) UpdateIncident(id int64, title, status string) (bool, error) {
+	res, err := db.conn.Exec(
+		"UPDATE incidents SET title = ?, status = ?, updated_at = ? WHERE id = ?",
+		title, status, time.Now(), id,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to update incident: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to update incident: %w", err)
+	}
+	return n > 0, nil
+}
+
+// AddIncidentAnalyses adds analyses to an incident, moving them out of the
+// incidents they were in
+func (db *DB) AddIncidentAnalyses(incidentID int64, analysisIDs []int64, author string) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	if err := linkIncidentAnalyses(tx, incidentID, analysisIDs, now, author); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("UPDATE incidents SET updated_at = ? WHERE id = ?", now, incidentID); err != nil {
+		return fmt.Errorf("failed to update incident: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit incident analyses: %w", err)
+	}
+	return nil
+}
+
+// RemoveIncidentAnalysis takes an analysis out of an incident and reports
+// whether the incident had it
+func (db *DB) RemoveIncidentAnalysis(incidentID, analysisID int64) (bool, error) {
+	res, err := db.conn.Exec(
+		"DELETE FROM incident_analyses WHERE incident_id = ? AND analysis_id = ?",
+		incidentID, analysisID,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to remove analysis from incident: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to remove analysis from incident: %w", err)
+	}
+	if n == 0 {
+		return false, nil
+	}
+	if _, err := db.conn.Exec("UPDATE incidents SET updated_at = ? WHERE id = ?", time.Now(), incidentID); err != nil {
+		return true, fmt.Errorf("failed to update incident: %w", err)
+	}
+	return true, nil
+}
+
+// MergeIncidents moves the analyses of the source incidents into the
+// target and deletes the sources. It returns the number of sources merged.
+func (db *DB) MergeIncidents(targetID int64, sourceIDs []int64) (int, error) {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	args := make([]interface{}, 0, len(sourceIDs)+1)
+	args = append(args, targetID)
+	for _, id := range sourceIDs {
+		args = append(args, id)
+	}
+	in := strings.TrimSuffix(strings.Repeat("?, ", len(sourceIDs)), ", ")
+
+	if _, err := tx.Exec("UPDATE incident_analyses SET incident_id = ? WHERE incident_id IN ("+in+")", args...); err != nil {
+		return 0, fmt.Errorf("failed to move incident analyses: %w", err)
+	}
+	res, err := tx.Exec("DELETE FROM incidents WHERE id IN ("+in+")", args[1:]...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete merged incidents: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count merged incidents: %w", err)
+	}
+	if _, err := tx.Exec("UPDATE incidents SET updated_at = ? WHERE id = ?", time.Now(), targetID); err != nil {
+		return 0, fmt.Errorf("failed to update incident: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit merge: %w", err)
+	}
+	return int(n), nil
+}
+
+// DeleteIncident deletes an incident, leaving its analyses ungrouped, and
+// reports whether it existed
+func (db *DB) DeleteIncident(id int64) (bool, error) {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM incident_analyses WHERE incident_id = ?", id); err != nil {
+		return false, fmt.Errorf("failed to delete incident analyses: %w", err)
+	}
+	res, err := tx.Exec("DELETE FROM incidents WHERE id = ?", id)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete incident: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to count deleted incidents: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit incident deletion: %w", err)
+	}
+	return n > 0, nil
+}
+
+// analysisIncident returns the ID of the incident an analysis belongs to,
+// 0 if none
+func (db *DB) analysisIncident(analysisID int64) (int64, error) {
+	var id int64
+	err := db.conn.QueryRow("SELECT incident_id FROM incident_analyses WHERE analysis_id = ?", analysisID).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to query analysis incident: %w", err)
+	}
+	return id, nil
+}
@@ -0,0 +1,147 @@
+package database
+
+import (
+	"fmt"
+	"time"
+)
+
+// Stored job statuses. A job is queued until a worker claims it, and
+// queued again with a later next_run_at when it is to be retried.
+const (
+	JobQueued  = "queued"
+	JobRunning = "running"
+)
+
+// QueuedJob is an analysis job kept in the jobs table until it finishes,
+// so that it survives restarts. Payload is the input of the job, as JSON.
+type QueuedJob struct {
+	ID        string
+	Kind      string
+	Target    string
+	Namespace string
+	RequestID string
+	Payload   string
+	Status    string
+	Attempts  int
+	LastError string
+	CreatedAt time.Time
+	NextRunAt time.Time
+	// ClaimedAt is when the running attempt started
+	ClaimedAt time.Time
+}
+
+const jobColumns = `id, kind, target, namespace, request_id, payload, status, attempts,
+	last_error, created_at, next_run_at, claimed_at`
+
+// SaveJob stores a queued job, due at its NextRunAt
+func (db *DB) SaveJob(job QueuedJob) error {
+	_, err := db.conn.Exec(`
+		INSERT INTO jobs (`+jobColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, 0, '', ?, ?, ?)`,
+		job.ID, job.Kind, job.Target, job.Namespace, job.RequestID, job.Payload,
+		JobQueued, job.CreatedAt, job.NextRunAt, time.Time{},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save job: %w", err)
+	}
+	return nil
+}
+
+// ClaimJob marks a job as running, unless it is already running since
+// staleBefore or later, and returns the number of the attempt starting. It
+// returns 0 if the job is gone or another worker has it.
+func (db *DB) ClaimJob(id string, staleBefore time.Time) (int, error) {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`
+		UPDATE jobs SET status = ?, attempts = attempts + 1, claimed_at = ?
+		WHERE id = ? AND (status = ? OR claimed_at < ?)`,
+		JobRunning, time.Now(), id, JobQueued, staleBefore.Local(),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim job: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim job: %w", err)
+	}
+	if n == 0 {
+		return 0, nil
+	}
+
+	var attempt int
+	if err := tx.QueryRow("SELECT attempts FROM jobs WHERE id = ?", id).Scan(&attempt); err != nil {
+		return 0, fmt.Errorf("failed to read job attempts: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit job claim: %w", err)
+	}
+	return attempt, nil
+}
+
+// RetryJob queues a job again, due at nextRunAt, recording why the last
+// attempt failed
+func (db *DB) RetryJob(id string, nextRunAt time.Time, lastError string) error {
+	_, err := db.conn.Exec(
+		"UPDATE jobs SET status = ?, next_run_at = ?, last_error = ? WHERE id = ?",
+		JobQueued, nextRunAt, lastError, id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to requeue job: %w", err)
+	}
+	return nil
+}
+
+// DeleteJob removes a finished job
+func (db *DB) DeleteJob(id string) error {
+	if _, err := db.conn.Exec("DELETE FROM jobs WHERE id = ?", id); err != nil {
+		return fmt.Errorf("failed to delete job: %w", err)
+	}
+	return nil
+}
+
+// DueJobs returns up to limit jobs to run now, oldest first: queued jobs
+// due by now, and running ones claimed before staleBefore, whose worker
+// went away
+func (db *DB) DueJobs(now, staleBefore time.Time, limit int) ([]QueuedJob, error) {
+	rows, err := db.conn.Query(`
+		SELECT `+jobColumns+`
+		FROM jobs
+		WHERE (status = ? AND next_run_at <= ?) OR (status = ? AND claimed_at < ?)
+		ORDER BY created_at ASC
+		LIMIT ?`,
+		JobQueued, now.Local(), JobRunning, staleBefore.Local(), limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query jobs: %w", err)
+	}
+	defer rows.Close()
+
+	var jobs []QueuedJob
+	for rows.Next() {
+		var j QueuedJob
+		err := rows.Scan(
+			&j.ID,
+			&j.Kind,
+			&j.Target,
+			&j.Namespace,
+			&j.RequestID,
+			&j.Payload,
+			&j.Status,
+			&j.Attempts,
+			&j.LastError,
+			&j.CreatedAt,
+			&j.NextRunAt,
+			&j.ClaimedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		jobs = append(jobs, j)
+	}
+	return jobs, rows.Err()
+}
@@ -0,0 +1,199 @@
+package database
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// migrationFiles holds the schema migrations of each dialect, in
+// migrations/<dialect>/<version>_<name>.up.sql with a .down.sql reverting
+// it. Versions only ever grow: a change to a released migration goes in a
+// new one, so that existing installs pick it up.
+//
+//go:embed migrations
+var migrationFiles embed.FS
+
+// migration is a versioned schema change
+type migration struct {
+	version int
+	name    string
+	up      string
+	down    string
+}
+
+// loadMigrations reads the migrations of a dialect, in version order
+func loadMigrations(d *dialect) ([]migration, error) {
+	dir := "migrations/" + d.name
+	entries, err := fs.ReadDir(migrationFiles, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := map[int]*migration{}
+	for _, entry := range entries {
+		base, direction, ok := strings.Cut(strings.TrimSuffix(entry.Name(), ".sql"), ".")
+		prefix, name, _ := strings.Cut(base, "_")
+		version, err := strconv.Atoi(prefix)
+		if !ok || err != nil || version <= 0 || (direction != "up" && direction != "down") {
+			return nil, fmt.Errorf("invalid migration file name %s", entry.Name())
+		}
+		content, err := fs.ReadFile(migrationFiles, dir+"/"+entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+
+		m := byVersion[version]
+		if m == nil {
+			m = &migration{version: version, name: name}
+			byVersion[version] = m
+		}
+		if m.name != name {
+			return nil, fmt.Errorf("migration %d is named both %s and %s", version, m.name, name)
+		}
+		if direction == "up" {
+			m.up = string(content)
+		} else {
+			m.down = string(content)
+		}
+	}
+
+	list := make([]migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.up == "" || m.down == "" {
+			return nil, fmt.Errorf("migration %04d_%s needs both an up and a down file", m.version, m.name)
+		}
+		list = append(list, *m)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].version < list[j].version })
+	return list, nil
+}
+
+// appliedMigrations returns the versions recorded in schema_migrations,
+// creating the table on first use
+func (db *DB) appliedMigrations() (map[int]bool, error) {
+	_, err := db.conn.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			applied_at ` + db.conn.dialect.timestamp + ` NOT NULL
+		)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	rows, err := db.conn.Query("SELECT version FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := map[int]bool{}
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			return nil, fmt.Errorf("failed to scan migration: %w", err)
+		}
+		applied[version] = true
+	}
+	return applied, rows.Err()
+}
+
+// SchemaVersion returns the newest migration applied to the database and
+// the newest one this build knows of. A database migrated by a newer build
+// reports a current version above latest.
+func (db *DB) SchemaVersion() (current, latest int, err error) {
+	list, err := loadMigrations(db.conn.dialect)
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(list) > 0 {
+		latest = list[len(list)-1].version
+	}
+
+	applied, err := db.appliedMigrations()
+	if err != nil {
+		return 0, 0, err
+	}
+	for version := range applied {
+		current = max(current, version)
+	}
+	return current, latest, nil
+}
+
+// Migrate applies the migrations up to version that have not run yet, or
+// reverts those above it, newest first, with their down migrations. A
+// negative version applies every pending migration and reverts none, so
+// that a build older than the database still starts. Each migration runs
+// in a transaction with its schema_migrations row, although MySQL commits
+// schema changes as they run.
+func (db *DB) Migrate(version int) error {
+	list, err := loadMigrations(db.conn.dialect)
+	if err != nil {
+		return err
+	}
+	revert := version >= 0
+	if !revert && len(list) > 0 {
+		version = list[len(list)-1].version
+	}
+
+	applied, err := db.appliedMigrations()
+	if err != nil {
+		return err
+	}
+
+	for _, m := range list {
+		if m.version > version || applied[m.version] {
+			continue
+		}
+		err := db.runMigration(m.up, "INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)", m.version, m.name, time.Now())
+		if err != nil {
+			return fmt.Errorf("failed to apply migration %04d_%s: %w", m.version, m.name, err)
+		}
+	}
+
+	known := map[int]migration{}
+	for _, m := range list {
+		known[m.version] = m
+	}
+	reverted := []int{}
+	for v := range applied {
+		if revert && v > version {
+			reverted = append(reverted, v)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(reverted)))
+	for _, v := range reverted {
+		m, ok := known[v]
+		if !ok {
+			return fmt.Errorf("cannot revert migration %d: it is unknown to this build", v)
+		}
+		if err := db.runMigration(m.down, "DELETE FROM schema_migrations WHERE version = ?", m.version); err != nil {
+			return fmt.Errorf("failed to revert migration %04d_%s: %w", m.version, m.name, err)
+		}
+	}
+	return db.detectVectorType()
+}
+
+// runMigration runs the statements of a migration and records it in
+// schema_migrations with record, in one transaction
+func (db *DB) runMigration(statements, record string, args ...any) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Run as written: migrations have no placeholders to rebind
+	if _, err := tx.Tx.Exec(statements); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(record, args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
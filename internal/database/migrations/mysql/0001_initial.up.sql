@@ -0,0 +1,105 @@
+-- The schema as of the introduction of versioned migrations. Databases
+-- created before then already have these tables: IF NOT EXISTS skips them.
+-- Indexes are declared inline as CREATE INDEX has no IF NOT EXISTS.
+CREATE TABLE IF NOT EXISTS analyses (
+	id BIGINT AUTO_INCREMENT PRIMARY KEY,
+	created_at DATETIME(6) NOT NULL,
+	alert_name VARCHAR(253) NOT NULL,
+	namespace VARCHAR(253) NOT NULL,
+	pod_name VARCHAR(253) NOT NULL,
+	severity VARCHAR(64) NOT NULL,
+	alert_started_at DATETIME(6) NOT NULL,
+	root_cause TEXT NOT NULL,
+	confidence VARCHAR(32) NOT NULL,
+	confidence_score INTEGER NOT NULL DEFAULT 0,
+	cluster VARCHAR(253) NOT NULL DEFAULT '',
+	analysis_json LONGTEXT NOT NULL,
+	UNIQUE(namespace, pod_name, alert_started_at),
+	INDEX idx_created_at (created_at DESC),
+	INDEX idx_namespace_pod (namespace, pod_name),
+	INDEX idx_severity (severity),
+	INDEX idx_confidence_score (confidence_score),
+	INDEX idx_cluster (cluster)
+);
+
+CREATE TABLE IF NOT EXISTS analysis_versions (
+	id BIGINT AUTO_INCREMENT PRIMARY KEY,
+	analysis_id BIGINT NOT NULL,
+	version INTEGER NOT NULL,
+	created_at DATETIME(6) NOT NULL,
+	model VARCHAR(253) NOT NULL,
+	prompt_version VARCHAR(64) NOT NULL,
+	root_cause TEXT NOT NULL,
+	confidence VARCHAR(32) NOT NULL,
+	confidence_score INTEGER NOT NULL DEFAULT 0,
+	analysis_json LONGTEXT NOT NULL,
+	UNIQUE(analysis_id, version)
+);
+
+CREATE TABLE IF NOT EXISTS target_failures (
+	namespace VARCHAR(253) NOT NULL,
+	target VARCHAR(253) NOT NULL,
+	failures INTEGER NOT NULL,
+	first_failure_at DATETIME(6) NOT NULL,
+	last_failure_at DATETIME(6) NOT NULL,
+	next_attempt_at DATETIME(6) NOT NULL,
+	last_error TEXT NOT NULL,
+	PRIMARY KEY(namespace, target)
+);
+
+CREATE TABLE IF NOT EXISTS daily_stats (
+	day VARCHAR(10) NOT NULL,
+	namespace VARCHAR(253) NOT NULL,
+	severity VARCHAR(64) NOT NULL,
+	category VARCHAR(253) NOT NULL,
+	count INTEGER NOT NULL,
+	PRIMARY KEY(day, namespace, severity, category)
+);
+
+CREATE TABLE IF NOT EXISTS feedback (
+	id BIGINT AUTO_INCREMENT PRIMARY KEY,
+	analysis_id BIGINT NOT NULL,
+	version INTEGER NOT NULL,
+	corrected_version INTEGER NOT NULL,
+	created_at DATETIME(6) NOT NULL,
+	author VARCHAR(253) NOT NULL,
+	rejected_root_cause TEXT NOT NULL,
+	hint TEXT NOT NULL,
+	corrected_root_cause TEXT NOT NULL,
+	INDEX idx_feedback_analysis (analysis_id)
+);
+
+CREATE TABLE IF NOT EXISTS remediations (
+	id BIGINT AUTO_INCREMENT PRIMARY KEY,
+	analysis_id BIGINT NOT NULL,
+	recommendation INTEGER NOT NULL,
+	created_at DATETIME(6) NOT NULL,
+	expires_at DATETIME(6) NOT NULL,
+	namespace VARCHAR(253) NOT NULL,
+	action VARCHAR(64) NOT NULL,
+	target VARCHAR(253) NOT NULL,
+	command TEXT NOT NULL,
+	status VARCHAR(32) NOT NULL,
+	decided_by VARCHAR(253) NOT NULL,
+	decided_at DATETIME(6) NOT NULL,
+	result TEXT NOT NULL,
+	INDEX idx_remediations_status (status, namespace)
+);
+
+CREATE TABLE IF NOT EXISTS remediation_events (
+	id BIGINT AUTO_INCREMENT PRIMARY KEY,
+	remediation_id BIGINT NOT NULL,
+	created_at DATETIME(6) NOT NULL,
+	event VARCHAR(32) NOT NULL,
+	actor VARCHAR(253) NOT NULL,
+	message TEXT NOT NULL,
+	INDEX idx_remediation_events (remediation_id)
+);
+
+CREATE TABLE IF NOT EXISTS alert_deliveries (
+	fingerprint VARCHAR(64) NOT NULL,
+	starts_at VARCHAR(64) NOT NULL,
+	analysis_id BIGINT NOT NULL,
+	created_at DATETIME(6) NOT NULL,
+	PRIMARY KEY(fingerprint, starts_at)
+);
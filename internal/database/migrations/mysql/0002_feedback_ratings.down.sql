@@ -0,0 +1,2 @@
+DELETE FROM feedback WHERE corrected_version = 0;
+ALTER TABLE feedback DROP COLUMN comment, DROP COLUMN rating;
@@ -0,0 +1,7 @@
+-- Feedback also records ratings of an analysis: up or down, with a comment
+-- and optionally the root cause a human found. Earlier records are
+-- corrections, which reject the version they correct.
+ALTER TABLE feedback
+	ADD COLUMN rating VARCHAR(8) NOT NULL DEFAULT '',
+	ADD COLUMN comment TEXT NOT NULL;
+UPDATE feedback SET rating = 'down';
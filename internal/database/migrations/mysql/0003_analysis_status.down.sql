@@ -0,0 +1,2 @@
+DELETE FROM analyses WHERE status != 'completed';
+ALTER TABLE analyses DROP INDEX idx_status, DROP COLUMN error_message, DROP COLUMN status;
@@ -0,0 +1,18 @@
+-- Webhook analysis jobs are kept until they finish, so that queued ones
+-- survive restarts and failed ones are retried. claimed_at is when the
+-- running attempt started.
+CREATE TABLE IF NOT EXISTS jobs (
+	id VARCHAR(64) PRIMARY KEY,
+	kind VARCHAR(32) NOT NULL,
+	target VARCHAR(253) NOT NULL DEFAULT '',
+	namespace VARCHAR(253) NOT NULL DEFAULT '',
+	request_id VARCHAR(128) NOT NULL DEFAULT '',
+	payload LONGTEXT NOT NULL,
+	status VARCHAR(16) NOT NULL,
+	attempts INTEGER NOT NULL DEFAULT 0,
+	last_error TEXT NOT NULL,
+	created_at DATETIME(6) NOT NULL,
+	next_run_at DATETIME(6) NOT NULL,
+	claimed_at DATETIME(6) NOT NULL,
+	INDEX idx_jobs_status_next_run (status, next_run_at)
+);
@@ -0,0 +1,29 @@
+-- The LLM provider, model and tokens of analyses and re-analyses, with
+-- their estimated USD cost. Daily stats sum them per model: the derived
+-- table is recreated with the new key and backfilled by the next rollup.
+ALTER TABLE analyses
+	ADD COLUMN provider VARCHAR(64) NOT NULL DEFAULT '',
+	ADD COLUMN model VARCHAR(128) NOT NULL DEFAULT '',
+	ADD COLUMN prompt_tokens INTEGER NOT NULL DEFAULT 0,
+	ADD COLUMN completion_tokens INTEGER NOT NULL DEFAULT 0,
+	ADD COLUMN cost DOUBLE NOT NULL DEFAULT 0;
+
+ALTER TABLE analysis_versions
+	ADD COLUMN provider VARCHAR(64) NOT NULL DEFAULT '',
+	ADD COLUMN prompt_tokens INTEGER NOT NULL DEFAULT 0,
+	ADD COLUMN completion_tokens INTEGER NOT NULL DEFAULT 0,
+	ADD COLUMN cost DOUBLE NOT NULL DEFAULT 0;
+
+DROP TABLE IF EXISTS daily_stats;
+CREATE TABLE daily_stats (
+	day VARCHAR(10) NOT NULL,
+	namespace VARCHAR(253) NOT NULL,
+	severity VARCHAR(64) NOT NULL,
+	category VARCHAR(253) NOT NULL,
+	model VARCHAR(128) NOT NULL,
+	count INTEGER NOT NULL,
+	prompt_tokens BIGINT NOT NULL,
+	completion_tokens BIGINT NOT NULL,
+	cost DOUBLE NOT NULL,
+	PRIMARY KEY(day, namespace, severity, category, model)
+);
@@ -0,0 +1,10 @@
+-- Free-form labels readers attach to analyses, such as "postmortem-filed",
+-- "false-positive" or a team name, to find them again
+CREATE TABLE IF NOT EXISTS analysis_tags (
+	analysis_id BIGINT NOT NULL,
+	tag VARCHAR(64) NOT NULL,
+	created_at DATETIME(6) NOT NULL,
+	author VARCHAR(253) NOT NULL DEFAULT '',
+	PRIMARY KEY (analysis_id, tag),
+	INDEX idx_analysis_tags_tag (tag)
+);
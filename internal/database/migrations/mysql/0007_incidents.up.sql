@@ -0,0 +1,19 @@
+-- Incidents group the analyses of one outage, across pods and alerts. An
+-- analysis belongs to at most one incident.
+CREATE TABLE IF NOT EXISTS incidents (
+	id BIGINT AUTO_INCREMENT PRIMARY KEY,
+	created_at DATETIME(6) NOT NULL,
+	updated_at DATETIME(6) NOT NULL,
+	title VARCHAR(255) NOT NULL,
+	status VARCHAR(16) NOT NULL,
+	author VARCHAR(253) NOT NULL DEFAULT '',
+	INDEX idx_incidents_status (status, updated_at)
+);
+
+CREATE TABLE IF NOT EXISTS incident_analyses (
+	analysis_id BIGINT PRIMARY KEY,
+	incident_id BIGINT NOT NULL,
+	added_at DATETIME(6) NOT NULL,
+	author VARCHAR(253) NOT NULL DEFAULT '',
+	INDEX idx_incident_analyses_incident (incident_id)
+);
@@ -0,0 +1,11 @@
+-- Embedding vectors of analyses for similarity search, one per analysis.
+-- Vectors are little-endian float32s; only those of the same model and
+-- dimensions are compared.
+CREATE TABLE IF NOT EXISTS analysis_embeddings (
+	analysis_id BIGINT PRIMARY KEY,
+	model VARCHAR(255) NOT NULL,
+	dimensions INT NOT NULL,
+	created_at DATETIME(6) NOT NULL,
+	embedding MEDIUMBLOB NOT NULL,
+	INDEX idx_analysis_embeddings_model (model, dimensions)
+);
@@ -0,0 +1,9 @@
+-- Older releases know no deleted analyses: they are purged
+DELETE FROM feedback WHERE analysis_id IN (SELECT id FROM analyses WHERE deleted_at IS NOT NULL);
+DELETE FROM alert_deliveries WHERE analysis_id IN (SELECT id FROM analyses WHERE deleted_at IS NOT NULL);
+DELETE FROM analysis_versions WHERE analysis_id IN (SELECT id FROM analyses WHERE deleted_at IS NOT NULL);
+DELETE FROM analysis_embeddings WHERE analysis_id IN (SELECT id FROM analyses WHERE deleted_at IS NOT NULL);
+DELETE FROM analysis_tags WHERE analysis_id IN (SELECT id FROM analyses WHERE deleted_at IS NOT NULL);
+DELETE FROM incident_analyses WHERE analysis_id IN (SELECT id FROM analyses WHERE deleted_at IS NOT NULL);
+DELETE FROM analyses WHERE deleted_at IS NOT NULL;
+ALTER TABLE analyses DROP INDEX idx_deleted_at, DROP COLUMN deleted_at;
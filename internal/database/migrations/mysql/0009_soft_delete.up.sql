@@ -0,0 +1,5 @@
+-- Deleted analyses are kept, hidden, until the retention job purges them,
+-- so that they can be restored. deleted_at is NULL for the others.
+ALTER TABLE analyses
+	ADD COLUMN deleted_at DATETIME(6) NULL,
+	ADD INDEX idx_deleted_at (deleted_at);
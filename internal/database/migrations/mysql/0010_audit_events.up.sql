@@ -0,0 +1,17 @@
+-- Security-relevant actions: who analyzed, re-ran, deleted or restored
+-- analyses, reloaded the configuration or decided remediations. Kept when
+-- the analyses are deleted.
+CREATE TABLE IF NOT EXISTS audit_events (
+	id BIGINT AUTO_INCREMENT PRIMARY KEY,
+	created_at DATETIME(6) NOT NULL,
+	actor VARCHAR(253) NOT NULL,
+	action VARCHAR(64) NOT NULL,
+	resource TEXT NOT NULL,
+	status INT NOT NULL DEFAULT 0,
+	client_ip VARCHAR(64) NOT NULL DEFAULT '',
+	request_id VARCHAR(128) NOT NULL DEFAULT '',
+	detail TEXT NOT NULL,
+	INDEX idx_audit_events_created_at (created_at),
+	INDEX idx_audit_events_actor (actor, created_at),
+	INDEX idx_audit_events_action (action, created_at)
+);
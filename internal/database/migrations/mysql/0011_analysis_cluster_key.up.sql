@@ -0,0 +1,6 @@
+-- Analyses are unique per cluster: alerts on pods of the same name in two
+-- clusters, starting at the same time, are analyzed apart. The old key is
+-- named after its first column.
+ALTER TABLE analyses
+	DROP INDEX namespace,
+	ADD UNIQUE KEY uq_analyses_alert (cluster, namespace, pod_name, alert_started_at);
@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS alert_deliveries;
+DROP TABLE IF EXISTS remediation_events;
+DROP TABLE IF EXISTS remediations;
+DROP TABLE IF EXISTS feedback;
+DROP TABLE IF EXISTS daily_stats;
+DROP TABLE IF EXISTS target_failures;
+DROP TABLE IF EXISTS analysis_versions;
+DROP TABLE IF EXISTS analyses;
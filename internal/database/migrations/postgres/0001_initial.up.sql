@@ -0,0 +1,109 @@
+-- The schema as of the introduction of versioned migrations. Databases
+-- created before then already have these tables: IF NOT EXISTS skips them.
+CREATE TABLE IF NOT EXISTS analyses (
+	id BIGSERIAL PRIMARY KEY,
+	created_at TIMESTAMPTZ NOT NULL,
+	alert_name TEXT NOT NULL,
+	namespace TEXT NOT NULL,
+	pod_name TEXT NOT NULL,
+	severity TEXT NOT NULL,
+	alert_started_at TIMESTAMPTZ NOT NULL,
+	root_cause TEXT NOT NULL,
+	confidence TEXT NOT NULL,
+	confidence_score INTEGER NOT NULL DEFAULT 0,
+	cluster TEXT NOT NULL DEFAULT '',
+	analysis_json TEXT NOT NULL,
+	UNIQUE(namespace, pod_name, alert_started_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_created_at ON analyses(created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_namespace_pod ON analyses(namespace, pod_name);
+CREATE INDEX IF NOT EXISTS idx_severity ON analyses(severity);
+
+CREATE TABLE IF NOT EXISTS analysis_versions (
+	id BIGSERIAL PRIMARY KEY,
+	analysis_id BIGINT NOT NULL,
+	version INTEGER NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL,
+	model TEXT NOT NULL,
+	prompt_version TEXT NOT NULL,
+	root_cause TEXT NOT NULL,
+	confidence TEXT NOT NULL,
+	confidence_score INTEGER NOT NULL DEFAULT 0,
+	analysis_json TEXT NOT NULL,
+	UNIQUE(analysis_id, version)
+);
+
+CREATE TABLE IF NOT EXISTS target_failures (
+	namespace TEXT NOT NULL,
+	target TEXT NOT NULL,
+	failures INTEGER NOT NULL,
+	first_failure_at TIMESTAMPTZ NOT NULL,
+	last_failure_at TIMESTAMPTZ NOT NULL,
+	next_attempt_at TIMESTAMPTZ NOT NULL,
+	last_error TEXT NOT NULL,
+	PRIMARY KEY(namespace, target)
+);
+
+CREATE TABLE IF NOT EXISTS daily_stats (
+	day TEXT NOT NULL,
+	namespace TEXT NOT NULL,
+	severity TEXT NOT NULL,
+	category TEXT NOT NULL,
+	count INTEGER NOT NULL,
+	PRIMARY KEY(day, namespace, severity, category)
+);
+
+CREATE TABLE IF NOT EXISTS feedback (
+	id BIGSERIAL PRIMARY KEY,
+	analysis_id BIGINT NOT NULL,
+	version INTEGER NOT NULL,
+	corrected_version INTEGER NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL,
+	author TEXT NOT NULL,
+	rejected_root_cause TEXT NOT NULL,
+	hint TEXT NOT NULL,
+	corrected_root_cause TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_feedback_analysis ON feedback(analysis_id);
+
+CREATE TABLE IF NOT EXISTS remediations (
+	id BIGSERIAL PRIMARY KEY,
+	analysis_id BIGINT NOT NULL,
+	recommendation INTEGER NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL,
+	expires_at TIMESTAMPTZ NOT NULL,
+	namespace TEXT NOT NULL,
+	action TEXT NOT NULL,
+	target TEXT NOT NULL,
+	command TEXT NOT NULL,
+	status TEXT NOT NULL,
+	decided_by TEXT NOT NULL,
+	decided_at TIMESTAMPTZ NOT NULL,
+	result TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_remediations_status ON remediations(status, namespace);
+
+CREATE TABLE IF NOT EXISTS remediation_events (
+	id BIGSERIAL PRIMARY KEY,
+	remediation_id BIGINT NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL,
+	event TEXT NOT NULL,
+	actor TEXT NOT NULL,
+	message TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_remediation_events ON remediation_events(remediation_id);
+
+CREATE TABLE IF NOT EXISTS alert_deliveries (
+	fingerprint TEXT NOT NULL,
+	starts_at TEXT NOT NULL,
+	analysis_id BIGINT NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL,
+	PRIMARY KEY(fingerprint, starts_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_confidence_score ON analyses(confidence_score);
+CREATE INDEX IF NOT EXISTS idx_cluster ON analyses(cluster);
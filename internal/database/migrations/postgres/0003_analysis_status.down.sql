@@ -0,0 +1,3 @@
+DELETE FROM analyses WHERE status != 'completed';
+DROP INDEX IF EXISTS idx_status;
+ALTER TABLE analyses DROP COLUMN error_message, DROP COLUMN status;
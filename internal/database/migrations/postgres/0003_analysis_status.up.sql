@@ -0,0 +1,6 @@
+-- Analyses are recorded when they start, as running, and end completed or
+-- failed with the error. Earlier analyses all completed.
+ALTER TABLE analyses
+	ADD COLUMN status TEXT NOT NULL DEFAULT 'completed',
+	ADD COLUMN error_message TEXT NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS idx_status ON analyses(status);
@@ -0,0 +1,22 @@
+DROP TABLE IF EXISTS daily_stats;
+CREATE TABLE daily_stats (
+	day TEXT NOT NULL,
+	namespace TEXT NOT NULL,
+	severity TEXT NOT NULL,
+	category TEXT NOT NULL,
+	count INTEGER NOT NULL,
+	PRIMARY KEY(day, namespace, severity, category)
+);
+
+ALTER TABLE analysis_versions
+	DROP COLUMN cost,
+	DROP COLUMN completion_tokens,
+	DROP COLUMN prompt_tokens,
+	DROP COLUMN provider;
+
+ALTER TABLE analyses
+	DROP COLUMN cost,
+	DROP COLUMN completion_tokens,
+	DROP COLUMN prompt_tokens,
+	DROP COLUMN model,
+	DROP COLUMN provider;
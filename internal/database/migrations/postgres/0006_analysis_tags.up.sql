@@ -0,0 +1,11 @@
+-- Free-form labels readers attach to analyses, such as "postmortem-filed",
+-- "false-positive" or a team name, to find them again
+CREATE TABLE IF NOT EXISTS analysis_tags (
+	analysis_id BIGINT NOT NULL,
+	tag TEXT NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL,
+	author TEXT NOT NULL DEFAULT '',
+	PRIMARY KEY (analysis_id, tag)
+);
+
+CREATE INDEX IF NOT EXISTS idx_analysis_tags_tag ON analysis_tags(tag);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS incident_analyses;
+DROP TABLE IF EXISTS incidents;
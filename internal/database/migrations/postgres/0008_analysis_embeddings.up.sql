@@ -0,0 +1,33 @@
+-- Embedding vectors of analyses for similarity search, one per analysis.
+-- Only vectors of the same model and dimensions are compared. They are
+-- pgvector vectors, compared by the database, when the extension can be
+-- created, and little-endian float32s in BYTEA otherwise.
+DO $$
+BEGIN
+	BEGIN
+		CREATE EXTENSION IF NOT EXISTS vector;
+	EXCEPTION WHEN OTHERS THEN
+		-- Not installed, or not allowed for this role
+		NULL;
+	END;
+
+	IF EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'vector') THEN
+		CREATE TABLE IF NOT EXISTS analysis_embeddings (
+			analysis_id BIGINT PRIMARY KEY,
+			model TEXT NOT NULL,
+			dimensions INTEGER NOT NULL,
+			created_at TIMESTAMPTZ NOT NULL,
+			embedding vector NOT NULL
+		);
+	ELSE
+		CREATE TABLE IF NOT EXISTS analysis_embeddings (
+			analysis_id BIGINT PRIMARY KEY,
+			model TEXT NOT NULL,
+			dimensions INTEGER NOT NULL,
+			created_at TIMESTAMPTZ NOT NULL,
+			embedding BYTEA NOT NULL
+		);
+	END IF;
+END $$;
+
+CREATE INDEX IF NOT EXISTS idx_analysis_embeddings_model ON analysis_embeddings(model, dimensions);
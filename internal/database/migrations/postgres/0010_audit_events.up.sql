@@ -0,0 +1,18 @@
+-- Security-relevant actions: who analyzed, re-ran, deleted or restored
+-- analyses, reloaded the configuration or decided remediations. Kept when
+-- the analyses are deleted.
+CREATE TABLE IF NOT EXISTS audit_events (
+	id BIGSERIAL PRIMARY KEY,
+	created_at TIMESTAMPTZ NOT NULL,
+	actor TEXT NOT NULL,
+	action TEXT NOT NULL,
+	resource TEXT NOT NULL DEFAULT '',
+	status INTEGER NOT NULL DEFAULT 0,
+	client_ip TEXT NOT NULL DEFAULT '',
+	request_id TEXT NOT NULL DEFAULT '',
+	detail TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_events_created_at ON audit_events(created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_events_actor ON audit_events(actor, created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_events_action ON audit_events(action, created_at);
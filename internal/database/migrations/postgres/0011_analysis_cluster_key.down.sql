@@ -0,0 +1,12 @@
+-- Older releases key analyses without their cluster: of those differing
+-- only in cluster, the first one is kept
+DELETE FROM feedback WHERE analysis_id IN (SELECT a.id FROM analyses a JOIN analyses b ON b.namespace = a.namespace AND b.pod_name = a.pod_name AND b.alert_started_at = a.alert_started_at AND b.id < a.id);
+DELETE FROM alert_deliveries WHERE analysis_id IN (SELECT a.id FROM analyses a JOIN analyses b ON b.namespace = a.namespace AND b.pod_name = a.pod_name AND b.alert_started_at = a.alert_started_at AND b.id < a.id);
+DELETE FROM analysis_versions WHERE analysis_id IN (SELECT a.id FROM analyses a JOIN analyses b ON b.namespace = a.namespace AND b.pod_name = a.pod_name AND b.alert_started_at = a.alert_started_at AND b.id < a.id);
+DELETE FROM analysis_embeddings WHERE analysis_id IN (SELECT a.id FROM analyses a JOIN analyses b ON b.namespace = a.namespace AND b.pod_name = a.pod_name AND b.alert_started_at = a.alert_started_at AND b.id < a.id);
+DELETE FROM analysis_tags WHERE analysis_id IN (SELECT a.id FROM analyses a JOIN analyses b ON b.namespace = a.namespace AND b.pod_name = a.pod_name AND b.alert_started_at = a.alert_started_at AND b.id < a.id);
+DELETE FROM incident_analyses WHERE analysis_id IN (SELECT a.id FROM analyses a JOIN analyses b ON b.namespace = a.namespace AND b.pod_name = a.pod_name AND b.alert_started_at = a.alert_started_at AND b.id < a.id);
+DELETE FROM analyses WHERE id IN (SELECT a.id FROM analyses a JOIN analyses b ON b.namespace = a.namespace AND b.pod_name = a.pod_name AND b.alert_started_at = a.alert_started_at AND b.id < a.id);
+ALTER TABLE analyses
+	DROP CONSTRAINT analyses_cluster_namespace_pod_name_alert_started_at_key,
+	ADD UNIQUE (namespace, pod_name, alert_started_at);
@@ -0,0 +1,5 @@
+-- Analyses are unique per cluster: alerts on pods of the same name in two
+-- clusters, starting at the same time, are analyzed apart
+ALTER TABLE analyses
+	DROP CONSTRAINT analyses_namespace_pod_name_alert_started_at_key,
+	ADD UNIQUE (cluster, namespace, pod_name, alert_started_at);
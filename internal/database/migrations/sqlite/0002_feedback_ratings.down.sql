@@ -0,0 +1,3 @@
+DELETE FROM feedback WHERE corrected_version = 0;
+ALTER TABLE feedback DROP COLUMN comment;
+ALTER TABLE feedback DROP COLUMN rating;
@@ -0,0 +1,6 @@
+-- Feedback also records ratings of an analysis: up or down, with a comment
+-- and optionally the root cause a human found. Earlier records are
+-- corrections, which reject the version they correct.
+ALTER TABLE feedback ADD COLUMN rating TEXT NOT NULL DEFAULT '';
+ALTER TABLE feedback ADD COLUMN comment TEXT NOT NULL DEFAULT '';
+UPDATE feedback SET rating = 'down';
@@ -0,0 +1,4 @@
+DELETE FROM analyses WHERE status != 'completed';
+DROP INDEX IF EXISTS idx_status;
+ALTER TABLE analyses DROP COLUMN error_message;
+ALTER TABLE analyses DROP COLUMN status;
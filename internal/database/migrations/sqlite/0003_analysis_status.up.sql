@@ -0,0 +1,5 @@
+-- Analyses are recorded when they start, as running, and end completed or
+-- failed with the error. Earlier analyses all completed.
+ALTER TABLE analyses ADD COLUMN status TEXT NOT NULL DEFAULT 'completed';
+ALTER TABLE analyses ADD COLUMN error_message TEXT NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS idx_status ON analyses(status);
@@ -0,0 +1,19 @@
+-- Webhook analysis jobs are kept until they finish, so that queued ones
+-- survive restarts and failed ones are retried. claimed_at is when the
+-- running attempt started.
+CREATE TABLE IF NOT EXISTS jobs (
+	id TEXT PRIMARY KEY,
+	kind TEXT NOT NULL,
+	target TEXT NOT NULL DEFAULT '',
+	namespace TEXT NOT NULL DEFAULT '',
+	request_id TEXT NOT NULL DEFAULT '',
+	payload TEXT NOT NULL,
+	status TEXT NOT NULL,
+	attempts INTEGER NOT NULL DEFAULT 0,
+	last_error TEXT NOT NULL DEFAULT '',
+	created_at DATETIME NOT NULL,
+	next_run_at DATETIME NOT NULL,
+	claimed_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_jobs_status_next_run ON jobs(status, next_run_at);
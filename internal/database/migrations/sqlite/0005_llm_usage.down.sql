@@ -0,0 +1,20 @@
+DROP TABLE IF EXISTS daily_stats;
+CREATE TABLE daily_stats (
+	day TEXT NOT NULL,
+	namespace TEXT NOT NULL,
+	severity TEXT NOT NULL,
+	category TEXT NOT NULL,
+	count INTEGER NOT NULL,
+	PRIMARY KEY(day, namespace, severity, category)
+);
+
+ALTER TABLE analysis_versions DROP COLUMN cost;
+ALTER TABLE analysis_versions DROP COLUMN completion_tokens;
+ALTER TABLE analysis_versions DROP COLUMN prompt_tokens;
+ALTER TABLE analysis_versions DROP COLUMN provider;
+
+ALTER TABLE analyses DROP COLUMN cost;
+ALTER TABLE analyses DROP COLUMN completion_tokens;
+ALTER TABLE analyses DROP COLUMN prompt_tokens;
+ALTER TABLE analyses DROP COLUMN model;
+ALTER TABLE analyses DROP COLUMN provider;
@@ -0,0 +1,27 @@
+-- The LLM provider, model and tokens of analyses and re-analyses, with
+-- their estimated USD cost. Daily stats sum them per model: the derived
+-- table is recreated with the new key and backfilled by the next rollup.
+ALTER TABLE analyses ADD COLUMN provider TEXT NOT NULL DEFAULT '';
+ALTER TABLE analyses ADD COLUMN model TEXT NOT NULL DEFAULT '';
+ALTER TABLE analyses ADD COLUMN prompt_tokens INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE analyses ADD COLUMN completion_tokens INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE analyses ADD COLUMN cost REAL NOT NULL DEFAULT 0;
+
+ALTER TABLE analysis_versions ADD COLUMN provider TEXT NOT NULL DEFAULT '';
+ALTER TABLE analysis_versions ADD COLUMN prompt_tokens INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE analysis_versions ADD COLUMN completion_tokens INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE analysis_versions ADD COLUMN cost REAL NOT NULL DEFAULT 0;
+
+DROP TABLE IF EXISTS daily_stats;
+CREATE TABLE daily_stats (
+	day TEXT NOT NULL,
+	namespace TEXT NOT NULL,
+	severity TEXT NOT NULL,
+	category TEXT NOT NULL,
+	model TEXT NOT NULL,
+	count INTEGER NOT NULL,
+	prompt_tokens INTEGER NOT NULL,
+	completion_tokens INTEGER NOT NULL,
+	cost REAL NOT NULL,
+	PRIMARY KEY(day, namespace, severity, category, model)
+);
@@ -0,0 +1,21 @@
+-- Incidents group the analyses of one outage, across pods and alerts. An
+-- analysis belongs to at most one incident.
+CREATE TABLE IF NOT EXISTS incidents (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL,
+	title TEXT NOT NULL,
+	status TEXT NOT NULL,
+	author TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_incidents_status ON incidents(status, updated_at);
+
+CREATE TABLE IF NOT EXISTS incident_analyses (
+	analysis_id INTEGER PRIMARY KEY,
+	incident_id INTEGER NOT NULL,
+	added_at DATETIME NOT NULL,
+	author TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_incident_analyses_incident ON incident_analyses(incident_id);
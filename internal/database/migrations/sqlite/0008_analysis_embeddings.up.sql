@@ -0,0 +1,12 @@
+-- Embedding vectors of analyses for similarity search, one per analysis.
+-- Vectors are little-endian float32s; only those of the same model and
+-- dimensions are compared.
+CREATE TABLE IF NOT EXISTS analysis_embeddings (
+	analysis_id INTEGER PRIMARY KEY,
+	model TEXT NOT NULL,
+	dimensions INTEGER NOT NULL,
+	created_at DATETIME NOT NULL,
+	embedding BLOB NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_analysis_embeddings_model ON analysis_embeddings(model, dimensions);
@@ -0,0 +1,10 @@
+-- Older releases know no deleted analyses: they are purged
+DELETE FROM feedback WHERE analysis_id IN (SELECT id FROM analyses WHERE deleted_at IS NOT NULL);
+DELETE FROM alert_deliveries WHERE analysis_id IN (SELECT id FROM analyses WHERE deleted_at IS NOT NULL);
+DELETE FROM analysis_versions WHERE analysis_id IN (SELECT id FROM analyses WHERE deleted_at IS NOT NULL);
+DELETE FROM analysis_embeddings WHERE analysis_id IN (SELECT id FROM analyses WHERE deleted_at IS NOT NULL);
+DELETE FROM analysis_tags WHERE analysis_id IN (SELECT id FROM analyses WHERE deleted_at IS NOT NULL);
+DELETE FROM incident_analyses WHERE analysis_id IN (SELECT id FROM analyses WHERE deleted_at IS NOT NULL);
+DELETE FROM analyses WHERE deleted_at IS NOT NULL;
+DROP INDEX IF EXISTS idx_deleted_at;
+ALTER TABLE analyses DROP COLUMN deleted_at;
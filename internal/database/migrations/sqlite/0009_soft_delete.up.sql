@@ -0,0 +1,4 @@
+-- Deleted analyses are kept, hidden, until the retention job purges them,
+-- so that they can be restored. deleted_at is NULL for the others.
+ALTER TABLE analyses ADD COLUMN deleted_at DATETIME;
+CREATE INDEX IF NOT EXISTS idx_deleted_at ON analyses(deleted_at);
@@ -0,0 +1,46 @@
+-- Older releases key analyses without their cluster: of those differing
+-- only in cluster, the first one is kept
+DELETE FROM feedback WHERE analysis_id IN (SELECT a.id FROM analyses a JOIN analyses b ON b.namespace = a.namespace AND b.pod_name = a.pod_name AND b.alert_started_at = a.alert_started_at AND b.id < a.id);
+DELETE FROM alert_deliveries WHERE analysis_id IN (SELECT a.id FROM analyses a JOIN analyses b ON b.namespace = a.namespace AND b.pod_name = a.pod_name AND b.alert_started_at = a.alert_started_at AND b.id < a.id);
+DELETE FROM analysis_versions WHERE analysis_id IN (SELECT a.id FROM analyses a JOIN analyses b ON b.namespace = a.namespace AND b.pod_name = a.pod_name AND b.alert_started_at = a.alert_started_at AND b.id < a.id);
+DELETE FROM analysis_embeddings WHERE analysis_id IN (SELECT a.id FROM analyses a JOIN analyses b ON b.namespace = a.namespace AND b.pod_name = a.pod_name AND b.alert_started_at = a.alert_started_at AND b.id < a.id);
+DELETE FROM analysis_tags WHERE analysis_id IN (SELECT a.id FROM analyses a JOIN analyses b ON b.namespace = a.namespace AND b.pod_name = a.pod_name AND b.alert_started_at = a.alert_started_at AND b.id < a.id);
+DELETE FROM incident_analyses WHERE analysis_id IN (SELECT a.id FROM analyses a JOIN analyses b ON b.namespace = a.namespace AND b.pod_name = a.pod_name AND b.alert_started_at = a.alert_started_at AND b.id < a.id);
+DELETE FROM analyses WHERE id IN (SELECT a.id FROM analyses a JOIN analyses b ON b.namespace = a.namespace AND b.pod_name = a.pod_name AND b.alert_started_at = a.alert_started_at AND b.id < a.id);
+CREATE TABLE analyses_new (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	created_at DATETIME NOT NULL,
+	alert_name TEXT NOT NULL,
+	namespace TEXT NOT NULL,
+	pod_name TEXT NOT NULL,
+	severity TEXT NOT NULL,
+	alert_started_at DATETIME NOT NULL,
+	root_cause TEXT NOT NULL,
+	confidence TEXT NOT NULL,
+	confidence_score INTEGER NOT NULL DEFAULT 0,
+	cluster TEXT NOT NULL DEFAULT '',
+	analysis_json TEXT NOT NULL,
+	status TEXT NOT NULL DEFAULT 'completed',
+	error_message TEXT NOT NULL DEFAULT '',
+	provider TEXT NOT NULL DEFAULT '',
+	model TEXT NOT NULL DEFAULT '',
+	prompt_tokens INTEGER NOT NULL DEFAULT 0,
+	completion_tokens INTEGER NOT NULL DEFAULT 0,
+	cost REAL NOT NULL DEFAULT 0,
+	deleted_at DATETIME,
+	UNIQUE(namespace, pod_name, alert_started_at)
+);
+INSERT INTO analyses_new (id, created_at, alert_name, namespace, pod_name, severity, alert_started_at, root_cause, confidence, confidence_score, cluster, analysis_json, status, error_message, provider, model, prompt_tokens, completion_tokens, cost, deleted_at)
+SELECT id, created_at, alert_name, namespace, pod_name, severity, alert_started_at, root_cause, confidence, confidence_score, cluster, analysis_json, status, error_message, provider, model, prompt_tokens, completion_tokens, cost, deleted_at FROM analyses;
+-- Keep IDs of purged analyses from being handed out again
+UPDATE sqlite_sequence SET seq = (SELECT seq FROM sqlite_sequence WHERE name = 'analyses') WHERE name = 'analyses_new';
+DROP TABLE analyses;
+ALTER TABLE analyses_new RENAME TO analyses;
+
+CREATE INDEX IF NOT EXISTS idx_created_at ON analyses(created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_namespace_pod ON analyses(namespace, pod_name);
+CREATE INDEX IF NOT EXISTS idx_severity ON analyses(severity);
+CREATE INDEX IF NOT EXISTS idx_confidence_score ON analyses(confidence_score);
+CREATE INDEX IF NOT EXISTS idx_cluster ON analyses(cluster);
+CREATE INDEX IF NOT EXISTS idx_status ON analyses(status);
+CREATE INDEX IF NOT EXISTS idx_deleted_at ON analyses(deleted_at);
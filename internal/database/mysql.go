@@ -0,0 +1,43 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// mysqlSQLMode drops NO_ZERO_DATE from the session defaults: unset times,
+// such as the decision time of a pending remediation, are stored as zero
+// dates
+const mysqlSQLMode = "'STRICT_TRANS_TABLES,NO_ENGINE_SUBSTITUTION'"
+
+var mysqlDialect = &dialect{
+	driver:       "mysql",
+	name:         "mysql",
+	timestamp:    "DATETIME(6)",
+	utcDay:       "DATE_FORMAT(created_at, '%Y-%m-%d')",
+	duplicateKey: true,
+	// Times are written in UTC, see mysqlDSN
+	timeLiteral:      "2006-01-02 15:04:05.999999",
+	zeroTime:         "0000-00-00 00:00:00",
+	backslashEscapes: true,
+}
+
+// mysqlDSN sets the connection options the queries rely on: migrations are
+// run as one multi-statement Exec each, and times are read back as
+// time.Time in UTC, the zone they are written in
+func mysqlDSN(dsn string) (string, error) {
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return "", fmt.Errorf("invalid mysql dsn: %w", err)
+	}
+	cfg.MultiStatements = true
+	cfg.ParseTime = true
+	cfg.Loc = time.UTC
+	if cfg.Params == nil {
+		cfg.Params = map[string]string{}
+	}
+	cfg.Params["sql_mode"] = mysqlSQLMode
+	return cfg.FormatDSN(), nil
+}
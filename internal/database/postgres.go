@@ -0,0 +1,16 @@
+package database
+
+import (
+	// Registers the "pgx" database/sql driver
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+var postgresDialect = &dialect{
+	driver:         "pgx",
+	name:           "postgres",
+	timestamp:      "TIMESTAMPTZ",
+	numberedParams: true,
+	utcDay:         "to_char(created_at AT TIME ZONE 'UTC', 'YYYY-MM-DD')",
+	timeLiteral:    "2006-01-02 15:04:05.999999-07:00",
+	resetSequence:  "SELECT setval(pg_get_serial_sequence('%[1]s', 'id'), MAX(id)) FROM %[1]s",
+}
@@ -0,0 +1,243 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Remediation statuses. A remediation is proposed as pending and moves
+// once: to rejected or expired, or through approved to executed or failed.
+const (
+	RemediationPending  = "pending"
+	RemediationApproved = "approved"
+	RemediationRejected = "rejected"
+	RemediationExpired  = "expired"
+	RemediationExecuted = "executed"
+	RemediationFailed   = "failed"
+)
+
+// RemediationProposed is the audit event recorded when a remediation is
+// created; the other events are named after the status entered
+const RemediationProposed = "proposed"
+
+// Remediation is a recommended action awaiting or past its approval.
+// Remediations and their events are the audit trail of automatic changes
+// and are kept when the analysis is deleted.
+type Remediation struct {
+	ID             int64
+	AnalysisID     int64
+	Recommendation int
+	CreatedAt      time.Time
+	ExpiresAt      time.Time
+	Namespace      string
+	Action         string
+	Target         string
+	Command        string
+	Status         string
+	DecidedBy      string
+	DecidedAt      time.Time
+	Result         string
+}
+
+// RemediationEvent is one entry of the audit trail of a remediation
+type RemediationEvent struct {
+	ID            int64
+	RemediationID int64
+	CreatedAt     time.Time
+	Event         string
+	Actor         string
+	Message       string
+}
+
+const remediationColumns = `id, analysis_id, recommendation, created_at, expires_at, namespace, action,
+	target, command, status, decided_by, decided_at, result`
+
+func scanRemediation(row interface{ Scan(...any) error }) (*Remediation, error) {
+	var r Remediation
+	err := row.Scan(
+		&r.ID,
+		&r.AnalysisID,
+		&r.Recommendation,
+		&r.CreatedAt,
+		&r.ExpiresAt,
+		&r.Namespace,
+		&r.Action,
+		&r.Target,
+		&r.Command,
+		&r.Status,
+		&r.DecidedBy,
+		&r.DecidedAt,
+		&r.Result,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// CreateRemediation stores a pending remediation with its "proposed" event
+// and returns its ID
+func (db *DB) CreateRemediation(r Remediation, actor string) (int64, error) {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	id, err := tx.dialect.insertID(tx, `
+		INSERT INTO remediations (
+			analysis_id, recommendation, created_at, expires_at, namespace, action,
+			target, command, status, decided_by, decided_at, result
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, '', ?, '')`,
+		r.AnalysisID, r.Recommendation, r.CreatedAt, r.ExpiresAt, r.Namespace, r.Action,
+		r.Target, r.Command, RemediationPending, time.Time{},
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert remediation: %w", err)
+	}
+	if err := insertRemediationEvent(tx, id, r.CreatedAt, RemediationProposed, actor, r.Command); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit remediation: %w", err)
+	}
+	return id, nil
+}
+
+// HasPendingRemediation reports whether the same command already awaits
+// approval in the namespace
+func (db *DB) HasPendingRemediation(namespace, command string) (bool, error) {
+	var n int
+	err := db.conn.QueryRow(`
+		SELECT COUNT(*) FROM remediations
+		WHERE namespace = ? AND command = ? AND status = ? AND expires_at > ?`,
+		namespace, command, RemediationPending, time.Now(),
+	).Scan(&n)
+	if err != nil {
+		return false, fmt.Errorf("failed to count pending remediations: %w", err)
+	}
+	return n > 0, nil
+}
+
+// GetRemediation returns a remediation by ID, or nil if it does not exist
+func (db *DB) GetRemediation(id int64) (*Remediation, error) {
+	row := db.conn.QueryRow(`SELECT `+remediationColumns+` FROM remediations WHERE id = ?`, id)
+	r, err := scanRemediation(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get remediation: %w", err)
+	}
+	return r, nil
+}
+
+// RemediationFilter selects remediations; zero fields match all
+type RemediationFilter struct {
+	Status     string
+	Namespace  string
+	AnalysisID int64
+	Limit      int
+}
+
+// ListRemediations returns the most recent remediations matching the
+// filter
+func (db *DB) ListRemediations(filter RemediationFilter) ([]Remediation, error) {
+	rows, err := db.conn.Query(`
+		SELECT `+remediationColumns+` FROM remediations
+		WHERE (? = '' OR status = ?) AND (? = '' OR namespace = ?) AND (? = 0 OR analysis_id = ?)
+		ORDER BY id DESC
+		LIMIT ?`,
+		filter.Status, filter.Status, filter.Namespace, filter.Namespace,
+		filter.AnalysisID, filter.AnalysisID, filter.Limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query remediations: %w", err)
+	}
+	defer rows.Close()
+
+	var out []Remediation
+	for rows.Next() {
+		r, err := scanRemediation(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		out = append(out, *r)
+	}
+	return out, rows.Err()
+}
+
+// TransitionRemediation moves a remediation from one status to another and
+// records the event. It reports false, changing nothing, when the
+// remediation is no longer in the from status, so that concurrent
+// approvals cannot both succeed. Leaving pending records the decision;
+// reaching executed or failed records the result.
+func (db *DB) TransitionRemediation(id int64, from, to, actor, message string) (bool, error) {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	var res sql.Result
+	if from == RemediationPending {
+		res, err = tx.Exec(`
+			UPDATE remediations SET status = ?, decided_by = ?, decided_at = ?, result = ?
+			WHERE id = ? AND status = ?`,
+			to, actor, now, message, id, from)
+	} else {
+		res, err = tx.Exec(`UPDATE remediations SET status = ?, result = ? WHERE id = ? AND status = ?`,
+			to, message, id, from)
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to update remediation: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return false, nil
+	}
+	if err := insertRemediationEvent(tx, id, now, to, actor, message); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit remediation: %w", err)
+	}
+	return true, nil
+}
+
+// ListRemediationEvents returns the audit trail of a remediation, oldest
+// first
+func (db *DB) ListRemediationEvents(id int64) ([]RemediationEvent, error) {
+	rows, err := db.conn.Query(`
+		SELECT id, remediation_id, created_at, event, actor, message
+		FROM remediation_events
+		WHERE remediation_id = ?
+		ORDER BY id`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query remediation events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []RemediationEvent
+	for rows.Next() {
+		var e RemediationEvent
+		if err := rows.Scan(&e.ID, &e.RemediationID, &e.CreatedAt, &e.Event, &e.Actor, &e.Message); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+func insertRemediationEvent(tx *sqlTx, id int64, at time.Time, event, actor, message string) error {
+	_, err := tx.Exec(`
+		INSERT INTO remediation_events (remediation_id, created_at, event, actor, message)
+		VALUES (?, ?, ?, ?, ?)`, id, at, event, actor, message)
+	if err != nil {
+		return fmt.Errorf("failed to insert remediation event: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// PruneAnalyses deletes the analyses created before before (unless it is
+// zero) and those older than the newest maxRows not deleted (unless it is
+// 0), with the records that refer to them, and returns the number deleted. Analyses
+// created at the same time as the oldest one kept are kept too. The daily
+// stats of pruned days are kept.
+func (db *DB) PruneAnalyses(before time.Time, maxRows int) (int, error) {
+	cutoff := before
+	if maxRows > 0 {
+		var oldestKept time.Time
+		err := db.conn.QueryRow(
+			"SELECT created_at FROM analyses WHERE deleted_at IS NULL ORDER BY created_at DESC LIMIT 1 OFFSET ?",
+			maxRows-1,
+		).Scan(&oldestKept)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return 0, fmt.Errorf("failed to find the oldest analysis kept: %w", err)
+		}
+		if oldestKept.After(cutoff) {
+			cutoff = oldestKept
+		}
+	}
+	if cutoff.IsZero() {
+		return 0, nil
+	}
+
+	return db.deleteAnalyses(" WHERE created_at < ?", []interface{}{cutoff.Local()})
+}
+
+// Vacuum returns the space freed by deleted rows to the file system and
+// refreshes the query planner statistics of a SQLite database. It rewrites
+// the whole file, blocking writes meanwhile. PostgreSQL and MySQL reclaim
+// space on their own, so it does nothing there.
+func (db *DB) Vacuum() error {
+	for _, statement := range db.conn.dialect.vacuum {
+		if _, err := db.conn.Exec(statement); err != nil {
+			return fmt.Errorf("failed to vacuum database: %w", err)
+		}
+	}
+	return nil
+}
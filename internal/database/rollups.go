@@ -0,0 +1,196 @@
+package database
+
+import (
+	"fmt"
+	"time"
+)
+
+// dayFormat is the layout of daily_stats days (UTC)
+const dayFormat = "2006-01-02"
+
+// DailyStat is the number of completed analyses created on one day (UTC)
+// for a namespace, severity, category and LLM model, with the tokens and
+// estimated USD cost the LLM spent on them and on their re-analyses that
+// day. The category is the alert name.
+type DailyStat struct {
+	Day              string
+	Namespace        string
+	Severity         string
+	Category         string
+	Model            string
+	Count            int
+	PromptTokens     int64
+	CompletionTokens int64
+	Cost             float64
+}
+
+// RollupDailyStats recomputes the daily aggregates for every day from since
+// (truncated to the UTC day) until today, and returns the number of
+// aggregate rows written. An empty summary table is backfilled from the
+// first analysis instead.
+func (db *DB) RollupDailyStats(since time.Time) (int, error) {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var existing int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM daily_stats").Scan(&existing); err != nil {
+		return 0, fmt.Errorf("failed to count daily stats: %w", err)
+	}
+
+	day := since.UTC().Format(dayFormat)
+	if existing == 0 {
+		day = "0000-00-00"
+	}
+
+	if _, err := tx.Exec("DELETE FROM daily_stats WHERE day >= ?", day); err != nil {
+		return 0, fmt.Errorf("failed to clear daily stats: %w", err)
+	}
+
+	// created_at is compared as stored first so the index narrows the scan,
+	// with a day of slack for timezone offsets; the UTC day then picks the
+	// exact days. Re-analyses add their spend to the day they ran on.
+	utcDay := db.conn.dialect.utcDay
+	scanStart := rollupScanStart(day)
+	res, err := tx.Exec(`
+		INSERT INTO daily_stats (
+			day, namespace, severity, category, model, count,
+			prompt_tokens, completion_tokens, cost
+		)
+		SELECT day, namespace, severity, category, model, SUM(n),
+		       SUM(prompt_tokens), SUM(completion_tokens), SUM(cost)
+		FROM (
+			SELECT `+utcDay+` AS day, namespace, severity, alert_name AS category, model,
+			       1 AS n, prompt_tokens, completion_tokens, cost
+			FROM analyses
+			WHERE created_at >= ? AND `+utcDay+` >= ? AND status = ? AND deleted_at IS NULL
+			UNION ALL
+			SELECT `+utcDay+` AS day, namespace, severity, alert_name AS category, model,
+			       0 AS n, prompt_tokens, completion_tokens, cost
+			FROM (
+				SELECT v.created_at, a.namespace, a.severity, a.alert_name, v.model,
+				       v.prompt_tokens, v.completion_tokens, v.cost
+				FROM analysis_versions v
+				JOIN analyses a ON a.id = v.analysis_id
+				WHERE v.created_at >= ? AND a.deleted_at IS NULL
+			) versions
+			WHERE `+utcDay+` >= ?
+		) spend
+		GROUP BY day, namespace, severity, category, model
+	`, scanStart, day, AnalysisCompleted, scanStart, day)
+	if err != nil {
+		return 0, fmt.Errorf("failed to roll up daily stats: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit daily stats: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count rolled up stats: %w", err)
+	}
+	return int(n), nil
+}
+
+// NamespaceTokens is the prompt and completion tokens the LLM spent on the
+// analyses of a namespace
+type NamespaceTokens struct {
+	Namespace        string
+	PromptTokens     int64
+	CompletionTokens int64
+}
+
+// ListTokenSpend returns the tokens spent per namespace on the analyses and
+// re-analyses of the UTC day of since, deleted ones included
+func (db *DB) ListTokenSpend(since time.Time) ([]NamespaceTokens, error) {
+	day := since.UTC().Format(dayFormat)
+	utcDay := db.conn.dialect.utcDay
+	scanStart := rollupScanStart(day)
+	rows, err := db.conn.Query(`
+		SELECT namespace, SUM(prompt_tokens), SUM(completion_tokens)
+		FROM (
+			SELECT namespace, prompt_tokens, completion_tokens
+			FROM analyses
+			WHERE created_at >= ? AND `+utcDay+` = ?
+			UNION ALL
+			SELECT namespace, prompt_tokens, completion_tokens
+			FROM (
+				SELECT v.created_at, a.namespace, v.prompt_tokens, v.completion_tokens
+				FROM analysis_versions v
+				JOIN analyses a ON a.id = v.analysis_id
+				WHERE v.created_at >= ?
+			) versions
+			WHERE `+utcDay+` = ?
+		) spend
+		GROUP BY namespace
+	`, scanStart, day, scanStart, day)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query token spend: %w", err)
+	}
+	defer rows.Close()
+
+	var spend []NamespaceTokens
+	for rows.Next() {
+		var t NamespaceTokens
+		if err := rows.Scan(&t.Namespace, &t.PromptTokens, &t.CompletionTokens); err != nil {
+			return nil, fmt.Errorf("failed to scan token spend: %w", err)
+		}
+		spend = append(spend, t)
+	}
+	return spend, rows.Err()
+}
+
+// rollupScanStart returns the created_at lower bound for rolling up day
+func rollupScanStart(day string) time.Time {
+	t, err := time.Parse(dayFormat, day)
+	if err != nil {
+		return time.Time{}
+	}
+	return t.AddDate(0, 0, -1)
+}
+
+// ListDailyStats returns the daily aggregates from since (truncated to the
+// UTC day) on, oldest first. An empty namespace returns all namespaces.
+func (db *DB) ListDailyStats(since time.Time, namespace string) ([]DailyStat, error) {
+	query := `
+		SELECT day, namespace, severity, category, model, count,
+		       prompt_tokens, completion_tokens, cost
+		FROM daily_stats WHERE day >= ?`
+	args := []interface{}{since.UTC().Format(dayFormat)}
+	if namespace != "" {
+		query += " AND namespace = ?"
+		args = append(args, namespace)
+	}
+	query += " ORDER BY day"
+
+	rows, err := db.conn.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query daily stats: %w", err)
+	}
+	defer rows.Close()
+
+	var stats []DailyStat
+	for rows.Next() {
+		var s DailyStat
+		err := rows.Scan(
+			&s.Day,
+			&s.Namespace,
+			&s.Severity,
+			&s.Category,
+			&s.Model,
+			&s.Count,
+			&s.PromptTokens,
+			&s.CompletionTokens,
+			&s.Cost,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		stats = append(stats, s)
+	}
+
+	return stats, rows.Err()
+}
@@ -0,0 +1,103 @@
+package database
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/emirozbir/micro-sre/internal/models"
+)
+
+// Store keeps analyses and the records around them: versions, feedback,
+// tags, incidents, embeddings, alert deliveries, queued jobs, failure
+// backoff, remediations, audit events and daily stats, and maintains their
+// schema, size and backups. DB implements it on SQLite, PostgreSQL and
+// MySQL; Open picks one from the config.
+type Store interface {
+	Ping(ctx context.Context) error
+	Close() error
+
+	SchemaVersion() (current, latest int, err error)
+	Migrate(version int) error
+
+	SaveAnalysis(result *models.AnalysisResult) (int64, error)
+	StartAnalysis(alert models.AlertSummary, status string) (int64, error)
+	SetAnalysisStatus(id int64, status, message string) (bool, error)
+	CompleteAnalysis(id int64, result *models.AnalysisResult) (int64, error)
+	GetAnalysis(id int64) (*StoredAnalysis, error)
+	ListAnalyses(limit, offset int, sort string) ([]StoredAnalysis, error)
+	CountAnalyses() (int, error)
+	CountFilteredAnalyses(filter AnalysisFilter) (int, error)
+	FindAnalyses(filter AnalysisFilter) ([]StoredAnalysis, error)
+	FindAnalysisIDs(filter AnalysisFilter) ([]int64, error)
+	FindSimilar(id int64, alertName, cluster, namespace string, limit int) ([]StoredAnalysis, error)
+	FindRecurrence(namespace, podName, alertName string, since time.Time) (*models.Recurrence, error)
+	ListClusters() ([]string, error)
+	CountIncidents(namespace, alertName, severity string) (int, error)
+	DeleteAnalysis(id int64) (bool, error)
+	DeleteAnalyses(filter AnalysisFilter) (int, error)
+	RestoreAnalysis(id int64) (bool, error)
+	RestoreAnalyses(filter AnalysisFilter) (int, error)
+
+	SaveAnalysisVersion(analysisID int64, result *models.AnalysisResult) (int, error)
+	ListAnalysisVersions(analysisID int64) ([]AnalysisVersion, error)
+
+	SaveFeedback(f FeedbackRecord) (int64, error)
+	ListFeedback(filter FeedbackFilter) ([]FeedbackRecord, error)
+	CountRatings(alertName string, since time.Time) (up, down int, err error)
+
+	AddAnalysisTags(analysisID int64, tags []string, author string) error
+	RemoveAnalysisTag(analysisID int64, tag string) (bool, error)
+	ListAnalysisTags(analysisIDs ...int64) (map[int64][]string, error)
+	ListTags() ([]TagCount, error)
+
+	SaveEmbedding(e Embedding) error
+	GetEmbedding(analysisID int64) (*Embedding, error)
+	FindSimilarByEmbedding(query Embedding, limit int) ([]SimilarAnalysis, error)
+
+	CreateIncident(incident Incident, analysisIDs []int64) (int64, error)
+	GetIncident(id int64) (*Incident, error)
+	ListIncidents(filter IncidentFilter) ([]Incident, error)
+	UpdateIncident(id int64, title, status string) (bool, error)
+	AddIncidentAnalyses(incidentID int64, analysisIDs []int64, author string) error
+	RemoveIncidentAnalysis(incidentID, analysisID int64) (bool, error)
+	MergeIncidents(targetID int64, sourceIDs []int64) (int, error)
+	DeleteIncident(id int64) (bool, error)
+
+	GetDeliveredAnalysisID(fingerprint string, startsAt time.Time) (int64, error)
+	SaveDelivery(fingerprint string, startsAt time.Time, analysisID int64) error
+
+	SaveJob(job QueuedJob) error
+	ClaimJob(id string, staleBefore time.Time) (int, error)
+	RetryJob(id string, nextRunAt time.Time, lastError string) error
+	DeleteJob(id string) error
+	DueJobs(now, staleBefore time.Time, limit int) ([]QueuedJob, error)
+
+	GetTargetFailure(namespace, target string) (*TargetFailure, error)
+	RecordTargetFailure(namespace, target, message string, backoff func(failures int) time.Duration) (*TargetFailure, error)
+	ClearTargetFailure(namespace, target string) error
+	ListTargetFailures(minFailures int) ([]TargetFailure, error)
+
+	CreateRemediation(r Remediation, actor string) (int64, error)
+	HasPendingRemediation(namespace, command string) (bool, error)
+	GetRemediation(id int64) (*Remediation, error)
+	ListRemediations(filter RemediationFilter) ([]Remediation, error)
+	TransitionRemediation(id int64, from, to, actor, message string) (bool, error)
+	ListRemediationEvents(id int64) ([]RemediationEvent, error)
+
+	SaveAuditEvent(e AuditEvent) (int64, error)
+	ListAuditEvents(filter AuditFilter) ([]AuditEvent, error)
+
+	RollupDailyStats(since time.Time) (int, error)
+	ListDailyStats(since time.Time, namespace string) ([]DailyStat, error)
+	ListTokenSpend(since time.Time) ([]NamespaceTokens, error)
+
+	PruneAnalyses(before time.Time, maxRows int) (int, error)
+	PurgeDeletedAnalyses(before time.Time) (int, error)
+	Vacuum() error
+
+	Export(ctx context.Context, w io.Writer, format string) error
+	Import(ctx context.Context, r io.Reader) (int, error)
+}
+
+var _ Store = (*DB)(nil)
@@ -0,0 +1,124 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TagCount is a tag with the number of analyses carrying it
+type TagCount struct {
+	Tag   string
+	Count int
+}
+
+// AddAnalysisTags attaches tags, which must be distinct, to an analysis in
+// one statement. Tags it already carries keep their original author and
+// time.
+func (db *DB) AddAnalysisTags(analysisID int64, tags []string, author string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	args := make([]any, 0, 4*len(tags))
+	for _, tag := range tags {
+		args = append(args, analysisID, tag, now, author)
+	}
+	query := "INSERT INTO analysis_tags (analysis_id, tag, created_at, author) VALUES " + valueRows(len(tags), 4) +
+		db.conn.dialect.upsert([]string{"analysis_id", "tag"}, "", "tag")
+	if _, err := db.conn.Exec(query, args...); err != nil {
+		return fmt.Errorf("failed to tag analysis: %w", err)
+	}
+	return nil
+}
+
+// RemoveAnalysisTag detaches a tag from an analysis and reports whether the
+// analysis carried it
+func (db *DB) RemoveAnalysisTag(analysisID int64, tag string) (bool, error) {
+	res, err := db.conn.Exec("DELETE FROM analysis_tags WHERE analysis_id = ? AND tag = ?", analysisID, tag)
+	if err != nil {
+		return false, fmt.Errorf("failed to remove tag: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to count removed tags: %w", err)
+	}
+	return n > 0, nil
+}
+
+// ListAnalysisTags returns the tags of the given analyses, sorted, by
+// analysis ID
+func (db *DB) ListAnalysisTags(analysisIDs ...int64) (map[int64][]string, error) {
+	tags := make(map[int64][]string)
+	if len(analysisIDs) == 0 {
+		return tags, nil
+	}
+
+	args := make([]interface{}, len(analysisIDs))
+	for i, id := range analysisIDs {
+		args[i] = id
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(args)), ", ")
+	rows, err := db.conn.Query(
+		"SELECT analysis_id, tag FROM analysis_tags WHERE analysis_id IN ("+placeholders+") ORDER BY tag",
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tags: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		var tag string
+		if err := rows.Scan(&id, &tag); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		tags[id] = append(tags[id], tag)
+	}
+	return tags, rows.Err()
+}
+
+// ListTags returns the tags of the analyses that were not deleted, most
+// used first
+func (db *DB) ListTags() ([]TagCount, error) {
+	rows, err := db.conn.Query(`
+		SELECT t.tag, COUNT(*) AS n
+		FROM analysis_tags t
+		JOIN analyses a ON a.id = t.analysis_id
+		WHERE a.deleted_at IS NULL
+		GROUP BY t.tag
+		ORDER BY n DESC, t.tag ASC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tags: %w", err)
+	}
+	defer rows.Close()
+
+	var tags []TagCount
+	for rows.Next() {
+		var t TagCount
+		if err := rows.Scan(&t.Tag, &t.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		tags = append(tags, t)
+	}
+	return tags, rows.Err()
+}
+
+// withTags fills in the tags of stored analyses
+func (db *DB) withTags(analyses []StoredAnalysis) error {
+	ids := make([]int64, len(analyses))
+	for i := range analyses {
+		ids[i] = analyses[i].ID
+	}
+	tags, err := db.ListAnalysisTags(ids...)
+	if err != nil {
+		return err
+	}
+	for i := range analyses {
+		analyses[i].Tags = tags[analyses[i].ID]
+	}
+	return nil
+}
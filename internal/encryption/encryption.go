@@ -0,0 +1,129 @@
+// Package encryption seals stored data that may hold log excerpts, such as
+// analysis results and collected artifacts, with AES-256-GCM. Sealed values
+// are text, marked with a prefix and the ID of their key, so that values
+// stored before encryption was enabled are still read as they are.
+package encryption
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/emirozbir/micro-sre/internal/config"
+)
+
+// prefix marks sealed values; the key ID and a colon follow it
+const prefix = "hepsre:enc:v1:"
+
+// keySize is the size of an AES-256 key
+const keySize = 32
+
+// ErrNoKey is returned when opening a sealed value without a key
+var ErrNoKey = errors.New("value is encrypted: set encryption.key, encryption.key_file or HEPSRE_ENCRYPTION_KEY")
+
+// Cipher seals and opens values with one key. A nil Cipher, when no key is
+// configured, stores values in the clear.
+type Cipher struct {
+	aead  cipher.AEAD
+	keyID string
+}
+
+// New returns the cipher of the configured key, read from encryption.key or
+// the file at encryption.key_file, or nil if there is none. The key is 32
+// random bytes, base64 encoded, as output by "openssl rand -base64 32".
+func New(cfg config.EncryptionConfig) (*Cipher, error) {
+	encoded := cfg.Key
+	if cfg.KeyFile != "" {
+		data, err := os.ReadFile(cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read encryption key: %w", err)
+		}
+		encoded = string(data)
+	}
+	encoded = strings.TrimSpace(encoded)
+	if encoded == "" {
+		return nil, nil
+	}
+
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(key) != keySize {
+		return nil, fmt.Errorf("invalid encryption key: want %d random bytes, base64 encoded", keySize)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption key: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	// The key ID tells a wrong key from corrupted data without revealing
+	// the key
+	sum := sha256.Sum256(key)
+	return &Cipher{aead: aead, keyID: hex.EncodeToString(sum[:4])}, nil
+}
+
+// Seal encrypts plaintext under a random nonce. It returns plaintext as is
+// when c is nil.
+func (c *Cipher) Seal(plaintext []byte) []byte {
+	if c == nil {
+		return plaintext
+	}
+
+	nonce := make([]byte, c.aead.NonceSize(), c.aead.NonceSize()+len(plaintext)+c.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		// crypto/rand does not fail on supported platforms
+		panic(fmt.Sprintf("failed to generate nonce: %v", err))
+	}
+	sealed := c.aead.Seal(nonce, nonce, plaintext, nil)
+
+	out := make([]byte, 0, len(prefix)+len(c.keyID)+1+base64.StdEncoding.EncodedLen(len(sealed)))
+	out = append(out, prefix...)
+	out = append(out, c.keyID...)
+	out = append(out, ':')
+	return base64.StdEncoding.AppendEncode(out, sealed)
+}
+
+// Open decrypts a value sealed by Seal. Values without the prefix of sealed
+// ones, stored before encryption was enabled, are returned as they are.
+func (c *Cipher) Open(data []byte) ([]byte, error) {
+	if !IsSealed(data) {
+		return data, nil
+	}
+	if c == nil {
+		return nil, ErrNoKey
+	}
+
+	keyID, encoded, ok := bytes.Cut(data[len(prefix):], []byte{':'})
+	if !ok {
+		return nil, fmt.Errorf("malformed encrypted value")
+	}
+	if string(keyID) != c.keyID {
+		return nil, fmt.Errorf("value is encrypted with key %s, not the configured key %s", keyID, c.keyID)
+	}
+	sealed, err := base64.StdEncoding.AppendDecode(nil, encoded)
+	if err != nil || len(sealed) < c.aead.NonceSize() {
+		return nil, fmt.Errorf("malformed encrypted value")
+	}
+
+	nonce, ciphertext := sealed[:c.aead.NonceSize()], sealed[c.aead.NonceSize():]
+	plaintext, err := c.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt value: %w", err)
+	}
+	return plaintext, nil
+}
+
+// IsSealed reports whether data is a value sealed by a Cipher
+func IsSealed(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(prefix))
+}
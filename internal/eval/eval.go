@@ -0,0 +1,230 @@
+// Package eval replays recorded incidents against the agent and scores the
+// analyses against their known root causes, to compare prompts and models.
+package eval
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/emirozbir/micro-sre/internal/agent"
+	"github.com/emirozbir/micro-sre/internal/collectors"
+	"github.com/emirozbir/micro-sre/internal/config"
+)
+
+// matchThreshold is the share of the expected root cause's significant
+// words an analysis must mention to be correct, for fixtures without
+// keywords
+const matchThreshold = 0.5
+
+// Target is an LLM provider and model to evaluate
+type Target struct {
+	Provider string `json:"provider"`
+	Model    string `json:"model"`
+}
+
+func (t Target) String() string {
+	return t.Provider + "/" + t.Model
+}
+
+// ParseTargets parses a comma-separated list of provider/model pairs, such
+// as "anthropic/claude-sonnet-4-20250514,openai/gpt-4o"
+func ParseTargets(list string) ([]Target, error) {
+	var targets []Target
+	for _, s := range strings.Split(list, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		provider, model, ok := strings.Cut(s, "/")
+		if !ok || provider == "" || model == "" {
+			return nil, fmt.Errorf("invalid model %q: use provider/model", s)
+		}
+		targets = append(targets, Target{Provider: provider, Model: model})
+	}
+	return targets, nil
+}
+
+// ConfigFor returns a copy of cfg that analyzes with the target. The API
+// key of a provider other than the configured one is read from
+// ANTHROPIC_API_KEY or OPENAI_API_KEY.
+func ConfigFor(cfg *config.Config, t Target) *config.Config {
+	c := *cfg
+	if t.Provider != cfg.LLM.Provider {
+		c.LLM.APIKey = os.Getenv(strings.ToUpper(t.Provider) + "_API_KEY")
+	}
+	c.LLM.Provider = t.Provider
+	c.LLM.Model = t.Model
+	return &c
+}
+
+// CaseResult is the outcome of one fixture
+type CaseResult struct {
+	Fixture   string `json:"fixture"`
+	Expected  string `json:"expected"`
+	RootCause string `json:"root_cause,omitempty"`
+	// Score is the share of keywords (or significant words) matched
+	Score   float64 `json:"score"`
+	Correct bool    `json:"correct"`
+	// LLM is set when the LLM answered; ValidJSON only counts then
+	LLM             bool     `json:"llm"`
+	ValidJSON       bool     `json:"valid_json"`
+	Analyzers       []string `json:"analyzers,omitempty"`
+	Confidence      string   `json:"confidence,omitempty"`
+	ConfidenceScore int      `json:"confidence_score"`
+	Duration        string   `json:"duration"`
+	Error           string   `json:"error,omitempty"`
+}
+
+// Report scores one target over all fixtures
+type Report struct {
+	Target
+	PromptVersion string       `json:"prompt_version"`
+	Cases         []CaseResult `json:"cases"`
+	Total         int          `json:"total"`
+	Correct       int          `json:"correct"`
+	Errors        int          `json:"errors"`
+	// Accuracy is Correct over Total; failed analyses count as wrong
+	Accuracy float64 `json:"accuracy"`
+	// LLMResponses counts the analyses the LLM answered, and ValidJSON
+	// those whose response was valid JSON
+	LLMResponses int     `json:"llm_responses"`
+	ValidJSON    int     `json:"valid_json"`
+	JSONValidity float64 `json:"json_validity"`
+}
+
+// Run analyzes every fixture with a, one at a time, and scores the results
+func Run(ctx context.Context, a *agent.Agent, target Target, fixtures []*Fixture) *Report {
+	_, promptVersion := a.Generator()
+	report := &Report{Target: target, PromptVersion: promptVersion}
+
+	for _, f := range fixtures {
+		c := runCase(ctx, a, f)
+		report.Cases = append(report.Cases, c)
+		report.Total++
+		if c.Error != "" {
+			report.Errors++
+		}
+		if c.Correct {
+			report.Correct++
+		}
+		if c.LLM {
+			report.LLMResponses++
+			if c.ValidJSON {
+				report.ValidJSON++
+			}
+		}
+	}
+	if report.Total > 0 {
+		report.Accuracy = float64(report.Correct) / float64(report.Total)
+	}
+	if report.LLMResponses > 0 {
+		report.JSONValidity = float64(report.ValidJSON) / float64(report.LLMResponses)
+	}
+	return report
+}
+
+func runCase(ctx context.Context, a *agent.Agent, f *Fixture) CaseResult {
+	c := CaseResult{Fixture: f.Name, Expected: f.Expected.RootCause}
+	if c.Expected == "" {
+		c.Expected = strings.Join(f.Expected.Keywords, ", ")
+	}
+
+	namespace := f.Pod.Namespace
+	if namespace == "" {
+		namespace = "default"
+	}
+	req := agent.AnalysisRequest{
+		Namespace: namespace,
+		PodName:   f.Pod.Name,
+		Lookback:  defaultLookback,
+		Alert:     f.Alert,
+	}
+	info := &collectors.PodInfo{Pod: f.Pod, Logs: f.Logs, Events: f.Events}
+
+	started := time.Now()
+	result, err := a.AnalyzeRecorded(ctx, req, info)
+	c.Duration = time.Since(started).Round(time.Millisecond).String()
+	if err != nil {
+		c.Error = err.Error()
+		return c
+	}
+
+	c.RootCause = result.Analysis.RootCause
+	c.Confidence = result.Analysis.Confidence
+	c.ConfidenceScore = result.Analysis.ConfidenceScore
+	c.Analyzers = result.Analyzers
+	for _, name := range result.Analyzers {
+		if name == agent.AnalyzerLLM {
+			c.LLM = true
+		}
+	}
+	c.ValidJSON = c.LLM && c.RootCause != agent.UnparsedRootCause
+	c.Score, c.Correct = Score(f.Expected, c.RootCause)
+	return c
+}
+
+// Score matches a root cause against the expected one. Keywords match
+// case-insensitively anywhere in the root cause.
+func Score(expected Expected, rootCause string) (float64, bool) {
+	actual := strings.ToLower(rootCause)
+
+	if len(expected.Keywords) > 0 {
+		matched := 0
+		for _, k := range expected.Keywords {
+			for _, alt := range strings.Split(k, "|") {
+				if alt = strings.ToLower(strings.TrimSpace(alt)); alt != "" && strings.Contains(actual, alt) {
+					matched++
+					break
+				}
+			}
+		}
+		score := float64(matched) / float64(len(expected.Keywords))
+		return score, matched == len(expected.Keywords)
+	}
+
+	words := significantWords(expected.RootCause)
+	if len(words) == 0 {
+		return 0, false
+	}
+	found := map[string]bool{}
+	for _, w := range significantWords(rootCause) {
+		found[w] = true
+	}
+	matched := 0
+	for _, w := range words {
+		if found[w] {
+			matched++
+		}
+	}
+	score := float64(matched) / float64(len(words))
+	return score, score >= matchThreshold
+}
+
+var wordPattern = regexp.MustCompile(`[a-z0-9_.-]+`)
+
+// stopWords are common words that say nothing about a root cause
+var stopWords = map[string]bool{
+	"the": true, "and": true, "for": true, "with": true, "was": true, "were": true,
+	"that": true, "this": true, "from": true, "into": true, "because": true, "due": true,
+	"pod": true, "container": true, "not": true, "are": true, "has": true, "its": true,
+}
+
+// significantWords returns the distinct words of three or more characters
+// that are not stop words, lowercased
+func significantWords(s string) []string {
+	seen := map[string]bool{}
+	var words []string
+	for _, w := range wordPattern.FindAllString(strings.ToLower(s), -1) {
+		w = strings.Trim(w, ".-")
+		if len(w) < 3 || stopWords[w] || seen[w] {
+			continue
+		}
+		seen[w] = true
+		words = append(words, w)
+	}
+	return words
+}
@@ -0,0 +1,122 @@
+package eval
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/emirozbir/micro-sre/internal/models"
+)
+
+// Fixture file names, inside one directory per incident
+const (
+	FilePod      = "pod.json"
+	FileLogs     = "logs.txt"
+	FileEvents   = "events.json"
+	FileAlert    = "alert.json"
+	FileExpected = "expected.json"
+)
+
+// defaultLookback is the time range fixtures are analyzed with
+const defaultLookback = time.Hour
+
+// Expected is the known answer of a fixture. An analysis is correct if its
+// root cause contains every keyword, where "a|b" accepts either; without
+// keywords, if it shares most significant words with RootCause.
+type Expected struct {
+	RootCause string   `json:"root_cause"`
+	Keywords  []string `json:"keywords,omitempty"`
+}
+
+// Fixture is a recorded incident: the pod as it was, its logs and events,
+// the alert that fired and the root cause an engineer confirmed
+type Fixture struct {
+	Name     string
+	Pod      *corev1.Pod
+	Logs     string
+	Events   []corev1.Event
+	Alert    *models.Alert
+	Expected Expected
+}
+
+// LoadFixtures loads every fixture directory under dir, by name
+func LoadFixtures(dir string) ([]*Fixture, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixtures directory: %w", err)
+	}
+
+	var fixtures []*Fixture
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		f, err := LoadFixture(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		fixtures = append(fixtures, f)
+	}
+	if len(fixtures) == 0 {
+		return nil, fmt.Errorf("no fixtures found in %s", dir)
+	}
+	sort.Slice(fixtures, func(i, j int) bool { return fixtures[i].Name < fixtures[j].Name })
+	return fixtures, nil
+}
+
+// LoadFixture loads one fixture directory. pod.json and expected.json are
+// required; logs, events and the alert are optional.
+func LoadFixture(dir string) (*Fixture, error) {
+	f := &Fixture{Name: filepath.Base(dir), Pod: &corev1.Pod{}}
+
+	if err := readJSON(filepath.Join(dir, FilePod), f.Pod); err != nil {
+		return nil, fmt.Errorf("fixture %s: %w", f.Name, err)
+	}
+	if err := readJSON(filepath.Join(dir, FileExpected), &f.Expected); err != nil {
+		return nil, fmt.Errorf("fixture %s: %w", f.Name, err)
+	}
+	if f.Expected.RootCause == "" && len(f.Expected.Keywords) == 0 {
+		return nil, fmt.Errorf("fixture %s: %s needs a root_cause or keywords", f.Name, FileExpected)
+	}
+
+	logs, err := os.ReadFile(filepath.Join(dir, FileLogs))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("fixture %s: failed to read %s: %w", f.Name, FileLogs, err)
+	}
+	f.Logs = string(logs)
+
+	f.Events = []corev1.Event{}
+	if err := readJSON(filepath.Join(dir, FileEvents), &f.Events); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("fixture %s: %w", f.Name, err)
+	}
+
+	alert := &models.Alert{}
+	if err := readJSON(filepath.Join(dir, FileAlert), alert); err == nil {
+		f.Alert = alert
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("fixture %s: %w", f.Name, err)
+	}
+
+	return f, nil
+}
+
+// readJSON decodes a JSON file; a missing file is returned as is
+func readJSON(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return fmt.Errorf("failed to read %s: %w", filepath.Base(path), err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package formatter
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/emirozbir/micro-sre/internal/models"
+)
+
+// AnalysisMarkdown renders a stored analysis as markdown, for attaching to
+// tickets and postmortems. An id of 0 is an analysis that was not stored.
+func AnalysisMarkdown(id int64, result *models.AnalysisResult) string {
+	var sb strings.Builder
+	alert := result.Alert
+
+	if id != 0 {
+		sb.WriteString(fmt.Sprintf("# Analysis #%d: %s\n\n", id, valueOrDefault(alert.Name, "Alert")))
+	} else {
+		sb.WriteString(fmt.Sprintf("# Analysis: %s\n\n", valueOrDefault(alert.Name, "Alert")))
+	}
+	sb.WriteString("| | |\n|---|---|\n")
+	if alert.Severity != "" {
+		sb.WriteString(fmt.Sprintf("| Severity | %s |\n", markdownCell(alert.Severity)))
+	}
+	if alert.Cluster != "" {
+		sb.WriteString(fmt.Sprintf("| Cluster | %s |\n", markdownCell(alert.Cluster)))
+	}
+	sb.WriteString(fmt.Sprintf("| Namespace | %s |\n", markdownCell(alert.Namespace)))
+	if alert.Workload != "" {
+		sb.WriteString(fmt.Sprintf("| Workload | %s |\n", markdownCell(alert.Workload)))
+	} else {
+		sb.WriteString(fmt.Sprintf("| Pod | %s |\n", markdownCell(alert.Pod)))
+	}
+	sb.WriteString(fmt.Sprintf("| Started | %s |\n", alert.StartedAt.Format(time.RFC3339)))
+	if result.Model != "" {
+		sb.WriteString(fmt.Sprintf("| Model | %s (prompt v%s) |\n", markdownCell(result.Model), result.PromptVersion))
+	}
+	sb.WriteString("\n")
+
+	analysis := result.Analysis
+	sb.WriteString("## Root Cause\n\n")
+	sb.WriteString(strings.TrimSpace(analysis.RootCause) + "\n\n")
+	sb.WriteString(fmt.Sprintf("**Confidence:** %s (%d/100)\n\n", valueOrDefault(analysis.Confidence, "unknown"), analysis.ConfidenceScore))
+	if len(analysis.Hypotheses) > 1 {
+		sb.WriteString("**Ranked hypotheses:**\n\n")
+		for i, h := range analysis.Hypotheses {
+			sb.WriteString(fmt.Sprintf("%d. %d%% %s\n", i+1, h.Percent(), h.RootCause))
+		}
+		sb.WriteString("\n")
+	}
+	if reasoning := strings.TrimSpace(analysis.Reasoning); reasoning != "" {
+		sb.WriteString("### Reasoning\n\n" + reasoning + "\n\n")
+	}
+
+	if len(analysis.Timeline) > 0 {
+		sb.WriteString("## Timeline\n\n")
+		for _, event := range analysis.Timeline {
+			line := fmt.Sprintf("- **%s** %s", event.Timestamp.Format("15:04:05"), event.Event)
+			if event.Details != "" {
+				line += ": " + event.Details
+			}
+			sb.WriteString(line + "\n")
+		}
+		sb.WriteString("\n")
+	}
+
+	evidence := analysis.Evidence
+	if len(evidence.Logs) > 0 || len(evidence.Events) > 0 {
+		sb.WriteString("## Evidence\n\n")
+		for i, log := range evidence.Logs {
+			sb.WriteString(fmt.Sprintf("- [L%d] %s `%s`\n", i+1, log.Timestamp.Format("15:04:05"), strings.ReplaceAll(strings.TrimSpace(log.Line), "`", "'")))
+		}
+		for i, event := range evidence.Events {
+			sb.WriteString(fmt.Sprintf("- [E%d] %s %s %s: %s\n", i+1, event.Timestamp.Format("15:04:05"), event.Type, event.Reason, event.Message))
+		}
+		sb.WriteString("\n")
+	}
+
+	if len(analysis.Recommendations) > 0 {
+		sb.WriteString("## Recommendations\n\n")
+		for _, rec := range analysis.Recommendations {
+			sb.WriteString(fmt.Sprintf("- [ ] **%s** %s\n", strings.ToUpper(valueOrDefault(rec.Priority, "medium")), rec.Action))
+			if rec.Details != "" {
+				sb.WriteString("  " + rec.Details + "\n")
+			}
+			if rec.Command != "" {
+				sb.WriteString(fmt.Sprintf("  `%s`\n", rec.Command))
+			}
+		}
+		sb.WriteString("\n")
+	}
+
+	data := result.CollectedData
+	sb.WriteString(fmt.Sprintf("_Collected %d log lines and %d events over %s._\n", data.LogLines, data.EventsCount, valueOrDefault(data.TimeRange, "the lookback")))
+	return sb.String()
+}
+
+// markdownCell escapes the pipes that would split a table cell
+func markdownCell(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
+
+// analysisCSVHeader names the columns written by AnalysisCSV
+var analysisCSVHeader = []string{
+	"id", "alert", "severity", "cluster", "namespace", "target", "started_at",
+	"root_cause", "confidence", "confidence_score", "model", "prompt_version",
+	"recommendations", "log_lines", "events",
+}
+
+// AnalysisCSV writes a stored analysis as a CSV header and one row, for
+// spreadsheets and ticket imports. Recommendations are joined by newlines.
+func AnalysisCSV(w io.Writer, id int64, result *models.AnalysisResult) error {
+	recommendations := make([]string, 0, len(result.Analysis.Recommendations))
+	for _, rec := range result.Analysis.Recommendations {
+		recommendations = append(recommendations, fmt.Sprintf("[%s] %s", valueOrDefault(rec.Priority, "medium"), rec.Action))
+	}
+
+	cw := csv.NewWriter(w)
+	cw.Write(analysisCSVHeader)
+	cw.Write([]string{
+		strconv.FormatInt(id, 10),
+		result.Alert.Name,
+		result.Alert.Severity,
+		result.Alert.Cluster,
+		result.Alert.Namespace,
+		result.Alert.Target(),
+		result.Alert.StartedAt.Format(time.RFC3339),
+		result.Analysis.RootCause,
+		result.Analysis.Confidence,
+		strconv.Itoa(result.Analysis.ConfidenceScore),
+		result.Model,
+		result.PromptVersion,
+		strings.Join(recommendations, "\n"),
+		strconv.Itoa(result.CollectedData.LogLines),
+		strconv.Itoa(result.CollectedData.EventsCount),
+	})
+	cw.Flush()
+	return cw.Error()
+}
+
+// AnalysisPDF renders a stored analysis as a PDF document, laid out from
+// its markdown
+func AnalysisPDF(id int64, result *models.AnalysisResult) []byte {
+	doc := newPDFDocument()
+	for _, line := range strings.Split(AnalysisMarkdown(id, result), "\n") {
+		switch {
+		case strings.HasPrefix(line, "# "):
+			doc.heading(plainMarkdown(line[2:]), 16)
+		case strings.HasPrefix(line, "## "):
+			doc.heading(plainMarkdown(line[3:]), 13)
+		case strings.HasPrefix(line, "### "):
+			doc.heading(plainMarkdown(line[4:]), 11)
+		case line == "| | |" || line == "|---|---|":
+		case strings.HasPrefix(line, "| "):
+			cells := strings.SplitN(strings.Trim(line, "| "), " | ", 2)
+			doc.text(plainMarkdown(strings.Join(cells, ": ")))
+		case strings.HasPrefix(line, "- [ ] "):
+			doc.text("- " + plainMarkdown(line[6:]))
+		default:
+			doc.text(plainMarkdown(line))
+		}
+	}
+	return doc.bytes()
+}
+
+// plainMarkdown strips the inline markup AnalysisMarkdown uses
+func plainMarkdown(s string) string {
+	return strings.NewReplacer("**", "", "`", "", `\|`, "|").Replace(strings.Trim(s, "_"))
+}
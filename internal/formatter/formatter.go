@@ -2,6 +2,7 @@ package formatter
 
 import (
 	"fmt"
+	"regexp"
 	"strings"
 	"time"
 
@@ -38,8 +39,58 @@ func (f *Formatter) FormatAnalysisResult(result *models.AnalysisResult) string {
 	// Alert Summary
 	f.writeAlertSummary(&sb, result.Alert)
 
+	// Workload status
+	if result.Workload != nil {
+		f.writeWorkload(&sb, result.Workload)
+	}
+
+	// Silences
+	if len(result.Silences) > 0 {
+		f.writeSilences(&sb, result.Silences)
+	}
+
+	// Related alerts
+	if len(result.RelatedAlerts) > 0 {
+		f.writeRelatedAlerts(&sb, result.RelatedAlerts)
+	}
+
+	// Alert expression values
+	if result.Metrics != nil && len(result.Metrics.Series) > 0 {
+		f.writeMetrics(&sb, result.Metrics)
+	}
+
+	// OOM correlation
+	if result.OOM != nil {
+		f.writeOOMContext(&sb, result.OOM)
+	}
+
+	// Admission failures
+	if result.Admission != nil {
+		f.writeAdmission(&sb, result.Admission)
+	}
+
+	// Connectivity probes
+	if result.Probes != nil {
+		f.writeProbes(&sb, result.Probes)
+	}
+
+	// Rollout
+	if result.Rollout != nil {
+		f.writeRollout(&sb, result.Rollout)
+	}
+
 	// Root Cause
 	f.writeRootCause(&sb, result.Analysis)
+	if result.Rule != nil {
+		f.writeRuleMatch(&sb, result.Rule)
+	}
+	if result.BudgetExceeded {
+		sb.WriteString(Warning("  LLM token budget spent for today; answered by heuristic triage"))
+		sb.WriteString("\n\n")
+	}
+	if len(result.Escalation) > 0 {
+		f.writeEscalation(&sb, result.Escalation)
+	}
 
 	// Timeline
 	if len(result.Analysis.Timeline) > 0 {
@@ -55,14 +106,31 @@ func (f *Formatter) FormatAnalysisResult(result *models.AnalysisResult) string {
 	}
 
 	// Collection Stats
-	f.writeCollectionStats(&sb, result.CollectedData)
+	f.writeCollectionStats(&sb, result.CollectedData, result.Stages, result.Analyzers)
+
+	// Data source manifest
+	if len(result.Manifest) > 0 || len(result.Redactions) > 0 {
+		f.writeManifest(&sb, result.Manifest, result.Redactions)
+	}
 
 	// Footer
 	sb.WriteString("\n")
 	sb.WriteString(Colorize(Cyan, divider))
 	sb.WriteString("\n")
 
-	return sb.String()
+	return f.render(sb.String())
+}
+
+// ansiEscape matches the color codes the helpers of colors.go write
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+// render returns a report as written, or without its color codes unless
+// the formatter uses colors
+func (f *Formatter) render(report string) string {
+	if f.useColors {
+		return report
+	}
+	return ansiEscape.ReplaceAllString(report, "")
 }
 
 func (f *Formatter) writeAlertSummary(sb *strings.Builder, alert models.AlertSummary) {
@@ -78,20 +146,248 @@ func (f *Formatter) writeAlertSummary(sb *strings.Builder, alert models.AlertSum
 		sb.WriteString(fmt.Sprintf("  Severity:    %s\n", SeverityBadge(alert.Severity)))
 	}
 	sb.WriteString(fmt.Sprintf("  Namespace:   %s\n", Info(alert.Namespace)))
-	sb.WriteString(fmt.Sprintf("  Pod:         %s\n", Info(alert.Pod)))
+	if alert.Workload != "" {
+		sb.WriteString(fmt.Sprintf("  Workload:    %s\n", Info(alert.Workload)))
+	} else {
+		sb.WriteString(fmt.Sprintf("  Pod:         %s\n", Info(alert.Pod)))
+	}
 	sb.WriteString(fmt.Sprintf("  Started At:  %s\n", Muted(alert.StartedAt.Format(time.RFC3339))))
 	sb.WriteString("\n")
 }
 
+func (f *Formatter) writeSilences(sb *strings.Builder, silences []models.Silence) {
+	sb.WriteString(SectionHeader("🔕 SILENCES"))
+	sb.WriteString("\n")
+	sb.WriteString(Colorize(Gray, sectionBreak))
+	sb.WriteString("\n")
+
+	for _, s := range silences {
+		if s.IsActive() {
+			sb.WriteString(fmt.Sprintf("  %s by %s until %s\n",
+				BoldColorize(Yellow, "Silenced"),
+				BoldColorize(White, s.CreatedBy),
+				Muted(s.EndsAt.Format(time.RFC3339)),
+			))
+		} else {
+			sb.WriteString(fmt.Sprintf("  %s by %s (expired %s)\n",
+				Colorize(Gray, "Was silenced"),
+				BoldColorize(White, s.CreatedBy),
+				Muted(s.EndsAt.Format(time.RFC3339)),
+			))
+		}
+		if s.Comment != "" {
+			sb.WriteString(fmt.Sprintf("     %s\n", Muted(s.Comment)))
+		}
+	}
+	sb.WriteString("\n")
+}
+
+func (f *Formatter) writeRelatedAlerts(sb *strings.Builder, related []models.RelatedAlert) {
+	sb.WriteString(SectionHeader(fmt.Sprintf("🔔 RELATED FIRING ALERTS (%d)", len(related))))
+	sb.WriteString("\n")
+	sb.WriteString(Colorize(Gray, sectionBreak))
+	sb.WriteString("\n")
+
+	for i, r := range related {
+		if i >= 10 {
+			sb.WriteString(fmt.Sprintf("  %s\n", Muted(fmt.Sprintf("... and %d more", len(related)-10))))
+			break
+		}
+		target := r.Namespace
+		if r.Pod != "" {
+			target += "/" + r.Pod
+		}
+		sb.WriteString(fmt.Sprintf("  %s %s %s\n",
+			SeverityBadge(r.Severity),
+			BoldColorize(White, r.AlertName),
+			Muted(target),
+		))
+	}
+	sb.WriteString("\n")
+}
+
+func (f *Formatter) writeMetrics(sb *strings.Builder, m *models.AlertMetrics) {
+	sb.WriteString(SectionHeader("📈 ALERT EXPRESSION"))
+	sb.WriteString("\n")
+	sb.WriteString(Colorize(Gray, sectionBreak))
+	sb.WriteString("\n")
+
+	sb.WriteString(fmt.Sprintf("  %s\n\n", Colorize(Green, m.Expression)))
+	for _, s := range m.Series {
+		sb.WriteString(fmt.Sprintf("  %s\n", Muted(s.LabelString())))
+		sb.WriteString(fmt.Sprintf("     min %s  max %s  last %s\n",
+			Info(fmt.Sprintf("%g", s.Min())),
+			Info(fmt.Sprintf("%g", s.Max())),
+			BoldColorize(White, fmt.Sprintf("%g", s.Last())),
+		))
+	}
+	sb.WriteString("\n")
+}
+
+func (f *Formatter) writeOOMContext(sb *strings.Builder, oom *models.OOMContext) {
+	sb.WriteString(SectionHeader("💥 MEMORY KILL"))
+	sb.WriteString("\n")
+	sb.WriteString(Colorize(Gray, sectionBreak))
+	sb.WriteString("\n")
+
+	if oom.Evicted {
+		sb.WriteString(fmt.Sprintf("  Evicted:      %s\n", Warning(oom.Message)))
+	} else {
+		sb.WriteString(fmt.Sprintf("  Container:    %s\n", BoldColorize(White, oom.Container)))
+		sb.WriteString(fmt.Sprintf("  Killed At:    %s\n", Muted(oom.KilledAt.Format(time.RFC3339))))
+		if oom.MemoryLimit != "" {
+			sb.WriteString(fmt.Sprintf("  Limit:        %s\n", Info(oom.MemoryLimit)))
+		}
+	}
+	if oom.NodeName != "" {
+		sb.WriteString(fmt.Sprintf("  Node:         %s\n", Info(oom.NodeName)))
+	}
+
+	switch oom.Classification {
+	case models.OOMNodePressure:
+		sb.WriteString(fmt.Sprintf("  Cause:        %s\n", Error("node memory pressure")))
+	case models.OOMCgroupLimit:
+		sb.WriteString(fmt.Sprintf("  Cause:        %s\n", Warning("container memory limit")))
+	default:
+		sb.WriteString(fmt.Sprintf("  Cause:        %s\n", Muted("undetermined")))
+	}
+	sb.WriteString("\n")
+}
+
+func (f *Formatter) writeWorkload(sb *strings.Builder, w *models.WorkloadStatus) {
+	sb.WriteString(SectionHeader("📦 WORKLOAD STATUS"))
+	sb.WriteString("\n")
+	sb.WriteString(Colorize(Gray, sectionBreak))
+	sb.WriteString("\n")
+
+	ready := fmt.Sprintf("%d/%d ready", w.Ready, w.Desired)
+	if w.Ready < w.Desired {
+		ready = Warning(ready)
+	} else {
+		ready = Success(ready)
+	}
+	sb.WriteString(fmt.Sprintf("  %s:  %s  %s  %s\n", w.Kind, BoldColorize(White, w.Name), ready,
+		Muted(fmt.Sprintf("(%d updated, %d available)", w.Updated, w.Available))))
+
+	for _, p := range w.Pods {
+		status := Success("healthy")
+		if !p.Healthy() {
+			status = Error(valueOrDefault(p.Reason, p.Phase))
+		}
+		marker := " "
+		if p.Analyzed {
+			marker = Info("*")
+		}
+		sb.WriteString(fmt.Sprintf("  %s %s  %s  %s\n", marker, p.Name, status, Muted(fmt.Sprintf("restarts=%d", p.Restarts))))
+	}
+	sb.WriteString(Muted("  * logs and events analyzed"))
+	sb.WriteString("\n\n")
+}
+
+func valueOrDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+func (f *Formatter) writeAdmission(sb *strings.Builder, adm *models.AdmissionContext) {
+	sb.WriteString(SectionHeader("🛡️  ADMISSION FAILURES"))
+	sb.WriteString("\n")
+	sb.WriteString(Colorize(Gray, sectionBreak))
+	sb.WriteString("\n")
+
+	if adm.PodMissing {
+		sb.WriteString(fmt.Sprintf("  %s\n", Error("Pod does not exist (never admitted)")))
+	}
+	if enforce, ok := adm.PodSecurity["enforce"]; ok {
+		sb.WriteString(fmt.Sprintf("  Pod Security: %s\n", Info("enforce="+enforce)))
+	}
+	for _, fail := range adm.Failures {
+		sb.WriteString(fmt.Sprintf("  %s %s/%s\n", Warning("["+fail.Category+"]"), fail.Kind, BoldColorize(White, fail.Name)))
+		sb.WriteString(fmt.Sprintf("    %s\n", Muted(fail.Message)))
+	}
+	sb.WriteString("\n")
+}
+
+func (f *Formatter) writeProbes(sb *strings.Builder, r *models.ProbeReport) {
+	sb.WriteString(SectionHeader("📡 CONNECTIVITY PROBES"))
+	sb.WriteString("\n")
+	sb.WriteString(Colorize(Gray, sectionBreak))
+	sb.WriteString("\n")
+
+	for _, res := range r.Results {
+		status := Success("ok")
+		if !res.OK {
+			status = Error("failed")
+		}
+		line := fmt.Sprintf("  %-4s %s  %s", strings.ToUpper(res.Kind), BoldColorize(White, res.Target), status)
+		if res.Detail != "" {
+			line += "  " + Muted(res.Detail)
+		}
+		sb.WriteString(line + "\n")
+	}
+	sb.WriteString("\n")
+}
+
+func (f *Formatter) writeRollout(sb *strings.Builder, r *models.RolloutInfo) {
+	sb.WriteString(SectionHeader("🚦 ROLLOUT STATUS"))
+	sb.WriteString("\n")
+	sb.WriteString(Colorize(Gray, sectionBreak))
+	sb.WriteString("\n")
+
+	sb.WriteString(fmt.Sprintf("  %s:  %s\n", r.Kind, BoldColorize(White, r.Name)))
+	phase := r.Phase
+	switch phase {
+	case "Degraded", "Failed":
+		phase = Error(phase)
+	case "Healthy", "Succeeded":
+		phase = Success(phase)
+	default:
+		phase = Warning(phase)
+	}
+	sb.WriteString(fmt.Sprintf("  Phase:        %s\n", phase))
+	if r.TotalSteps > 0 {
+		sb.WriteString(fmt.Sprintf("  Step:         %s\n", Info(fmt.Sprintf("%d/%d", r.CurrentStep, r.TotalSteps))))
+	}
+	sb.WriteString(fmt.Sprintf("  Canary:       %s\n", Info(fmt.Sprintf("%d%%", r.CanaryWeight))))
+	if r.Message != "" {
+		sb.WriteString(fmt.Sprintf("  Message:      %s\n", Muted(r.Message)))
+	}
+	sb.WriteString("\n")
+}
+
 func (f *Formatter) writeRootCause(sb *strings.Builder, analysis models.Analysis) {
 	sb.WriteString(SectionHeader("🎯 ROOT CAUSE ANALYSIS"))
 	sb.WriteString("\n")
 	sb.WriteString(Colorize(Gray, sectionBreak))
 	sb.WriteString("\n")
 
-	sb.WriteString(fmt.Sprintf("  Confidence:  %s\n", ConfidenceBadge(analysis.Confidence)))
+	if analysis.ConfidenceRubric != nil {
+		r := analysis.ConfidenceRubric
+		sb.WriteString(fmt.Sprintf("  Confidence:  %s %s\n", ConfidenceBadge(analysis.Confidence), Info(fmt.Sprintf("%d/100", analysis.ConfidenceScore))))
+		sb.WriteString(Muted(fmt.Sprintf("               evidence %d/%d · events %d/%d · self-reported %d/%d\n",
+			r.EvidenceCoverage, models.RubricEvidenceCoverage,
+			r.CorroboratingEvents, models.RubricCorroboratingEvents,
+			r.SelfReported, models.RubricSelfReported)))
+	} else {
+		sb.WriteString(fmt.Sprintf("  Confidence:  %s\n", ConfidenceBadge(analysis.Confidence)))
+	}
 	sb.WriteString(fmt.Sprintf("  Root Cause:  %s\n\n", BoldColorize(Yellow, analysis.RootCause)))
 
+	if len(analysis.Hypotheses) > 1 {
+		sb.WriteString(Colorize(Gray, "  Ranked Hypotheses:"))
+		sb.WriteString("\n")
+		for i, h := range analysis.Hypotheses {
+			sb.WriteString(fmt.Sprintf("    %s %s %s\n",
+				Colorize(Yellow, fmt.Sprintf("%d.", i+1)),
+				BoldColorize(Cyan, fmt.Sprintf("%3d%%", h.Percent())),
+				h.RootCause,
+			))
+		}
+		sb.WriteString("\n")
+	}
+
 	if analysis.Reasoning != "" {
 		sb.WriteString(Colorize(Gray, "  Detailed Reasoning:"))
 		sb.WriteString("\n")
@@ -101,6 +397,29 @@ func (f *Formatter) writeRootCause(sb *strings.Builder, analysis models.Analysis
 	sb.WriteString("\n")
 }
 
+func (f *Formatter) writeRuleMatch(sb *strings.Builder, m *models.RuleMatch) {
+	if m.Answered {
+		sb.WriteString(Muted(fmt.Sprintf("  Recognized by rule %s; answered without the LLM", m.Rule)))
+	} else {
+		sb.WriteString(Muted(fmt.Sprintf("  Rule %s suspected: %s", m.Rule, m.Cause)))
+	}
+	sb.WriteString("\n\n")
+}
+
+func (f *Formatter) writeEscalation(sb *strings.Builder, chain []models.EscalationStep) {
+	sb.WriteString(Muted("  Escalated after low confidence:"))
+	sb.WriteString("\n")
+	for _, step := range chain {
+		if step.Error != "" {
+			sb.WriteString(Muted(fmt.Sprintf("    %-10s failed: %s", step.Scope, step.Error)))
+		} else {
+			sb.WriteString(Muted(fmt.Sprintf("    %-10s %s (%d/100): %s", step.Scope, step.Confidence, step.ConfidenceScore, step.RootCause)))
+		}
+		sb.WriteString("\n")
+	}
+	sb.WriteString("\n")
+}
+
 func (f *Formatter) writeTimeline(sb *strings.Builder, timeline []models.TimelineEvent) {
 	sb.WriteString(SectionHeader("⏰ EVENT TIMELINE"))
 	sb.WriteString("\n")
@@ -152,8 +471,8 @@ func (f *Formatter) writeEvidence(sb *strings.Builder, evidence models.Evidence)
 
 		for i, log := range evidence.Logs {
 			timeStr := log.Timestamp.Format("15:04:05")
-			sb.WriteString(fmt.Sprintf("    %s. %s %s\n",
-				Colorize(Yellow, fmt.Sprintf("%d", i+1)),
+			sb.WriteString(fmt.Sprintf("    %s %s %s\n",
+				Colorize(Yellow, fmt.Sprintf("[L%d]", i+1)),
 				Colorize(Magenta, timeStr),
 				Muted("→"),
 			))
@@ -190,8 +509,8 @@ func (f *Formatter) writeEvidence(sb *strings.Builder, evidence models.Evidence)
 				eventType = Success("Normal")
 			}
 
-			sb.WriteString(fmt.Sprintf("    %s. %s [%s] %s\n",
-				Colorize(Yellow, fmt.Sprintf("%d", i+1)),
+			sb.WriteString(fmt.Sprintf("    %s %s [%s] %s\n",
+				Colorize(Yellow, fmt.Sprintf("[E%d]", i+1)),
 				Colorize(Magenta, timeStr),
 				eventType,
 				BoldColorize(White, event.Reason),
@@ -221,12 +540,23 @@ func (f *Formatter) writeRecommendations(sb *strings.Builder, recommendations []
 		if rec.Command != "" {
 			sb.WriteString(fmt.Sprintf("     %s\n", Muted("Command:")))
 			sb.WriteString(fmt.Sprintf("     %s\n", Colorize(Green, fmt.Sprintf("$ %s", rec.Command))))
+			if rec.Check != nil && !rec.Check.Valid {
+				sb.WriteString(fmt.Sprintf("     %s\n", Warning("⚠ "+rec.Check.Error)))
+			}
+		}
+
+		if len(rec.EvidenceRefs) > 0 {
+			labels := make([]string, 0, len(rec.EvidenceRefs))
+			for _, ref := range rec.EvidenceRefs {
+				labels = append(labels, "["+ref.Label()+"]")
+			}
+			sb.WriteString(fmt.Sprintf("     %s %s\n", Muted("Evidence:"), Colorize(Yellow, strings.Join(labels, " "))))
 		}
 		sb.WriteString("\n")
 	}
 }
 
-func (f *Formatter) writeCollectionStats(sb *strings.Builder, data models.CollectedData) {
+func (f *Formatter) writeCollectionStats(sb *strings.Builder, data models.CollectedData, stages []models.Stage, analyzers []string) {
 	sb.WriteString(SectionHeader("📊 DATA COLLECTION STATS"))
 	sb.WriteString("\n")
 	sb.WriteString(Colorize(Gray, sectionBreak))
@@ -235,6 +565,48 @@ func (f *Formatter) writeCollectionStats(sb *strings.Builder, data models.Collec
 	sb.WriteString(fmt.Sprintf("  Log Lines:    %s\n", Info(fmt.Sprintf("%d", data.LogLines))))
 	sb.WriteString(fmt.Sprintf("  Events:       %s\n", Info(fmt.Sprintf("%d", data.EventsCount))))
 	sb.WriteString(fmt.Sprintf("  Time Range:   %s\n", Info(data.TimeRange)))
+	if len(stages) > 0 {
+		parts := make([]string, 0, len(stages))
+		for _, s := range stages {
+			parts = append(parts, fmt.Sprintf("%s %s", s.Name, Muted(s.Duration)))
+		}
+		sb.WriteString(fmt.Sprintf("  Stages:       %s\n", strings.Join(parts, ", ")))
+	}
+	if len(analyzers) > 0 {
+		sb.WriteString(fmt.Sprintf("  Analyzers:    %s\n", Info(strings.Join(analyzers, " → "))))
+	}
+	sb.WriteString("\n")
+}
+
+func (f *Formatter) writeManifest(sb *strings.Builder, manifest []models.DataSource, redactions []models.Redaction) {
+	sb.WriteString(SectionHeader("🧾 DATA SOURCES"))
+	sb.WriteString("\n")
+	sb.WriteString(Colorize(Gray, sectionBreak))
+	sb.WriteString("\n")
+
+	for _, s := range manifest {
+		name := fmt.Sprintf("%-24s", s.Name)
+		switch {
+		case s.Skipped:
+			sb.WriteString(fmt.Sprintf("  %s %s %s\n", Muted("○"), Muted(name), Muted("skipped: "+s.SkipReason)))
+		case s.Error != "":
+			msg := s.Error
+			if s.ErrorClass != "" {
+				msg = "[" + s.ErrorClass + "] " + msg
+			}
+			sb.WriteString(fmt.Sprintf("  %s %s %s\n", Error("✗"), name, Error(msg)))
+		default:
+			sb.WriteString(fmt.Sprintf("  %s %s %s %s\n", Success("✓"), name,
+				Info(fmt.Sprintf("%d items", s.Items)), Muted(s.Duration)))
+		}
+	}
+	if len(redactions) > 0 {
+		var parts []string
+		for _, r := range redactions {
+			parts = append(parts, fmt.Sprintf("%s ×%d", r.Detector, r.Count))
+		}
+		sb.WriteString(fmt.Sprintf("  %s %s\n", Muted("Redacted before sending:"), strings.Join(parts, ", ")))
+	}
 	sb.WriteString("\n")
 }
 
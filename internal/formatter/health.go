@@ -0,0 +1,98 @@
+package formatter
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/emirozbir/micro-sre/internal/models"
+)
+
+// FormatHealthReport renders a namespace health report for the terminal
+func (f *Formatter) FormatHealthReport(report *models.HealthReport) string {
+	var sb strings.Builder
+
+	// Header
+	sb.WriteString("\n")
+	sb.WriteString(Colorize(Cyan, divider))
+	sb.WriteString("\n")
+	sb.WriteString(Title("  🩺 MICRO-SRE NAMESPACE HEALTH REPORT"))
+	sb.WriteString("\n")
+	sb.WriteString(Colorize(Cyan, divider))
+	sb.WriteString("\n\n")
+
+	sb.WriteString(SectionHeader("📋 SUMMARY"))
+	sb.WriteString("\n")
+	sb.WriteString(Colorize(Gray, sectionBreak))
+	sb.WriteString("\n")
+	sb.WriteString(fmt.Sprintf("  Namespace:   %s\n", Info(report.Namespace)))
+	sb.WriteString(fmt.Sprintf("  Status:      %s\n", healthBadge(report.Status)))
+	sb.WriteString(fmt.Sprintf("  Time Range:  %s\n", report.TimeRange))
+	sb.WriteString(fmt.Sprintf("  Generated:   %s\n\n", Muted(report.GeneratedAt.Format(time.RFC3339))))
+	sb.WriteString(f.indentText(report.Summary, "  "))
+	sb.WriteString("\n\n")
+
+	healthy := 0
+	sb.WriteString(SectionHeader("📦 WORKLOADS"))
+	sb.WriteString("\n")
+	sb.WriteString(Colorize(Gray, sectionBreak))
+	sb.WriteString("\n")
+	for _, w := range report.Workloads {
+		if w.Healthy() {
+			healthy++
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("  %s  %s  %s\n",
+			BoldColorize(White, w.Key()),
+			Warning(strings.Join(w.Issues, ", ")),
+			SeverityBadge(w.Severity)))
+		if w.Finding != "" {
+			sb.WriteString(f.indentText(w.Finding, "    "))
+			sb.WriteString("\n")
+		}
+		if w.Recommendation != "" {
+			sb.WriteString(fmt.Sprintf("    %s %s\n", Colorize(Green, "→"), w.Recommendation))
+		}
+		for i, e := range w.Warnings {
+			if i == 3 {
+				sb.WriteString(Muted(fmt.Sprintf("    ... %d more warning events\n", len(w.Warnings)-i)))
+				break
+			}
+			sb.WriteString(Muted(fmt.Sprintf("    %s %s: %s\n", e.Object, e.Reason, e.Message)))
+		}
+		sb.WriteString("\n")
+	}
+	sb.WriteString(Success(fmt.Sprintf("  %d of %d workloads healthy", healthy, len(report.Workloads))))
+	sb.WriteString("\n\n")
+
+	if len(report.OtherWarnings) > 0 {
+		sb.WriteString(SectionHeader("⚠️  OTHER WARNING EVENTS"))
+		sb.WriteString("\n")
+		sb.WriteString(Colorize(Gray, sectionBreak))
+		sb.WriteString("\n")
+		for _, e := range report.OtherWarnings {
+			sb.WriteString(fmt.Sprintf("  %s  %s %s: %s\n",
+				Muted(e.LastSeen.Format(time.RFC3339)), e.Object, Warning(e.Reason), e.Message))
+		}
+		sb.WriteString("\n")
+	}
+
+	// Footer
+	sb.WriteString(Colorize(Cyan, divider))
+	sb.WriteString("\n")
+
+	return f.render(sb.String())
+}
+
+func healthBadge(status string) string {
+	switch status {
+	case models.HealthHealthy:
+		return BoldColorize(Green, "● HEALTHY")
+	case models.HealthDegraded:
+		return BoldColorize(Yellow, "● DEGRADED")
+	case models.HealthCritical:
+		return BoldColorize(Red, "● CRITICAL")
+	default:
+		return BoldColorize(Gray, "● UNKNOWN")
+	}
+}
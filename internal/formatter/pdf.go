@@ -0,0 +1,167 @@
+package formatter
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// A4 page layout in points
+const (
+	pdfPageWidth  = 595
+	pdfPageHeight = 842
+	pdfMargin     = 50
+	pdfFontSize   = 10
+	pdfLeading    = 14
+	// pdfCharWidth approximates the average Helvetica glyph width as a
+	// fraction of the font size, for wrapping
+	pdfCharWidth = 0.5
+)
+
+// pdfDocument lays out headings and wrapped text on A4 pages using the
+// standard Helvetica fonts, so no fonts need embedding. Characters
+// WinAnsiEncoding lacks are replaced by "?".
+type pdfDocument struct {
+	pages []*bytes.Buffer
+	// y is the baseline of the next line on the current page
+	y float64
+}
+
+func newPDFDocument() *pdfDocument {
+	d := &pdfDocument{}
+	d.newPage()
+	return d
+}
+
+func (d *pdfDocument) newPage() {
+	d.pages = append(d.pages, &bytes.Buffer{})
+	d.y = pdfPageHeight - pdfMargin
+}
+
+// line writes one line in font F1 (regular) or F2 (bold), starting a new
+// page when the current one is full
+func (d *pdfDocument) line(font string, size float64, text string) {
+	if d.y < pdfMargin {
+		d.newPage()
+	}
+	fmt.Fprintf(d.pages[len(d.pages)-1], "BT /%s %.0f Tf %d %.1f Td (%s) Tj ET\n", font, size, pdfMargin, d.y, pdfString(text))
+	d.y -= size * pdfLeading / pdfFontSize
+}
+
+// heading writes a bold heading with some space above it
+func (d *pdfDocument) heading(text string, size float64) {
+	if d.y < pdfPageHeight-pdfMargin {
+		d.y -= pdfLeading / 2
+	}
+	for _, l := range wrapText(text, size) {
+		d.line("F2", size, l)
+	}
+}
+
+// text writes a paragraph line, wrapped to the page width. An empty line
+// adds half a line of space.
+func (d *pdfDocument) text(text string) {
+	if strings.TrimSpace(text) == "" {
+		d.y -= pdfLeading / 2
+		return
+	}
+	for _, l := range wrapText(text, pdfFontSize) {
+		d.line("F1", pdfFontSize, l)
+	}
+}
+
+// wrapText breaks text into lines that fit the page width at size,
+// splitting words longer than a line
+func wrapText(text string, size float64) []string {
+	width := int((pdfPageWidth - 2*pdfMargin) / (size * pdfCharWidth))
+	var lines []string
+	var current []rune
+	for _, word := range strings.Fields(text) {
+		w := []rune(word)
+		if len(current) > 0 && len(current)+1+len(w) > width {
+			lines = append(lines, string(current))
+			current = nil
+		}
+		for len(w) > width {
+			lines = append(lines, string(w[:width]))
+			w = w[width:]
+		}
+		if len(current) > 0 {
+			current = append(current, ' ')
+		}
+		current = append(current, w...)
+	}
+	if len(current) > 0 {
+		lines = append(lines, string(current))
+	}
+	return lines
+}
+
+// winAnsiPunctuation maps the typographic characters LLM responses tend to
+// use to their WinAnsiEncoding codes outside Latin-1
+var winAnsiPunctuation = map[rune]byte{
+	'€': 0x80, '…': 0x85, '‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94,
+	'•': 0x95, '–': 0x96, '—': 0x97, '™': 0x99,
+}
+
+// pdfString encodes text as the contents of a PDF literal string in
+// WinAnsiEncoding
+func pdfString(text string) string {
+	var sb strings.Builder
+	for _, r := range text {
+		if b, ok := winAnsiPunctuation[r]; ok {
+			fmt.Fprintf(&sb, "\\%03o", b)
+			continue
+		}
+		switch {
+		case r == '\\' || r == '(' || r == ')':
+			sb.WriteByte('\\')
+			sb.WriteRune(r)
+		case r < ' ':
+			sb.WriteByte(' ')
+		case r < 0x80:
+			sb.WriteRune(r)
+		case r >= 0xA0 && r <= 0xFF:
+			fmt.Fprintf(&sb, "\\%03o", r)
+		default:
+			sb.WriteByte('?')
+		}
+	}
+	return sb.String()
+}
+
+// bytes assembles the document: catalog, page tree, the two fonts, and a
+// page and content stream per page, followed by the cross-reference table
+func (d *pdfDocument) bytes() []byte {
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"", // page tree, filled in below
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>",
+	}
+	var kids []string
+	for _, content := range d.pages {
+		page := len(objects) + 1
+		kids = append(kids, fmt.Sprintf("%d 0 R", page))
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+				pdfPageWidth, pdfPageHeight, page+1),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()))
+	}
+	objects[1] = fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(kids))
+
+	var out bytes.Buffer
+	out.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = out.Len()
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return out.Bytes()
+}
@@ -0,0 +1,85 @@
+package formatter
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/emirozbir/micro-sre/internal/models"
+)
+
+// PostmortemMarkdown renders a postmortem report as markdown, for pasting
+// into incident reviews and wikis
+func PostmortemMarkdown(report *models.PostmortemReport) string {
+	var sb strings.Builder
+
+	sb.WriteString("# Postmortem")
+	if report.Period != "" {
+		sb.WriteString(fmt.Sprintf(" (%s)", report.Period))
+	}
+	if report.Namespace != "" {
+		sb.WriteString(fmt.Sprintf(": %s", report.Namespace))
+	}
+	sb.WriteString("\n\n")
+	sb.WriteString(fmt.Sprintf("**Period:** %s to %s  \n", report.From.Format(time.RFC3339), report.To.Format(time.RFC3339)))
+	sb.WriteString(fmt.Sprintf("**Incidents:** %d  \n", report.Incidents))
+	generated := report.GeneratedAt.Format(time.RFC3339)
+	if report.Model != "" {
+		generated += fmt.Sprintf(" by %s", report.Model)
+	}
+	sb.WriteString(fmt.Sprintf("**Generated:** %s\n\n", generated))
+
+	sb.WriteString("## Summary\n\n")
+	sb.WriteString(strings.TrimSpace(report.Summary))
+	sb.WriteString("\n\n")
+
+	sb.WriteString("## Top Recurring Causes\n\n")
+	if len(report.RecurringCauses) == 0 {
+		sb.WriteString("_No cause was shared by more than one incident._\n\n")
+	}
+	for i, c := range report.RecurringCauses {
+		ids := make([]string, len(c.AnalysisIDs))
+		for j, id := range c.AnalysisIDs {
+			ids[j] = fmt.Sprintf("#%d", id)
+		}
+		sb.WriteString(fmt.Sprintf("%d. %s (%d incidents: %s)\n", i+1, c.Cause, c.Count, strings.Join(ids, ", ")))
+	}
+	if len(report.RecurringCauses) > 0 {
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString("## Affected Services\n\n")
+	if len(report.AffectedServices) == 0 {
+		sb.WriteString("_None._\n\n")
+	} else {
+		sb.WriteString("| Service | Incidents | Critical | Alerts |\n")
+		sb.WriteString("|---|---:|---:|---|\n")
+		for _, s := range report.AffectedServices {
+			sb.WriteString(fmt.Sprintf("| %s/%s | %d | %d | %s |\n",
+				s.Namespace, s.Service, s.Incidents, s.Critical, strings.Join(s.Alerts, ", ")))
+		}
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString("## Action Items\n\n")
+	if len(report.ActionItems) == 0 {
+		sb.WriteString("_None._\n")
+	}
+	for _, a := range report.ActionItems {
+		line := fmt.Sprintf("- [ ] **%s** %s", strings.ToUpper(valueOrDefault(a.Priority, "medium")), a.Action)
+		if a.Owner != "" {
+			line += fmt.Sprintf(" _(owner: %s)_", a.Owner)
+		}
+		sb.WriteString(line + "\n")
+	}
+
+	redacted := 0
+	for _, r := range report.Redactions {
+		redacted += r.Count
+	}
+	if redacted > 0 {
+		sb.WriteString(fmt.Sprintf("\n_%d values were redacted before the incidents were sent to the model._\n", redacted))
+	}
+
+	return sb.String()
+}
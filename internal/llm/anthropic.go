@@ -35,18 +35,28 @@ func NewAnthropicClient(cfg *config.Config) (*AnthropicClient, error) {
 }
 
 func (a *AnthropicClient) Analyze(ctx context.Context, prompt string) (string, error) {
-	message, err := a.client.Messages.New(ctx, anthropic.MessageNewParams{
-		Model:     anthropic.F(a.model),
-		MaxTokens: anthropic.Int(int64(a.maxTokens)),
+	params := anthropic.MessageNewParams{
+		Model:     anthropic.F(Model(ctx, a.model)),
+		MaxTokens: anthropic.Int(int64(maxTokens(ctx, a.maxTokens))),
 		Messages: anthropic.F([]anthropic.MessageParam{
 			anthropic.NewUserMessage(anthropic.NewTextBlock(prompt)),
 		}),
 		Temperature: anthropic.Float(float64(a.temperature)),
-	})
+	}
 
+	var (
+		message *anthropic.Message
+		err     error
+	)
+	if onText := tokenFunc(ctx); onText != nil {
+		message, err = a.stream(ctx, params, onText)
+	} else {
+		message, err = a.client.Messages.New(ctx, params)
+	}
 	if err != nil {
 		return "", fmt.Errorf("anthropic API call failed: %w", err)
 	}
+	recordUsage(ctx, "anthropic", message.Usage.InputTokens, message.Usage.OutputTokens)
 
 	if len(message.Content) == 0 {
 		return "", fmt.Errorf("empty response from Anthropic")
@@ -59,3 +69,39 @@ func (a *AnthropicClient) Analyze(ctx context.Context, prompt string) (string, e
 
 	return "", fmt.Errorf("unexpected response format from Anthropic")
 }
+
+// Check looks up the configured model, which fails for invalid keys and
+// unknown models alike
+func (a *AnthropicClient) Check(ctx context.Context) error {
+	var model struct {
+		ID string `json:"id"`
+	}
+	if err := a.client.Get(ctx, "v1/models/"+a.model, nil, &model); err != nil {
+		return fmt.Errorf("anthropic model lookup failed: %w", err)
+	}
+	return nil
+}
+
+// stream runs the request as a stream, passing text deltas to onText, and
+// returns the accumulated message
+func (a *AnthropicClient) stream(ctx context.Context, params anthropic.MessageNewParams, onText func(string)) (*anthropic.Message, error) {
+	stream := a.client.Messages.NewStreaming(ctx, params)
+	defer stream.Close()
+
+	message := &anthropic.Message{}
+	for stream.Next() {
+		event := stream.Current()
+		if err := message.Accumulate(event); err != nil {
+			return nil, err
+		}
+		if delta, ok := event.AsUnion().(anthropic.ContentBlockDeltaEvent); ok {
+			if text, ok := delta.Delta.AsUnion().(anthropic.TextDelta); ok {
+				onText(text.Text)
+			}
+		}
+	}
+	if err := stream.Err(); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
@@ -0,0 +1,272 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/emirozbir/micro-sre/internal/config"
+	"github.com/emirozbir/micro-sre/internal/metrics"
+)
+
+// ErrBudgetExceeded is returned by Analyze once the day's token budget of
+// the namespace, or the total one, is spent
+var ErrBudgetExceeded = errors.New("LLM token budget exceeded")
+
+// Usage counts the tokens of provider calls
+type Usage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+// Total returns the prompt and response tokens together
+func (u Usage) Total() int {
+	return u.InputTokens + u.OutputTokens
+}
+
+type usageKey struct{}
+
+// UsageTracker sums the tokens of the calls made under a context returned
+// by WithUsage, including calls made concurrently
+type UsageTracker struct {
+	mu    sync.Mutex
+	usage Usage
+	// parent is the tracker of the context WithUsage derived from
+	parent *UsageTracker
+}
+
+// WithUsage returns a context whose Analyze calls add their tokens to the
+// returned tracker, and to the trackers of the contexts it derives from
+func WithUsage(ctx context.Context) (context.Context, *UsageTracker) {
+	parent, _ := ctx.Value(usageKey{}).(*UsageTracker)
+	t := &UsageTracker{parent: parent}
+	return context.WithValue(ctx, usageKey{}, t), t
+}
+
+// Usage returns the tokens tracked so far
+func (t *UsageTracker) Usage() Usage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.usage
+}
+
+func (t *UsageTracker) add(input, output int) {
+	for ; t != nil; t = t.parent {
+		t.mu.Lock()
+		t.usage.InputTokens += input
+		t.usage.OutputTokens += output
+		t.mu.Unlock()
+	}
+}
+
+// recordUsage adds the tokens reported by a provider to its token metrics,
+// the span of the call and the usage tracked on ctx, if any
+func recordUsage(ctx context.Context, provider string, input, output int64) {
+	metrics.LLMTokens.WithLabelValues(provider, "input").Add(float64(input))
+	metrics.LLMTokens.WithLabelValues(provider, "output").Add(float64(output))
+	trace.SpanFromContext(ctx).SetAttributes(
+		attribute.Int64("gen_ai.usage.input_tokens", input),
+		attribute.Int64("gen_ai.usage.output_tokens", output))
+	if t, ok := ctx.Value(usageKey{}).(*UsageTracker); ok {
+		t.add(int(input), int(output))
+	}
+}
+
+// Budget tracks the tokens spent per UTC day, in total and per namespace
+// (the tenant set with WithTenant), against the configured limits
+type Budget struct {
+	config config.LLMBudgetConfig
+
+	mu          sync.Mutex
+	day         string
+	total       Usage
+	byNamespace map[string]Usage
+}
+
+// NewBudget creates a budget tracker for the configured limits
+func NewBudget(cfg config.LLMBudgetConfig) *Budget {
+	return &Budget{config: cfg, byNamespace: map[string]Usage{}}
+}
+
+// OnExceeded returns what to do with over-budget analyses
+func (b *Budget) OnExceeded() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.config.OnExceeded
+}
+
+// Reconfigure replaces the limits, keeping today's spend
+func (b *Budget) Reconfigure(cfg config.LLMBudgetConfig) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.config = cfg
+}
+
+// BudgetResetsAt returns when the current day's budgets start over
+func BudgetResetsAt() time.Time {
+	y, m, d := time.Now().UTC().Date()
+	return time.Date(y, m, d+1, 0, 0, 0, 0, time.UTC)
+}
+
+// namespaceLimit returns the daily limit of a namespace, 0 if unlimited
+func (b *Budget) namespaceLimit(namespace string) int {
+	if limit, ok := b.config.Namespaces[namespace]; ok {
+		return limit
+	}
+	return b.config.NamespaceDailyTokens
+}
+
+// rollover starts a new day's usage if the UTC day changed; b.mu must be
+// held
+func (b *Budget) rollover() {
+	day := time.Now().UTC().Format("2006-01-02")
+	if day != b.day {
+		b.day = day
+		b.total = Usage{}
+		b.byNamespace = map[string]Usage{}
+	}
+}
+
+// Allow returns an error wrapping ErrBudgetExceeded if the total or the
+// namespace's budget for today is spent
+func (b *Budget) Allow(namespace string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.rollover()
+
+	if limit := b.config.DailyTokens; limit > 0 && b.total.Total() >= limit {
+		return fmt.Errorf("%w: %d of %d daily tokens used", ErrBudgetExceeded, b.total.Total(), limit)
+	}
+	if namespace == "" {
+		return nil
+	}
+	if limit := b.namespaceLimit(namespace); limit > 0 && b.byNamespace[namespace].Total() >= limit {
+		return fmt.Errorf("%w: namespace %s used %d of %d daily tokens", ErrBudgetExceeded, namespace, b.byNamespace[namespace].Total(), limit)
+	}
+	return nil
+}
+
+// Record adds the tokens of a call made for namespace
+func (b *Budget) Record(namespace string, u Usage) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.rollover()
+
+	b.total.InputTokens += u.InputTokens
+	b.total.OutputTokens += u.OutputTokens
+	if namespace != "" {
+		ns := b.byNamespace[namespace]
+		ns.InputTokens += u.InputTokens
+		ns.OutputTokens += u.OutputTokens
+		b.byNamespace[namespace] = ns
+	}
+}
+
+// Seed adds the tokens spent today before the budget was created, per
+// namespace, so that a restart does not start the day's budgets over
+func (b *Budget) Seed(spend map[string]Usage) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.rollover()
+
+	for namespace, u := range spend {
+		b.total.InputTokens += u.InputTokens
+		b.total.OutputTokens += u.OutputTokens
+		if namespace != "" {
+			ns := b.byNamespace[namespace]
+			ns.InputTokens += u.InputTokens
+			ns.OutputTokens += u.OutputTokens
+			b.byNamespace[namespace] = ns
+		}
+	}
+}
+
+// BudgetUsage is the spend of the day against a limit; Limit 0 is
+// unlimited
+type BudgetUsage struct {
+	Namespace string `json:"namespace,omitempty"`
+	Usage
+	Used     int  `json:"used"`
+	Limit    int  `json:"limit"`
+	Exceeded bool `json:"exceeded"`
+}
+
+// BudgetState is the day's spend, in total and per namespace
+type BudgetState struct {
+	Day        string        `json:"day"`
+	ResetsAt   time.Time     `json:"resets_at"`
+	OnExceeded string        `json:"on_exceeded"`
+	Total      BudgetUsage   `json:"total"`
+	Namespaces []BudgetUsage `json:"namespaces"`
+}
+
+// State returns the day's spend. Namespaces with a limit are listed even
+// before their first call.
+func (b *Budget) State() BudgetState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.rollover()
+
+	usage := func(namespace string, u Usage, limit int) BudgetUsage {
+		return BudgetUsage{
+			Namespace: namespace,
+			Usage:     u,
+			Used:      u.Total(),
+			Limit:     limit,
+			Exceeded:  limit > 0 && u.Total() >= limit,
+		}
+	}
+
+	state := BudgetState{
+		Day:        b.day,
+		ResetsAt:   BudgetResetsAt(),
+		OnExceeded: b.config.OnExceeded,
+		Total:      usage("", b.total, b.config.DailyTokens),
+		Namespaces: []BudgetUsage{},
+	}
+	names := map[string]bool{}
+	for ns := range b.byNamespace {
+		names[ns] = true
+	}
+	for ns := range b.config.Namespaces {
+		names[ns] = true
+	}
+	for ns := range names {
+		state.Namespaces = append(state.Namespaces, usage(ns, b.byNamespace[ns], b.namespaceLimit(ns)))
+	}
+	sort.Slice(state.Namespaces, func(i, j int) bool {
+		return state.Namespaces[i].Used > state.Namespaces[j].Used ||
+			state.Namespaces[i].Used == state.Namespaces[j].Used && state.Namespaces[i].Namespace < state.Namespaces[j].Namespace
+	})
+	return state
+}
+
+// budgetedClient refuses Analyze calls once the budget is spent and
+// records the tokens of the others
+type budgetedClient struct {
+	Client
+	budget *Budget
+}
+
+// WithBudget returns a client whose calls are counted against budget
+func WithBudget(client Client, budget *Budget) Client {
+	return &budgetedClient{Client: client, budget: budget}
+}
+
+func (c *budgetedClient) Analyze(ctx context.Context, prompt string) (string, error) {
+	namespace := tenant(ctx)
+	if err := c.budget.Allow(namespace); err != nil {
+		return "", err
+	}
+
+	ctx, usage := WithUsage(ctx)
+	text, err := c.Client.Analyze(ctx, prompt)
+	c.budget.Record(namespace, usage.Usage())
+	return text, err
+}
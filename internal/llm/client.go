@@ -3,15 +3,28 @@ package llm
 import (
 	"context"
 	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/emirozbir/micro-sre/internal/config"
+	"github.com/emirozbir/micro-sre/internal/metrics"
+	"github.com/emirozbir/micro-sre/internal/tracing"
 )
 
 type Client interface {
 	Analyze(ctx context.Context, prompt string) (string, error)
 }
 
-func NewClient(cfg *config.Config) (Client, error) {
+// Checker verifies the provider credentials and model without spending
+// tokens, for readiness checks
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// NewChecker creates a checker for the configured provider
+func NewChecker(cfg *config.Config) (Checker, error) {
 	switch cfg.LLM.Provider {
 	case "anthropic":
 		return NewAnthropicClient(cfg)
@@ -21,3 +34,104 @@ func NewClient(cfg *config.Config) (Client, error) {
 		return nil, fmt.Errorf("unknown LLM provider: %s", cfg.LLM.Provider)
 	}
 }
+
+type maxTokensKey struct{}
+
+// WithMaxTokens returns a context that caps the response of Analyze calls
+// made with it at n tokens instead of llm.max_tokens
+func WithMaxTokens(ctx context.Context, n int) context.Context {
+	return context.WithValue(ctx, maxTokensKey{}, n)
+}
+
+// maxTokens returns the response cap set on ctx, or def
+func maxTokens(ctx context.Context, def int) int {
+	if n, ok := ctx.Value(maxTokensKey{}).(int); ok && n > 0 {
+		return n
+	}
+	return def
+}
+
+type modelKey struct{}
+
+// WithModel returns a context whose Analyze calls use model instead of
+// llm.model. The model must belong to the configured provider.
+func WithModel(ctx context.Context, model string) context.Context {
+	return context.WithValue(ctx, modelKey{}, model)
+}
+
+// Model returns the model set on ctx, or def
+func Model(ctx context.Context, def string) string {
+	if m, ok := ctx.Value(modelKey{}).(string); ok && m != "" {
+		return m
+	}
+	return def
+}
+
+type tokensKey struct{}
+
+// WithTokens returns a context whose Analyze calls stream the response,
+// passing each piece of text to fn as it arrives
+func WithTokens(ctx context.Context, fn func(text string)) context.Context {
+	return context.WithValue(ctx, tokensKey{}, fn)
+}
+
+// tokenFunc returns the function set with WithTokens, or nil
+func tokenFunc(ctx context.Context) func(string) {
+	fn, _ := ctx.Value(tokensKey{}).(func(string))
+	return fn
+}
+
+// NewClient creates a client for the configured provider. With
+// llm.max_concurrent_requests set, calls queue on a limiter shared by every
+// client of the provider.
+func NewClient(cfg *config.Config) (Client, error) {
+	var (
+		client Client
+		err    error
+	)
+	switch cfg.LLM.Provider {
+	case "anthropic":
+		client, err = NewAnthropicClient(cfg)
+	case "openai":
+		client, err = NewOpenAIClient(cfg)
+	default:
+		return nil, fmt.Errorf("unknown LLM provider: %s", cfg.LLM.Provider)
+	}
+	if err != nil {
+		return nil, err
+	}
+	client = &instrumentedClient{Client: client, provider: cfg.LLM.Provider, model: cfg.LLM.Model}
+	if cfg.LLM.MaxConcurrentRequests <= 0 {
+		return client, nil
+	}
+	return &limitedClient{
+		Client:  client,
+		limiter: limiterFor(cfg.LLM.Provider, cfg.LLM.MaxConcurrentRequests),
+	}, nil
+}
+
+// instrumentedClient times and traces provider calls and counts the failed
+// ones. It wraps the provider directly, so time spent queued on the limiter
+// is not counted.
+type instrumentedClient struct {
+	Client
+	provider string
+	model    string
+}
+
+func (c *instrumentedClient) Analyze(ctx context.Context, prompt string) (string, error) {
+	ctx, span := tracing.Start(ctx, "llm analyze", trace.WithAttributes(
+		attribute.String("gen_ai.system", c.provider),
+		attribute.String("gen_ai.request.model", Model(ctx, c.model)),
+		attribute.Int("hepsre.prompt_bytes", len(prompt))))
+	started := time.Now()
+	text, err := c.Client.Analyze(ctx, prompt)
+	tracing.End(span, err)
+	outcome := "ok"
+	if err != nil {
+		outcome = "error"
+		metrics.LLMErrors.WithLabelValues(c.provider).Inc()
+	}
+	metrics.LLMDuration.WithLabelValues(c.provider, outcome).Observe(time.Since(started).Seconds())
+	return text, err
+}
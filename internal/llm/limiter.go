@@ -0,0 +1,177 @@
+package llm
+
+import (
+	"context"
+	"sync"
+)
+
+type tenantKey struct{}
+
+// WithTenant returns a context whose Analyze calls are queued as tenant's
+// when the provider is at llm.max_concurrent_requests
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+func tenant(ctx context.Context) string {
+	t, _ := ctx.Value(tenantKey{}).(string)
+	return t
+}
+
+// fairLimiter bounds concurrent requests to a provider. Waiting requests are
+// served round-robin across tenants and in arrival order within a tenant, so
+// one tenant's burst cannot starve the others.
+type fairLimiter struct {
+	mu   sync.Mutex
+	size int
+	// free is negative while more requests run than a shrunk size allows
+	free   int
+	queues map[string][]*waiter
+	// order lists tenants with waiting requests, next to be served first
+	order []string
+}
+
+type waiter struct {
+	ready   chan struct{}
+	granted bool
+}
+
+func newFairLimiter(size int) *fairLimiter {
+	return &fairLimiter{size: size, free: size, queues: map[string][]*waiter{}}
+}
+
+// acquire waits for a free slot. It returns ctx.Err() if the context is done
+// first, in which case no slot is held.
+func (l *fairLimiter) acquire(ctx context.Context, tenant string) error {
+	l.mu.Lock()
+	if l.free > 0 && len(l.order) == 0 {
+		l.free--
+		l.mu.Unlock()
+		return nil
+	}
+	w := &waiter{ready: make(chan struct{})}
+	if len(l.queues[tenant]) == 0 {
+		l.order = append(l.order, tenant)
+	}
+	l.queues[tenant] = append(l.queues[tenant], w)
+	l.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+	}
+
+	l.mu.Lock()
+	if w.granted {
+		// Lost the race with release; hand the slot on
+		l.mu.Unlock()
+		l.release()
+		return ctx.Err()
+	}
+	l.remove(tenant, w)
+	l.mu.Unlock()
+	return ctx.Err()
+}
+
+// release frees a slot, handing it to the next waiter if there is one
+func (l *fairLimiter) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.free < 0 || len(l.order) == 0 {
+		l.free++
+		return
+	}
+	l.grantNext()
+}
+
+// resize changes the number of slots after a configuration reload. Requests
+// over a smaller size run to completion but are not replaced; a larger size
+// admits waiters right away.
+func (l *fairLimiter) resize(size int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.free += size - l.size
+	l.size = size
+	for l.free > 0 && len(l.order) > 0 {
+		l.free--
+		l.grantNext()
+	}
+}
+
+// grantNext hands a slot to the next waiter; l.mu must be held and a
+// waiter queued
+func (l *fairLimiter) grantNext() {
+	tenant := l.order[0]
+	queue := l.queues[tenant]
+	w := queue[0]
+	l.order = l.order[1:]
+	if len(queue) > 1 {
+		l.queues[tenant] = queue[1:]
+		l.order = append(l.order, tenant)
+	} else {
+		delete(l.queues, tenant)
+	}
+
+	w.granted = true
+	close(w.ready)
+}
+
+// remove drops a waiter that gave up; l.mu must be held
+func (l *fairLimiter) remove(tenant string, w *waiter) {
+	queue := l.queues[tenant]
+	for i, q := range queue {
+		if q == w {
+			queue = append(queue[:i], queue[i+1:]...)
+			break
+		}
+	}
+	if len(queue) > 0 {
+		l.queues[tenant] = queue
+		return
+	}
+	delete(l.queues, tenant)
+	for i, t := range l.order {
+		if t == tenant {
+			l.order = append(l.order[:i], l.order[i+1:]...)
+			break
+		}
+	}
+}
+
+// limiters holds one limiter per provider, shared by all its clients and
+// resized when a reload changes llm.max_concurrent_requests
+var (
+	limitersMu sync.Mutex
+	limiters   = map[string]*fairLimiter{}
+)
+
+func limiterFor(provider string, size int) *fairLimiter {
+	limitersMu.Lock()
+	defer limitersMu.Unlock()
+
+	l, ok := limiters[provider]
+	if !ok {
+		l = newFairLimiter(size)
+		limiters[provider] = l
+	} else {
+		l.resize(size)
+	}
+	return l
+}
+
+// limitedClient queues Analyze calls on the provider's limiter
+type limitedClient struct {
+	Client
+	limiter *fairLimiter
+}
+
+func (c *limitedClient) Analyze(ctx context.Context, prompt string) (string, error) {
+	if err := c.limiter.acquire(ctx, tenant(ctx)); err != nil {
+		return "", err
+	}
+	defer c.limiter.release()
+	return c.Client.Analyze(ctx, prompt)
+}
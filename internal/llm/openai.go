@@ -35,18 +35,28 @@ func NewOpenAIClient(cfg *config.Config) (*OpenAIClient, error) {
 }
 
 func (o *OpenAIClient) Analyze(ctx context.Context, prompt string) (string, error) {
-	completion, err := o.client.Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
-		Model: openai.ChatModel(o.model),
+	params := openai.ChatCompletionNewParams{
+		Model: openai.ChatModel(Model(ctx, o.model)),
 		Messages: []openai.ChatCompletionMessageParamUnion{
 			openai.UserMessage(prompt),
 		},
-		MaxTokens:   openai.Int(int64(o.maxTokens)),
+		MaxTokens:   openai.Int(int64(maxTokens(ctx, o.maxTokens))),
 		Temperature: openai.Float(float64(o.temperature)),
-	})
+	}
 
+	var (
+		completion *openai.ChatCompletion
+		err        error
+	)
+	if onText := tokenFunc(ctx); onText != nil {
+		completion, err = o.stream(ctx, params, onText)
+	} else {
+		completion, err = o.client.Chat.Completions.New(ctx, params)
+	}
 	if err != nil {
 		return "", fmt.Errorf("openai API call failed: %w", err)
 	}
+	recordUsage(ctx, "openai", completion.Usage.PromptTokens, completion.Usage.CompletionTokens)
 
 	if len(completion.Choices) == 0 {
 		return "", fmt.Errorf("empty response from OpenAI")
@@ -54,3 +64,35 @@ func (o *OpenAIClient) Analyze(ctx context.Context, prompt string) (string, erro
 
 	return completion.Choices[0].Message.Content, nil
 }
+
+// stream runs the request as a stream, passing content deltas to onText,
+// and returns the accumulated completion
+func (o *OpenAIClient) stream(ctx context.Context, params openai.ChatCompletionNewParams, onText func(string)) (*openai.ChatCompletion, error) {
+	// Usage is only reported in a final chunk when asked for
+	params.StreamOptions = openai.ChatCompletionStreamOptionsParam{IncludeUsage: openai.Bool(true)}
+
+	stream := o.client.Chat.Completions.NewStreaming(ctx, params)
+	defer stream.Close()
+
+	acc := openai.ChatCompletionAccumulator{}
+	for stream.Next() {
+		chunk := stream.Current()
+		acc.AddChunk(chunk)
+		if len(chunk.Choices) > 0 && chunk.Choices[0].Delta.Content != "" {
+			onText(chunk.Choices[0].Delta.Content)
+		}
+	}
+	if err := stream.Err(); err != nil {
+		return nil, err
+	}
+	return &acc.ChatCompletion, nil
+}
+
+// Check looks up the configured model, which fails for invalid keys and
+// unknown models alike
+func (o *OpenAIClient) Check(ctx context.Context) error {
+	if _, err := o.client.Models.Get(ctx, o.model); err != nil {
+		return fmt.Errorf("openai model lookup failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+// Package metrics holds the Prometheus metrics of the server, exposed at
+// /metrics. Metrics are registered on a registry of their own, together
+// with the Go runtime and process collectors.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Registry holds every hepsre metric
+var Registry = prometheus.NewRegistry()
+
+var (
+	// HTTPRequests counts served requests by method, route and status code
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "hepsre_http_requests_total",
+		Help: "HTTP requests served, by method, route and status code.",
+	}, []string{"method", "route", "code"})
+
+	// HTTPDuration times served requests by method and route
+	HTTPDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "hepsre_http_request_duration_seconds",
+		Help:    "Time to serve HTTP requests, by method and route.",
+		Buckets: []float64{.01, .05, .1, .5, 1, 5, 15, 30, 60, 120, 300},
+	}, []string{"method", "route"})
+
+	// Analyses counts stored analyses by confidence level
+	Analyses = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "hepsre_analyses_total",
+		Help: "Analyses stored, by confidence level.",
+	}, []string{"confidence"})
+
+	// ConfidenceScore is the distribution of the 0-100 confidence score of
+	// stored analyses
+	ConfidenceScore = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "hepsre_analysis_confidence_score",
+		Help:    "Confidence score (0-100) of stored analyses.",
+		Buckets: prometheus.LinearBuckets(10, 10, 10),
+	})
+
+	// FeedbackRatings counts the ratings given to analyses by rating (up or
+	// down)
+	FeedbackRatings = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "hepsre_feedback_ratings_total",
+		Help: "Ratings of analyses, by rating (up or down).",
+	}, []string{"rating"})
+
+	// LLMDuration times provider calls by provider and outcome
+	LLMDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "hepsre_llm_request_duration_seconds",
+		Help:    "Time of LLM provider calls, by provider and outcome (ok or error).",
+		Buckets: []float64{1, 2.5, 5, 10, 20, 30, 60, 120, 300},
+	}, []string{"provider", "outcome"})
+
+	// LLMTokens counts the tokens reported by providers by provider and
+	// direction (input or output)
+	LLMTokens = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "hepsre_llm_tokens_total",
+		Help: "LLM tokens used, by provider and direction (input or output).",
+	}, []string{"provider", "direction"})
+
+	// LLMErrors counts failed provider calls by provider
+	LLMErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "hepsre_llm_errors_total",
+		Help: "Failed LLM provider calls, by provider.",
+	}, []string{"provider"})
+
+	// CollectorDuration times collector runs by data source and error class
+	// (empty for successful runs)
+	CollectorDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "hepsre_collector_duration_seconds",
+		Help:    "Time of collector runs, by data source and error class (empty on success).",
+		Buckets: []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+	}, []string{"source", "error_class"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests,
+		HTTPDuration,
+		Analyses,
+		ConfidenceScore,
+		FeedbackRatings,
+		LLMDuration,
+		LLMTokens,
+		LLMErrors,
+		CollectorDuration,
+	)
+}
+
+// QueueDepth registers a gauge reporting the number of analysis jobs
+// waiting for a worker, read from depth at scrape time
+func QueueDepth(depth func() int) {
+	Registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "hepsre_analysis_queue_depth",
+		Help: "Analysis jobs waiting for a worker.",
+	}, func() float64 { return float64(depth()) }))
+}
+
+// Handler serves the metrics in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
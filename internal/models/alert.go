@@ -1,23 +1,54 @@
 package models
 
-import "time"
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Annotations alert authors set to tune the analysis of their alerts
+const (
+	// AnnotationLookback overrides the lookback, e.g. "4h"
+	AnnotationLookback = "hepsre.io/lookback"
+	// AnnotationContainer selects the container whose logs are collected
+	AnnotationContainer = "hepsre.io/container"
+	// AnnotationSkip set to "true" skips the analysis of the alert
+	AnnotationSkip = "hepsre.io/skip"
+)
 
 type Alert struct {
-	Labels      map[string]string `json:"labels"`
-	Annotations map[string]string `json:"annotations"`
-	StartsAt    time.Time         `json:"startsAt"`
-	EndsAt      time.Time         `json:"endsAt"`
-	Status      string            `json:"status"`
-	Fingerprint string            `json:"fingerprint"`
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations"`
+	StartsAt     time.Time         `json:"startsAt"`
+	EndsAt       time.Time         `json:"endsAt"`
+	Status       string            `json:"status"`
+	Fingerprint  string            `json:"fingerprint"`
+	GeneratorURL string            `json:"generatorURL,omitempty"`
+}
+
+// Silence is an AlertManager silence that matched an alert's labels
+type Silence struct {
+	ID        string    `json:"id"`
+	State     string    `json:"state"`
+	CreatedBy string    `json:"created_by"`
+	Comment   string    `json:"comment"`
+	StartsAt  time.Time `json:"starts_at"`
+	EndsAt    time.Time `json:"ends_at"`
+}
+
+// IsActive reports whether the silence is currently in effect
+func (s *Silence) IsActive() bool {
+	return s.State == "active"
 }
 
 type AlertContext struct {
-	Alert      Alert
-	Namespace  string
-	PodName    string
-	Severity   string
-	AlertName  string
-	StartedAt  time.Time
+	Alert     Alert
+	Namespace string
+	PodName   string
+	Severity  string
+	AlertName string
+	StartedAt time.Time
 }
 
 func (a *Alert) GetNamespace() string {
@@ -53,3 +84,43 @@ func (a *Alert) GetAlertName() string {
 	}
 	return "unknown"
 }
+
+// Lookback returns the lookback set by AnnotationLookback, or 0 if there
+// is none
+func (a *Alert) Lookback() (time.Duration, error) {
+	v := a.Annotations[AnnotationLookback]
+	if v == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid %s annotation %q", AnnotationLookback, v)
+	}
+	return d, nil
+}
+
+// Container returns the container set by AnnotationContainer, or "" for
+// the pod's main container
+func (a *Alert) Container() string {
+	return a.Annotations[AnnotationContainer]
+}
+
+// Skip reports whether AnnotationSkip opts the alert out of analysis
+func (a *Alert) Skip() bool {
+	skip, _ := strconv.ParseBool(a.Annotations[AnnotationSkip])
+	return skip
+}
+
+// SeverityRank orders alert severities: the higher, the more severe.
+// Unknown severities rank lowest.
+func SeverityRank(severity string) int {
+	switch strings.ToLower(severity) {
+	case "critical":
+		return 3
+	case "warning":
+		return 2
+	case "info":
+		return 1
+	}
+	return 0
+}
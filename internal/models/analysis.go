@@ -1,28 +1,459 @@
 package models
 
-import "time"
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
 
 type AnalysisResult struct {
-	Alert          AlertSummary    `json:"alert"`
-	Analysis       Analysis        `json:"analysis"`
-	CollectedData  CollectedData   `json:"collected_data"`
+	Alert AlertSummary `json:"alert"`
+	// Model and PromptVersion identify what produced the analysis
+	Model         string            `json:"model,omitempty"`
+	PromptVersion string            `json:"prompt_version,omitempty"`
+	Verbosity     string            `json:"verbosity,omitempty"`
+	Analysis      Analysis          `json:"analysis"`
+	CollectedData CollectedData     `json:"collected_data"`
+	Silences      []Silence         `json:"silences,omitempty"`
+	Rollout       *RolloutInfo      `json:"rollout,omitempty"`
+	RelatedAlerts []RelatedAlert    `json:"related_alerts,omitempty"`
+	Metrics       *AlertMetrics     `json:"metrics,omitempty"`
+	OOM           *OOMContext       `json:"oom,omitempty"`
+	Probes        *ProbeReport      `json:"probes,omitempty"`
+	Admission     *AdmissionContext `json:"admission,omitempty"`
+	Workload      *WorkloadStatus   `json:"workload,omitempty"`
+	Rule          *RuleMatch        `json:"rule,omitempty"`
+	// Analyzers are the pipeline analyzers that contributed, in order
+	Analyzers  []string      `json:"analyzers,omitempty"`
+	Manifest   []DataSource  `json:"manifest,omitempty"`
+	Artifacts  []ArtifactRef `json:"artifacts,omitempty"`
+	Redactions []Redaction   `json:"redactions,omitempty"`
+	// BudgetExceeded is set when the LLM token budget was spent and the
+	// analysis was answered without the LLM
+	BudgetExceeded bool `json:"budget_exceeded,omitempty"`
+	// Provider is the LLM provider of Model, and Usage what its calls for
+	// the analysis spent; both are unset when the LLM was not called
+	Provider string    `json:"provider,omitempty"`
+	Usage    *LLMUsage `json:"usage,omitempty"`
+	// Feedback is the human correction a re-analysis was run with
+	Feedback *Feedback `json:"feedback,omitempty"`
+	// Recurrence counts earlier analyses of the same alert on the same pod
+	Recurrence *Recurrence `json:"recurrence,omitempty"`
+	// Escalation is the chain of analyses run with broadened context after
+	// a low-confidence pod analysis, starting with the pod itself
+	Escalation []EscalationStep `json:"escalation,omitempty"`
+	// Stages times the steps of the analysis, in order
+	Stages []Stage `json:"stages,omitempty"`
+	// RequestID is the X-Request-ID of the request that ran the analysis
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// Escalation scopes, from narrowest to broadest
+const (
+	EscalationPod        = "pod"
+	EscalationController = "controller"
+	EscalationNamespace  = "namespace"
+)
+
+// EscalationStep is the outcome of analyzing an alert at one scope. Error
+// is set when the context of the scope could not be collected.
+type EscalationStep struct {
+	Scope           string `json:"scope"`
+	RootCause       string `json:"root_cause,omitempty"`
+	Confidence      string `json:"confidence,omitempty"`
+	ConfidenceScore int    `json:"confidence_score"`
+	Error           string `json:"error,omitempty"`
+}
+
+// Stage is one timed step of an analysis, such as fetching the pod or
+// querying the LLM
+type Stage struct {
+	Name       string `json:"name"`
+	Duration   string `json:"duration"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// Recurrence summarizes earlier analyses of the same alert on the same pod.
+// Recurring is set once the count reaches agent.recurrence.threshold: the
+// incident is chronic, not novel.
+type Recurrence struct {
+	Count          int       `json:"count"`
+	Since          time.Time `json:"since"`
+	Recurring      bool      `json:"recurring"`
+	LastAnalysisID int64     `json:"last_analysis_id"`
+	LastSeen       time.Time `json:"last_seen"`
+	LastRootCause  string    `json:"last_root_cause"`
+}
+
+// Feedback marks the root cause of one version of an analysis as wrong and
+// gives a hint for the re-analysis
+type Feedback struct {
+	// Version is the analysis version judged wrong; 1 is the original
+	Version           int       `json:"version"`
+	RejectedRootCause string    `json:"rejected_root_cause"`
+	Hint              string    `json:"hint"`
+	Author            string    `json:"author,omitempty"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+// Redaction counts the values a detector removed from the prompt before it
+// was sent to the LLM
+type Redaction struct {
+	Detector string `json:"detector"`
+	Count    int    `json:"count"`
+}
+
+// RuleMatch records that a deterministic rule recognized the incident.
+// Answered is set when the rule produced the analysis and the LLM was not
+// called; otherwise the suspected cause was added to the prompt.
+type RuleMatch struct {
+	Rule     string `json:"rule"`
+	Cause    string `json:"cause"`
+	Answered bool   `json:"answered"`
+}
+
+// Artifact names
+const (
+	ArtifactLogs    = "logs"
+	ArtifactEvents  = "events"
+	ArtifactPodSpec = "pod"
+)
+
+// LLMUsage is the tokens spent on the LLM calls of an analysis, and their
+// cost in USD estimated from llm.prices
+type LLMUsage struct {
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	CostUSD          float64 `json:"cost_usd"`
+}
+
+// ArtifactRef points at a collected blob kept in the artifact store.
+// Truncated blobs were cut down to artifacts.max_size, keeping the newest
+// logs or events.
+type ArtifactRef struct {
+	Name        string `json:"name"`
+	ID          string `json:"id"`
+	Size        int    `json:"size"`
+	ContentType string `json:"content_type"`
+	Truncated   bool   `json:"truncated,omitempty"`
+}
+
+// DataSource records one collector run that fed the analysis, so consumers
+// can judge how complete the context given to the LLM was
+type DataSource struct {
+	Name       string    `json:"name"`
+	Version    string    `json:"version"`
+	Items      int       `json:"items"`
+	Since      time.Time `json:"since,omitempty"`
+	Until      time.Time `json:"until,omitempty"`
+	Duration   string    `json:"duration,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	ErrorClass string    `json:"error_class,omitempty"`
+	Skipped    bool      `json:"skipped,omitempty"`
+	SkipReason string    `json:"skip_reason,omitempty"`
+}
+
+// WorkloadStatus summarizes a Deployment or StatefulSet and the health of
+// its pods, for deployment-level analyses
+type WorkloadStatus struct {
+	Kind       string              `json:"kind"`
+	Name       string              `json:"name"`
+	Namespace  string              `json:"namespace"`
+	Desired    int32               `json:"desired"`
+	Ready      int32               `json:"ready"`
+	Updated    int32               `json:"updated"`
+	Available  int32               `json:"available"`
+	Conditions []WorkloadCondition `json:"conditions,omitempty"`
+	Pods       []PodHealth         `json:"pods"`
+}
+
+type WorkloadCondition struct {
+	Type    string `json:"type"`
+	Status  string `json:"status"`
+	Reason  string `json:"reason,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// PodHealth is the health summary of one pod of a workload. Analyzed marks
+// the pods whose logs and events were given to the LLM.
+type PodHealth struct {
+	Name     string `json:"name"`
+	Phase    string `json:"phase"`
+	Ready    bool   `json:"ready"`
+	Restarts int32  `json:"restarts"`
+	Reason   string `json:"reason,omitempty"`
+	Node     string `json:"node,omitempty"`
+	Analyzed bool   `json:"analyzed,omitempty"`
+}
+
+// Healthy reports whether the pod is running, ready and not waiting or
+// crashing
+func (p PodHealth) Healthy() bool {
+	return p.Ready && p.Phase == "Running" && p.Reason == ""
+}
+
+// Admission failure categories
+const (
+	AdmissionPodSecurity     = "pod_security"
+	AdmissionWebhook         = "webhook"
+	AdmissionQuota           = "quota"
+	AdmissionSecurityContext = "security_context"
+	AdmissionOther           = "other"
+)
+
+// AdmissionContext explains why pods could not be created or started, as
+// seen from the controller side. PodMissing is set when the analyzed pod
+// does not exist at all.
+type AdmissionContext struct {
+	PodMissing       bool               `json:"pod_missing,omitempty"`
+	PodSecurity      map[string]string  `json:"pod_security,omitempty"`
+	PodSecurityError string             `json:"pod_security_error,omitempty"`
+	Failures         []AdmissionFailure `json:"failures"`
+}
+
+type AdmissionFailure struct {
+	Kind     string    `json:"kind"`
+	Name     string    `json:"name"`
+	Category string    `json:"category"`
+	Reason   string    `json:"reason"`
+	Message  string    `json:"message"`
+	Count    int32     `json:"count,omitempty"`
+	LastSeen time.Time `json:"last_seen"`
+}
+
+// Probe kinds
+const (
+	ProbeDNS = "dns"
+	ProbeTCP = "tcp"
+)
+
+// ProbeReport holds live connectivity checks run from inside the pod
+type ProbeReport struct {
+	Container string        `json:"container"`
+	Image     string        `json:"image"`
+	Results   []ProbeResult `json:"results"`
+}
+
+type ProbeResult struct {
+	Kind   string `json:"kind"`
+	Target string `json:"target"`
+	OK     bool   `json:"ok"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// Failed returns the number of failed checks
+func (r *ProbeReport) Failed() int {
+	n := 0
+	for _, res := range r.Results {
+		if !res.OK {
+			n++
+		}
+	}
+	return n
+}
+
+// OOM kill classifications
+const (
+	OOMCgroupLimit  = "cgroup_limit"
+	OOMNodePressure = "node_pressure"
+	OOMUnknown      = "unknown"
+)
+
+// OOMContext correlates an OOM kill or eviction with the state of the node
+// the pod ran on
+type OOMContext struct {
+	Container            string       `json:"container,omitempty"`
+	Evicted              bool         `json:"evicted,omitempty"`
+	Message              string       `json:"message,omitempty"`
+	KilledAt             time.Time    `json:"killed_at"`
+	MemoryLimit          string       `json:"memory_limit,omitempty"`
+	MemoryRequest        string       `json:"memory_request,omitempty"`
+	NodeName             string       `json:"node_name,omitempty"`
+	NodeMemoryPressure   bool         `json:"node_memory_pressure"`
+	NodeAllocatableBytes int64        `json:"node_allocatable_bytes,omitempty"`
+	NodeAvailableBytes   int64        `json:"node_available_bytes,omitempty"`
+	NodeWorkingSetBytes  int64        `json:"node_working_set_bytes,omitempty"`
+	NodeEvents           []EventEntry `json:"node_events,omitempty"`
+	SummaryError         string       `json:"summary_error,omitempty"`
+	Classification       string       `json:"classification"`
+}
+
+// AlertMetrics holds the recent values of the alert's PromQL expression
+type AlertMetrics struct {
+	Expression  string         `json:"expression"`
+	TotalSeries int            `json:"total_series"`
+	Series      []MetricSeries `json:"series"`
+}
+
+type MetricSeries struct {
+	Labels map[string]string `json:"labels"`
+	Points []MetricPoint     `json:"points"`
+}
+
+type MetricPoint struct {
+	Timestamp time.Time `json:"timestamp"`
+	Value     float64   `json:"value"`
+}
+
+// Min returns the smallest value of the series
+func (s MetricSeries) Min() float64 {
+	if len(s.Points) == 0 {
+		return 0
+	}
+	m := s.Points[0].Value
+	for _, p := range s.Points[1:] {
+		m = math.Min(m, p.Value)
+	}
+	return m
+}
+
+// Max returns the largest value of the series
+func (s MetricSeries) Max() float64 {
+	if len(s.Points) == 0 {
+		return 0
+	}
+	m := s.Points[0].Value
+	for _, p := range s.Points[1:] {
+		m = math.Max(m, p.Value)
+	}
+	return m
+}
+
+// Last returns the most recent value of the series
+func (s MetricSeries) Last() float64 {
+	if len(s.Points) == 0 {
+		return 0
+	}
+	return s.Points[len(s.Points)-1].Value
+}
+
+// LabelString renders the series labels in PromQL selector form
+func (s MetricSeries) LabelString() string {
+	keys := make([]string, 0, len(s.Labels))
+	for k := range s.Labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, fmt.Sprintf("%s=%q", k, s.Labels[k]))
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
+
+// RelatedAlert is another firing alert from the same namespace or
+// AlertManager group as the analyzed one
+type RelatedAlert struct {
+	Fingerprint string    `json:"fingerprint"`
+	AlertName   string    `json:"alert_name"`
+	Namespace   string    `json:"namespace"`
+	Pod         string    `json:"pod,omitempty"`
+	Severity    string    `json:"severity"`
+	Summary     string    `json:"summary,omitempty"`
+	StartsAt    time.Time `json:"starts_at"`
+}
+
+// RolloutInfo describes the progressive delivery state of the workload
+// owning the analyzed pod (Argo Rollouts or Flagger)
+type RolloutInfo struct {
+	Kind             string   `json:"kind"`
+	Name             string   `json:"name"`
+	Namespace        string   `json:"namespace"`
+	Phase            string   `json:"phase"`
+	Message          string   `json:"message,omitempty"`
+	CurrentStep      int      `json:"current_step"`
+	TotalSteps       int      `json:"total_steps,omitempty"`
+	CanaryWeight     int      `json:"canary_weight"`
+	FailedChecks     int      `json:"failed_checks,omitempty"`
+	Aborted          bool     `json:"aborted,omitempty"`
+	Primary          bool     `json:"primary,omitempty"`
+	RollbackCommands []string `json:"rollback_commands,omitempty"`
 }
 
 type AlertSummary struct {
-	Name      string    `json:"name"`
-	Severity  string    `json:"severity"`
-	Namespace string    `json:"namespace"`
-	Pod       string    `json:"pod"`
+	Name     string `json:"name"`
+	Severity string `json:"severity"`
+	// Cluster identifies the cluster the alert fired in, when known
+	Cluster   string `json:"cluster,omitempty"`
+	Namespace string `json:"namespace"`
+	Pod       string `json:"pod"`
+	// Workload is set instead of Pod for deployment-level analyses, as
+	// "<Kind>/<name>"
+	Workload  string    `json:"workload,omitempty"`
 	StartedAt time.Time `json:"started_at"`
 }
 
+// Target returns the analyzed pod, or the workload for deployment-level
+// analyses
+func (s AlertSummary) Target() string {
+	if s.Pod != "" {
+		return s.Pod
+	}
+	return s.Workload
+}
+
 type Analysis struct {
-	RootCause       string           `json:"root_cause"`
-	Confidence      string           `json:"confidence"`
-	Reasoning       string           `json:"reasoning"`
-	Timeline        []TimelineEvent  `json:"timeline"`
-	Evidence        Evidence         `json:"evidence"`
-	Recommendations []Recommendation `json:"recommendations"`
+	RootCause string `json:"root_cause"`
+	// Confidence is the level (high/medium/low) of ConfidenceScore, a
+	// 0-100 score computed from ConfidenceRubric
+	Confidence       string            `json:"confidence"`
+	ConfidenceScore  int               `json:"confidence_score"`
+	ConfidenceRubric *ConfidenceRubric `json:"confidence_rubric,omitempty"`
+	Reasoning        string            `json:"reasoning"`
+	Timeline         []TimelineEvent   `json:"timeline"`
+	Evidence         Evidence          `json:"evidence"`
+	Recommendations  []Recommendation  `json:"recommendations"`
+	Hypotheses       []Hypothesis      `json:"hypotheses,omitempty"`
+}
+
+// Confidence rubric weights; they sum to 100
+const (
+	RubricEvidenceCoverage    = 40
+	RubricCorroboratingEvents = 25
+	RubricSelfReported        = 35
+)
+
+// ConfidenceRubric breaks a confidence score down by criterion. Evidence
+// coverage rewards cited log lines found in the collected logs and
+// recommendations backed by evidence; corroborating events counts cited
+// events found among the collected events; self-reported is the certainty
+// the LLM (or rule) gave itself.
+type ConfidenceRubric struct {
+	EvidenceCoverage    int `json:"evidence_coverage"`
+	CorroboratingEvents int `json:"corroborating_events"`
+	SelfReported        int `json:"self_reported"`
+	// SelfReportedLevel and Certainty (0-100, if given) are what the LLM
+	// reported
+	SelfReportedLevel string `json:"self_reported_level,omitempty"`
+	Certainty         int    `json:"certainty,omitempty"`
+}
+
+// ConfidenceLevel maps a confidence score to a level
+func ConfidenceLevel(score int) string {
+	switch {
+	case score >= 70:
+		return "high"
+	case score >= 40:
+		return "medium"
+	default:
+		return "low"
+	}
+}
+
+// Hypothesis is one candidate root cause with its estimated probability
+// (0-1). Hypotheses are stored ranked by descending probability.
+type Hypothesis struct {
+	RootCause   string  `json:"root_cause"`
+	Probability float64 `json:"probability"`
+	Reasoning   string  `json:"reasoning,omitempty"`
+}
+
+// Percent returns the probability as a rounded percentage
+func (h Hypothesis) Percent() int {
+	return int(math.Round(h.Probability * 100))
 }
 
 type TimelineEvent struct {
@@ -51,10 +482,55 @@ type EventEntry struct {
 }
 
 type Recommendation struct {
-	Priority string `json:"priority"`
-	Action   string `json:"action"`
-	Details  string `json:"details,omitempty"`
-	Command  string `json:"command,omitempty"`
+	Priority     string        `json:"priority"`
+	Action       string        `json:"action"`
+	Details      string        `json:"details,omitempty"`
+	Command      string        `json:"command,omitempty"`
+	EvidenceRefs []EvidenceRef `json:"evidence_refs,omitempty"`
+	// Check is the validation result of Command
+	Check *CommandCheck `json:"check,omitempty"`
+}
+
+// CommandCheck records whether a recommended command parses and uses an
+// allowed kubectl verb
+type CommandCheck struct {
+	Valid bool   `json:"valid"`
+	Verb  string `json:"verb,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// DryRunResult reports whether a recommended command would apply cleanly
+type DryRunResult struct {
+	// Status is passed, failed or checked (only the target was looked up)
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+	Target  string `json:"target,omitempty"`
+}
+
+// Evidence reference types
+const (
+	EvidenceLog   = "log"
+	EvidenceEvent = "event"
+)
+
+// EvidenceRef points at the Evidence entry (by zero-based index into
+// Evidence.Logs or Evidence.Events) that supports a recommendation
+type EvidenceRef struct {
+	Type  string `json:"type"`
+	Index int    `json:"index"`
+}
+
+// Label returns the footnote label for the reference, e.g. "L1" or "E2"
+func (r EvidenceRef) Label() string {
+	if r.Type == EvidenceEvent {
+		return fmt.Sprintf("E%d", r.Index+1)
+	}
+	return fmt.Sprintf("L%d", r.Index+1)
+}
+
+// Anchor returns the HTML anchor of the referenced evidence entry
+func (r EvidenceRef) Anchor() string {
+	return fmt.Sprintf("%s-%d", r.Type, r.Index)
 }
 
 type CollectedData struct {
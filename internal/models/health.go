@@ -0,0 +1,61 @@
+package models
+
+import "time"
+
+// Namespace health levels
+const (
+	HealthHealthy  = "healthy"
+	HealthDegraded = "degraded"
+	HealthCritical = "critical"
+)
+
+// HealthReport is an LLM-summarized health digest of a namespace
+type HealthReport struct {
+	Namespace   string           `json:"namespace"`
+	GeneratedAt time.Time        `json:"generated_at"`
+	TimeRange   string           `json:"time_range"`
+	Model       string           `json:"model,omitempty"`
+	Status      string           `json:"status"`
+	Summary     string           `json:"summary"`
+	Workloads   []WorkloadHealth `json:"workloads"`
+	// OtherWarnings are recent Warning events not tied to a workload, such
+	// as volume or quota problems
+	OtherWarnings []WarningEvent `json:"other_warnings,omitempty"`
+	Redactions    []Redaction    `json:"redactions,omitempty"`
+	Stages        []Stage        `json:"stages,omitempty"`
+}
+
+// WorkloadHealth is the scan result of one workload. Issues are found by
+// the scan itself; Severity, Finding and Recommendation come from the LLM.
+type WorkloadHealth struct {
+	Kind           string         `json:"kind"`
+	Name           string         `json:"name"`
+	Desired        int32          `json:"desired"`
+	Ready          int32          `json:"ready"`
+	Restarts       int32          `json:"restarts"`
+	Pods           []PodHealth    `json:"pods,omitempty"`
+	Warnings       []WarningEvent `json:"warnings,omitempty"`
+	Issues         []string       `json:"issues,omitempty"`
+	Severity       string         `json:"severity,omitempty"`
+	Finding        string         `json:"finding,omitempty"`
+	Recommendation string         `json:"recommendation,omitempty"`
+}
+
+// Key identifies the workload as Kind/Name
+func (w WorkloadHealth) Key() string {
+	return w.Kind + "/" + w.Name
+}
+
+// Healthy reports whether the scan found no issues
+func (w WorkloadHealth) Healthy() bool {
+	return len(w.Issues) == 0
+}
+
+// WarningEvent is an aggregated Warning event
+type WarningEvent struct {
+	Object   string    `json:"object"`
+	Reason   string    `json:"reason"`
+	Message  string    `json:"message"`
+	Count    int32     `json:"count"`
+	LastSeen time.Time `json:"last_seen"`
+}
@@ -0,0 +1,12 @@
+package models
+
+// Link is a HAL link object
+type Link struct {
+	Href   string `json:"href"`
+	Method string `json:"method,omitempty"`
+	Type   string `json:"type,omitempty"`
+	Title  string `json:"title,omitempty"`
+}
+
+// Links maps a link relation to its target, serialized as HAL "_links"
+type Links map[string]Link
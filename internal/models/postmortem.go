@@ -0,0 +1,67 @@
+package models
+
+import "time"
+
+// Postmortem periods
+const (
+	PostmortemDaily  = "daily"
+	PostmortemWeekly = "weekly"
+)
+
+// PostmortemIncident is a stored analysis summarized for a postmortem
+type PostmortemIncident struct {
+	ID        int64     `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	AlertName string    `json:"alert_name"`
+	Namespace string    `json:"namespace"`
+	// Target is the analyzed pod or "<Kind>/<name>" workload
+	Target          string   `json:"target"`
+	Severity        string   `json:"severity"`
+	RootCause       string   `json:"root_cause"`
+	Confidence      string   `json:"confidence"`
+	Recurring       bool     `json:"recurring,omitempty"`
+	Recommendations []string `json:"recommendations,omitempty"`
+}
+
+// PostmortemReport summarizes the incidents analyzed in a time window:
+// recurring causes, affected services and action items
+type PostmortemReport struct {
+	Period           string              `json:"period"`
+	From             time.Time           `json:"from"`
+	To               time.Time           `json:"to"`
+	Namespace        string              `json:"namespace,omitempty"`
+	GeneratedAt      time.Time           `json:"generated_at"`
+	Model            string              `json:"model,omitempty"`
+	Incidents        int                 `json:"incidents"`
+	Summary          string              `json:"summary"`
+	RecurringCauses  []PostmortemCause   `json:"recurring_causes"`
+	AffectedServices []PostmortemService `json:"affected_services"`
+	ActionItems      []PostmortemAction  `json:"action_items"`
+	Redactions       []Redaction         `json:"redactions,omitempty"`
+	Stages           []Stage             `json:"stages,omitempty"`
+}
+
+// PostmortemCause is a root cause shared by several analyses
+type PostmortemCause struct {
+	Cause       string  `json:"cause"`
+	Count       int     `json:"count"`
+	AnalysisIDs []int64 `json:"analysis_ids"`
+}
+
+// PostmortemService counts the incidents of a service, the workload name
+// its pods were analyzed under
+type PostmortemService struct {
+	Namespace string `json:"namespace"`
+	Service   string `json:"service"`
+	Incidents int    `json:"incidents"`
+	Critical  int    `json:"critical"`
+	// Alerts are the distinct alert names, most frequent first
+	Alerts []string `json:"alerts"`
+}
+
+// PostmortemAction is a follow-up to prevent the incidents from recurring
+type PostmortemAction struct {
+	Priority string `json:"priority"`
+	Action   string `json:"action"`
+	Owner    string `json:"owner,omitempty"`
+}
@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 // AlertManagerWebhook represents the standard AlertManager webhook payload
 type AlertManagerWebhook struct {
 	Version           string            `json:"version"`
@@ -21,23 +23,69 @@ type WebhookAnalysisResponse struct {
 	Failed   int                   `json:"failed"`
 	Results  []AlertAnalysisResult `json:"results"`
 	Errors   []AlertAnalysisError  `json:"errors,omitempty"`
+	// Skipped are alerts that were deliberately not analyzed
+	Skipped []SkippedAlert `json:"skipped,omitempty"`
+	// Incidents are groups of alerts on one workload analyzed together
+	Incidents []IncidentResult `json:"incidents,omitempty"`
 }
 
-// AlertAnalysisResult represents the analysis result for a single alert
-type AlertAnalysisResult struct {
-	Fingerprint   string         `json:"fingerprint"`
-	AlertName     string         `json:"alert_name"`
+// IncidentResult is the rolled-up analysis of alerts firing on pods of one
+// workload. Each alert also has a result pointing at it.
+type IncidentResult struct {
+	ID            int64          `json:"id,omitempty"`
 	Namespace     string         `json:"namespace"`
-	Pod           string         `json:"pod,omitempty"`
+	Workload      string         `json:"workload"`
 	Severity      string         `json:"severity"`
-	Status        string         `json:"status"`
+	Alerts        []string       `json:"alerts"`
+	Pods          []string       `json:"pods"`
 	Analysis      *Analysis      `json:"analysis"`
 	CollectedData *CollectedData `json:"collected_data"`
+	Links         Links          `json:"_links,omitempty"`
+}
+
+// AlertAnalysisResult represents the analysis result for a single alert
+type AlertAnalysisResult struct {
+	ID          int64  `json:"id,omitempty"`
+	Fingerprint string `json:"fingerprint"`
+	AlertName   string `json:"alert_name"`
+	Namespace   string `json:"namespace"`
+	Pod         string `json:"pod,omitempty"`
+	Severity    string `json:"severity"`
+	Status      string `json:"status"`
+	// Analysis and CollectedData are left out for alerts of an incident,
+	// whose analysis is in the incident result
+	Analysis      *Analysis      `json:"analysis,omitempty"`
+	CollectedData *CollectedData `json:"collected_data,omitempty"`
+	// Incident is the "<namespace>/<workload>" incident the alert was
+	// analyzed with
+	Incident string `json:"incident,omitempty"`
+	// Recurrence counts earlier analyses of the alert on the pod
+	Recurrence *Recurrence `json:"recurrence,omitempty"`
+	// Deduplicated is set when the analysis of an identical request was
+	// reused instead of running a new one
+	Deduplicated bool `json:"deduplicated,omitempty"`
+	// Replayed is set when an earlier delivery of the alert (same
+	// fingerprint and start time) was analyzed and that analysis is returned
+	Replayed bool  `json:"replayed,omitempty"`
+	Links    Links `json:"_links,omitempty"`
 }
 
 // AlertAnalysisError represents an error that occurred during alert analysis
+// SkippedAlert is an alert left out of the analysis, and why
+type SkippedAlert struct {
+	Fingerprint string `json:"fingerprint"`
+	AlertName   string `json:"alert_name"`
+	Reason      string `json:"reason"`
+}
+
 type AlertAnalysisError struct {
 	Fingerprint string `json:"fingerprint"`
 	AlertName   string `json:"alert_name"`
 	Error       string `json:"error"`
+	// ErrorClass is the collector error class (not_found, forbidden,
+	// timeout, connection_refused, unknown) of a failed analysis
+	ErrorClass string `json:"error_class,omitempty"`
+	// RetryAfter is set when the target is backing off after repeated
+	// failures and the alert was not analyzed
+	RetryAfter *time.Time `json:"retry_after,omitempty"`
 }
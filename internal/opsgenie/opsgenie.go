@@ -0,0 +1,169 @@
+// Package opsgenie turns Opsgenie outgoing webhooks into alerts and
+// annotates Opsgenie alerts with analysis results: a note with the summary
+// and a tag with the root cause category.
+package opsgenie
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/emirozbir/micro-sre/internal/collectors"
+	"github.com/emirozbir/micro-sre/internal/config"
+	"github.com/emirozbir/micro-sre/internal/models"
+)
+
+// ActionCreate is the only webhook action that starts an analysis
+const ActionCreate = "Create"
+
+// source names hepsre in the alert activity log
+const source = "hepsre"
+
+// Webhook is the payload of the Opsgenie webhook integration
+// (https://support.atlassian.com/opsgenie/docs/integrate-opsgenie-with-outgoing-webhook/)
+type Webhook struct {
+	Action          string `json:"action"`
+	IntegrationName string `json:"integrationName"`
+	Alert           Alert  `json:"alert"`
+}
+
+// Alert is the Opsgenie alert of a webhook. Details are the alert's extra
+// properties, where the Kubernetes metadata is expected.
+type Alert struct {
+	AlertID   string            `json:"alertId"`
+	TinyID    string            `json:"tinyId"`
+	Alias     string            `json:"alias"`
+	Message   string            `json:"message"`
+	Entity    string            `json:"entity"`
+	Priority  string            `json:"priority"`
+	Tags      []string          `json:"tags"`
+	Details   map[string]string `json:"details"`
+	CreatedAt int64             `json:"createdAt"`
+}
+
+// detailKeys are the detail keys read into alert labels, in order of
+// preference
+var detailKeys = map[string][]string{
+	"namespace": {"namespace", "k8s_namespace", "kubernetes_namespace"},
+	"pod":       {"pod", "pod_name", "k8s_pod", "kubernetes_pod"},
+	"alertname": {"alertname", "alert_name", "alert"},
+	"severity":  {"severity"},
+}
+
+// prioritySeverities maps Opsgenie priorities to alert severities
+var prioritySeverities = map[string]string{
+	"P1": "critical",
+	"P2": "critical",
+	"P3": "warning",
+	"P4": "info",
+	"P5": "info",
+}
+
+// ToAlert converts an Opsgenie alert into an alert. Kubernetes metadata is
+// taken from the details; the message names the alert when the details do
+// not, and the priority stands in for a missing severity. The alert is
+// fingerprinted by its Opsgenie ID, so redeliveries are recognized.
+func (a Alert) ToAlert() models.Alert {
+	details := map[string]string{}
+	for k, v := range a.Details {
+		details[strings.ToLower(k)] = v
+	}
+
+	labels := map[string]string{}
+	for label, keys := range detailKeys {
+		for _, key := range keys {
+			if v := details[key]; v != "" {
+				labels[label] = v
+				break
+			}
+		}
+	}
+	if labels["alertname"] == "" {
+		labels["alertname"] = a.Message
+	}
+	if labels["severity"] == "" {
+		labels["severity"] = prioritySeverities[a.Priority]
+	}
+
+	startsAt := time.UnixMilli(a.CreatedAt)
+	if a.CreatedAt == 0 {
+		startsAt = time.Now()
+	}
+	return models.Alert{
+		Labels: labels,
+		Annotations: map[string]string{
+			"summary":           a.Message,
+			"opsgenie_alert_id": a.AlertID,
+			"opsgenie_tiny_id":  a.TinyID,
+			"opsgenie_alias":    a.Alias,
+		},
+		StartsAt:    startsAt,
+		Status:      "firing",
+		Fingerprint: "opsgenie-" + a.AlertID,
+	}
+}
+
+// Client annotates Opsgenie alerts through the Alert API
+type Client struct {
+	apiURL string
+	apiKey string
+	client *http.Client
+}
+
+// New returns an Opsgenie client, or nil if no API key is configured
+func New(cfg config.OpsgenieConfig) *Client {
+	if cfg.APIKey == "" {
+		return nil
+	}
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	return &Client{
+		apiURL: strings.TrimSuffix(cfg.APIURL, "/"),
+		apiKey: cfg.APIKey,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// AddNote adds a note to an alert
+func (c *Client) AddNote(ctx context.Context, alertID, note string) error {
+	return c.post(ctx, alertID, "notes", map[string]any{"source": source, "note": note})
+}
+
+// AddTags adds tags to an alert
+func (c *Client) AddTags(ctx context.Context, alertID string, tags ...string) error {
+	return c.post(ctx, alertID, "tags", map[string]any{"source": source, "tags": tags})
+}
+
+// post sends an action on an alert. Opsgenie accepts actions with 202 and
+// applies them asynchronously.
+func (c *Client) post(ctx context.Context, alertID, action string, payload map[string]any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode opsgenie %s: %w", action, err)
+	}
+	target := fmt.Sprintf("%s/v2/alerts/%s/%s?identifierType=id", c.apiURL, url.PathEscape(alertID), action)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "GenieKey "+c.apiKey)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post opsgenie %s: %w", action, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return &collectors.StatusError{Service: "opsgenie", Code: resp.StatusCode}
+	}
+	return nil
+}
@@ -0,0 +1,212 @@
+// Package pagerduty turns PagerDuty V3 webhooks into alerts, verifies their
+// signatures and posts analysis results back as incident notes.
+package pagerduty
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/emirozbir/micro-sre/internal/collectors"
+	"github.com/emirozbir/micro-sre/internal/config"
+	"github.com/emirozbir/micro-sre/internal/models"
+)
+
+// EventIncidentTriggered is the only event type that starts an analysis
+const EventIncidentTriggered = "incident.triggered"
+
+// ErrBadSignature is returned for requests that were not signed with the
+// webhook subscription's secret
+var ErrBadSignature = errors.New("invalid pagerduty signature")
+
+// Webhook is a V3 webhook payload
+// (https://developer.pagerduty.com/docs/webhooks-overview)
+type Webhook struct {
+	Event Event `json:"event"`
+}
+
+// Event is the event of a V3 webhook
+type Event struct {
+	ID           string    `json:"id"`
+	EventType    string    `json:"event_type"`
+	ResourceType string    `json:"resource_type"`
+	OccurredAt   time.Time `json:"occurred_at"`
+	Data         Incident  `json:"data"`
+}
+
+// Incident is the incident an event is about. Body holds the custom
+// details of the triggering alert when PagerDuty includes them.
+type Incident struct {
+	ID      string `json:"id"`
+	Type    string `json:"type"`
+	Title   string `json:"title"`
+	HTMLURL string `json:"html_url"`
+	Status  string `json:"status"`
+	Urgency string `json:"urgency"`
+	Service struct {
+		ID      string `json:"id"`
+		Summary string `json:"summary"`
+	} `json:"service"`
+	Body struct {
+		Details any `json:"details"`
+	} `json:"body"`
+	CustomDetails any `json:"custom_details"`
+}
+
+// detailKeys are the custom detail keys read into alert labels, in order of
+// preference
+var detailKeys = map[string][]string{
+	"namespace": {"namespace", "k8s_namespace", "kubernetes_namespace"},
+	"pod":       {"pod", "pod_name", "k8s_pod", "kubernetes_pod"},
+	"alertname": {"alertname", "alert_name", "alert"},
+	"severity":  {"severity"},
+}
+
+// Alert converts the incident of an event into an alert. Kubernetes
+// metadata is taken from the custom details; the incident title names the
+// alert when the details do not, and its urgency stands in for a missing
+// severity. The alert is fingerprinted by the incident, so redeliveries
+// are recognized.
+func (e Event) Alert() models.Alert {
+	details := map[string]any{}
+	for _, d := range []any{e.Data.Body.Details, e.Data.CustomDetails} {
+		if m, ok := d.(map[string]any); ok {
+			for k, v := range m {
+				details[strings.ToLower(k)] = v
+			}
+		}
+	}
+
+	labels := map[string]string{}
+	for label, keys := range detailKeys {
+		for _, key := range keys {
+			if s, ok := details[key].(string); ok && s != "" {
+				labels[label] = s
+				break
+			}
+		}
+	}
+	if labels["alertname"] == "" {
+		labels["alertname"] = e.Data.Title
+	}
+	if labels["severity"] == "" {
+		labels["severity"] = map[string]string{"high": "critical", "low": "warning"}[e.Data.Urgency]
+	}
+
+	startsAt := e.OccurredAt
+	if startsAt.IsZero() {
+		startsAt = time.Now()
+	}
+	return models.Alert{
+		Labels: labels,
+		Annotations: map[string]string{
+			"summary":                e.Data.Title,
+			"pagerduty_incident":     e.Data.ID,
+			"pagerduty_service":      e.Data.Service.Summary,
+			"pagerduty_event_id":     e.ID,
+			"pagerduty_incident_url": e.Data.HTMLURL,
+		},
+		StartsAt:     startsAt,
+		Status:       "firing",
+		Fingerprint:  "pagerduty-" + e.Data.ID,
+		GeneratorURL: e.Data.HTMLURL,
+	}
+}
+
+// Client verifies webhooks and posts incident notes
+type Client struct {
+	apiURL        string
+	apiToken      string
+	fromEmail     string
+	webhookSecret string
+	client        *http.Client
+}
+
+// New returns a PagerDuty client, or nil if neither a webhook secret nor an
+// API token is configured
+func New(cfg config.PagerDutyConfig) *Client {
+	if cfg.WebhookSecret == "" && cfg.APIToken == "" {
+		return nil
+	}
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	return &Client{
+		apiURL:        strings.TrimSuffix(cfg.APIURL, "/"),
+		apiToken:      cfg.APIToken,
+		fromEmail:     cfg.FromEmail,
+		webhookSecret: cfg.WebhookSecret,
+		client:        &http.Client{Timeout: timeout},
+	}
+}
+
+// CanVerify reports whether a webhook secret is configured
+func (c *Client) CanVerify() bool {
+	return c != nil && c.webhookSecret != ""
+}
+
+// CanPostNotes reports whether an API token and the requester email the
+// notes API requires are configured
+func (c *Client) CanPostNotes() bool {
+	return c != nil && c.apiToken != "" && c.fromEmail != ""
+}
+
+// Verify checks the X-PagerDuty-Signature of a request body. The header
+// lists one "v1=<hex>" signature per active secret, so any match is
+// accepted.
+func (c *Client) Verify(header http.Header, body []byte) error {
+	if !c.CanVerify() {
+		return ErrBadSignature
+	}
+	mac := hmac.New(sha256.New, []byte(c.webhookSecret))
+	mac.Write(body)
+	expected := "v1=" + hex.EncodeToString(mac.Sum(nil))
+
+	ok := false
+	for _, signature := range strings.Split(header.Get("X-PagerDuty-Signature"), ",") {
+		if hmac.Equal([]byte(expected), []byte(strings.TrimSpace(signature))) {
+			ok = true
+		}
+	}
+	if !ok {
+		return ErrBadSignature
+	}
+	return nil
+}
+
+// AddNote adds a note to an incident
+func (c *Client) AddNote(ctx context.Context, incidentID, content string) error {
+	body, err := json.Marshal(map[string]any{"note": map[string]string{"content": content}})
+	if err != nil {
+		return fmt.Errorf("failed to encode pagerduty note: %w", err)
+	}
+	url := fmt.Sprintf("%s/incidents/%s/notes", c.apiURL, incidentID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/vnd.pagerduty+json;version=2")
+	req.Header.Set("Authorization", "Token token="+c.apiToken)
+	req.Header.Set("From", c.fromEmail)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post pagerduty note: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return &collectors.StatusError{Service: "pagerduty", Code: resp.StatusCode}
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+// Package pushgateway pushes incident records to a Prometheus Pushgateway so
+// incident frequency can be charted next to SLO dashboards. Each label set
+// (namespace, category, severity) is one Pushgateway group, which keeps the
+// number of series bounded however many analyses run.
+package pushgateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/emirozbir/micro-sre/internal/collectors"
+	"github.com/emirozbir/micro-sre/internal/config"
+)
+
+// Incident is the record pushed after an analysis is stored. Stored is the
+// number of analyses stored for its label set, which drops when retention
+// deletes some, so it is pushed as a gauge.
+type Incident struct {
+	Namespace string
+	Category  string
+	Severity  string
+	Timestamp time.Time
+	Stored    int
+}
+
+// Pusher pushes incident records to a Pushgateway
+type Pusher struct {
+	url    string
+	job    string
+	client *http.Client
+}
+
+// New returns a pusher for the configured Pushgateway, or nil if none is
+// configured
+func New(cfg config.PushgatewayConfig) *Pusher {
+	if cfg.URL == "" {
+		return nil
+	}
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	return &Pusher{
+		url:    strings.TrimRight(cfg.URL, "/"),
+		job:    cfg.Job,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// Push replaces the group of the incident's label set with its current
+// number of stored analyses and the time of the latest incident
+func (p *Pusher) Push(ctx context.Context, inc Incident) error {
+	var body bytes.Buffer
+	fmt.Fprintf(&body, "# TYPE hepsre_incidents_stored gauge\nhepsre_incidents_stored %d\n", inc.Stored)
+	fmt.Fprintf(&body, "# TYPE hepsre_incident_last_timestamp_seconds gauge\nhepsre_incident_last_timestamp_seconds %d\n", inc.Timestamp.Unix())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, p.groupURL(inc), &body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to push to pushgateway: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return &collectors.StatusError{Service: "pushgateway", Code: resp.StatusCode}
+	}
+	return nil
+}
+
+// groupURL returns the URL of the incident's group. Label values are base64
+// encoded, as they may contain slashes.
+func (p *Pusher) groupURL(inc Incident) string {
+	labels := []string{
+		"job", p.job,
+		"namespace", inc.Namespace,
+		"category", inc.Category,
+		"severity", inc.Severity,
+	}
+	var sb strings.Builder
+	sb.WriteString(p.url + "/metrics")
+	for i := 0; i < len(labels); i += 2 {
+		sb.WriteString("/" + labels[i] + "@base64/" + encodeLabel(labels[i+1]))
+	}
+	return sb.String()
+}
+
+func encodeLabel(value string) string {
+	if value == "" {
+		return "="
+	}
+	return base64.URLEncoding.EncodeToString([]byte(value))
+}
@@ -0,0 +1,218 @@
+// Package redact scrubs secrets and personal data from text before it is
+// embedded in an LLM prompt. Each detector replaces what it finds with a
+// "[REDACTED:<detector>]" marker and the number of replacements is reported
+// so analyses can record what was removed.
+package redact
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/emirozbir/micro-sre/internal/config"
+	"github.com/emirozbir/micro-sre/internal/models"
+)
+
+// Built-in detector names
+const (
+	DetectorTokens      = "tokens"
+	DetectorJWT         = "jwt"
+	DetectorAWSKey      = "aws_key"
+	DetectorEmail       = "email"
+	DetectorIP          = "ip"
+	DetectorEnvValue    = "env_value"
+	DetectorSecretValue = "secret_value"
+)
+
+// minEnvValueLength skips short env values such as ports, flags and
+// replica counts that would redact unrelated text
+const minEnvValueLength = 8
+
+// minSecretValueLength is lower: a short Secret value is still a secret
+const minSecretValueLength = 4
+
+type detector struct {
+	name string
+	re   *regexp.Regexp
+	// keep is the number of leading capture groups kept in the output, so
+	// that "password=" survives and only the value is replaced
+	keep int
+}
+
+// builtin detectors, in the order they run. JWTs and AWS keys run before the
+// generic token pattern so they are reported under their own name; the
+// token pattern skips values that already are a redaction marker.
+var builtin = []detector{
+	{name: DetectorJWT, re: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{5,}\.[A-Za-z0-9_-]{5,}\.[A-Za-z0-9_-]+`)},
+	{name: DetectorAWSKey, re: regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{name: DetectorTokens, re: regexp.MustCompile(`(?i)(\bbearer\s+)[A-Za-z0-9._~+/-]{8,}=*`), keep: 1},
+	{name: DetectorTokens, re: regexp.MustCompile(`(?i)(\b(?:password|passwd|pwd|secret|token|api[_-]?key|access[_-]?key|client[_-]?secret)["']?\s*[:=]\s*["']?)[^\s"',;&\[][^\s"',;&]*`), keep: 1},
+	{name: DetectorEmail, re: regexp.MustCompile(`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}\b`)},
+	{name: DetectorIP, re: regexp.MustCompile(`\b(?:(?:25[0-5]|2[0-4]\d|1?\d?\d)\.){3}(?:25[0-5]|2[0-4]\d|1?\d?\d)\b`)},
+}
+
+// Redactor removes sensitive values from prompt text
+type Redactor struct {
+	enabled      bool
+	detectors    []detector
+	envValues    bool
+	secretValues bool
+	// secrets are the Secret values set by WithSecrets, longest first
+	secrets []string
+}
+
+// New builds a redactor from the configured detectors and patterns
+func New(cfg config.RedactionConfig) (*Redactor, error) {
+	r := &Redactor{enabled: cfg.Enabled, envValues: cfg.EnvValues, secretValues: cfg.SecretValues}
+	if !cfg.Enabled {
+		return r, nil
+	}
+
+	enabled := map[string]bool{}
+	for _, name := range cfg.Detectors {
+		enabled[name] = true
+	}
+	for name := range enabled {
+		if !isBuiltin(name) {
+			return nil, fmt.Errorf("unknown redaction detector %q", name)
+		}
+	}
+	for _, d := range builtin {
+		if len(enabled) == 0 || enabled[d.name] {
+			r.detectors = append(r.detectors, d)
+		}
+	}
+
+	for _, p := range cfg.Patterns {
+		if p.Name == "" {
+			return nil, fmt.Errorf("redaction pattern %q has no name", p.Regex)
+		}
+		re, err := regexp.Compile(p.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compile redaction pattern %s: %w", p.Name, err)
+		}
+		r.detectors = append(r.detectors, detector{name: p.Name, re: re})
+	}
+	return r, nil
+}
+
+func isBuiltin(name string) bool {
+	for _, d := range builtin {
+		if d.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactsSecrets reports whether the values of the Secrets referenced by the
+// analyzed pods should be looked up and passed to WithSecrets
+func (r *Redactor) RedactsSecrets() bool {
+	return r != nil && r.enabled && r.secretValues
+}
+
+// WithSecrets returns a copy of the redactor that also removes the given
+// Secret values; the receiver is left unchanged so it can be shared
+func (r *Redactor) WithSecrets(values []string) *Redactor {
+	if !r.RedactsSecrets() || len(values) == 0 {
+		return r
+	}
+	out := *r
+	out.secrets = nil
+	seen := map[string]bool{}
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if len(v) < minSecretValueLength || seen[v] || trivial(v) {
+			continue
+		}
+		seen[v] = true
+		out.secrets = append(out.secrets, v)
+	}
+	sort.Slice(out.secrets, func(i, j int) bool { return len(out.secrets[i]) > len(out.secrets[j]) })
+	return &out
+}
+
+// Redact returns text with sensitive values replaced, and how many values
+// each detector removed. Secret values and the env var values of pods are
+// redacted first.
+func (r *Redactor) Redact(text string, pods ...*corev1.Pod) (string, []models.Redaction) {
+	if r == nil || !r.enabled {
+		return text, nil
+	}
+
+	counts := map[string]int{}
+	for _, value := range r.secrets {
+		if n := strings.Count(text, value); n > 0 {
+			text = strings.ReplaceAll(text, value, marker(DetectorSecretValue))
+			counts[DetectorSecretValue] += n
+		}
+	}
+	if r.envValues {
+		for _, value := range envValues(pods) {
+			if n := strings.Count(text, value); n > 0 {
+				text = strings.ReplaceAll(text, value, marker(DetectorEnvValue))
+				counts[DetectorEnvValue] += n
+			}
+		}
+	}
+
+	for _, d := range r.detectors {
+		text = d.re.ReplaceAllStringFunc(text, func(match string) string {
+			counts[d.name]++
+			if d.keep == 0 {
+				return marker(d.name)
+			}
+			groups := d.re.FindStringSubmatch(match)
+			return strings.Join(groups[1:d.keep+1], "") + marker(d.name)
+		})
+	}
+
+	var out []models.Redaction
+	for name, n := range counts {
+		out = append(out, models.Redaction{Detector: name, Count: n})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Detector < out[j].Detector })
+	return text, out
+}
+
+func marker(name string) string {
+	return "[REDACTED:" + name + "]"
+}
+
+// envValues returns the literal env var values of the pods' containers,
+// longest first so that a value containing another is replaced whole
+func envValues(pods []*corev1.Pod) []string {
+	seen := map[string]bool{}
+	var values []string
+	for _, pod := range pods {
+		if pod == nil {
+			continue
+		}
+		containers := append(append([]corev1.Container(nil), pod.Spec.InitContainers...), pod.Spec.Containers...)
+		for _, c := range containers {
+			for _, env := range c.Env {
+				v := env.Value
+				if len(v) < minEnvValueLength || seen[v] || trivial(v) {
+					continue
+				}
+				seen[v] = true
+				values = append(values, v)
+			}
+		}
+	}
+	sort.Slice(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+	return values
+}
+
+// trivial reports values that are never secret: numbers and booleans
+func trivial(v string) bool {
+	if _, err := strconv.ParseFloat(v, 64); err == nil {
+		return true
+	}
+	_, err := strconv.ParseBool(v)
+	return err == nil
+}
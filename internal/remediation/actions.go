@@ -0,0 +1,50 @@
+package remediation
+
+import (
+	"strconv"
+)
+
+// Remediation actions: the recommendation classes that can be executed
+// after approval
+const (
+	// ActionRestart is a rollout restart of a deployment, statefulset or
+	// daemonset
+	ActionRestart = "restart"
+	// ActionScale scales a deployment or statefulset
+	ActionScale = "scale"
+	// ActionDeletePod deletes a single pod, to restart it or to clear a pod
+	// stuck terminating (--force --grace-period=0)
+	ActionDeletePod = "delete_pod"
+)
+
+// Actions lists every remediation action
+var Actions = []string{ActionRestart, ActionScale, ActionDeletePod}
+
+// Classify returns the remediation action of a validated command, or ""
+// when the command cannot be executed automatically
+func Classify(cmd *Command) string {
+	res, ok := lookupResource(cmd.Kind)
+	if !ok || cmd.Name == "" || !res.namespaced {
+		return ""
+	}
+	switch res.gvr.Resource {
+	case "deployments", "statefulsets", "daemonsets":
+		if cmd.Verb == "rollout restart" {
+			return ActionRestart
+		}
+		if cmd.Verb == "scale" && res.gvr.Resource != "daemonsets" {
+			return ActionScale
+		}
+	case "pods":
+		if cmd.Verb == "delete" {
+			return ActionDeletePod
+		}
+	}
+	return ""
+}
+
+// Replicas returns the --replicas value of a scale command
+func Replicas(cmd *Command) int {
+	n, _ := strconv.Atoi(cmd.Flags["--replicas"])
+	return n
+}
@@ -0,0 +1,367 @@
+// Package remediation checks the kubectl commands recommended by analyses:
+// it parses them, validates them against an allowlist of verbs, and asks
+// the API server to dry-run them.
+package remediation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidCommand marks commands that cannot be parsed or are not allowed
+var ErrInvalidCommand = errors.New("invalid command")
+
+// Verbs that only read cluster state and need no dry-run
+var readOnlyVerbs = map[string]bool{
+	"get": true, "describe": true, "logs": true, "top": true,
+	"events": true, "explain": true, "api-resources": true,
+}
+
+// Subcommands of the verbs that take one
+var subcommands = map[string][]string{
+	"rollout": {"restart", "undo", "status", "history", "pause", "resume"},
+	"set":     {"image", "resources", "env"},
+	// The Argo Rollouts kubectl plugin
+	"argo rollouts": {"abort", "undo", "promote", "retry", "pause", "status", "get"},
+	"argo":          {"rollouts"},
+}
+
+// valueFlags take a value when it is not given with "="; other flags are
+// boolean
+var valueFlags = map[string]bool{
+	"-n": true, "--namespace": true, "-p": true, "--patch": true,
+	"--type": true, "--replicas": true, "-c": true, "--containers": true,
+	"--container": true, "--limits": true, "--requests": true, "-l": true,
+	"--selector": true, "--to-revision": true, "-o": true, "--output": true,
+	"--grace-period": true, "--timeout": true, "--tail": true, "--since": true,
+	"--field-selector": true,
+}
+
+// Flags that point the command at other files or clusters than the ones
+// that were analyzed
+var rejectedFlags = map[string]bool{
+	"-f": true, "--filename": true, "-k": true, "--kustomize": true,
+	"--context": true, "--kubeconfig": true, "--cluster": true,
+	"--server": true, "-s": true, "--as": true, "--as-group": true,
+}
+
+// Command is a parsed kubectl command
+type Command struct {
+	// Verb is the kubectl verb, with its subcommand if it has one
+	// (e.g. "rollout restart")
+	Verb string
+	// Kind and Name identify the target object, if the command has one
+	Kind string
+	Name string
+	// Namespace is the -n flag, if given
+	Namespace string
+	// Args are the positional arguments after the target
+	Args  []string
+	Flags map[string]string
+}
+
+// ReadOnly reports whether the command only reads cluster state
+func (c *Command) ReadOnly() bool {
+	switch c.Verb {
+	case "rollout status", "rollout history", "argo rollouts status", "argo rollouts get":
+		return true
+	}
+	return readOnlyVerbs[c.Verb]
+}
+
+// Target returns the target as "<kind>/<name>"
+func (c *Command) Target() string {
+	if c.Name == "" {
+		return c.Kind
+	}
+	return c.Kind + "/" + c.Name
+}
+
+// Validate parses a recommended command and checks it against the allowed
+// verbs and the syntax of the verb. Errors wrap ErrInvalidCommand.
+func Validate(command string, allowedVerbs []string) (*Command, error) {
+	cmd, err := parse(command)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCommand, err)
+	}
+	if !verbAllowed(cmd.Verb, allowedVerbs) {
+		return nil, fmt.Errorf("%w: verb %q is not allowed", ErrInvalidCommand, cmd.Verb)
+	}
+	if err := checkSyntax(cmd); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCommand, err)
+	}
+	return cmd, nil
+}
+
+// verbAllowed matches the verb, or the verb without its subcommand
+func verbAllowed(verb string, allowed []string) bool {
+	base, _, _ := strings.Cut(verb, " ")
+	for _, v := range allowed {
+		if v == verb || v == base {
+			return true
+		}
+	}
+	return false
+}
+
+// placeholderPattern matches template placeholders such as <pod-name>
+var placeholderPattern = regexp.MustCompile(`<[A-Za-z0-9_.-]+>`)
+
+func parse(command string) (*Command, error) {
+	if p := placeholderPattern.FindString(command); p != "" {
+		return nil, fmt.Errorf("command contains placeholder %s", p)
+	}
+	words, err := split(strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(command), "$")))
+	if err != nil {
+		return nil, err
+	}
+	if len(words) == 0 || words[0] != "kubectl" {
+		return nil, fmt.Errorf("not a kubectl command")
+	}
+
+	cmd := &Command{Flags: map[string]string{}}
+	var positional []string
+	for i := 1; i < len(words); i++ {
+		word := words[i]
+		if !strings.HasPrefix(word, "-") || word == "-" {
+			positional = append(positional, word)
+			continue
+		}
+
+		name, value, hasValue := strings.Cut(word, "=")
+		// -nfoo is -n foo
+		if !hasValue && len(name) > 2 && !strings.HasPrefix(name, "--") && valueFlags[name[:2]] {
+			name, value, hasValue = name[:2], name[2:], true
+		}
+		if rejectedFlags[name] {
+			return nil, fmt.Errorf("flag %s is not supported", name)
+		}
+		if !hasValue && valueFlags[name] {
+			if i+1 >= len(words) {
+				return nil, fmt.Errorf("flag %s needs a value", name)
+			}
+			i++
+			value = words[i]
+		}
+		cmd.Flags[name] = value
+	}
+	cmd.Namespace = firstFlag(cmd.Flags, "-n", "--namespace")
+
+	if len(positional) == 0 {
+		return nil, fmt.Errorf("missing verb")
+	}
+	cmd.Verb, positional = positional[0], positional[1:]
+	for {
+		subs, ok := subcommands[cmd.Verb]
+		if !ok {
+			break
+		}
+		if len(positional) == 0 || !contains(subs, positional[0]) {
+			return nil, fmt.Errorf("kubectl %s needs one of: %s", cmd.Verb, strings.Join(subs, ", "))
+		}
+		cmd.Verb, positional = cmd.Verb+" "+positional[0], positional[1:]
+	}
+
+	// The target is "kind/name" or "kind name"; cordon and friends take a
+	// bare node name
+	switch {
+	case cmd.Verb == "cordon" || cmd.Verb == "uncordon" || cmd.Verb == "drain":
+		cmd.Kind = "node"
+		if len(positional) > 0 {
+			cmd.Name, positional = positional[0], positional[1:]
+		}
+	case strings.HasPrefix(cmd.Verb, "argo rollouts "):
+		cmd.Kind = "rollout"
+		if len(positional) > 0 {
+			cmd.Name, positional = positional[0], positional[1:]
+		}
+	case cmd.Verb == "logs":
+		cmd.Kind = "pod"
+		if len(positional) > 0 {
+			cmd.Name, positional = positional[0], positional[1:]
+			if kind, name, ok := strings.Cut(cmd.Name, "/"); ok {
+				cmd.Kind, cmd.Name = kind, name
+			}
+		}
+	case len(positional) > 0 && strings.Contains(positional[0], "/"):
+		cmd.Kind, cmd.Name, _ = strings.Cut(positional[0], "/")
+		positional = positional[1:]
+	case len(positional) > 0:
+		cmd.Kind, positional = positional[0], positional[1:]
+		if len(positional) > 0 && !strings.Contains(positional[0], "=") {
+			cmd.Name, positional = positional[0], positional[1:]
+		}
+	}
+	cmd.Args = positional
+	return cmd, nil
+}
+
+// checkSyntax checks the arguments each mutating verb needs
+func checkSyntax(cmd *Command) error {
+	if cmd.ReadOnly() {
+		return nil
+	}
+	if cmd.Name == "" {
+		return fmt.Errorf("kubectl %s needs a named target", cmd.Verb)
+	}
+	if _, ok := lookupResource(cmd.Kind); !ok {
+		return fmt.Errorf("unknown resource type %q", cmd.Kind)
+	}
+	if _, ok := cmd.Flags["--all"]; ok {
+		return fmt.Errorf("--all is not supported; name the object")
+	}
+	if firstFlag(cmd.Flags, "-l", "--selector") != "" {
+		return fmt.Errorf("label selectors are not supported; name the object")
+	}
+
+	switch cmd.Verb {
+	case "scale":
+		replicas, ok := cmd.Flags["--replicas"]
+		if !ok {
+			return fmt.Errorf("kubectl scale needs --replicas")
+		}
+		if n, err := strconv.Atoi(replicas); err != nil || n < 0 {
+			return fmt.Errorf("invalid --replicas %q", replicas)
+		}
+	case "set image":
+		if len(cmd.Args) == 0 {
+			return fmt.Errorf("kubectl set image needs container=image arguments")
+		}
+		for _, arg := range cmd.Args {
+			if container, image, ok := strings.Cut(arg, "="); !ok || container == "" || image == "" {
+				return fmt.Errorf("invalid container=image argument %q", arg)
+			}
+		}
+	case "set resources":
+		if _, err := resourceList(cmd.Flags["--limits"]); err != nil {
+			return err
+		}
+		if _, err := resourceList(cmd.Flags["--requests"]); err != nil {
+			return err
+		}
+		if cmd.Flags["--limits"] == "" && cmd.Flags["--requests"] == "" {
+			return fmt.Errorf("kubectl set resources needs --limits or --requests")
+		}
+	case "patch":
+		patch := firstFlag(cmd.Flags, "-p", "--patch")
+		if patch == "" {
+			return fmt.Errorf("kubectl patch needs -p")
+		}
+		if !json.Valid([]byte(patch)) {
+			return fmt.Errorf("patch is not valid JSON")
+		}
+		switch cmd.Flags["--type"] {
+		case "", "strategic", "merge", "json":
+		default:
+			return fmt.Errorf("unknown patch type %q", cmd.Flags["--type"])
+		}
+	case "label", "annotate":
+		if len(cmd.Args) == 0 {
+			return fmt.Errorf("kubectl %s needs key=value arguments", cmd.Verb)
+		}
+		for _, arg := range cmd.Args {
+			if !strings.Contains(arg, "=") && !strings.HasSuffix(arg, "-") {
+				return fmt.Errorf("invalid %s argument %q", cmd.Verb, arg)
+			}
+		}
+	}
+	return nil
+}
+
+// resourceList parses a --limits/--requests value such as
+// "cpu=200m,memory=512Mi"
+func resourceList(value string) (map[string]string, error) {
+	out := map[string]string{}
+	if value == "" {
+		return out, nil
+	}
+	for _, pair := range strings.Split(value, ",") {
+		name, quantity, ok := strings.Cut(pair, "=")
+		if !ok || name == "" || quantity == "" {
+			return nil, fmt.Errorf("invalid resource %q", pair)
+		}
+		out[name] = quantity
+	}
+	return out, nil
+}
+
+// split breaks a command line into words like a POSIX shell would, without
+// expansions. Pipes, command chaining, redirects and substitutions are
+// rejected: a recommendation must be a single kubectl invocation.
+func split(line string) ([]string, error) {
+	var (
+		words   []string
+		word    strings.Builder
+		inWord  bool
+		quote   rune
+		escaped bool
+	)
+	for _, r := range line {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case quote == '"':
+			switch r {
+			case '"':
+				quote = 0
+			case '\\':
+				escaped = true
+			case '$', '`':
+				return nil, fmt.Errorf("shell substitutions are not supported")
+			default:
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inWord = r, true
+		case r == '\\':
+			escaped, inWord = true, true
+		case r == ' ' || r == '\t':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		case strings.ContainsRune("|;&<>`$\n", r):
+			return nil, fmt.Errorf("only a single kubectl command is supported (found %q)", r)
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 || escaped {
+		return nil, fmt.Errorf("unterminated quote")
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+func firstFlag(flags map[string]string, names ...string) string {
+	for _, name := range names {
+		if v, ok := flags[name]; ok {
+			return v
+		}
+	}
+	return ""
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,306 @@
+package remediation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+
+	"github.com/emirozbir/micro-sre/internal/collectors"
+	"github.com/emirozbir/micro-sre/internal/models"
+)
+
+// Dry-run outcomes
+const (
+	// DryRunPassed means the API server accepted the change without
+	// persisting it
+	DryRunPassed = "passed"
+	// DryRunFailed means the API server rejected the change
+	DryRunFailed = "failed"
+	// DryRunChecked means the command cannot be dry-run (it is read-only or
+	// has no server-side equivalent) and only its target was looked up
+	DryRunChecked = "checked"
+)
+
+type resource struct {
+	gvr        schema.GroupVersionResource
+	namespaced bool
+}
+
+var resources = map[string]resource{
+	"pod":                     {schema.GroupVersionResource{Version: "v1", Resource: "pods"}, true},
+	"service":                 {schema.GroupVersionResource{Version: "v1", Resource: "services"}, true},
+	"configmap":               {schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}, true},
+	"secret":                  {schema.GroupVersionResource{Version: "v1", Resource: "secrets"}, true},
+	"persistentvolumeclaim":   {schema.GroupVersionResource{Version: "v1", Resource: "persistentvolumeclaims"}, true},
+	"node":                    {schema.GroupVersionResource{Version: "v1", Resource: "nodes"}, false},
+	"namespace":               {schema.GroupVersionResource{Version: "v1", Resource: "namespaces"}, false},
+	"deployment":              {schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}, true},
+	"statefulset":             {schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "statefulsets"}, true},
+	"daemonset":               {schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "daemonsets"}, true},
+	"replicaset":              {schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "replicasets"}, true},
+	"job":                     {schema.GroupVersionResource{Group: "batch", Version: "v1", Resource: "jobs"}, true},
+	"cronjob":                 {schema.GroupVersionResource{Group: "batch", Version: "v1", Resource: "cronjobs"}, true},
+	"horizontalpodautoscaler": {schema.GroupVersionResource{Group: "autoscaling", Version: "v2", Resource: "horizontalpodautoscalers"}, true},
+	"poddisruptionbudget":     {schema.GroupVersionResource{Group: "policy", Version: "v1", Resource: "poddisruptionbudgets"}, true},
+	"ingress":                 {schema.GroupVersionResource{Group: "networking.k8s.io", Version: "v1", Resource: "ingresses"}, true},
+	"rollout":                 {schema.GroupVersionResource{Group: "argoproj.io", Version: "v1alpha1", Resource: "rollouts"}, true},
+	"canary":                  {schema.GroupVersionResource{Group: "flagger.app", Version: "v1beta1", Resource: "canaries"}, true},
+	"networkpolicy":           {schema.GroupVersionResource{Group: "networking.k8s.io", Version: "v1", Resource: "networkpolicies"}, true},
+}
+
+var kindAliases = map[string]string{
+	"po": "pod", "svc": "service", "cm": "configmap", "pvc": "persistentvolumeclaim",
+	"no": "node", "ns": "namespace", "deploy": "deployment", "sts": "statefulset",
+	"ds": "daemonset", "rs": "replicaset", "cj": "cronjob", "hpa": "horizontalpodautoscaler",
+	"pdb": "poddisruptionbudget", "ing": "ingress", "netpol": "networkpolicy",
+}
+
+// lookupResource resolves a kubectl resource type such as "deploy",
+// "deployments" or "deployments.apps"
+func lookupResource(kind string) (resource, bool) {
+	kind = strings.ToLower(kind)
+	kind, _, _ = strings.Cut(kind, ".")
+	if alias, ok := kindAliases[kind]; ok {
+		kind = alias
+	}
+	if r, ok := resources[kind]; ok {
+		return r, true
+	}
+	// Plural forms
+	for _, suffix := range []string{"es", "s"} {
+		if r, ok := resources[strings.TrimSuffix(kind, suffix)]; ok && strings.HasSuffix(kind, suffix) {
+			return r, true
+		}
+	}
+	switch kind {
+	case "networkpolicies":
+		return resources["networkpolicy"], true
+	case "canaries":
+		return resources["canary"], true
+	}
+	return resource{}, false
+}
+
+// Runner submits validated commands to the API server. DryRun sets
+// dryRun=All, so admission and validation run but nothing is persisted;
+// Execute applies approved remediation actions.
+type Runner struct {
+	client       dynamic.Interface
+	allowedVerbs []string
+}
+
+// NewRunner creates a runner from the mutating capability
+func NewRunner(mutator collectors.Mutator, allowedVerbs []string) (*Runner, error) {
+	client, err := dynamic.NewForConfig(mutator.RESTConfig())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dynamic client: %w", err)
+	}
+	return &Runner{client: client, allowedVerbs: allowedVerbs}, nil
+}
+
+// DryRun validates the command and dry-runs it. namespace is used when the
+// command has no -n flag. Validation errors wrap ErrInvalidCommand; API
+// rejections are reported as a failed result, not an error.
+func (d *Runner) DryRun(ctx context.Context, command, namespace string) (*models.DryRunResult, error) {
+	cmd, err := Validate(command, d.allowedVerbs)
+	if err != nil {
+		return nil, err
+	}
+	if cmd.Namespace != "" {
+		namespace = cmd.Namespace
+	}
+
+	res, ok := lookupResource(cmd.Kind)
+	if !ok || cmd.Name == "" {
+		return &models.DryRunResult{
+			Status:  DryRunChecked,
+			Message: "command has no single target to check",
+			Target:  cmd.Target(),
+		}, nil
+	}
+	var client dynamic.ResourceInterface = d.client.Resource(res.gvr)
+	if res.namespaced {
+		client = d.client.Resource(res.gvr).Namespace(namespace)
+	}
+
+	result := &models.DryRunResult{Status: DryRunPassed, Target: cmd.Target()}
+	if res.namespaced {
+		result.Target = namespace + "/" + cmd.Target()
+	}
+
+	err = d.apply(ctx, client, cmd, result, true)
+	if err != nil {
+		result.Status = DryRunFailed
+		result.Message = err.Error()
+	}
+	return result, nil
+}
+
+// Execute applies a command for real. Only commands that classify as a
+// remediation action are accepted; namespace is used when the command has
+// no -n flag.
+func (d *Runner) Execute(ctx context.Context, cmd *Command, namespace string) error {
+	if Classify(cmd) == "" {
+		return fmt.Errorf("%w: %s is not a remediation action", ErrInvalidCommand, cmd.Verb)
+	}
+	if cmd.Namespace != "" {
+		namespace = cmd.Namespace
+	}
+	res, _ := lookupResource(cmd.Kind)
+	return d.apply(ctx, d.client.Resource(res.gvr).Namespace(namespace), cmd, &models.DryRunResult{}, false)
+}
+
+func (d *Runner) apply(ctx context.Context, client dynamic.ResourceInterface, cmd *Command, result *models.DryRunResult, dryRun bool) error {
+	var dryRunOpts []string
+	if dryRun {
+		dryRunOpts = []string{metav1.DryRunAll}
+	}
+	patchOpts := metav1.PatchOptions{DryRun: dryRunOpts}
+
+	patch := func(pt types.PatchType, body any, subresources ...string) error {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		_, err = client.Patch(ctx, cmd.Name, pt, data, patchOpts, subresources...)
+		return err
+	}
+
+	switch cmd.Verb {
+	case "delete":
+		opts := metav1.DeleteOptions{DryRun: dryRunOpts}
+		if s, ok := cmd.Flags["--grace-period"]; ok {
+			grace, err := strconv.ParseInt(s, 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid --grace-period %q", s)
+			}
+			opts.GracePeriodSeconds = &grace
+		}
+		return client.Delete(ctx, cmd.Name, opts)
+	case "scale":
+		replicas, _ := strconv.Atoi(cmd.Flags["--replicas"])
+		return patch(types.MergePatchType, map[string]any{"spec": map[string]any{"replicas": replicas}}, "scale")
+	case "rollout restart":
+		return patch(types.StrategicMergePatchType, podTemplateAnnotations(map[string]string{
+			"kubectl.kubernetes.io/restartedAt": time.Now().Format(time.RFC3339),
+		}))
+	case "rollout pause", "rollout resume":
+		return patch(types.MergePatchType, map[string]any{"spec": map[string]any{"paused": cmd.Verb == "rollout pause"}})
+	case "set image":
+		var containers []map[string]any
+		for _, arg := range cmd.Args {
+			name, image, _ := strings.Cut(arg, "=")
+			containers = append(containers, map[string]any{"name": name, "image": image})
+		}
+		return patch(types.StrategicMergePatchType, containersPatch(cmd, containers))
+	case "set resources":
+		return d.setResources(ctx, client, cmd, patch)
+	case "patch":
+		pt := types.StrategicMergePatchType
+		switch cmd.Flags["--type"] {
+		case "merge":
+			pt = types.MergePatchType
+		case "json":
+			pt = types.JSONPatchType
+		}
+		_, err := client.Patch(ctx, cmd.Name, pt, []byte(firstFlag(cmd.Flags, "-p", "--patch")), patchOpts)
+		return err
+	case "label", "annotate":
+		field := "labels"
+		if cmd.Verb == "annotate" {
+			field = "annotations"
+		}
+		values := map[string]any{}
+		for _, arg := range cmd.Args {
+			if key, value, ok := strings.Cut(arg, "="); ok {
+				values[key] = value
+			} else {
+				values[strings.TrimSuffix(arg, "-")] = nil
+			}
+		}
+		return patch(types.MergePatchType, map[string]any{"metadata": map[string]any{field: values}})
+	case "cordon", "uncordon":
+		return patch(types.MergePatchType, map[string]any{"spec": map[string]any{"unschedulable": cmd.Verb == "cordon"}})
+	}
+
+	// No server-side dry-run equivalent (read-only verbs, rollout undo, set
+	// env, drain): make sure the target exists
+	result.Status = DryRunChecked
+	if _, err := client.Get(ctx, cmd.Name, metav1.GetOptions{}); err != nil {
+		return err
+	}
+	result.Message = "target exists; command cannot be dry-run server-side"
+	return nil
+}
+
+// setResources patches container resources. Without -c every container of
+// the pod template is updated, like kubectl does.
+func (d *Runner) setResources(ctx context.Context, client dynamic.ResourceInterface, cmd *Command,
+	patch func(types.PatchType, any, ...string) error) error {
+	limits, _ := resourceList(cmd.Flags["--limits"])
+	requests, _ := resourceList(cmd.Flags["--requests"])
+	resources := map[string]any{}
+	if len(limits) > 0 {
+		resources["limits"] = limits
+	}
+	if len(requests) > 0 {
+		resources["requests"] = requests
+	}
+
+	var names []string
+	if c := firstFlag(cmd.Flags, "-c", "--containers"); c != "" && c != "*" {
+		names = strings.Split(c, ",")
+	} else {
+		obj, err := client.Get(ctx, cmd.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		containers, _, _ := unstructured.NestedSlice(obj.Object, containersPath(cmd)...)
+		for _, c := range containers {
+			if m, ok := c.(map[string]any); ok {
+				if name, ok := m["name"].(string); ok {
+					names = append(names, name)
+				}
+			}
+		}
+	}
+
+	var containers []map[string]any
+	for _, name := range names {
+		containers = append(containers, map[string]any{"name": name, "resources": resources})
+	}
+	return patch(types.StrategicMergePatchType, containersPatch(cmd, containers))
+}
+
+func podTemplateAnnotations(annotations map[string]string) map[string]any {
+	return map[string]any{"spec": map[string]any{"template": map[string]any{
+		"metadata": map[string]any{"annotations": annotations},
+	}}}
+}
+
+// containersPatch builds a patch of the pod template containers, or of the
+// pod's own containers when the target is a pod
+func containersPatch(cmd *Command, containers []map[string]any) map[string]any {
+	var patch any = map[string]any{"containers": containers}
+	path := containersPath(cmd)
+	for i := len(path) - 2; i >= 0; i-- {
+		patch = map[string]any{path[i]: patch}
+	}
+	return patch.(map[string]any)
+}
+
+func containersPath(cmd *Command) []string {
+	if res, _ := lookupResource(cmd.Kind); res.gvr.Resource == "pods" {
+		return []string{"spec", "containers"}
+	}
+	return []string{"spec", "template", "spec", "containers"}
+}
@@ -0,0 +1,59 @@
+// Package requestid carries the ID of the request an analysis runs for, so
+// access logs, agent logs and stored analyses can be correlated.
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"go.uber.org/zap"
+)
+
+// Header is the header a request ID is read from and echoed in
+const Header = "X-Request-ID"
+
+// maxLength bounds IDs accepted from callers
+const maxLength = 128
+
+type key struct{}
+
+// With returns a context carrying id
+func With(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, key{}, id)
+}
+
+// From returns the request ID carried by ctx, or ""
+func From(ctx context.Context) string {
+	id, _ := ctx.Value(key{}).(string)
+	return id
+}
+
+// New returns a random request ID
+func New() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Valid reports whether an ID sent by a caller is safe to log and echo:
+// non-empty, bounded and made of printable ASCII without spaces
+func Valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// Logger returns logger with the request ID of ctx as a field, if any
+func Logger(ctx context.Context, logger *zap.Logger) *zap.Logger {
+	if id := From(ctx); id != "" {
+		return logger.With(zap.String("request_id", id))
+	}
+	return logger
+}
@@ -0,0 +1,45 @@
+package rules
+
+import (
+	"regexp"
+
+	"github.com/emirozbir/micro-sre/internal/models"
+)
+
+// Root cause categories, used to tag alerts in incident tools
+const (
+	CategoryOOM        = "oom"
+	CategoryImagePull  = "image-pull"
+	CategoryConfig     = "config"
+	CategoryProbe      = "probe-failure"
+	CategoryScheduling = "scheduling"
+	CategoryDependency = "dependency"
+	CategoryCrash      = "crash"
+	CategoryUnknown    = "unknown"
+)
+
+// categoryPatterns are matched against the root cause in order; the first
+// match wins, so specific causes come before the generic crash
+var categoryPatterns = []struct {
+	category string
+	pattern  *regexp.Regexp
+}{
+	{CategoryOOM, regexp.MustCompile(`(?i)\boom|out of memory|memory limit`)},
+	{CategoryImagePull, regexp.MustCompile(`(?i)imagepull|errimagepull|pull(ing)? (the )?image|image pull|registry`)},
+	{CategoryConfig, regexp.MustCompile(`(?i)configmap|secret|environment variable|config(uration)? (error|missing|invalid)|missing (key|config)`)},
+	{CategoryProbe, regexp.MustCompile(`(?i)liveness|readiness|startup probe|probe fail`)},
+	{CategoryScheduling, regexp.MustCompile(`(?i)unschedulable|insufficient (cpu|memory)|node affinity|taint|pending`)},
+	{CategoryDependency, regexp.MustCompile(`(?i)connection refused|timed? ?out|dns|database|upstream|dependency|unreachable`)},
+	{CategoryCrash, regexp.MustCompile(`(?i)crashloop|exit code|panic|segfault|exception|crash`)},
+}
+
+// Category classifies the root cause of an analysis into a short,
+// tag-friendly category, or CategoryUnknown
+func Category(analysis models.Analysis) string {
+	for _, c := range categoryPatterns {
+		if c.pattern.MatchString(analysis.RootCause) {
+			return c.category
+		}
+	}
+	return CategoryUnknown
+}
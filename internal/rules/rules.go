@@ -0,0 +1,221 @@
+// Package rules recognizes routine incident patterns without the LLM. A
+// conclusive match answers the analysis on its own; an inconclusive one
+// seeds the prompt with the suspected cause. Triage summarizes any failing
+// pod for pipelines that run without the LLM.
+package rules
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/emirozbir/micro-sre/internal/models"
+)
+
+// Rule names
+const (
+	RuleImagePullAuth    = "image_pull_auth"
+	RuleOOMLimit         = "oom_limit"
+	RuleConfigMissingRef = "config_missing_reference"
+)
+
+// Input is the collected data rules are evaluated against
+type Input struct {
+	Pod    *corev1.Pod
+	Events []corev1.Event
+	OOM    *models.OOMContext
+	// Logs are only used by Triage
+	Logs string
+}
+
+// Match is the outcome of a rule. Analysis is a complete answer for
+// conclusive matches and a hint (root cause and reasoning only) otherwise.
+type Match struct {
+	Rule       string
+	Conclusive bool
+	Analysis   models.Analysis
+}
+
+type rule func(in Input) *Match
+
+// rules are evaluated in order; the first match wins
+var rules = []rule{
+	imagePullAuth,
+	configMissingReference,
+	oomLimit,
+}
+
+// Classify returns the first rule matching the input, or nil
+func Classify(in Input) *Match {
+	if in.Pod == nil {
+		return nil
+	}
+	for _, r := range rules {
+		if m := r(in); m != nil {
+			return m
+		}
+	}
+	return nil
+}
+
+var pullAuthPattern = regexp.MustCompile(`(?i)\b401\b|unauthorized|authentication required|pull access denied|no basic auth credentials`)
+
+// imagePullAuth matches image pulls rejected by the registry for missing or
+// wrong credentials
+func imagePullAuth(in Input) *Match {
+	for _, cs := range allStatuses(in.Pod) {
+		w := cs.State.Waiting
+		if w == nil || (w.Reason != "ImagePullBackOff" && w.Reason != "ErrImagePull") {
+			continue
+		}
+
+		evidence := matchingEvents(in.Events, []string{"Failed"}, pullAuthPattern)
+		if !pullAuthPattern.MatchString(w.Message) && len(evidence) == 0 {
+			continue
+		}
+
+		secrets := "none"
+		if len(in.Pod.Spec.ImagePullSecrets) > 0 {
+			var names []string
+			for _, s := range in.Pod.Spec.ImagePullSecrets {
+				names = append(names, s.Name)
+			}
+			secrets = strings.Join(names, ", ")
+		}
+
+		return &Match{
+			Rule:       RuleImagePullAuth,
+			Conclusive: true,
+			Analysis: models.Analysis{
+				RootCause:  fmt.Sprintf("Registry rejected the credentials for image %s of container %s", cs.Image, cs.Name),
+				Confidence: "high",
+				Reasoning: fmt.Sprintf("Container %s is in %s and the pull error reports an authentication failure. "+
+					"The pod's imagePullSecrets are: %s. Either the secret is missing, not linked to the pod or "+
+					"service account, or its credentials are expired or lack access to the repository.",
+					cs.Name, w.Reason, secrets),
+				Evidence: models.Evidence{Events: evidence},
+				Recommendations: []models.Recommendation{
+					{
+						Priority: "high",
+						Action:   "Check the pull secrets referenced by the pod",
+						Command:  fmt.Sprintf("kubectl get pod %s -n %s -o jsonpath='{.spec.imagePullSecrets}'", in.Pod.Name, in.Pod.Namespace),
+					},
+					{
+						Priority: "high",
+						Action:   "Recreate the registry secret with valid credentials",
+						Command:  fmt.Sprintf("kubectl create secret docker-registry <name> -n %s --docker-server=<registry> --docker-username=<user> --docker-password=<token>", in.Pod.Namespace),
+					},
+				},
+			},
+		}
+	}
+	return nil
+}
+
+var missingRefPattern = regexp.MustCompile(`(?i)couldn't find key (\S+) in (ConfigMap|Secret) (\S+)|(configmap|secret) "([^"]+)" not found`)
+
+// configMissingReference matches containers that cannot start because a
+// referenced ConfigMap, Secret or key does not exist
+func configMissingReference(in Input) *Match {
+	for _, cs := range allStatuses(in.Pod) {
+		w := cs.State.Waiting
+		if w == nil || w.Reason != "CreateContainerConfigError" {
+			continue
+		}
+		m := missingRefPattern.FindStringSubmatch(w.Message)
+		if m == nil {
+			continue
+		}
+
+		var cause, command string
+		if m[1] != "" {
+			kind, ref := strings.ToLower(m[2]), m[3]
+			ns, name := in.Pod.Namespace, ref
+			if i := strings.Index(ref, "/"); i >= 0 {
+				ns, name = ref[:i], ref[i+1:]
+			}
+			cause = fmt.Sprintf("Key %s is missing from %s %s/%s referenced by container %s", m[1], m[2], ns, name, cs.Name)
+			command = fmt.Sprintf("kubectl get %s %s -n %s -o jsonpath='{.data}'", kind, name, ns)
+		} else {
+			kind := strings.ToLower(m[4])
+			cause = fmt.Sprintf("%s %s referenced by container %s does not exist", kindTitle(kind), m[5], cs.Name)
+			command = fmt.Sprintf("kubectl get %s -n %s", kind, in.Pod.Namespace)
+		}
+
+		return &Match{
+			Rule:       RuleConfigMissingRef,
+			Conclusive: true,
+			Analysis: models.Analysis{
+				RootCause:  cause,
+				Confidence: "high",
+				Reasoning:  fmt.Sprintf("Container %s is in CreateContainerConfigError: %s", cs.Name, w.Message),
+				Evidence: models.Evidence{
+					Events: matchingEvents(in.Events, []string{"Failed"}, missingRefPattern),
+				},
+				Recommendations: []models.Recommendation{
+					{
+						Priority: "high",
+						Action:   "Create the missing object or key, or fix the reference in the pod spec",
+						Command:  command,
+					},
+				},
+			},
+		}
+	}
+	return nil
+}
+
+// oomLimit matches containers killed for exceeding their own memory limit.
+// Whether the limit is too low or the process leaks needs the logs, so the
+// match only seeds the prompt.
+func oomLimit(in Input) *Match {
+	oom := in.OOM
+	if oom == nil || oom.Evicted || oom.Classification != models.OOMCgroupLimit {
+		return nil
+	}
+	limit := oom.MemoryLimit
+	if limit == "" {
+		limit = "its limit"
+	}
+	return &Match{
+		Rule: RuleOOMLimit,
+		Analysis: models.Analysis{
+			RootCause: fmt.Sprintf("Container %s exceeded its memory limit (%s) and was OOM-killed", oom.Container, limit),
+			Reasoning: "The kernel killed the container inside its own cgroup while the node had memory to spare, so the container's usage went above its limit.",
+		},
+	}
+}
+
+func allStatuses(pod *corev1.Pod) []corev1.ContainerStatus {
+	statuses := append([]corev1.ContainerStatus(nil), pod.Status.InitContainerStatuses...)
+	return append(statuses, pod.Status.ContainerStatuses...)
+}
+
+// matchingEvents returns the events with one of the reasons whose message
+// matches pattern, as evidence entries
+func matchingEvents(events []corev1.Event, reasons []string, pattern *regexp.Regexp) []models.EventEntry {
+	var out []models.EventEntry
+	for _, e := range events {
+		for _, reason := range reasons {
+			if e.Reason == reason && pattern.MatchString(e.Message) {
+				out = append(out, models.EventEntry{
+					Type:      e.Type,
+					Reason:    e.Reason,
+					Message:   e.Message,
+					Timestamp: e.LastTimestamp.Time,
+				})
+				break
+			}
+		}
+	}
+	return out
+}
+
+func kindTitle(kind string) string {
+	if kind == "configmap" {
+		return "ConfigMap"
+	}
+	return "Secret"
+}
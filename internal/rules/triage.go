@@ -0,0 +1,180 @@
+package rules
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/emirozbir/micro-sre/internal/models"
+)
+
+// Triage limits
+const (
+	triageMaxEvents   = 5
+	triageMaxLogLines = 5
+)
+
+// triageCertainty is the self-reported certainty of a triage: it names the
+// failing state, rarely the reason behind it
+const triageCertainty = 30
+
+var errorLinePattern = regexp.MustCompile(`(?i)\b(error|fatal|panic|exception|failed|refused|denied|timeout)\b`)
+
+// Triage summarizes the failing state of a pod from its container statuses,
+// Warning events and error log lines, without the LLM. It always returns an
+// analysis, with a low certainty, and reports whether anything was failing.
+func Triage(in Input) (models.Analysis, bool) {
+	analysis := models.Analysis{
+		Confidence:       "low",
+		ConfidenceRubric: &models.ConfidenceRubric{SelfReportedLevel: "low", Certainty: triageCertainty},
+	}
+	if in.Pod == nil {
+		analysis.RootCause = "Pod not found"
+		return analysis, false
+	}
+
+	var findings []string
+	var cause string
+	for _, cs := range allStatuses(in.Pod) {
+		finding := containerFinding(cs)
+		if finding == "" {
+			continue
+		}
+		findings = append(findings, finding)
+		if cause == "" {
+			cause = finding
+		}
+		if t := cs.LastTerminationState.Terminated; t != nil && !t.FinishedAt.IsZero() {
+			analysis.Timeline = append(analysis.Timeline, models.TimelineEvent{
+				Timestamp: t.FinishedAt.Time,
+				Event:     fmt.Sprintf("Container %s terminated", cs.Name),
+				Details:   fmt.Sprintf("%s, exit code %d", t.Reason, t.ExitCode),
+			})
+		}
+	}
+	for _, c := range in.Pod.Status.Conditions {
+		if c.Type == corev1.PodScheduled && c.Status == corev1.ConditionFalse {
+			finding := fmt.Sprintf("Pod %s cannot be scheduled: %s", in.Pod.Name, c.Message)
+			findings = append(findings, finding)
+			if cause == "" {
+				cause = finding
+			}
+		}
+	}
+
+	analysis.Evidence.Events = warningEvents(in.Events)
+	analysis.Evidence.Logs = errorLines(in.Logs)
+
+	failing := cause != "" || len(analysis.Evidence.Events) > 0
+	switch {
+	case cause != "":
+		analysis.RootCause = cause
+	case len(analysis.Evidence.Events) > 0:
+		e := analysis.Evidence.Events[0]
+		analysis.RootCause = fmt.Sprintf("Pod %s reports %s: %s", in.Pod.Name, e.Reason, e.Message)
+	default:
+		analysis.RootCause = fmt.Sprintf("No failing container state or Warning event found for pod %s", in.Pod.Name)
+	}
+
+	reasoning := []string{"Heuristic triage of the pod state; no model was asked for the underlying cause."}
+	reasoning = append(reasoning, findings...)
+	switch n := len(analysis.Evidence.Logs); n {
+	case 0:
+	case 1:
+		reasoning = append(reasoning, "A recent log line reports an error.")
+	default:
+		reasoning = append(reasoning, fmt.Sprintf("%d recent log lines report errors.", n))
+	}
+	analysis.Reasoning = strings.Join(reasoning, " ")
+
+	analysis.Recommendations = triageRecommendations(in.Pod)
+	return analysis, failing
+}
+
+// containerFinding describes a failing container state, or returns ""
+func containerFinding(cs corev1.ContainerStatus) string {
+	last := ""
+	if t := cs.LastTerminationState.Terminated; t != nil {
+		last = fmt.Sprintf(" (last exit: %s, code %d)", t.Reason, t.ExitCode)
+	}
+
+	if w := cs.State.Waiting; w != nil {
+		switch w.Reason {
+		case "CrashLoopBackOff":
+			return fmt.Sprintf("Container %s keeps crashing and is in CrashLoopBackOff after %d restarts%s.", cs.Name, cs.RestartCount, last)
+		case "ImagePullBackOff", "ErrImagePull", "InvalidImageName":
+			return fmt.Sprintf("Container %s cannot pull image %s (%s): %s", cs.Name, cs.Image, w.Reason, w.Message)
+		case "CreateContainerConfigError", "CreateContainerError", "RunContainerError":
+			return fmt.Sprintf("Container %s cannot be started (%s): %s", cs.Name, w.Reason, w.Message)
+		}
+	}
+	if t := cs.State.Terminated; t != nil && t.ExitCode != 0 {
+		return fmt.Sprintf("Container %s terminated with %s, exit code %d.", cs.Name, t.Reason, t.ExitCode)
+	}
+	if t := cs.LastTerminationState.Terminated; t != nil && t.Reason == "OOMKilled" {
+		return fmt.Sprintf("Container %s was OOM-killed and restarted %d times.", cs.Name, cs.RestartCount)
+	}
+	if cs.RestartCount > 0 && !cs.Ready {
+		return fmt.Sprintf("Container %s is not ready after %d restarts%s.", cs.Name, cs.RestartCount, last)
+	}
+	return ""
+}
+
+// warningEvents returns the distinct Warning events, most recent last
+func warningEvents(events []corev1.Event) []models.EventEntry {
+	seen := map[string]bool{}
+	var out []models.EventEntry
+	for _, e := range events {
+		key := e.Reason + "\x00" + e.Message
+		if e.Type != corev1.EventTypeWarning || seen[key] {
+			continue
+		}
+		seen[key] = true
+		out = append(out, models.EventEntry{
+			Type:      e.Type,
+			Reason:    e.Reason,
+			Message:   e.Message,
+			Timestamp: e.LastTimestamp.Time,
+		})
+	}
+	if len(out) > triageMaxEvents {
+		out = out[len(out)-triageMaxEvents:]
+	}
+	return out
+}
+
+// errorLines returns the last distinct log lines that look like errors
+func errorLines(logs string) []models.LogEntry {
+	lines := strings.Split(logs, "\n")
+	seen := map[string]bool{}
+	var out []models.LogEntry
+	for i := len(lines) - 1; i >= 0 && len(out) < triageMaxLogLines; i-- {
+		line := strings.TrimSpace(lines[i])
+		if line == "" || seen[line] || !errorLinePattern.MatchString(line) {
+			continue
+		}
+		seen[line] = true
+		out = append([]models.LogEntry{{Line: line}}, out...)
+	}
+	return out
+}
+
+func triageRecommendations(pod *corev1.Pod) []models.Recommendation {
+	recs := []models.Recommendation{{
+		Priority: "medium",
+		Action:   "Inspect the pod's state and events",
+		Command:  fmt.Sprintf("kubectl describe pod %s -n %s", pod.Name, pod.Namespace),
+	}}
+	for _, cs := range pod.Status.ContainerStatuses {
+		if cs.RestartCount > 0 {
+			recs = append(recs, models.Recommendation{
+				Priority: "medium",
+				Action:   fmt.Sprintf("Read the logs of the previous run of container %s", cs.Name),
+				Command:  fmt.Sprintf("kubectl logs %s -n %s -c %s --previous", pod.Name, pod.Namespace, cs.Name),
+			})
+		}
+	}
+	return recs
+}
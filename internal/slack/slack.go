@@ -0,0 +1,196 @@
+// Package slack posts messages to a Slack incoming webhook and verifies the
+// requests Slack sends to hepsre, such as interactive button clicks and
+// slash commands.
+package slack
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/emirozbir/micro-sre/internal/collectors"
+	"github.com/emirozbir/micro-sre/internal/config"
+)
+
+// maxRequestAge bounds the age of a signed request, against replays
+const maxRequestAge = 5 * time.Minute
+
+// ErrBadSignature is returned for requests that were not signed by Slack
+var ErrBadSignature = errors.New("invalid slack signature")
+
+// Message is a Slack message. Blocks are optional; Text is the fallback
+// shown in notifications. ResponseType is "ephemeral" (the default, only
+// seen by the user) or "in_channel" for replies to slash commands.
+type Message struct {
+	Text            string  `json:"text"`
+	Blocks          []Block `json:"blocks,omitempty"`
+	ReplaceOriginal bool    `json:"replace_original,omitempty"`
+	ResponseType    string  `json:"response_type,omitempty"`
+}
+
+// Block is a section or actions block
+type Block struct {
+	Type     string    `json:"type"`
+	Text     *Text     `json:"text,omitempty"`
+	Elements []Element `json:"elements,omitempty"`
+}
+
+// Text is a text object
+type Text struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// Element is a button of an actions block
+type Element struct {
+	Type     string `json:"type"`
+	Text     *Text  `json:"text,omitempty"`
+	ActionID string `json:"action_id,omitempty"`
+	Value    string `json:"value,omitempty"`
+	Style    string `json:"style,omitempty"`
+}
+
+// Section returns a section block with markdown text
+func Section(markdown string) Block {
+	return Block{Type: "section", Text: &Text{Type: "mrkdwn", Text: markdown}}
+}
+
+// Buttons returns an actions block
+func Buttons(buttons ...Element) Block {
+	return Block{Type: "actions", Elements: buttons}
+}
+
+// Button returns a button; style is "", "primary" or "danger"
+func Button(label, actionID, value, style string) Element {
+	return Element{
+		Type:     "button",
+		Text:     &Text{Type: "plain_text", Text: label},
+		ActionID: actionID,
+		Value:    value,
+		Style:    style,
+	}
+}
+
+// Interaction is the payload Slack posts when a button is clicked
+type Interaction struct {
+	Type string `json:"type"`
+	User struct {
+		ID       string `json:"id"`
+		Username string `json:"username"`
+		Name     string `json:"name"`
+	} `json:"user"`
+	Actions []struct {
+		ActionID string `json:"action_id"`
+		Value    string `json:"value"`
+	} `json:"actions"`
+	ResponseURL string `json:"response_url"`
+}
+
+// ParseInteraction decodes the "payload" form field of an interaction
+// request
+func ParseInteraction(payload string) (*Interaction, error) {
+	var i Interaction
+	if err := json.Unmarshal([]byte(payload), &i); err != nil {
+		return nil, fmt.Errorf("failed to parse slack payload: %w", err)
+	}
+	return &i, nil
+}
+
+// Client posts messages and verifies requests
+type Client struct {
+	webhookURL    string
+	signingSecret string
+	client        *http.Client
+}
+
+// New returns a Slack client, or nil if neither a webhook nor a signing
+// secret is configured
+func New(cfg config.SlackConfig) *Client {
+	if cfg.WebhookURL == "" && cfg.SigningSecret == "" {
+		return nil
+	}
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	return &Client{
+		webhookURL:    cfg.WebhookURL,
+		signingSecret: cfg.SigningSecret,
+		client:        &http.Client{Timeout: timeout},
+	}
+}
+
+// CanPost reports whether an incoming webhook is configured
+func (c *Client) CanPost() bool {
+	return c != nil && c.webhookURL != ""
+}
+
+// CanVerify reports whether a signing secret is configured. Requests from
+// Slack must be rejected when it is not.
+func (c *Client) CanVerify() bool {
+	return c != nil && c.signingSecret != ""
+}
+
+// Post sends a message to the incoming webhook
+func (c *Client) Post(ctx context.Context, msg Message) error {
+	return c.send(ctx, c.webhookURL, msg)
+}
+
+// Respond sends a message to the response_url of an interaction
+func (c *Client) Respond(ctx context.Context, responseURL string, msg Message) error {
+	return c.send(ctx, responseURL, msg)
+}
+
+func (c *Client) send(ctx context.Context, url string, msg Message) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to encode slack message: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post to slack: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return &collectors.StatusError{Service: "slack", Code: resp.StatusCode}
+	}
+	return nil
+}
+
+// Verify checks the X-Slack-Signature of a request body
+// (https://api.slack.com/authentication/verifying-requests-from-slack)
+func (c *Client) Verify(header http.Header, body []byte) error {
+	if !c.CanVerify() {
+		return ErrBadSignature
+	}
+	ts, err := strconv.ParseInt(header.Get("X-Slack-Request-Timestamp"), 10, 64)
+	if err != nil {
+		return ErrBadSignature
+	}
+	if age := time.Since(time.Unix(ts, 0)); age > maxRequestAge || age < -maxRequestAge {
+		return ErrBadSignature
+	}
+
+	mac := hmac.New(sha256.New, []byte(c.signingSecret))
+	fmt.Fprintf(mac, "v0:%d:%s", ts, body)
+	expected := "v0=" + hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(expected), []byte(header.Get("X-Slack-Signature"))) {
+		return ErrBadSignature
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+(function () {
+    var maxLines = 500;
+    var button = document.getElementById('logtail-toggle');
+    var output = document.getElementById('logtail-output');
+    var status = document.getElementById('logtail-status');
+    var socket = null;
+
+    button.addEventListener('click', function () {
+        if (socket) {
+            socket.close();
+            return;
+        }
+
+        var scheme = location.protocol === 'https:' ? 'wss:' : 'ws:';
+        var path = '/api/v1/pods/' + encodeURIComponent(button.dataset.namespace) +
+            '/' + encodeURIComponent(button.dataset.pod) + '/logs/tail';
+        socket = new WebSocket(scheme + '//' + location.host + path);
+        status.textContent = 'connecting...';
+        button.textContent = 'Stop tail';
+
+        socket.onopen = function () {
+            status.textContent = 'streaming';
+        };
+        socket.onmessage = function (event) {
+            var atBottom = output.scrollTop + output.clientHeight >= output.scrollHeight - 5;
+            output.appendChild(document.createTextNode(event.data + '\n'));
+            while (output.childNodes.length > maxLines) {
+                output.removeChild(output.firstChild);
+            }
+            if (atBottom) {
+                output.scrollTop = output.scrollHeight;
+            }
+        };
+        socket.onclose = function (event) {
+            status.textContent = event.reason || 'stopped';
+            button.textContent = 'Start tail';
+            socket = null;
+        };
+    });
+})();
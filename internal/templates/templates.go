@@ -0,0 +1,29 @@
+// Package templates holds the web UI: the HTML templates and, under
+// static/, the stylesheets and scripts they load.
+package templates
+
+import (
+	"embed"
+	"io/fs"
+	"os"
+)
+
+//go:embed *.html static
+var files embed.FS
+
+// FS returns the web UI files built into the binary, or those of dir when it
+// is set, so a deployment can restyle the UI without rebuilding. dir must
+// have the same layout as this directory.
+func FS(dir string) fs.FS {
+	if dir == "" {
+		return files
+	}
+	return os.DirFS(dir)
+}
+
+// Static returns the static/ subtree of ui
+func Static(ui fs.FS) fs.FS {
+	// fs.Sub only fails for an invalid directory name
+	static, _ := fs.Sub(ui, "static")
+	return static
+}
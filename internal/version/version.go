@@ -0,0 +1,5 @@
+package version
+
+// Version is the hepsre release, overridable at build time with
+// -ldflags "-X github.com/emirozbir/micro-sre/internal/version.Version=..."
+var Version = "0.1.0"